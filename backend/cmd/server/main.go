@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/kruakemaths/tru-activity/backend/graph"
 	"github.com/kruakemaths/tru-activity/backend/graph/generated"
@@ -15,51 +29,460 @@ import (
 	"github.com/kruakemaths/tru-activity/backend/internal/handlers"
 	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
 	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	"github.com/kruakemaths/tru-activity/backend/pkg/compression"
+	pkgdb "github.com/kruakemaths/tru-activity/backend/pkg/database"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"github.com/kruakemaths/tru-activity/backend/pkg/notifications"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/kruakemaths/tru-activity/backend/pkg/resolvers"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
 )
 
+const StartupMigrationLockKey = "startup:migrate"
+
+// CacheWarmTimeout bounds the background cache warm at startup
+const CacheWarmTimeout = time.Minute
+
+// StartupCacheWarmLockKey lets one of the instances starting together warm the shared cache
+const StartupCacheWarmLockKey = "startup:cache_warm"
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	shutdownTracing, err := monitoring.SetupTracing(context.Background(), monitoring.TracingConfig{
+		Enabled:     cfg.TracingEnabled,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		SampleRatio: cfg.TracingSampleRatio,
+		ServiceName: "tru-activity-backend",
+		Environment: cfg.Environment,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+
 	// Connect to database
 	db, err := database.NewConnection(cfg.DatabaseURL, cfg.Environment)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	db.RetryPolicy = database.RetryPolicy{
+		MaxAttempts: cfg.DBRetryMaxAttempts,
+		BaseDelay:   time.Duration(cfg.DBRetryBaseDelayMs) * time.Millisecond,
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		log.Fatal("Failed to access database pool:", err)
+	}
+	dbPool, err := pkgdb.NewConnectionPool(sqlDB, pkgdb.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.DBConnMaxLifetimeMinutes) * time.Minute,
+		ConnMaxIdleTime: time.Duration(cfg.DBConnMaxIdleTimeMinutes) * time.Minute,
+	})
+	if err != nil {
+		log.Fatal("Invalid database pool settings:", err)
+	}
 
-	// Auto-migrate database models
-	err = db.Migrate(
-		&models.User{},
-		&models.Faculty{},
-		&models.Department{},
-		&models.Activity{},
-		&models.Participation{},
-		&models.Subscription{},
-	)
+	// Connect to Redis, keeping keys under the deployment's prefix
+	rediskeys.SetPrefix(cfg.RedisKeyPrefix)
+	if rediskeys.Prefix() != "" {
+		log.Printf("Redis keys are prefixed with %q", rediskeys.Prefix())
+	}
+	redisOptions, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		log.Fatal("Failed to parse Redis URL:", err)
+	}
+	redisClient := redis.NewClient(redisOptions)
+	distributedLock := lock.NewDistributedLock(redisClient)
+	scannerTokens := auth.NewScannerTokenStore(redisClient)
+
+	// Auto-migrate database models on one instance while the others wait
+	startupLockWait := time.Duration(cfg.StartupLockWaitSeconds) * time.Second
+	err = distributedLock.RunOnce(context.Background(), StartupMigrationLockKey, lock.DefaultLockTTL, startupLockWait, func() error {
+		return db.Migrate(
+			&models.User{},
+			&models.Faculty{},
+			&models.Department{},
+			&models.Activity{},
+			&models.Participation{},
+			&models.Subscription{},
+			&models.FeatureFlag{},
+			&models.PersistedQuery{},
+			&audit.AuditEvent{},
+			&audit.SecurityEvent{},
+		)
+	})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	auditLogger := audit.NewAuditLogger(db.DB, redisClient)
+	maskingRules := audit.DefaultMaskingRules()
+	if len(cfg.AuditMaskHashKeys) > 0 {
+		maskingRules.HashKeys = cfg.AuditMaskHashKeys
+	}
+	if len(cfg.AuditMaskRedactKeys) > 0 {
+		maskingRules.RedactKeys = cfg.AuditMaskRedactKeys
+	}
+	maskingRules.HashSecret = cfg.AuditMaskHashSecret
+	if maskingRules.HashSecret == "" {
+		maskingRules.HashSecret = cfg.JWTSecret
+	}
+	auditLogger.SetMasking(audit.NewDetailMasker(maskingRules))
+	auditLogger.StartBatching(audit.BatchConfig{
+		Size:          cfg.AuditBatchSize,
+		FlushInterval: time.Duration(cfg.AuditFlushIntervalMs) * time.Millisecond,
+		AsyncWrites:   cfg.AuditAsyncWrites,
+	})
+	exportLimiter := lock.NewConcurrencyLimiter(redisClient, "export", cfg.MaxConcurrentExports, 30*time.Minute)
+	joinLimiter := services.NewJoinLimiter(db.DB, redisClient, cfg.DailyJoinLimit, cfg.ActiveJoinLimit)
+
+	performanceMonitor := monitoring.NewPerformanceMonitor(db.DB, redisClient)
+	go dbPool.StartMonitoring(context.Background(), performanceMonitor, pkgdb.DefaultPoolSampleInterval)
+
+	var alertNotifiers []monitoring.AlertNotifier
+	for _, name := range cfg.AlertNotifiers {
+		switch strings.ToLower(name) {
+		case "email":
+			alertNotifiers = append(alertNotifiers, &monitoring.EmailNotifier{
+				Host:     cfg.SMTPHost,
+				Port:     cfg.SMTPPort,
+				Username: cfg.SMTPUsername,
+				Password: cfg.SMTPPassword,
+				From:     cfg.SMTPFrom,
+				To:       cfg.AlertEmailTo,
+			})
+		case "slack":
+			if cfg.AlertSlackWebhookURL == "" {
+				log.Fatal("ALERT_SLACK_WEBHOOK_URL is required for the slack alert notifier")
+			}
+			alertNotifiers = append(alertNotifiers, monitoring.NewSlackNotifier(cfg.AlertSlackWebhookURL))
+		default:
+			log.Fatalf("Unknown alert notifier %q", name)
+		}
+	}
+	performanceMonitor.SetAlertNotifiers(alertNotifiers, time.Duration(cfg.AlertNotifyThrottleMinutes)*time.Minute, cfg.AlertDashboardURL)
+
+	// Installs a GORM logger that records per-statement timings
+	var queryOptimizer *pkgdb.QueryOptimizer
+	if cfg.QueryStatsEnabled {
+		queryOptimizer = pkgdb.NewQueryOptimizer(db.DB, redisClient)
+	}
+	cacheManager := performance.NewCacheManager(redisClient, db.DB)
+	if cfg.CacheWarmOnStartup {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), CacheWarmTimeout)
+			defer cancel()
+			// The cache is shared, so the other instances wait for the one warming it
+			err := distributedLock.RunOnce(ctx, StartupCacheWarmLockKey, CacheWarmTimeout, CacheWarmTimeout, func() error {
+				warmed, err := cacheManager.WarmCache(ctx)
+				log.Printf("Cache warmed with %d entries", warmed)
+				return err
+			})
+			if err != nil {
+				log.Printf("Cache warming incomplete: %v", err)
+			}
+		}()
+	}
+	facultyComparison := services.NewFacultyComparisonService(db.DB, cacheManager)
+	featureFlags := services.NewFeatureFlagService(db.DB, cacheManager)
+	qrEncoding, err := security.ParseQREncoding(cfg.QREncoding)
+	if err != nil {
+		log.Fatal("Invalid QR_ENCODING:", err)
+	}
+	qrSecurity := security.NewQRSecurityManager(redisClient, []byte(cfg.QRMasterSecret), security.QRScanRateLimits{
+		StudentLimit:     cfg.QRStudentScanLimit,
+		StudentWindow:    time.Duration(cfg.QRStudentScanWindowSeconds) * time.Second,
+		ScannerLimit:     cfg.QRScannerScanLimit,
+		ScannerWindow:    time.Duration(cfg.QRScannerScanWindowSeconds) * time.Second,
+		GenerationLimit:  cfg.QRGenerationLimit,
+		GenerationWindow: time.Duration(cfg.QRGenerationWindowSeconds) * time.Second,
+	}, time.Duration(cfg.QRClockSkewSeconds)*time.Second, security.QRCodeFormat{
+		Encoding:   qrEncoding,
+		MaxPayload: cfg.QRMaxPayloadLength,
+	}, security.QRRedisPolicy{
+		FailureThreshold: cfg.QRRedisBreakerThreshold,
+		Cooldown:         time.Duration(cfg.QRRedisBreakerCooldownSeconds) * time.Second,
+		Degrade:          cfg.QRDegradedMode,
+	})
+	qrSecurity.SetAuditLogger(auditLogger)
+
+	instanceID, _ := os.Hostname()
+
+	// Initialize realtime event publishing
+	if cfg.RealtimeFanoutAlertThreshold > 0 {
+		performanceMonitor.UpdateAlertThreshold(monitoring.FanoutSizeMetric, monitoring.AlertThreshold{
+			MetricName:    monitoring.FanoutSizeMetric,
+			WarningLevel:  float64(cfg.RealtimeFanoutAlertThreshold),
+			CriticalLevel: float64(10 * cfg.RealtimeFanoutAlertThreshold),
+			Duration:      5 * time.Minute,
+			Enabled:       true,
+		})
+	}
+	// Slow consumers on either realtime transport are recorded as performance metrics
+	reportBufferOverflow := func(overflow monitoring.BufferOverflow) {
+		if err := performanceMonitor.RecordBufferOverflow(context.Background(), overflow); err != nil {
+			log.Printf("Failed to record buffer overflow for %s connection %s: %v", overflow.Kind, overflow.ConnectionID, err)
+		}
+	}
+
+	var eventPublisher *services.EventPublisher
+	var subscriptionResolver *resolvers.SubscriptionResolver
+	var connectionManager *services.ConnectionManager
+	pubSubService, err := services.NewPubSubService(cfg.RedisURL, instanceID)
+	if err != nil {
+		log.Printf("Realtime events disabled: %v", err)
+	} else {
+		connectionRegistry := services.NewConnectionRegistry(redisClient, instanceID,
+			time.Duration(cfg.ConnectionHeartbeatSeconds)*time.Second,
+			time.Duration(cfg.ConnectionInstanceTTLSeconds)*time.Second)
+		connectionManager = services.NewConnectionManager(pubSubService, connectionRegistry, instanceID, 1000,
+			time.Duration(cfg.ConnectionIdleTimeoutSeconds)*time.Second,
+			time.Duration(cfg.ConnectionCleanupIntervalSeconds)*time.Second,
+			monitoring.BufferConfig{
+				Size:              cfg.ConnectionBufferSize,
+				OverflowThreshold: cfg.BufferOverflowThreshold,
+				OnOverflow:        reportBufferOverflow,
+			})
+		eventPublisher = services.NewEventPublisher(db.DB, pubSubService, connectionManager, instanceID, services.FanoutConfig{
+			BatchSize:           cfg.RealtimeFanoutBatchSize,
+			MaxRecipients:       cfg.RealtimeFanoutMaxRecipients,
+			BackgroundThreshold: cfg.RealtimeFanoutBackgroundThreshold,
+			AlertThreshold:      cfg.RealtimeFanoutAlertThreshold,
+			OnFanout: func(sample monitoring.FanoutSample) {
+				if err := performanceMonitor.RecordFanout(context.Background(), sample); err != nil {
+					log.Printf("Failed to record fan-out of event %s: %v", sample.Event, err)
+				}
+			},
+		})
+		go eventPublisher.StartFanoutWorker(context.Background())
+		subscriptionResolver = resolvers.NewSubscriptionResolver(connectionManager, pubSubService,
+			time.Duration(cfg.SubscriptionReorderWindowMs)*time.Millisecond, scannerTokens)
+
+		if cfg.ConnectionStatsIntervalSeconds > 0 {
+			go eventPublisher.StartConnectionStatsPublisher(context.Background(), time.Duration(cfg.ConnectionStatsIntervalSeconds)*time.Second)
+		}
+		if cfg.ConnectionMetricsIntervalSeconds > 0 {
+			go connectionManager.StartMetricsExport(context.Background(), performanceMonitor, time.Duration(cfg.ConnectionMetricsIntervalSeconds)*time.Second)
+		}
+	}
+
+	// Start background jobs
+	activityArchiver := services.NewActivityArchiver(db.DB, eventPublisher, distributedLock, cfg.ActivityArchiveAfterDays)
+	go activityArchiver.StartArchiveScheduler(context.Background())
+
+	activityRescheduler := services.NewActivityRescheduler(db.DB, eventPublisher, distributedLock, cfg.RescheduleConfirmWindowHours, cfg.RescheduleReopenRegistration)
+	go activityRescheduler.StartAutoConfirmScheduler(context.Background())
+
+	metricsSnapshotter := services.NewMetricsSnapshotter(db.DB, distributedLock)
+	go metricsSnapshotter.StartSnapshotScheduler(context.Background())
+	maintenanceJobs := services.NewMaintenanceJobs(db.DB, redisClient, distributedLock, metricsSnapshotter, cacheManager)
+
+	subscriptionExpiryMonitor := services.NewSubscriptionExpiryMonitor(db.DB, eventPublisher, distributedLock, cfg.SubscriptionWarningDays)
+	go subscriptionExpiryMonitor.StartExpiryScheduler(context.Background())
+
 	// Initialize JWT service
-	jwtService := auth.NewJWTService(cfg.JWTSecret, cfg.JWTExpireHours)
+	jwtService := auth.NewJWTService(cfg.JWTSecret, cfg.JWTExpireHours, auth.ClaimOptions{
+		OrgCodes: cfg.JWTOrgCodeClaims,
+		OrgNames: cfg.JWTOrgNameClaims,
+	})
+	sessionStore := auth.NewSessionStore(redisClient)
+	passwordResetStore := auth.NewPasswordResetStore(redisClient)
+
+	passwordPolicy := utils.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireLetter: cfg.PasswordRequireLetter,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+	}
+	if cfg.PasswordBreachListPath != "" {
+		passwordPolicy.Breached, err = utils.LoadBreachedPasswordFilter(cfg.PasswordBreachListPath, cfg.PasswordBreachFalseRate)
+		if err != nil {
+			log.Fatal("Failed to load breached password list:", err)
+		}
+	}
+
+	// Initialize notification service
+	notificationService := notifications.NewNotificationService(db.DB, notifications.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+
+	unstaffedMonitor := services.NewUnstaffedActivityMonitor(db.DB, notificationService, distributedLock, cfg.UnstaffedAlertLeadHours)
+	go unstaffedMonitor.StartUnstaffedCheckScheduler(context.Background())
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
-	gqlAuthMiddleware := middleware.NewGraphQLAuthMiddleware(jwtService, db.DB)
+	gqlAuthMiddleware := middleware.NewGraphQLAuthMiddleware(jwtService, sessionStore, db.DB)
+	if err := gqlAuthMiddleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(redisClient, time.Duration(cfg.IdempotencyTTLSeconds)*time.Second)
+
+	compressionConfig := compression.Config{
+		Enabled:      cfg.CompressionEnabled,
+		MinBytes:     cfg.CompressionMinBytes,
+		ContentTypes: cfg.CompressionContentTypes,
+	}
+	compressionMiddleware := middleware.NewCompressionMiddleware(compressionConfig)
 
 	// Initialize SSE handler
-	sseHandler := handlers.NewSSEHandler(db, jwtService)
+	sseHandler := handlers.NewSSEHandler(db, jwtService, sessionStore, compressionConfig, monitoring.BufferConfig{
+		Size:              cfg.SSEBufferSize,
+		OverflowThreshold: cfg.BufferOverflowThreshold,
+		OnOverflow:        reportBufferOverflow,
+	}, handlers.SSEKeepaliveConfig{
+		HeartbeatInterval:    time.Duration(cfg.SSEHeartbeatSeconds) * time.Second,
+		MinHeartbeatInterval: time.Duration(cfg.SSEMinHeartbeatSeconds) * time.Second,
+		IdleTimeout:          time.Duration(cfg.SSEIdleTimeoutSeconds) * time.Second,
+	}, handlers.SSEReconnectConfig{
+		BaseDelay:    time.Duration(cfg.SSEReconnectBaseDelayMs) * time.Millisecond,
+		MaxDelay:     time.Duration(cfg.SSEReconnectMaxDelaySeconds) * time.Second,
+		JitterWindow: time.Duration(cfg.SSEReconnectJitterSeconds) * time.Second,
+	})
+
+	// Signing a user out everywhere also drops their websockets, subscriptions and SSE streams
+	connectionClosers := []services.ConnectionCloser{gqlAuthMiddleware.CloseUserWebsockets, sseHandler.CloseUserClients}
+	if connectionManager != nil {
+		connectionClosers = append(connectionClosers, connectionManager.CloseUserConnections)
+	}
+	sessionTerminator := services.NewSessionTerminator(pubSubService, instanceID, connectionClosers...)
+	if err := sessionTerminator.Start(); err != nil {
+		log.Printf("Session termination limited to this instance: %v", err)
+	}
+
+	participationExportHandler := handlers.NewParticipationExportHandler(db, auditLogger, exportLimiter, cfg.ExportPageSize)
+
+	if _, err := models.LoadTimezone(cfg.DefaultTimezone); err != nil {
+		log.Fatal("Invalid DEFAULT_TIMEZONE:", err)
+	}
 
 	// Initialize GraphQL resolver
+	activityDateRules := services.ActivityDateRules{
+		StartGrace:  time.Duration(cfg.ActivityStartGraceMinutes) * time.Minute,
+		MaxDuration: time.Duration(cfg.ActivityMaxDurationDays) * 24 * time.Hour,
+	}
+	activityService := services.NewActivityService(db.DB, distributedLock)
+	activityService.DateRules = activityDateRules
+
 	resolverConfig := &graph.Resolver{
-		DB:         db,
-		JWTService: jwtService,
+		DB:                       db,
+		JWTService:               jwtService,
+		AuditLogger:              auditLogger,
+		SessionStore:             sessionStore,
+		SessionTerminator:        sessionTerminator,
+		PasswordResets:           passwordResetStore,
+		NotificationService:      notificationService,
+		PasswordResetURL:         cfg.PasswordResetURL,
+		PasswordPolicy:           passwordPolicy,
+		ExportLimiter:            exportLimiter,
+		FacultyComparisonService: facultyComparison,
+		FeatureFlagService:       featureFlags,
+		MetricsSnapshotter:       metricsSnapshotter,
+		MaintenanceJobs:          maintenanceJobs,
+		JoinLimiter:              joinLimiter,
+		EventPublisher:           eventPublisher,
+		ActivityService:          activityService,
+		ActivityRescheduler:      activityRescheduler,
+		CacheManager:             cacheManager,
+		QRSecurity:               qrSecurity,
+		ScannerTokens:            scannerTokens,
+		QueryOptimizer:           queryOptimizer,
+		PerformanceMonitor:       performanceMonitor,
+		ActivityDateRules:        activityDateRules,
+		DefaultTimezone:          cfg.DefaultTimezone,
+		EnforceFacultyScope:      cfg.EnforceFacultyScope,
+		Subscriptions:            subscriptionResolver,
+	}
+
+	// Origins allowed to call the API, for CORS and websocket upgrades alike
+	allowAllOrigins := cfg.Environment == "development" && cfg.CORSAllowAllInDevelopment
+	allowedOrigin := middleware.NewOriginMatcher(cfg.CORSAllowedOrigins)
+	if allowAllOrigins {
+		allowedOrigin = func(string) bool { return true }
 	}
 
 	// Create GraphQL server
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolverConfig}))
+	// Same transports as handler.NewDefaultServer, with websockets authenticated on connection_init
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers: resolverConfig,
+		Directives: generated.DirectiveRoot{
+			Auth:          middleware.AuthDirective,
+			HasRole:       middleware.HasRoleDirective,
+			HasPermission: middleware.HasPermissionDirective,
+		},
+	}))
+	srv.AddTransport(transport.Websocket{
+		Upgrader:              websocket.Upgrader{CheckOrigin: middleware.NewWebsocketOriginCheck(allowedOrigin)},
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              gqlAuthMiddleware.WebsocketInit,
+		CloseFunc:             gqlAuthMiddleware.WebsocketClose,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	// Registered first, so its timing covers the extensions after it
+	srv.Use(middleware.PerformanceExtension{
+		Monitor:        performanceMonitor,
+		SlowThreshold:  time.Duration(cfg.GraphQLSlowOperationMs) * time.Millisecond,
+		HighComplexity: cfg.GraphQLHighComplexity,
+	})
+	if cfg.PersistedQueriesStrict {
+		allowlist := middleware.NewPersistedQueryAllowlist()
+		if cfg.PersistedQueriesFile != "" {
+			if _, err := allowlist.LoadFile(cfg.PersistedQueriesFile); err != nil {
+				log.Fatal("Failed to load persisted queries:", err)
+			}
+		}
+		if _, err := allowlist.LoadDB(context.Background(), db.DB); err != nil {
+			log.Fatal("Failed to load persisted queries:", err)
+		}
+		log.Printf("Persisted queries enforced with %d allowed operations", allowlist.Len())
+		// Development keeps registering new queries so the playground and local clients work
+		srv.Use(middleware.StrictPersistedQueries{
+			Allowlist:         allowlist,
+			AllowRegistration: cfg.Environment == "development",
+		})
+	} else {
+		srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](100)})
+	}
 	srv.Use(gqlAuthMiddleware.ExtractAuth())
+	// Registered after ExtractAuth, whose caller it limits and authorizes, and before the cache,
+	// so cached responses count against the rate limit too
+	srv.Use(middleware.NewSecurityMiddleware(redisClient, auditLogger, middleware.RateLimitConfig{
+		Window:         time.Duration(cfg.RateLimitWindowSeconds) * time.Second,
+		DefaultLimit:   cfg.RateLimitDefault,
+		AdminLimit:     cfg.RateLimitAdmin,
+		AnonymousLimit: cfg.RateLimitAnonymous,
+		AuthLimit:      cfg.RateLimitAuth,
+		QRScanLimit:    cfg.RateLimitQRScan,
+	}, cfg.QRMaxPayloadLength))
+	// Registered after ExtractAuth, which it needs to key private responses by user
+	srv.Use(middleware.CacheExtension{Cache: cacheManager})
+	srv.Use(idempotencyMiddleware)
+	if cfg.TracingEnabled {
+		srv.Use(middleware.GraphQLTracer{})
+	}
+	// Each operation gets its own DataLoaders, so nothing they cache outlives it
+	srv.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		return next(performance.WithDataLoaders(ctx, performance.NewDataLoaderContainer(db.DB, nil)))
+	})
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,13 +495,22 @@ func main() {
 	})
 
 	// Middleware
+	app.Use(requestid.New())
+	app.Use(middleware.TracingMiddleware())
 	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOriginsFunc: func(origin string) bool { return true },
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
-		AllowCredentials: true,
-	}))
+	app.Use(compressionMiddleware.Compress())
+	corsConfig := cors.Config{
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Idempotency-Key, X-Scanner-Token",
+		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+	}
+	if allowAllOrigins {
+		// Any origin, but never together with credentials
+		corsConfig.AllowOrigins = "*"
+	} else {
+		corsConfig.AllowOriginsFunc = allowedOrigin
+		corsConfig.AllowCredentials = true
+	}
+	app.Use(cors.New(corsConfig))
 
 	// Health check endpoint
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -102,9 +534,30 @@ func main() {
 			})
 		}
 
+		// Realtime delivery is checked end to end; a broken pub/sub degrades but doesn't fail readiness
+		if err := performanceMonitor.CheckPubSubRoundTrip(c.Context(), monitoring.PubSubHealthTimeout); err != nil {
+			return c.JSON(fiber.Map{
+				"status":        "degraded",
+				"message":       "TRU Activity API is ready without realtime events",
+				"pubsub_status": "DEGRADED",
+				"cache":         cacheManager.BreakerStats(),
+				"qr_redis":      qrSecurity.RedisBreakerStats(),
+				"sse":           sseHandler.GetBufferStats(),
+				"sse_keepalive": sseHandler.GetKeepaliveStats(),
+				"database_pool": dbPool.LastSample(),
+				"error":         err.Error(),
+			})
+		}
+
 		return c.JSON(fiber.Map{
-			"status":  "ready",
-			"message": "TRU Activity API is ready",
+			"status":        "ready",
+			"message":       "TRU Activity API is ready",
+			"pubsub_status": "HEALTHY",
+			"cache":         cacheManager.BreakerStats(),
+			"qr_redis":      qrSecurity.RedisBreakerStats(),
+			"sse":           sseHandler.GetBufferStats(),
+			"sse_keepalive": sseHandler.GetKeepaliveStats(),
+			"database_pool": dbPool.LastSample(),
 		})
 	})
 
@@ -149,13 +602,12 @@ func main() {
 		})
 	}
 
-	// GraphQL endpoint - disable for now due to compatibility issues
-	app.All("/query", func(c *fiber.Ctx) error {
-		// TODO: Fix Fiber to net/http adapter
-		return c.JSON(fiber.Map{
-			"error": "GraphQL endpoint temporarily disabled due to HTTP adapter issues",
-		})
-	})
+	// GraphQL endpoint; websocket subscriptions are upgraded on the same path and report their
+	// rate limits in the response extension rather than headers
+	app.All("/query", middleware.HTTPHandlerWithWebsockets(
+		middleware.WithRemoteAddr(middleware.WithRateLimitHeaders(srv)),
+		middleware.WithRemoteAddr(srv),
+	))
 
 	// SSE endpoints
 	app.Get("/events", sseHandler.HandleSSEConnection)
@@ -170,12 +622,41 @@ func main() {
 	// Admin routes group
 	admin := protected.Group("/admin")
 	admin.Use(authMiddleware.RequireRole("super_admin", "faculty_admin", "regular_admin"))
+	admin.Get("/activities/:id/participations/export", participationExportHandler.HandleExport)
+
+	// Synthetic realtime load, for load tests only
+	if cfg.LoadTestEnabled {
+		connectionLoadHandler := handlers.NewConnectionLoadHandler(connectionManager)
+		admin.Post("/load-test/connections", authMiddleware.RequireRole("super_admin"), connectionLoadHandler.HandleStart)
+		log.Printf("Load test endpoint enabled at /api/admin/load-test/connections")
+	}
 
 	log.Printf("Server starting on port %s", cfg.Port)
 	log.Printf("GraphQL playground available at http://localhost:%s/", cfg.Port)
 	log.Printf("GraphQL endpoint at http://localhost:%s/query", cfg.Port)
 
+	// On SIGINT/SIGTERM drain realtime clients, stop accepting requests and flush buffered audit events
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		log.Println("Shutting down server...")
+		if connectionManager != nil {
+			connectionManager.Drain(time.Duration(cfg.ConnectionDrainSeconds) * time.Second)
+		}
+		sseHandler.Shutdown()
+		if err := app.Shutdown(); err != nil {
+			log.Printf("Server shutdown failed: %v", err)
+		}
+	}()
+
 	if err := app.Listen(":" + cfg.Port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
+
+	auditLogger.Close()
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
 }
@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+// loadCoHostTarget resolves the activity and faculty of a co-host change and checks that the
+// caller may manage the activity
+func (r *Resolver) loadCoHostTarget(ctx context.Context, activityID, facultyID string) (*middleware.AuthContext, *models.Activity, *models.Faculty, error) {
+	actID, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, nil, nil, errcode.Validation("invalid activity ID")
+	}
+	fID, err := strconv.ParseUint(facultyID, 10, 32)
+	if err != nil {
+		return nil, nil, nil, errcode.Validation("invalid faculty ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, actID).Error; err != nil {
+		return nil, nil, nil, errcode.NotFound("activity not found")
+	}
+
+	authCtx, err := r.requireActivityScope(ctx, &activity, activityID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var faculty models.Faculty
+	if err := r.DB.WithContext(ctx).First(&faculty, fID).Error; err != nil {
+		return nil, nil, nil, errcode.NotFound("faculty not found")
+	}
+	return authCtx, &activity, &faculty, nil
+}
+
+func isCoHost(activity *models.Activity, facultyID uint) bool {
+	for _, faculty := range activity.CoHostFaculties {
+		if faculty.ID == facultyID {
+			return true
+		}
+	}
+	return false
+}
+
+// addActivityCoHost lets another faculty co-host the activity. Any admin who may manage the
+// activity can invite a faculty; the invited faculty's admins may then manage it too.
+func (r *Resolver) addActivityCoHost(ctx context.Context, activityID, facultyID string) (*models.Activity, error) {
+	authCtx, activity, faculty, err := r.loadCoHostTarget(ctx, activityID, facultyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !faculty.IsActive {
+		return nil, errcode.Validation("faculty is not active")
+	}
+	if activity.FacultyID != nil && *activity.FacultyID == faculty.ID {
+		return nil, errcode.Validation("faculty already hosts this activity")
+	}
+	if isCoHost(activity, faculty.ID) {
+		return nil, errcode.Conflict("faculty already co-hosts this activity")
+	}
+
+	if err := r.DB.WithContext(ctx).Model(activity).Association("CoHostFaculties").Append(faculty); err != nil {
+		return nil, fmt.Errorf("failed to add co-host faculty")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivity, activityID, map[string]interface{}{
+		"co_host_added": faculty.ID,
+	})
+	return r.coHostsChanged(ctx, authCtx, activity)
+}
+
+// removeActivityCoHost stops a faculty co-hosting the activity
+func (r *Resolver) removeActivityCoHost(ctx context.Context, activityID, facultyID string) (*models.Activity, error) {
+	authCtx, activity, faculty, err := r.loadCoHostTarget(ctx, activityID, facultyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isCoHost(activity, faculty.ID) {
+		return nil, errcode.NotFound("faculty does not co-host this activity")
+	}
+
+	if err := r.DB.WithContext(ctx).Model(activity).Association("CoHostFaculties").Delete(faculty); err != nil {
+		return nil, fmt.Errorf("failed to remove co-host faculty")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivity, activityID, map[string]interface{}{
+		"co_host_removed": faculty.ID,
+	})
+	return r.coHostsChanged(ctx, authCtx, activity)
+}
+
+// coHostsChanged reloads the activity and tells its subscribers about the new co-hosts
+func (r *Resolver) coHostsChanged(ctx context.Context, authCtx *middleware.AuthContext, activity *models.Activity) (*models.Activity, error) {
+	var updated models.Activity
+	if err := r.DB.WithContext(ctx).Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy").
+		First(&updated, activity.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load activity")
+	}
+
+	if r.EventPublisher != nil {
+		if err := r.EventPublisher.PublishActivityUpdated(&updated, "co_hosts_changed", &services.EventContext{
+			UserID:     &authCtx.UserID,
+			FacultyID:  updated.FacultyID,
+			ActivityID: &updated.ID,
+			Source:     "activity_co_hosts",
+		}); err != nil {
+			log.Printf("Failed to publish co-host change of activity %d: %v", updated.ID, err)
+		}
+	}
+
+	return convertActivityToGraphQL(&updated), nil
+}
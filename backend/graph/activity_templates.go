@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// parseActivityType maps the GraphQL enum value onto the stored activity type
+func parseActivityType(activityType models.ActivityType) (models.ActivityType, error) {
+	switch normalized := models.ActivityType(strings.ToLower(string(activityType))); normalized {
+	case models.ActivityTypeWorkshop, models.ActivityTypeSeminar, models.ActivityTypeCompetition,
+		models.ActivityTypeVolunteer, models.ActivityTypeOther:
+		return normalized, nil
+	default:
+		return "", errcode.Validation("unknown activity type %q", activityType)
+	}
+}
+
+// validateActivityTemplate checks the defaults an activity inherits from its template
+func (r *Resolver) validateActivityTemplate(template *models.ActivityTemplate) error {
+	if strings.TrimSpace(template.Name) == "" {
+		return errcode.Validation("template name is required")
+	}
+	if template.DefaultDuration <= 0 {
+		return errcode.Validation("default duration must be at least one minute")
+	}
+	if maxDuration := r.ActivityDateRules.MaxDuration; maxDuration > 0 && time.Duration(template.DefaultDuration)*time.Minute > maxDuration {
+		return errcode.Validation("default duration cannot exceed %d minutes", int(maxDuration.Minutes()))
+	}
+	if template.Points < 0 {
+		return errcode.Validation("points cannot be negative")
+	}
+	if template.MaxParticipants != nil && *template.MaxParticipants <= 0 {
+		return errcode.Validation("max participants must be positive")
+	}
+	// Walk-ins are only auto-approved when they scan the activity's QR code
+	if template.AutoApprove && !template.QRCodeRequired {
+		return errcode.Validation("auto approve requires a QR code")
+	}
+	return nil
+}
+
+// loadActivityTemplate fetches a template with the relations exposed in GraphQL
+func (r *Resolver) loadActivityTemplate(ctx context.Context, id uint) (*models.ActivityTemplate, error) {
+	var template models.ActivityTemplate
+	if err := r.DB.WithContext(ctx).Preload("Faculty").Preload("CreatedBy").Preload("Activities").First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// activityTemplateAuditDetails snapshots the editable fields of a template for the audit trail
+func activityTemplateAuditDetails(template *models.ActivityTemplate) map[string]interface{} {
+	details := map[string]interface{}{
+		"name":             template.Name,
+		"description":      template.Description,
+		"type":             string(template.Type),
+		"default_duration": template.DefaultDuration,
+		"location":         template.Location,
+		"require_approval": template.RequireApproval,
+		"points":           template.Points,
+		"qr_code_required": template.QRCodeRequired,
+		"auto_approve":     template.AutoApprove,
+		"is_active":        template.IsActive,
+	}
+	if template.MaxParticipants != nil {
+		details["max_participants"] = *template.MaxParticipants
+	}
+	if template.FacultyID != nil {
+		details["faculty_id"] = *template.FacultyID
+	}
+	return details
+}
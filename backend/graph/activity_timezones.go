@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// activityTimezone picks the zone of a new activity: the one requested, else its faculty's,
+// else the institution's
+func (r *Resolver) activityTimezone(ctx context.Context, requested *string, facultyID *uint) (string, error) {
+	if requested != nil {
+		if _, err := models.LoadTimezone(*requested); err != nil {
+			return "", timezoneError(ctx, "input.timezone", *requested)
+		}
+		return *requested, nil
+	}
+
+	if facultyID != nil {
+		var faculty models.Faculty
+		if err := r.DB.WithContext(ctx).Select("timezone").First(&faculty, *facultyID).Error; err == nil && faculty.Timezone != "" {
+			return faculty.Timezone, nil
+		}
+	}
+	if r.DefaultTimezone != "" {
+		return r.DefaultTimezone, nil
+	}
+	return models.DefaultTimezone, nil
+}
+
+func timezoneError(ctx context.Context, field, name string) error {
+	gqlErr := errcode.Validation("unknown time zone %q, use an IANA name such as Asia/Bangkok", name)
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["field"] = field
+	return gqlErr
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+func TestActivityLocalDatesCarryTheOffset(t *testing.T) {
+	r := &activityResolver{&Resolver{}}
+	// 23:30 UTC on December 31 is already the next day and year in Bangkok
+	start := time.Date(2026, 12, 31, 23, 30, 0, 0, time.UTC)
+	activity := &models.Activity{Timezone: "Asia/Bangkok", StartDate: start, EndDate: start.Add(time.Hour)}
+
+	localStart, err := r.LocalStartDate(context.Background(), activity)
+	if err != nil {
+		t.Fatalf("LocalStartDate: %v", err)
+	}
+	if got := localStart.Format(time.RFC3339); got != "2027-01-01T06:30:00+07:00" {
+		t.Errorf("localStartDate = %s, want 2027-01-01T06:30:00+07:00", got)
+	}
+	localEnd, _ := r.LocalEndDate(context.Background(), activity)
+	if !localEnd.Equal(activity.EndDate) {
+		t.Errorf("localEndDate = %v, want the same instant as %v", localEnd, activity.EndDate)
+	}
+
+	// An activity without a zone is shown in the institution's
+	activity.Timezone = ""
+	localStart, _ = r.LocalStartDate(context.Background(), activity)
+	if _, offset := localStart.Zone(); offset != 7*60*60 {
+		t.Errorf("localStartDate without a zone = %s, want the +07:00 default", localStart.Format(time.RFC3339))
+	}
+}
+
+func TestActivityTimezoneFallbacks(t *testing.T) {
+	db, _ := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.HasPrefix(query, `SELECT "timezone" FROM "faculties"`) && args[0].Value == int64(3) {
+			return fakedb.Result{Columns: []string{"timezone"}, Rows: [][]driver.Value{{"Europe/London"}}}
+		}
+		return fakedb.Result{}
+	})
+	r := &Resolver{DB: &database.DB{DB: db}, DefaultTimezone: "Asia/Tokyo"}
+	zone := func(name string) *string { return &name }
+	faculty := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name      string
+		requested *string
+		facultyID *uint
+		want      string
+	}{
+		{"requested zone", zone("America/New_York"), faculty(3), "America/New_York"},
+		{"faculty zone", nil, faculty(3), "Europe/London"},
+		{"faculty without a zone", nil, faculty(4), "Asia/Tokyo"},
+		{"no faculty", nil, nil, "Asia/Tokyo"},
+	}
+	for _, tt := range tests {
+		if got, err := r.activityTimezone(context.Background(), tt.requested, tt.facultyID); err != nil || got != tt.want {
+			t.Errorf("%s: activityTimezone() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, name := range []string{"Mars/Olympus", "Local", ""} {
+		_, err := r.activityTimezone(context.Background(), zone(name), nil)
+		if errorCode(err) != string(errcode.CodeValidation) {
+			t.Errorf("activityTimezone(%q) error = %v, want a validation error", name, err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/resolvers"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDashboardLimit = 10
+	maxDashboardLimit     = 50
+)
+
+// dashboardSection loads one part of the admin dashboard; a failure only empties that part
+type dashboardSection struct {
+	name string
+	load func() error
+}
+
+func dashboardLimit(limit *int) int {
+	if limit != nil && *limit > 0 && *limit <= maxDashboardLimit {
+		return *limit
+	}
+	return defaultDashboardLimit
+}
+
+func (r *Resolver) adminDashboard(ctx context.Context, limit *int) (*model.AdminDashboard, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	pageLimit := dashboardLimit(limit)
+	// Nil for super admins; faculty admins are limited to their faculty in every section
+	facultyID := securityEventFaculty(authCtx)
+	superAdmin := facultyID == nil
+
+	dashboard := &model.AdminDashboard{
+		Errors:      []*model.DashboardSectionError{},
+		GeneratedAt: time.Now(),
+	}
+
+	sections := []dashboardSection{
+		{"facultyMetrics", func() (err error) {
+			dashboard.FacultyMetrics, err = r.dashboardFacultyMetrics(ctx, facultyID)
+			return err
+		}},
+		{"securityEvents", func() (err error) {
+			dashboard.RecentAlerts, dashboard.SecurityEvents, err = r.dashboardSecurityEvents(ctx, facultyID, pageLimit)
+			return err
+		}},
+		{"pendingParticipations", func() (err error) {
+			var count int
+			dashboard.PendingParticipations, count, err = r.dashboardPendingParticipations(ctx, facultyID, pageLimit)
+			if err == nil {
+				dashboard.PendingParticipationCount = &count
+			}
+			return err
+		}},
+	}
+	if superAdmin {
+		sections = append(sections,
+			dashboardSection{"systemMetrics", func() (err error) {
+				dashboard.SystemMetrics, err = r.dashboardSystemMetrics(ctx)
+				return err
+			}},
+			dashboardSection{"connectionStats", func() error {
+				if r.Subscriptions == nil {
+					return fmt.Errorf("realtime events are unavailable")
+				}
+				stats := r.Subscriptions.ConnectionManager.GetConnectionStats(true)
+				dashboard.ConnectionStats = resolvers.ConvertConnectionStats(stats, time.Now())
+				return nil
+			}},
+			dashboardSection{"slowQueries", func() error {
+				if r.QueryOptimizer == nil {
+					return fmt.Errorf("query statistics are not available")
+				}
+				slowQueries := r.QueryOptimizer.GetSlowQueries(pageLimit)
+				dashboard.SlowQueries = make([]*model.SlowQuery, 0, len(slowQueries))
+				for _, query := range slowQueries {
+					dashboard.SlowQueries = append(dashboard.SlowQueries, r.convertSlowQuery(query))
+				}
+				return nil
+			}},
+		)
+	}
+
+	// Sections write disjoint fields of the dashboard, so they can load side by side
+	errs := make([]error, len(sections))
+	var wg sync.WaitGroup
+	for i, section := range sections {
+		wg.Add(1)
+		go func(i int, section dashboardSection) {
+			defer wg.Done()
+			errs[i] = section.load()
+		}(i, section)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		log.Printf("Admin dashboard section %s failed: %v", sections[i].name, err)
+		dashboard.Errors = append(dashboard.Errors, &model.DashboardSectionError{
+			Section: sections[i].name,
+			Message: err.Error(),
+		})
+	}
+	return dashboard, nil
+}
+
+// cachedDashboardSection reads a section through the cache, computing it on a miss or while Redis is down
+func (r *Resolver) cachedDashboardSection(ctx context.Context, key string, dest interface{}, compute func() (interface{}, error)) error {
+	if r.CacheManager == nil {
+		value, err := compute()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, dest)
+	}
+	return r.CacheManager.GetOrSet(ctx, key, performance.DashboardCacheConfig, compute, dest)
+}
+
+// dashboardScopeKey names the faculty slice a cached section was computed for
+func dashboardScopeKey(facultyID *uint) string {
+	if facultyID == nil {
+		return "all"
+	}
+	return strconv.FormatUint(uint64(*facultyID), 10)
+}
+
+func (r *Resolver) dashboardSystemMetrics(ctx context.Context) (*models.SystemMetrics, error) {
+	var metrics *models.SystemMetrics
+	err := r.cachedDashboardSection(ctx, "system_metrics", &metrics, func() (interface{}, error) {
+		var latest models.SystemMetrics
+		err := r.DB.WithContext(ctx).Order("date DESC").First(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// No snapshot has been taken yet
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system metrics")
+		}
+		return &latest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metrics == nil {
+		return nil, nil
+	}
+	return convertSystemMetricsToGraphQL(metrics), nil
+}
+
+func (r *Resolver) dashboardFacultyMetrics(ctx context.Context, facultyID *uint) ([]*models.FacultyMetrics, error) {
+	var metrics []models.FacultyMetrics
+	err := r.cachedDashboardSection(ctx, "faculty_metrics:"+dashboardScopeKey(facultyID), &metrics, func() (interface{}, error) {
+		// Each faculty's most recent snapshot
+		query := r.DB.WithContext(ctx).Model(&models.FacultyMetrics{}).Preload("Faculty").
+			Where("date = (SELECT MAX(latest.date) FROM faculty_metrics latest WHERE latest.faculty_id = faculty_metrics.faculty_id)")
+		if facultyID != nil {
+			query = query.Where("faculty_id = ?", *facultyID)
+		}
+
+		var latest []models.FacultyMetrics
+		if err := query.Order("faculty_id ASC").Find(&latest).Error; err != nil {
+			return nil, fmt.Errorf("failed to load faculty metrics")
+		}
+		return latest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.FacultyMetrics, len(metrics))
+	for i := range metrics {
+		result[i] = convertFacultyMetricsToGraphQL(&metrics[i])
+	}
+	return result, nil
+}
+
+// dashboardSecurityEvents splits one read of the live feed into the alerts and the full event list
+func (r *Resolver) dashboardSecurityEvents(ctx context.Context, facultyID *uint, limit int) ([]*model.LiveSecurityEvent, []*model.LiveSecurityEvent, error) {
+	if r.AuditLogger == nil {
+		return nil, nil, fmt.Errorf("audit logging is not available")
+	}
+
+	events, err := r.AuditLogger.GetLiveSecurityEvents(ctx, audit.MaxLiveSecurityEvents)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch live security events")
+	}
+	if facultyID != nil {
+		events = r.filterSecurityEventsByFaculty(ctx, *facultyID, events)
+	}
+
+	alerts := []*model.LiveSecurityEvent{}
+	recent := []*model.LiveSecurityEvent{}
+	for _, event := range events {
+		if event.Alert && len(alerts) < limit {
+			alerts = append(alerts, convertLiveSecurityEvent(event))
+		}
+		if len(recent) < limit {
+			recent = append(recent, convertLiveSecurityEvent(event))
+		}
+	}
+	return alerts, recent, nil
+}
+
+// dashboardPendingParticipations returns the participations waiting longest for approval and how many wait in total
+func (r *Resolver) dashboardPendingParticipations(ctx context.Context, facultyID *uint, limit int) ([]*models.Participation, int, error) {
+	var pending struct {
+		Participations []models.Participation `json:"participations"`
+		Count          int64                  `json:"count"`
+	}
+	key := fmt.Sprintf("pending_participations:%s:%d", dashboardScopeKey(facultyID), limit)
+	err := r.cachedDashboardSection(ctx, key, &pending, func() (interface{}, error) {
+		query := r.DB.WithContext(ctx).Model(&models.Participation{}).
+			Joins("JOIN activities ON activities.id = participations.activity_id AND activities.deleted_at IS NULL").
+			Where("participations.status = ?", models.ParticipationStatusPending)
+		if facultyID != nil {
+			query = query.Where("activities.faculty_id = ?", *facultyID)
+		}
+
+		result := pending
+		if err := query.Session(&gorm.Session{}).Count(&result.Count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count pending participations")
+		}
+		if err := query.Preload("User").Preload("Activity").
+			Order("participations.registered_at ASC").
+			Limit(limit).
+			Find(&result.Participations).Error; err != nil {
+			return nil, fmt.Errorf("failed to load pending participations")
+		}
+
+		// The cached copy must not carry credentials
+		for i := range result.Participations {
+			result.Participations[i].User = *cacheableUser(&result.Participations[i].User)
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	participations := make([]*models.Participation, len(pending.Participations))
+	for i := range pending.Participations {
+		participations[i] = &pending.Participations[i]
+	}
+	return participations, int(pending.Count), nil
+}
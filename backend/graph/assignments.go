@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+// assignAdmin assigns a regular admin of the activity's faculty to the activity and notifies them.
+// canScanQR, canApprove and notes are optional; permissions default to granted.
+func (r *Resolver) assignAdmin(ctx context.Context, activityID, adminID string, canScanQR, canApprove *bool, notes *string) (*models.ActivityAssignment, error) {
+	actID, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+	aID, err := strconv.ParseUint(adminID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid admin ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.First(&activity, actID).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+
+	authCtx, err := r.requireActivityScope(ctx, &activity, activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var admin models.User
+	if err := r.DB.First(&admin, aID).Error; err != nil {
+		return nil, errcode.NotFound("admin not found")
+	}
+	if admin.Role != models.UserRoleRegularAdmin {
+		return nil, errcode.Validation("only regular admins can be assigned to activities")
+	}
+	if activity.FacultyID == nil || admin.FacultyID == nil || *admin.FacultyID != *activity.FacultyID {
+		return nil, errcode.Validation("admin must belong to the activity's faculty")
+	}
+
+	var existing int64
+	r.DB.Model(&models.ActivityAssignment{}).
+		Where("activity_id = ? AND admin_id = ?", activity.ID, admin.ID).
+		Count(&existing)
+	if existing > 0 {
+		return nil, errcode.Conflict("admin is already assigned to this activity")
+	}
+
+	assignment := models.ActivityAssignment{
+		ActivityID:   activity.ID,
+		AdminID:      admin.ID,
+		AssignedByID: authCtx.UserID,
+		CanScanQR:    true,
+		CanApprove:   true,
+	}
+	if canScanQR != nil {
+		assignment.CanScanQR = *canScanQR
+	}
+	if canApprove != nil {
+		assignment.CanApprove = *canApprove
+	}
+	if notes != nil {
+		assignment.Notes = *notes
+	}
+
+	// Select keeps explicit false permissions from falling back to the column defaults
+	if err := r.DB.Select("ActivityID", "AdminID", "AssignedByID", "CanScanQR", "CanApprove", "Notes", "CreatedAt", "UpdatedAt").
+		Create(&assignment).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign admin")
+	}
+
+	r.DB.Preload("Activity").Preload("Admin").Preload("AssignedBy").First(&assignment, assignment.ID)
+
+	r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceActivity, activityID, map[string]interface{}{
+		"assignment_id":  assignment.ID,
+		"admin_id":       assignment.AdminID,
+		"can_scan_qr":    assignment.CanScanQR,
+		"can_approve":    assignment.CanApprove,
+		"assigned_by_id": assignment.AssignedByID,
+	})
+
+	if r.EventPublisher != nil {
+		if err := r.EventPublisher.PublishActivityAssigned(&assignment, &services.EventContext{
+			UserID:     &authCtx.UserID,
+			FacultyID:  activity.FacultyID,
+			ActivityID: &activity.ID,
+			Source:     "assign_admin",
+		}); err != nil {
+			log.Printf("Failed to publish assignment of admin %d to activity %d: %v", admin.ID, activity.ID, err)
+		}
+	}
+
+	return &assignment, nil
+}
+
+// unassignAdmin removes an assignment after checking scope over its activity
+func (r *Resolver) unassignAdmin(ctx context.Context, assignment *models.ActivityAssignment) error {
+	var activity models.Activity
+	if err := r.DB.Unscoped().First(&activity, assignment.ActivityID).Error; err != nil {
+		return errcode.NotFound("activity not found")
+	}
+
+	activityID := strconv.FormatUint(uint64(activity.ID), 10)
+	if _, err := r.requireActivityScope(ctx, &activity, activityID); err != nil {
+		return err
+	}
+
+	if err := r.DB.Delete(assignment).Error; err != nil {
+		return fmt.Errorf("failed to remove assignment")
+	}
+
+	r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceActivity, activityID, map[string]interface{}{
+		"assignment_id": assignment.ID,
+		"admin_id":      assignment.AdminID,
+	})
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+// AttendanceCountInterval is the minimum gap between two attendance count updates
+const AttendanceCountInterval = time.Second
+
+// Event types, as published by PubSubService, that can change an activity's counts
+var attendanceCountEventTypes = []string{"participation_event", "qr_scan_event"}
+
+// canViewActivityAttendance allows the activity's managers and the admins assigned to it
+func (r *Resolver) canViewActivityAttendance(ctx context.Context, user *models.User, activity *models.Activity) bool {
+	if user.CanManageActivity(activity) {
+		return true
+	}
+
+	var assignments int64
+	r.DB.WithContext(ctx).Model(&models.ActivityAssignment{}).
+		Where("activity_id = ? AND admin_id = ?", activity.ID, user.ID).
+		Count(&assignments)
+	return assignments > 0
+}
+
+func (r *Resolver) activityAttendanceCount(ctx context.Context, activityID string) (<-chan *model.ActivityAttendanceCount, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Subscriptions == nil {
+		return nil, fmt.Errorf("realtime events are unavailable")
+	}
+
+	id, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, id).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+	if !r.canViewActivityAttendance(ctx, authCtx.User, &activity) {
+		return nil, errcode.Forbidden("access denied to activity")
+	}
+
+	initial, err := r.countActivityAttendance(ctx, activity.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance")
+	}
+
+	clientIP, _ := ctx.Value("client_ip").(string)
+	userAgent, _ := ctx.Value("user_agent").(string)
+	cm := r.Subscriptions.ConnectionManager
+	connection, err := cm.CreateConnection(authCtx.UserID, authCtx.User, map[string]interface{}{
+		"subscription_type": "activity_attendance_count",
+		"activity_id":       activityID,
+		"remote_addr":       clientIP,
+		"user_agent":        userAgent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection: %v", err)
+	}
+
+	filters := map[string]interface{}{"activity_id": activityID}
+	for _, eventType := range attendanceCountEventTypes {
+		if err := cm.Subscribe(connection.ID, eventType, filters); err != nil {
+			cm.CloseConnection(connection.ID)
+			return nil, fmt.Errorf("failed to subscribe: %v", err)
+		}
+	}
+
+	output := make(chan *model.ActivityAttendanceCount, 1)
+	output <- initial
+	go r.streamActivityAttendanceCount(ctx, connection, activity.ID, output)
+
+	return output, nil
+}
+
+// streamActivityAttendanceCount recounts after events for the activity, coalescing bursts so
+// that subscribers get at most one update per AttendanceCountInterval
+func (r *Resolver) streamActivityAttendanceCount(ctx context.Context, conn *services.Connection, activityID uint, output chan<- *model.ActivityAttendanceCount) {
+	defer close(output)
+	defer r.Subscriptions.ConnectionManager.CloseConnection(conn.ID)
+
+	// The initial count went out with the subscription
+	lastEmit := time.Now()
+	var (
+		pending *time.Timer
+		flush   <-chan time.Time
+	)
+	defer func() {
+		if pending != nil {
+			pending.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case msg, ok := <-conn.Channel:
+			if !ok {
+				return
+			}
+			if !isActivityAttendanceEvent(msg, activityID) || flush != nil {
+				continue
+			}
+			pending = time.NewTimer(time.Until(lastEmit.Add(AttendanceCountInterval)))
+			flush = pending.C
+		case <-flush:
+			flush = nil
+			counts, err := r.countActivityAttendance(ctx, activityID)
+			if err != nil {
+				log.Printf("Failed to count attendance for activity %d: %v", activityID, err)
+				continue
+			}
+			lastEmit = time.Now()
+			select {
+			case output <- counts:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-conn.Context.Done():
+			return
+		}
+	}
+}
+
+// isActivityAttendanceEvent matches events by the pub/sub channel they were published on,
+// which carries the activity ID
+func isActivityAttendanceEvent(msg *services.SubscriptionPayload, activityID uint) bool {
+	channel, _ := msg.Metadata["channel"].(string)
+	switch msg.Type {
+	case "qr_scan_event":
+		return channel == fmt.Sprintf(services.QRScanEventsChannel, activityID)
+	case "participation_event":
+		return strings.HasPrefix(channel, fmt.Sprintf("participation_events:%d:", activityID))
+	}
+	return false
+}
+
+func (r *Resolver) countActivityAttendance(ctx context.Context, activityID uint) (*model.ActivityAttendanceCount, error) {
+	var rows []struct {
+		Status models.ParticipationStatus
+		Count  int
+	}
+	if err := r.DB.WithContext(ctx).Model(&models.Participation{}).
+		Select("status, COUNT(*) AS count").
+		Where("activity_id = ?", activityID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := &model.ActivityAttendanceCount{
+		ActivityID: strconv.FormatUint(uint64(activityID), 10),
+		Timestamp:  time.Now(),
+	}
+	for _, row := range rows {
+		switch row.Status {
+		case models.ParticipationStatusAttended:
+			counts.Attended = row.Count
+		case models.ParticipationStatusApproved:
+			counts.Approved = row.Count
+		case models.ParticipationStatusRejected, models.ParticipationStatusWithdrawn:
+			continue
+		}
+		counts.Total += row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,214 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/redis/go-redis/v9"
+)
+
+// newAuditedResolver returns a resolver whose audit logger writes to the fake database
+func newAuditedResolver(t *testing.T, respond fakedb.Responder) (*Resolver, *fakedb.DB) {
+	t.Helper()
+	db, fake := fakedb.Open(t, respond)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Resolver{
+		DB:                  &database.DB{DB: db},
+		AuditLogger:         audit.NewAuditLogger(db, client),
+		EnforceFacultyScope: true,
+	}, fake
+}
+
+// insertArgs returns the arguments of every insert into table
+func insertArgs(respond fakedb.Responder, table string, inserts *[][]interface{}) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.HasPrefix(query, `INSERT INTO "`+table+`"`) {
+			values := make([]interface{}, len(args))
+			for i, arg := range args {
+				values[i] = arg.Value
+			}
+			*inserts = append(*inserts, values)
+			return fakedb.Result{RowsAffected: 1}
+		}
+		if respond == nil {
+			return fakedb.Result{}
+		}
+		return respond(query, args)
+	}
+}
+
+func containsValue(values []interface{}, want interface{}) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLogAdminActionAttributesTheAdmin(t *testing.T) {
+	var inserts [][]interface{}
+	r, _ := newAuditedResolver(t, insertArgs(nil, "audit_events", &inserts))
+
+	ctx := withAdmin(models.UserRoleFacultyAdmin, 3)
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivity, "5", map[string]interface{}{"title": "Open day"})
+
+	if len(inserts) != 1 {
+		t.Fatalf("stored %d audit events, want 1", len(inserts))
+	}
+	for _, want := range []interface{}{"1", string(models.UserRoleFacultyAdmin), "3", audit.ActionUpdate, audit.ResourceActivity, "5", audit.CategoryAdmin, true} {
+		if !containsValue(inserts[0], want) {
+			t.Errorf("audit event %v is missing %v", inserts[0], want)
+		}
+	}
+	if !containsValue(inserts[0], `{"title":"Open day"}`) {
+		t.Errorf("audit event %v does not store the details as JSON", inserts[0])
+	}
+
+	// Without an audit logger nothing is written
+	db, fake := fakedb.Open(t, nil)
+	(&Resolver{DB: &database.DB{DB: db}}).logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivity, "5", nil)
+	if len(fake.Statements()) != 0 {
+		t.Errorf("ran %q without an audit logger", fake.Statements())
+	}
+}
+
+func TestLogSecurityEventScopesToTheCallersFaculty(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		facultyID   string
+		wantFaculty string
+	}{
+		{"faculty admin", withAdmin(models.UserRoleFacultyAdmin, 3), "", "3"},
+		{"explicit faculty", withAdmin(models.UserRoleFacultyAdmin, 3), "4", "4"},
+		{"anonymous", context.Background(), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserts [][]interface{}
+			r, _ := newAuditedResolver(t, insertArgs(nil, "security_events", &inserts))
+
+			event := &audit.SecurityEvent{EventType: "cross_faculty_access", FacultyID: tt.facultyID, RiskLevel: audit.RiskLevelMedium}
+			r.logSecurityEvent(tt.ctx, event)
+
+			if len(inserts) != 1 {
+				t.Fatalf("stored %d security events, want 1", len(inserts))
+			}
+			if event.FacultyID != tt.wantFaculty {
+				t.Errorf("FacultyID = %q, want %q", event.FacultyID, tt.wantFaculty)
+			}
+		})
+	}
+}
+
+// auditTrail answers the activity lookup and serves stored events newest first, the way
+// the ORDER BY asks for them
+func auditTrail(activityFaculty int64, details ...string) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		switch {
+		case strings.Contains(query, `FROM "activities"`):
+			return fakedb.Result{
+				Columns: []string{"id", "faculty_id"},
+				Rows:    [][]driver.Value{{args[0].Value, activityFaculty}},
+			}
+		case strings.Contains(query, `count(*) FROM "audit_events"`):
+			return fakedb.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(details))}}}
+		case strings.Contains(query, `FROM "audit_events"`):
+			start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+			rows := [][]driver.Value{}
+			for i := len(details) - 1; i >= 0; i-- {
+				rows = append(rows, []driver.Value{
+					fmt.Sprintf("event-%d", i), "1", audit.ActionUpdate, details[i], start.Add(time.Duration(i) * time.Minute), true,
+				})
+			}
+			return fakedb.Result{Columns: []string{"id", "user_id", "action", "details", "timestamp", "success"}, Rows: rows}
+		}
+		return fakedb.Result{}
+	}
+}
+
+func TestResourceAuditTrailOrdering(t *testing.T) {
+	r, fake := newAuditedResolver(t, auditTrail(1, `{"title":"Draft"}`, `{"title":"Open day"}`, `{"title":"Open day","points":5}`))
+	q := &queryResolver{r}
+
+	limit := 2
+	page, err := q.ResourceAuditTrail(withAdmin(models.UserRoleFacultyAdmin, 1), model.AuditResourceActivity, "5", &limit, nil)
+	if err != nil {
+		t.Fatalf("ResourceAuditTrail: %v", err)
+	}
+	if len(fakedb.Containing(fake.Statements(), "ORDER BY timestamp DESC,id DESC")) != 1 {
+		t.Errorf("audit trail is not read newest first: %q", fake.Statements())
+	}
+	if page.TotalCount != 3 {
+		t.Errorf("TotalCount = %d, want 3", page.TotalCount)
+	}
+
+	// The oldest event only serves as the base of the last entry's diff
+	if len(page.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(page.Entries))
+	}
+	want := []struct {
+		id      string
+		changes string
+	}{
+		{"event-2", `[{"field":"points","newValue":"5"}]`},
+		{"event-1", `[{"field":"title","oldValue":"Draft","newValue":"Open day"}]`},
+	}
+	for i, entry := range page.Entries {
+		changes, _ := json.Marshal(entry.Changes)
+		if entry.ID != want[i].id || string(changes) != want[i].changes {
+			t.Errorf("entry %d = %s %s, want %s %s", i, entry.ID, changes, want[i].id, want[i].changes)
+		}
+	}
+}
+
+func TestResourceAuditTrailFacultyScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode string
+	}{
+		{"own faculty", withAdmin(models.UserRoleFacultyAdmin, 2), ""},
+		{"another faculty", withAdmin(models.UserRoleFacultyAdmin, 1), "FORBIDDEN"},
+		{"super admin", withAdmin(models.UserRoleSuperAdmin, 1), ""},
+		{"regular admin", withAdmin(models.UserRoleRegularAdmin, 2), "FORBIDDEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, fake := newAuditedResolver(t, auditTrail(2, `{"title":"Draft"}`))
+
+			_, err := (&queryResolver{r}).ResourceAuditTrail(tt.ctx, model.AuditResourceActivity, "5", nil, nil)
+			read := fakedb.Containing(fake.Statements(), `SELECT * FROM "audit_events"`)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ResourceAuditTrail: %v", err)
+				}
+				if len(read) != 1 {
+					t.Errorf("read the trail %d times, want once", len(read))
+				}
+				return
+			}
+			if code := errorCode(err); code != tt.wantCode {
+				t.Errorf("ResourceAuditTrail() error = %v (code %v), want %s", err, code, tt.wantCode)
+			}
+			if len(read) != 0 {
+				t.Errorf("read another faculty's audit trail: %q", read)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+func (r *Resolver) loadFaculty(ctx context.Context, id string) (*models.Faculty, error) {
+	facultyID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid faculty ID")
+	}
+
+	var faculty models.Faculty
+	if err := r.DB.WithContext(ctx).First(&faculty, facultyID).Error; err != nil {
+		return nil, errcode.NotFound("faculty not found")
+	}
+	return &faculty, nil
+}
+
+// loadDepartment loads a department the caller may manage: super admins any, faculty admins their own faculty's
+func (r *Resolver) loadDepartment(ctx context.Context, id string) (*models.Department, error) {
+	departmentID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid department ID")
+	}
+
+	var department models.Department
+	if err := r.DB.WithContext(ctx).Preload("Faculty").First(&department, departmentID).Error; err != nil {
+		return nil, errcode.NotFound("department not found")
+	}
+	if _, err := r.requireFacultyScope(ctx, &department.FacultyID, audit.ResourceDepartment, id); err != nil {
+		return nil, err
+	}
+	return &department, nil
+}
+
+// requireUniqueFacultyCode rejects a code another faculty uses. Deleted faculties still hold
+// their codes in the unique index, so they count too.
+func (r *Resolver) requireUniqueFacultyCode(ctx context.Context, code string, exceptID uint) error {
+	var count int64
+	if err := r.DB.WithContext(ctx).Unscoped().Model(&models.Faculty{}).
+		Where("code = ? AND id <> ?", strings.TrimSpace(code), exceptID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check faculty code")
+	}
+	if count > 0 {
+		return errcode.Conflict("faculty code %s is already in use", strings.TrimSpace(code))
+	}
+	return nil
+}
+
+// requireUniqueDepartmentCode rejects a code another department of the faculty uses
+func (r *Resolver) requireUniqueDepartmentCode(ctx context.Context, facultyID uint, code string, exceptID uint) error {
+	var count int64
+	if err := r.DB.WithContext(ctx).Model(&models.Department{}).
+		Where("faculty_id = ? AND code = ? AND id <> ?", facultyID, strings.TrimSpace(code), exceptID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check department code")
+	}
+	if count > 0 {
+		return errcode.Conflict("department code %s is already in use in this faculty", strings.TrimSpace(code))
+	}
+	return nil
+}
+
+// facultyChanged drops the cached faculty and tells the faculty's members about the change
+func (r *Resolver) facultyChanged(ctx context.Context, faculty *models.Faculty, updateType string) {
+	if r.CacheManager != nil {
+		if err := r.CacheManager.InvalidateFaculty(ctx, strconv.FormatUint(uint64(faculty.ID), 10)); err != nil {
+			log.Printf("Failed to invalidate cached faculty %d: %v", faculty.ID, err)
+		}
+	}
+	r.invalidateCachedResponses(ctx)
+
+	if r.EventPublisher != nil {
+		eventCtx := &services.EventContext{FacultyID: &faculty.ID, Source: "faculty_management"}
+		if authCtx, err := middleware.GetAuthContext(ctx); err == nil {
+			eventCtx.UserID = &authCtx.UserID
+		}
+		if err := r.EventPublisher.PublishFacultyUpdated(faculty, updateType, eventCtx); err != nil {
+			log.Printf("Failed to publish update of faculty %d: %v", faculty.ID, err)
+		}
+	}
+}
+
+// invalidateCachedResponses drops the cached GraphQL responses, in which faculties and
+// departments are nested too deep to track
+func (r *Resolver) invalidateCachedResponses(ctx context.Context) {
+	if r.CacheManager == nil {
+		return
+	}
+	if err := r.CacheManager.InvalidateByTag(ctx, middleware.CachedResponseTag); err != nil {
+		log.Printf("Failed to invalidate cached GraphQL responses: %v", err)
+	}
+}
+
+func facultyAuditDetails(faculty *models.Faculty) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        faculty.Name,
+		"code":        faculty.Code,
+		"description": faculty.Description,
+		"is_active":   faculty.IsActive,
+		"timezone":    faculty.Timezone,
+	}
+}
+
+func departmentAuditDetails(department *models.Department) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       department.Name,
+		"code":       department.Code,
+		"faculty_id": department.FacultyID,
+		"is_active":  department.IsActive,
+	}
+}
+
+func (r *Resolver) updateFaculty(ctx context.Context, id string, input model.UpdateFacultyInput) (*models.Faculty, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
+
+	faculty, err := r.loadFaculty(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Code != nil {
+		if err := r.requireUniqueFacultyCode(ctx, *input.Code, faculty.ID); err != nil {
+			return nil, err
+		}
+		updates["code"] = *input.Code
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.Timezone != nil {
+		if *input.Timezone != "" {
+			if _, err := models.LoadTimezone(*input.Timezone); err != nil {
+				return nil, timezoneError(ctx, "input.timezone", *input.Timezone)
+			}
+		}
+		updates["timezone"] = *input.Timezone
+	}
+	if len(updates) == 0 {
+		return convertFacultyToGraphQL(faculty), nil
+	}
+
+	if err := r.DB.WithContext(ctx).Model(faculty).Updates(updates).Error; err != nil {
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
+		return nil, fmt.Errorf("failed to update faculty")
+	}
+	if err := r.DB.WithContext(ctx).First(faculty, faculty.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load faculty")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceFaculty, id, facultyAuditDetails(faculty))
+	r.facultyChanged(ctx, faculty, "updated")
+
+	return convertFacultyToGraphQL(faculty), nil
+}
+
+// setFacultyActive activates or deactivates a faculty. Deactivation is refused while the
+// faculty has active activities, unless forced.
+func (r *Resolver) setFacultyActive(ctx context.Context, id string, active bool, force bool) (*models.Faculty, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
+
+	faculty, err := r.loadFaculty(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if faculty.IsActive == active {
+		return convertFacultyToGraphQL(faculty), nil
+	}
+
+	var activeActivities int64
+	if !active {
+		if err := r.DB.WithContext(ctx).Model(&models.Activity{}).
+			Where("faculty_id = ? AND status = ?", faculty.ID, models.ActivityStatusActive).
+			Count(&activeActivities).Error; err != nil {
+			return nil, fmt.Errorf("failed to check faculty activities")
+		}
+		if activeActivities > 0 && !force {
+			return nil, errcode.Conflict("faculty has %d active activities; deactivate with force to proceed", activeActivities)
+		}
+	}
+
+	if err := r.DB.WithContext(ctx).Model(faculty).Update("is_active", active).Error; err != nil {
+		return nil, fmt.Errorf("failed to update faculty")
+	}
+	faculty.IsActive = active
+
+	updateType := "activated"
+	if !active {
+		updateType = "deactivated"
+	}
+	details := facultyAuditDetails(faculty)
+	if !active {
+		details["forced"] = force
+		details["active_activities"] = activeActivities
+	}
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceFaculty, id, details)
+	r.facultyChanged(ctx, faculty, updateType)
+
+	return convertFacultyToGraphQL(faculty), nil
+}
+
+func (r *Resolver) createDepartment(ctx context.Context, input model.CreateDepartmentInput) (*models.Department, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	faculty, err := r.loadFaculty(ctx, input.FacultyID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.requireFacultyScope(ctx, &faculty.ID, audit.ResourceDepartment, ""); err != nil {
+		return nil, err
+	}
+	if !faculty.IsActive {
+		return nil, errcode.Validation("faculty is not active")
+	}
+	if err := r.requireUniqueDepartmentCode(ctx, faculty.ID, input.Code, 0); err != nil {
+		return nil, err
+	}
+
+	department := models.Department{
+		Name:      input.Name,
+		Code:      input.Code,
+		FacultyID: faculty.ID,
+		IsActive:  true,
+	}
+	if err := r.DB.WithContext(ctx).Create(&department).Error; err != nil {
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
+		return nil, fmt.Errorf("failed to create department")
+	}
+	department.Faculty = *faculty
+
+	r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceDepartment, strconv.FormatUint(uint64(department.ID), 10), departmentAuditDetails(&department))
+	r.facultyChanged(ctx, faculty, "updated")
+
+	return convertDepartmentToGraphQL(&department), nil
+}
+
+func (r *Resolver) updateDepartment(ctx context.Context, id string, input model.UpdateDepartmentInput) (*models.Department, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	department, err := r.loadDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Code != nil {
+		if err := r.requireUniqueDepartmentCode(ctx, department.FacultyID, *input.Code, department.ID); err != nil {
+			return nil, err
+		}
+		updates["code"] = *input.Code
+	}
+	if len(updates) == 0 {
+		return convertDepartmentToGraphQL(department), nil
+	}
+
+	if err := r.DB.WithContext(ctx).Model(department).Updates(updates).Error; err != nil {
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
+		return nil, fmt.Errorf("failed to update department")
+	}
+	if err := r.DB.WithContext(ctx).Preload("Faculty").First(department, department.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load department")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceDepartment, id, departmentAuditDetails(department))
+	r.facultyChanged(ctx, &department.Faculty, "updated")
+
+	return convertDepartmentToGraphQL(department), nil
+}
+
+func (r *Resolver) setDepartmentActive(ctx context.Context, id string, active bool) (*models.Department, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	department, err := r.loadDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if department.IsActive == active {
+		return convertDepartmentToGraphQL(department), nil
+	}
+
+	if err := r.DB.WithContext(ctx).Model(department).Update("is_active", active).Error; err != nil {
+		return nil, fmt.Errorf("failed to update department")
+	}
+	department.IsActive = active
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceDepartment, id, departmentAuditDetails(department))
+	r.facultyChanged(ctx, &department.Faculty, "updated")
+
+	return convertDepartmentToGraphQL(department), nil
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// twoFaculties answers lookups for users and departments split across faculties 1 and 2
+func twoFaculties(query string, args []driver.NamedValue) fakedb.Result {
+	if len(args) == 0 || !strings.HasPrefix(query, "SELECT") {
+		return fakedb.Result{RowsAffected: 1}
+	}
+	facultyOf := map[string]int64{"9": 1, "10": 2, "4": 1, "5": 2}
+	id := fmt.Sprint(args[0].Value)
+	faculty, ok := facultyOf[id]
+	if !ok {
+		return fakedb.Result{}
+	}
+	switch {
+	case strings.Contains(query, `FROM "users"`):
+		return fakedb.Result{
+			Columns: []string{"id", "role", "faculty_id", "is_active"},
+			Rows:    [][]driver.Value{{args[0].Value, "student", faculty, true}},
+		}
+	case strings.Contains(query, `FROM "departments"`):
+		return fakedb.Result{
+			Columns: []string{"id", "faculty_id", "is_active"},
+			Rows:    [][]driver.Value{{args[0].Value, faculty, true}},
+		}
+	}
+	return fakedb.Result{}
+}
+
+func withAdmin(role models.UserRole, facultyID uint) context.Context {
+	return context.WithValue(context.Background(), middleware.AuthContextKey, &middleware.AuthContext{
+		User:      &models.User{ID: 1, Role: role, FacultyID: &facultyID},
+		UserID:    1,
+		Role:      role,
+		FacultyID: &facultyID,
+	})
+}
+
+func errorCode(err error) interface{} {
+	if gqlErr, ok := err.(*gqlerror.Error); ok {
+		return gqlErr.Extensions["code"]
+	}
+	return nil
+}
+
+func TestAssignRegularAdminAcrossFaculties(t *testing.T) {
+	department := func(id string) *string { return &id }
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		userID       string
+		facultyID    string
+		departmentID *string
+		wantCode     string
+	}{
+		{"into another faculty", withAdmin(models.UserRoleFacultyAdmin, 1), "9", "2", nil, "FORBIDDEN"},
+		{"user from another faculty", withAdmin(models.UserRoleFacultyAdmin, 1), "10", "1", nil, "FORBIDDEN"},
+		{"department from another faculty", withAdmin(models.UserRoleFacultyAdmin, 1), "9", "1", department("5"), "FORBIDDEN"},
+		{"super admin with a mismatched department", withAdmin(models.UserRoleSuperAdmin, 0), "9", "1", department("5"), "VALIDATION"},
+		{"own faculty and department", withAdmin(models.UserRoleFacultyAdmin, 1), "9", "1", department("4"), ""},
+		{"super admin across faculties", withAdmin(models.UserRoleSuperAdmin, 0), "10", "1", department("4"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := fakedb.Open(t, twoFaculties)
+			r := &mutationResolver{&Resolver{DB: &database.DB{DB: db}, EnforceFacultyScope: true}}
+
+			_, err := r.AssignRegularAdmin(tt.ctx, tt.userID, tt.facultyID, tt.departmentID)
+			updates := fakedb.Containing(fake.Statements(), `UPDATE "users"`)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("AssignRegularAdmin: %v", err)
+				}
+				if len(updates) != 1 || !strings.Contains(updates[0], `"department_id"`) {
+					t.Errorf("updates = %q, want one setting the department", updates)
+				}
+				return
+			}
+			if code := errorCode(err); code != tt.wantCode {
+				t.Errorf("AssignRegularAdmin() error = %v (code %v), want %s", err, code, tt.wantCode)
+			}
+			if len(updates) != 0 {
+				t.Errorf("rejected assignment still ran %q", updates)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// featureEnabled reports whether flag is on for the faculty, placing the signed-in user in any
+// partial rollout
+func (r *Resolver) featureEnabled(ctx context.Context, facultyID *uint, flag string) bool {
+	if r.FeatureFlagService == nil {
+		return false
+	}
+	if authCtx, err := middleware.GetAuthContext(ctx); err == nil {
+		return r.FeatureFlagService.IsFeatureEnabledFor(ctx, facultyID, flag, authCtx.UserID)
+	}
+	return r.FeatureFlagService.IsFeatureEnabled(ctx, facultyID, flag)
+}
+
+// requireFeature fails with a readable error when flag is off for the faculty
+func (r *Resolver) requireFeature(ctx context.Context, facultyID *uint, flag string) error {
+	if !r.featureEnabled(ctx, facultyID, flag) {
+		return errcode.Forbidden("feature %q is not enabled for this faculty", flag)
+	}
+	return nil
+}
+
+// parseOptionalFacultyID parses an optional faculty ID argument
+func parseOptionalFacultyID(facultyID *string) (*uint, error) {
+	if facultyID == nil {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(*facultyID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid faculty ID")
+	}
+	parsed := uint(id)
+	return &parsed, nil
+}
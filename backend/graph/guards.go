@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// requireFacultyScope asserts that a faculty-scoped mutation targets the caller's own faculty.
+// Super admins bypass the check; violations are recorded as privilege escalation attempts.
+func (r *Resolver) requireFacultyScope(ctx context.Context, targetFacultyID *uint, resource, resourceID string) (*middleware.AuthContext, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if middleware.InFacultyScope(authCtx, targetFacultyID) {
+		return authCtx, nil
+	}
+
+	r.logPrivilegeEscalation(ctx, authCtx, targetFacultyID, resource, resourceID)
+
+	if !r.EnforceFacultyScope {
+		return authCtx, nil
+	}
+	return nil, errcode.Forbidden("Access denied: resource belongs to another faculty")
+}
+
+// requireActivityScope is requireFacultyScope for an activity: admins of a co-host faculty
+// may manage it as well as those of its own faculty
+func (r *Resolver) requireActivityScope(ctx context.Context, activity *models.Activity, resourceID string) (*middleware.AuthContext, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var coHostIDs []uint
+	if err := r.DB.WithContext(ctx).Table("activity_faculties").
+		Where("activity_id = ?", activity.ID).
+		Pluck("faculty_id", &coHostIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load co-host faculties")
+	}
+	for i := range coHostIDs {
+		if middleware.InFacultyScope(authCtx, &coHostIDs[i]) {
+			return authCtx, nil
+		}
+	}
+
+	return r.requireFacultyScope(ctx, activity.FacultyID, audit.ResourceActivity, resourceID)
+}
+
+func (r *Resolver) logPrivilegeEscalation(ctx context.Context, authCtx *middleware.AuthContext, targetFacultyID *uint, resource, resourceID string) {
+	details := map[string]interface{}{
+		"resource":    resource,
+		"resource_id": resourceID,
+		"user_role":   string(authCtx.Role),
+		"enforced":    r.EnforceFacultyScope,
+	}
+	if authCtx.FacultyID != nil {
+		details["user_faculty_id"] = *authCtx.FacultyID
+	}
+	if targetFacultyID != nil {
+		details["target_faculty_id"] = *targetFacultyID
+	}
+
+	log.Printf("Faculty scope violation by user %d on %s %s", authCtx.UserID, resource, resourceID)
+
+	r.logSecurityEvent(ctx, &audit.SecurityEvent{
+		EventType: audit.SecurityEventPrivilegeEscalation,
+		UserID:    strconv.FormatUint(uint64(authCtx.UserID), 10),
+		Details:   details,
+		RiskLevel: audit.RiskLevelHigh,
+		Blocked:   r.EnforceFacultyScope,
+	})
+}
+
+// logSecurityEvent records a security event when an audit logger is configured
+func (r *Resolver) logSecurityEvent(ctx context.Context, event *audit.SecurityEvent) {
+	if r.AuditLogger == nil {
+		return
+	}
+
+	// Ties the event to the caller's faculty for the faculty admins' live feed
+	if event.FacultyID == "" {
+		if authCtx, err := middleware.GetAuthContext(ctx); err == nil && authCtx.FacultyID != nil {
+			event.FacultyID = strconv.FormatUint(uint64(*authCtx.FacultyID), 10)
+		}
+	}
+
+	if err := r.AuditLogger.LogSecurityEvent(ctx, event); err != nil {
+		log.Printf("Failed to log %s security event: %v", event.EventType, err)
+	}
+}
+
+// logAdminAction records an audit event attributed to the current admin
+func (r *Resolver) logAdminAction(ctx context.Context, action, resource, resourceID string, details map[string]interface{}) {
+	if r.AuditLogger == nil {
+		return
+	}
+
+	event := &audit.AuditEvent{
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+		Success:    true,
+		Severity:   audit.SeverityInfo,
+		Category:   audit.CategoryAdmin,
+	}
+	if authCtx, err := middleware.GetAuthContext(ctx); err == nil {
+		event.UserID = strconv.FormatUint(uint64(authCtx.UserID), 10)
+		event.UserRole = string(authCtx.Role)
+		if authCtx.FacultyID != nil {
+			event.FacultyID = strconv.FormatUint(uint64(*authCtx.FacultyID), 10)
+		}
+	}
+
+	if err := r.AuditLogger.LogEvent(ctx, event); err != nil {
+		log.Printf("Failed to log %s %s audit event: %v", action, resource, err)
+	}
+}
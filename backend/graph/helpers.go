@@ -0,0 +1,367 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+// Helper converter functions
+
+func convertUserToGraphQL(user *models.User) *models.User {
+	return user
+}
+
+// deletedAtTime exposes a soft-delete timestamp, nil while the row is live
+func deletedAtTime(deletedAt gorm.DeletedAt) *time.Time {
+	if !deletedAt.Valid {
+		return nil
+	}
+	return &deletedAt.Time
+}
+
+func convertFacultyToGraphQL(faculty *models.Faculty) *models.Faculty {
+	return faculty
+}
+
+func convertDepartmentToGraphQL(department *models.Department) *models.Department {
+	return department
+}
+
+func convertActivityToGraphQL(activity *models.Activity) *models.Activity {
+	return activity
+}
+
+func convertParticipationToGraphQL(participation *models.Participation) *models.Participation {
+	return participation
+}
+
+func convertSubscriptionToGraphQL(subscription *models.Subscription) *model.FacultySubscription {
+	return &model.FacultySubscription{
+		ID:                strconv.FormatUint(uint64(subscription.ID), 10),
+		Faculty:           &subscription.Faculty,
+		Type:              subscription.Type,
+		Status:            subscription.Status,
+		StartDate:         subscription.StartDate,
+		EndDate:           subscription.EndDate,
+		DaysUntilExpiry:   subscription.DaysUntilExpiry(),
+		NeedsNotification: subscription.NeedsNotification(),
+		Version:           subscription.Version,
+		CreatedAt:         subscription.CreatedAt,
+		UpdatedAt:         subscription.UpdatedAt,
+	}
+}
+
+func convertSystemMetricsToGraphQL(metrics *models.SystemMetrics) *models.SystemMetrics {
+	return metrics
+}
+
+func convertFacultyMetricsToGraphQL(metrics *models.FacultyMetrics) *models.FacultyMetrics {
+	return metrics
+}
+
+func convertScanSessionToGraphQL(session *services.ScanSession) *model.ScanSession {
+	scanners := make([]*models.User, len(session.Scanners))
+	for i := range session.Scanners {
+		scanners[i] = convertUserToGraphQL(&session.Scanners[i])
+	}
+
+	return &model.ScanSession{
+		Activity:       convertActivityToGraphQL(&session.Activity),
+		ScansPerMinute: session.ScansPerMinute,
+		SuccessCount:   session.SuccessCount,
+		FailureCount:   session.FailureCount,
+		LastScanAt:     session.LastScanAt,
+		Scanners:       scanners,
+	}
+}
+
+func convertAuditEventToTrailEntry(event *audit.AuditEvent, actor *models.User, previousDetails map[string]interface{}) *model.AuditTrailEntry {
+	changes := []*model.AuditFieldChange{}
+	for _, change := range audit.DiffDetails(previousDetails, event.Details) {
+		changes = append(changes, &model.AuditFieldChange{
+			Field:    change.Field,
+			OldValue: formatAuditValue(change.OldValue),
+			NewValue: formatAuditValue(change.NewValue),
+		})
+	}
+
+	entry := &model.AuditTrailEntry{
+		ID:        event.ID,
+		Action:    event.Action,
+		Actor:     actor,
+		Timestamp: event.Timestamp,
+		Success:   event.Success,
+		Changes:   changes,
+	}
+	if event.UserRole != "" {
+		entry.ActorRole = &event.UserRole
+	}
+	return entry
+}
+
+func formatAuditValue(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+
+	var formatted string
+	switch v := value.(type) {
+	case string:
+		formatted = v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		formatted = string(data)
+	default:
+		formatted = fmt.Sprintf("%v", v)
+	}
+	return &formatted
+}
+
+// activityAuditDetails snapshots the editable fields of an activity for the audit trail
+func activityAuditDetails(activity *models.Activity) map[string]interface{} {
+	details := map[string]interface{}{
+		"title":             activity.Title,
+		"description":       activity.Description,
+		"type":              string(activity.Type),
+		"status":            string(activity.Status),
+		"start_date":        activity.StartDate,
+		"end_date":          activity.EndDate,
+		"location":          activity.Location,
+		"require_approval":  activity.RequireApproval,
+		"points":            activity.Points,
+		"qr_code_required":  activity.QRCodeRequired,
+		"auto_approve":      activity.AutoApprove,
+		"attendance_policy": string(activity.AttendancePolicy),
+		"timezone":          activity.Timezone,
+	}
+	if activity.MaxParticipants != nil {
+		details["max_participants"] = *activity.MaxParticipants
+	}
+	if activity.FacultyID != nil {
+		details["faculty_id"] = *activity.FacultyID
+	}
+	if activity.DepartmentID != nil {
+		details["department_id"] = *activity.DepartmentID
+	}
+	if activity.QRExpiryMinutes != nil {
+		details["qr_expiry_minutes"] = *activity.QRExpiryMinutes
+	}
+	return details
+}
+
+// parseQRExpiryMinutes validates a QR expiry override; 0 clears it
+func parseQRExpiryMinutes(minutes int) (*int, error) {
+	if minutes < 0 || time.Duration(minutes)*time.Minute > security.MaxQRValidity {
+		return nil, errcode.Validation("qr expiry must be between 0 and %d minutes", int(security.MaxQRValidity.Minutes()))
+	}
+	if minutes == 0 {
+		return nil, nil
+	}
+	return &minutes, nil
+}
+
+// parseAttendancePolicy maps the GraphQL enum value onto the stored policy
+func parseAttendancePolicy(policy models.AttendancePolicy) (models.AttendancePolicy, error) {
+	switch normalized := models.AttendancePolicy(strings.ToLower(string(policy))); normalized {
+	case models.AttendancePolicyFirstScan, models.AttendancePolicyLatestScan:
+		return normalized, nil
+	default:
+		return "", errcode.Validation("invalid attendance policy: %s", policy)
+	}
+}
+
+// filterActivityStatus applies the activities feed's status filter. The GraphQL enum value is
+// stored lower case; archived activities are left out unless asked for by status or by
+// includeArchived.
+func filterActivityStatus(query *gorm.DB, status *models.ActivityStatus, includeArchived *bool) *gorm.DB {
+	var wanted models.ActivityStatus
+	if status != nil {
+		wanted = models.ActivityStatus(strings.ToLower(string(*status)))
+		query = query.Where("status = ?", wanted)
+	}
+	if (includeArchived == nil || !*includeArchived) && wanted != models.ActivityStatusArchived {
+		query = query.Where("status <> ?", models.ActivityStatusArchived)
+	}
+	return query
+}
+
+// validateActivityDates applies the configured date rules, reporting the offending
+// input field in the error's "field" extension
+func (r *Resolver) validateActivityDates(ctx context.Context, start, end time.Time, checkStart bool) error {
+	err := r.ActivityDateRules.Validate(start, end, checkStart)
+
+	var dateErr *services.ActivityDateError
+	if !errors.As(err, &dateErr) {
+		return err
+	}
+	gqlErr := errcode.Validation("%s", dateErr.Message)
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["field"] = "input." + dateErr.Field
+	return gqlErr
+}
+
+// textFieldError turns a model text field failure into a validation error naming the input field;
+// it returns nil for any other error
+func textFieldError(ctx context.Context, err error) error {
+	var fieldErr *models.TextFieldError
+	if !errors.As(err, &fieldErr) {
+		return nil
+	}
+	gqlErr := errcode.Validation("%s", fieldErr.Message)
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["field"] = "input." + fieldErr.Field
+	return gqlErr
+}
+
+// errActivityVersionConflict aborts an activity update whose expected version no longer matches
+var errActivityVersionConflict = errors.New("activity version conflict")
+
+// updateVersioned applies updates to model and increments its version. With an expected
+// version the write only lands while the stored version still matches, so of two edits made
+// from the same read one wins and the other is reported as conflicted, as is a write that
+// found no row.
+func updateVersioned(db *gorm.DB, model interface{}, expectedVersion *int, updates map[string]interface{}) (conflicted bool, err error) {
+	updates["version"] = gorm.Expr("version + 1")
+
+	query := db.Model(model)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 0, nil
+}
+
+// versionConflictError reports that a record changed since the client read it, so the client can refetch and retry
+func versionConflictError(ctx context.Context, resource string, currentVersion int) error {
+	gqlErr := errcode.Conflict("%s was modified by someone else, please reload and try again", resource)
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["currentVersion"] = currentVersion
+	return gqlErr
+}
+
+// duplicateUserField names the user field whose unique constraint err violated: "email",
+// "studentID", or "" for any other error
+func duplicateUserField(err error) string {
+	constraint, ok := database.UniqueViolationConstraint(err)
+	switch {
+	case !ok:
+		return ""
+	case strings.Contains(constraint, "student_id"):
+		return "studentID"
+	case strings.Contains(constraint, "email"):
+		return "email"
+	}
+	return ""
+}
+
+// registeredUserField names the field of a student ID or email someone has registered
+// already, or returns "". Deleted users still hold theirs in the unique indexes, so they count too.
+func (r *Resolver) registeredUserField(ctx context.Context, studentID, email string) (string, error) {
+	var existing []models.User
+	if err := r.DB.WithContext(ctx).Unscoped().Select("student_id", "email").
+		Where("student_id = ? OR LOWER(email) = LOWER(?)", studentID, email).
+		Find(&existing).Error; err != nil {
+		return "", fmt.Errorf("failed to check existing users")
+	}
+	for _, user := range existing {
+		if user.StudentID == studentID {
+			return "studentID", nil
+		}
+	}
+	if len(existing) > 0 {
+		return "email", nil
+	}
+	return "", nil
+}
+
+// duplicateUserError reports that a student ID or email is already registered, naming the input field
+func duplicateUserError(ctx context.Context, field string) error {
+	var gqlErr *gqlerror.Error
+	if field == "studentID" {
+		gqlErr = errcode.Conflict("student ID is already registered")
+	} else {
+		gqlErr = errcode.Conflict("email is already registered")
+	}
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["field"] = "input." + field
+	return gqlErr
+}
+
+// validatePassword applies the password policy, reporting the failed rule and input field in the error extensions
+func (r *Resolver) validatePassword(ctx context.Context, password, field string) error {
+	err := utils.ValidatePassword(password, r.PasswordPolicy)
+
+	var policyErr *utils.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return err
+	}
+	gqlErr := errcode.Validation("%s", policyErr.Message)
+	gqlErr.Path = graphql.GetPath(ctx)
+	gqlErr.Extensions["rule"] = policyErr.Rule
+	gqlErr.Extensions["field"] = field
+	return gqlErr
+}
+
+// convertQRSecurityMetricsToGraphQL totals the daily buckets; failure reasons keep a fixed order for charting
+func convertQRSecurityMetricsToGraphQL(daily []security.QRDailySecurityMetrics) *model.QRSecurityMetrics {
+	result := &model.QRSecurityMetrics{
+		Daily: make([]*model.QRSecurityDailyMetrics, len(daily)),
+	}
+	reasonTotals := make(map[string]int64, len(security.QRFailureReasons))
+
+	for i, day := range daily {
+		bucket := &model.QRSecurityDailyMetrics{
+			Date:           day.Date,
+			TotalScans:     int(day.TotalScans),
+			SuccessScans:   int(day.SuccessScans),
+			FailureScans:   int(day.FailureScans),
+			FailureReasons: make([]*model.QRFailureReasonCount, len(security.QRFailureReasons)),
+		}
+		for j, reason := range security.QRFailureReasons {
+			bucket.FailureReasons[j] = &model.QRFailureReasonCount{Reason: reason, Count: int(day.FailureReasons[reason])}
+			reasonTotals[reason] += day.FailureReasons[reason]
+		}
+		result.Daily[i] = bucket
+
+		result.TotalScans += bucket.TotalScans
+		result.SuccessScans += bucket.SuccessScans
+		result.FailureScans += bucket.FailureScans
+	}
+
+	result.FailureReasons = make([]*model.QRFailureReasonCount, len(security.QRFailureReasons))
+	for i, reason := range security.QRFailureReasons {
+		result.FailureReasons[i] = &model.QRFailureReasonCount{Reason: reason, Count: int(reasonTotals[reason])}
+	}
+
+	return result
+}
+
+// recordLastLogin stores the login time on the user. UpdateColumn binds a real timestamp and
+// leaves updated_at alone, as logging in doesn't change the user.
+func recordLastLogin(db *gorm.DB, user *models.User, now time.Time) error {
+	if err := db.Model(user).UpdateColumn("last_login_at", now).Error; err != nil {
+		return err
+	}
+	user.LastLoginAt = &now
+	return nil
+}
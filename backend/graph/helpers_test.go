@@ -0,0 +1,225 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type capturedStatement struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// newDryRunDB returns a Postgres session that builds statements without a database, recording
+// each one it would have run
+func newDryRunDB(t *testing.T) (*gorm.DB, *[]capturedStatement) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("opening dry run database: %v", err)
+	}
+
+	statements := &[]capturedStatement{}
+	capture := func(tx *gorm.DB) {
+		*statements = append(*statements, capturedStatement{SQL: tx.Statement.SQL.String(), Vars: tx.Statement.Vars})
+	}
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:create").Register("test:capture", capture)
+	callbacks.Query().After("gorm:query").Register("test:capture", capture)
+	callbacks.Update().After("gorm:update").Register("test:capture", capture)
+	callbacks.Delete().After("gorm:delete").Register("test:capture", capture)
+	return db, statements
+}
+
+func TestRecordLastLogin(t *testing.T) {
+	db, statements := newDryRunDB(t)
+	user := &models.User{ID: 7}
+	now := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+
+	if err := recordLastLogin(db, user, now); err != nil {
+		t.Fatalf("recordLastLogin: %v", err)
+	}
+	if user.LastLoginAt == nil || !user.LastLoginAt.Equal(now) {
+		t.Errorf("LastLoginAt = %v, want %v", user.LastLoginAt, now)
+	}
+
+	if len(*statements) != 1 {
+		t.Fatalf("ran %d statements, want 1", len(*statements))
+	}
+	stmt := (*statements)[0]
+	want := `UPDATE "users" SET "last_login_at"=$1 WHERE "users"."deleted_at" IS NULL AND "id" = $2`
+	if stmt.SQL != want {
+		t.Errorf("SQL = %s\nwant  %s", stmt.SQL, want)
+	}
+	// A timestamp, not the string "NOW()", and updated_at is left alone
+	if len(stmt.Vars) != 2 || stmt.Vars[0] != now {
+		t.Errorf("vars = %v, want [%v 7]", stmt.Vars, now)
+	}
+}
+
+// versionedRow answers updates of one row the way Postgres would, matching any version
+// condition against the stored version and incrementing it on every write
+func versionedRow(stored int) (fakedb.Responder, func() int) {
+	var mu sync.Mutex
+	condition := regexp.MustCompile(`version = \$(\d+)`)
+	respond := func(query string, args []driver.NamedValue) fakedb.Result {
+		if !strings.HasPrefix(query, "UPDATE") {
+			return fakedb.Result{}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if match := condition.FindStringSubmatch(query); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			if expected, _ := args[n-1].Value.(int64); int(expected) != stored {
+				return fakedb.Result{RowsAffected: 0}
+			}
+		}
+		stored++
+		return fakedb.Result{RowsAffected: 1}
+	}
+	current := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return stored
+	}
+	return respond, current
+}
+
+func TestUpdateVersionedConcurrentEdits(t *testing.T) {
+	respond, current := versionedRow(3)
+	db, fake := fakedb.Open(t, respond)
+
+	// Two admins save edits made from the same read of version 3
+	const editors = 2
+	expected := 3
+	conflicts := make([]bool, editors)
+	errs := make([]error, editors)
+	var wg sync.WaitGroup
+	for i := 0; i < editors; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			updates := map[string]interface{}{"title": "Edit " + strconv.Itoa(i)}
+			conflicts[i], errs[i] = updateVersioned(db, &models.Activity{ID: 1}, &expected, updates)
+		}(i)
+	}
+	wg.Wait()
+
+	conflicted := 0
+	for i := range conflicts {
+		if errs[i] != nil {
+			t.Fatalf("updateVersioned: %v", errs[i])
+		}
+		if conflicts[i] {
+			conflicted++
+		}
+	}
+	if conflicted != 1 {
+		t.Errorf("%d of %d edits conflicted, want 1", conflicted, editors)
+	}
+	if got := current(); got != 4 {
+		t.Errorf("stored version = %d, want 4", got)
+	}
+
+	updates := fakedb.Containing(fake.Statements(), `UPDATE "activities"`, `"version"=version + 1`, "version = $")
+	if len(updates) != editors {
+		t.Errorf("got %d versioned updates, want %d: %q", len(updates), editors, fake.Statements())
+	}
+
+	// The losing editor reloads version 4 and saves again
+	reloaded := 4
+	conflict, err := updateVersioned(db, &models.Activity{ID: 1}, &reloaded, map[string]interface{}{"title": "Retried"})
+	if err != nil || conflict {
+		t.Errorf("retry from version 4 = %v, %v, want it to land", conflict, err)
+	}
+}
+
+func TestUpdateVersionedWithoutExpectedVersion(t *testing.T) {
+	respond, current := versionedRow(3)
+	db, fake := fakedb.Open(t, respond)
+
+	conflict, err := updateVersioned(db, &models.Subscription{ID: 1}, nil, map[string]interface{}{"status": "active"})
+	if err != nil || conflict {
+		t.Fatalf("updateVersioned() = %v, %v, want it to land", conflict, err)
+	}
+	if got := current(); got != 4 {
+		t.Errorf("stored version = %d, want 4", got)
+	}
+	if len(fakedb.Containing(fake.Statements(), "version = $")) != 0 {
+		t.Errorf("unexpected version condition: %q", fake.Statements())
+	}
+}
+
+func TestTextFieldError(t *testing.T) {
+	db, _ := newDryRunDB(t)
+	err := db.Create(&models.Activity{Title: " "}).Error
+
+	gqlErr, ok := textFieldError(context.Background(), fmt.Errorf("creating activity: %w", err)).(*gqlerror.Error)
+	if !ok {
+		t.Fatalf("textFieldError(%v) did not return a GraphQL error", err)
+	}
+	if gqlErr.Extensions["code"] != "VALIDATION" || gqlErr.Extensions["field"] != "input.title" {
+		t.Errorf("extensions = %v, want the VALIDATION code on input.title", gqlErr.Extensions)
+	}
+
+	if err := textFieldError(context.Background(), errors.New("connection refused")); err != nil {
+		t.Errorf("textFieldError() = %v for a database error, want nil", err)
+	}
+}
+
+func TestFilterActivityStatus(t *testing.T) {
+	archived, active := models.ActivityStatus("ARCHIVED"), models.ActivityStatus("ACTIVE")
+	yes := true
+
+	tests := []struct {
+		name            string
+		status          *models.ActivityStatus
+		includeArchived *bool
+		wantWhere       string
+		wantVars        []interface{}
+	}{
+		{"default feed", nil, nil, "WHERE status <> $1", []interface{}{models.ActivityStatusArchived}},
+		{"including archived", nil, &yes, "", nil},
+		// GraphQL enum values are upper case, stored statuses lower case
+		{"archived status", &archived, nil, "WHERE status = $1", []interface{}{models.ActivityStatusArchived}},
+		{"other status", &active, nil, "WHERE status = $1 AND status <> $2", []interface{}{models.ActivityStatusActive, models.ActivityStatusArchived}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, statements := newDryRunDB(t)
+			var activities []models.Activity
+			filterActivityStatus(db.Unscoped(), tt.status, tt.includeArchived).Find(&activities)
+
+			stmt := (*statements)[0]
+			if where := strings.TrimSpace(strings.TrimPrefix(stmt.SQL, `SELECT * FROM "activities"`)); where != tt.wantWhere {
+				t.Errorf("filter = %q, want %q", where, tt.wantWhere)
+			}
+			if len(stmt.Vars) != len(tt.wantVars) {
+				t.Fatalf("vars = %v, want %v", stmt.Vars, tt.wantVars)
+			}
+			for i, v := range tt.wantVars {
+				if stmt.Vars[i] != v {
+					t.Errorf("vars = %v, want %v", stmt.Vars, tt.wantVars)
+				}
+			}
+		})
+	}
+}
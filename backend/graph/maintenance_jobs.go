@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+// startMaintenanceJob authorizes a super admin, starts a job and audits it with the job's ID
+func (r *Resolver) startMaintenanceJob(ctx context.Context, action string, details map[string]interface{}, start func(userID uint) (*services.MaintenanceJob, error)) (*model.MaintenanceJob, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaintenanceJobs == nil {
+		return nil, fmt.Errorf("maintenance jobs are unavailable")
+	}
+
+	job, err := start(authCtx.UserID)
+	if errors.Is(err, services.ErrMaintenanceJobRunning) {
+		return nil, errcode.Conflict("this job is already running")
+	}
+	if err != nil {
+		log.Printf("Failed to start %s job: %v", action, err)
+		return nil, fmt.Errorf("failed to start the job")
+	}
+
+	r.logAdminAction(ctx, action, audit.ResourceMaintenanceJob, job.ID, details)
+	return convertMaintenanceJob(job), nil
+}
+
+func (r *Resolver) rebuildSearchIndex(ctx context.Context) (*model.MaintenanceJob, error) {
+	return r.startMaintenanceJob(ctx, audit.ActionRebuildSearchIndex, nil, func(userID uint) (*services.MaintenanceJob, error) {
+		return r.MaintenanceJobs.StartSearchIndexRebuild(ctx, userID)
+	})
+}
+
+func (r *Resolver) recomputeMetrics(ctx context.Context, date time.Time) (*model.MaintenanceJob, error) {
+	if date.After(time.Now()) {
+		return nil, errcode.Validation("metrics can't be recomputed for a future date")
+	}
+	details := map[string]interface{}{"date": date.Format("2006-01-02")}
+	return r.startMaintenanceJob(ctx, audit.ActionRecomputeMetrics, details, func(userID uint) (*services.MaintenanceJob, error) {
+		return r.MaintenanceJobs.StartMetricsRecompute(ctx, date, userID)
+	})
+}
+
+func (r *Resolver) maintenanceJob(ctx context.Context, id string) (*model.MaintenanceJob, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
+	if r.MaintenanceJobs == nil {
+		return nil, fmt.Errorf("maintenance jobs are unavailable")
+	}
+
+	job, err := r.MaintenanceJobs.Get(ctx, id)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	return convertMaintenanceJob(job), nil
+}
+
+func convertMaintenanceJob(job *services.MaintenanceJob) *model.MaintenanceJob {
+	converted := &model.MaintenanceJob{
+		ID:          job.ID,
+		Type:        model.MaintenanceJobType(strings.ToUpper(string(job.Type))),
+		Status:      model.MaintenanceJobStatus(strings.ToUpper(string(job.Status))),
+		Completed:   job.Completed,
+		Total:       job.Total,
+		Date:        job.Date,
+		StartedByID: strconv.FormatUint(uint64(job.StartedByID), 10),
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+	if job.Error != "" {
+		converted.Error = &job.Error
+	}
+	return converted
+}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxManualAttendanceNoteLen = 500
+
+var (
+	errActivityFull          = errors.New("activity is full")
+	errParticipationRejected = errors.New("participation was rejected")
+)
+
+// markAttendanceManual records a student's attendance without a QR scan. A student who hasn't
+// registered is registered on the spot while the activity has room. Attendance already recorded,
+// by a scan or by hand, is left as it is.
+func (r *Resolver) markAttendanceManual(ctx context.Context, activityID, userID, note string) (*models.Participation, error) {
+	if err := requireScannerCredentials(ctx); err != nil {
+		return nil, err
+	}
+
+	note = strings.TrimSpace(note)
+	if note == "" || len(note) > maxManualAttendanceNoteLen {
+		return nil, errcode.Validation("note must be between 1 and %d characters", maxManualAttendanceNoteLen)
+	}
+	actID, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, actID).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+	scanner, err := r.authorizeScanner(ctx, &activity)
+	if err != nil {
+		return nil, err
+	}
+
+	var student models.User
+	if err := r.DB.WithContext(ctx).First(&student, uID).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+
+	var participation models.Participation
+	err = r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+		// Lock the activity so concurrent registrations can't take its last places twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Activity{}, activity.ID).Error; err != nil {
+			return err
+		}
+
+		participation = models.Participation{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND activity_id = ?", student.ID, activity.ID).
+			First(&participation).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		switch participation.Status {
+		case models.ParticipationStatusAttended:
+			return nil
+		case models.ParticipationStatusRejected:
+			return errParticipationRejected
+		case "", models.ParticipationStatusWithdrawn:
+			// Taking a place the student doesn't hold
+			if err := r.requireActivityPlace(tx, &activity); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if participation.ID == 0 {
+			participation = models.Participation{
+				UserID:       student.ID,
+				ActivityID:   activity.ID,
+				Status:       models.ParticipationStatusAttended,
+				RegisteredAt: now,
+				ApprovedAt:   &now,
+				AttendedAt:   &now,
+				ScannedByID:  &scanner.UserID,
+			}
+			if err := tx.Create(&participation).Error; err != nil {
+				return err
+			}
+		} else {
+			updates := map[string]interface{}{
+				"status":        models.ParticipationStatusAttended,
+				"attended_at":   now,
+				"scanned_by_id": scanner.UserID,
+			}
+			if participation.ApprovedAt == nil || participation.Status == models.ParticipationStatusWithdrawn {
+				updates["approved_at"] = now
+			}
+			if err := tx.Model(&participation).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		after.Do(func() {
+			details := map[string]interface{}{
+				"activity_id": activity.ID,
+				"user_id":     student.ID,
+				"student_id":  student.StudentID,
+				"operator_id": scanner.UserID,
+				"note":        note,
+			}
+			if scanner.TokenID != nil {
+				details["scanner_token_id"] = *scanner.TokenID
+			}
+			r.logAdminAction(ctx, audit.ActionMarkAttendanceManual, audit.ResourceParticipation, strconv.FormatUint(uint64(participation.ID), 10), details)
+			r.publishManualAttendance(&activity, &participation)
+		})
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errActivityFull):
+		return nil, errcode.Conflict("activity is full")
+	case errors.Is(err, errParticipationRejected):
+		return nil, errcode.Conflict("the student's participation was rejected")
+	case errors.Is(err, database.ErrRetriesExhausted):
+		return nil, err
+	default:
+		log.Printf("Failed to mark attendance of user %d for activity %d: %v", student.ID, activity.ID, err)
+		return nil, fmt.Errorf("failed to mark attendance")
+	}
+
+	r.DB.WithContext(ctx).Preload("User").Preload("Activity").First(&participation, participation.ID)
+	return convertParticipationToGraphQL(&participation), nil
+}
+
+// requireActivityPlace returns errActivityFull when the activity has no place left for another
+// participant; the activity row must be locked in tx
+func (r *Resolver) requireActivityPlace(tx *gorm.DB, activity *models.Activity) error {
+	if activity.MaxParticipants == nil {
+		return nil
+	}
+
+	var taken int64
+	if err := tx.Model(&models.Participation{}).
+		Where("activity_id = ? AND status IN ?", activity.ID, []models.ParticipationStatus{
+			models.ParticipationStatusPending,
+			models.ParticipationStatusApproved,
+			models.ParticipationStatusAttended,
+		}).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken >= int64(*activity.MaxParticipants) {
+		return errActivityFull
+	}
+	return nil
+}
+
+func (r *Resolver) publishManualAttendance(activity *models.Activity, participation *models.Participation) {
+	if r.EventPublisher == nil {
+		return
+	}
+	published := *participation
+	published.Activity = *activity
+	if err := r.EventPublisher.PublishParticipationUpdated(&published, "attended", &services.EventContext{
+		UserID:     &participation.UserID,
+		FacultyID:  activity.FacultyID,
+		ActivityID: &activity.ID,
+		Source:     "manual_attendance",
+	}); err != nil {
+		log.Printf("Failed to publish manual attendance of user %d for activity %d: %v", participation.UserID, activity.ID, err)
+	}
+}
@@ -3,6 +3,10 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
@@ -12,11 +16,91 @@ type SubscriptionData interface {
 	IsSubscriptionData()
 }
 
+type ActivityAttendanceCount struct {
+	ActivityID string    `json:"activityID"`
+	Attended   int       `json:"attended"`
+	Approved   int       `json:"approved"`
+	Total      int       `json:"total"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type ActivityQRBatch struct {
+	Codes     []*StudentQRCode    `json:"codes"`
+	Failures  []*StudentQRFailure `json:"failures"`
+	ExpiresAt time.Time           `json:"expiresAt"`
+}
+
+type ActivitySearchPage struct {
+	Activities []*models.Activity `json:"activities"`
+	TotalCount int                `json:"totalCount"`
+}
+
+type AdminDashboard struct {
+	SystemMetrics             *models.SystemMetrics    `json:"systemMetrics,omitempty"`
+	FacultyMetrics            []*models.FacultyMetrics `json:"facultyMetrics,omitempty"`
+	RecentAlerts              []*LiveSecurityEvent     `json:"recentAlerts,omitempty"`
+	SecurityEvents            []*LiveSecurityEvent     `json:"securityEvents,omitempty"`
+	ConnectionStats           *ConnectionStats         `json:"connectionStats,omitempty"`
+	SlowQueries               []*SlowQuery             `json:"slowQueries,omitempty"`
+	PendingParticipations     []*models.Participation  `json:"pendingParticipations,omitempty"`
+	PendingParticipationCount *int                     `json:"pendingParticipationCount,omitempty"`
+	Errors                    []*DashboardSectionError `json:"errors"`
+	GeneratedAt               time.Time                `json:"generatedAt"`
+}
+
+type AuditFieldChange struct {
+	Field    string  `json:"field"`
+	OldValue *string `json:"oldValue,omitempty"`
+	NewValue *string `json:"newValue,omitempty"`
+}
+
+type AuditTrailEntry struct {
+	ID        string              `json:"id"`
+	Action    string              `json:"action"`
+	Actor     *models.User        `json:"actor,omitempty"`
+	ActorRole *string             `json:"actorRole,omitempty"`
+	Timestamp time.Time           `json:"timestamp"`
+	Success   bool                `json:"success"`
+	Changes   []*AuditFieldChange `json:"changes"`
+}
+
+type AuditTrailPage struct {
+	Entries    []*AuditTrailEntry `json:"entries"`
+	TotalCount int                `json:"totalCount"`
+}
+
 type AuthPayload struct {
 	Token string       `json:"token"`
 	User  *models.User `json:"user"`
 }
 
+type ClusterConnectionStats struct {
+	Instances          int                       `json:"instances"`
+	TotalConnections   int                       `json:"totalConnections"`
+	FacultyConnections []*FacultyConnectionCount `json:"facultyConnections"`
+}
+
+type ConnectionBufferStats struct {
+	Size            int               `json:"size"`
+	HighWatermark   int               `json:"highWatermark"`
+	Drops           int               `json:"drops"`
+	SlowConnections []*SlowConnection `json:"slowConnections"`
+}
+
+type ConnectionStats struct {
+	InstanceID          string                    `json:"instanceID"`
+	TotalConnections    int                       `json:"totalConnections"`
+	FacultyConnections  []*FacultyConnectionCount `json:"facultyConnections"`
+	ActiveSubscriptions []*SubscriptionTypeCount  `json:"activeSubscriptions"`
+	IdleConnections     int                       `json:"idleConnections"`
+	IdleTimeoutSeconds  int                       `json:"idleTimeoutSeconds"`
+	Draining            bool                      `json:"draining"`
+	UptimeSeconds       int                       `json:"uptimeSeconds"`
+	Timestamp           time.Time                 `json:"timestamp"`
+	Cluster             *ClusterConnectionStats   `json:"cluster,omitempty"`
+	Buffer              *ConnectionBufferStats    `json:"buffer"`
+}
+
 type CreateActivityAssignmentInput struct {
 	ActivityID string  `json:"activityID"`
 	AdminID    string  `json:"adminID"`
@@ -26,22 +110,25 @@ type CreateActivityAssignmentInput struct {
 }
 
 type CreateActivityInput struct {
-	Title           string              `json:"title"`
-	Description     *string             `json:"description,omitempty"`
-	Type            models.ActivityType `json:"type"`
-	StartDate       time.Time           `json:"startDate"`
-	EndDate         time.Time           `json:"endDate"`
-	Location        *string             `json:"location,omitempty"`
-	MaxParticipants *int                `json:"maxParticipants,omitempty"`
-	RequireApproval bool                `json:"requireApproval"`
-	Points          int                 `json:"points"`
-	FacultyID       *string             `json:"facultyID,omitempty"`
-	DepartmentID    *string             `json:"departmentID,omitempty"`
-	TemplateID      *string             `json:"templateID,omitempty"`
-	IsRecurring     *bool               `json:"isRecurring,omitempty"`
-	RecurrenceRule  *string             `json:"recurrenceRule,omitempty"`
-	QRCodeRequired  *bool               `json:"qrCodeRequired,omitempty"`
-	AutoApprove     *bool               `json:"autoApprove,omitempty"`
+	Title            string                   `json:"title"`
+	Description      *string                  `json:"description,omitempty"`
+	Type             models.ActivityType      `json:"type"`
+	StartDate        time.Time                `json:"startDate"`
+	EndDate          time.Time                `json:"endDate"`
+	Timezone         *string                  `json:"timezone,omitempty"`
+	Location         *string                  `json:"location,omitempty"`
+	MaxParticipants  *int                     `json:"maxParticipants,omitempty"`
+	RequireApproval  bool                     `json:"requireApproval"`
+	Points           int                      `json:"points"`
+	FacultyID        *string                  `json:"facultyID,omitempty"`
+	DepartmentID     *string                  `json:"departmentID,omitempty"`
+	TemplateID       *string                  `json:"templateID,omitempty"`
+	IsRecurring      *bool                    `json:"isRecurring,omitempty"`
+	RecurrenceRule   *string                  `json:"recurrenceRule,omitempty"`
+	QRCodeRequired   *bool                    `json:"qrCodeRequired,omitempty"`
+	AutoApprove      *bool                    `json:"autoApprove,omitempty"`
+	AttendancePolicy *models.AttendancePolicy `json:"attendancePolicy,omitempty"`
+	QRExpiryMinutes  *int                     `json:"qrExpiryMinutes,omitempty"`
 }
 
 type CreateActivityTemplateInput struct {
@@ -68,6 +155,7 @@ type CreateFacultyInput struct {
 	Name        string  `json:"name"`
 	Code        string  `json:"code"`
 	Description *string `json:"description,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
 }
 
 type CreateSubscriptionInput struct {
@@ -77,6 +165,26 @@ type CreateSubscriptionInput struct {
 	EndDate   time.Time               `json:"endDate"`
 }
 
+type DashboardSectionError struct {
+	Section string `json:"section"`
+	Message string `json:"message"`
+}
+
+type DateRangeInput struct {
+	FromDate *time.Time `json:"fromDate,omitempty"`
+	ToDate   *time.Time `json:"toDate,omitempty"`
+}
+
+type FacultyComparisonEntry struct {
+	Faculty *models.Faculty `json:"faculty"`
+	Value   float64         `json:"value"`
+}
+
+type FacultyConnectionCount struct {
+	FacultyID   string `json:"facultyID"`
+	Connections int    `json:"connections"`
+}
+
 type FacultySubscription struct {
 	ID                string                    `json:"id"`
 	Faculty           *models.Faculty           `json:"faculty"`
@@ -86,20 +194,83 @@ type FacultySubscription struct {
 	EndDate           time.Time                 `json:"endDate"`
 	DaysUntilExpiry   int                       `json:"daysUntilExpiry"`
 	NeedsNotification bool                      `json:"needsNotification"`
+	Version           int                       `json:"version"`
 	CreatedAt         time.Time                 `json:"createdAt"`
 	UpdatedAt         time.Time                 `json:"updatedAt"`
 }
 
 func (FacultySubscription) IsSubscriptionData() {}
 
+type LiveSecurityEvent struct {
+	ID        string    `json:"id"`
+	EventType string    `json:"eventType"`
+	RiskLevel string    `json:"riskLevel"`
+	UserID    *string   `json:"userID,omitempty"`
+	FacultyID *string   `json:"facultyID,omitempty"`
+	IPAddress *string   `json:"ipAddress,omitempty"`
+	Blocked   bool      `json:"blocked"`
+	Alert     bool      `json:"alert"`
+	Details   *string   `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type MaintenanceJob struct {
+	ID          string               `json:"id"`
+	Type        MaintenanceJobType   `json:"type"`
+	Status      MaintenanceJobStatus `json:"status"`
+	Completed   int                  `json:"completed"`
+	Total       int                  `json:"total"`
+	Date        *time.Time           `json:"date,omitempty"`
+	Error       *string              `json:"error,omitempty"`
+	StartedByID string               `json:"startedByID"`
+	StartedAt   time.Time            `json:"startedAt"`
+	FinishedAt  *time.Time           `json:"finishedAt,omitempty"`
+}
+
 type Mutation struct {
 }
 
+type MyQRCode struct {
+	QRString  string    `json:"qrString"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type OfflineScanInput struct {
+	ID           string    `json:"id"`
+	QRData       string    `json:"qrData"`
+	ScannedAt    time.Time `json:"scannedAt"`
+	ScanLocation *string   `json:"scanLocation,omitempty"`
+}
+
+type OfflineScanReconciliation struct {
+	ID            string                `json:"id"`
+	Status        OfflineScanStatus     `json:"status"`
+	Message       string                `json:"message"`
+	Duplicate     bool                  `json:"duplicate"`
+	Participation *models.Participation `json:"participation,omitempty"`
+	ScanLog       *models.QRScanLog     `json:"scanLog,omitempty"`
+}
+
+type OperationCostBucket struct {
+	Bucket         string  `json:"bucket"`
+	MinComplexity  int     `json:"minComplexity"`
+	MaxComplexity  *int    `json:"maxComplexity,omitempty"`
+	Operations     int     `json:"operations"`
+	SlowOperations int     `json:"slowOperations"`
+	AvgDurationMs  float64 `json:"avgDurationMs"`
+}
+
+type ParticipationHistory struct {
+	Participations []*models.Participation `json:"participations"`
+	TotalPoints    int                     `json:"totalPoints"`
+	AttendedCount  int                     `json:"attendedCount"`
+}
+
 type QRData struct {
 	StudentID string `json:"studentID"`
 	Timestamp string `json:"timestamp"`
@@ -108,6 +279,17 @@ type QRData struct {
 	QRString  string `json:"qrString"`
 }
 
+type QRFailureReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+type QRRevocation struct {
+	Signature         string    `json:"signature"`
+	RevokedUntil      time.Time `json:"revokedUntil"`
+	SecretRegenerated bool      `json:"secretRegenerated"`
+}
+
 type QRScanInput struct {
 	QRData       string  `json:"qrData"`
 	ActivityID   string  `json:"activityID"`
@@ -122,9 +304,36 @@ type QRScanResult struct {
 	ScanLog       *models.QRScanLog     `json:"scanLog,omitempty"`
 }
 
+type QRSecurityDailyMetrics struct {
+	Date           string                  `json:"date"`
+	TotalScans     int                     `json:"totalScans"`
+	SuccessScans   int                     `json:"successScans"`
+	FailureScans   int                     `json:"failureScans"`
+	FailureReasons []*QRFailureReasonCount `json:"failureReasons"`
+}
+
+type QRSecurityMetrics struct {
+	TotalScans     int                       `json:"totalScans"`
+	SuccessScans   int                       `json:"successScans"`
+	FailureScans   int                       `json:"failureScans"`
+	FailureReasons []*QRFailureReasonCount   `json:"failureReasons"`
+	Daily          []*QRSecurityDailyMetrics `json:"daily"`
+}
+
 type Query struct {
 }
 
+type QueryStatistic struct {
+	QueryHash     string    `json:"queryHash"`
+	Query         string    `json:"query"`
+	CallCount     int       `json:"callCount"`
+	ErrorCount    int       `json:"errorCount"`
+	AvgDurationMs float64   `json:"avgDurationMs"`
+	MinDurationMs float64   `json:"minDurationMs"`
+	MaxDurationMs float64   `json:"maxDurationMs"`
+	LastExecuted  time.Time `json:"lastExecuted"`
+}
+
 type RegisterInput struct {
 	StudentID    string  `json:"studentID"`
 	Email        string  `json:"email"`
@@ -135,11 +344,72 @@ type RegisterInput struct {
 	DepartmentID *string `json:"departmentID,omitempty"`
 }
 
+type ScanSession struct {
+	Activity       *models.Activity `json:"activity"`
+	ScansPerMinute float64          `json:"scansPerMinute"`
+	SuccessCount   int              `json:"successCount"`
+	FailureCount   int              `json:"failureCount"`
+	LastScanAt     time.Time        `json:"lastScanAt"`
+	Scanners       []*models.User   `json:"scanners"`
+}
+
+type ScannerToken struct {
+	ID         string    `json:"id"`
+	ActivityID string    `json:"activityID"`
+	IssuedByID string    `json:"issuedByID"`
+	IssuedAt   time.Time `json:"issuedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Token      *string   `json:"token,omitempty"`
+}
+
+type SetFeatureFlagInput struct {
+	Name              string  `json:"name"`
+	FacultyID         *string `json:"facultyID,omitempty"`
+	Enabled           bool    `json:"enabled"`
+	RolloutPercentage *int    `json:"rolloutPercentage,omitempty"`
+}
+
+type SlowConnection struct {
+	ConnectionID  string `json:"connectionID"`
+	UserID        string `json:"userID"`
+	HighWatermark int    `json:"highWatermark"`
+	Drops         int    `json:"drops"`
+}
+
+type SlowQuery struct {
+	ID           string          `json:"id"`
+	QueryHash    string          `json:"queryHash"`
+	Query        string          `json:"query"`
+	DurationMs   float64         `json:"durationMs"`
+	Tables       []string        `json:"tables"`
+	RowsReturned int             `json:"rowsReturned"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Statistics   *QueryStatistic `json:"statistics,omitempty"`
+}
+
+type StudentQRCode struct {
+	StudentID string `json:"studentID"`
+	QRString  string `json:"qrString"`
+}
+
+type StudentQRFailure struct {
+	StudentID string `json:"studentID"`
+	Error     string `json:"error"`
+}
+
 type SubscriptionFilter struct {
-	FacultyID  *string  `json:"facultyID,omitempty"`
-	ActivityID *string  `json:"activityID,omitempty"`
-	UserID     *string  `json:"userID,omitempty"`
-	Types      []string `json:"types,omitempty"`
+	FacultyID   *string               `json:"facultyID,omitempty"`
+	ActivityID  *string               `json:"activityID,omitempty"`
+	UserID      *string               `json:"userID,omitempty"`
+	Types       []string              `json:"types,omitempty"`
+	MinSeverity *models.AlertSeverity `json:"minSeverity,omitempty"`
+}
+
+type SubscriptionListFilter struct {
+	Status             *models.SubscriptionStatus `json:"status,omitempty"`
+	Type               *models.SubscriptionType   `json:"type,omitempty"`
+	ExpiringWithinDays *int                       `json:"expiringWithinDays,omitempty"`
+	NeedsNotification  *bool                      `json:"needsNotification,omitempty"`
 }
 
 type SubscriptionMetadata struct {
@@ -155,6 +425,13 @@ type SubscriptionPayload struct {
 	Timestamp time.Time             `json:"timestamp"`
 	Data      SubscriptionData      `json:"data,omitempty"`
 	Metadata  *SubscriptionMetadata `json:"metadata,omitempty"`
+	Stream    *string               `json:"stream,omitempty"`
+	Sequence  int                   `json:"sequence"`
+}
+
+type SubscriptionTypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
 }
 
 type UpdateActivityAssignmentInput struct {
@@ -164,20 +441,24 @@ type UpdateActivityAssignmentInput struct {
 }
 
 type UpdateActivityInput struct {
-	Title           *string                `json:"title,omitempty"`
-	Description     *string                `json:"description,omitempty"`
-	Type            *models.ActivityType   `json:"type,omitempty"`
-	Status          *models.ActivityStatus `json:"status,omitempty"`
-	StartDate       *time.Time             `json:"startDate,omitempty"`
-	EndDate         *time.Time             `json:"endDate,omitempty"`
-	Location        *string                `json:"location,omitempty"`
-	MaxParticipants *int                   `json:"maxParticipants,omitempty"`
-	RequireApproval *bool                  `json:"requireApproval,omitempty"`
-	Points          *int                   `json:"points,omitempty"`
-	FacultyID       *string                `json:"facultyID,omitempty"`
-	DepartmentID    *string                `json:"departmentID,omitempty"`
-	QRCodeRequired  *bool                  `json:"qrCodeRequired,omitempty"`
-	AutoApprove     *bool                  `json:"autoApprove,omitempty"`
+	Title            *string                  `json:"title,omitempty"`
+	Description      *string                  `json:"description,omitempty"`
+	Type             *models.ActivityType     `json:"type,omitempty"`
+	Status           *models.ActivityStatus   `json:"status,omitempty"`
+	StartDate        *time.Time               `json:"startDate,omitempty"`
+	EndDate          *time.Time               `json:"endDate,omitempty"`
+	Timezone         *string                  `json:"timezone,omitempty"`
+	Location         *string                  `json:"location,omitempty"`
+	MaxParticipants  *int                     `json:"maxParticipants,omitempty"`
+	RequireApproval  *bool                    `json:"requireApproval,omitempty"`
+	Points           *int                     `json:"points,omitempty"`
+	FacultyID        *string                  `json:"facultyID,omitempty"`
+	DepartmentID     *string                  `json:"departmentID,omitempty"`
+	QRCodeRequired   *bool                    `json:"qrCodeRequired,omitempty"`
+	AutoApprove      *bool                    `json:"autoApprove,omitempty"`
+	AttendancePolicy *models.AttendancePolicy `json:"attendancePolicy,omitempty"`
+	QRExpiryMinutes  *int                     `json:"qrExpiryMinutes,omitempty"`
+	ExpectedVersion  *int                     `json:"expectedVersion,omitempty"`
 }
 
 type UpdateActivityTemplateInput struct {
@@ -199,9 +480,488 @@ type UpdateDepartmentInput struct {
 	Code *string `json:"code,omitempty"`
 }
 
+type UpdateFacultyInput struct {
+	Name        *string `json:"name,omitempty"`
+	Code        *string `json:"code,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+}
+
 type UpdateSubscriptionInput struct {
-	Type      *models.SubscriptionType   `json:"type,omitempty"`
-	StartDate *time.Time                 `json:"startDate,omitempty"`
-	EndDate   *time.Time                 `json:"endDate,omitempty"`
-	Status    *models.SubscriptionStatus `json:"status,omitempty"`
+	Type            *models.SubscriptionType   `json:"type,omitempty"`
+	StartDate       *time.Time                 `json:"startDate,omitempty"`
+	EndDate         *time.Time                 `json:"endDate,omitempty"`
+	Status          *models.SubscriptionStatus `json:"status,omitempty"`
+	ExpectedVersion *int                       `json:"expectedVersion,omitempty"`
+}
+
+type UserImportResult struct {
+	Total      int              `json:"total"`
+	Created    int              `json:"created"`
+	Duplicates int              `json:"duplicates"`
+	Invalid    int              `json:"invalid"`
+	Rows       []*UserImportRow `json:"rows"`
+}
+
+type UserImportRow struct {
+	Row       int              `json:"row"`
+	StudentID string           `json:"studentID"`
+	Email     string           `json:"email"`
+	Status    UserImportStatus `json:"status"`
+	Message   *string          `json:"message,omitempty"`
+	User      *models.User     `json:"user,omitempty"`
+}
+
+type AuditResource string
+
+const (
+	AuditResourceActivity     AuditResource = "ACTIVITY"
+	AuditResourceUser         AuditResource = "USER"
+	AuditResourceSubscription AuditResource = "SUBSCRIPTION"
+)
+
+var AllAuditResource = []AuditResource{
+	AuditResourceActivity,
+	AuditResourceUser,
+	AuditResourceSubscription,
+}
+
+func (e AuditResource) IsValid() bool {
+	switch e {
+	case AuditResourceActivity, AuditResourceUser, AuditResourceSubscription:
+		return true
+	}
+	return false
+}
+
+func (e AuditResource) String() string {
+	return string(e)
+}
+
+func (e *AuditResource) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditResource(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditResource", str)
+	}
+	return nil
+}
+
+func (e AuditResource) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AuditResource) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AuditResource) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type CacheControlScope string
+
+const (
+	CacheControlScopePublic  CacheControlScope = "PUBLIC"
+	CacheControlScopePrivate CacheControlScope = "PRIVATE"
+)
+
+var AllCacheControlScope = []CacheControlScope{
+	CacheControlScopePublic,
+	CacheControlScopePrivate,
+}
+
+func (e CacheControlScope) IsValid() bool {
+	switch e {
+	case CacheControlScopePublic, CacheControlScopePrivate:
+		return true
+	}
+	return false
+}
+
+func (e CacheControlScope) String() string {
+	return string(e)
+}
+
+func (e *CacheControlScope) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CacheControlScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CacheControlScope", str)
+	}
+	return nil
+}
+
+func (e CacheControlScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *CacheControlScope) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e CacheControlScope) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type FacultyComparisonMetric string
+
+const (
+	FacultyComparisonMetricStudents       FacultyComparisonMetric = "STUDENTS"
+	FacultyComparisonMetricActivities     FacultyComparisonMetric = "ACTIVITIES"
+	FacultyComparisonMetricAttendanceRate FacultyComparisonMetric = "ATTENDANCE_RATE"
+	FacultyComparisonMetricPointsAwarded  FacultyComparisonMetric = "POINTS_AWARDED"
+)
+
+var AllFacultyComparisonMetric = []FacultyComparisonMetric{
+	FacultyComparisonMetricStudents,
+	FacultyComparisonMetricActivities,
+	FacultyComparisonMetricAttendanceRate,
+	FacultyComparisonMetricPointsAwarded,
+}
+
+func (e FacultyComparisonMetric) IsValid() bool {
+	switch e {
+	case FacultyComparisonMetricStudents, FacultyComparisonMetricActivities, FacultyComparisonMetricAttendanceRate, FacultyComparisonMetricPointsAwarded:
+		return true
+	}
+	return false
+}
+
+func (e FacultyComparisonMetric) String() string {
+	return string(e)
+}
+
+func (e *FacultyComparisonMetric) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FacultyComparisonMetric(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FacultyComparisonMetric", str)
+	}
+	return nil
+}
+
+func (e FacultyComparisonMetric) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *FacultyComparisonMetric) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e FacultyComparisonMetric) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type MaintenanceJobStatus string
+
+const (
+	MaintenanceJobStatusRunning   MaintenanceJobStatus = "RUNNING"
+	MaintenanceJobStatusCompleted MaintenanceJobStatus = "COMPLETED"
+	MaintenanceJobStatusFailed    MaintenanceJobStatus = "FAILED"
+)
+
+var AllMaintenanceJobStatus = []MaintenanceJobStatus{
+	MaintenanceJobStatusRunning,
+	MaintenanceJobStatusCompleted,
+	MaintenanceJobStatusFailed,
+}
+
+func (e MaintenanceJobStatus) IsValid() bool {
+	switch e {
+	case MaintenanceJobStatusRunning, MaintenanceJobStatusCompleted, MaintenanceJobStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e MaintenanceJobStatus) String() string {
+	return string(e)
+}
+
+func (e *MaintenanceJobStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = MaintenanceJobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid MaintenanceJobStatus", str)
+	}
+	return nil
+}
+
+func (e MaintenanceJobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *MaintenanceJobStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e MaintenanceJobStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type MaintenanceJobType string
+
+const (
+	MaintenanceJobTypeRebuildSearchIndex MaintenanceJobType = "REBUILD_SEARCH_INDEX"
+	MaintenanceJobTypeRecomputeMetrics   MaintenanceJobType = "RECOMPUTE_METRICS"
+)
+
+var AllMaintenanceJobType = []MaintenanceJobType{
+	MaintenanceJobTypeRebuildSearchIndex,
+	MaintenanceJobTypeRecomputeMetrics,
+}
+
+func (e MaintenanceJobType) IsValid() bool {
+	switch e {
+	case MaintenanceJobTypeRebuildSearchIndex, MaintenanceJobTypeRecomputeMetrics:
+		return true
+	}
+	return false
+}
+
+func (e MaintenanceJobType) String() string {
+	return string(e)
+}
+
+func (e *MaintenanceJobType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = MaintenanceJobType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid MaintenanceJobType", str)
+	}
+	return nil
+}
+
+func (e MaintenanceJobType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *MaintenanceJobType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e MaintenanceJobType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type OfflineScanStatus string
+
+const (
+	OfflineScanStatusAttended    OfflineScanStatus = "ATTENDED"
+	OfflineScanStatusRejected    OfflineScanStatus = "REJECTED"
+	OfflineScanStatusNeedsReview OfflineScanStatus = "NEEDS_REVIEW"
+)
+
+var AllOfflineScanStatus = []OfflineScanStatus{
+	OfflineScanStatusAttended,
+	OfflineScanStatusRejected,
+	OfflineScanStatusNeedsReview,
+}
+
+func (e OfflineScanStatus) IsValid() bool {
+	switch e {
+	case OfflineScanStatusAttended, OfflineScanStatusRejected, OfflineScanStatusNeedsReview:
+		return true
+	}
+	return false
+}
+
+func (e OfflineScanStatus) String() string {
+	return string(e)
+}
+
+func (e *OfflineScanStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OfflineScanStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OfflineScanStatus", str)
+	}
+	return nil
+}
+
+func (e OfflineScanStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *OfflineScanStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e OfflineScanStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type QueryStatsSort string
+
+const (
+	QueryStatsSortAvgDuration QueryStatsSort = "AVG_DURATION"
+	QueryStatsSortCallCount   QueryStatsSort = "CALL_COUNT"
+	QueryStatsSortErrorCount  QueryStatsSort = "ERROR_COUNT"
+)
+
+var AllQueryStatsSort = []QueryStatsSort{
+	QueryStatsSortAvgDuration,
+	QueryStatsSortCallCount,
+	QueryStatsSortErrorCount,
+}
+
+func (e QueryStatsSort) IsValid() bool {
+	switch e {
+	case QueryStatsSortAvgDuration, QueryStatsSortCallCount, QueryStatsSortErrorCount:
+		return true
+	}
+	return false
+}
+
+func (e QueryStatsSort) String() string {
+	return string(e)
+}
+
+func (e *QueryStatsSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = QueryStatsSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid QueryStatsSort", str)
+	}
+	return nil
+}
+
+func (e QueryStatsSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *QueryStatsSort) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e QueryStatsSort) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type UserImportStatus string
+
+const (
+	UserImportStatusCreated   UserImportStatus = "CREATED"
+	UserImportStatusDuplicate UserImportStatus = "DUPLICATE"
+	UserImportStatusInvalid   UserImportStatus = "INVALID"
+)
+
+var AllUserImportStatus = []UserImportStatus{
+	UserImportStatusCreated,
+	UserImportStatusDuplicate,
+	UserImportStatusInvalid,
+}
+
+func (e UserImportStatus) IsValid() bool {
+	switch e {
+	case UserImportStatusCreated, UserImportStatusDuplicate, UserImportStatusInvalid:
+		return true
+	}
+	return false
+}
+
+func (e UserImportStatus) String() string {
+	return string(e)
+}
+
+func (e *UserImportStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UserImportStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UserImportStatus", str)
+	}
+	return nil
+}
+
+func (e UserImportStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *UserImportStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e UserImportStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+const (
+	MaxOfflineScanBatch = 500
+	maxOfflineScanIDLen = 64
+)
+
+// canScanForActivity allows the activity's managers and the admins assigned to scan for it
+func (r *Resolver) canScanForActivity(ctx context.Context, user *models.User, activity *models.Activity) bool {
+	if user.CanManageActivity(activity) {
+		return true
+	}
+
+	var assignments int64
+	r.DB.WithContext(ctx).Model(&models.ActivityAssignment{}).
+		Where("activity_id = ? AND admin_id = ? AND can_scan_qr = ?", activity.ID, user.ID, true).
+		Count(&assignments)
+	return assignments > 0
+}
+
+// reconcileOfflineScans validates the scans an offline scanner accepted and records the
+// attendance of those still valid. Revoked and replayed codes are logged for review rather
+// than counted. Each scan is reconciled once: resubmitting it returns the stored result, so a
+// scanner can safely retry a batch that failed part way.
+func (r *Resolver) reconcileOfflineScans(ctx context.Context, activityID string, scans []*model.OfflineScanInput) ([]*model.OfflineScanReconciliation, error) {
+	if err := requireScannerCredentials(ctx); err != nil {
+		return nil, err
+	}
+	if r.QRSecurity == nil {
+		return nil, fmt.Errorf("qr codes are not available")
+	}
+	if len(scans) == 0 || len(scans) > MaxOfflineScanBatch {
+		return nil, errcode.Validation("submit between 1 and %d scans at a time", MaxOfflineScanBatch)
+	}
+
+	id, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, id).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+	scanner, err := r.authorizeScanner(ctx, &activity)
+	if err != nil {
+		return nil, err
+	}
+
+	scanIDs := make([]string, 0, len(scans))
+	seen := make(map[string]bool, len(scans))
+	for _, scan := range scans {
+		scan.ID = strings.TrimSpace(scan.ID)
+		if scan.ID == "" || len(scan.ID) > maxOfflineScanIDLen {
+			return nil, errcode.Validation("scan IDs must be between 1 and %d characters", maxOfflineScanIDLen)
+		}
+		if seen[scan.ID] {
+			return nil, errcode.Validation("scan %s is submitted twice", scan.ID)
+		}
+		seen[scan.ID] = true
+		scanIDs = append(scanIDs, scan.ID)
+	}
+
+	reconciled, err := r.reconciledOfflineScans(ctx, activity.ID, scanIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*model.OfflineScanReconciliation, 0, len(scans))
+	counts := make(map[model.OfflineScanStatus]int)
+	duplicates := 0
+	for _, scan := range scans {
+		var result *model.OfflineScanReconciliation
+		if scanLog, ok := reconciled[scan.ID]; ok {
+			result = r.offlineScanResult(ctx, scanLog, true)
+		} else if result, err = r.reconcileOfflineScan(ctx, scanner, &activity, scan); err != nil {
+			return nil, err
+		}
+
+		if result.Duplicate {
+			duplicates++
+		} else {
+			counts[result.Status]++
+		}
+		results = append(results, result)
+	}
+
+	details := map[string]interface{}{
+		"offline_scans": len(scans),
+		"attended":      counts[model.OfflineScanStatusAttended],
+		"rejected":      counts[model.OfflineScanStatusRejected],
+		"needs_review":  counts[model.OfflineScanStatusNeedsReview],
+		"duplicates":    duplicates,
+	}
+	if scanner.TokenID != nil {
+		details["scanner_token_id"] = *scanner.TokenID
+	}
+	r.logAdminAction(ctx, audit.ActionScan, audit.ResourceActivity, activityID, details)
+
+	return results, nil
+}
+
+// reconciledOfflineScans returns the scan logs of the scans reconciled already, by scan ID
+func (r *Resolver) reconciledOfflineScans(ctx context.Context, activityID uint, scanIDs []string) (map[string]*models.QRScanLog, error) {
+	var logs []*models.QRScanLog
+	if err := r.DB.WithContext(ctx).
+		Where("activity_id = ? AND offline_scan_id IN ?", activityID, scanIDs).
+		Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load reconciled scans")
+	}
+
+	reconciled := make(map[string]*models.QRScanLog, len(logs))
+	for _, scanLog := range logs {
+		reconciled[*scanLog.OfflineScanID] = scanLog
+	}
+	return reconciled, nil
+}
+
+// reconcileOfflineScan validates one scan and stores its outcome with its scan ID. The code is
+// marked used only once the attendance has committed, so a failed attempt can be retried
+// without being mistaken for a replay.
+func (r *Resolver) reconcileOfflineScan(ctx context.Context, scanner *activityScanner, activity *models.Activity, scan *model.OfflineScanInput) (*model.OfflineScanReconciliation, error) {
+	var facultyID string
+	if activity.FacultyID != nil {
+		facultyID = strconv.FormatUint(uint64(*activity.FacultyID), 10)
+	}
+	clientIP, _ := ctx.Value("client_ip").(string)
+	userAgent, _ := ctx.Value("user_agent").(string)
+
+	check, err := r.QRSecurity.CheckOfflineScan(ctx, scan.QRData, scan.ScannedAt, scanner.ID(),
+		strconv.FormatUint(uint64(activity.ID), 10), facultyID, clientIP, userAgent)
+	if err != nil {
+		log.Printf("Offline scan %s for activity %d could not be validated: %v", scan.ID, activity.ID, err)
+		return nil, fmt.Errorf("QR validation service error, please submit the scans again")
+	}
+
+	scanID := scan.ID
+	scanLog := &models.QRScanLog{
+		StudentID:      check.StudentID,
+		ActivityID:     activity.ID,
+		ScannedByID:    scanner.UserID,
+		ScanTimestamp:  scan.ScannedAt,
+		QRTimestamp:    check.QRTimestamp,
+		Valid:          check.Valid,
+		NeedsReview:    check.NeedsReview,
+		IPAddress:      clientIP,
+		UserAgent:      userAgent,
+		OfflineScanID:  &scanID,
+		ScannerTokenID: scanner.TokenID,
+	}
+	if !check.Valid {
+		scanLog.ErrorMessage = check.Message
+	}
+	if scan.ScanLocation != nil {
+		scanLog.ScanLocation = *scan.ScanLocation
+	}
+
+	var student models.User
+	if check.StudentID != "" {
+		if err := r.DB.WithContext(ctx).Where("student_id = ?", check.StudentID).First(&student).Error; err == nil {
+			scanLog.UserID = &student.ID
+		} else if check.Valid {
+			check.Valid = false
+			scanLog.Valid = false
+			scanLog.ErrorMessage = "Student not found"
+		}
+	}
+
+	_, message, err := r.recordScan(ctx, activity, scanLog, student.ID, func(participation *models.Participation) {
+		if err := r.QRSecurity.MarkOfflineScanUsed(ctx, check); err != nil {
+			log.Printf("Failed to mark QR code of offline scan %s used: %v", scan.ID, err)
+		}
+		r.publishScanAttendance(activity, participation, "offline_scan")
+	})
+	if database.IsUniqueViolation(err) {
+		// Another submission of the same batch reconciled the scan first
+		var existing models.QRScanLog
+		if err := r.DB.WithContext(ctx).Where("activity_id = ? AND offline_scan_id = ?", activity.ID, scan.ID).First(&existing).Error; err != nil {
+			return nil, fmt.Errorf("failed to load reconciled scan")
+		}
+		return r.offlineScanResult(ctx, &existing, true), nil
+	}
+	if err != nil {
+		log.Printf("Failed to reconcile offline scan %s for activity %d: %v", scan.ID, activity.ID, err)
+		return nil, fmt.Errorf("failed to record attendance, please submit the scans again")
+	}
+
+	if scanLog.NeedsReview {
+		r.logSecurityEvent(ctx, &audit.SecurityEvent{
+			EventType: audit.SecurityEventQRTampering,
+			UserID:    check.StudentID,
+			FacultyID: facultyID,
+			IPAddress: clientIP,
+			UserAgent: userAgent,
+			Details: map[string]interface{}{
+				"reason":          check.Reason,
+				"activity_id":     activity.ID,
+				"scanner_id":      scanner.ID(),
+				"offline_scan_id": scan.ID,
+				"scanned_at":      scan.ScannedAt.Unix(),
+			},
+			RiskLevel: audit.RiskLevelHigh,
+		})
+	}
+
+	result := r.offlineScanResult(ctx, scanLog, false)
+	if scanLog.Valid {
+		result.Message = message
+	}
+	return result, nil
+}
+
+// offlineScanResult describes a reconciled scan from its stored scan log
+func (r *Resolver) offlineScanResult(ctx context.Context, scanLog *models.QRScanLog, duplicate bool) *model.OfflineScanReconciliation {
+	result := &model.OfflineScanReconciliation{
+		ID:        *scanLog.OfflineScanID,
+		Status:    model.OfflineScanStatusRejected,
+		Message:   scanLog.ErrorMessage,
+		Duplicate: duplicate,
+		ScanLog:   scanLog,
+	}
+
+	switch {
+	case scanLog.NeedsReview:
+		result.Status = model.OfflineScanStatusNeedsReview
+	case scanLog.Valid:
+		result.Status = model.OfflineScanStatusAttended
+		result.Message = "Attendance recorded"
+		if scanLog.UserID != nil {
+			var participation models.Participation
+			if err := r.DB.WithContext(ctx).Preload("User").Preload("Activity").
+				Where("user_id = ? AND activity_id = ?", *scanLog.UserID, scanLog.ActivityID).
+				First(&participation).Error; err == nil {
+				result.Participation = &participation
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errAlreadyParticipating    = errors.New("already participating in this activity")
+	errParticipationNotPending = errors.New("participation is not pending approval")
+	errAlreadyAttended         = errors.New("cannot withdraw after attendance has been recorded")
+	errCannotWithdraw          = errors.New("participation cannot be withdrawn")
+)
+
+// reviewParticipation moves a pending participation to approved or rejected.
+// The row is locked and re-checked inside a retried transaction so concurrent reviews can't both apply.
+func (r *Resolver) reviewParticipation(ctx context.Context, participationID string, status models.ParticipationStatus) (*models.Participation, error) {
+	pID, err := strconv.ParseUint(participationID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid participation ID")
+	}
+
+	var participation models.Participation
+	if err := r.DB.Preload("Activity").First(&participation, pID).Error; err != nil {
+		return nil, errcode.NotFound("participation not found")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, participation.Activity.FacultyID, audit.ResourceParticipation, participationID); err != nil {
+		return nil, err
+	}
+
+	err = r.DB.TransactionWithRetry(ctx, func(tx *gorm.DB) error {
+		var current models.Participation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, pID).Error; err != nil {
+			return err
+		}
+		if current.Status != models.ParticipationStatusPending {
+			return errParticipationNotPending
+		}
+
+		updates := map[string]interface{}{"status": status}
+		if status == models.ParticipationStatusApproved {
+			updates["approved_at"] = time.Now()
+		}
+		return tx.Model(&current).Updates(updates).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errParticipationNotPending), errors.Is(err, database.ErrRetriesExhausted):
+		return nil, err
+	default:
+		return nil, fmt.Errorf("failed to update participation")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceParticipation, participationID, map[string]interface{}{
+		"activity_id": participation.ActivityID,
+		"user_id":     participation.UserID,
+		"status":      string(status),
+	})
+
+	r.DB.Preload("User").Preload("Activity").First(&participation, participation.ID)
+
+	return convertParticipationToGraphQL(&participation), nil
+}
+
+// withdrawParticipation cancels a user's registration for an activity. Pending registrations
+// are removed outright; approved ones are kept as withdrawn so attendance history stays intact.
+// It returns nil when the registration was removed.
+func (r *Resolver) withdrawParticipation(ctx context.Context, userID, activityID uint) (*models.Participation, error) {
+	var participation models.Participation
+	if err := r.DB.Preload("Activity").
+		Where("user_id = ? AND activity_id = ?", userID, activityID).
+		First(&participation).Error; err != nil {
+		return nil, errcode.NotFound("you are not registered for this activity")
+	}
+
+	removed := false
+	err := r.DB.TransactionWithRetry(ctx, func(tx *gorm.DB) error {
+		var current models.Participation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, participation.ID).Error; err != nil {
+			return err
+		}
+
+		switch current.Status {
+		case models.ParticipationStatusPending:
+			removed = true
+			return tx.Delete(&current).Error
+		case models.ParticipationStatusApproved:
+			return tx.Model(&current).Update("status", models.ParticipationStatusWithdrawn).Error
+		case models.ParticipationStatusAttended:
+			return errAlreadyAttended
+		default:
+			return errCannotWithdraw
+		}
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errAlreadyAttended), errors.Is(err, errCannotWithdraw), errors.Is(err, database.ErrRetriesExhausted):
+		return nil, err
+	default:
+		return nil, fmt.Errorf("failed to withdraw from activity")
+	}
+
+	participationID := strconv.FormatUint(uint64(participation.ID), 10)
+	action := audit.ActionUpdate
+	if removed {
+		action = audit.ActionDelete
+	}
+	r.logAdminAction(ctx, action, audit.ResourceParticipation, participationID, map[string]interface{}{
+		"activity_id": participation.ActivityID,
+		"user_id":     participation.UserID,
+		"status":      string(models.ParticipationStatusWithdrawn),
+		"removed":     removed,
+	})
+
+	participation.Status = models.ParticipationStatusWithdrawn
+	if r.EventPublisher != nil {
+		if err := r.EventPublisher.PublishParticipationUpdated(&participation, "withdrawn", &services.EventContext{
+			UserID:     &userID,
+			FacultyID:  participation.Activity.FacultyID,
+			ActivityID: &participation.ActivityID,
+			Source:     "leave_activity",
+		}); err != nil {
+			log.Printf("Failed to publish withdrawal of user %d from activity %d: %v", userID, activityID, err)
+		}
+	}
+
+	if removed {
+		return nil, nil
+	}
+
+	r.DB.Preload("User").Preload("Activity").First(&participation, participation.ID)
+	return convertParticipationToGraphQL(&participation), nil
+}
+
+// participationHistory lists a user's participations, most recent activity first, with the
+// points earned from the attended ones. Participations come from the operation's DataLoader,
+// so the queries are shared with every other participation lookup in the operation.
+func (r *Resolver) participationHistory(ctx context.Context, userID uint, status *models.ParticipationStatus, rangeArg *model.DateRangeInput) (*model.ParticipationHistory, error) {
+	loaders := performance.GetDataLoaders(ctx)
+	if loaders == nil {
+		loaders = performance.NewDataLoaderContainer(r.DB.DB, nil)
+	}
+
+	participations, err := loaders.Participation.LoadByUser(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load participations of user %d: %v", userID, err)
+		return nil, fmt.Errorf("failed to fetch participations")
+	}
+	return buildParticipationHistory(participations, status, rangeArg), nil
+}
+
+// buildParticipationHistory filters and sorts copies of the loaded participations
+func buildParticipationHistory(participations []models.Participation, status *models.ParticipationStatus, rangeArg *model.DateRangeInput) *model.ParticipationHistory {
+	var wantStatus models.ParticipationStatus
+	if status != nil {
+		wantStatus = models.ParticipationStatus(strings.ToLower(string(*status)))
+	}
+
+	history := &model.ParticipationHistory{Participations: []*models.Participation{}}
+	for i := range participations {
+		participation := participations[i]
+		if wantStatus != "" && participation.Status != wantStatus {
+			continue
+		}
+		if rangeArg != nil {
+			if rangeArg.FromDate != nil && participation.Activity.StartDate.Before(*rangeArg.FromDate) {
+				continue
+			}
+			if rangeArg.ToDate != nil && participation.Activity.StartDate.After(*rangeArg.ToDate) {
+				continue
+			}
+		}
+
+		history.Participations = append(history.Participations, &participation)
+		if participation.Status == models.ParticipationStatusAttended {
+			history.AttendedCount++
+			history.TotalPoints += participation.Activity.Points
+		}
+	}
+
+	sort.SliceStable(history.Participations, func(i, j int) bool {
+		a, b := history.Participations[i], history.Participations[j]
+		if !a.Activity.StartDate.Equal(b.Activity.StartDate) {
+			return a.Activity.StartDate.After(b.Activity.StartDate)
+		}
+		return a.RegisteredAt.After(b.RegisteredAt)
+	})
+	return history
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+func TestBuildParticipationHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC) }
+	participation := func(id uint, status models.ParticipationStatus, start time.Time, points int) models.Participation {
+		return models.Participation{
+			ID:       id,
+			Status:   status,
+			Activity: models.Activity{StartDate: start, Points: points},
+		}
+	}
+	loaded := []models.Participation{
+		participation(1, models.ParticipationStatusAttended, day(1), 10),
+		participation(2, models.ParticipationStatusApproved, day(20), 5),
+		participation(3, models.ParticipationStatusAttended, day(10), 20),
+		participation(4, models.ParticipationStatusWithdrawn, day(15), 7),
+	}
+	attended := models.ParticipationStatus("ATTENDED")
+	from, to := day(5), day(16)
+
+	tests := []struct {
+		name       string
+		status     *models.ParticipationStatus
+		rangeArg   *model.DateRangeInput
+		wantIDs    []uint
+		wantPoints int
+	}{
+		{"all, most recent first", nil, nil, []uint{2, 4, 3, 1}, 30},
+		{"by status", &attended, nil, []uint{3, 1}, 30},
+		{"by date range", nil, &model.DateRangeInput{FromDate: &from, ToDate: &to}, []uint{4, 3}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := buildParticipationHistory(loaded, tt.status, tt.rangeArg)
+
+			var ids []uint
+			for _, p := range history.Participations {
+				ids = append(ids, p.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("participations = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("participations = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+			if history.TotalPoints != tt.wantPoints {
+				t.Errorf("totalPoints = %d, want %d", history.TotalPoints, tt.wantPoints)
+			}
+		})
+	}
+
+	// The loaded slice is shared with the DataLoader's cache
+	if loaded[0].ID != 1 || loaded[1].ID != 2 {
+		t.Error("loaded participations were reordered")
+	}
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+)
+
+// GenerateQRStringsForActivity issues QR strings, keyed by student ID, for every approved
+// participant of the activity, e.g. to pre-print badges. The activity's QR expiry override
+// applies when set. It returns the per-student failures and when the codes expire.
+func (r *Resolver) GenerateQRStringsForActivity(ctx context.Context, activityID uint) (map[string]string, map[string]error, time.Time, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin); err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if r.QRSecurity == nil {
+		return nil, nil, time.Time{}, fmt.Errorf("qr codes are not available")
+	}
+
+	var activity models.Activity
+	if err := r.DB.First(&activity, activityID).Error; err != nil {
+		return nil, nil, time.Time{}, errcode.NotFound("activity not found")
+	}
+
+	resourceID := strconv.FormatUint(uint64(activity.ID), 10)
+	if _, err := r.requireActivityScope(ctx, &activity, resourceID); err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if err := r.requireFeature(ctx, activity.FacultyID, models.FeatureQRBadges); err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	var studentIDs []string
+	if err := r.DB.Model(&models.Participation{}).
+		Joins("JOIN users ON users.id = participations.user_id AND users.deleted_at IS NULL").
+		Where("participations.activity_id = ? AND participations.status = ?", activity.ID, models.ParticipationStatusApproved).
+		Where("users.student_id <> ''").
+		Pluck("users.student_id", &studentIDs).Error; err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("failed to load participants")
+	}
+
+	// The zero time asks for the standard expiry
+	var override time.Time
+	expiresAt := time.Now().Add(security.QRExpiryDuration)
+	if activity.QRExpiryMinutes != nil {
+		override = time.Now().Add(time.Duration(*activity.QRExpiryMinutes) * time.Minute)
+		expiresAt = override
+	}
+
+	codes, failures, err := r.QRSecurity.GenerateQRStrings(ctx, studentIDs, override)
+	if err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("failed to generate qr codes")
+	}
+
+	r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceQRCode, resourceID, map[string]interface{}{
+		"activity_id":  activity.ID,
+		"participants": len(studentIDs),
+		"generated":    len(codes),
+		"failed":       len(failures),
+		"expires_at":   expiresAt,
+	})
+
+	return codes, failures, expiresAt, nil
+}
+
+// myQRCode generates a QR code for the signed-in student. Only the student's own code can be
+// generated: the student ID comes from the session, never from the request.
+func (r *Resolver) myQRCode(ctx context.Context) (*model.MyQRCode, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if authCtx.User.StudentID == "" {
+		return nil, errcode.Forbidden("only students have QR codes")
+	}
+	if r.QRSecurity == nil {
+		return nil, fmt.Errorf("qr codes are not available")
+	}
+
+	issued, err := r.QRSecurity.IssueQRCode(ctx, authCtx.User.StudentID)
+	var limitErr *security.QRGenerationLimitError
+	if errors.As(err, &limitErr) {
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		gqlErr := errcode.RateLimited("too many QR codes generated, please try again in %d seconds", retryAfter)
+		gqlErr.Extensions["retryAfter"] = retryAfter
+		return nil, gqlErr
+	}
+	if err != nil {
+		log.Printf("Failed to generate QR code for student %s: %v", authCtx.User.StudentID, err)
+		return nil, fmt.Errorf("failed to generate qr code")
+	}
+
+	if issued.Audit {
+		r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceQRCode, authCtx.User.StudentID, map[string]interface{}{
+			"student_id": authCtx.User.StudentID,
+			"expires_at": issued.ExpiresAt,
+		})
+	}
+
+	return &model.MyQRCode{
+		QRString:  issued.QRString,
+		ExpiresAt: issued.ExpiresAt,
+	}, nil
+}
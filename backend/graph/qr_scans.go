@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"gorm.io/gorm"
+)
+
+// scanQRCode validates a scanned code and records the student's attendance. Invalid scans
+// are logged too, so they show up in the activity's scan history.
+func (r *Resolver) scanQRCode(ctx context.Context, input model.QRScanInput) (*model.QRScanResult, error) {
+	if err := requireScannerCredentials(ctx); err != nil {
+		return nil, err
+	}
+	if r.QRSecurity == nil {
+		return nil, fmt.Errorf("qr codes are not available")
+	}
+
+	id, err := strconv.ParseUint(input.ActivityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, id).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+	scanner, err := r.authorizeScanner(ctx, &activity)
+	if err != nil {
+		return nil, err
+	}
+
+	var facultyID string
+	if activity.FacultyID != nil {
+		facultyID = strconv.FormatUint(uint64(*activity.FacultyID), 10)
+	}
+	clientIP, _ := ctx.Value("client_ip").(string)
+	userAgent, _ := ctx.Value("user_agent").(string)
+
+	validation, err := r.QRSecurity.ValidateQRData(ctx, input.QRData, scanner.ID(), input.ActivityID, facultyID, clientIP, userAgent)
+	if err != nil {
+		log.Printf("QR scan for activity %d could not be validated: %v", activity.ID, err)
+		return nil, fmt.Errorf("QR validation service error, please scan again")
+	}
+
+	scannedAt := time.Now()
+	scanLog := &models.QRScanLog{
+		StudentID:      validation.StudentID,
+		ActivityID:     activity.ID,
+		ScannedByID:    scanner.UserID,
+		ScanTimestamp:  scannedAt,
+		Valid:          validation.Valid,
+		IPAddress:      clientIP,
+		UserAgent:      userAgent,
+		ScannerTokenID: scanner.TokenID,
+	}
+	if !validation.Valid {
+		scanLog.ErrorMessage = validation.Message
+	}
+	if input.ScanLocation != nil {
+		scanLog.ScanLocation = *input.ScanLocation
+	}
+	if decoded, err := security.DecodeQRData(input.QRData); err == nil {
+		scanLog.QRTimestamp = time.Unix(decoded.Timestamp, 0)
+		if scanLog.StudentID == "" {
+			scanLog.StudentID = decoded.StudentID
+		}
+	}
+
+	var student models.User
+	if validation.Valid {
+		if err := r.DB.WithContext(ctx).Where("student_id = ?", validation.StudentID).First(&student).Error; err == nil {
+			scanLog.UserID = &student.ID
+		} else {
+			scanLog.Valid = false
+			scanLog.ErrorMessage = "Student not found"
+		}
+	}
+
+	participation, message, err := r.recordScan(ctx, &activity, scanLog, student.ID, func(participation *models.Participation) {
+		r.publishScanAttendance(&activity, participation, "qr_scan")
+	})
+	if err != nil {
+		log.Printf("Failed to record QR scan for activity %d: %v", activity.ID, err)
+		return nil, fmt.Errorf("failed to record attendance, please scan again")
+	}
+
+	details := map[string]interface{}{"student_id": scanLog.StudentID, "valid": scanLog.Valid}
+	if scanner.TokenID != nil {
+		details["scanner_token_id"] = *scanner.TokenID
+	}
+	r.logAdminAction(ctx, audit.ActionScan, audit.ResourceActivity, input.ActivityID, details)
+
+	result := &model.QRScanResult{
+		Success: scanLog.Valid,
+		Message: scanLog.ErrorMessage,
+		ScanLog: scanLog,
+	}
+	if scanLog.Valid {
+		result.Message = message
+		result.User = &student
+		if err := r.DB.WithContext(ctx).Preload("User").Preload("Activity").First(participation, participation.ID).Error; err == nil {
+			result.Participation = participation
+		}
+	}
+	return result, nil
+}
+
+// recordScan stores scanLog and, while it is valid, the attendance it records, in one
+// transaction. A student who must register first turns the scan invalid. onCommit runs
+// with the participation once a valid scan has committed.
+func (r *Resolver) recordScan(ctx context.Context, activity *models.Activity, scanLog *models.QRScanLog, studentID uint, onCommit func(*models.Participation)) (*models.Participation, string, error) {
+	var participation *models.Participation
+	var message string
+	err := r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+		if scanLog.Valid {
+			var err error
+			participation, message, err = services.RecordScanAttendance(tx, activity, studentID, scanLog.ScannedByID, scanLog.ScanTimestamp, scanLog.ScanLocation)
+			if errors.Is(err, services.ErrRegistrationRequired) {
+				scanLog.Valid = false
+				scanLog.ErrorMessage = "Student must register for this activity first"
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(scanLog).Error; err != nil {
+			return err
+		}
+
+		if scanLog.Valid {
+			after.Do(func() { onCommit(participation) })
+		}
+		return nil
+	})
+	return participation, message, err
+}
+
+func (r *Resolver) publishScanAttendance(activity *models.Activity, participation *models.Participation, source string) {
+	if r.EventPublisher == nil || participation == nil {
+		return
+	}
+	if err := r.EventPublisher.PublishParticipationUpdated(participation, "qr_scanned", &services.EventContext{
+		UserID:     &participation.UserID,
+		FacultyID:  activity.FacultyID,
+		ActivityID: &activity.ID,
+		Source:     source,
+	}); err != nil {
+		log.Printf("Failed to publish attendance of user %d for activity %d: %v", participation.UserID, activity.ID, err)
+	}
+}
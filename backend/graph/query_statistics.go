@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	pkgdb "github.com/kruakemaths/tru-activity/backend/pkg/database"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+const (
+	defaultQueryReportLimit = 50
+	maxQueryReportLimit     = 500
+
+	defaultOperationCostDays = 7
+)
+
+func queryReportLimit(limit *int) int {
+	if limit != nil && *limit > 0 && *limit <= maxQueryReportLimit {
+		return *limit
+	}
+	return defaultQueryReportLimit
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func convertQueryStats(stats pkgdb.QueryStats) *model.QueryStatistic {
+	return &model.QueryStatistic{
+		QueryHash:     stats.QueryHash,
+		Query:         stats.Query,
+		CallCount:     int(stats.TotalCalls),
+		ErrorCount:    int(stats.ErrorCount),
+		AvgDurationMs: durationMs(stats.AverageDuration),
+		MinDurationMs: durationMs(stats.MinDuration),
+		MaxDurationMs: durationMs(stats.MaxDuration),
+		LastExecuted:  stats.LastExecuted,
+	}
+}
+
+func (r *Resolver) convertSlowQuery(query *pkgdb.SlowQuery) *model.SlowQuery {
+	converted := &model.SlowQuery{
+		ID:           query.ID,
+		QueryHash:    query.QueryHash,
+		Query:        query.Query,
+		DurationMs:   durationMs(query.Duration),
+		Tables:       query.Tables,
+		RowsReturned: int(query.RowsReturned),
+		Timestamp:    query.Timestamp,
+	}
+	if converted.Tables == nil {
+		converted.Tables = []string{}
+	}
+	if stats, ok := r.QueryOptimizer.QueryStatistics(query.QueryHash); ok {
+		converted.Statistics = convertQueryStats(stats)
+	}
+	return converted
+}
+
+func (r *Resolver) operationCostReport(ctx context.Context, days *int) ([]*model.OperationCostBucket, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
+	if r.PerformanceMonitor == nil {
+		return nil, fmt.Errorf("operation costs are not available")
+	}
+
+	period := defaultOperationCostDays
+	if days != nil {
+		if *days < 1 || *days > monitoring.MaxOperationCostReportDays {
+			return nil, errcode.Validation("days must be between 1 and %d", monitoring.MaxOperationCostReportDays)
+		}
+		period = *days
+	}
+
+	buckets, err := r.PerformanceMonitor.OperationCostReport(ctx, period)
+	if err != nil {
+		log.Printf("Failed to load operation cost report: %v", err)
+		return nil, fmt.Errorf("failed to load operation costs")
+	}
+
+	result := make([]*model.OperationCostBucket, len(buckets))
+	for i, bucket := range buckets {
+		result[i] = &model.OperationCostBucket{
+			Bucket:         bucket.Label,
+			MinComplexity:  bucket.MinComplexity,
+			MaxComplexity:  bucket.MaxComplexity,
+			Operations:     int(bucket.Operations),
+			SlowOperations: int(bucket.SlowOperations),
+			AvgDurationMs:  durationMs(bucket.AverageDuration()),
+		}
+	}
+	return result, nil
+}
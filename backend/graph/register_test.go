@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// uniqueViolation is the error Postgres returns when an insert breaks a unique index
+type uniqueViolation struct{ constraint string }
+
+func (e *uniqueViolation) Error() string {
+	return fmt.Sprintf(`ERROR: duplicate key value violates unique constraint "%s" (SQLSTATE 23505)`, e.constraint)
+}
+func (e *uniqueViolation) SQLState() string { return "23505" }
+
+// registeredUsers answers the duplicate check with the given users, and fails the insert with insertErr
+func registeredUsers(insertErr error, users ...[]driver.Value) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		switch {
+		case strings.HasPrefix(query, `SELECT "student_id","email" FROM "users"`):
+			return fakedb.Result{Columns: []string{"student_id", "email"}, Rows: users}
+		case strings.HasPrefix(query, `INSERT INTO "users"`):
+			return fakedb.Result{Err: insertErr}
+		}
+		return fakedb.Result{}
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	input := model.RegisterInput{
+		StudentID: "6400123",
+		Email:     "Ann@Example.com",
+		FirstName: "Ann",
+		LastName:  "Lee",
+		Password:  "correct horse 42",
+	}
+
+	tests := []struct {
+		name       string
+		respond    fakedb.Responder
+		wantField  string
+		wantMsg    string
+		wantInsert bool
+	}{
+		{
+			name:      "student ID taken",
+			respond:   registeredUsers(nil, []driver.Value{"6400123", "someone@example.com"}),
+			wantField: "input.studentID",
+			wantMsg:   "student ID is already registered",
+		},
+		{
+			name:      "email taken in another case",
+			respond:   registeredUsers(nil, []driver.Value{"6400999", "ann@example.com"}),
+			wantField: "input.email",
+			wantMsg:   "email is already registered",
+		},
+		{
+			name:      "both taken reports the student ID",
+			respond:   registeredUsers(nil, []driver.Value{"6400999", "ann@example.com"}, []driver.Value{"6400123", "other@example.com"}),
+			wantField: "input.studentID",
+			wantMsg:   "student ID is already registered",
+		},
+		{
+			name:       "email registered since the check",
+			respond:    registeredUsers(&uniqueViolation{"idx_users_email"}),
+			wantField:  "input.email",
+			wantMsg:    "email is already registered",
+			wantInsert: true,
+		},
+		{
+			name:       "student ID registered since the check",
+			respond:    registeredUsers(&uniqueViolation{"idx_users_student_id"}),
+			wantField:  "input.studentID",
+			wantMsg:    "student ID is already registered",
+			wantInsert: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := fakedb.Open(t, tt.respond)
+			r := &mutationResolver{&Resolver{DB: &database.DB{DB: db}, PasswordPolicy: utils.DefaultPasswordPolicy}}
+
+			payload, err := r.Register(context.Background(), input)
+			if payload != nil || errorCode(err) != string(errcode.CodeConflict) {
+				t.Fatalf("Register() = %v, %v, want a %s error", payload, err, errcode.CodeConflict)
+			}
+			gqlErr := err.(*gqlerror.Error)
+			if gqlErr.Message != tt.wantMsg || gqlErr.Extensions["field"] != tt.wantField {
+				t.Errorf("error = %q on %v, want %q on %s", gqlErr.Message, gqlErr.Extensions["field"], tt.wantMsg, tt.wantField)
+			}
+
+			inserts := fakedb.Containing(fake.Statements(), `INSERT INTO "users"`)
+			if got := len(inserts) > 0; got != tt.wantInsert {
+				t.Errorf("user inserted = %v, want %v", got, tt.wantInsert)
+			}
+		})
+	}
+}
+
+func TestRegisterReportsOtherInsertErrors(t *testing.T) {
+	db, _ := fakedb.Open(t, registeredUsers(fmt.Errorf("connection reset")))
+	r := &mutationResolver{&Resolver{DB: &database.DB{DB: db}, PasswordPolicy: utils.DefaultPasswordPolicy}}
+
+	_, err := r.Register(context.Background(), model.RegisterInput{StudentID: "6400123", Email: "ann@example.com", Password: "correct horse 42"})
+	if err == nil || errorCode(err) == string(errcode.CodeConflict) {
+		t.Fatalf("Register() error = %v, want a plain failure", err)
+	}
+}
@@ -2,7 +2,17 @@ package graph
 
 import (
 	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
 	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	pkgdb "github.com/kruakemaths/tru-activity/backend/pkg/database"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"github.com/kruakemaths/tru-activity/backend/pkg/notifications"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/resolvers"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
 )
 
 // This file will not be regenerated automatically.
@@ -10,6 +20,73 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct{
-	DB         *database.DB
-	JWTService *auth.JWTService
+	DB          *database.DB
+	JWTService  *auth.JWTService
+	AuditLogger *audit.AuditLogger
+
+	SessionStore        *auth.SessionStore
+	PasswordResets      *auth.PasswordResetStore
+	NotificationService *notifications.NotificationService
+	PasswordResetURL    string
+
+	// SessionTerminator drops a signed-out user's realtime connections on every instance
+	SessionTerminator *services.SessionTerminator
+
+	// PasswordPolicy is enforced whenever a password is set
+	PasswordPolicy utils.PasswordPolicy
+
+	// ExportLimiter caps concurrent exports per admin
+	ExportLimiter *lock.ConcurrencyLimiter
+
+	// FacultyComparisonService aggregates cached per-faculty metrics for super admins
+	FacultyComparisonService *services.FacultyComparisonService
+
+	// MetricsSnapshotter serves the stored daily system and faculty metrics
+	MetricsSnapshotter *services.MetricsSnapshotter
+
+	// MaintenanceJobs rebuilds the search indexes and recomputes stored metrics in the background
+	MaintenanceJobs *services.MaintenanceJobs
+
+	// JoinLimiter caps daily and concurrent activity joins per student
+	JoinLimiter *services.JoinLimiter
+
+	// EventPublisher publishes realtime events; nil when pub/sub is unavailable
+	EventPublisher *services.EventPublisher
+
+	// ActivityService generates activities from templates and recurrence rules
+	ActivityService *services.ActivityService
+
+	// ActivityRescheduler asks participants to confirm when an activity's dates change
+	ActivityRescheduler *services.ActivityRescheduler
+
+	// CacheManager caches user profiles for the user query; nil disables caching
+	CacheManager *performance.CacheManager
+
+	// FeatureFlagService gates features per faculty; nil leaves every gated feature off
+	FeatureFlagService *services.FeatureFlagService
+
+	// QueryOptimizer collects database query statistics; nil when QUERY_STATS_ENABLED is off
+	QueryOptimizer *pkgdb.QueryOptimizer
+
+	// PerformanceMonitor reports GraphQL operation durations per complexity bucket
+	PerformanceMonitor *monitoring.PerformanceMonitor
+
+	// QRSecurity exposes QR scan security counters
+	QRSecurity *security.QRSecurityManager
+
+	// ScannerTokens lets volunteers scan for one activity; nil leaves scanning to admins
+	ScannerTokens *auth.ScannerTokenStore
+
+	// ActivityDateRules validates activity schedules on create and update
+	ActivityDateRules services.ActivityDateRules
+
+	// DefaultTimezone is the zone of new activities whose faculty sets none
+	DefaultTimezone string
+
+	// EnforceFacultyScope rejects cross-faculty mutations by non-super admins;
+	// when false violations are only logged
+	EnforceFacultyScope bool
+
+	// Subscriptions serves realtime subscriptions; nil when pub/sub is unavailable
+	Subscriptions *resolvers.SubscriptionResolver
 }
\ No newline at end of file
@@ -0,0 +1,191 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// activityScanner is who a scan is made by: an admin, or a volunteer holding a scanner token
+type activityScanner struct {
+	// UserID is the admin, or the admin who issued the scanner token
+	UserID uint
+	// TokenID is the scanner token scanned with; nil for an admin
+	TokenID *string
+}
+
+// ID identifies the scanner in QR security logs and rate limits
+func (s *activityScanner) ID() string {
+	if s.TokenID != nil {
+		return "scanner_token:" + *s.TokenID
+	}
+	return strconv.FormatUint(uint64(s.UserID), 10)
+}
+
+func presentedScannerToken(ctx context.Context) string {
+	token, _ := ctx.Value("scanner_token").(string)
+	return token
+}
+
+// requireScannerCredentials rejects a scan made neither signed in nor with a scanner token,
+// before anything about the activity is looked up
+func requireScannerCredentials(ctx context.Context) error {
+	if presentedScannerToken(ctx) != "" {
+		return nil
+	}
+	_, err := middleware.RequireAuth(ctx)
+	return err
+}
+
+// authorizeScanner returns who is scanning for the activity: an admin who can scan for it,
+// or the holder of a scanner token issued for it
+func (r *Resolver) authorizeScanner(ctx context.Context, activity *models.Activity) (*activityScanner, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	if err == nil && r.canScanForActivity(ctx, authCtx.User, activity) {
+		return &activityScanner{UserID: authCtx.UserID}, nil
+	}
+
+	presented := presentedScannerToken(ctx)
+	if presented == "" || r.ScannerTokens == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errcode.Forbidden("permission denied")
+	}
+
+	token, err := r.ScannerTokens.Validate(ctx, presented)
+	if errors.Is(err, auth.ErrInvalidScannerToken) {
+		return nil, errcode.Unauthenticated("invalid or expired scanner token")
+	}
+	if err != nil {
+		log.Printf("Failed to validate scanner token: %v", err)
+		return nil, fmt.Errorf("failed to validate scanner token")
+	}
+	if token.ActivityID != activity.ID {
+		return nil, errcode.Forbidden("scanner token is for another activity")
+	}
+	return &activityScanner{UserID: token.IssuedByID, TokenID: &token.ID}, nil
+}
+
+// loadManagedActivity loads an activity the caller can manage, for handing out scanner tokens
+func (r *Resolver) loadManagedActivity(ctx context.Context, activityID string) (*middleware.AuthContext, *models.Activity, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.ScannerTokens == nil {
+		return nil, nil, fmt.Errorf("scanner tokens are unavailable")
+	}
+
+	id, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, nil, errcode.Validation("invalid activity ID")
+	}
+	var activity models.Activity
+	if err := r.DB.WithContext(ctx).Preload("CoHostFaculties").First(&activity, id).Error; err != nil {
+		return nil, nil, errcode.NotFound("activity not found")
+	}
+	if !authCtx.User.CanManageActivity(&activity) {
+		return nil, nil, errcode.Forbidden("permission denied")
+	}
+	return authCtx, &activity, nil
+}
+
+func (r *Resolver) issueScannerToken(ctx context.Context, activityID string, ttlMinutes *int) (*model.ScannerToken, error) {
+	authCtx, activity, err := r.loadManagedActivity(ctx, activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := auth.DefaultScannerTokenTTL
+	if ttlMinutes != nil {
+		ttl = time.Duration(*ttlMinutes) * time.Minute
+	}
+	if ttl < auth.MinScannerTokenTTL || ttl > auth.MaxScannerTokenTTL {
+		return nil, errcode.Validation("ttlMinutes must be between %d and %d",
+			int(auth.MinScannerTokenTTL.Minutes()), int(auth.MaxScannerTokenTTL.Minutes()))
+	}
+
+	secret, token, err := r.ScannerTokens.Issue(ctx, activity.ID, authCtx.UserID, ttl)
+	if err != nil {
+		log.Printf("Failed to issue scanner token for activity %d: %v", activity.ID, err)
+		return nil, fmt.Errorf("failed to issue scanner token")
+	}
+
+	r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceScannerToken, token.ID, map[string]interface{}{
+		"activity_id": activity.ID,
+		"expires_at":  token.ExpiresAt,
+	})
+
+	converted := convertScannerToken(token)
+	converted.Token = &secret
+	return converted, nil
+}
+
+func (r *Resolver) revokeScannerToken(ctx context.Context, id string) (bool, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return false, err
+	}
+	if r.ScannerTokens == nil {
+		return false, fmt.Errorf("scanner tokens are unavailable")
+	}
+
+	token, err := r.ScannerTokens.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		return false, errcode.NotFound("scanner token not found or already expired")
+	}
+	if _, _, err := r.loadManagedActivity(ctx, strconv.FormatUint(uint64(token.ActivityID), 10)); err != nil {
+		return false, err
+	}
+
+	if err := r.ScannerTokens.Revoke(ctx, token); err != nil {
+		log.Printf("Failed to revoke scanner token %s: %v", token.ID, err)
+		return false, fmt.Errorf("failed to revoke scanner token")
+	}
+
+	r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceScannerToken, token.ID, map[string]interface{}{
+		"activity_id":  token.ActivityID,
+		"issued_by_id": token.IssuedByID,
+	})
+	return true, nil
+}
+
+func (r *Resolver) listScannerTokens(ctx context.Context, activityID string) ([]*model.ScannerToken, error) {
+	_, activity, err := r.loadManagedActivity(ctx, activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := r.ScannerTokens.ListForActivity(ctx, activity.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.ScannerToken, len(tokens))
+	for i, token := range tokens {
+		result[i] = convertScannerToken(token)
+	}
+	return result, nil
+}
+
+func convertScannerToken(token *auth.ScannerToken) *model.ScannerToken {
+	return &model.ScannerToken{
+		ID:         token.ID,
+		ActivityID: strconv.FormatUint(uint64(token.ActivityID), 10),
+		IssuedByID: strconv.FormatUint(uint64(token.IssuedByID), 10),
+		IssuedAt:   token.IssuedAt,
+		ExpiresAt:  token.ExpiresAt,
+	}
+}
@@ -6,16 +6,29 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/kruakemaths/tru-activity/backend/graph/generated"
 	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
 	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	pkgdb "github.com/kruakemaths/tru-activity/backend/pkg/database"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
 	"github.com/kruakemaths/tru-activity/backend/pkg/permissions"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
 	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
+	"gorm.io/gorm"
 )
 
 // ID is the resolver for the id field.
@@ -23,14 +36,31 @@ func (r *activityResolver) ID(ctx context.Context, obj *models.Activity) (string
 	panic(fmt.Errorf("not implemented: ID - id"))
 }
 
+// LocalStartDate is the resolver for the localStartDate field.
+func (r *activityResolver) LocalStartDate(ctx context.Context, obj *models.Activity) (*time.Time, error) {
+	localStart := obj.StartDate.In(obj.TimeLocation())
+	return &localStart, nil
+}
+
+// LocalEndDate is the resolver for the localEndDate field.
+func (r *activityResolver) LocalEndDate(ctx context.Context, obj *models.Activity) (*time.Time, error) {
+	localEnd := obj.EndDate.In(obj.TimeLocation())
+	return &localEnd, nil
+}
+
+// DeletedAt is the resolver for the deletedAt field.
+func (r *activityResolver) DeletedAt(ctx context.Context, obj *models.Activity) (*time.Time, error) {
+	return deletedAtTime(obj.DeletedAt), nil
+}
+
 // ID is the resolver for the id field.
 func (r *activityAssignmentResolver) ID(ctx context.Context, obj *models.ActivityAssignment) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
 }
 
 // ID is the resolver for the id field.
 func (r *activityTemplateResolver) ID(ctx context.Context, obj *models.ActivityTemplate) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
 }
 
 // ID is the resolver for the id field.
@@ -45,34 +75,35 @@ func (r *facultyResolver) ID(ctx context.Context, obj *models.Faculty) (string,
 
 // ID is the resolver for the id field.
 func (r *facultyMetricsResolver) ID(ctx context.Context, obj *models.FacultyMetrics) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// ID is the resolver for the id field.
+func (r *featureFlagResolver) ID(ctx context.Context, obj *models.FeatureFlag) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
 }
 
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error) {
 	var user models.User
 	if err := r.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, errcode.Unauthenticated("invalid credentials")
 	}
 
 	if !utils.CheckPasswordHash(input.Password, user.Password) {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, errcode.Unauthenticated("invalid credentials")
 	}
 
 	// Generate JWT token with faculty and department info
-	token, err := r.JWTService.GenerateToken(
-		user.ID,
-		user.Email,
-		string(user.Role),
-		user.FacultyID,
-		user.DepartmentID,
-	)
+	token, err := r.issueToken(ctx, &user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token")
 	}
 
-	// Update last login
-	r.DB.Model(&user).Update("last_login_at", "NOW()")
+	if err := recordLastLogin(r.DB.DB, &user, time.Now()); err != nil {
+		log.Printf("Failed to update last login of user %d: %v", user.ID, err)
+	}
+	r.invalidateUserCache(ctx, user.ID)
 
 	return &model.AuthPayload{
 		Token: token,
@@ -82,6 +113,16 @@ func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*
 
 // Register is the resolver for the register field.
 func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInput) (*model.AuthPayload, error) {
+	if err := r.validatePassword(ctx, input.Password, "input.password"); err != nil {
+		return nil, err
+	}
+
+	if field, err := r.registeredUserField(ctx, input.StudentID, input.Email); err != nil {
+		return nil, err
+	} else if field != "" {
+		return nil, duplicateUserError(ctx, field)
+	}
+
 	// Hash password
 	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
@@ -117,17 +158,15 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 	}
 
 	if err := r.DB.Create(&user).Error; err != nil {
+		// Someone registered the same student ID or email since the check above
+		if field := duplicateUserField(err); field != "" {
+			return nil, duplicateUserError(ctx, field)
+		}
 		return nil, fmt.Errorf("failed to create user")
 	}
 
 	// Generate JWT token
-	token, err := r.JWTService.GenerateToken(
-		user.ID,
-		user.Email,
-		string(user.Role),
-		user.FacultyID,
-		user.DepartmentID,
-	)
+	token, err := r.issueToken(ctx, &user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token")
 	}
@@ -146,13 +185,7 @@ func (r *mutationResolver) RefreshToken(ctx context.Context) (*model.AuthPayload
 	}
 
 	// Generate new token
-	token, err := r.JWTService.GenerateToken(
-		authCtx.User.ID,
-		authCtx.User.Email,
-		string(authCtx.User.Role),
-		authCtx.User.FacultyID,
-		authCtx.User.DepartmentID,
-	)
+	token, err := r.issueToken(ctx, authCtx.User)
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh token")
 	}
@@ -163,6 +196,121 @@ func (r *mutationResolver) RefreshToken(ctx context.Context) (*model.AuthPayload
 	}, nil
 }
 
+// RequestPasswordReset is the resolver for the requestPasswordReset field.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	clientIP, _ := ctx.Value("client_ip").(string)
+
+	// Rate limit per email and per IP
+	if allowed, err := r.PasswordResets.AllowRequest(ctx, "email:"+email, auth.PasswordResetLimitPerEmail); err != nil || !allowed {
+		r.logSecurityEvent(ctx, &audit.SecurityEvent{
+			EventType: audit.SecurityEventRateLimitExceeded,
+			RiskLevel: audit.RiskLevelMedium,
+			Details: map[string]interface{}{
+				"operation": "requestPasswordReset",
+				"email":     email,
+			},
+		})
+		return false, errcode.RateLimited("too many password reset requests, please try again later")
+	}
+	if clientIP != "" {
+		if allowed, err := r.PasswordResets.AllowRequest(ctx, "ip:"+clientIP, auth.PasswordResetLimitPerIP); err != nil || !allowed {
+			r.logSecurityEvent(ctx, &audit.SecurityEvent{
+				EventType: audit.SecurityEventRateLimitExceeded,
+				RiskLevel: audit.RiskLevelMedium,
+				Details: map[string]interface{}{
+					"operation": "requestPasswordReset",
+				},
+			})
+			return false, errcode.RateLimited("too many password reset requests, please try again later")
+		}
+	}
+
+	var user models.User
+	if err := r.DB.Where("LOWER(email) = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
+		// Respond the same way whether or not the account exists to avoid user enumeration
+		r.logSecurityEvent(ctx, &audit.SecurityEvent{
+			EventType: audit.SecurityEventPasswordResetRequest,
+			RiskLevel: audit.RiskLevelLow,
+			Details: map[string]interface{}{
+				"email":      email,
+				"user_found": false,
+			},
+		})
+		return true, nil
+	}
+
+	token, err := r.PasswordResets.CreateToken(ctx, user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to request password reset")
+	}
+
+	resetURL := fmt.Sprintf("%s?token=%s", r.PasswordResetURL, token)
+	if r.NotificationService != nil {
+		if err := r.NotificationService.SendPasswordResetEmail(&user, resetURL); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}
+
+	r.logSecurityEvent(ctx, &audit.SecurityEvent{
+		EventType: audit.SecurityEventPasswordResetRequest,
+		UserID:    strconv.FormatUint(uint64(user.ID), 10),
+		RiskLevel: audit.RiskLevelLow,
+		Details: map[string]interface{}{
+			"email":      email,
+			"user_found": true,
+		},
+	})
+
+	return true, nil
+}
+
+// ResetPassword is the resolver for the resetPassword field.
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	if err := r.validatePassword(ctx, newPassword, "newPassword"); err != nil {
+		return false, err
+	}
+
+	userID, err := r.PasswordResets.ConsumeToken(ctx, token)
+	if err != nil {
+		r.logSecurityEvent(ctx, &audit.SecurityEvent{
+			EventType: audit.SecurityEventPasswordReset,
+			RiskLevel: audit.RiskLevelMedium,
+			Details: map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			},
+		})
+		return false, err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password")
+	}
+
+	if err := r.DB.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error; err != nil {
+		return false, fmt.Errorf("failed to reset password")
+	}
+	r.invalidateUserCache(ctx, userID)
+
+	// Sign out every existing session for this user
+	if _, err := r.signOutEverywhere(ctx, userID); err != nil {
+		log.Printf("Failed to revoke sessions for user %d: %v", userID, err)
+	}
+
+	r.logSecurityEvent(ctx, &audit.SecurityEvent{
+		EventType: audit.SecurityEventPasswordReset,
+		UserID:    strconv.FormatUint(uint64(userID), 10),
+		RiskLevel: audit.RiskLevelMedium,
+		Details: map[string]interface{}{
+			"success": true,
+		},
+	})
+
+	return true, nil
+}
+
 // CreateActivity is the resolver for the createActivity field.
 func (r *mutationResolver) CreateActivity(ctx context.Context, input model.CreateActivityInput) (*models.Activity, error) {
 	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
@@ -178,8 +326,15 @@ func (r *mutationResolver) CreateActivity(ctx context.Context, input model.Creat
 
 		// Check faculty permission
 		if !authCtx.Permissions.HasFacultyPermission(authCtx.User, permissions.PermCreateActivity, facultyIDUint) {
-			return nil, fmt.Errorf("permission denied for this faculty")
+			return nil, errcode.Forbidden("permission denied for this faculty")
 		}
+	} else if authCtx.Role != models.UserRoleSuperAdmin {
+		// Faculty admins always create activities in their own faculty
+		facultyID = authCtx.FacultyID
+	}
+
+	if _, err := r.requireFacultyScope(ctx, facultyID, audit.ResourceActivity, ""); err != nil {
+		return nil, err
 	}
 
 	if input.DepartmentID != nil {
@@ -188,6 +343,15 @@ func (r *mutationResolver) CreateActivity(ctx context.Context, input model.Creat
 		departmentID = &departmentIDUint
 	}
 
+	if err := r.validateActivityDates(ctx, input.StartDate, input.EndDate, true); err != nil {
+		return nil, err
+	}
+
+	timezone, err := r.activityTimezone(ctx, input.Timezone, facultyID)
+	if err != nil {
+		return nil, err
+	}
+
 	var description, location string
 	if input.Description != nil {
 		description = *input.Description
@@ -196,40 +360,316 @@ func (r *mutationResolver) CreateActivity(ctx context.Context, input model.Creat
 		location = *input.Location
 	}
 
-	activity := models.Activity{
-		Title:           input.Title,
-		Description:     description,
-		Type:            models.ActivityType(input.Type),
-		Status:          models.ActivityStatusDraft,
-		StartDate:       input.StartDate,
-		EndDate:         input.EndDate,
-		Location:        location,
-		MaxParticipants: input.MaxParticipants,
-		RequireApproval: input.RequireApproval,
-		Points:          input.Points,
-		FacultyID:       facultyID,
-		DepartmentID:    departmentID,
-		CreatedByID:     authCtx.User.ID,
+	attendancePolicy := models.AttendancePolicyFirstScan
+	if input.AttendancePolicy != nil {
+		attendancePolicy, err = parseAttendancePolicy(*input.AttendancePolicy)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if err := r.DB.Create(&activity).Error; err != nil {
-		return nil, fmt.Errorf("failed to create activity")
+	isRecurring := input.IsRecurring != nil && *input.IsRecurring
+	var recurrenceRule string
+	if isRecurring {
+		if err := r.requireFeature(ctx, facultyID, models.FeatureRecurringActivities); err != nil {
+			return nil, err
+		}
+		if input.RecurrenceRule != nil {
+			recurrenceRule = *input.RecurrenceRule
+		}
 	}
 
-	// Load relationships
-	r.DB.Preload("Faculty").Preload("Department").Preload("CreatedBy").First(&activity, activity.ID)
+	var qrExpiryMinutes *int
+	if input.QRExpiryMinutes != nil {
+		qrExpiryMinutes, err = parseQRExpiryMinutes(*input.QRExpiryMinutes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	activity := models.Activity{
+		Title:            input.Title,
+		Description:      description,
+		Type:             models.ActivityType(input.Type),
+		Status:           models.ActivityStatusDraft,
+		StartDate:        input.StartDate.UTC(),
+		EndDate:          input.EndDate.UTC(),
+		Timezone:         timezone,
+		Location:         location,
+		MaxParticipants:  input.MaxParticipants,
+		RequireApproval:  input.RequireApproval,
+		Points:           input.Points,
+		FacultyID:        facultyID,
+		DepartmentID:     departmentID,
+		CreatedByID:      authCtx.User.ID,
+		IsRecurring:      isRecurring,
+		RecurrenceRule:   recurrenceRule,
+		AttendancePolicy: attendancePolicy,
+		QRExpiryMinutes:  qrExpiryMinutes,
+	}
+
+	err = r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+		activity.ID = 0
+		if err := tx.Create(&activity).Error; err != nil {
+			return err
+		}
+
+		// Load relationships
+		if err := tx.Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+
+		after.Do(func() {
+			r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceActivity, strconv.FormatUint(uint64(activity.ID), 10), activityAuditDetails(&activity))
+		})
+		return nil
+	})
+	if err != nil {
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
+		if errors.Is(err, database.ErrRetriesExhausted) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create activity")
+	}
 
 	return convertActivityToGraphQL(&activity), nil
 }
 
 // UpdateActivity is the resolver for the updateActivity field.
 func (r *mutationResolver) UpdateActivity(ctx context.Context, id string, input model.UpdateActivityInput) (*models.Activity, error) {
-	panic(fmt.Errorf("not implemented: UpdateActivity - updateActivity"))
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	actID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.First(&activity, actID).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+
+	if _, err := r.requireActivityScope(ctx, &activity, id); err != nil {
+		return nil, err
+	}
+
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != activity.Version {
+		return nil, versionConflictError(ctx, "activity", activity.Version)
+	}
+
+	updates := map[string]interface{}{}
+	if input.FacultyID != nil {
+		fID, err := strconv.ParseUint(*input.FacultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(fID)
+
+		// Moving an activity requires scope over the target faculty as well
+		if _, err := r.requireFacultyScope(ctx, &facultyIDUint, audit.ResourceActivity, id); err != nil {
+			return nil, err
+		}
+		updates["faculty_id"] = facultyIDUint
+	}
+	if input.DepartmentID != nil {
+		dID, err := strconv.ParseUint(*input.DepartmentID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid department ID")
+		}
+		updates["department_id"] = uint(dID)
+	}
+	if input.Title != nil {
+		updates["title"] = *input.Title
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.Type != nil {
+		updates["type"] = *input.Type
+	}
+	if input.Status != nil {
+		updates["status"] = *input.Status
+	}
+	if input.StartDate != nil || input.EndDate != nil {
+		start, end := activity.StartDate, activity.EndDate
+		if input.StartDate != nil {
+			start = *input.StartDate
+		}
+		if input.EndDate != nil {
+			end = *input.EndDate
+		}
+		startChanged := input.StartDate != nil && !input.StartDate.Equal(activity.StartDate)
+		if err := r.validateActivityDates(ctx, start, end, startChanged); err != nil {
+			return nil, err
+		}
+		updates["start_date"] = start.UTC()
+		updates["end_date"] = end.UTC()
+	}
+	if input.Timezone != nil {
+		if _, err := models.LoadTimezone(*input.Timezone); err != nil {
+			return nil, timezoneError(ctx, "input.timezone", *input.Timezone)
+		}
+		updates["timezone"] = *input.Timezone
+	}
+	if input.Location != nil {
+		updates["location"] = *input.Location
+	}
+	if input.MaxParticipants != nil {
+		updates["max_participants"] = *input.MaxParticipants
+	}
+	if input.RequireApproval != nil {
+		updates["require_approval"] = *input.RequireApproval
+	}
+	if input.Points != nil {
+		updates["points"] = *input.Points
+	}
+	if input.QRCodeRequired != nil {
+		updates["qr_code_required"] = *input.QRCodeRequired
+	}
+	if input.AutoApprove != nil {
+		updates["auto_approve"] = *input.AutoApprove
+	}
+	if input.AttendancePolicy != nil {
+		policy, err := parseAttendancePolicy(*input.AttendancePolicy)
+		if err != nil {
+			return nil, err
+		}
+		updates["attendance_policy"] = policy
+	}
+	if input.QRExpiryMinutes != nil {
+		minutes, err := parseQRExpiryMinutes(*input.QRExpiryMinutes)
+		if err != nil {
+			return nil, err
+		}
+		updates["qr_expiry_minutes"] = minutes
+	}
+
+	rescheduled := services.DatesChanged(&activity, input.StartDate, input.EndDate)
+	oldStart, oldEnd := activity.StartDate, activity.EndDate
+
+	err = r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+		if len(updates) > 0 {
+			// The version condition rejects the write if another edit landed after the check above
+			conflicted, err := updateVersioned(tx, &activity, input.ExpectedVersion, updates)
+			if err != nil {
+				return err
+			}
+			if conflicted {
+				return errActivityVersionConflict
+			}
+		}
+
+		// Load relationships
+		if err := tx.Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+
+		if len(updates) > 0 {
+			after.Do(func() {
+				r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivity, id, activityAuditDetails(&activity))
+			})
+		}
+
+		if rescheduled && r.ActivityRescheduler != nil {
+			if _, err := r.ActivityRescheduler.HandleReschedule(tx, after, &activity, oldStart, oldEnd, &services.EventContext{
+				UserID:     &authCtx.UserID,
+				FacultyID:  activity.FacultyID,
+				ActivityID: &activity.ID,
+				Source:     "update_activity",
+			}); err != nil {
+				return fmt.Errorf("failed to handle reschedule: %w", err)
+			}
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errActivityVersionConflict):
+		var current models.Activity
+		r.DB.Select("version").First(&current, activity.ID)
+		return nil, versionConflictError(ctx, "activity", current.Version)
+	case errors.Is(err, database.ErrRetriesExhausted):
+		return nil, err
+	default:
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
+		log.Printf("Failed to update activity %d: %v", activity.ID, err)
+		return nil, fmt.Errorf("failed to update activity")
+	}
+
+	return convertActivityToGraphQL(&activity), nil
 }
 
 // DeleteActivity is the resolver for the deleteActivity field.
 func (r *mutationResolver) DeleteActivity(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeleteActivity - deleteActivity"))
+	activityID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid activity ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.First(&activity, activityID).Error; err != nil {
+		return false, errcode.NotFound("activity not found")
+	}
+
+	if _, err := r.requireActivityScope(ctx, &activity, id); err != nil {
+		return false, err
+	}
+
+	// Soft delete keeps participations and the audit trail intact
+	if err := r.DB.Delete(&activity).Error; err != nil {
+		return false, fmt.Errorf("failed to delete activity")
+	}
+
+	r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceActivity, id, activityAuditDetails(&activity))
+
+	return true, nil
+}
+
+// RestoreActivity is the resolver for the restoreActivity field.
+func (r *mutationResolver) RestoreActivity(ctx context.Context, id string) (*models.Activity, error) {
+	activityID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+
+	var activity models.Activity
+	if err := r.DB.Unscoped().First(&activity, activityID).Error; err != nil {
+		return nil, errcode.NotFound("activity not found")
+	}
+
+	if !activity.DeletedAt.Valid {
+		return nil, fmt.Errorf("activity is not deleted")
+	}
+
+	if _, err := r.requireActivityScope(ctx, &activity, id); err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.Unscoped().Model(&activity).Update("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore activity")
+	}
+
+	r.logAdminAction(ctx, audit.ActionRestore, audit.ResourceActivity, id, activityAuditDetails(&activity))
+
+	r.DB.Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy").First(&activity, activity.ID)
+	return convertActivityToGraphQL(&activity), nil
+}
+
+// AddActivityCoHost is the resolver for the addActivityCoHost field.
+func (r *mutationResolver) AddActivityCoHost(ctx context.Context, activityID string, facultyID string) (*models.Activity, error) {
+	return r.addActivityCoHost(ctx, activityID, facultyID)
+}
+
+// RemoveActivityCoHost is the resolver for the removeActivityCoHost field.
+func (r *mutationResolver) RemoveActivityCoHost(ctx context.Context, activityID string, facultyID string) (*models.Activity, error) {
+	return r.removeActivityCoHost(ctx, activityID, facultyID)
 }
 
 // JoinActivity is the resolver for the joinActivity field.
@@ -241,13 +681,13 @@ func (r *mutationResolver) JoinActivity(ctx context.Context, activityID string)
 
 	actID, err := strconv.ParseUint(activityID, 10, 32)
 	if err != nil {
-		return nil, fmt.Errorf("invalid activity ID")
+		return nil, errcode.Validation("invalid activity ID")
 	}
 
 	// Check if activity exists and is active
 	var activity models.Activity
 	if err := r.DB.First(&activity, actID).Error; err != nil {
-		return nil, fmt.Errorf("activity not found")
+		return nil, errcode.NotFound("activity not found")
 	}
 
 	if activity.Status != models.ActivityStatusActive {
@@ -256,8 +696,39 @@ func (r *mutationResolver) JoinActivity(ctx context.Context, activityID string)
 
 	// Check if already participating
 	var existingParticipation models.Participation
-	if err := r.DB.Where("user_id = ? AND activity_id = ?", authCtx.User.ID, actID).First(&existingParticipation).Error; err == nil {
-		return nil, fmt.Errorf("already participating in this activity")
+	if err := r.DB.Where("user_id = ? AND activity_id = ? AND status <> ?", authCtx.User.ID, actID, models.ParticipationStatusWithdrawn).First(&existingParticipation).Error; err == nil {
+		return nil, errcode.Conflict("already participating in this activity")
+	}
+
+	// Students are limited in how many activities they can join; admins are exempt
+	reserved := false
+	if r.JoinLimiter != nil && authCtx.Role == models.UserRoleStudent {
+		count, err := r.JoinLimiter.Reserve(ctx, authCtx.User.ID)
+		switch err {
+		case nil:
+			reserved = r.JoinLimiter.DailyLimit() > 0
+		case services.ErrDailyJoinLimitReached:
+			// Report the first attempt past the limit each day
+			if count == int64(r.JoinLimiter.DailyLimit())+1 {
+				r.logSecurityEvent(ctx, &audit.SecurityEvent{
+					EventType: audit.SecurityEventSuspiciousActivity,
+					UserID:    strconv.FormatUint(uint64(authCtx.UserID), 10),
+					Details: map[string]interface{}{
+						"reason":      "daily_join_limit_exceeded",
+						"daily_limit": r.JoinLimiter.DailyLimit(),
+						"activity_id": activityID,
+					},
+					RiskLevel: audit.RiskLevelMedium,
+					Blocked:   true,
+				})
+			}
+			return nil, errcode.RateLimited("daily limit of %d activity joins reached, please try again tomorrow", r.JoinLimiter.DailyLimit())
+		case services.ErrActiveJoinLimitReached:
+			return nil, errcode.RateLimited("you can be registered for at most %d active activities at once", r.JoinLimiter.ActiveLimit())
+		default:
+			// Don't block joins when the limiter is unavailable
+			log.Printf("Join limit check failed for user %d: %v", authCtx.UserID, err)
+		}
 	}
 
 	status := models.ParticipationStatusApproved
@@ -271,7 +742,40 @@ func (r *mutationResolver) JoinActivity(ctx context.Context, activityID string)
 		Status:     status,
 	}
 
-	if err := r.DB.Create(&participation).Error; err != nil {
+	err = r.DB.TransactionWithRetry(ctx, func(tx *gorm.DB) error {
+		var existing []models.Participation
+		if err := tx.Where("user_id = ? AND activity_id = ?", authCtx.User.ID, actID).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, e := range existing {
+			if e.Status != models.ParticipationStatusWithdrawn {
+				return errAlreadyParticipating
+			}
+		}
+
+		// Rejoining after a withdrawal reuses the withdrawn registration
+		if len(existing) > 0 {
+			participation.ID = existing[0].ID
+			return tx.Model(&participation).Updates(map[string]interface{}{
+				"status":        status,
+				"registered_at": time.Now(),
+				"approved_at":   nil,
+			}).Error
+		}
+		participation.ID = 0
+		return tx.Create(&participation).Error
+	})
+	if err != nil {
+		if reserved {
+			r.JoinLimiter.Release(ctx, authCtx.User.ID)
+		}
+		switch {
+		case errors.Is(err, errAlreadyParticipating):
+			return nil, errcode.Conflict("already participating in this activity")
+		case errors.Is(err, database.ErrRetriesExhausted):
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to join activity")
 	}
 
@@ -282,18 +786,28 @@ func (r *mutationResolver) JoinActivity(ctx context.Context, activityID string)
 }
 
 // LeaveActivity is the resolver for the leaveActivity field.
-func (r *mutationResolver) LeaveActivity(ctx context.Context, activityID string) (bool, error) {
-	panic(fmt.Errorf("not implemented: LeaveActivity - leaveActivity"))
+func (r *mutationResolver) LeaveActivity(ctx context.Context, activityID string) (*models.Participation, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actID, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+
+	return r.withdrawParticipation(ctx, authCtx.UserID, uint(actID))
 }
 
 // ApproveParticipation is the resolver for the approveParticipation field.
 func (r *mutationResolver) ApproveParticipation(ctx context.Context, participationID string) (*models.Participation, error) {
-	panic(fmt.Errorf("not implemented: ApproveParticipation - approveParticipation"))
+	return r.reviewParticipation(ctx, participationID, models.ParticipationStatusApproved)
 }
 
 // RejectParticipation is the resolver for the rejectParticipation field.
 func (r *mutationResolver) RejectParticipation(ctx context.Context, participationID string) (*models.Participation, error) {
-	panic(fmt.Errorf("not implemented: RejectParticipation - rejectParticipation"))
+	return r.reviewParticipation(ctx, participationID, models.ParticipationStatusRejected)
 }
 
 // MarkAttendance is the resolver for the markAttendance field.
@@ -301,6 +815,37 @@ func (r *mutationResolver) MarkAttendance(ctx context.Context, participationID s
 	panic(fmt.Errorf("not implemented: MarkAttendance - markAttendance"))
 }
 
+// RespondToReschedule is the resolver for the respondToReschedule field.
+func (r *mutationResolver) RespondToReschedule(ctx context.Context, participationID string, accept bool) (bool, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	pID, err := strconv.ParseUint(participationID, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid participation ID")
+	}
+
+	var participation models.Participation
+	if err := r.DB.Preload("Activity").Where("id = ? AND user_id = ?", pID, authCtx.UserID).First(&participation).Error; err != nil {
+		return false, errcode.NotFound("participation not found")
+	}
+
+	if r.ActivityRescheduler == nil {
+		return false, fmt.Errorf("reschedule handling is not available")
+	}
+
+	if err := r.ActivityRescheduler.Respond(&participation, accept); err != nil {
+		if err == services.ErrNoReschedulePending {
+			return false, errcode.NotFound("no reschedule is awaiting your response")
+		}
+		return false, fmt.Errorf("failed to record reschedule response")
+	}
+
+	return true, nil
+}
+
 // CreateFaculty is the resolver for the createFaculty field.
 func (r *mutationResolver) CreateFaculty(ctx context.Context, input model.CreateFacultyInput) (*models.Faculty, error) {
 	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
@@ -313,23 +858,40 @@ func (r *mutationResolver) CreateFaculty(ctx context.Context, input model.Create
 		description = *input.Description
 	}
 
+	if err := r.requireUniqueFacultyCode(ctx, input.Code, 0); err != nil {
+		return nil, err
+	}
+
+	var timezone string
+	if input.Timezone != nil && *input.Timezone != "" {
+		if _, err := models.LoadTimezone(*input.Timezone); err != nil {
+			return nil, timezoneError(ctx, "input.timezone", *input.Timezone)
+		}
+		timezone = *input.Timezone
+	}
+
 	faculty := models.Faculty{
 		Name:        input.Name,
 		Code:        input.Code,
 		Description: description,
+		Timezone:    timezone,
 		IsActive:    true,
 	}
 
 	if err := r.DB.Create(&faculty).Error; err != nil {
+		if fieldErr := textFieldError(ctx, err); fieldErr != nil {
+			return nil, fieldErr
+		}
 		return nil, fmt.Errorf("failed to create faculty")
 	}
+	r.invalidateCachedResponses(ctx)
 
 	return convertFacultyToGraphQL(&faculty), nil
 }
 
 // UpdateFaculty is the resolver for the updateFaculty field.
-func (r *mutationResolver) UpdateFaculty(ctx context.Context, id string, input model.CreateFacultyInput) (*models.Faculty, error) {
-	panic(fmt.Errorf("not implemented: UpdateFaculty - updateFaculty"))
+func (r *mutationResolver) UpdateFaculty(ctx context.Context, id string, input model.UpdateFacultyInput) (*models.Faculty, error) {
+	return r.updateFaculty(ctx, id, input)
 }
 
 // DeleteFaculty is the resolver for the deleteFaculty field.
@@ -337,14 +899,24 @@ func (r *mutationResolver) DeleteFaculty(ctx context.Context, id string) (bool,
 	panic(fmt.Errorf("not implemented: DeleteFaculty - deleteFaculty"))
 }
 
+// ActivateFaculty is the resolver for the activateFaculty field.
+func (r *mutationResolver) ActivateFaculty(ctx context.Context, id string) (*models.Faculty, error) {
+	return r.setFacultyActive(ctx, id, true, false)
+}
+
+// DeactivateFaculty is the resolver for the deactivateFaculty field.
+func (r *mutationResolver) DeactivateFaculty(ctx context.Context, id string, force *bool) (*models.Faculty, error) {
+	return r.setFacultyActive(ctx, id, false, force != nil && *force)
+}
+
 // CreateDepartment is the resolver for the createDepartment field.
 func (r *mutationResolver) CreateDepartment(ctx context.Context, input model.CreateDepartmentInput) (*models.Department, error) {
-	panic(fmt.Errorf("not implemented: CreateDepartment - createDepartment"))
+	return r.createDepartment(ctx, input)
 }
 
 // UpdateDepartment is the resolver for the updateDepartment field.
 func (r *mutationResolver) UpdateDepartment(ctx context.Context, id string, input model.UpdateDepartmentInput) (*models.Department, error) {
-	panic(fmt.Errorf("not implemented: UpdateDepartment - updateDepartment"))
+	return r.updateDepartment(ctx, id, input)
 }
 
 // DeleteDepartment is the resolver for the deleteDepartment field.
@@ -352,6 +924,16 @@ func (r *mutationResolver) DeleteDepartment(ctx context.Context, id string) (boo
 	panic(fmt.Errorf("not implemented: DeleteDepartment - deleteDepartment"))
 }
 
+// ActivateDepartment is the resolver for the activateDepartment field.
+func (r *mutationResolver) ActivateDepartment(ctx context.Context, id string) (*models.Department, error) {
+	return r.setDepartmentActive(ctx, id, true)
+}
+
+// DeactivateDepartment is the resolver for the deactivateDepartment field.
+func (r *mutationResolver) DeactivateDepartment(ctx context.Context, id string) (*models.Department, error) {
+	return r.setDepartmentActive(ctx, id, false)
+}
+
 // CreateSubscription is the resolver for the createSubscription field.
 func (r *mutationResolver) CreateSubscription(ctx context.Context, input model.CreateSubscriptionInput) (*model.FacultySubscription, error) {
 	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
@@ -361,7 +943,7 @@ func (r *mutationResolver) CreateSubscription(ctx context.Context, input model.C
 
 	facultyID, err := strconv.ParseUint(input.FacultyID, 10, 32)
 	if err != nil {
-		return nil, fmt.Errorf("invalid faculty ID")
+		return nil, errcode.Validation("invalid faculty ID")
 	}
 
 	subscription := models.Subscription{
@@ -382,7 +964,69 @@ func (r *mutationResolver) CreateSubscription(ctx context.Context, input model.C
 
 // UpdateSubscription is the resolver for the updateSubscription field.
 func (r *mutationResolver) UpdateSubscription(ctx context.Context, id string, input model.UpdateSubscriptionInput) (*model.FacultySubscription, error) {
-	panic(fmt.Errorf("not implemented: UpdateSubscription - updateSubscription"))
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid subscription ID")
+	}
+
+	var subscription models.Subscription
+	if err := r.DB.First(&subscription, subscriptionID).Error; err != nil {
+		return nil, errcode.NotFound("subscription not found")
+	}
+
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != subscription.Version {
+		return nil, versionConflictError(ctx, "subscription", subscription.Version)
+	}
+
+	updates := map[string]interface{}{}
+	if input.Type != nil {
+		updates["type"] = *input.Type
+	}
+	if input.Status != nil {
+		updates["status"] = *input.Status
+	}
+	startDate, endDate := subscription.StartDate, subscription.EndDate
+	if input.StartDate != nil {
+		startDate = *input.StartDate
+		updates["start_date"] = startDate
+	}
+	if input.EndDate != nil {
+		endDate = *input.EndDate
+		updates["end_date"] = endDate
+	}
+	if !endDate.After(startDate) {
+		return nil, errcode.Validation("end date must be after start date")
+	}
+
+	if len(updates) > 0 {
+		details := map[string]interface{}{"faculty_id": subscription.FacultyID}
+		for field, value := range updates {
+			details[field] = value
+		}
+		// A later end date lets expiry warnings fire again
+		if endDate.After(subscription.EndDate) {
+			updates["expiry_warning_days"] = nil
+		}
+		conflicted, err := updateVersioned(r.DB.DB, &subscription, input.ExpectedVersion, updates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update subscription")
+		}
+		if conflicted {
+			var current models.Subscription
+			r.DB.Select("version").First(&current, subscription.ID)
+			return nil, versionConflictError(ctx, "subscription", current.Version)
+		}
+
+		r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceSubscription, id, details)
+	}
+
+	r.DB.Preload("Faculty").First(&subscription, subscription.ID)
+	return convertSubscriptionToGraphQL(&subscription), nil
 }
 
 // DeleteSubscription is the resolver for the deleteSubscription field.
@@ -390,362 +1034,1833 @@ func (r *mutationResolver) DeleteSubscription(ctx context.Context, id string) (b
 	panic(fmt.Errorf("not implemented: DeleteSubscription - deleteSubscription"))
 }
 
-// AssignFacultyAdmin is the resolver for the assignFacultyAdmin field.
-func (r *mutationResolver) AssignFacultyAdmin(ctx context.Context, userID string, facultyID string) (*models.User, error) {
-	panic(fmt.Errorf("not implemented: AssignFacultyAdmin - assignFacultyAdmin"))
-}
+// RenewSubscription is the resolver for the renewSubscription field.
+func (r *mutationResolver) RenewSubscription(ctx context.Context, subscriptionID string, newEndDate time.Time) (*model.FacultySubscription, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
 
-// AssignRegularAdmin is the resolver for the assignRegularAdmin field.
-func (r *mutationResolver) AssignRegularAdmin(ctx context.Context, userID string, facultyID string, departmentID *string) (*models.User, error) {
-	panic(fmt.Errorf("not implemented: AssignRegularAdmin - assignRegularAdmin"))
-}
+	id, err := strconv.ParseUint(subscriptionID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid subscription ID")
+	}
+
+	var subscription models.Subscription
+	if err := r.DB.First(&subscription, id).Error; err != nil {
+		return nil, errcode.NotFound("subscription not found")
+	}
+
+	authCtx, err := r.requireFacultyScope(ctx, &subscription.FacultyID, audit.ResourceSubscription, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !newEndDate.After(subscription.EndDate) {
+		return nil, errcode.Validation("new end date must be after the current end date")
+	}
+
+	oldEndDate, oldStatus := subscription.EndDate, subscription.Status
+
+	// Resetting the notification flags lets expiry warnings fire again for the new end date
+	updates := map[string]interface{}{
+		"end_date":                 newEndDate,
+		"notification_sent_7_days": false,
+		"notification_sent_1_day":  false,
+		"last_notification_at":     nil,
+		"expiry_warning_days":      nil,
+	}
+	if subscription.Status == models.SubscriptionStatusExpired {
+		updates["status"] = models.SubscriptionStatusActive
+	}
+
+	conflicted, err := updateVersioned(r.DB.DB, &subscription, &subscription.Version, updates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew subscription")
+	}
+	if conflicted {
+		var current models.Subscription
+		r.DB.Select("version").First(&current, subscription.ID)
+		return nil, versionConflictError(ctx, "subscription", current.Version)
+	}
+
+	r.DB.Preload("Faculty").First(&subscription, subscription.ID)
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceSubscription, subscriptionID, map[string]interface{}{
+		"action":       "renew",
+		"faculty_id":   subscription.FacultyID,
+		"old_end_date": oldEndDate,
+		"new_end_date": subscription.EndDate,
+		"old_status":   oldStatus,
+		"new_status":   subscription.Status,
+	})
+
+	if r.EventPublisher != nil {
+		if err := r.EventPublisher.PublishSubscriptionWarning(&subscription, "renewed", &services.EventContext{
+			UserID:    &authCtx.UserID,
+			FacultyID: &subscription.FacultyID,
+			Source:    "renew_subscription",
+		}); err != nil {
+			log.Printf("Failed to publish renewal of subscription %d: %v", subscription.ID, err)
+		}
+	}
+
+	return convertSubscriptionToGraphQL(&subscription), nil
+}
+
+// AssignFacultyAdmin is the resolver for the assignFacultyAdmin field.
+func (r *mutationResolver) AssignFacultyAdmin(ctx context.Context, userID string, facultyID string) (*models.User, error) {
+	panic(fmt.Errorf("not implemented: AssignFacultyAdmin - assignFacultyAdmin"))
+}
+
+// AssignRegularAdmin is the resolver for the assignRegularAdmin field.
+func (r *mutationResolver) AssignRegularAdmin(ctx context.Context, userID string, facultyID string, departmentID *string) (*models.User, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	fID, err := strconv.ParseUint(facultyID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid faculty ID")
+	}
+	facultyIDUint := uint(fID)
+
+	if _, err := r.requireFacultyScope(ctx, &facultyIDUint, audit.ResourceUser, userID); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := r.DB.First(&user, uID).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+
+	// The promoted user must already belong to the admin's faculty
+	if user.FacultyID != nil {
+		if _, err := r.requireFacultyScope(ctx, user.FacultyID, audit.ResourceUser, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Role == models.UserRoleSuperAdmin || user.Role == models.UserRoleFacultyAdmin {
+		return nil, errcode.Conflict("user already has a higher admin role")
+	}
+
+	updates := map[string]interface{}{
+		"role":       models.UserRoleRegularAdmin,
+		"faculty_id": facultyIDUint,
+	}
+	if departmentID != nil {
+		department, err := r.loadDepartment(ctx, *departmentID)
+		if err != nil {
+			return nil, err
+		}
+		if department.FacultyID != facultyIDUint {
+			return nil, errcode.Validation("department does not belong to the faculty")
+		}
+		updates["department_id"] = department.ID
+	}
+
+	if err := r.DB.Model(&user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign regular admin")
+	}
+	r.invalidateUserCache(ctx, user.ID)
+
+	r.DB.Preload("Faculty").Preload("Department").First(&user, user.ID)
+	return convertUserToGraphQL(&user), nil
+}
+
+// RemoveAdminRole is the resolver for the removeAdminRole field.
+func (r *mutationResolver) RemoveAdminRole(ctx context.Context, userID string) (*models.User, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	var user models.User
+	if err := r.DB.First(&user, uID).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, user.FacultyID, audit.ResourceUser, userID); err != nil {
+		return nil, err
+	}
+
+	// Faculty admins may only demote regular admins
+	if authCtx.Role != models.UserRoleSuperAdmin && user.Role != models.UserRoleRegularAdmin {
+		return nil, errcode.Forbidden("permission denied")
+	}
+
+	if err := r.DB.Model(&user).Update("role", models.UserRoleStudent).Error; err != nil {
+		return nil, fmt.Errorf("failed to remove admin role")
+	}
+	r.invalidateUserCache(ctx, user.ID)
+
+	r.DB.Preload("Faculty").Preload("Department").First(&user, user.ID)
+	return convertUserToGraphQL(&user), nil
+}
+
+// DeactivateUser is the resolver for the deactivateUser field.
+func (r *mutationResolver) DeactivateUser(ctx context.Context, userID string) (bool, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid user ID")
+	}
+
+	if uint(uID) == authCtx.UserID {
+		return false, errcode.Validation("cannot deactivate your own account")
+	}
+
+	var user models.User
+	if err := r.DB.First(&user, uID).Error; err != nil {
+		return false, errcode.NotFound("user not found")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, user.FacultyID, audit.ResourceUser, userID); err != nil {
+		return false, err
+	}
+
+	// Faculty admins may not deactivate other faculty or super admins
+	if authCtx.Role != models.UserRoleSuperAdmin && (user.Role == models.UserRoleSuperAdmin || user.Role == models.UserRoleFacultyAdmin) {
+		return false, errcode.Forbidden("permission denied")
+	}
+
+	// Soft-deleted users are excluded from login and token authentication
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to deactivate user")
+	}
+	r.invalidateUserCache(ctx, user.ID)
+
+	// Deactivation also severs the user's live subscriptions and streams
+	closed, err := r.signOutEverywhere(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions for user %d: %v", user.ID, err)
+	}
+
+	r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceUser, userID, map[string]interface{}{
+		"email":                    user.Email,
+		"role":                     string(user.Role),
+		"local_connections_closed": closed,
+	})
+
+	return true, nil
+}
+
+// TerminateUserSessions is the resolver for the terminateUserSessions field.
+func (r *mutationResolver) TerminateUserSessions(ctx context.Context, userID string) (bool, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid user ID")
+	}
+
+	// Deactivated users are included, their connections may outlive the deactivation
+	var user models.User
+	if err := r.DB.Unscoped().First(&user, uID).Error; err != nil {
+		return false, errcode.NotFound("user not found")
+	}
+
+	closed, err := r.signOutEverywhere(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions for user %d: %v", user.ID, err)
+		return false, fmt.Errorf("failed to terminate sessions")
+	}
+
+	r.logAdminAction(ctx, audit.ActionTerminateSessions, audit.ResourceUser, userID, map[string]interface{}{
+		"email":                    user.Email,
+		"role":                     string(user.Role),
+		"local_connections_closed": closed,
+	})
+
+	return true, nil
+}
+
+// ReactivateUser is the resolver for the reactivateUser field.
+func (r *mutationResolver) ReactivateUser(ctx context.Context, userID string) (*models.User, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	var user models.User
+	if err := r.DB.Unscoped().First(&user, uID).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+
+	if !user.DeletedAt.Valid && user.IsActive {
+		return nil, errcode.Conflict("user is already active")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, user.FacultyID, audit.ResourceUser, userID); err != nil {
+		return nil, err
+	}
+
+	if authCtx.Role != models.UserRoleSuperAdmin && (user.Role == models.UserRoleSuperAdmin || user.Role == models.UserRoleFacultyAdmin) {
+		return nil, errcode.Forbidden("permission denied")
+	}
+
+	if err := r.DB.Unscoped().Model(&user).Updates(map[string]interface{}{
+		"is_active":  true,
+		"deleted_at": nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to reactivate user")
+	}
+	r.invalidateUserCache(ctx, user.ID)
+
+	r.logAdminAction(ctx, audit.ActionRestore, audit.ResourceUser, userID, map[string]interface{}{
+		"email": user.Email,
+		"role":  string(user.Role),
+	})
+
+	r.DB.Preload("Faculty").Preload("Department").First(&user, user.ID)
+	return convertUserToGraphQL(&user), nil
+}
+
+// UpdateUserRole is the resolver for the updateUserRole field.
+func (r *mutationResolver) UpdateUserRole(ctx context.Context, userID string, role models.UserRole, facultyID *string) (*models.User, error) {
+	return r.updateUserRole(ctx, userID, role, facultyID)
+}
+
+// ImportUsers is the resolver for the importUsers field.
+func (r *mutationResolver) ImportUsers(ctx context.Context, facultyID string, departmentID *string, file graphql.Upload) (*model.UserImportResult, error) {
+	return r.importUsers(ctx, facultyID, departmentID, file)
+}
+
+// CreateActivityTemplate is the resolver for the createActivityTemplate field.
+func (r *mutationResolver) CreateActivityTemplate(ctx context.Context, input model.CreateActivityTemplateInput) (*models.ActivityTemplate, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var facultyID *uint
+	if input.FacultyID != nil {
+		id, err := strconv.ParseUint(*input.FacultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(id)
+		facultyID = &facultyIDUint
+	} else if authCtx.Role != models.UserRoleSuperAdmin {
+		// Faculty admins always create templates in their own faculty; shared templates are super admin only
+		facultyID = authCtx.FacultyID
+	}
+
+	if _, err := r.requireFacultyScope(ctx, facultyID, audit.ResourceActivityTemplate, ""); err != nil {
+		return nil, err
+	}
+
+	activityType, err := parseActivityType(input.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	template := models.ActivityTemplate{
+		Name:            strings.TrimSpace(input.Name),
+		Type:            activityType,
+		DefaultDuration: input.DefaultDuration,
+		MaxParticipants: input.MaxParticipants,
+		RequireApproval: input.RequireApproval,
+		Points:          input.Points,
+		QRCodeRequired:  true,
+		FacultyID:       facultyID,
+		CreatedByID:     authCtx.User.ID,
+		IsActive:        true,
+	}
+	if input.Description != nil {
+		template.Description = *input.Description
+	}
+	if input.Location != nil {
+		template.Location = *input.Location
+	}
+	if input.QRCodeRequired != nil {
+		template.QRCodeRequired = *input.QRCodeRequired
+	}
+	if input.AutoApprove != nil {
+		template.AutoApprove = *input.AutoApprove
+	}
+
+	if err := r.validateActivityTemplate(&template); err != nil {
+		return nil, err
+	}
+
+	qrCodeRequired := template.QRCodeRequired
+	err = r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+		template.ID = 0
+		template.QRCodeRequired = qrCodeRequired
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+
+		// GORM writes the column default in place of a false value, so store an opt-out explicitly
+		if !qrCodeRequired {
+			template.QRCodeRequired = false
+			if err := tx.Model(&template).Update("qr_code_required", false).Error; err != nil {
+				return err
+			}
+		}
+
+		after.Do(func() {
+			r.logAdminAction(ctx, audit.ActionCreate, audit.ResourceActivityTemplate, strconv.FormatUint(uint64(template.ID), 10), activityTemplateAuditDetails(&template))
+		})
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrRetriesExhausted) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create activity template")
+	}
+
+	created, err := r.loadActivityTemplate(ctx, template.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load activity template")
+	}
+	return created, nil
+}
+
+// UpdateActivityTemplate is the resolver for the updateActivityTemplate field.
+func (r *mutationResolver) UpdateActivityTemplate(ctx context.Context, id string, input model.UpdateActivityTemplateInput) (*models.ActivityTemplate, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	templateID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid template ID")
+	}
+
+	var template models.ActivityTemplate
+	if err := r.DB.WithContext(ctx).First(&template, templateID).Error; err != nil {
+		return nil, errcode.NotFound("activity template not found")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, template.FacultyID, audit.ResourceActivityTemplate, id); err != nil {
+		return nil, err
+	}
+
+	// Apply the changes to a copy so the merged template is validated as a whole
+	updated := template
+	if input.Name != nil {
+		updated.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		updated.Description = *input.Description
+	}
+	if input.Type != nil {
+		updated.Type, err = parseActivityType(*input.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.DefaultDuration != nil {
+		updated.DefaultDuration = *input.DefaultDuration
+	}
+	if input.Location != nil {
+		updated.Location = *input.Location
+	}
+	if input.MaxParticipants != nil {
+		updated.MaxParticipants = input.MaxParticipants
+	}
+	if input.RequireApproval != nil {
+		updated.RequireApproval = *input.RequireApproval
+	}
+	if input.Points != nil {
+		updated.Points = *input.Points
+	}
+	if input.QRCodeRequired != nil {
+		updated.QRCodeRequired = *input.QRCodeRequired
+	}
+	if input.AutoApprove != nil {
+		updated.AutoApprove = *input.AutoApprove
+	}
+	if input.IsActive != nil {
+		updated.IsActive = *input.IsActive
+	}
+
+	if err := r.validateActivityTemplate(&updated); err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.WithContext(ctx).Model(&template).Updates(map[string]interface{}{
+		"name":             updated.Name,
+		"description":      updated.Description,
+		"type":             updated.Type,
+		"default_duration": updated.DefaultDuration,
+		"location":         updated.Location,
+		"max_participants": updated.MaxParticipants,
+		"require_approval": updated.RequireApproval,
+		"points":           updated.Points,
+		"qr_code_required": updated.QRCodeRequired,
+		"auto_approve":     updated.AutoApprove,
+		"is_active":        updated.IsActive,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update activity template")
+	}
+
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceActivityTemplate, id, activityTemplateAuditDetails(&updated))
+
+	result, err := r.loadActivityTemplate(ctx, template.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load activity template")
+	}
+	return result, nil
+}
+
+// DeleteActivityTemplate is the resolver for the deleteActivityTemplate field.
+func (r *mutationResolver) DeleteActivityTemplate(ctx context.Context, id string) (bool, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return false, err
+	}
+
+	templateID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid template ID")
+	}
+
+	var template models.ActivityTemplate
+	if err := r.DB.WithContext(ctx).First(&template, templateID).Error; err != nil {
+		return false, errcode.NotFound("activity template not found")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, template.FacultyID, audit.ResourceActivityTemplate, id); err != nil {
+		return false, err
+	}
+
+	// Activities keep pointing at their template, so one still in use can only be deactivated
+	var activities int64
+	if err := r.DB.WithContext(ctx).Model(&models.Activity{}).Where("template_id = ?", template.ID).Count(&activities).Error; err != nil {
+		return false, fmt.Errorf("failed to check template usage")
+	}
+	if activities > 0 {
+		return false, errcode.Conflict("template is used by %d activities, deactivate it instead", activities)
+	}
+
+	if err := r.DB.WithContext(ctx).Delete(&template).Error; err != nil {
+		return false, fmt.Errorf("failed to delete activity template")
+	}
+
+	r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceActivityTemplate, id, activityTemplateAuditDetails(&template))
+
+	return true, nil
+}
+
+// AssignActivity is the resolver for the assignActivity field.
+func (r *mutationResolver) AssignActivity(ctx context.Context, input model.CreateActivityAssignmentInput) (*models.ActivityAssignment, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	return r.assignAdmin(ctx, input.ActivityID, input.AdminID, input.CanScanQR, input.CanApprove, input.Notes)
+}
+
+// AssignAdminToActivity is the resolver for the assignAdminToActivity field.
+func (r *mutationResolver) AssignAdminToActivity(ctx context.Context, activityID string, adminUserID string) (*models.ActivityAssignment, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	return r.assignAdmin(ctx, activityID, adminUserID, nil, nil, nil)
+}
+
+// UnassignAdminFromActivity is the resolver for the unassignAdminFromActivity field.
+func (r *mutationResolver) UnassignAdminFromActivity(ctx context.Context, activityID string, adminUserID string) (bool, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return false, err
+	}
+
+	actID, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid activity ID")
+	}
+	aID, err := strconv.ParseUint(adminUserID, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid admin ID")
+	}
+
+	var assignment models.ActivityAssignment
+	if err := r.DB.Where("activity_id = ? AND admin_id = ?", actID, aID).First(&assignment).Error; err != nil {
+		return false, errcode.NotFound("assignment not found")
+	}
+
+	if err := r.unassignAdmin(ctx, &assignment); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateActivityAssignment is the resolver for the updateActivityAssignment field.
+func (r *mutationResolver) UpdateActivityAssignment(ctx context.Context, id string, input model.UpdateActivityAssignmentInput) (*models.ActivityAssignment, error) {
+	panic(fmt.Errorf("not implemented: UpdateActivityAssignment - updateActivityAssignment"))
+}
+
+// RemoveActivityAssignment is the resolver for the removeActivityAssignment field.
+func (r *mutationResolver) RemoveActivityAssignment(ctx context.Context, id string) (bool, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return false, err
+	}
+
+	assignmentID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return false, errcode.Validation("invalid assignment ID")
+	}
+
+	var assignment models.ActivityAssignment
+	if err := r.DB.First(&assignment, assignmentID).Error; err != nil {
+		return false, errcode.NotFound("assignment not found")
+	}
+
+	if err := r.unassignAdmin(ctx, &assignment); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ScanQRCode is the resolver for the scanQRCode field.
+func (r *mutationResolver) ScanQRCode(ctx context.Context, input model.QRScanInput) (*model.QRScanResult, error) {
+	return r.scanQRCode(ctx, input)
+}
+
+// ReconcileOfflineScans is the resolver for the reconcileOfflineScans field.
+func (r *mutationResolver) ReconcileOfflineScans(ctx context.Context, activityID string, scans []*model.OfflineScanInput) ([]*model.OfflineScanReconciliation, error) {
+	return r.reconcileOfflineScans(ctx, activityID, scans)
+}
+
+// MarkAttendanceManual is the resolver for the markAttendanceManual field.
+func (r *mutationResolver) MarkAttendanceManual(ctx context.Context, activityID string, userID string, note string) (*models.Participation, error) {
+	return r.markAttendanceManual(ctx, activityID, userID, note)
+}
+
+// IssueScannerToken is the resolver for the issueScannerToken field.
+func (r *mutationResolver) IssueScannerToken(ctx context.Context, activityID string, ttlMinutes *int) (*model.ScannerToken, error) {
+	return r.issueScannerToken(ctx, activityID, ttlMinutes)
+}
+
+// RevokeScannerToken is the resolver for the revokeScannerToken field.
+func (r *mutationResolver) RevokeScannerToken(ctx context.Context, id string) (bool, error) {
+	return r.revokeScannerToken(ctx, id)
+}
+
+// MyQRCode is the resolver for the myQRCode field.
+func (r *mutationResolver) MyQRCode(ctx context.Context) (*model.MyQRCode, error) {
+	return r.myQRCode(ctx)
+}
+
+// RefreshMyQRSecret is the resolver for the refreshMyQRSecret field.
+func (r *mutationResolver) RefreshMyQRSecret(ctx context.Context) (*model.QRData, error) {
+	panic(fmt.Errorf("not implemented: RefreshMyQRSecret - refreshMyQRSecret"))
+}
+
+// RefreshUserQRSecret is the resolver for the refreshUserQRSecret field.
+func (r *mutationResolver) RefreshUserQRSecret(ctx context.Context, userID string) (*model.QRData, error) {
+	panic(fmt.Errorf("not implemented: RefreshUserQRSecret - refreshUserQRSecret"))
+}
+
+// GenerateActivityQRCodes is the resolver for the generateActivityQRCodes field.
+func (r *mutationResolver) GenerateActivityQRCodes(ctx context.Context, activityID string) (*model.ActivityQRBatch, error) {
+	id, err := strconv.ParseUint(activityID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid activity ID")
+	}
+
+	codes, failures, expiresAt, err := r.GenerateQRStringsForActivity(ctx, uint(id))
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &model.ActivityQRBatch{
+		Codes:     make([]*model.StudentQRCode, 0, len(codes)),
+		Failures:  make([]*model.StudentQRFailure, 0, len(failures)),
+		ExpiresAt: expiresAt,
+	}
+	for studentID, qrString := range codes {
+		batch.Codes = append(batch.Codes, &model.StudentQRCode{StudentID: studentID, QRString: qrString})
+	}
+	for studentID, err := range failures {
+		batch.Failures = append(batch.Failures, &model.StudentQRFailure{StudentID: studentID, Error: err.Error()})
+	}
+
+	// Badges print in student ID order
+	sort.Slice(batch.Codes, func(i, j int) bool { return batch.Codes[i].StudentID < batch.Codes[j].StudentID })
+	sort.Slice(batch.Failures, func(i, j int) bool { return batch.Failures[i].StudentID < batch.Failures[j].StudentID })
+
+	return batch, nil
+}
+
+// RevokeQRCode is the resolver for the revokeQRCode field.
+func (r *mutationResolver) RevokeQRCode(ctx context.Context, signature string, reason string, regenerateSecret *bool) (*model.QRRevocation, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject malformed input before it reaches Redis
+	signature = strings.ToLower(strings.TrimSpace(signature))
+	if !security.ValidQRSignature(signature) {
+		return nil, errcode.Validation("invalid QR signature")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > 500 {
+		return nil, errcode.Validation("reason must be between 1 and 500 characters")
+	}
+	if r.QRSecurity == nil {
+		return nil, fmt.Errorf("qr codes are not available")
+	}
+
+	studentID, err := r.QRSecurity.QROwner(ctx, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up QR code")
+	}
+
+	if authCtx.User.IsAdmin() {
+		// Codes whose owner is no longer known can only be revoked by super admins
+		var facultyID *uint
+		if studentID != "" {
+			var owner models.User
+			if err := r.DB.Where("student_id = ?", studentID).First(&owner).Error; err == nil {
+				facultyID = owner.FacultyID
+			}
+		}
+		if _, err := r.requireFacultyScope(ctx, facultyID, audit.ResourceQRCode, signature); err != nil {
+			return nil, err
+		}
+	} else if studentID == "" || studentID != authCtx.User.StudentID {
+		return nil, errcode.Forbidden("permission denied")
+	}
+
+	revokedUntil, err := r.QRSecurity.BlacklistQR(ctx, signature, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke QR code")
+	}
+
+	// A new secret makes every other outstanding code of the student invalid too
+	regenerated := false
+	if regenerateSecret != nil && *regenerateSecret && studentID != "" {
+		if err := r.QRSecurity.RegenerateUserSecret(ctx, studentID); err != nil {
+			log.Printf("Failed to regenerate QR secret for student %s: %v", studentID, err)
+		} else {
+			regenerated = true
+		}
+	}
+
+	r.logSecurityEvent(ctx, &audit.SecurityEvent{
+		EventType: audit.SecurityEventQRTampering,
+		UserID:    strconv.FormatUint(uint64(authCtx.UserID), 10),
+		RiskLevel: audit.RiskLevelMedium,
+		Blocked:   true,
+		Details: map[string]interface{}{
+			"action":             "revoke",
+			"signature":          signature,
+			"student_id":         studentID,
+			"reason":             reason,
+			"revoked_until":      revokedUntil,
+			"secret_regenerated": regenerated,
+		},
+	})
+
+	return &model.QRRevocation{
+		Signature:         signature,
+		RevokedUntil:      revokedUntil,
+		SecretRegenerated: regenerated,
+	}, nil
+}
+
+// SetFeatureFlag is the resolver for the setFeatureFlag field.
+func (r *mutationResolver) SetFeatureFlag(ctx context.Context, input model.SetFeatureFlagInput) (*models.FeatureFlag, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if r.FeatureFlagService == nil {
+		return nil, fmt.Errorf("feature flags are not available")
+	}
+
+	facultyID, err := parseOptionalFacultyID(input.FacultyID)
+	if err != nil {
+		return nil, err
+	}
+	if facultyID != nil {
+		if err := r.DB.First(&models.Faculty{}, *facultyID).Error; err != nil {
+			return nil, errcode.NotFound("faculty not found")
+		}
+	}
+
+	flag, err := r.FeatureFlagService.Set(ctx, facultyID, input.Name, input.Enabled, input.RolloutPercentage, authCtx.UserID)
+	if errors.Is(err, services.ErrUnknownFeatureFlag) {
+		return nil, errcode.Validation("unknown feature flag: %s", input.Name)
+	}
+	if errors.Is(err, services.ErrInvalidRolloutPercentage) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set feature flag")
+	}
+
+	details := map[string]interface{}{
+		"name":    flag.Name,
+		"enabled": flag.Enabled,
+	}
+	if flag.FacultyID != nil {
+		details["faculty_id"] = *flag.FacultyID
+	}
+	if flag.RolloutPercentage != nil {
+		details["rollout_percentage"] = *flag.RolloutPercentage
+	}
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceFeatureFlag, strconv.FormatUint(uint64(flag.ID), 10), details)
+
+	return flag, nil
+}
+
+// ClearFeatureFlag is the resolver for the clearFeatureFlag field.
+func (r *mutationResolver) ClearFeatureFlag(ctx context.Context, name string, facultyID *string) (bool, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return false, err
+	}
+	if r.FeatureFlagService == nil {
+		return false, fmt.Errorf("feature flags are not available")
+	}
+
+	targetFacultyID, err := parseOptionalFacultyID(facultyID)
+	if err != nil {
+		return false, err
+	}
+
+	cleared, err := r.FeatureFlagService.Clear(ctx, targetFacultyID, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear feature flag")
+	}
+
+	if cleared {
+		details := map[string]interface{}{"name": name}
+		if targetFacultyID != nil {
+			details["faculty_id"] = *targetFacultyID
+		}
+		r.logAdminAction(ctx, audit.ActionDelete, audit.ResourceFeatureFlag, name, details)
+	}
+
+	return cleared, nil
+}
+
+// RebuildSearchIndex is the resolver for the rebuildSearchIndex field.
+func (r *mutationResolver) RebuildSearchIndex(ctx context.Context) (*model.MaintenanceJob, error) {
+	return r.rebuildSearchIndex(ctx)
+}
+
+// RecomputeMetrics is the resolver for the recomputeMetrics field.
+func (r *mutationResolver) RecomputeMetrics(ctx context.Context, date time.Time) (*model.MaintenanceJob, error) {
+	return r.recomputeMetrics(ctx, date)
+}
+
+// ID is the resolver for the id field.
+func (r *notificationLogResolver) ID(ctx context.Context, obj *models.NotificationLog) (string, error) {
+	panic(fmt.Errorf("not implemented: ID - id"))
+}
+
+// Subscription is the resolver for the subscription field.
+func (r *notificationLogResolver) Subscription(ctx context.Context, obj *models.NotificationLog) (*model.FacultySubscription, error) {
+	panic(fmt.Errorf("not implemented: Subscription - subscription"))
+}
+
+// Type is the resolver for the type field.
+func (r *notificationLogResolver) Type(ctx context.Context, obj *models.NotificationLog) (string, error) {
+	panic(fmt.Errorf("not implemented: Type - type"))
+}
+
+// Status is the resolver for the status field.
+func (r *notificationLogResolver) Status(ctx context.Context, obj *models.NotificationLog) (string, error) {
+	panic(fmt.Errorf("not implemented: Status - status"))
+}
+
+// ID is the resolver for the id field.
+func (r *participationResolver) ID(ctx context.Context, obj *models.Participation) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// ID is the resolver for the id field.
+func (r *qRScanLogResolver) ID(ctx context.Context, obj *models.QRScanLog) (string, error) {
+	panic(fmt.Errorf("not implemented: ID - id"))
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The auth extension loaded the user row for this request; only its faculty and
+	// department may come from token claims, carrying just the ID, code and name
+	user := *authCtx.User
+	if user.FacultyID != nil {
+		var faculty models.Faculty
+		if err := r.DB.First(&faculty, *user.FacultyID).Error; err == nil {
+			user.Faculty = &faculty
+		}
+	}
+	if user.DepartmentID != nil {
+		var department models.Department
+		if err := r.DB.First(&department, *user.DepartmentID).Error; err == nil {
+			user.Department = &department
+		}
+	}
+	return convertUserToGraphQL(&user), nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, limit *int, offset *int, departmentID *string, includeDeleted *bool) ([]*models.User, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.Model(&models.User{}).Preload("Faculty").Preload("Department")
+	if includeDeleted != nil && *includeDeleted {
+		query = query.Unscoped()
+	}
+
+	// Apply faculty and department filtering for non-super admins
+	query = middleware.FilterByFaculty(ctx, query, "faculty_id")
+	query = middleware.FilterByDepartment(ctx, query, "department_id")
+
+	if departmentID != nil {
+		dID, err := strconv.ParseUint(*departmentID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid department ID")
+		}
+		query = query.Where("department_id = ?", dID)
+	}
+
+	if offset != nil {
+		query = query.Offset(*offset)
+	}
+	rowBudget := middleware.RowBudgetFrom(ctx)
+	if limit := rowBudget.Limit(limit); limit != nil {
+		query = query.Limit(*limit)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users")
+	}
+	users = users[:rowBudget.Take(len(users))]
+
+	result := make([]*models.User, len(users))
+	for i, user := range users {
+		result[i] = convertUserToGraphQL(&user)
+	}
+	return result, nil
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*models.User, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	user := r.getCachedUser(ctx, uint(userID))
+	if user == nil {
+		user = &models.User{}
+		if err := r.DB.Preload("Faculty").Preload("Department").First(user, userID).Error; err != nil {
+			return nil, errcode.NotFound("user not found")
+		}
+		r.cacheUser(ctx, user)
+	}
+
+	// Check permission to view user
+	if !authCtx.User.CanViewUser(user) {
+		return nil, errcode.Forbidden("permission denied")
+	}
+
+	// QR secrets are only returned to their owner, whether or not the profile was cached
+	if user.ID == authCtx.UserID {
+		user.QRSecret = authCtx.User.QRSecret
+	} else {
+		user.QRSecret = ""
+	}
+
+	return convertUserToGraphQL(user), nil
+}
+
+// Faculties is the resolver for the faculties field.
+func (r *queryResolver) Faculties(ctx context.Context) ([]*models.Faculty, error) {
+	_, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var faculties []models.Faculty
+	if err := r.DB.Where("is_active = ?", true).Find(&faculties).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch faculties")
+	}
+
+	result := make([]*models.Faculty, len(faculties))
+	for i, faculty := range faculties {
+		result[i] = convertFacultyToGraphQL(&faculty)
+	}
+	return result, nil
+}
+
+// Faculty is the resolver for the faculty field.
+func (r *queryResolver) Faculty(ctx context.Context, id string) (*models.Faculty, error) {
+	_, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facultyID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid faculty ID")
+	}
+
+	var faculty models.Faculty
+	if err := r.DB.First(&faculty, facultyID).Error; err != nil {
+		return nil, errcode.NotFound("faculty not found")
+	}
+
+	return convertFacultyToGraphQL(&faculty), nil
+}
+
+// Departments is the resolver for the departments field.
+func (r *queryResolver) Departments(ctx context.Context, facultyID *string) ([]*models.Department, error) {
+	_, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.Model(&models.Department{}).Preload("Faculty").Where("is_active = ?", true)
+
+	// Faculty members only see departments within their faculty
+	query = middleware.FilterByFaculty(ctx, query, "faculty_id")
+
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		query = query.Where("faculty_id = ?", fID)
+	}
+
+	var departments []models.Department
+	if err := query.Order("name").Find(&departments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch departments")
+	}
+
+	result := make([]*models.Department, len(departments))
+	for i := range departments {
+		result[i] = convertDepartmentToGraphQL(&departments[i])
+	}
+	return result, nil
+}
+
+// Department is the resolver for the department field.
+func (r *queryResolver) Department(ctx context.Context, id string) (*models.Department, error) {
+	_, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-// RemoveAdminRole is the resolver for the removeAdminRole field.
-func (r *mutationResolver) RemoveAdminRole(ctx context.Context, userID string) (*models.User, error) {
-	panic(fmt.Errorf("not implemented: RemoveAdminRole - removeAdminRole"))
+	departmentID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid department ID")
+	}
+
+	query := middleware.FilterByFaculty(ctx, r.DB.Model(&models.Department{}).Preload("Faculty"), "faculty_id")
+
+	var department models.Department
+	if err := query.First(&department, departmentID).Error; err != nil {
+		return nil, errcode.NotFound("department not found")
+	}
+
+	return convertDepartmentToGraphQL(&department), nil
 }
 
-// CreateActivityTemplate is the resolver for the createActivityTemplate field.
-func (r *mutationResolver) CreateActivityTemplate(ctx context.Context, input model.CreateActivityTemplateInput) (*models.ActivityTemplate, error) {
-	panic(fmt.Errorf("not implemented: CreateActivityTemplate - createActivityTemplate"))
+// Activities is the resolver for the activities field.
+func (r *queryResolver) Activities(ctx context.Context, limit *int, offset *int, facultyID *string, departmentID *string, status *models.ActivityStatus, includeArchived *bool, includeDeleted *bool) ([]*models.Activity, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.Model(&models.Activity{}).Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy")
+
+	// Deleted activities are only listed for admins
+	if includeDeleted != nil && *includeDeleted && authCtx.Role != models.UserRoleStudent {
+		query = query.Unscoped()
+	}
+
+	// Apply faculty filtering, and department filtering for department admins. Others aren't
+	// filtered by department, as activities co-hosted with their faculty may belong to another
+	// faculty's department.
+	query = middleware.FilterActivitiesByFaculty(ctx, query, "activities")
+	if authCtx.User.GetDepartmentScope() != nil {
+		query = middleware.FilterByDepartment(ctx, query, "activities.department_id")
+	}
+
+	if facultyID != nil {
+		fID, _ := strconv.ParseUint(*facultyID, 10, 32)
+		query = query.Where("activities.faculty_id = ? OR activities.id IN ("+models.ActivityCoHostSubquery+")", fID, fID)
+	}
+
+	if departmentID != nil {
+		dID, err := strconv.ParseUint(*departmentID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid department ID")
+		}
+		query = middleware.FilterByDepartment(ctx, query.Where("activities.department_id = ?", dID), "activities.department_id")
+	}
+
+	// Archived activities are kept for reporting but hidden from default feeds
+	query = filterActivityStatus(query, status, includeArchived)
+
+	if offset != nil {
+		query = query.Offset(*offset)
+	}
+	rowBudget := middleware.RowBudgetFrom(ctx)
+	if limit := rowBudget.Limit(limit); limit != nil {
+		query = query.Limit(*limit)
+	}
+
+	var activities []models.Activity
+	if err := query.Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch activities")
+	}
+	activities = activities[:rowBudget.Take(len(activities))]
+
+	result := make([]*models.Activity, len(activities))
+	for i, activity := range activities {
+		result[i] = convertActivityToGraphQL(&activity)
+	}
+	return result, nil
 }
 
-// UpdateActivityTemplate is the resolver for the updateActivityTemplate field.
-func (r *mutationResolver) UpdateActivityTemplate(ctx context.Context, id string, input model.UpdateActivityTemplateInput) (*models.ActivityTemplate, error) {
-	panic(fmt.Errorf("not implemented: UpdateActivityTemplate - updateActivityTemplate"))
+// SearchActivities is the resolver for the searchActivities field.
+func (r *queryResolver) SearchActivities(ctx context.Context, query string, limit *int, offset *int, facultyID *string) (*model.ActivitySearchPage, error) {
+	_, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageLimit, pageOffset := services.DefaultActivitySearchLimit, 0
+	if limit != nil && *limit > 0 && *limit <= services.MaxActivitySearchLimit {
+		pageLimit = *limit
+	}
+	if offset != nil && *offset > 0 {
+		pageOffset = *offset
+	}
+
+	// Same visibility as the activities feed
+	scope := middleware.FilterActivitiesByFaculty(ctx, r.DB.Model(&models.Activity{}), "activities")
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		scope = scope.Where("activities.faculty_id = ? OR activities.id IN ("+models.ActivityCoHostSubquery+")", fID, fID)
+	}
+	scope = scope.Where("activities.status <> ?", models.ActivityStatusArchived)
+
+	activities, total, err := services.SearchActivities(scope, query, pageLimit, pageOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search activities")
+	}
+
+	result := make([]*models.Activity, len(activities))
+	for i := range activities {
+		result[i] = convertActivityToGraphQL(&activities[i])
+	}
+	return &model.ActivitySearchPage{
+		Activities: result,
+		TotalCount: int(total),
+	}, nil
 }
 
-// DeleteActivityTemplate is the resolver for the deleteActivityTemplate field.
-func (r *mutationResolver) DeleteActivityTemplate(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeleteActivityTemplate - deleteActivityTemplate"))
+// Activity is the resolver for the activity field.
+func (r *queryResolver) Activity(ctx context.Context, id string) (*models.Activity, error) {
+	panic(fmt.Errorf("not implemented: Activity - activity"))
 }
 
-// AssignActivity is the resolver for the assignActivity field.
-func (r *mutationResolver) AssignActivity(ctx context.Context, input model.CreateActivityAssignmentInput) (*models.ActivityAssignment, error) {
-	panic(fmt.Errorf("not implemented: AssignActivity - assignActivity"))
+// MyActivities is the resolver for the myActivities field.
+func (r *queryResolver) MyActivities(ctx context.Context) ([]*models.Activity, error) {
+	panic(fmt.Errorf("not implemented: MyActivities - myActivities"))
 }
 
-// UpdateActivityAssignment is the resolver for the updateActivityAssignment field.
-func (r *mutationResolver) UpdateActivityAssignment(ctx context.Context, id string, input model.UpdateActivityAssignmentInput) (*models.ActivityAssignment, error) {
-	panic(fmt.Errorf("not implemented: UpdateActivityAssignment - updateActivityAssignment"))
+// UnstaffedActivities is the resolver for the unstaffedActivities field.
+func (r *queryResolver) UnstaffedActivities(ctx context.Context, facultyID *string) ([]*models.Activity, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetFacultyID *uint
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(fID)
+		targetFacultyID = &facultyIDUint
+	} else if authCtx.Role != models.UserRoleSuperAdmin {
+		// Faculty admins only see their own faculty
+		targetFacultyID = authCtx.FacultyID
+	}
+
+	if _, err := r.requireFacultyScope(ctx, targetFacultyID, audit.ResourceActivity, ""); err != nil {
+		return nil, err
+	}
+
+	activities, err := r.ActivityService.GetUnstaffedActivities(targetFacultyID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unstaffed activities")
+	}
+
+	result := make([]*models.Activity, len(activities))
+	for i := range activities {
+		result[i] = convertActivityToGraphQL(&activities[i])
+	}
+
+	return result, nil
 }
 
-// RemoveActivityAssignment is the resolver for the removeActivityAssignment field.
-func (r *mutationResolver) RemoveActivityAssignment(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: RemoveActivityAssignment - removeActivityAssignment"))
+// Participations is the resolver for the participations field.
+func (r *queryResolver) Participations(ctx context.Context, activityID *string, userID *string) ([]*models.Participation, error) {
+	panic(fmt.Errorf("not implemented: Participations - participations"))
 }
 
-// ScanQRCode is the resolver for the scanQRCode field.
-func (r *mutationResolver) ScanQRCode(ctx context.Context, input model.QRScanInput) (*model.QRScanResult, error) {
-	panic(fmt.Errorf("not implemented: ScanQRCode - scanQRCode"))
+// MyParticipations is the resolver for the myParticipations field.
+func (r *queryResolver) MyParticipations(ctx context.Context, status *models.ParticipationStatus, rangeArg *model.DateRangeInput) (*model.ParticipationHistory, error) {
+	authCtx, err := middleware.RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.participationHistory(ctx, authCtx.UserID, status, rangeArg)
 }
 
-// RefreshMyQRSecret is the resolver for the refreshMyQRSecret field.
-func (r *mutationResolver) RefreshMyQRSecret(ctx context.Context) (*model.QRData, error) {
-	panic(fmt.Errorf("not implemented: RefreshMyQRSecret - refreshMyQRSecret"))
+// UserParticipations is the resolver for the userParticipations field.
+func (r *queryResolver) UserParticipations(ctx context.Context, userID string, status *models.ParticipationStatus, rangeArg *model.DateRangeInput) (*model.ParticipationHistory, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+
+	var user models.User
+	if err := r.DB.First(&user, id).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+	if !authCtx.User.CanViewUser(&user) {
+		return nil, errcode.Forbidden("permission denied for this user")
+	}
+
+	return r.participationHistory(ctx, user.ID, status, rangeArg)
 }
 
-// RefreshUserQRSecret is the resolver for the refreshUserQRSecret field.
-func (r *mutationResolver) RefreshUserQRSecret(ctx context.Context, userID string) (*model.QRData, error) {
-	panic(fmt.Errorf("not implemented: RefreshUserQRSecret - refreshUserQRSecret"))
+// Subscriptions is the resolver for the subscriptions field.
+func (r *queryResolver) Subscriptions(ctx context.Context, filter *model.SubscriptionListFilter, limit *int, offset *int) ([]*model.FacultySubscription, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.listSubscriptions(ctx, nil, filter, limit, offset)
 }
 
-// ID is the resolver for the id field.
-func (r *notificationLogResolver) ID(ctx context.Context, obj *models.NotificationLog) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+// FacultySubscriptions is the resolver for the facultySubscriptions field.
+func (r *queryResolver) FacultySubscriptions(ctx context.Context, facultyID *string, filter *model.SubscriptionListFilter, limit *int, offset *int) ([]*model.FacultySubscription, error) {
+	return r.facultySubscriptions(ctx, facultyID, filter, limit, offset)
 }
 
 // Subscription is the resolver for the subscription field.
-func (r *notificationLogResolver) Subscription(ctx context.Context, obj *models.NotificationLog) (*model.FacultySubscription, error) {
+func (r *queryResolver) Subscription(ctx context.Context, id string) (*model.FacultySubscription, error) {
 	panic(fmt.Errorf("not implemented: Subscription - subscription"))
 }
 
-// Type is the resolver for the type field.
-func (r *notificationLogResolver) Type(ctx context.Context, obj *models.NotificationLog) (string, error) {
-	panic(fmt.Errorf("not implemented: Type - type"))
+// FacultySubscription is the resolver for the facultySubscription field.
+func (r *queryResolver) FacultySubscription(ctx context.Context, facultyID string) (*model.FacultySubscription, error) {
+	fID, err := strconv.ParseUint(facultyID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid faculty ID")
+	}
+	facultyIDUint := uint(fID)
+
+	// Check if user has permission to view this faculty's subscription
+	if _, err := r.requireFacultyScope(ctx, &facultyIDUint, audit.ResourceSubscription, facultyID); err != nil {
+		return nil, err
+	}
+
+	var subscription models.Subscription
+	if err := r.DB.Preload("Faculty").Where("faculty_id = ?", fID).First(&subscription).Error; err != nil {
+		return nil, errcode.NotFound("subscription not found")
+	}
+
+	return convertSubscriptionToGraphQL(&subscription), nil
+}
+
+// SystemMetrics is the resolver for the systemMetrics field.
+func (r *queryResolver) SystemMetrics(ctx context.Context, fromDate *time.Time, toDate *time.Time) ([]*models.SystemMetrics, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
+
+	metrics, err := r.MetricsSnapshotter.ListSystemMetrics(fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.SystemMetrics, len(metrics))
+	for i := range metrics {
+		result[i] = convertSystemMetricsToGraphQL(&metrics[i])
+	}
+	return result, nil
+}
+
+// FacultyMetrics is the resolver for the facultyMetrics field.
+func (r *queryResolver) FacultyMetrics(ctx context.Context, facultyID *string, fromDate *time.Time, toDate *time.Time) ([]*models.FacultyMetrics, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetFacultyID *uint
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		id := uint(fID)
+		targetFacultyID = &id
+	}
+
+	// Faculty admins only see their own faculty
+	if authCtx.Role != models.UserRoleSuperAdmin {
+		if targetFacultyID == nil {
+			targetFacultyID = authCtx.FacultyID
+		}
+		if !middleware.InFacultyScope(authCtx, targetFacultyID) {
+			return nil, errcode.Forbidden("permission denied")
+		}
+	}
+
+	metrics, err := r.MetricsSnapshotter.ListFacultyMetrics(targetFacultyID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.FacultyMetrics, len(metrics))
+	for i := range metrics {
+		result[i] = convertFacultyMetricsToGraphQL(&metrics[i])
+	}
+	return result, nil
+}
+
+// FacultyComparison is the resolver for the facultyComparison field.
+func (r *queryResolver) FacultyComparison(ctx context.Context, rangeArg *model.DateRangeInput, metric model.FacultyComparisonMetric) ([]*model.FacultyComparisonEntry, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromDate, toDate *time.Time
+	if rangeArg != nil {
+		fromDate, toDate = rangeArg.FromDate, rangeArg.ToDate
+	}
+
+	values, err := r.FacultyComparisonService.Compare(ctx, services.FacultyComparisonMetric(strings.ToLower(string(metric))), fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare faculties: %v", err)
+	}
+
+	var faculties []models.Faculty
+	if err := r.DB.Order("id").Find(&faculties).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch faculties")
+	}
+	facultyByID := make(map[uint]*models.Faculty, len(faculties))
+	for i := range faculties {
+		facultyByID[faculties[i].ID] = &faculties[i]
+	}
+
+	// Values arrive sorted descending; faculties without data follow with zero
+	entries := make([]*model.FacultyComparisonEntry, 0, len(faculties))
+	for _, value := range values {
+		faculty, ok := facultyByID[value.FacultyID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, &model.FacultyComparisonEntry{
+			Faculty: convertFacultyToGraphQL(faculty),
+			Value:   value.Value,
+		})
+		delete(facultyByID, value.FacultyID)
+	}
+	for i := range faculties {
+		if _, remaining := facultyByID[faculties[i].ID]; remaining {
+			entries = append(entries, &model.FacultyComparisonEntry{
+				Faculty: convertFacultyToGraphQL(&faculties[i]),
+				Value:   0,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// NotificationLogs is the resolver for the notificationLogs field.
+func (r *queryResolver) NotificationLogs(ctx context.Context, subscriptionID *string, limit *int, offset *int) ([]*models.NotificationLog, error) {
+	panic(fmt.Errorf("not implemented: NotificationLogs - notificationLogs"))
+}
+
+// ActivityTemplates is the resolver for the activityTemplates field.
+func (r *queryResolver) ActivityTemplates(ctx context.Context, facultyID *string) ([]*models.ActivityTemplate, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.WithContext(ctx).Model(&models.ActivityTemplate{})
+	if facultyID != nil {
+		id, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(id)
+		if _, err := r.requireFacultyScope(ctx, &facultyIDUint, audit.ResourceActivityTemplate, ""); err != nil {
+			return nil, err
+		}
+		query = query.Where("faculty_id = ?", facultyIDUint)
+	} else if authCtx.Role != models.UserRoleSuperAdmin {
+		// Faculty admins see their faculty's templates and the shared ones
+		if authCtx.FacultyID != nil {
+			query = query.Where("faculty_id = ? OR faculty_id IS NULL", *authCtx.FacultyID)
+		} else {
+			query = query.Where("faculty_id IS NULL")
+		}
+	}
+
+	var templates []*models.ActivityTemplate
+	if err := query.Preload("Faculty").Preload("CreatedBy").Preload("Activities").Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch activity templates")
+	}
+	return templates, nil
+}
+
+// ActivityTemplate is the resolver for the activityTemplate field.
+func (r *queryResolver) ActivityTemplate(ctx context.Context, id string) (*models.ActivityTemplate, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	templateID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid template ID")
+	}
+
+	template, err := r.loadActivityTemplate(ctx, uint(templateID))
+	if err != nil {
+		return nil, errcode.NotFound("activity template not found")
+	}
+
+	// Shared templates are readable by every admin
+	if template.FacultyID != nil {
+		if _, err := r.requireFacultyScope(ctx, template.FacultyID, audit.ResourceActivityTemplate, id); err != nil {
+			return nil, err
+		}
+	}
+	return template, nil
+}
+
+// ActivityAssignments is the resolver for the activityAssignments field.
+func (r *queryResolver) ActivityAssignments(ctx context.Context, activityID *string, adminID *string) ([]*models.ActivityAssignment, error) {
+	panic(fmt.Errorf("not implemented: ActivityAssignments - activityAssignments"))
+}
+
+// MyActivityAssignments is the resolver for the myActivityAssignments field.
+func (r *queryResolver) MyActivityAssignments(ctx context.Context) ([]*models.ActivityAssignment, error) {
+	panic(fmt.Errorf("not implemented: MyActivityAssignments - myActivityAssignments"))
 }
 
-// Status is the resolver for the status field.
-func (r *notificationLogResolver) Status(ctx context.Context, obj *models.NotificationLog) (string, error) {
-	panic(fmt.Errorf("not implemented: Status - status"))
+// MyQRData is the resolver for the myQRData field.
+func (r *queryResolver) MyQRData(ctx context.Context) (*model.QRData, error) {
+	panic(fmt.Errorf("not implemented: MyQRData - myQRData"))
 }
 
-// ID is the resolver for the id field.
-func (r *participationResolver) ID(ctx context.Context, obj *models.Participation) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+// QRScanLogs is the resolver for the qrScanLogs field.
+func (r *queryResolver) QRScanLogs(ctx context.Context, activityID *string, userID *string, limit *int) ([]*models.QRScanLog, error) {
+	panic(fmt.Errorf("not implemented: QRScanLogs - qrScanLogs"))
 }
 
-// ID is the resolver for the id field.
-func (r *qRScanLogResolver) ID(ctx context.Context, obj *models.QRScanLog) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+// ScannerTokens is the resolver for the scannerTokens field.
+func (r *queryResolver) ScannerTokens(ctx context.Context, activityID string) ([]*model.ScannerToken, error) {
+	return r.listScannerTokens(ctx, activityID)
 }
 
-// Me is the resolver for the me field.
-func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
-	authCtx, err := middleware.RequireAuth(ctx)
+// ActiveScanSessions is the resolver for the activeScanSessions field.
+func (r *queryResolver) ActiveScanSessions(ctx context.Context, windowMinutes *int) ([]*model.ScanSession, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
 	if err != nil {
 		return nil, err
 	}
-	return convertUserToGraphQL(authCtx.User), nil
-}
 
-// Users is the resolver for the users field.
-func (r *queryResolver) Users(ctx context.Context, limit *int, offset *int) ([]*models.User, error) {
-	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
-	if err != nil {
-		return nil, err
+	// Faculty admins only see scanning in their own faculty
+	var targetFacultyID *uint
+	if authCtx.Role != models.UserRoleSuperAdmin {
+		targetFacultyID = authCtx.FacultyID
+		if _, err := r.requireFacultyScope(ctx, targetFacultyID, audit.ResourceActivity, ""); err != nil {
+			return nil, err
+		}
 	}
 
-	query := r.DB.Model(&models.User{}).Preload("Faculty").Preload("Department")
-
-	// Apply faculty filtering for non-super admins
-	query = middleware.FilterByFaculty(ctx, query, "faculty_id")
-
-	if offset != nil {
-		query = query.Offset(*offset)
-	}
-	if limit != nil {
-		query = query.Limit(*limit)
+	window := services.DefaultScanSessionWindow
+	if windowMinutes != nil {
+		window = time.Duration(*windowMinutes) * time.Minute
 	}
 
-	var users []models.User
-	if err := query.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch users")
+	sessions, err := services.NewScanMonitor(r.DB.DB).GetActiveScanSessions(window, targetFacultyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch active scan sessions")
 	}
 
-	result := make([]*models.User, len(users))
-	for i, user := range users {
-		result[i] = convertUserToGraphQL(&user)
+	result := make([]*model.ScanSession, len(sessions))
+	for i := range sessions {
+		result[i] = convertScanSessionToGraphQL(&sessions[i])
 	}
 	return result, nil
 }
 
-// User is the resolver for the user field.
-func (r *queryResolver) User(ctx context.Context, id string) (*models.User, error) {
-	authCtx, err := middleware.RequireAuth(ctx)
+// QRSecurityMetrics is the resolver for the qrSecurityMetrics field.
+func (r *queryResolver) QRSecurityMetrics(ctx context.Context, days *int, facultyID *string) (*model.QRSecurityMetrics, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("invalid user ID")
+	if r.QRSecurity == nil {
+		return nil, fmt.Errorf("QR security metrics are not available")
 	}
 
-	var user models.User
-	if err := r.DB.Preload("Faculty").Preload("Department").First(&user, userID).Error; err != nil {
-		return nil, fmt.Errorf("user not found")
+	// Faculty admins default to, and are limited to, their own faculty
+	var targetFacultyID *uint
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(fID)
+		targetFacultyID = &facultyIDUint
+	} else if authCtx.Role != models.UserRoleSuperAdmin {
+		targetFacultyID = authCtx.FacultyID
+	}
+	if authCtx.Role != models.UserRoleSuperAdmin {
+		if targetFacultyID == nil {
+			return nil, errcode.Forbidden("permission denied")
+		}
+		if _, err := r.requireFacultyScope(ctx, targetFacultyID, audit.ResourceQRCode, ""); err != nil {
+			return nil, err
+		}
 	}
 
-	// Check permission to view user
-	if !authCtx.User.CanViewUser(&user) {
-		return nil, fmt.Errorf("permission denied")
+	metricDays := 7
+	if days != nil && *days > 0 && *days <= 90 {
+		metricDays = *days
 	}
 
-	return convertUserToGraphQL(&user), nil
-}
-
-// Faculties is the resolver for the faculties field.
-func (r *queryResolver) Faculties(ctx context.Context) ([]*models.Faculty, error) {
-	_, err := middleware.RequireAuth(ctx)
-	if err != nil {
-		return nil, err
+	scope := ""
+	if targetFacultyID != nil {
+		scope = strconv.FormatUint(uint64(*targetFacultyID), 10)
 	}
 
-	var faculties []models.Faculty
-	if err := r.DB.Where("is_active = ?", true).Find(&faculties).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch faculties")
+	daily, err := r.QRSecurity.GetDailySecurityMetrics(ctx, metricDays, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch QR security metrics")
 	}
 
-	result := make([]*models.Faculty, len(faculties))
-	for i, faculty := range faculties {
-		result[i] = convertFacultyToGraphQL(&faculty)
-	}
-	return result, nil
+	return convertQRSecurityMetricsToGraphQL(daily), nil
 }
 
-// Faculty is the resolver for the faculty field.
-func (r *queryResolver) Faculty(ctx context.Context, id string) (*models.Faculty, error) {
-	_, err := middleware.RequireAuth(ctx)
+// FeatureFlags is the resolver for the featureFlags field.
+func (r *queryResolver) FeatureFlags(ctx context.Context, facultyID *string) ([]*models.FeatureFlag, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
 	if err != nil {
 		return nil, err
 	}
+	if r.FeatureFlagService == nil {
+		return []*models.FeatureFlag{}, nil
+	}
 
-	facultyID, err := strconv.ParseUint(id, 10, 32)
+	targetFacultyID, err := parseOptionalFacultyID(facultyID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid faculty ID")
+		return nil, err
+	}
+	if authCtx.Role != models.UserRoleSuperAdmin {
+		if targetFacultyID == nil {
+			targetFacultyID = authCtx.FacultyID
+		}
+		if targetFacultyID == nil {
+			return nil, errcode.Forbidden("permission denied")
+		}
+		if _, err := r.requireFacultyScope(ctx, targetFacultyID, audit.ResourceFeatureFlag, ""); err != nil {
+			return nil, err
+		}
 	}
 
-	var faculty models.Faculty
-	if err := r.DB.First(&faculty, facultyID).Error; err != nil {
-		return nil, fmt.Errorf("faculty not found")
+	flags, err := r.FeatureFlagService.List(targetFacultyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feature flags")
 	}
 
-	return convertFacultyToGraphQL(&faculty), nil
+	result := make([]*models.FeatureFlag, len(flags))
+	for i := range flags {
+		result[i] = &flags[i]
+	}
+	return result, nil
 }
 
-// Departments is the resolver for the departments field.
-func (r *queryResolver) Departments(ctx context.Context, facultyID *string) ([]*models.Department, error) {
-	panic(fmt.Errorf("not implemented: Departments - departments"))
-}
+// RunningExports is the resolver for the runningExports field.
+func (r *queryResolver) RunningExports(ctx context.Context) (int, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	if err != nil {
+		return 0, err
+	}
 
-// Department is the resolver for the department field.
-func (r *queryResolver) Department(ctx context.Context, id string) (*models.Department, error) {
-	panic(fmt.Errorf("not implemented: Department - department"))
+	if r.ExportLimiter == nil {
+		return 0, nil
+	}
+
+	running, err := r.ExportLimiter.Running(ctx, strconv.FormatUint(uint64(authCtx.UserID), 10))
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch running exports: %v", err)
+	}
+	return running, nil
 }
 
-// Activities is the resolver for the activities field.
-func (r *queryResolver) Activities(ctx context.Context, limit *int, offset *int, facultyID *string, status *models.ActivityStatus) ([]*models.Activity, error) {
-	_, err := middleware.RequireAuth(ctx)
+// ResourceAuditTrail is the resolver for the resourceAuditTrail field.
+func (r *queryResolver) ResourceAuditTrail(ctx context.Context, resource model.AuditResource, resourceID string, limit *int, offset *int) (*model.AuditTrailPage, error) {
+	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	query := r.DB.Model(&models.Activity{}).Preload("Faculty").Preload("Department").Preload("CreatedBy")
+	if r.AuditLogger == nil {
+		return nil, fmt.Errorf("audit logging is not available")
+	}
 
-	// Apply faculty filtering
-	query = middleware.FilterByFaculty(ctx, query, "faculty_id")
+	id, err := strconv.ParseUint(resourceID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid resource ID")
+	}
 
-	if facultyID != nil {
-		fID, _ := strconv.ParseUint(*facultyID, 10, 32)
-		query = query.Where("faculty_id = ?", fID)
+	// Resolve the owning faculty (including soft-deleted rows) for scoping
+	var facultyID *uint
+	switch resource {
+	case model.AuditResourceActivity:
+		var activity models.Activity
+		if err := r.DB.Unscoped().First(&activity, id).Error; err != nil {
+			return nil, errcode.NotFound("activity not found")
+		}
+		facultyID = activity.FacultyID
+	case model.AuditResourceUser:
+		var user models.User
+		if err := r.DB.Unscoped().First(&user, id).Error; err != nil {
+			return nil, errcode.NotFound("user not found")
+		}
+		facultyID = user.FacultyID
+	case model.AuditResourceSubscription:
+		var subscription models.Subscription
+		if err := r.DB.Unscoped().First(&subscription, id).Error; err != nil {
+			return nil, errcode.NotFound("subscription not found")
+		}
+		facultyID = &subscription.FacultyID
+	default:
+		return nil, errcode.Validation("unsupported resource: %s", resource)
 	}
 
-	if status != nil {
-		query = query.Where("status = ?", string(*status))
+	if _, err := r.requireFacultyScope(ctx, facultyID, string(resource), resourceID); err != nil {
+		return nil, err
 	}
 
-	if offset != nil {
-		query = query.Offset(*offset)
+	pageLimit, pageOffset := 50, 0
+	if limit != nil && *limit > 0 && *limit <= 200 {
+		pageLimit = *limit
 	}
-	if limit != nil {
-		query = query.Limit(*limit)
+	if offset != nil && *offset > 0 {
+		pageOffset = *offset
 	}
 
-	var activities []models.Activity
-	if err := query.Find(&activities).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch activities")
+	events, total, err := r.AuditLogger.GetResourceAuditTrail(ctx, string(resource), resourceID, pageLimit, pageOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit trail: %v", err)
 	}
 
-	result := make([]*models.Activity, len(activities))
-	for i, activity := range activities {
-		result[i] = convertActivityToGraphQL(&activity)
+	// Load actors in one query
+	actorIDs := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.UserID != "" {
+			actorIDs = append(actorIDs, event.UserID)
+		}
+	}
+	actors := make(map[string]*models.User)
+	if len(actorIDs) > 0 {
+		var users []models.User
+		r.DB.Unscoped().Where("id IN ?", actorIDs).Find(&users)
+		for i := range users {
+			actors[strconv.FormatUint(uint64(users[i].ID), 10)] = &users[i]
+		}
 	}
-	return result, nil
-}
 
-// Activity is the resolver for the activity field.
-func (r *queryResolver) Activity(ctx context.Context, id string) (*models.Activity, error) {
-	panic(fmt.Errorf("not implemented: Activity - activity"))
-}
+	entries := make([]*model.AuditTrailEntry, 0, pageLimit)
+	for i, event := range events {
+		if i == pageLimit {
+			break // the extra event only serves as the diff base
+		}
 
-// MyActivities is the resolver for the myActivities field.
-func (r *queryResolver) MyActivities(ctx context.Context) ([]*models.Activity, error) {
-	panic(fmt.Errorf("not implemented: MyActivities - myActivities"))
-}
+		var previous map[string]interface{}
+		if i+1 < len(events) {
+			previous = events[i+1].Details
+		}
 
-// Participations is the resolver for the participations field.
-func (r *queryResolver) Participations(ctx context.Context, activityID *string, userID *string) ([]*models.Participation, error) {
-	panic(fmt.Errorf("not implemented: Participations - participations"))
-}
+		entries = append(entries, convertAuditEventToTrailEntry(&events[i], actors[event.UserID], previous))
+	}
 
-// MyParticipations is the resolver for the myParticipations field.
-func (r *queryResolver) MyParticipations(ctx context.Context) ([]*models.Participation, error) {
-	panic(fmt.Errorf("not implemented: MyParticipations - myParticipations"))
+	return &model.AuditTrailPage{
+		Entries:    entries,
+		TotalCount: int(total),
+	}, nil
 }
 
-// Subscriptions is the resolver for the subscriptions field.
-func (r *queryResolver) Subscriptions(ctx context.Context) ([]*model.FacultySubscription, error) {
-	_, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+// LiveSecurityEvents is the resolver for the liveSecurityEvents field.
+func (r *queryResolver) LiveSecurityEvents(ctx context.Context, limit *int) ([]*model.LiveSecurityEvent, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
 	if err != nil {
 		return nil, err
 	}
 
-	var subscriptions []models.Subscription
-	if err := r.DB.Preload("Faculty").Find(&subscriptions).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch subscriptions: %v", err)
+	if r.AuditLogger == nil {
+		return nil, fmt.Errorf("audit logging is not available")
 	}
 
-	result := make([]*model.FacultySubscription, len(subscriptions))
-	for i, sub := range subscriptions {
-		result[i] = convertSubscriptionToGraphQL(&sub)
+	pageLimit := 100
+	if limit != nil && *limit > 0 && *limit <= audit.MaxLiveSecurityEvents {
+		pageLimit = *limit
 	}
-	return result, nil
-}
-
-// Subscription is the resolver for the subscription field.
-func (r *queryResolver) Subscription(ctx context.Context, id string) (*model.FacultySubscription, error) {
-	panic(fmt.Errorf("not implemented: Subscription - subscription"))
-}
 
-// FacultySubscription is the resolver for the facultySubscription field.
-func (r *queryResolver) FacultySubscription(ctx context.Context, facultyID string) (*model.FacultySubscription, error) {
-	authCtx, err := middleware.RequireAuth(ctx)
-	if err != nil {
-		return nil, err
+	// Faculty admins filter down from the full feed, so read all of it
+	facultyID := securityEventFaculty(authCtx)
+	readLimit := pageLimit
+	if facultyID != nil {
+		readLimit = audit.MaxLiveSecurityEvents
 	}
 
-	fID, err := strconv.ParseUint(facultyID, 10, 32)
+	events, err := r.AuditLogger.GetLiveSecurityEvents(ctx, readLimit)
 	if err != nil {
-		return nil, fmt.Errorf("invalid faculty ID")
+		return nil, fmt.Errorf("failed to fetch live security events: %v", err)
 	}
-
-	// Check if user has permission to view this faculty's subscription
-	if authCtx.Role != models.UserRoleSuperAdmin && authCtx.FacultyID != nil && *authCtx.FacultyID != uint(fID) {
-		return nil, fmt.Errorf("access denied")
+	if facultyID != nil {
+		events = r.filterSecurityEventsByFaculty(ctx, *facultyID, events)
 	}
-
-	var subscription models.Subscription
-	if err := r.DB.Preload("Faculty").Where("faculty_id = ?", fID).First(&subscription).Error; err != nil {
-		return nil, fmt.Errorf("subscription not found")
+	if len(events) > pageLimit {
+		events = events[:pageLimit]
 	}
 
-	return convertSubscriptionToGraphQL(&subscription), nil
+	result := make([]*model.LiveSecurityEvent, len(events))
+	for i, event := range events {
+		result[i] = convertLiveSecurityEvent(event)
+	}
+	return result, nil
 }
 
-// SystemMetrics is the resolver for the systemMetrics field.
-func (r *queryResolver) SystemMetrics(ctx context.Context, fromDate *time.Time, toDate *time.Time) ([]*models.SystemMetrics, error) {
-	panic(fmt.Errorf("not implemented: SystemMetrics - systemMetrics"))
-}
+// SlowQueries is the resolver for the slowQueries field.
+func (r *queryResolver) SlowQueries(ctx context.Context, limit *int) ([]*model.SlowQuery, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
 
-// FacultyMetrics is the resolver for the facultyMetrics field.
-func (r *queryResolver) FacultyMetrics(ctx context.Context, facultyID *string, fromDate *time.Time, toDate *time.Time) ([]*models.FacultyMetrics, error) {
-	panic(fmt.Errorf("not implemented: FacultyMetrics - facultyMetrics"))
-}
+	if r.QueryOptimizer == nil {
+		return nil, fmt.Errorf("query statistics are not available")
+	}
 
-// NotificationLogs is the resolver for the notificationLogs field.
-func (r *queryResolver) NotificationLogs(ctx context.Context, subscriptionID *string, limit *int, offset *int) ([]*models.NotificationLog, error) {
-	panic(fmt.Errorf("not implemented: NotificationLogs - notificationLogs"))
+	slowQueries := r.QueryOptimizer.GetSlowQueries(queryReportLimit(limit))
+	result := make([]*model.SlowQuery, 0, len(slowQueries))
+	for _, query := range slowQueries {
+		result = append(result, r.convertSlowQuery(query))
+	}
+	return result, nil
 }
 
-// ActivityTemplates is the resolver for the activityTemplates field.
-func (r *queryResolver) ActivityTemplates(ctx context.Context, facultyID *string) ([]*models.ActivityTemplate, error) {
-	panic(fmt.Errorf("not implemented: ActivityTemplates - activityTemplates"))
-}
+// QueryStatistics is the resolver for the queryStatistics field.
+func (r *queryResolver) QueryStatistics(ctx context.Context, limit *int, sortBy *model.QueryStatsSort) ([]*model.QueryStatistic, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin); err != nil {
+		return nil, err
+	}
 
-// ActivityTemplate is the resolver for the activityTemplate field.
-func (r *queryResolver) ActivityTemplate(ctx context.Context, id string) (*models.ActivityTemplate, error) {
-	panic(fmt.Errorf("not implemented: ActivityTemplate - activityTemplate"))
-}
+	if r.QueryOptimizer == nil {
+		return nil, fmt.Errorf("query statistics are not available")
+	}
 
-// ActivityAssignments is the resolver for the activityAssignments field.
-func (r *queryResolver) ActivityAssignments(ctx context.Context, activityID *string, adminID *string) ([]*models.ActivityAssignment, error) {
-	panic(fmt.Errorf("not implemented: ActivityAssignments - activityAssignments"))
+	order := pkgdb.QueryStatsByAverageDuration
+	if sortBy != nil {
+		order = pkgdb.QueryStatsSort(strings.ToLower(string(*sortBy)))
+	}
+
+	stats := r.QueryOptimizer.TopQueryStatistics(queryReportLimit(limit), order)
+	result := make([]*model.QueryStatistic, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, convertQueryStats(s))
+	}
+	return result, nil
 }
 
-// MyActivityAssignments is the resolver for the myActivityAssignments field.
-func (r *queryResolver) MyActivityAssignments(ctx context.Context) ([]*models.ActivityAssignment, error) {
-	panic(fmt.Errorf("not implemented: MyActivityAssignments - myActivityAssignments"))
+// OperationCostReport is the resolver for the operationCostReport field.
+func (r *queryResolver) OperationCostReport(ctx context.Context, days *int) ([]*model.OperationCostBucket, error) {
+	return r.operationCostReport(ctx, days)
 }
 
-// MyQRData is the resolver for the myQRData field.
-func (r *queryResolver) MyQRData(ctx context.Context) (*model.QRData, error) {
-	panic(fmt.Errorf("not implemented: MyQRData - myQRData"))
+// MaintenanceJob is the resolver for the maintenanceJob field.
+func (r *queryResolver) MaintenanceJob(ctx context.Context, id string) (*model.MaintenanceJob, error) {
+	return r.maintenanceJob(ctx, id)
 }
 
-// QRScanLogs is the resolver for the qrScanLogs field.
-func (r *queryResolver) QRScanLogs(ctx context.Context, activityID *string, userID *string, limit *int) ([]*models.QRScanLog, error) {
-	panic(fmt.Errorf("not implemented: QRScanLogs - qrScanLogs"))
+// AdminDashboard is the resolver for the adminDashboard field.
+func (r *queryResolver) AdminDashboard(ctx context.Context, limit *int) (*model.AdminDashboard, error) {
+	return r.adminDashboard(ctx, limit)
 }
 
 // PersonalNotifications is the resolver for the personalNotifications field.
@@ -765,12 +2880,18 @@ func (r *subscriptionResolver) FacultyUpdates(ctx context.Context, facultyID str
 
 // SystemAlerts is the resolver for the systemAlerts field.
 func (r *subscriptionResolver) SystemAlerts(ctx context.Context, filter *model.SubscriptionFilter) (<-chan *model.SubscriptionPayload, error) {
-	panic(fmt.Errorf("not implemented: SystemAlerts - systemAlerts"))
+	if r.Subscriptions == nil {
+		return nil, fmt.Errorf("realtime events are unavailable")
+	}
+	return r.Subscriptions.SystemAlerts(ctx, filter)
 }
 
 // QRScanEvents is the resolver for the qrScanEvents field.
 func (r *subscriptionResolver) QRScanEvents(ctx context.Context, activityID *string) (<-chan *model.SubscriptionPayload, error) {
-	panic(fmt.Errorf("not implemented: QRScanEvents - qrScanEvents"))
+	if r.Subscriptions == nil {
+		return nil, fmt.Errorf("realtime events are unavailable")
+	}
+	return r.Subscriptions.QrScanEvents(ctx, activityID)
 }
 
 // ParticipationEvents is the resolver for the participationEvents field.
@@ -798,6 +2919,53 @@ func (r *subscriptionResolver) Heartbeat(ctx context.Context) (<-chan string, er
 	panic(fmt.Errorf("not implemented: Heartbeat - heartbeat"))
 }
 
+// ConnectionStats is the resolver for the connectionStats field.
+func (r *subscriptionResolver) ConnectionStats(ctx context.Context) (<-chan *model.ConnectionStats, error) {
+	if r.Subscriptions == nil {
+		return nil, fmt.Errorf("realtime events are unavailable")
+	}
+	return r.Subscriptions.ConnectionStats(ctx)
+}
+
+// SecurityAlerts is the resolver for the securityAlerts field.
+func (r *subscriptionResolver) SecurityAlerts(ctx context.Context) (<-chan *model.LiveSecurityEvent, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.AuditLogger == nil {
+		return nil, fmt.Errorf("audit logging is not available")
+	}
+
+	alerts, err := r.AuditLogger.SubscribeSecurityAlerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facultyID := securityEventFaculty(authCtx)
+	output := make(chan *model.LiveSecurityEvent)
+	go func() {
+		defer close(output)
+		for alert := range alerts {
+			if facultyID != nil && len(r.filterSecurityEventsByFaculty(ctx, *facultyID, []*audit.LiveSecurityEvent{alert})) == 0 {
+				continue
+			}
+			select {
+			case output <- convertLiveSecurityEvent(alert):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return output, nil
+}
+
+// ActivityAttendanceCount is the resolver for the activityAttendanceCount field.
+func (r *subscriptionResolver) ActivityAttendanceCount(ctx context.Context, activityID string) (<-chan *model.ActivityAttendanceCount, error) {
+	return r.activityAttendanceCount(ctx, activityID)
+}
+
 // ID is the resolver for the id field.
 func (r *systemAlertResolver) ID(ctx context.Context, obj *models.SystemAlert) (string, error) {
 	panic(fmt.Errorf("not implemented: ID - id"))
@@ -835,7 +3003,7 @@ func (r *systemAlertResolver) Timestamp(ctx context.Context, obj *models.SystemA
 
 // ID is the resolver for the id field.
 func (r *systemMetricsResolver) ID(ctx context.Context, obj *models.SystemMetrics) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
 }
 
 // ID is the resolver for the id field.
@@ -843,6 +3011,11 @@ func (r *userResolver) ID(ctx context.Context, obj *models.User) (string, error)
 	panic(fmt.Errorf("not implemented: ID - id"))
 }
 
+// DeletedAt is the resolver for the deletedAt field.
+func (r *userResolver) DeletedAt(ctx context.Context, obj *models.User) (*time.Time, error) {
+	return deletedAtTime(obj.DeletedAt), nil
+}
+
 // Subscriptions is the resolver for the subscriptions field.
 func (r *userResolver) Subscriptions(ctx context.Context, obj *models.User) ([]*model.FacultySubscription, error) {
 	panic(fmt.Errorf("not implemented: Subscriptions - subscriptions"))
@@ -872,6 +3045,9 @@ func (r *Resolver) FacultyMetrics() generated.FacultyMetricsResolver {
 	return &facultyMetricsResolver{r}
 }
 
+// FeatureFlag returns generated.FeatureFlagResolver implementation.
+func (r *Resolver) FeatureFlag() generated.FeatureFlagResolver { return &featureFlagResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
@@ -907,6 +3083,7 @@ type activityTemplateResolver struct{ *Resolver }
 type departmentResolver struct{ *Resolver }
 type facultyResolver struct{ *Resolver }
 type facultyMetricsResolver struct{ *Resolver }
+type featureFlagResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type notificationLogResolver struct{ *Resolver }
 type participationResolver struct{ *Resolver }
@@ -916,44 +3093,3 @@ type subscriptionResolver struct{ *Resolver }
 type systemAlertResolver struct{ *Resolver }
 type systemMetricsResolver struct{ *Resolver }
 type userResolver struct{ *Resolver }
-
-// Helper converter functions
-
-func convertUserToGraphQL(user *models.User) *models.User {
-	return user
-}
-
-func convertFacultyToGraphQL(faculty *models.Faculty) *models.Faculty {
-	return faculty
-}
-
-func convertActivityToGraphQL(activity *models.Activity) *models.Activity {
-	return activity
-}
-
-func convertParticipationToGraphQL(participation *models.Participation) *models.Participation {
-	return participation
-}
-
-func convertSubscriptionToGraphQL(subscription *models.Subscription) *model.FacultySubscription {
-	return &model.FacultySubscription{
-		ID:                strconv.FormatUint(uint64(subscription.ID), 10),
-		Faculty:           &subscription.Faculty,
-		Type:              subscription.Type,
-		Status:            subscription.Status,
-		StartDate:         subscription.StartDate,
-		EndDate:           subscription.EndDate,
-		DaysUntilExpiry:   subscription.DaysUntilExpiry(),
-		NeedsNotification: subscription.NeedsNotification(),
-		CreatedAt:         subscription.CreatedAt,
-		UpdatedAt:         subscription.UpdatedAt,
-	}
-}
-
-func convertSystemMetricsToGraphQL(metrics *models.SystemMetrics) *models.SystemMetrics {
-	return metrics
-}
-
-func convertFacultyMetricsToGraphQL(metrics *models.FacultyMetrics) *models.FacultyMetrics {
-	return metrics
-}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+)
+
+// securityEventFaculty returns the faculty a caller's security feed is limited to, or nil for super admins
+func securityEventFaculty(authCtx *middleware.AuthContext) *uint {
+	if authCtx.Role == models.UserRoleSuperAdmin {
+		return nil
+	}
+	if authCtx.FacultyID == nil {
+		// A faculty admin without a faculty sees nothing rather than everything
+		none := uint(0)
+		return &none
+	}
+	return authCtx.FacultyID
+}
+
+// filterSecurityEventsByFaculty keeps the events tied to facultyID: those recorded with it, and
+// those recorded without a faculty whose user belongs to it
+func (r *Resolver) filterSecurityEventsByFaculty(ctx context.Context, facultyID uint, events []*audit.LiveSecurityEvent) []*audit.LiveSecurityEvent {
+	want := strconv.FormatUint(uint64(facultyID), 10)
+
+	var userIDs []string
+	for _, event := range events {
+		if event.FacultyID == "" && event.UserID != "" {
+			userIDs = append(userIDs, event.UserID)
+		}
+	}
+	inFaculty := make(map[string]bool)
+	if len(userIDs) > 0 {
+		var ids []uint
+		r.DB.WithContext(ctx).Model(&models.User{}).
+			Where("id IN ? AND faculty_id = ?", userIDs, facultyID).
+			Pluck("id", &ids)
+		for _, id := range ids {
+			inFaculty[strconv.FormatUint(uint64(id), 10)] = true
+		}
+	}
+
+	filtered := make([]*audit.LiveSecurityEvent, 0, len(events))
+	for _, event := range events {
+		if event.FacultyID == want || (event.FacultyID == "" && inFaculty[event.UserID]) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+func convertLiveSecurityEvent(event *audit.LiveSecurityEvent) *model.LiveSecurityEvent {
+	converted := &model.LiveSecurityEvent{
+		ID:        event.ID,
+		EventType: event.EventType,
+		RiskLevel: event.RiskLevel,
+		UserID:    optionalString(event.UserID),
+		FacultyID: optionalString(event.FacultyID),
+		IPAddress: optionalString(event.IPAddress),
+		Blocked:   event.Blocked,
+		Alert:     event.Alert,
+		Timestamp: event.Timestamp,
+	}
+	if len(event.Details) > 0 {
+		if details, err := json.Marshal(event.Details); err == nil {
+			converted.Details = optionalString(string(details))
+		}
+	}
+	return converted
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
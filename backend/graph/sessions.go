@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"context"
+	"log"
+)
+
+// signOutEverywhere revokes every token issued to the user, so reconnecting requires logging in
+// again, and closes their realtime connections on all instances. It returns the number of
+// connections closed on this instance.
+func (r *Resolver) signOutEverywhere(ctx context.Context, userID uint) (int, error) {
+	if err := r.SessionStore.RevokeUserSessions(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	if r.SessionTerminator == nil {
+		return 0, nil
+	}
+	closed, err := r.SessionTerminator.TerminateUser(userID)
+	if err != nil {
+		// Connections on other instances still end when their tokens are next checked
+		log.Printf("Failed to terminate connections of user %d everywhere: %v", userID, err)
+	}
+	return closed, nil
+}
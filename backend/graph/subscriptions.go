@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+)
+
+// listSubscriptions returns subscriptions, optionally of one faculty, soonest to expire first.
+// The expiry filters select the same subscriptions as the daysUntilExpiry and
+// needsNotification fields, but in SQL so pagination stays exact.
+func (r *Resolver) listSubscriptions(ctx context.Context, facultyID *uint, filter *model.SubscriptionListFilter, limit, offset *int) ([]*model.FacultySubscription, error) {
+	query := r.DB.WithContext(ctx).Model(&models.Subscription{}).Preload("Faculty")
+	if facultyID != nil {
+		query = query.Where("faculty_id = ?", *facultyID)
+	}
+
+	if filter != nil {
+		now := time.Now()
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.Type != nil {
+			query = query.Where("type = ?", *filter.Type)
+		}
+		if filter.ExpiringWithinDays != nil {
+			if *filter.ExpiringWithinDays < 0 {
+				return nil, errcode.Validation("expiringWithinDays must not be negative")
+			}
+			query = models.WhereDaysUntilExpiry(query, now, 0, *filter.ExpiringWithinDays)
+		}
+		if filter.NeedsNotification != nil {
+			query = models.WhereNeedsNotification(query, now, *filter.NeedsNotification)
+		}
+	}
+
+	query = query.Order("end_date ASC").Order("id ASC")
+	if offset != nil {
+		query = query.Offset(*offset)
+	}
+	rowBudget := middleware.RowBudgetFrom(ctx)
+	if limit := rowBudget.Limit(limit); limit != nil {
+		query = query.Limit(*limit)
+	}
+
+	var subscriptions []models.Subscription
+	if err := query.Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions")
+	}
+	subscriptions = subscriptions[:rowBudget.Take(len(subscriptions))]
+
+	result := make([]*model.FacultySubscription, len(subscriptions))
+	for i := range subscriptions {
+		result[i] = convertSubscriptionToGraphQL(&subscriptions[i])
+	}
+	return result, nil
+}
+
+// facultySubscriptions lists one faculty's subscriptions; faculty admins see only their own
+func (r *Resolver) facultySubscriptions(ctx context.Context, facultyID *string, filter *model.SubscriptionListFilter, limit, offset *int) ([]*model.FacultySubscription, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetID *uint
+	if facultyID != nil {
+		id, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		facultyIDUint := uint(id)
+		targetID = &facultyIDUint
+	} else {
+		targetID = authCtx.FacultyID
+	}
+	if targetID == nil {
+		return nil, errcode.Validation("facultyID is required")
+	}
+
+	if _, err := r.requireFacultyScope(ctx, targetID, audit.ResourceSubscription, strconv.FormatUint(uint64(*targetID), 10)); err != nil {
+		return nil, err
+	}
+	return r.listSubscriptions(ctx, targetID, filter, limit, offset)
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+)
+
+// issueToken signs a token for the user, embedding their faculty, department and current
+// session epoch. Faculty and department are read fresh so renames reach the next token.
+func (r *Resolver) issueToken(ctx context.Context, user *models.User) (string, error) {
+	subject := auth.TokenSubject{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         string(user.Role),
+		FacultyID:    user.FacultyID,
+		DepartmentID: user.DepartmentID,
+	}
+
+	if user.FacultyID != nil {
+		var faculty models.Faculty
+		if err := r.DB.Select("id", "code", "name").First(&faculty, *user.FacultyID).Error; err == nil {
+			subject.Faculty = &auth.OrgClaim{ID: faculty.ID, Code: faculty.Code, Name: faculty.Name}
+		}
+	}
+	if user.DepartmentID != nil {
+		var department models.Department
+		if err := r.DB.Select("id", "code", "name").First(&department, *user.DepartmentID).Error; err == nil {
+			subject.Department = &auth.OrgClaim{ID: department.ID, Code: department.Code, Name: department.Name}
+		}
+	}
+
+	// A token from a stale epoch would be rejected as soon as it is used
+	if r.SessionStore != nil {
+		epoch, err := r.SessionStore.CurrentEpoch(ctx, user.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate token")
+		}
+		subject.Epoch = epoch
+	}
+
+	return r.JWTService.GenerateToken(subject)
+}
@@ -0,0 +1,14 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"gorm.io/gorm"
+)
+
+// inTransaction runs a multi-step mutation atomically. Any error rolls back every write fn made;
+// side effects queued on after, such as published events and audit entries, run only once it commits.
+func (r *Resolver) inTransaction(ctx context.Context, fn func(tx *gorm.DB, after *database.AfterCommit) error) error {
+	return r.DB.TransactionAfterCommit(ctx, fn)
+}
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+// cacheableUser copies a user for the cache without credentials or loaded participations
+func cacheableUser(user *models.User) *models.User {
+	cached := *user
+	cached.Password = ""
+	cached.QRSecret = ""
+	cached.Participations = nil
+	return &cached
+}
+
+// getCachedUser returns the cached profile of a user, or nil on a miss or when caching is off
+func (r *Resolver) getCachedUser(ctx context.Context, userID uint) *models.User {
+	if r.CacheManager == nil {
+		return nil
+	}
+
+	var user models.User
+	if err := r.CacheManager.GetCachedUser(ctx, strconv.FormatUint(uint64(userID), 10), &user); err != nil {
+		return nil
+	}
+	return &user
+}
+
+// cacheUser stores a sanitized copy of the user; failures only cost a later cache miss
+func (r *Resolver) cacheUser(ctx context.Context, user *models.User) {
+	if r.CacheManager == nil || user == nil {
+		return
+	}
+
+	if err := r.CacheManager.CacheUser(ctx, strconv.FormatUint(uint64(user.ID), 10), cacheableUser(user)); err != nil {
+		log.Printf("Failed to cache user %d: %v", user.ID, err)
+	}
+}
+
+// invalidateUserCache drops the cached profile after any change to the user
+func (r *Resolver) invalidateUserCache(ctx context.Context, userID uint) {
+	if r.CacheManager == nil {
+		return
+	}
+
+	if err := r.CacheManager.InvalidateUser(ctx, strconv.FormatUint(uint64(userID), 10)); err != nil {
+		log.Printf("Failed to invalidate cached user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,418 @@
+package graph
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/graph/model"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportFileSize = 2 << 20
+	maxImportRows     = 2000
+	importBatchSize   = 100
+	// importLookupChunk bounds the IN lists of the existing user lookup
+	importLookupChunk = 500
+
+	// Limits of the users table columns
+	importStudentIDMaxLength = 20
+	importEmailMaxLength     = 100
+	importNameMaxLength      = 50
+)
+
+// importHeaderAliases maps the accepted spellings of each CSV column to its canonical name
+var importHeaderAliases = map[string]string{
+	"student_id": "student_id",
+	"studentid":  "student_id",
+	"email":      "email",
+	"first_name": "first_name",
+	"firstname":  "first_name",
+	"last_name":  "last_name",
+	"lastname":   "last_name",
+	"name":       "name",
+	"full_name":  "name",
+}
+
+// importRow is one data row of an import and, while it is still to be created, its new user
+type importRow struct {
+	result *model.UserImportRow
+	user   *models.User
+}
+
+func (row *importRow) reject(status model.UserImportStatus, message string) {
+	row.result.Status = status
+	row.result.Message = &message
+	row.user = nil
+}
+
+// importUsers creates students in a faculty from a CSV upload. Rows that fail validation or
+// match an existing user are reported and skipped; the rest are created together, with
+// unusable random passwords, and emailed a link to set their own once the import commits.
+func (r *Resolver) importUsers(ctx context.Context, facultyID string, departmentID *string, file graphql.Upload) (*model.UserImportResult, error) {
+	if _, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin); err != nil {
+		return nil, err
+	}
+
+	faculty, err := r.loadFaculty(ctx, facultyID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.requireFacultyScope(ctx, &faculty.ID, audit.ResourceUser, ""); err != nil {
+		return nil, err
+	}
+	if !faculty.IsActive {
+		return nil, errcode.Validation("faculty is not active")
+	}
+
+	var department *models.Department
+	if departmentID != nil {
+		department, err = r.loadDepartment(ctx, *departmentID)
+		if err != nil {
+			return nil, err
+		}
+		if department.FacultyID != faculty.ID {
+			return nil, errcode.Validation("department does not belong to the faculty")
+		}
+		if !department.IsActive {
+			return nil, errcode.Validation("department is not active")
+		}
+	}
+
+	if file.Size > maxImportFileSize {
+		return nil, errcode.Validation("file must be at most %d MB", maxImportFileSize>>20)
+	}
+	rows, err := parseUserImportCSV(io.LimitReader(file.File, maxImportFileSize))
+	if err != nil {
+		return nil, errcode.Validation("%s", err.Error())
+	}
+
+	if err := r.rejectExistingUsers(ctx, rows); err != nil {
+		return nil, err
+	}
+
+	var users []*models.User
+	for _, row := range rows {
+		if row.user == nil {
+			continue
+		}
+		row.user.FacultyID = &faculty.ID
+		if department != nil {
+			row.user.DepartmentID = &department.ID
+		}
+		users = append(users, row.user)
+	}
+	if err := hashTemporaryPasswords(users); err != nil {
+		return nil, fmt.Errorf("failed to import users")
+	}
+
+	if len(users) > 0 {
+		err = r.inTransaction(ctx, func(tx *gorm.DB, after *database.AfterCommit) error {
+			if err := tx.CreateInBatches(users, importBatchSize).Error; err != nil {
+				return err
+			}
+			after.Do(func() {
+				go r.sendImportInvites(context.WithoutCancel(ctx), users)
+			})
+			return nil
+		})
+		if database.IsUniqueViolation(err) {
+			switch duplicateUserField(err) {
+			case "studentID":
+				return nil, errcode.Conflict("a student ID in this file was registered while the import ran, please import the file again")
+			case "email":
+				return nil, errcode.Conflict("an email in this file was registered while the import ran, please import the file again")
+			}
+			return nil, errcode.Conflict("some of these users were registered while the import ran, please import the file again")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to import users")
+		}
+	}
+
+	result := &model.UserImportResult{Total: len(rows), Rows: make([]*model.UserImportRow, 0, len(rows))}
+	for _, row := range rows {
+		switch {
+		case row.user != nil:
+			row.result.Status = model.UserImportStatusCreated
+			row.result.User = convertUserToGraphQL(row.user)
+			result.Created++
+		case row.result.Status == model.UserImportStatusDuplicate:
+			result.Duplicates++
+		default:
+			result.Invalid++
+		}
+		result.Rows = append(result.Rows, row.result)
+	}
+
+	details := map[string]interface{}{
+		"faculty_id": faculty.ID,
+		"file_name":  file.Filename,
+		"total":      result.Total,
+		"created":    result.Created,
+		"duplicates": result.Duplicates,
+		"invalid":    result.Invalid,
+	}
+	if department != nil {
+		details["department_id"] = department.ID
+	}
+	r.logAdminAction(ctx, audit.ActionImport, audit.ResourceUser, "", details)
+
+	return result, nil
+}
+
+// parseUserImportCSV reads the import's rows, validating each and rejecting repeats within
+// the file. The header names the columns; names come from first_name and last_name, or from
+// a single name column split at the first space.
+func parseUserImportCSV(reader io.Reader) ([]*importRow, error) {
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = -1
+	records.TrimLeadingSpace = true
+
+	header, err := records.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if canonical, ok := importHeaderAliases[name]; ok {
+			columns[canonical] = i
+		}
+	}
+	_, hasFirst := columns["first_name"]
+	_, hasLast := columns["last_name"]
+	_, hasName := columns["name"]
+	if _, ok := columns["student_id"]; !ok {
+		return nil, errors.New("CSV header must include a student_id column")
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("CSV header must include an email column")
+	}
+	if !(hasFirst && hasLast) && !hasName {
+		return nil, errors.New("CSV header must include first_name and last_name columns, or a name column")
+	}
+
+	var rows []*importRow
+	studentIDRows := make(map[string]int)
+	emailRows := make(map[string]int)
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("file has more than %d rows; split it into smaller imports", maxImportRows)
+		}
+
+		field := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		line, _ := records.FieldPos(0)
+		row := &importRow{result: &model.UserImportRow{
+			Row:       line,
+			StudentID: field("student_id"),
+			Email:     strings.ToLower(field("email")),
+		}}
+		rows = append(rows, row)
+
+		firstName, lastName := field("first_name"), field("last_name")
+		if !(hasFirst && hasLast) {
+			if parts := strings.Fields(field("name")); len(parts) > 0 {
+				firstName, lastName = parts[0], strings.Join(parts[1:], " ")
+			}
+		}
+		if message := validateImportRow(row.result.StudentID, row.result.Email, firstName, lastName); message != "" {
+			row.reject(model.UserImportStatusInvalid, message)
+			continue
+		}
+
+		if previous, ok := studentIDRows[row.result.StudentID]; ok {
+			row.reject(model.UserImportStatusDuplicate, fmt.Sprintf("student ID repeats row %d", previous))
+			continue
+		}
+		if previous, ok := emailRows[row.result.Email]; ok {
+			row.reject(model.UserImportStatusDuplicate, fmt.Sprintf("email repeats row %d", previous))
+			continue
+		}
+		studentIDRows[row.result.StudentID] = line
+		emailRows[row.result.Email] = line
+
+		row.user = &models.User{
+			StudentID: row.result.StudentID,
+			Email:     row.result.Email,
+			FirstName: firstName,
+			LastName:  lastName,
+			Role:      models.UserRoleStudent,
+			QRSecret:  utils.GenerateQRSecret(),
+			IsActive:  true,
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("file has no rows to import")
+	}
+	return rows, nil
+}
+
+// validateImportRow returns why a row can't be imported, or "" if it can
+func validateImportRow(studentID, email, firstName, lastName string) string {
+	switch {
+	case studentID == "":
+		return "student ID is required"
+	case len(studentID) > importStudentIDMaxLength:
+		return fmt.Sprintf("student ID must be at most %d characters", importStudentIDMaxLength)
+	case strings.ContainsAny(studentID, " \t"):
+		return "student ID must not contain spaces"
+	case email == "":
+		return "email is required"
+	case len(email) > importEmailMaxLength:
+		return fmt.Sprintf("email must be at most %d characters", importEmailMaxLength)
+	case firstName == "" || lastName == "":
+		return "first and last name are required"
+	case utf8.RuneCountInString(firstName) > importNameMaxLength || utf8.RuneCountInString(lastName) > importNameMaxLength:
+		return fmt.Sprintf("first and last name must each be at most %d characters", importNameMaxLength)
+	}
+
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return "email is not a valid address"
+	}
+	return ""
+}
+
+// rejectExistingUsers marks rows whose student ID or email is already taken as duplicates.
+// Deleted users still hold theirs in the unique indexes, so they count too.
+func (r *Resolver) rejectExistingUsers(ctx context.Context, rows []*importRow) error {
+	var candidates []*importRow
+	for _, row := range rows {
+		if row.user != nil {
+			candidates = append(candidates, row)
+		}
+	}
+
+	takenStudentIDs := make(map[string]bool)
+	takenEmails := make(map[string]bool)
+	for start := 0; start < len(candidates); start += importLookupChunk {
+		end := min(start+importLookupChunk, len(candidates))
+		studentIDs := make([]string, 0, end-start)
+		emails := make([]string, 0, end-start)
+		for _, row := range candidates[start:end] {
+			studentIDs = append(studentIDs, row.user.StudentID)
+			emails = append(emails, row.user.Email)
+		}
+
+		var existing []models.User
+		if err := r.DB.WithContext(ctx).Unscoped().Select("student_id", "email").
+			Where("student_id IN ? OR LOWER(email) IN ?", studentIDs, emails).
+			Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check existing users")
+		}
+		for _, user := range existing {
+			takenStudentIDs[user.StudentID] = true
+			takenEmails[strings.ToLower(user.Email)] = true
+		}
+	}
+
+	for _, row := range candidates {
+		switch {
+		case takenStudentIDs[row.user.StudentID]:
+			row.reject(model.UserImportStatusDuplicate, "student ID is already registered")
+		case takenEmails[row.user.Email]:
+			row.reject(model.UserImportStatusDuplicate, "email is already registered")
+		}
+	}
+	return nil
+}
+
+// hashTemporaryPasswords gives each user a random password nobody knows, hashing them in
+// parallel since bcrypt is slow by design. Users sign in after setting their own.
+func hashTemporaryPasswords(users []*models.User) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, runtime.NumCPU())
+
+	for _, user := range users {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(user *models.User) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			bytes := make([]byte, 32)
+			_, err := rand.Read(bytes)
+			if err == nil {
+				user.Password, err = utils.HashPassword(hex.EncodeToString(bytes))
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(user)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// sendImportInvites emails each imported user a link to set their password. Users it fails
+// for can still use the forgotten password flow.
+func (r *Resolver) sendImportInvites(ctx context.Context, users []*models.User) {
+	var failed int
+	for _, user := range users {
+		token, err := r.PasswordResets.CreateInviteToken(ctx, user.ID)
+		if err != nil {
+			log.Printf("Failed to create invite token for imported user %d: %v", user.ID, err)
+			failed++
+			continue
+		}
+
+		setPasswordURL := fmt.Sprintf("%s?token=%s", r.PasswordResetURL, token)
+		if r.NotificationService != nil {
+			if err := r.NotificationService.SendAccountInviteEmail(user, setPasswordURL); err != nil {
+				log.Printf("Failed to send invite email to imported user %d: %v", user.ID, err)
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("Sent invites to %d of %d imported users", len(users)-failed, len(users))
+	}
+}
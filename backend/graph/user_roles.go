@@ -0,0 +1,206 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRoleChangedConcurrently = errors.New("user's role was changed by someone else, please reload and try again")
+	errLastSuperAdmin          = errors.New("cannot remove the last super admin")
+)
+
+// roleRank orders roles by privilege; a change to a lower rank is a downgrade
+func roleRank(role models.UserRole) int {
+	switch role {
+	case models.UserRoleSuperAdmin:
+		return 3
+	case models.UserRoleFacultyAdmin:
+		return 2
+	case models.UserRoleRegularAdmin:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseUserRole(role models.UserRole) (models.UserRole, bool) {
+	switch normalized := models.UserRole(strings.ToLower(string(role))); normalized {
+	case models.UserRoleStudent, models.UserRoleRegularAdmin, models.UserRoleFacultyAdmin, models.UserRoleSuperAdmin:
+		return normalized, true
+	}
+	return "", false
+}
+
+func sameFacultyID(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// updateUserRole changes a user's role and faculty, clearing the department when the faculty
+// changes. Super admins may set any role; faculty admins may only promote students in their
+// faculty to regular admin. The row is locked and re-checked in a transaction, and downgraded
+// users are signed out everywhere.
+func (r *Resolver) updateUserRole(ctx context.Context, userID string, role models.UserRole, facultyID *string) (*models.User, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	newRole, ok := parseUserRole(role)
+	if !ok {
+		return nil, errcode.Validation("invalid role")
+	}
+
+	uID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, errcode.Validation("invalid user ID")
+	}
+	if uint(uID) == authCtx.UserID {
+		return nil, errcode.Validation("cannot change your own role")
+	}
+
+	var user models.User
+	if err := r.DB.First(&user, uID).Error; err != nil {
+		return nil, errcode.NotFound("user not found")
+	}
+	oldRole := user.Role
+
+	newFacultyID := user.FacultyID
+	if facultyID != nil {
+		fID, err := strconv.ParseUint(*facultyID, 10, 32)
+		if err != nil {
+			return nil, errcode.Validation("invalid faculty ID")
+		}
+		var faculty models.Faculty
+		if err := r.DB.First(&faculty, fID).Error; err != nil {
+			return nil, errcode.NotFound("faculty not found")
+		}
+		id := uint(fID)
+		newFacultyID = &id
+	}
+
+	if authCtx.Role != models.UserRoleSuperAdmin {
+		if newRole != models.UserRoleRegularAdmin || oldRole != models.UserRoleStudent {
+			if roleRank(newRole) > roleRank(models.UserRoleRegularAdmin) {
+				r.logRoleEscalationAttempt(ctx, authCtx, &user, newRole)
+			}
+			return nil, errcode.Forbidden("faculty admins can only promote students to regular admin")
+		}
+		if newFacultyID == nil {
+			newFacultyID = authCtx.FacultyID
+		}
+		if _, err := r.requireFacultyScope(ctx, newFacultyID, audit.ResourceUser, userID); err != nil {
+			return nil, err
+		}
+		if user.FacultyID != nil {
+			if _, err := r.requireFacultyScope(ctx, user.FacultyID, audit.ResourceUser, userID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if (newRole == models.UserRoleFacultyAdmin || newRole == models.UserRoleRegularAdmin) && newFacultyID == nil {
+		return nil, errcode.Validation("faculty is required for faculty and regular admins")
+	}
+	if newRole == oldRole && sameFacultyID(user.FacultyID, newFacultyID) {
+		return nil, errcode.Conflict("user already has this role")
+	}
+
+	err = r.DB.TransactionWithRetry(ctx, func(tx *gorm.DB) error {
+		var current models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, user.ID).Error; err != nil {
+			return err
+		}
+		if current.Role != oldRole {
+			return errRoleChangedConcurrently
+		}
+
+		if oldRole == models.UserRoleSuperAdmin && newRole != models.UserRoleSuperAdmin {
+			var superAdmins int64
+			if err := tx.Model(&models.User{}).Where("role = ?", models.UserRoleSuperAdmin).Count(&superAdmins).Error; err != nil {
+				return err
+			}
+			if superAdmins <= 1 {
+				return errLastSuperAdmin
+			}
+		}
+
+		updates := map[string]interface{}{
+			"role":       newRole,
+			"faculty_id": newFacultyID,
+		}
+		// A department belongs to one faculty, so moving faculties leaves the user without one
+		if !sameFacultyID(current.FacultyID, newFacultyID) {
+			updates["department_id"] = nil
+		}
+		return tx.Model(&current).Updates(updates).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errRoleChangedConcurrently), errors.Is(err, errLastSuperAdmin):
+		return nil, errcode.Conflict("%v", err)
+	case errors.Is(err, database.ErrRetriesExhausted):
+		return nil, err
+	default:
+		return nil, fmt.Errorf("failed to update user role")
+	}
+	r.invalidateUserCache(ctx, user.ID)
+
+	downgraded := roleRank(newRole) < roleRank(oldRole)
+	if downgraded {
+		if _, err := r.signOutEverywhere(ctx, user.ID); err != nil {
+			log.Printf("Failed to revoke sessions for user %d: %v", user.ID, err)
+		}
+	}
+
+	// role_changes is what the audit logger's privilege escalation check reads
+	roleChanges := map[string]interface{}{
+		"old_role": string(oldRole),
+		"new_role": string(newRole),
+	}
+	if user.FacultyID != nil {
+		roleChanges["old_faculty_id"] = *user.FacultyID
+	}
+	if newFacultyID != nil {
+		roleChanges["new_faculty_id"] = *newFacultyID
+	}
+	r.logAdminAction(ctx, audit.ActionUpdate, audit.ResourceUser, userID, map[string]interface{}{
+		"email":            user.Email,
+		"role_changes":     roleChanges,
+		"sessions_revoked": downgraded,
+	})
+
+	r.DB.Preload("Faculty").Preload("Department").First(&user, user.ID)
+	return convertUserToGraphQL(&user), nil
+}
+
+// logRoleEscalationAttempt records a faculty admin trying to grant a role above their own reach
+func (r *Resolver) logRoleEscalationAttempt(ctx context.Context, authCtx *middleware.AuthContext, user *models.User, newRole models.UserRole) {
+	log.Printf("User %d tried to grant %s to user %d", authCtx.UserID, newRole, user.ID)
+
+	r.logSecurityEvent(ctx, &audit.SecurityEvent{
+		EventType: audit.SecurityEventPrivilegeEscalation,
+		UserID:    strconv.FormatUint(uint64(authCtx.UserID), 10),
+		Details: map[string]interface{}{
+			"resource":       audit.ResourceUser,
+			"resource_id":    user.ID,
+			"user_role":      string(authCtx.Role),
+			"target_role":    string(user.Role),
+			"requested_role": string(newRole),
+		},
+		RiskLevel: audit.RiskLevelHigh,
+		Blocked:   true,
+	})
+}
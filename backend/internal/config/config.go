@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -11,10 +12,211 @@ import (
 type Config struct {
 	DatabaseURL    string
 	RedisURL       string
+	RedisKeyPrefix string // e.g. "tru:prod:", for deployments sharing a Redis
 	JWTSecret      string
 	JWTExpireHours int
+	QRMasterSecret string
 	Port           string
 	Environment    string
+
+	// Optional JWT claims; names are only embedded together with codes
+	JWTOrgCodeClaims bool
+	JWTOrgNameClaims bool
+
+	// QR scan rate limits, per student and per scanner device
+	QRStudentScanLimit         int
+	QRStudentScanWindowSeconds int
+	QRScannerScanLimit         int
+	QRScannerScanWindowSeconds int
+
+	// How many QR codes a student may generate for themselves per window
+	QRGenerationLimit         int
+	QRGenerationWindowSeconds int
+
+	// Scanner clock drift tolerated on both ends of a QR code's validity window
+	QRClockSkewSeconds int
+
+	// Encoding of newly issued QR codes (json or binary) and the longest scanned QR string accepted
+	QREncoding         string
+	QRMaxPayloadLength int
+
+	// Circuit breaker around QR validation's Redis checks; with degraded mode on, scans are still
+	// accepted on signature, timestamp and expiry while it is open
+	QRRedisBreakerThreshold       int
+	QRRedisBreakerCooldownSeconds int
+	QRDegradedMode                bool
+
+	// Startup
+	StartupLockWaitSeconds int
+
+	// Retries for transient database errors (deadlocks, serialization failures)
+	DBRetryMaxAttempts int
+	DBRetryBaseDelayMs int
+
+	// Database connection pool; idle connections can't exceed open ones
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeMinutes int
+	DBConnMaxIdleTimeMinutes int
+
+	// Response compression
+	CompressionEnabled      bool
+	CompressionMinBytes     int
+	CompressionContentTypes []string
+
+	// Activity archiving
+	ActivityArchiveAfterDays int
+
+	// Activity date validation (0 max days disables the duration cap)
+	ActivityStartGraceMinutes int
+	ActivityMaxDurationDays   int
+
+	// IANA zone of activities whose faculty sets none, e.g. Asia/Bangkok
+	DefaultTimezone string
+
+	// Unstaffed activity alerts
+	UnstaffedAlertLeadHours int
+
+	// Activity reschedules (0 hours disables auto-confirm)
+	RescheduleConfirmWindowHours int
+	RescheduleReopenRegistration bool
+
+	// Subscription expiry: faculties are warned once at each of these days before expiry
+	SubscriptionWarningDays []int
+
+	// Security
+	EnforceFacultyScope bool
+
+	// Proxies, as IPs or CIDR ranges, whose X-Forwarded-For entries are believed about the client
+	// address; by default the private ranges Cloud Run and load balancers connect from
+	TrustedProxies []string
+
+	// GraphQL rate limits per rolling window: per user, higher for admins, and per client IP for
+	// signed-out callers and for login, register and password reset attempts
+	RateLimitWindowSeconds int
+	RateLimitDefault       int
+	RateLimitAdmin         int
+	RateLimitAnonymous     int
+	RateLimitAuth          int
+	RateLimitQRScan        int
+
+	// Password policy; an empty breach list path disables the breached password check
+	PasswordMinLength       int
+	PasswordRequireLetter   bool
+	PasswordRequireUpper    bool
+	PasswordRequireLower    bool
+	PasswordRequireDigit    bool
+	PasswordRequireSymbol   bool
+	PasswordBreachListPath  string
+	PasswordBreachFalseRate float64
+
+	// CORS origins allowed to make credentialed requests; "https://*.example.com" matches subdomains.
+	// CORSAllowAllInDevelopment accepts any origin in development, with credentials disabled.
+	CORSAllowedOrigins        []string
+	CORSAllowAllInDevelopment bool
+
+	// Exports
+	MaxConcurrentExports int
+	ExportPageSize       int
+
+	// Mutation replay window for Idempotency-Key headers
+	IdempotencyTTLSeconds int
+
+	// Audit log batching
+	AuditBatchSize       int
+	AuditFlushIntervalMs int
+	AuditAsyncWrites     bool
+
+	// Audit detail masking. Keys matching AuditMaskHashKeys are stored as a keyed hash of their
+	// value, keyed by AuditMaskHashSecret (the JWT secret when unset), and keys matching
+	// AuditMaskRedactKeys are redacted. Patterns may use * wildcards; unset lists keep the defaults.
+	AuditMaskHashKeys   []string
+	AuditMaskRedactKeys []string
+	AuditMaskHashSecret string
+
+	// Activity join limits (0 disables)
+	DailyJoinLimit  int
+	ActiveJoinLimit int
+
+	// Realtime (Cloud Run sends SIGTERM ~10s before stopping an instance, so drain within that)
+	ConnectionStatsIntervalSeconds   int
+	ConnectionIdleTimeoutSeconds     int
+	ConnectionCleanupIntervalSeconds int
+	ConnectionDrainSeconds           int
+
+	ConnectionHeartbeatSeconds   int
+	ConnectionInstanceTTLSeconds int
+
+	// Connection churn and fan-out are recorded as performance metrics this often (0 disables)
+	ConnectionMetricsIntervalSeconds int
+	// LoadTestEnabled exposes the super admin endpoint that opens synthetic connections
+	LoadTestEnabled bool
+
+	// Per-connection channel buffers; a consumer dropping BufferOverflowThreshold messages in a row is reported
+	ConnectionBufferSize    int
+	SSEBufferSize           int
+	BufferOverflowThreshold int
+
+	// Events sent to many recipients are published RealtimeFanoutBatchSize at a time, to at most
+	// RealtimeFanoutMaxRecipients; fan-outs above RealtimeFanoutBackgroundThreshold recipients
+	// are published in the background, and above RealtimeFanoutAlertThreshold raise an alert
+	RealtimeFanoutBatchSize           int
+	RealtimeFanoutMaxRecipients       int
+	RealtimeFanoutBackgroundThreshold int
+	RealtimeFanoutAlertThreshold      int
+
+	// Subscription payloads are held back up to this long to forward each entity's events in
+	// sequence order; 0 forwards them as received and leaves ordering to clients
+	SubscriptionReorderWindowMs int
+
+	// SSE keepalive; clients may request heartbeats down to SSEMinHeartbeatSeconds with ?heartbeat=
+	SSEHeartbeatSeconds    int
+	SSEMinHeartbeatSeconds int
+	SSEIdleTimeoutSeconds  int
+
+	// Reconnect delay suggested to SSE clients the server disconnects: doubling from the base delay
+	// up to the max, plus up to SSEReconnectJitterSeconds of random jitter to spread reconnections
+	SSEReconnectBaseDelayMs     int
+	SSEReconnectMaxDelaySeconds int
+	SSEReconnectJitterSeconds   int
+
+	// Per-statement database query statistics for the slowQueries and queryStatistics queries, off by default
+	QueryStatsEnabled bool
+
+	// Preload faculties, active activities and metrics into the cache at startup; off for fast local restarts
+	CacheWarmOnStartup bool
+
+	// Strict persisted queries: only allowlisted operations run, loaded from PersistedQueriesFile
+	// and the persisted_queries table. Off leaves automatic persisted queries permissive.
+	PersistedQueriesStrict bool
+	PersistedQueriesFile   string
+
+	// Monitoring: OTLP/HTTP trace export, off by default
+	TracingEnabled     bool
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+
+	// GraphQL operations taking GraphQLSlowOperationMs or more are logged as slow, blamed on the
+	// query from GraphQLHighComplexity and on the data layer below it
+	GraphQLSlowOperationMs int
+	GraphQLHighComplexity  int
+
+	// Critical performance alerts go to these notifiers ("email", "slack"), at most once per
+	// AlertNotifyThrottleMinutes per metric; AlertDashboardURL is linked from each message
+	AlertNotifiers             []string
+	AlertNotifyThrottleMinutes int
+	AlertDashboardURL          string
+	AlertEmailTo               []string
+	AlertSlackWebhookURL       string
+
+	// Email
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+	PasswordResetURL string
 }
 
 func Load() *Config {
@@ -23,14 +225,254 @@ func Load() *Config {
 	}
 
 	jwtExpireHours, _ := strconv.Atoi(getEnv("JWT_EXPIRE_HOURS", "24"))
+	jwtOrgCodeClaims, _ := strconv.ParseBool(getEnv("JWT_ORG_CODE_CLAIMS", "true"))
+	jwtOrgNameClaims, _ := strconv.ParseBool(getEnv("JWT_ORG_NAME_CLAIMS", "false"))
+	startupLockWaitSeconds, _ := strconv.Atoi(getEnv("STARTUP_LOCK_WAIT_SECONDS", "120"))
+	compressionEnabled, _ := strconv.ParseBool(getEnv("COMPRESSION_ENABLED", "true"))
+	compressionMinBytes, _ := strconv.Atoi(getEnv("COMPRESSION_MIN_BYTES", "1024"))
+	activityArchiveAfterDays, _ := strconv.Atoi(getEnv("ACTIVITY_ARCHIVE_AFTER_DAYS", "30"))
+	activityStartGraceMinutes, _ := strconv.Atoi(getEnv("ACTIVITY_START_GRACE_MINUTES", "60"))
+	activityMaxDurationDays, _ := strconv.Atoi(getEnv("ACTIVITY_MAX_DURATION_DAYS", "30"))
+	enforceFacultyScope, _ := strconv.ParseBool(getEnv("ENFORCE_FACULTY_SCOPE", "true"))
+	trustedProxies := getEnvList("TRUSTED_PROXIES")
+	if trustedProxies == nil {
+		trustedProxies = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16", "fc00::/7"}
+	}
+	rateLimitWindowSeconds, _ := strconv.Atoi(getEnv("RATE_LIMIT_WINDOW_SECONDS", "60"))
+	rateLimitDefault, _ := strconv.Atoi(getEnv("RATE_LIMIT_DEFAULT", "100"))
+	rateLimitAdmin, _ := strconv.Atoi(getEnv("RATE_LIMIT_ADMIN", "1000"))
+	rateLimitAnonymous, _ := strconv.Atoi(getEnv("RATE_LIMIT_ANONYMOUS", "30"))
+	rateLimitAuth, _ := strconv.Atoi(getEnv("RATE_LIMIT_AUTH", "10"))
+	rateLimitQRScan, _ := strconv.Atoi(getEnv("RATE_LIMIT_QR_SCAN", "30"))
+	passwordMinLength, _ := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8"))
+	passwordRequireLetter, _ := strconv.ParseBool(getEnv("PASSWORD_REQUIRE_LETTER", "true"))
+	passwordRequireUpper, _ := strconv.ParseBool(getEnv("PASSWORD_REQUIRE_UPPER", "false"))
+	passwordRequireLower, _ := strconv.ParseBool(getEnv("PASSWORD_REQUIRE_LOWER", "false"))
+	passwordRequireDigit, _ := strconv.ParseBool(getEnv("PASSWORD_REQUIRE_DIGIT", "true"))
+	passwordRequireSymbol, _ := strconv.ParseBool(getEnv("PASSWORD_REQUIRE_SYMBOL", "false"))
+	passwordBreachFalseRate, _ := strconv.ParseFloat(getEnv("PASSWORD_BREACH_FALSE_POSITIVE_RATE", "0.001"), 64)
+	corsAllowedOrigins := getEnvList("CORS_ORIGINS")
+	if corsAllowedOrigins == nil {
+		corsAllowedOrigins = []string{"https://tru.ac.th", "https://*.tru.ac.th"}
+	}
+	corsAllowAllInDevelopment, _ := strconv.ParseBool(getEnv("CORS_ALLOW_ALL_IN_DEVELOPMENT", "false"))
+	unstaffedAlertLeadHours, _ := strconv.Atoi(getEnv("UNSTAFFED_ALERT_LEAD_HOURS", "24"))
+	rescheduleConfirmWindowHours, _ := strconv.Atoi(getEnv("RESCHEDULE_CONFIRM_WINDOW_HOURS", "48"))
+	rescheduleReopenRegistration, _ := strconv.ParseBool(getEnv("RESCHEDULE_REOPEN_REGISTRATION", "true"))
+	var subscriptionWarningDays []int
+	for _, value := range strings.Split(getEnv("SUBSCRIPTION_WARNING_DAYS", "30,14,7,1"), ",") {
+		if days, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && days > 0 {
+			subscriptionWarningDays = append(subscriptionWarningDays, days)
+		}
+	}
+	maxConcurrentExports, _ := strconv.Atoi(getEnv("MAX_CONCURRENT_EXPORTS", "2"))
+	qrStudentScanLimit, _ := strconv.Atoi(getEnv("QR_STUDENT_SCAN_LIMIT", "5"))
+	qrStudentScanWindowSeconds, _ := strconv.Atoi(getEnv("QR_STUDENT_SCAN_WINDOW_SECONDS", "60"))
+	qrScannerScanLimit, _ := strconv.Atoi(getEnv("QR_SCANNER_SCAN_LIMIT", "120"))
+	qrScannerScanWindowSeconds, _ := strconv.Atoi(getEnv("QR_SCANNER_SCAN_WINDOW_SECONDS", "60"))
+	qrGenerationLimit, _ := strconv.Atoi(getEnv("QR_GENERATION_LIMIT", "10"))
+	qrGenerationWindowSeconds, _ := strconv.Atoi(getEnv("QR_GENERATION_WINDOW_SECONDS", "60"))
+	qrClockSkewSeconds, _ := strconv.Atoi(getEnv("QR_CLOCK_SKEW_SECONDS", "60"))
+	qrMaxPayloadLength, _ := strconv.Atoi(getEnv("QR_MAX_PAYLOAD_LENGTH", "2000"))
+	qrRedisBreakerThreshold, _ := strconv.Atoi(getEnv("QR_REDIS_BREAKER_THRESHOLD", "5"))
+	qrRedisBreakerCooldownSeconds, _ := strconv.Atoi(getEnv("QR_REDIS_BREAKER_COOLDOWN_SECONDS", "30"))
+	qrDegradedMode, _ := strconv.ParseBool(getEnv("QR_DEGRADED_MODE", "true"))
+	exportPageSize, _ := strconv.Atoi(getEnv("EXPORT_PAGE_SIZE", "1000"))
+	dbRetryMaxAttempts, _ := strconv.Atoi(getEnv("DB_RETRY_MAX_ATTEMPTS", "3"))
+	dbRetryBaseDelayMs, _ := strconv.Atoi(getEnv("DB_RETRY_BASE_DELAY_MS", "50"))
+	dbMaxOpenConns, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
+	dbMaxIdleConns, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10"))
+	dbConnMaxLifetimeMinutes, _ := strconv.Atoi(getEnv("DB_CONN_MAX_LIFETIME_MINUTES", "30"))
+	dbConnMaxIdleTimeMinutes, _ := strconv.Atoi(getEnv("DB_CONN_MAX_IDLE_TIME_MINUTES", "5"))
+
+	alertNotifyThrottleMinutes, _ := strconv.Atoi(getEnv("ALERT_NOTIFY_THROTTLE_MINUTES", "15"))
+	idempotencyTTLSeconds, _ := strconv.Atoi(getEnv("IDEMPOTENCY_TTL_SECONDS", "300"))
+	auditBatchSize, _ := strconv.Atoi(getEnv("AUDIT_BATCH_SIZE", "100"))
+	auditFlushIntervalMs, _ := strconv.Atoi(getEnv("AUDIT_FLUSH_INTERVAL_MS", "500"))
+	auditAsyncWrites, _ := strconv.ParseBool(getEnv("AUDIT_ASYNC_WRITES", "false"))
+	dailyJoinLimit, _ := strconv.Atoi(getEnv("DAILY_JOIN_LIMIT", "10"))
+	activeJoinLimit, _ := strconv.Atoi(getEnv("ACTIVE_JOIN_LIMIT", "20"))
+	connectionStatsIntervalSeconds, _ := strconv.Atoi(getEnv("CONNECTION_STATS_INTERVAL_SECONDS", "10"))
+	connectionIdleTimeoutSeconds, _ := strconv.Atoi(getEnv("CONNECTION_IDLE_TIMEOUT_SECONDS", "600"))
+	connectionCleanupIntervalSeconds, _ := strconv.Atoi(getEnv("CONNECTION_CLEANUP_INTERVAL_SECONDS", "120"))
+	tracingEnabled, _ := strconv.ParseBool(getEnv("TRACING_ENABLED", "false"))
+	tracingInsecure, _ := strconv.ParseBool(getEnv("TRACING_INSECURE", "false"))
+	tracingSampleRatio, _ := strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1.0"), 64)
+	connectionDrainSeconds, _ := strconv.Atoi(getEnv("CONNECTION_DRAIN_SECONDS", "8"))
+	connectionHeartbeatSeconds, _ := strconv.Atoi(getEnv("CONNECTION_HEARTBEAT_SECONDS", "15"))
+	connectionInstanceTTLSeconds, _ := strconv.Atoi(getEnv("CONNECTION_INSTANCE_TTL_SECONDS", "60"))
+	connectionMetricsIntervalSeconds, _ := strconv.Atoi(getEnv("CONNECTION_METRICS_INTERVAL_SECONDS", "10"))
+	loadTestEnabled, _ := strconv.ParseBool(getEnv("LOAD_TEST_ENABLED", "false"))
+	connectionBufferSize, _ := strconv.Atoi(getEnv("CONNECTION_BUFFER_SIZE", "100"))
+	subscriptionReorderWindowMs, _ := strconv.Atoi(getEnv("SUBSCRIPTION_REORDER_WINDOW_MS", "0"))
+	realtimeFanoutBatchSize, _ := strconv.Atoi(getEnv("REALTIME_FANOUT_BATCH_SIZE", "100"))
+	realtimeFanoutMaxRecipients, _ := strconv.Atoi(getEnv("REALTIME_FANOUT_MAX_RECIPIENTS", "10000"))
+	realtimeFanoutBackgroundThreshold, _ := strconv.Atoi(getEnv("REALTIME_FANOUT_BACKGROUND_THRESHOLD", "200"))
+	realtimeFanoutAlertThreshold, _ := strconv.Atoi(getEnv("REALTIME_FANOUT_ALERT_THRESHOLD", "1000"))
+	sseBufferSize, _ := strconv.Atoi(getEnv("SSE_BUFFER_SIZE", "64"))
+	bufferOverflowThreshold, _ := strconv.Atoi(getEnv("BUFFER_OVERFLOW_THRESHOLD", "10"))
+	sseHeartbeatSeconds, _ := strconv.Atoi(getEnv("SSE_HEARTBEAT_SECONDS", "30"))
+	sseMinHeartbeatSeconds, _ := strconv.Atoi(getEnv("SSE_MIN_HEARTBEAT_SECONDS", "5"))
+	sseIdleTimeoutSeconds, _ := strconv.Atoi(getEnv("SSE_IDLE_TIMEOUT_SECONDS", "300"))
+	sseReconnectBaseDelayMs, _ := strconv.Atoi(getEnv("SSE_RECONNECT_BASE_DELAY_MS", "1000"))
+	sseReconnectMaxDelaySeconds, _ := strconv.Atoi(getEnv("SSE_RECONNECT_MAX_DELAY_SECONDS", "30"))
+	sseReconnectJitterSeconds, _ := strconv.Atoi(getEnv("SSE_RECONNECT_JITTER_SECONDS", "10"))
+	queryStatsEnabled, _ := strconv.ParseBool(getEnv("QUERY_STATS_ENABLED", "false"))
+	cacheWarmOnStartup, _ := strconv.ParseBool(getEnv("CACHE_WARM_ON_STARTUP", "true"))
+	persistedQueriesStrict, _ := strconv.ParseBool(getEnv("PERSISTED_QUERIES_STRICT", "false"))
+	graphQLSlowOperationMs, _ := strconv.Atoi(getEnv("GRAPHQL_SLOW_OPERATION_MS", "1000"))
+	graphQLHighComplexity, _ := strconv.Atoi(getEnv("GRAPHQL_HIGH_COMPLEXITY", "200"))
 
 	return &Config{
 		DatabaseURL:    buildDatabaseURL(),
 		RedisURL:       buildRedisURL(),
+		RedisKeyPrefix: getEnv("REDIS_KEY_PREFIX", ""),
 		JWTSecret:      getEnv("JWT_SECRET", "default-secret-key"),
+		QRMasterSecret: getEnv("QR_MASTER_SECRET", getEnv("JWT_SECRET", "default-secret-key")),
 		JWTExpireHours: jwtExpireHours,
 		Port:           getEnv("PORT", "8080"),
 		Environment:    getEnv("ENV", "development"),
+
+		JWTOrgCodeClaims: jwtOrgCodeClaims,
+		JWTOrgNameClaims: jwtOrgNameClaims,
+
+		QRStudentScanLimit:         qrStudentScanLimit,
+		QRStudentScanWindowSeconds: qrStudentScanWindowSeconds,
+		QRScannerScanLimit:         qrScannerScanLimit,
+		QRScannerScanWindowSeconds: qrScannerScanWindowSeconds,
+		QRGenerationLimit:          qrGenerationLimit,
+		QRGenerationWindowSeconds:  qrGenerationWindowSeconds,
+
+		QRClockSkewSeconds: qrClockSkewSeconds,
+
+		QREncoding:         getEnv("QR_ENCODING", "json"),
+		QRMaxPayloadLength: qrMaxPayloadLength,
+
+		QRRedisBreakerThreshold:       qrRedisBreakerThreshold,
+		QRRedisBreakerCooldownSeconds: qrRedisBreakerCooldownSeconds,
+		QRDegradedMode:                qrDegradedMode,
+
+		StartupLockWaitSeconds: startupLockWaitSeconds,
+
+		DBRetryMaxAttempts: dbRetryMaxAttempts,
+		DBRetryBaseDelayMs: dbRetryBaseDelayMs,
+
+		DBMaxOpenConns:           dbMaxOpenConns,
+		DBMaxIdleConns:           dbMaxIdleConns,
+		DBConnMaxLifetimeMinutes: dbConnMaxLifetimeMinutes,
+		DBConnMaxIdleTimeMinutes: dbConnMaxIdleTimeMinutes,
+
+		CompressionEnabled:      compressionEnabled,
+		CompressionMinBytes:     compressionMinBytes,
+		CompressionContentTypes: getEnvList("COMPRESSION_CONTENT_TYPES"),
+
+		ActivityArchiveAfterDays: activityArchiveAfterDays,
+
+		ActivityStartGraceMinutes: activityStartGraceMinutes,
+		ActivityMaxDurationDays:   activityMaxDurationDays,
+		DefaultTimezone:           getEnv("DEFAULT_TIMEZONE", "Asia/Bangkok"),
+
+		UnstaffedAlertLeadHours: unstaffedAlertLeadHours,
+
+		RescheduleConfirmWindowHours: rescheduleConfirmWindowHours,
+		RescheduleReopenRegistration: rescheduleReopenRegistration,
+
+		SubscriptionWarningDays: subscriptionWarningDays,
+
+		EnforceFacultyScope: enforceFacultyScope,
+		TrustedProxies:      trustedProxies,
+
+		RateLimitWindowSeconds: rateLimitWindowSeconds,
+		RateLimitDefault:       rateLimitDefault,
+		RateLimitAdmin:         rateLimitAdmin,
+		RateLimitAnonymous:     rateLimitAnonymous,
+		RateLimitAuth:          rateLimitAuth,
+		RateLimitQRScan:        rateLimitQRScan,
+
+		PasswordMinLength:       passwordMinLength,
+		PasswordRequireLetter:   passwordRequireLetter,
+		PasswordRequireUpper:    passwordRequireUpper,
+		PasswordRequireLower:    passwordRequireLower,
+		PasswordRequireDigit:    passwordRequireDigit,
+		PasswordRequireSymbol:   passwordRequireSymbol,
+		PasswordBreachListPath:  getEnv("PASSWORD_BREACH_LIST_PATH", ""),
+		PasswordBreachFalseRate: passwordBreachFalseRate,
+
+		CORSAllowedOrigins:        corsAllowedOrigins,
+		CORSAllowAllInDevelopment: corsAllowAllInDevelopment,
+
+		MaxConcurrentExports: maxConcurrentExports,
+		ExportPageSize:       exportPageSize,
+
+		IdempotencyTTLSeconds: idempotencyTTLSeconds,
+
+		AuditBatchSize:       auditBatchSize,
+		AuditFlushIntervalMs: auditFlushIntervalMs,
+		AuditAsyncWrites:     auditAsyncWrites,
+		AuditMaskHashKeys:    getEnvList("AUDIT_MASK_HASH_KEYS"),
+		AuditMaskRedactKeys:  getEnvList("AUDIT_MASK_REDACT_KEYS"),
+		AuditMaskHashSecret:  getEnv("AUDIT_MASK_HASH_SECRET", ""),
+
+		DailyJoinLimit:  dailyJoinLimit,
+		ActiveJoinLimit: activeJoinLimit,
+
+		ConnectionStatsIntervalSeconds:   connectionStatsIntervalSeconds,
+		ConnectionIdleTimeoutSeconds:     connectionIdleTimeoutSeconds,
+		ConnectionCleanupIntervalSeconds: connectionCleanupIntervalSeconds,
+		ConnectionDrainSeconds:           connectionDrainSeconds,
+
+		ConnectionHeartbeatSeconds:   connectionHeartbeatSeconds,
+		ConnectionInstanceTTLSeconds: connectionInstanceTTLSeconds,
+
+		ConnectionMetricsIntervalSeconds: connectionMetricsIntervalSeconds,
+		LoadTestEnabled:                  loadTestEnabled,
+
+		ConnectionBufferSize:    connectionBufferSize,
+		SSEBufferSize:           sseBufferSize,
+		BufferOverflowThreshold: bufferOverflowThreshold,
+
+		RealtimeFanoutBatchSize:           realtimeFanoutBatchSize,
+		RealtimeFanoutMaxRecipients:       realtimeFanoutMaxRecipients,
+		RealtimeFanoutBackgroundThreshold: realtimeFanoutBackgroundThreshold,
+		RealtimeFanoutAlertThreshold:      realtimeFanoutAlertThreshold,
+
+		SubscriptionReorderWindowMs: subscriptionReorderWindowMs,
+
+		SSEHeartbeatSeconds:    sseHeartbeatSeconds,
+		SSEMinHeartbeatSeconds: sseMinHeartbeatSeconds,
+		SSEIdleTimeoutSeconds:  sseIdleTimeoutSeconds,
+
+		SSEReconnectBaseDelayMs:     sseReconnectBaseDelayMs,
+		SSEReconnectMaxDelaySeconds: sseReconnectMaxDelaySeconds,
+		SSEReconnectJitterSeconds:   sseReconnectJitterSeconds,
+
+		QueryStatsEnabled: queryStatsEnabled,
+
+		CacheWarmOnStartup: cacheWarmOnStartup,
+
+		PersistedQueriesStrict: persistedQueriesStrict,
+		PersistedQueriesFile:   getEnv("PERSISTED_QUERIES_FILE", ""),
+
+		TracingEnabled:     tracingEnabled,
+		TracingEndpoint:    getEnv("TRACING_ENDPOINT", "localhost:4318"),
+		TracingInsecure:    tracingInsecure,
+		TracingSampleRatio: tracingSampleRatio,
+
+		GraphQLSlowOperationMs: graphQLSlowOperationMs,
+		GraphQLHighComplexity:  graphQLHighComplexity,
+
+		AlertNotifiers:             getEnvList("ALERT_NOTIFIERS"),
+		AlertNotifyThrottleMinutes: alertNotifyThrottleMinutes,
+		AlertDashboardURL:          getEnv("ALERT_DASHBOARD_URL", ""),
+		AlertEmailTo:               getEnvList("ALERT_EMAIL_TO"),
+		AlertSlackWebhookURL:       getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+
+		SMTPHost:         getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:         getEnv("SMTP_FROM", "noreply@tru.ac.th"),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
 	}
 }
 
@@ -42,7 +484,7 @@ func buildDatabaseURL() string {
 	dbname := getEnv("DB_NAME", "tru_activity")
 	sslmode := getEnv("DB_SSLMODE", "disable")
 
-	return "host=" + host + " port=" + port + " user=" + user + " password=" + password + " dbname=" + dbname + " sslmode=" + sslmode
+	return "host=" + host + " port=" + port + " user=" + user + " password=" + password + " dbname=" + dbname + " sslmode=" + sslmode + " TimeZone=UTC"
 }
 
 func buildRedisURL() string {
@@ -62,3 +504,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated variable, returning nil when it is unset
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
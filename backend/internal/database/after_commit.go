@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// AfterCommit collects work, such as publishing events, that must only happen once a
+// transaction has committed
+type AfterCommit struct {
+	hooks []func()
+}
+
+// Do queues fn to run after the transaction commits. It never runs if the transaction rolls back.
+func (a *AfterCommit) Do(fn func()) {
+	a.hooks = append(a.hooks, fn)
+}
+
+func (a *AfterCommit) run() {
+	for _, fn := range a.hooks {
+		fn()
+	}
+}
+
+// RetryTransactionAfterCommit is RetryTransaction for functions with side effects. Hooks queued
+// by an attempt that rolls back are discarded; those of the committed attempt run in order.
+func RetryTransactionAfterCommit(ctx context.Context, db *gorm.DB, policy RetryPolicy, fn func(tx *gorm.DB, after *AfterCommit) error) error {
+	var after *AfterCommit
+	err := RetryTransaction(ctx, db, policy, func(tx *gorm.DB) error {
+		after = &AfterCommit{}
+		return fn(tx, after)
+	})
+	if err != nil {
+		return err
+	}
+
+	after.run()
+	return nil
+}
+
+// TransactionAfterCommit runs fn with the connection's retry policy and then its after-commit hooks
+func (db *DB) TransactionAfterCommit(ctx context.Context, fn func(tx *gorm.DB, after *AfterCommit) error) error {
+	return RetryTransactionAfterCommit(ctx, db.DB, db.RetryPolicy, fn)
+}
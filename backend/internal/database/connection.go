@@ -10,6 +10,9 @@ import (
 
 type DB struct {
 	*gorm.DB
+
+	// RetryPolicy applies to TransactionWithRetry
+	RetryPolicy RetryPolicy
 }
 
 func NewConnection(databaseURL string, env string) (*DB, error) {
@@ -28,7 +31,7 @@ func NewConnection(databaseURL string, env string) (*DB, error) {
 	}
 
 	log.Println("Database connected successfully")
-	return &DB{db}, nil
+	return &DB{DB: db, RetryPolicy: DefaultRetryPolicy}, nil
 }
 
 func (db *DB) Migrate(models ...interface{}) error {
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Postgres error codes that are safe to retry by re-running the whole transaction
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateUniqueViolation      = "23505"
+)
+
+var ErrRetriesExhausted = errors.New("database is busy, please try again")
+
+// RetryPolicy bounds how often a transaction is retried after a transient error
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+}
+
+// sqlStateError is implemented by pgconn.PgError
+type sqlStateError interface {
+	SQLState() string
+}
+
+// IsRetryable reports whether err is a deadlock or serialization failure
+func IsRetryable(err error) bool {
+	var pgErr sqlStateError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+func IsUniqueViolation(err error) bool {
+	var pgErr sqlStateError
+	return errors.As(err, &pgErr) && pgErr.SQLState() == sqlStateUniqueViolation
+}
+
+// UniqueViolationConstraint returns the name of the unique constraint err violated, and
+// false when err is not a unique constraint violation or doesn't name one
+func UniqueViolationConstraint(err error) (string, bool) {
+	if !IsUniqueViolation(err) {
+		return "", false
+	}
+	// pgconn.PgError keeps the name in a field rather than a method, but its message
+	// always quotes it: duplicate key value violates unique constraint "users_email_key"
+	_, rest, found := strings.Cut(err.Error(), `unique constraint "`)
+	if !found {
+		return "", false
+	}
+	name, _, found := strings.Cut(rest, `"`)
+	return name, found && name != ""
+}
+
+// RetryTransaction runs fn in a transaction, re-running it with exponential backoff
+// while it fails with a retryable error. Other errors are returned unchanged.
+func RetryTransaction(ctx context.Context, db *gorm.DB, policy RetryPolicy, fn func(tx *gorm.DB) error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := policy.BaseDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = db.WithContext(ctx).Transaction(fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt == attempts {
+			break
+		}
+
+		log.Printf("Retrying transaction after transient error (attempt %d/%d): %v", attempt, attempts, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+
+	return fmt.Errorf("%w: %v", ErrRetriesExhausted, err)
+}
+
+// TransactionWithRetry runs fn with the connection's retry policy
+func (db *DB) TransactionWithRetry(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return RetryTransaction(ctx, db.DB, db.RetryPolicy, fn)
+}
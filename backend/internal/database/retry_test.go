@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// pgError stands in for pgconn.PgError
+type pgError struct {
+	code    string
+	message string
+}
+
+func (e *pgError) Error() string    { return fmt.Sprintf("ERROR: %s (SQLSTATE %s)", e.message, e.code) }
+func (e *pgError) SQLState() string { return e.code }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgError{code: "40001"}, true},
+		{"deadlock", &pgError{code: "40P01"}, true},
+		{"wrapped deadlock", fmt.Errorf("joining activity: %w", &pgError{code: "40P01"}), true},
+		{"unique violation", &pgError{code: "23505"}, false},
+		{"not a database error", errors.New("deadlock"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueViolationConstraint(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      string
+		wantFound bool
+	}{
+		{"named", &pgError{code: "23505", message: `duplicate key value violates unique constraint "users_email_key"`}, "users_email_key", true},
+		{"unnamed", &pgError{code: "23505", message: "duplicate key value"}, "", false},
+		{"other error", &pgError{code: "40001", message: `unique constraint "users_email_key"`}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := UniqueViolationConstraint(tt.err)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("UniqueViolationConstraint() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+// txCounter is a database that only counts transactions
+type txCounter struct {
+	commits, rollbacks int
+}
+
+func (c *txCounter) Open(name string) (driver.Conn, error) { return &txCounterConn{c}, nil }
+
+type txCounterConn struct{ counter *txCounter }
+
+func (c *txCounterConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("statements are not supported")
+}
+func (c *txCounterConn) Close() error              { return nil }
+func (c *txCounterConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *txCounterConn) Commit() error             { c.counter.commits++; return nil }
+func (c *txCounterConn) Rollback() error           { c.counter.rollbacks++; return nil }
+
+func newTxCounterDB(t *testing.T) (*gorm.DB, *txCounter) {
+	t.Helper()
+	counter := &txCounter{}
+	sqlDB := sql.OpenDB(driverConnector{counter})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening GORM: %v", err)
+	}
+	return db, counter
+}
+
+type driverConnector struct{ counter *txCounter }
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.counter.Open("") }
+func (c driverConnector) Driver() driver.Driver                        { return c.counter }
+
+var errCapacity = errors.New("activity is full")
+
+func TestRetryTransaction(t *testing.T) {
+	deadlock := &pgError{code: "40P01", message: "deadlock detected"}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	tests := []struct {
+		name          string
+		failures      []error
+		wantErr       error
+		wantAttempts  int
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{"succeeds", nil, nil, 1, 1, 0},
+		{"retries deadlocks", []error{deadlock, deadlock}, nil, 3, 1, 2},
+		{"gives up", []error{deadlock, deadlock, deadlock}, ErrRetriesExhausted, 3, 0, 3},
+		{"other errors are returned", []error{errCapacity}, errCapacity, 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, counter := newTxCounterDB(t)
+			attempts := 0
+			err := RetryTransaction(context.Background(), db, policy, func(tx *gorm.DB) error {
+				attempts++
+				if attempts <= len(tt.failures) {
+					return tt.failures[attempts-1]
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("RetryTransaction() = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if counter.commits != tt.wantCommits || counter.rollbacks != tt.wantRollbacks {
+				t.Errorf("commits, rollbacks = %d, %d, want %d, %d", counter.commits, counter.rollbacks, tt.wantCommits, tt.wantRollbacks)
+			}
+		})
+	}
+}
+
+func TestRetryTransactionAfterCommitRunsHooksOfTheCommittedAttempt(t *testing.T) {
+	db, _ := newTxCounterDB(t)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	var ran []int
+	attempts := 0
+	err := RetryTransactionAfterCommit(context.Background(), db, policy, func(tx *gorm.DB, after *AfterCommit) error {
+		attempts++
+		attempt := attempts
+		after.Do(func() { ran = append(ran, attempt) })
+		if attempt == 1 {
+			return &pgError{code: "40001", message: "could not serialize access"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RetryTransactionAfterCommit: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != 2 {
+		t.Errorf("hooks of attempts %v ran, want only attempt 2", ran)
+	}
+}
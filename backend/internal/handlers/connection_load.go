@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+const (
+	DefaultSyntheticLoadHold = 5 * time.Minute
+	MaxSyntheticLoadHold     = time.Hour
+)
+
+// ConnectionLoadHandler opens synthetic realtime connections, so subscription load can be
+// reproduced without real clients. Only one load runs at a time per instance.
+type ConnectionLoadHandler struct {
+	connections *services.ConnectionManager
+	running     atomic.Bool
+}
+
+func NewConnectionLoadHandler(connections *services.ConnectionManager) *ConnectionLoadHandler {
+	return &ConnectionLoadHandler{connections: connections}
+}
+
+type connectionLoadRequest struct {
+	Connections       int      `json:"connections"`
+	SubscriptionTypes []string `json:"subscription_types"`
+	HoldSeconds       int      `json:"hold_seconds"`
+}
+
+// HandleStart serves POST /api/admin/load-test/connections. The connections stay open for
+// hold_seconds and are then closed.
+func (h *ConnectionLoadHandler) HandleStart(c *fiber.Ctx) error {
+	if h.connections == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Realtime events are disabled"})
+	}
+
+	var req connectionLoadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	hold := DefaultSyntheticLoadHold
+	if req.HoldSeconds > 0 {
+		hold = time.Duration(req.HoldSeconds) * time.Second
+	}
+	if hold > MaxSyntheticLoadHold {
+		hold = MaxSyntheticLoadHold
+	}
+
+	if !h.running.CompareAndSwap(false, true) {
+		return c.Status(409).JSON(fiber.Map{"error": "A load test is already running"})
+	}
+
+	load, err := h.connections.OpenSyntheticConnections(req.Connections, req.SubscriptionTypes)
+	if load == nil {
+		h.running.Store(false)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	time.AfterFunc(hold, func() {
+		load.Close()
+		h.running.Store(false)
+	})
+	log.Printf("User %v started a load test with %d synthetic connections for %v", c.Locals("userID"), load.Len(), hold)
+
+	resp := fiber.Map{
+		"opened":             load.Len(),
+		"subscription_types": req.SubscriptionTypes,
+		"closes_at":          time.Now().Add(hold),
+	}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	return c.Status(202).JSON(resp)
+}
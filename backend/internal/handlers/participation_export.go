@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+)
+
+const DefaultExportPageSize = 1000
+
+var participationExportHeader = []string{
+	"participation_id", "student_id", "first_name", "last_name", "email", "status",
+	"registered_at", "approved_at", "attended_at", "qr_scanned_at", "scanned_by", "scan_location", "notes",
+}
+
+// ParticipationExportHandler streams an activity's participations as CSV one page at a time,
+// so large events never have to be held in memory
+type ParticipationExportHandler struct {
+	db            *database.DB
+	auditLogger   *audit.AuditLogger
+	exportLimiter *lock.ConcurrencyLimiter
+	pageSize      int
+}
+
+func NewParticipationExportHandler(db *database.DB, auditLogger *audit.AuditLogger, exportLimiter *lock.ConcurrencyLimiter, pageSize int) *ParticipationExportHandler {
+	if pageSize <= 0 {
+		pageSize = DefaultExportPageSize
+	}
+	return &ParticipationExportHandler{
+		db:            db,
+		auditLogger:   auditLogger,
+		exportLimiter: exportLimiter,
+		pageSize:      pageSize,
+	}
+}
+
+// HandleExport serves GET /api/admin/activities/:id/participations/export.
+// It expects the auth middleware to have set userID and userRole.
+func (h *ParticipationExportHandler) HandleExport(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	activityID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid activity ID"})
+	}
+
+	// Load the admin rather than trusting the token, so faculty moves take effect immediately
+	var admin models.User
+	if err := h.db.First(&admin, userID).Error; err != nil || !admin.IsActive {
+		return c.Status(401).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var activity models.Activity
+	if err := h.db.First(&activity, activityID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Activity not found"})
+	}
+
+	if admin.Role != models.UserRoleSuperAdmin &&
+		(admin.FacultyID == nil || activity.FacultyID == nil || *admin.FacultyID != *activity.FacultyID) {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied: resource belongs to another faculty"})
+	}
+
+	release := func() {}
+	if h.exportLimiter != nil {
+		release, err = h.exportLimiter.Acquire(c.Context(), strconv.FormatUint(uint64(admin.ID), 10))
+		if err == lock.ErrConcurrencyLimitReached {
+			return c.Status(429).JSON(fiber.Map{
+				"error": fmt.Sprintf("You already have %d exports running. Please wait for one to finish and try again", h.exportLimiter.Limit()),
+			})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to start export"})
+		}
+	}
+
+	event := &audit.AuditEvent{
+		UserID:     strconv.FormatUint(uint64(admin.ID), 10),
+		UserRole:   string(admin.Role),
+		Action:     audit.ActionExport,
+		Resource:   audit.ResourceParticipation,
+		ResourceID: strconv.FormatUint(uint64(activity.ID), 10),
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		Severity:   audit.SeverityInfo,
+		Category:   audit.CategoryData,
+	}
+	if admin.FacultyID != nil {
+		event.FacultyID = strconv.FormatUint(uint64(*admin.FacultyID), 10)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="activity-%d-participations.csv"`, activity.ID))
+
+	// The writer runs after the handler returns, so the export slot is released from inside it
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer release()
+
+		rows, err := h.streamParticipations(w, activity.ID)
+		event.Success = err == nil
+		event.Details = map[string]interface{}{
+			"activity_id": activity.ID,
+			"row_count":   rows,
+		}
+		if err != nil {
+			event.ErrorMessage = err.Error()
+			event.Severity = audit.SeverityWarn
+			log.Printf("Participation export for activity %d stopped after %d rows: %v", activity.ID, rows, err)
+		}
+
+		if h.auditLogger != nil {
+			if err := h.auditLogger.LogEvent(context.Background(), event); err != nil {
+				log.Printf("Failed to audit participation export for activity %d: %v", activity.ID, err)
+			}
+		}
+	})
+
+	return nil
+}
+
+// streamParticipations writes the CSV using keyset pagination on the participation ID,
+// flushing after every page. It returns the number of data rows written.
+func (h *ParticipationExportHandler) streamParticipations(w *bufio.Writer, activityID uint) (int, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(participationExportHeader); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	var lastID uint
+	for {
+		var page []models.Participation
+		if err := h.db.Joins("User").Preload("ScannedBy").
+			Where("participations.activity_id = ? AND participations.id > ?", activityID, lastID).
+			Order("participations.id ASC").
+			Limit(h.pageSize).
+			Find(&page).Error; err != nil {
+			return rows, fmt.Errorf("failed to load participations: %v", err)
+		}
+
+		for _, p := range page {
+			if err := writer.Write(participationExportRow(&p)); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return rows, err
+		}
+		// A failed flush means the client has gone away
+		if err := w.Flush(); err != nil {
+			return rows, err
+		}
+
+		if len(page) < h.pageSize {
+			return rows, nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+func participationExportRow(p *models.Participation) []string {
+	scannedBy := ""
+	if p.ScannedBy != nil {
+		scannedBy = p.ScannedBy.FirstName + " " + p.ScannedBy.LastName
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(p.ID), 10),
+		p.User.StudentID,
+		p.User.FirstName,
+		p.User.LastName,
+		p.User.Email,
+		string(p.Status),
+		formatExportTime(&p.RegisteredAt),
+		formatExportTime(p.ApprovedAt),
+		formatExportTime(p.AttendedAt),
+		formatExportTime(p.QRScannedAt),
+		scannedBy,
+		p.ScanLocation,
+		p.Notes,
+	}
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHandleExportRejectsConcurrentExportsPastTheLimit(t *testing.T) {
+	// Exports hang on their first page until unblocked, so they stay running
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 10)
+	db, _ := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		switch {
+		case strings.Contains(query, `FROM "participations"`):
+			started <- struct{}{}
+			<-unblock
+			return fakedb.Result{}
+		case strings.Contains(query, `FROM "users"`):
+			return fakedb.Result{
+				Columns: []string{"id", "role", "faculty_id", "is_active"},
+				Rows:    [][]driver.Value{{int64(7), "faculty_admin", int64(1), true}},
+			}
+		case strings.Contains(query, `FROM "activities"`):
+			return fakedb.Result{
+				Columns: []string{"id", "faculty_id"},
+				Rows:    [][]driver.Value{{int64(3), int64(1)}},
+			}
+		}
+		return fakedb.Result{}
+	})
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	limiter := lock.NewConcurrencyLimiter(client, "export", 2, time.Minute)
+
+	handler := NewParticipationExportHandler(&database.DB{DB: db}, nil, limiter, 0)
+	app := fiber.New()
+	app.Get("/activities/:id/participations/export", func(c *fiber.Ctx) error {
+		c.Locals("userID", uint(7))
+		return c.Next()
+	}, handler.HandleExport)
+
+	export := func() (int, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/activities/3/participations/export", nil), -1)
+		if err != nil {
+			t.Errorf("export request: %v", err)
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Two exports at the limit are left running
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			status, _ := export()
+			done <- status
+		}()
+		<-started
+	}
+
+	status, body := export()
+	if status != fiber.StatusTooManyRequests || !strings.Contains(body, "You already have 2 exports running") {
+		t.Errorf("third export = %d %s, want 429 naming the limit", status, body)
+	}
+
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if status := <-done; status != fiber.StatusOK {
+			t.Errorf("running export finished with %d, want 200", status)
+		}
+	}
+
+	// Finished exports give their slots back
+	if status, _ := export(); status != fiber.StatusOK {
+		t.Errorf("export after the others finished = %d, want 200", status)
+	}
+}
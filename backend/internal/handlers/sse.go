@@ -2,17 +2,56 @@ package handlers
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/kruakemaths/tru-activity/backend/internal/database"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
 	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	"github.com/kruakemaths/tru-activity/backend/pkg/compression"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+const (
+	DefaultSSEBufferSize = 64
+
+	DefaultSSEHeartbeatInterval    = 30 * time.Second
+	DefaultSSEIdleTimeout          = 5 * time.Minute
+	DefaultSSEMinHeartbeatInterval = 5 * time.Second
+
+	DefaultSSEReconnectBaseDelay    = time.Second
+	DefaultSSEReconnectMaxDelay     = 30 * time.Second
+	DefaultSSEReconnectJitterWindow = 10 * time.Second
+
+	// maxSlowSSEClientsReported caps the slow clients listed in buffer stats
+	maxSlowSSEClientsReported = 20
+
+	// maxSSEReconnectAttempt caps the backoff exponent so the shift can't overflow
+	maxSSEReconnectAttempt = 16
+)
+
+// Reasons sent in the disconnect event written just before the server closes a stream
+const (
+	// SSEDisconnectIdle: no subscribe, unsubscribe or heartbeat within the idle timeout
+	SSEDisconnectIdle = "idle"
+	// SSEDisconnectOverflow: the client fell behind until its buffer overflowed
+	SSEDisconnectOverflow = "overflow"
+	// SSEDisconnectShutdown: the instance is shutting down
+	SSEDisconnectShutdown = "shutdown"
+	// SSEDisconnectReplaced: a newer connection reconnected with this client ID
+	SSEDisconnectReplaced = "replaced"
+	// SSEDisconnectSessionEnded: the user was signed out and must log in before reconnecting
+	SSEDisconnectSessionEnded = "session_ended"
 )
 
 type SSEEvent struct {
@@ -20,6 +59,9 @@ type SSEEvent struct {
 	Timestamp string                 `json:"timestamp"`
 	Data      interface{}            `json:"data"`
 	Metadata  *SSEEventMetadata      `json:"metadata,omitempty"`
+
+	// Retry, when set, is written as the SSE retry field so EventSource waits that long to reconnect
+	Retry time.Duration `json:"-"`
 }
 
 type SSEEventMetadata struct {
@@ -30,6 +72,12 @@ type SSEEventMetadata struct {
 	CorrelationID string `json:"correlationId,omitempty"`
 }
 
+// SSECompressedEvent wraps an event whose JSON was gzipped for clients that opted in
+type SSECompressedEvent struct {
+	Encoding string `json:"encoding"`
+	Payload  string `json:"payload"` // base64 of the compressed SSEEvent JSON
+}
+
 type SSESubscription struct {
 	EventType string                 `json:"eventType"`
 	Filter    map[string]interface{} `json:"filter,omitempty"`
@@ -41,31 +89,146 @@ type SSEClient struct {
 	FacultyID     *uint
 	Role          string
 	Channel       chan SSEEvent
+	Buffer        *monitoring.BufferUsage
 	Subscriptions map[string]SSESubscription
+	Compression   string // per-event encoding the client can decode, empty for none
 	LastSeen      time.Time
 	Context       context.Context
 	Cancel        context.CancelFunc
 	mu            sync.RWMutex
+
+	// reconnectAttempt is how many times in a row the client has reconnected (?attempt=)
+	reconnectAttempt int
+	// disconnectReason is set by the server before it closes the stream
+	disconnectReason string
 }
 
 type SSEHandler struct {
-	db          *database.DB
-	jwtService  *auth.JWTService
-	clients     map[string]*SSEClient
-	broadcast   chan SSEEvent
-	register    chan *SSEClient
-	unregister  chan *SSEClient
-	mu          sync.RWMutex
+	db           *database.DB
+	jwtService   *auth.JWTService
+	sessionStore *auth.SessionStore // nil skips revocation checks
+	clients      map[string]*SSEClient
+	broadcast    chan SSEEvent
+	register     chan *SSEClient
+	unregister   chan *SSEClient
+	compression  compression.Config
+	buffers      monitoring.BufferConfig
+	dropped      atomic.Int64
+	keepalive    SSEKeepaliveConfig
+	reconnect    SSEReconnectConfig
+	heartbeats   atomic.Int64
+	reaped       atomic.Int64
+	mu           sync.RWMutex
+}
+
+// SSEKeepaliveConfig controls connection keepalive. Each connection gets a heartbeat every
+// HeartbeatInterval, or every ?heartbeat=<seconds> down to MinHeartbeatInterval for clients
+// behind proxies that cut idle streams sooner. Clients that haven't subscribed, unsubscribed
+// or posted a heartbeat within IdleTimeout are disconnected.
+type SSEKeepaliveConfig struct {
+	HeartbeatInterval    time.Duration
+	MinHeartbeatInterval time.Duration
+	IdleTimeout          time.Duration
+}
+
+// WithDefaults fills in zero fields
+func (c SSEKeepaliveConfig) WithDefaults() SSEKeepaliveConfig {
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = DefaultSSEHeartbeatInterval
+	}
+	if c.MinHeartbeatInterval <= 0 {
+		c.MinHeartbeatInterval = DefaultSSEMinHeartbeatInterval
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = DefaultSSEIdleTimeout
+	}
+	return c
+}
+
+// SSEReconnectConfig shapes the reconnect delay suggested when the server closes a stream.
+// The delay doubles from BaseDelay with each consecutive reconnect the client reports, up to
+// MaxDelay, plus a random jitter of up to JitterWindow so clients closed together spread out.
+type SSEReconnectConfig struct {
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	JitterWindow time.Duration
+}
+
+// WithDefaults fills in zero fields
+func (c SSEReconnectConfig) WithDefaults() SSEReconnectConfig {
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = DefaultSSEReconnectBaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = DefaultSSEReconnectMaxDelay
+	}
+	if c.JitterWindow < 0 {
+		c.JitterWindow = 0
+	}
+	return c
+}
+
+// Delay returns the suggested wait before reconnect number attempt+1
+func (c SSEReconnectConfig) Delay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+	if attempt > maxSSEReconnectAttempt {
+		attempt = maxSSEReconnectAttempt
+	}
+
+	delay := c.BaseDelay << attempt
+	if delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	if c.JitterWindow > 0 {
+		delay += time.Duration(rand.Int63n(int64(c.JitterWindow)))
+	}
+	return delay
+}
+
+// SSEKeepaliveStats counts keepalive activity since startup
+type SSEKeepaliveStats struct {
+	HeartbeatIntervalSeconds int   `json:"heartbeat_interval_seconds"`
+	IdleTimeoutSeconds       int   `json:"idle_timeout_seconds"`
+	HeartbeatsSent           int64 `json:"heartbeats_sent"`
+	ClientsReaped            int64 `json:"clients_reaped"`
+}
+
+// SSEBufferStats summarizes client channel usage. Drops count every event dropped since
+// startup; slow clients are the connected clients that dropped events, worst first.
+type SSEBufferStats struct {
+	BufferSize    int                    `json:"buffer_size"`
+	HighWatermark int                    `json:"high_watermark"`
+	Drops         int64                  `json:"drops"`
+	SlowClients   []SSEClientBufferStats `json:"slow_clients,omitempty"`
 }
 
-func NewSSEHandler(db *database.DB, jwtService *auth.JWTService) *SSEHandler {
+type SSEClientBufferStats struct {
+	ClientID      string `json:"client_id"`
+	UserID        uint   `json:"user_id"`
+	HighWatermark int    `json:"high_watermark"`
+	Drops         int64  `json:"drops"`
+}
+
+// NewSSEHandler creates the SSE hub; zero buffer, keepalive or reconnect settings use the defaults.
+// Tokens revoked in sessionStore are refused.
+func NewSSEHandler(db *database.DB, jwtService *auth.JWTService, sessionStore *auth.SessionStore, compressionConfig compression.Config, buffers monitoring.BufferConfig, keepalive SSEKeepaliveConfig, reconnect SSEReconnectConfig) *SSEHandler {
+	if buffers.Size <= 0 {
+		buffers.Size = DefaultSSEBufferSize
+	}
 	handler := &SSEHandler{
-		db:         db,
-		jwtService: jwtService,
-		clients:    make(map[string]*SSEClient),
-		broadcast:  make(chan SSEEvent, 256),
-		register:   make(chan *SSEClient),
-		unregister: make(chan *SSEClient),
+		db:           db,
+		jwtService:   jwtService,
+		sessionStore: sessionStore,
+		compression:  compressionConfig,
+		buffers:      buffers.WithDefaults(),
+		keepalive:    keepalive.WithDefaults(),
+		reconnect:    reconnect.WithDefaults(),
+		clients:      make(map[string]*SSEClient),
+		broadcast:    make(chan SSEEvent, 256),
+		register:     make(chan *SSEClient),
+		unregister:   make(chan *SSEClient),
 	}
 
 	// Start the hub goroutine
@@ -75,20 +238,33 @@ func NewSSEHandler(db *database.DB, jwtService *auth.JWTService) *SSEHandler {
 }
 
 func (h *SSEHandler) run() {
-	ticker := time.NewTicker(30 * time.Second)
+	// Heartbeats are written by each connection; the hub only reaps idle clients
+	ticker := time.NewTicker(h.keepalive.IdleTimeout / 2)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			// A reconnect under the same client ID takes over the old stream's subscriptions
+			if previous, ok := h.clients[client.ID]; ok {
+				previous.mu.RLock()
+				client.mu.Lock()
+				for eventType, subscription := range previous.Subscriptions {
+					client.Subscriptions[eventType] = subscription
+				}
+				client.mu.Unlock()
+				previous.mu.RUnlock()
+				h.closeClient(previous, SSEDisconnectReplaced)
+			}
 			h.clients[client.ID] = client
 			h.mu.Unlock()
 			log.Printf("SSE client connected: %s (user: %d)", client.ID, client.UserID)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.ID]; ok {
+			// The client may already have been closed, or replaced by a reconnect with its ID
+			if current, ok := h.clients[client.ID]; ok && current == client {
 				delete(h.clients, client.ID)
 				close(client.Channel)
 			}
@@ -101,26 +277,31 @@ func (h *SSEHandler) run() {
 				if h.shouldReceiveEvent(client, event) {
 					select {
 					case client.Channel <- event:
+						client.Buffer.Sent(len(client.Channel))
 					default:
-						// Client channel is full, disconnect
-						go func(c *SSEClient) {
-							h.unregister <- c
-						}(client)
+						h.dropped.Add(1)
+						// A client that keeps falling behind is disconnected
+						if client.Buffer.Dropped() == int64(h.buffers.OverflowThreshold) {
+							if h.buffers.OnOverflow != nil {
+								h.buffers.OnOverflow(monitoring.BufferOverflow{
+									Kind:         "sse",
+									ConnectionID: client.ID,
+									UserID:       client.UserID,
+									Usage:        client.Buffer.Stats(),
+								})
+							}
+							client.setDisconnectReason(SSEDisconnectOverflow)
+							go func(c *SSEClient) {
+								h.unregister <- c
+							}(client)
+						}
 					}
 				}
 			}
 			h.mu.RUnlock()
 
 		case <-ticker.C:
-			// Clean up inactive clients
 			h.cleanupInactiveClients()
-
-			// Send heartbeat
-			h.broadcast <- SSEEvent{
-				Type:      "heartbeat",
-				Timestamp: time.Now().Format(time.RFC3339),
-				Data:      "ping",
-			}
 		}
 	}
 }
@@ -138,8 +319,9 @@ func (h *SSEHandler) shouldReceiveEvent(client *SSEClient, event SSEEvent) bool
 	// Role-based filtering
 	switch event.Type {
 	case "system_alert":
-		// Only admins receive system alerts
-		return client.Role != "student"
+		// Only admins receive system alerts, and only those at or above their minimum severity
+		return client.Role != "student" &&
+			models.AlertSeverityOf(event.Data).AtLeast(minAlertSeverity(subscription.Filter))
 		
 	case "personal_notification":
 		// Personal notifications only for the specific user
@@ -217,20 +399,115 @@ func (h *SSEHandler) matchesFilter(event SSEEvent, filter map[string]interface{}
 	return true
 }
 
+// minAlertSeverity returns the minSeverity of a subscription filter, or the default floor
+func minAlertSeverity(filter map[string]interface{}) models.AlertSeverity {
+	if value, ok := filter["minSeverity"].(string); ok {
+		if severity, ok := models.ParseAlertSeverity(value); ok {
+			return severity
+		}
+	}
+	return models.DefaultMinAlertSeverity
+}
+
 func (h *SSEHandler) cleanupInactiveClients() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	now := time.Now()
 	for id, client := range h.clients {
-		if now.Sub(client.LastSeen) > 5*time.Minute {
-			delete(h.clients, id)
-			close(client.Channel)
+		client.mu.RLock()
+		idle := now.Sub(client.LastSeen)
+		client.mu.RUnlock()
+
+		if idle > h.keepalive.IdleTimeout {
+			h.closeClient(client, SSEDisconnectIdle)
+			h.reaped.Add(1)
 			log.Printf("Cleaned up inactive SSE client: %s", id)
 		}
 	}
 }
 
+// closeClient removes a client and ends its stream, which writes a disconnect event with the
+// reason before closing. The caller holds h.mu.
+func (h *SSEHandler) closeClient(client *SSEClient, reason string) {
+	if current, ok := h.clients[client.ID]; ok && current == client {
+		delete(h.clients, client.ID)
+	}
+	client.setDisconnectReason(reason)
+	close(client.Channel)
+	client.Cancel()
+}
+
+func (c *SSEClient) setDisconnectReason(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disconnectReason == "" {
+		c.disconnectReason = reason
+	}
+}
+
+// disconnectEvent is the last event of a stream the server closed, or nil when the client went away
+func (h *SSEHandler) disconnectEvent(client *SSEClient) *SSEEvent {
+	client.mu.RLock()
+	reason := client.disconnectReason
+	attempt := client.reconnectAttempt
+	client.mu.RUnlock()
+	if reason == "" {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"reason":    reason,
+		"reconnect": reason != SSEDisconnectSessionEnded,
+	}
+	event := &SSEEvent{
+		Type:      "disconnect",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      data,
+	}
+	if reason != SSEDisconnectSessionEnded {
+		event.Retry = h.reconnect.Delay(attempt)
+		data["retryAfterMs"] = event.Retry.Milliseconds()
+	}
+	return event
+}
+
+// writeDisconnectEvent tells a client the server is closing its stream, and when to come back
+func (h *SSEHandler) writeDisconnectEvent(c *fiber.Ctx, client *SSEClient) error {
+	event := h.disconnectEvent(client)
+	if event == nil {
+		return nil
+	}
+	return h.writeSSEEvent(c, client, *event)
+}
+
+// heartbeatInterval returns the interval a connection asked for in seconds, clamped between
+// the minimum and the idle timeout, or the default when it didn't ask
+func (h *SSEHandler) heartbeatInterval(requested string) time.Duration {
+	seconds, err := strconv.Atoi(requested)
+	if err != nil || seconds <= 0 {
+		return h.keepalive.HeartbeatInterval
+	}
+
+	interval := time.Duration(seconds) * time.Second
+	if interval < h.keepalive.MinHeartbeatInterval {
+		return h.keepalive.MinHeartbeatInterval
+	}
+	if interval > h.keepalive.IdleTimeout {
+		return h.keepalive.IdleTimeout
+	}
+	return interval
+}
+
+// negotiateEventCompression returns the per-event encoding requested by the client.
+// EventSource can't set headers, so clients opt in with ?compression=gzip.
+func (h *SSEHandler) negotiateEventCompression(c *fiber.Ctx) string {
+	if !h.compression.Enabled {
+		return ""
+	}
+	return compression.NegotiateEncoding(c.Query("compression"), compression.SupportedEncodings)
+}
+
 // HTTP Handlers
 
 func (h *SSEHandler) HandleSSEConnection(c *fiber.Ctx) error {
@@ -247,11 +524,14 @@ func (h *SSEHandler) HandleSSEConnection(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
 	}
 
-	// Validate token
+	// Validate token; signed out sessions must log in again before reconnecting
 	claims, err := h.jwtService.ValidateToken(token)
 	if err != nil {
 		return c.Status(401).JSON(fiber.Map{"error": "Invalid token"})
 	}
+	if h.sessionStore != nil && h.sessionStore.IsRevoked(c.Context(), claims) {
+		return c.Status(401).JSON(fiber.Map{"error": "Session has ended, please log in again"})
+	}
 
 	// Set SSE headers
 	c.Set("Content-Type", "text/event-stream")
@@ -260,18 +540,34 @@ func (h *SSEHandler) HandleSSEConnection(c *fiber.Ctx) error {
 	c.Set("Access-Control-Allow-Origin", "*")
 	c.Set("Access-Control-Allow-Headers", "Cache-Control")
 
+	// A client reconnecting with ?clientId= keeps its ID, and its subscriptions, if the ID is its own
+	clientID := fmt.Sprintf("%d_%d", claims.UserID, time.Now().UnixNano())
+	if requested := c.Query("clientId"); requested != "" {
+		h.mu.RLock()
+		previous, ok := h.clients[requested]
+		h.mu.RUnlock()
+		if ok && previous.UserID == claims.UserID {
+			clientID = requested
+		}
+	}
+	reconnectAttempt, _ := strconv.Atoi(c.Query("attempt"))
+
 	// Create client
 	ctx, cancel := context.WithCancel(c.Context())
 	client := &SSEClient{
-		ID:            fmt.Sprintf("%d_%d", claims.UserID, time.Now().UnixNano()),
+		ID:            clientID,
 		UserID:        claims.UserID,
 		FacultyID:     claims.FacultyID,
 		Role:          claims.Role,
-		Channel:       make(chan SSEEvent, 64),
+		Channel:       make(chan SSEEvent, h.buffers.Size),
+		Buffer:        monitoring.NewBufferUsage(h.buffers.Size),
 		Subscriptions: make(map[string]SSESubscription),
+		Compression:   h.negotiateEventCompression(c),
 		LastSeen:      time.Now(),
 		Context:       ctx,
 		Cancel:        cancel,
+
+		reconnectAttempt: reconnectAttempt,
 	}
 
 	// Register client
@@ -283,43 +579,86 @@ func (h *SSEHandler) HandleSSEConnection(c *fiber.Ctx) error {
 		h.unregister <- client
 	}()
 
-	// Send initial connection event
+	// Send initial connection event, with the negotiated keepalive
+	heartbeat := h.heartbeatInterval(c.Query("heartbeat"))
 	initialEvent := SSEEvent{
 		Type:      "connection",
 		Timestamp: time.Now().Format(time.RFC3339),
-		Data:      map[string]interface{}{"status": "connected", "clientId": client.ID},
+		Data: map[string]interface{}{
+			"status":                   "connected",
+			"clientId":                 client.ID,
+			"heartbeatIntervalSeconds": int(heartbeat.Seconds()),
+			"idleTimeoutSeconds":       int(h.keepalive.IdleTimeout.Seconds()),
+		},
 	}
 	
-	if err := h.writeSSEEvent(c, initialEvent); err != nil {
+	if err := h.writeSSEEvent(c, client, initialEvent); err != nil {
 		return err
 	}
 
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
 	// Listen for events
 	for {
 		select {
-		case event := <-client.Channel:
-			if err := h.writeSSEEvent(c, event); err != nil {
+		case event, ok := <-client.Channel:
+			if !ok {
+				// Reaped or dropped by the hub
+				return h.writeDisconnectEvent(c, client)
+			}
+			if err := h.writeSSEEvent(c, client, event); err != nil {
 				return err
 			}
+
+		case <-ticker.C:
+			heartbeatEvent := SSEEvent{
+				Type:      "heartbeat",
+				Timestamp: time.Now().Format(time.RFC3339),
+				Data:      "ping",
+			}
+			if err := h.writeSSEEvent(c, client, heartbeatEvent); err != nil {
+				return err
+			}
+			h.heartbeats.Add(1)
 			
 		case <-ctx.Done():
-			return nil
+			return h.writeDisconnectEvent(c, client)
 		}
 	}
 }
 
-func (h *SSEHandler) writeSSEEvent(c *fiber.Ctx, event SSEEvent) error {
+func (h *SSEHandler) writeSSEEvent(c *fiber.Ctx, client *SSEClient, event SSEEvent) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 
+	// Large events are compressed individually so each one can still be flushed immediately
+	if client.Compression != "" && h.compression.ShouldCompress(len(data)) {
+		compressed, err := compression.Compress(client.Compression, data)
+		if err == nil {
+			data, err = json.Marshal(SSECompressedEvent{
+				Encoding: client.Compression,
+				Payload:  base64.StdEncoding.EncodeToString(compressed),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	// Write SSE format
 	if event.Type != "heartbeat" {
 		if _, err := fmt.Fprintf(c, "event: %s\n", event.Type); err != nil {
 			return err
 		}
 	}
+	if event.Retry > 0 {
+		if _, err := fmt.Fprintf(c, "retry: %d\n", event.Retry.Milliseconds()); err != nil {
+			return err
+		}
+	}
 	
 	if _, err := fmt.Fprintf(c, "data: %s\n\n", data); err != nil {
 		return err
@@ -353,6 +692,14 @@ func (h *SSEHandler) HandleSubscribe(c *fiber.Ctx) error {
 	if err := c.BodyParser(&subscription); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid subscription data"})
 	}
+	if value, ok := subscription.Filter["minSeverity"]; ok {
+		name, _ := value.(string)
+		severity, ok := models.ParseAlertSeverity(name)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "minSeverity must be one of info, warning, error or critical"})
+		}
+		subscription.Filter["minSeverity"] = string(severity)
+	}
 
 	// Find client and add subscription
 	clientID := c.Get("X-Client-ID") // Client should send this header
@@ -421,8 +768,31 @@ func (h *SSEHandler) HandleUnsubscribe(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "unsubscribed", "eventType": subscription.EventType})
 }
 
+// HandleHeartbeat answers a keepalive ping. With an X-Client-ID header and the owner's token it
+// also marks that connection as active, so clients can keep it from being reaped.
 func (h *SSEHandler) HandleHeartbeat(c *fiber.Ctx) error {
-	// Simple heartbeat endpoint
+	if clientID := c.Get("X-Client-ID"); clientID != "" {
+		token := c.Get("Authorization")
+		if !strings.HasPrefix(token, "Bearer ") {
+			return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+		}
+		claims, err := h.jwtService.ValidateToken(strings.TrimPrefix(token, "Bearer "))
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Invalid token"})
+		}
+
+		h.mu.RLock()
+		client, exists := h.clients[clientID]
+		h.mu.RUnlock()
+		if !exists || client.UserID != claims.UserID {
+			return c.Status(404).JSON(fiber.Map{"error": "Client not found"})
+		}
+
+		client.mu.Lock()
+		client.LastSeen = time.Now()
+		client.mu.Unlock()
+	}
+
 	return c.JSON(fiber.Map{"status": "ok", "timestamp": time.Now().Format(time.RFC3339)})
 }
 
@@ -536,6 +906,36 @@ func (h *SSEHandler) GetConnectedClients() int {
 	return len(h.clients)
 }
 
+// CloseUserClients disconnects every SSE client of the user and returns how many there were
+func (h *SSEHandler) CloseUserClients(userID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for _, client := range h.clients {
+		if client.UserID != userID {
+			continue
+		}
+		h.closeClient(client, SSEDisconnectSessionEnded)
+		closed++
+	}
+	if closed > 0 {
+		log.Printf("Closed %d SSE clients of user %d", closed, userID)
+	}
+	return closed
+}
+
+// Shutdown disconnects every client, suggesting jittered reconnect delays so they don't all
+// hit the remaining instances at once
+func (h *SSEHandler) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, client := range h.clients {
+		h.closeClient(client, SSEDisconnectShutdown)
+	}
+}
+
 // GetClientsByFaculty returns clients for a specific faculty
 func (h *SSEHandler) GetClientsByFaculty(facultyID uint) []*SSEClient {
 	h.mu.RLock()
@@ -548,4 +948,43 @@ func (h *SSEHandler) GetClientsByFaculty(facultyID uint) []*SSEClient {
 		}
 	}
 	return clients
-}
\ No newline at end of file
+}
+
+// GetBufferStats reports how full client channels run
+func (h *SSEHandler) GetBufferStats() SSEBufferStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := SSEBufferStats{BufferSize: h.buffers.Size, Drops: h.dropped.Load()}
+	for _, client := range h.clients {
+		usage := client.Buffer.Stats()
+		if usage.HighWatermark > stats.HighWatermark {
+			stats.HighWatermark = usage.HighWatermark
+		}
+		if usage.Drops > 0 {
+			stats.SlowClients = append(stats.SlowClients, SSEClientBufferStats{
+				ClientID:      client.ID,
+				UserID:        client.UserID,
+				HighWatermark: usage.HighWatermark,
+				Drops:         usage.Drops,
+			})
+		}
+	}
+	sort.Slice(stats.SlowClients, func(i, j int) bool {
+		return stats.SlowClients[i].Drops > stats.SlowClients[j].Drops
+	})
+	if len(stats.SlowClients) > maxSlowSSEClientsReported {
+		stats.SlowClients = stats.SlowClients[:maxSlowSSEClientsReported]
+	}
+	return stats
+}
+
+// GetKeepaliveStats reports heartbeats sent and idle clients reaped
+func (h *SSEHandler) GetKeepaliveStats() SSEKeepaliveStats {
+	return SSEKeepaliveStats{
+		HeartbeatIntervalSeconds: int(h.keepalive.HeartbeatInterval.Seconds()),
+		IdleTimeoutSeconds:       int(h.keepalive.IdleTimeout.Seconds()),
+		HeartbeatsSent:           h.heartbeats.Load(),
+		ClientsReaped:            h.reaped.Load(),
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	"github.com/kruakemaths/tru-activity/backend/pkg/compression"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+type sseFrame struct {
+	event string
+	retry string
+	data  map[string]interface{}
+}
+
+// parseSSE splits a stream body into its events
+func parseSSE(t *testing.T, body string) []sseFrame {
+	t.Helper()
+	var frames []sseFrame
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var frame sseFrame
+		for _, line := range strings.Split(block, "\n") {
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "event":
+				frame.event = value
+			case "retry":
+				frame.retry = value
+			case "data":
+				if err := json.Unmarshal([]byte(value), &frame.data); err != nil {
+					t.Fatalf("decoding event data %q: %v", value, err)
+				}
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestSSEDisconnectEventPrecedesClose(t *testing.T) {
+	jwtService := auth.NewJWTService("test-secret", 1, auth.ClaimOptions{})
+	token, err := jwtService.GenerateToken(auth.TokenSubject{UserID: 7, Email: "student@example.com", Role: "student"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		close         func(h *SSEHandler)
+		wantReason    string
+		wantReconnect bool
+	}{
+		{"shutdown", func(h *SSEHandler) { h.Shutdown() }, SSEDisconnectShutdown, true},
+		{"signed out", func(h *SSEHandler) { h.CloseUserClients(7) }, SSEDisconnectSessionEnded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSSEHandler(nil, jwtService, nil, compression.Config{}, monitoring.BufferConfig{}, SSEKeepaliveConfig{}, SSEReconnectConfig{
+				BaseDelay:    time.Second,
+				MaxDelay:     time.Minute,
+				JitterWindow: time.Second,
+			})
+			app := fiber.New()
+			app.Get("/events", h.HandleSSEConnection)
+
+			// Close the stream from the server once the client is connected
+			go func() {
+				for h.GetConnectedClients() == 0 {
+					time.Sleep(5 * time.Millisecond)
+				}
+				tt.close(h)
+			}()
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/events?token="+token+"&attempt=2", nil), 5000)
+			if err != nil {
+				t.Fatalf("GET /events: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			frames := parseSSE(t, string(body))
+			if len(frames) < 2 || frames[0].event != "connection" {
+				t.Fatalf("stream = %q, want a connection event first", body)
+			}
+
+			// The disconnect event is the last thing written before the stream ends
+			last := frames[len(frames)-1]
+			if last.event != "disconnect" {
+				t.Fatalf("last event = %q, want disconnect: %q", last.event, body)
+			}
+			data, _ := last.data["data"].(map[string]interface{})
+			if data["reason"] != tt.wantReason {
+				t.Errorf("disconnect data = %v, want reason %s", data, tt.wantReason)
+			}
+			if data["reconnect"] != tt.wantReconnect {
+				t.Errorf("reconnect = %v, want %v", data["reconnect"], tt.wantReconnect)
+			}
+
+			if !tt.wantReconnect {
+				if last.retry != "" || data["retryAfterMs"] != nil {
+					t.Errorf("signed out client was told to retry after %s", last.retry)
+				}
+				return
+			}
+			// The third attempt waits four base delays plus up to a second of jitter
+			retry, _ := data["retryAfterMs"].(float64)
+			if retry < 4000 || retry >= 5000 {
+				t.Errorf("retryAfterMs = %v, want 4000 to 5000", retry)
+			}
+			if last.retry != strconv.Itoa(int(retry)) {
+				t.Errorf("retry field = %q, want it to match retryAfterMs %v", last.retry, retry)
+			}
+		})
+	}
+}
+
+func TestSystemAlertSeverityFloor(t *testing.T) {
+	h := &SSEHandler{}
+	severities := []models.AlertSeverity{models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityError, models.AlertSeverityCritical}
+
+	tests := []struct {
+		minSeverity interface{}
+		wantFrom    models.AlertSeverity
+	}{
+		{nil, models.AlertSeverityWarning},
+		{"info", models.AlertSeverityInfo},
+		{"warning", models.AlertSeverityWarning},
+		{"error", models.AlertSeverityError},
+		{"critical", models.AlertSeverityCritical},
+	}
+
+	for _, tt := range tests {
+		filter := map[string]interface{}{}
+		if tt.minSeverity != nil {
+			filter["minSeverity"] = tt.minSeverity
+		}
+		admin := &SSEClient{Role: "regular_admin", Subscriptions: map[string]SSESubscription{
+			"system_alert": {EventType: "system_alert", Filter: filter},
+		}}
+
+		// Each alert is delivered from the minimum severity up, and not below it
+		passed := false
+		for _, severity := range severities {
+			passed = passed || severity == tt.wantFrom
+			event := SSEEvent{Type: "system_alert", Data: map[string]interface{}{"severity": string(severity)}}
+			if got := h.shouldReceiveEvent(admin, event); got != passed {
+				t.Errorf("minSeverity %v: %s alert delivered = %v, want %v", tt.minSeverity, severity, got, passed)
+			}
+		}
+	}
+
+	student := &SSEClient{Role: "student", Subscriptions: map[string]SSESubscription{
+		"system_alert": {EventType: "system_alert", Filter: map[string]interface{}{"minSeverity": "info"}},
+	}}
+	if h.shouldReceiveEvent(student, SSEEvent{Type: "system_alert", Data: &models.SystemAlert{Severity: models.AlertSeverityCritical}}) {
+		t.Error("a student received a system alert")
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	CacheControlDirective = "cacheControl"
+
+	// CacheScopePublic responses are shared by every caller with the same role
+	CacheScopePublic = "PUBLIC"
+	// CacheScopePrivate responses are cached per user
+	CacheScopePrivate = "PRIVATE"
+
+	// CachedResponseTag tags every cached response, so they can all be dropped at once
+	CachedResponseTag = "graphql_responses"
+)
+
+// CachePolicy is how long, and for whom, an operation's response may be cached
+type CachePolicy struct {
+	MaxAge time.Duration
+	Scope  string
+}
+
+// CacheExtension caches query responses as the schema's @cacheControl hints allow. A query
+// is cached only if each root field has a hint, and so does each field below it that
+// selects subfields; scalar fields inherit their parent's. The shortest maxAge wins, and
+// any PRIVATE field makes the whole response private.
+//
+// Public responses are shared by callers with the same role rather than by everyone,
+// because @auth and @hasRole, which decide who may see a field, don't run on a cache hit.
+// Fields whose result depends on the caller's faculty or identity must be PRIVATE.
+type CacheExtension struct {
+	Cache *performance.CacheManager
+}
+
+func (ce CacheExtension) ExtensionName() string {
+	return "CacheControl"
+}
+
+func (ce CacheExtension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (ce CacheExtension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if ce.Cache == nil || oc == nil || oc.Operation == nil || oc.Operation.Operation != ast.Query {
+		return next(ctx)
+	}
+
+	policy, ok := OperationCachePolicy(oc.Operation.SelectionSet, oc.Doc.Fragments)
+	if !ok {
+		return next(ctx)
+	}
+	partition, ok := cachePartition(ctx, policy.Scope)
+	if !ok {
+		return next(ctx)
+	}
+
+	key := partition + ":" + hashCachedOperation(oc)
+	config := performance.CacheConfig{
+		KeyPrefix: "graphql_response:",
+		TTL:       policy.MaxAge,
+		Tags:      []string{CachedResponseTag},
+	}
+
+	var cached graphql.Response
+	if err := ce.Cache.Get(ctx, key, config, &cached); err == nil {
+		return graphql.OneShot(&cached)
+	}
+
+	handler := next(ctx)
+	first := true
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if !first {
+			return resp
+		}
+		first = false
+
+		// Responses with errors or deferred parts are not cached; extensions such as rate
+		// limit quotas describe this request only
+		if resp != nil && len(resp.Errors) == 0 && resp.HasNext == nil {
+			if err := ce.Cache.Set(ctx, key, &graphql.Response{Data: resp.Data}, config); err != nil {
+				log.Printf("Failed to cache %s response: %v", oc.OperationName, err)
+			}
+		}
+		return resp
+	}
+}
+
+// cachePartition returns the part of the cache key that decides who shares a response.
+// Private responses of signed-out callers are not cached.
+func cachePartition(ctx context.Context, scope string) (string, bool) {
+	authCtx, err := GetAuthContext(ctx)
+	if scope == CacheScopePrivate {
+		if err != nil {
+			return "", false
+		}
+		return "private:" + strconv.FormatUint(uint64(authCtx.UserID), 10), true
+	}
+	if err != nil {
+		return "public:anonymous", true
+	}
+	return "public:" + string(authCtx.Role), true
+}
+
+func hashCachedOperation(oc *graphql.OperationContext) string {
+	sum := sha256.Sum256([]byte(oc.RawQuery + "\x00" + oc.OperationName + "\x00" + utils.HashKey(oc.Variables)))
+	return hex.EncodeToString(sum[:])
+}
+
+// OperationCachePolicy combines the @cacheControl hints of the selected fields. It returns
+// false when the operation must not be cached.
+func OperationCachePolicy(selectionSet ast.SelectionSet, fragments ast.FragmentDefinitionList) (CachePolicy, bool) {
+	policy := CachePolicy{Scope: CacheScopePublic}
+	if !combineCachePolicy(&policy, selectionSet, fragments, true, map[string]bool{}) || policy.MaxAge <= 0 {
+		return CachePolicy{}, false
+	}
+	return policy, true
+}
+
+func combineCachePolicy(policy *CachePolicy, selectionSet ast.SelectionSet, fragments ast.FragmentDefinitionList, root bool, visiting map[string]bool) bool {
+	for _, selection := range selectionSet {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if sel.Name == "__typename" {
+				continue
+			}
+			hint, hinted := fieldCacheHint(sel.Definition)
+			if !hinted && (root || sel.SelectionSet != nil) {
+				return false
+			}
+			if hinted {
+				if hint.MaxAge <= 0 {
+					return false
+				}
+				if policy.MaxAge == 0 || hint.MaxAge < policy.MaxAge {
+					policy.MaxAge = hint.MaxAge
+				}
+				if hint.Scope == CacheScopePrivate {
+					policy.Scope = CacheScopePrivate
+				}
+			}
+			if sel.SelectionSet != nil && !combineCachePolicy(policy, sel.SelectionSet, fragments, false, visiting) {
+				return false
+			}
+		case *ast.InlineFragment:
+			if !combineCachePolicy(policy, sel.SelectionSet, fragments, root, visiting) {
+				return false
+			}
+		case *ast.FragmentSpread:
+			definition := resolveFragment(sel, fragments)
+			if definition == nil || visiting[sel.Name] {
+				continue
+			}
+			visiting[sel.Name] = true
+			ok := combineCachePolicy(policy, definition.SelectionSet, fragments, root, visiting)
+			delete(visiting, sel.Name)
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fieldCacheHint reads the field's @cacheControl directive. A maxAge of 0 makes the field
+// uncacheable; scope defaults to PUBLIC.
+func fieldCacheHint(definition *ast.FieldDefinition) (CachePolicy, bool) {
+	if definition == nil {
+		return CachePolicy{}, false
+	}
+	directive := definition.Directives.ForName(CacheControlDirective)
+	if directive == nil {
+		return CachePolicy{}, false
+	}
+
+	hint := CachePolicy{Scope: CacheScopePublic}
+	if arg := directive.Arguments.ForName("maxAge"); arg != nil {
+		if value, err := arg.Value.Value(nil); err == nil {
+			if seconds, ok := toInt(value); ok && seconds > 0 {
+				hint.MaxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if arg := directive.Arguments.ForName("scope"); arg != nil && arg.Value.Raw == CacheScopePrivate {
+		hint.Scope = CacheScopePrivate
+	}
+	return hint, true
+}
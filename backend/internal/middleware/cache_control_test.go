@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/graph/generated"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func withTestAuth(userID uint, role models.UserRole, facultyID uint) context.Context {
+	return context.WithValue(context.Background(), AuthContextKey, &AuthContext{
+		User:      &models.User{ID: userID, Role: role, FacultyID: &facultyID},
+		UserID:    userID,
+		Role:      role,
+		FacultyID: &facultyID,
+	})
+}
+
+func TestCachePartition(t *testing.T) {
+	studentA := withTestAuth(1, models.UserRoleStudent, 10)
+	studentB := withTestAuth(2, models.UserRoleStudent, 20)
+	anonymous := context.Background()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		scope         string
+		wantPartition string
+		wantCached    bool
+	}{
+		{"public anonymous", anonymous, CacheScopePublic, "public:anonymous", true},
+		{"public signed in", studentA, CacheScopePublic, "public:student", true},
+		{"private anonymous", anonymous, CacheScopePrivate, "", false},
+		{"private signed in", studentA, CacheScopePrivate, "private:1", true},
+		{"private other user", studentB, CacheScopePrivate, "private:2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partition, cached := cachePartition(tt.ctx, tt.scope)
+			if partition != tt.wantPartition || cached != tt.wantCached {
+				t.Errorf("cachePartition() = %q, %v, want %q, %v", partition, cached, tt.wantPartition, tt.wantCached)
+			}
+		})
+	}
+
+	// Public responses are shared across faculties, so faculty-filtered fields must be private
+	publicA, _ := cachePartition(studentA, CacheScopePublic)
+	publicB, _ := cachePartition(studentB, CacheScopePublic)
+	if publicA != publicB {
+		t.Errorf("public partitions of students differ: %q and %q", publicA, publicB)
+	}
+}
+
+func TestOperationCachePolicy(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantPolicy CachePolicy
+		wantCached bool
+	}{
+		{"public", "{ faculties { id name } }", CachePolicy{MaxAge: 300 * time.Second, Scope: CacheScopePublic}, true},
+		// Filtered by the caller's faculty
+		{"departments", "{ departments { id name } }", CachePolicy{MaxAge: 300 * time.Second, Scope: CacheScopePrivate}, true},
+		{"department", `{ department(id: "1") { id name } }`, CachePolicy{MaxAge: 300 * time.Second, Scope: CacheScopePrivate}, true},
+		{"private field makes all private", "{ faculties { id } departments { id } }", CachePolicy{MaxAge: 300 * time.Second, Scope: CacheScopePrivate}, true},
+		{"unhinted root field", "{ faculties { id } me { id } }", CachePolicy{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(schema, tt.query)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", tt.query, err)
+			}
+			policy, cached := OperationCachePolicy(doc.Operations[0].SelectionSet, doc.Fragments)
+			if policy != tt.wantPolicy || cached != tt.wantCached {
+				t.Errorf("OperationCachePolicy() = %+v, %v, want %+v, %v", policy, cached, tt.wantPolicy, tt.wantCached)
+			}
+		})
+	}
+}
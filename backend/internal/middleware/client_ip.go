@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type remoteAddrKey struct{}
+
+// WithRemoteAddr records the address of the connection a request came in on, for the auth
+// extension to tell proxied requests from direct ones
+func WithRemoteAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), remoteAddrKey{}, host)))
+	})
+}
+
+func remoteAddrFromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// SetTrustedProxies sets the proxies, as IPs or CIDR ranges, whose X-Forwarded-For entries
+// are believed. Without any, only the last entry is, being the one the proxy in front of the
+// server appended.
+func (gam *GraphQLAuthMiddleware) SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	gam.trustedProxies = networks
+	return nil
+}
+
+func (gam *GraphQLAuthMiddleware) isTrustedProxy(ip net.IP) bool {
+	for _, network := range gam.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client in front of the trusted proxies. A request
+// straight from an untrusted address is that address, whatever its headers say. Otherwise
+// X-Forwarded-For is read from the right: each proxy appends the address it got the request
+// from, so entries left of the first untrusted one are whatever the client sent.
+func (gam *GraphQLAuthMiddleware) clientIP(ctx context.Context, headers http.Header) string {
+	peer := remoteAddrFromContext(ctx)
+	if peerIP := net.ParseIP(peer); peerIP != nil && !gam.isTrustedProxy(peerIP) {
+		return peer
+	}
+
+	var hops []string
+	for _, value := range headers.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			break
+		}
+		client = hop
+		if !gam.isTrustedProxy(ip) {
+			break
+		}
+	}
+	if client != "" {
+		return client
+	}
+
+	// X-Real-IP is only believed from a proxy known to set it
+	if peer != "" {
+		if realIP := net.ParseIP(strings.TrimSpace(headers.Get("X-Real-IP"))); realIP != nil {
+			return realIP.String()
+		}
+	}
+	return peer
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	gam := &GraphQLAuthMiddleware{}
+	if err := gam.SetTrustedProxies([]string{"10.0.0.0/8", "169.254.1.1"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		realIP    string
+		want      string
+	}{
+		{"direct request ignores headers", "203.0.113.9", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.9"},
+		{"proxied request", "10.1.2.3", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed leftmost entry", "10.1.2.3", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3", []string{"1.2.3.4, 198.51.100.1, 169.254.1.1, 10.0.0.5"}, "", "198.51.100.1"},
+		{"repeated headers", "10.1.2.3", []string{"1.2.3.4", "198.51.100.1, 10.0.0.5"}, "", "198.51.100.1"},
+		{"only trusted hops", "10.1.2.3", []string{"10.0.0.7, 10.0.0.5"}, "", "10.0.0.7"},
+		{"garbage stops the walk", "10.1.2.3", []string{"198.51.100.1, not-an-ip, 10.0.0.5"}, "", "10.0.0.5"},
+		{"real IP from a trusted proxy", "10.1.2.3", nil, "198.51.100.2", "198.51.100.2"},
+		{"no headers", "10.1.2.3", nil, "", "10.1.2.3"},
+		{"unknown peer uses the last hop", "", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"unknown peer ignores real IP", "", nil, "198.51.100.2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.peer != "" {
+				ctx = context.WithValue(ctx, remoteAddrKey{}, tt.peer)
+			}
+			headers := http.Header{}
+			for _, value := range tt.forwarded {
+				headers.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				headers.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := gam.clientIP(ctx, headers); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	gam := &GraphQLAuthMiddleware{}
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.internal", ""} {
+		if err := gam.SetTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("SetTrustedProxies(%q) succeeded, want an error", proxy)
+		}
+	}
+}
+
+func TestWithRemoteAddr(t *testing.T) {
+	var got string
+	handler := WithRemoteAddr(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = remoteAddrFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.RemoteAddr = "203.0.113.9:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "203.0.113.9" {
+		t.Errorf("remote address = %q, want 203.0.113.9", got)
+	}
+}
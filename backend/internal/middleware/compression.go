@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/compression"
+)
+
+type CompressionMiddleware struct {
+	config compression.Config
+}
+
+func NewCompressionMiddleware(config compression.Config) *CompressionMiddleware {
+	return &CompressionMiddleware{
+		config: config,
+	}
+}
+
+// Compress compresses responses according to the client's Accept-Encoding header.
+// Only allowlisted content types are compressed; small payloads, streamed bodies,
+// event streams and already-encoded responses are passed through untouched.
+func (cm *CompressionMiddleware) Compress() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		if !cm.config.Enabled {
+			return nil
+		}
+
+		response := c.Response()
+		c.Vary(fiber.HeaderAcceptEncoding)
+
+		if response.IsBodyStream() || len(response.Header.Peek(fiber.HeaderContentEncoding)) > 0 {
+			return nil
+		}
+		if !cm.config.AllowsContentType(string(response.Header.ContentType())) {
+			return nil
+		}
+
+		body := response.Body()
+		if !cm.config.ShouldCompress(len(body)) {
+			return nil
+		}
+
+		encoding := compression.NegotiateEncoding(c.Get(fiber.HeaderAcceptEncoding), compression.ResponseEncodings)
+		if encoding == "" {
+			return nil
+		}
+
+		compressed, err := compression.Compress(encoding, body)
+		if err != nil {
+			// Fall back to the uncompressed body rather than failing the request
+			log.Printf("Failed to %s response: %v", encoding, err)
+			return nil
+		}
+
+		response.SetBody(compressed)
+		c.Set(fiber.HeaderContentEncoding, encoding)
+		return nil
+	}
+}
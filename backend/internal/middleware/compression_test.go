@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/compression"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("activity ", 500) + `"}`
+	small := `{"data":"ok"}`
+
+	app := fiber.New()
+	app.Use(NewCompressionMiddleware(compression.Config{Enabled: true, MinBytes: compression.DefaultMinBytes}).Compress())
+	app.Get("/large", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.SendString(large)
+	})
+	app.Get("/small", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.SendString(small)
+	})
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"":                         func(r io.Reader) (io.Reader, error) { return r, nil },
+		compression.EncodingBrotli: func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		compression.EncodingGzip:   func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		{"/large", "br, gzip", compression.EncodingBrotli, large},
+		{"/large", "gzip", compression.EncodingGzip, large},
+		{"/large", "gzip;q=0, *", compression.EncodingBrotli, large},
+		{"/large", "", "", large},
+		{"/small", "br, gzip", "", small},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.acceptEncoding != "" {
+			req.Header.Set(fiber.HeaderAcceptEncoding, tt.acceptEncoding)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		encoding := resp.Header.Get(fiber.HeaderContentEncoding)
+		if encoding != tt.wantEncoding {
+			t.Errorf("GET %s with %q: Content-Encoding = %q, want %q", tt.path, tt.acceptEncoding, encoding, tt.wantEncoding)
+			continue
+		}
+		if !strings.Contains(resp.Header.Get(fiber.HeaderVary), fiber.HeaderAcceptEncoding) {
+			t.Errorf("GET %s: Vary = %q, want Accept-Encoding", tt.path, resp.Header.Get(fiber.HeaderVary))
+		}
+
+		reader, err := decoders[encoding](bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("GET %s: opening %s body: %v", tt.path, encoding, err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("GET %s: decoding %s body: %v", tt.path, encoding, err)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("GET %s with %q: decoded body differs from the original", tt.path, tt.acceptEncoding)
+		}
+		if encoding != "" && len(raw) >= len(tt.wantBody) {
+			t.Errorf("GET %s: %s body is %d bytes, not smaller than %d", tt.path, encoding, len(raw), len(tt.wantBody))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NewOriginMatcher returns a CORS origin check against an allowlist. Entries are exact
+// origins such as "https://activity.tru.ac.th" or wildcard subdomains such as
+// "https://*.tru.ac.th", which match any subdomain but not the bare domain.
+func NewOriginMatcher(allowedOrigins []string) func(origin string) bool {
+	exact := make(map[string]bool)
+	var wildcards []originPattern
+
+	for _, allowed := range allowedOrigins {
+		allowed = strings.ToLower(strings.TrimRight(strings.TrimSpace(allowed), "/"))
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			wildcards = append(wildcards, originPattern{scheme: scheme, suffix: "." + host})
+			continue
+		}
+		if allowed != "" {
+			exact[allowed] = true
+		}
+	}
+
+	return func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Host == "" || parsed.Path != "" {
+			return false
+		}
+		for _, pattern := range wildcards {
+			if parsed.Scheme == pattern.scheme && strings.HasSuffix(parsed.Host, pattern.suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type originPattern struct {
+	scheme string
+	suffix string
+}
+
+// NewWebsocketOriginCheck returns a websocket upgrader's origin check. Browsers don't apply CORS
+// to websockets, so cross-origin upgrades are only accepted from allowed origins; requests
+// without an Origin header don't come from a browser page.
+func NewWebsocketOriginCheck(allowed func(origin string) bool) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+			return true
+		}
+		return allowed(origin)
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+var adminRoles = []models.UserRole{models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin}
+
+// defaultFieldRules keys are "Type.field"; "Type.*" covers every field of a type and "*.field" a field on any type
+var defaultFieldRules = map[string]FieldRule{
+	"AuthPayload.token": {Public: true},
+	// Returned once, to the admin issuing it
+	"ScannerToken.token": {Roles: adminRoles},
+
+	"Query.systemMetrics":   {Roles: []models.UserRole{models.UserRoleSuperAdmin}},
+	"Query.facultyMetrics":  {Roles: []models.UserRole{models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin}},
+	"Query.subscriptions":   {Roles: []models.UserRole{models.UserRoleSuperAdmin}},
+	"Faculty.subscriptions": {Roles: []models.UserRole{models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin}},
+
+	// Students only ever see their own QR secret and participations
+	"User.qrSecret":       {Owner: ownsUser},
+	"User.participations": {Roles: adminRoles, Owner: ownsUser},
+	"Participation.*":     {Roles: adminRoles, Owner: ownsParticipation},
+}
+
+// defaultSensitiveFields are denied wherever no rule names them
+var defaultSensitiveFields = []string{"password", "passwordHash", "secret", "qrSecret", "privateKey", "token"}
+
+// DefaultFieldPermissions is the registry the security middleware uses unless given another
+var DefaultFieldPermissions = NewFieldPermissions(defaultFieldRules, defaultSensitiveFields)
+
+// OwnershipRule reports whether the caller owns obj, the object the field is read from.
+// obj may be a value, a pointer or a pointer to a pointer of the model.
+type OwnershipRule func(caller FieldCaller, obj interface{}) bool
+
+// FieldRule grants access to a field. Public fields are readable by anyone; otherwise the
+// caller must be signed in and either hold one of Roles or pass the Owner check.
+type FieldRule struct {
+	Public bool
+	Roles  []models.UserRole
+	Owner  OwnershipRule
+}
+
+// FieldCaller is who a field is being read for
+type FieldCaller struct {
+	Authenticated bool
+	UserID        uint
+	Role          models.UserRole
+}
+
+// FieldAccess is the outcome of a permission check
+type FieldAccess int
+
+const (
+	FieldAllowed FieldAccess = iota
+	FieldDenied
+	// FieldNeedsOwner means access depends on an ownership rule that needs the parent object
+	FieldNeedsOwner
+)
+
+// FieldPermissions is a declarative field path to access rule registry. Fields without a
+// rule are allowed unless their name is listed as sensitive.
+type FieldPermissions struct {
+	rules     map[string]FieldRule
+	sensitive map[string]bool
+}
+
+func NewFieldPermissions(rules map[string]FieldRule, sensitiveFields []string) *FieldPermissions {
+	sensitive := make(map[string]bool, len(sensitiveFields))
+	for _, field := range sensitiveFields {
+		sensitive[field] = true
+	}
+	return &FieldPermissions{rules: rules, sensitive: sensitive}
+}
+
+// Check decides access to object.field without looking at the parent object
+func (fp *FieldPermissions) Check(caller FieldCaller, object, field string) FieldAccess {
+	rule, ok := fp.lookup(object, field)
+	if !ok {
+		if fp.sensitive[field] {
+			return FieldDenied
+		}
+		return FieldAllowed
+	}
+
+	if rule.Public {
+		return FieldAllowed
+	}
+	if !caller.Authenticated {
+		return FieldDenied
+	}
+	for _, role := range rule.Roles {
+		if role == caller.Role {
+			return FieldAllowed
+		}
+	}
+	if rule.Owner != nil {
+		return FieldNeedsOwner
+	}
+	return FieldDenied
+}
+
+// Allows decides access to object.field, using obj for ownership rules
+func (fp *FieldPermissions) Allows(caller FieldCaller, object, field string, obj interface{}) bool {
+	switch fp.Check(caller, object, field) {
+	case FieldAllowed:
+		return true
+	case FieldNeedsOwner:
+		rule, _ := fp.lookup(object, field)
+		return obj != nil && rule.Owner(caller, obj)
+	default:
+		return false
+	}
+}
+
+func (fp *FieldPermissions) lookup(object, field string) (FieldRule, bool) {
+	for _, key := range []string{object + "." + field, object + ".*", "*." + field} {
+		if rule, ok := fp.rules[key]; ok {
+			return rule, true
+		}
+	}
+	return FieldRule{}, false
+}
+
+// fieldCallerFromContext builds the caller from the GraphQL auth context
+func fieldCallerFromContext(ctx context.Context) FieldCaller {
+	authCtx, err := GetAuthContext(ctx)
+	if err != nil {
+		return FieldCaller{}
+	}
+	return FieldCaller{Authenticated: true, UserID: authCtx.UserID, Role: authCtx.Role}
+}
+
+// parentObject returns the object the current field is being read from. gqlgen stores each
+// resolved object, and each list element, as the Result of an enclosing field context.
+func parentObject(fc *graphql.FieldContext) interface{} {
+	for parent := fc.Parent; parent != nil; parent = parent.Parent {
+		if parent.Result != nil {
+			return parent.Result
+		}
+	}
+	return nil
+}
+
+func ownsUser(caller FieldCaller, obj interface{}) bool {
+	switch user := obj.(type) {
+	case *models.User:
+		return user != nil && user.ID == caller.UserID
+	case **models.User:
+		return user != nil && *user != nil && (*user).ID == caller.UserID
+	case models.User:
+		return user.ID == caller.UserID
+	}
+	return false
+}
+
+func ownsParticipation(caller FieldCaller, obj interface{}) bool {
+	switch participation := obj.(type) {
+	case *models.Participation:
+		return participation != nil && participation.UserID == caller.UserID
+	case **models.Participation:
+		return participation != nil && *participation != nil && (*participation).UserID == caller.UserID
+	case models.Participation:
+		return participation.UserID == caller.UserID
+	}
+	return false
+}
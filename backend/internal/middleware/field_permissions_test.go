@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+func TestFieldPermissionsCheck(t *testing.T) {
+	anonymous := FieldCaller{}
+	student := FieldCaller{Authenticated: true, UserID: 1, Role: models.UserRoleStudent}
+	facultyAdmin := FieldCaller{Authenticated: true, UserID: 2, Role: models.UserRoleFacultyAdmin}
+	superAdmin := FieldCaller{Authenticated: true, UserID: 3, Role: models.UserRoleSuperAdmin}
+
+	tests := []struct {
+		name   string
+		caller FieldCaller
+		object string
+		field  string
+		want   FieldAccess
+	}{
+		{"unlisted field", anonymous, "Activity", "title", FieldAllowed},
+		{"public rule on a sensitive name", anonymous, "AuthPayload", "token", FieldAllowed},
+		{"sensitive name without a rule", student, "Faculty", "secret", FieldDenied},
+		{"role rule, role held", superAdmin, "Query", "systemMetrics", FieldAllowed},
+		{"role rule, role missing", facultyAdmin, "Query", "systemMetrics", FieldDenied},
+		{"role rule, signed out", anonymous, "Query", "subscriptions", FieldDenied},
+		{"owner rule needs the object", student, "User", "qrSecret", FieldNeedsOwner},
+		{"owner rule, signed out", anonymous, "User", "qrSecret", FieldDenied},
+		{"wildcard type rule, admin", facultyAdmin, "Participation", "notes", FieldAllowed},
+		{"wildcard type rule, student", student, "Participation", "notes", FieldNeedsOwner},
+		{"scanner token for a student", student, "ScannerToken", "token", FieldDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultFieldPermissions.Check(tt.caller, tt.object, tt.field); got != tt.want {
+				t.Errorf("Check(%s.%s) = %v, want %v", tt.object, tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldPermissionsAllowsOwner(t *testing.T) {
+	student := FieldCaller{Authenticated: true, UserID: 1, Role: models.UserRoleStudent}
+	own := &models.User{ID: 1}
+	other := &models.User{ID: 2}
+
+	tests := []struct {
+		name   string
+		object string
+		field  string
+		obj    interface{}
+		want   bool
+	}{
+		{"own user", "User", "qrSecret", own, true},
+		{"own user by pointer to pointer", "User", "qrSecret", &own, true},
+		{"own user by value", "User", "qrSecret", *own, true},
+		{"other user", "User", "qrSecret", other, false},
+		{"no parent object", "User", "qrSecret", nil, false},
+		{"own participation", "Participation", "status", &models.Participation{UserID: 1}, true},
+		{"other participation", "Participation", "status", &models.Participation{UserID: 2}, false},
+		{"wrong type", "Participation", "status", own, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultFieldPermissions.Allows(student, tt.object, tt.field, tt.obj); got != tt.want {
+				t.Errorf("Allows(%s.%s) = %v, want %v", tt.object, tt.field, got, tt.want)
+			}
+		})
+	}
+}
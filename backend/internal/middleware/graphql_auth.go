@@ -3,20 +3,29 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
 	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
 	"github.com/kruakemaths/tru-activity/backend/pkg/permissions"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/gorm"
 )
 
 type GraphQLAuthMiddleware struct {
-	jwtService  *auth.JWTService
-	db          *gorm.DB
-	permissions *permissions.PermissionChecker
+	jwtService   *auth.JWTService
+	sessionStore *auth.SessionStore
+	db           *gorm.DB
+	permissions  *permissions.PermissionChecker
+
+	// websockets holds the open GraphQL websockets so a user's can be closed on demand
+	websockets *websocketRegistry
+
+	// trustedProxies are believed about the client address; see clientIP
+	trustedProxies []*net.IPNet
 }
 
 type AuthContext struct {
@@ -32,27 +41,27 @@ type AuthContext struct {
 
 const AuthContextKey = "auth"
 
-func NewGraphQLAuthMiddleware(jwtService *auth.JWTService, db *gorm.DB) *GraphQLAuthMiddleware {
+// ScannerTokenHeader carries a volunteer's scanner token; resolvers read it from the
+// context under "scanner_token"
+const ScannerTokenHeader = "X-Scanner-Token"
+
+func NewGraphQLAuthMiddleware(jwtService *auth.JWTService, sessionStore *auth.SessionStore, db *gorm.DB) *GraphQLAuthMiddleware {
 	return &GraphQLAuthMiddleware{
-		jwtService:  jwtService,
-		db:          db,
-		permissions: permissions.NewPermissionChecker(),
+		jwtService:   jwtService,
+		sessionStore: sessionStore,
+		db:           db,
+		permissions:  permissions.NewPermissionChecker(),
+		websockets:   newWebsocketRegistry(),
 	}
 }
 
 // ExtractAuth middleware สำหรับการ extract ข้อมูล auth จาก header
 func (gam *GraphQLAuthMiddleware) ExtractAuth() graphql.HandlerExtension {
-	return &authExtension{
-		jwtService:  gam.jwtService,
-		db:          gam.db,
-		permissions: gam.permissions,
-	}
+	return &authExtension{GraphQLAuthMiddleware: gam}
 }
 
 type authExtension struct {
-	jwtService  *auth.JWTService
-	db          *gorm.DB
-	permissions *permissions.PermissionChecker
+	*GraphQLAuthMiddleware
 }
 
 func (ae *authExtension) ExtensionName() string {
@@ -66,26 +75,18 @@ func (ae *authExtension) Validate(schema graphql.ExecutableSchema) error {
 func (ae *authExtension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
 	// Extract token from context (HTTP headers)
 	if reqCtx := graphql.GetOperationContext(ctx); reqCtx != nil {
-		authHeader := reqCtx.Headers.Get("Authorization")
-		if authHeader != "" {
-			tokenParts := strings.Split(authHeader, " ")
-			if len(tokenParts) == 2 && tokenParts[0] == "Bearer" {
-				if claims, err := ae.jwtService.ValidateToken(tokenParts[1]); err == nil {
-					// Load user from database
-					var user models.User
-					if err := ae.db.Preload("Faculty").Preload("Department").First(&user, claims.UserID).Error; err == nil {
-						authCtx := &AuthContext{
-							User:         &user,
-							Claims:       claims,
-							Permissions:  ae.permissions,
-							UserID:       user.ID,
-							Role:         user.Role,
-							FacultyID:    user.FacultyID,
-							DepartmentID: user.DepartmentID,
-						}
-						ctx = context.WithValue(ctx, AuthContextKey, authCtx)
-					}
-				}
+		// Client info สำหรับ audit log และ rate limiting
+		ctx = context.WithValue(ctx, "client_ip", ae.clientIP(ctx, reqCtx.Headers))
+		ctx = context.WithValue(ctx, "user_agent", reqCtx.Headers.Get("User-Agent"))
+		ctx = context.WithValue(ctx, "scanner_token", reqCtx.Headers.Get(ScannerTokenHeader))
+
+		// Websocket operations are already authenticated by WebsocketInit
+		if _, ok := ctx.Value(AuthContextKey).(*AuthContext); !ok {
+			if authHeader := reqCtx.Headers.Get("Authorization"); authHeader != "" {
+				opCtx := ctx
+				ctx = context.WithValue(ctx, lazyAuthKey{}, &lazyAuth{resolve: func() (*AuthContext, error) {
+					return ae.authenticate(opCtx, authHeader)
+				}})
 			}
 		}
 	}
@@ -93,6 +94,82 @@ func (ae *authExtension) InterceptOperation(ctx context.Context, next graphql.Op
 	return next(ctx)
 }
 
+type lazyAuthKey struct{}
+
+// lazyAuth โหลด user ครั้งแรกที่ resolver ต้องการ แล้วใช้ผลเดิมตลอด operation
+// operation ที่ไม่ตรวจสอบ auth (เช่น login) จึงไม่ต้อง query user เลย
+type lazyAuth struct {
+	once    sync.Once
+	resolve func() (*AuthContext, error)
+	authCtx *AuthContext
+	err     error
+}
+
+func (la *lazyAuth) get() (*AuthContext, error) {
+	la.once.Do(func() {
+		la.authCtx, la.err = la.resolve()
+	})
+	return la.authCtx, la.err
+}
+
+// authenticate ตรวจสอบ "Bearer <token>" แล้วโหลด user เป็น AuthContext
+func (gam *GraphQLAuthMiddleware) authenticate(ctx context.Context, authHeader string) (*AuthContext, error) {
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return nil, fmt.Errorf("invalid authorization header format")
+	}
+
+	claims, err := gam.jwtService.ValidateToken(tokenParts[1])
+	if err != nil || gam.isRevoked(ctx, claims) {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	// Load user from database; faculty and department come from the claims when they
+	// still match the user, saving a query for each
+	var user models.User
+	if err := gam.db.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.FacultyID != nil {
+		if org := claims.Faculty; org != nil && org.ID == *user.FacultyID {
+			user.Faculty = &models.Faculty{ID: org.ID, Code: org.Code, Name: org.Name}
+		} else {
+			var faculty models.Faculty
+			if err := gam.db.First(&faculty, *user.FacultyID).Error; err == nil {
+				user.Faculty = &faculty
+			}
+		}
+	}
+	if user.DepartmentID != nil {
+		if org := claims.Department; org != nil && org.ID == *user.DepartmentID {
+			user.Department = &models.Department{ID: org.ID, Code: org.Code, Name: org.Name}
+		} else {
+			var department models.Department
+			if err := gam.db.First(&department, *user.DepartmentID).Error; err == nil {
+				user.Department = &department
+			}
+		}
+	}
+
+	return &AuthContext{
+		User:         &user,
+		Claims:       claims,
+		Permissions:  gam.permissions,
+		UserID:       user.ID,
+		Role:         user.Role,
+		FacultyID:    user.FacultyID,
+		DepartmentID: user.DepartmentID,
+	}, nil
+}
+
+// isRevoked ตรวจสอบว่า token ถูกยกเลิกแล้วหรือไม่ (เช่น หลัง reset password)
+func (gam *GraphQLAuthMiddleware) isRevoked(ctx context.Context, claims *auth.JWTClaims) bool {
+	if gam.sessionStore == nil {
+		return false
+	}
+	return gam.sessionStore.IsRevoked(ctx, claims)
+}
+
 func (ae *authExtension) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
 	return next(ctx)
 }
@@ -104,10 +181,16 @@ func (ae *authExtension) InterceptResponse(ctx context.Context, next graphql.Res
 // Helper functions สำหรับใช้ใน resolvers
 
 // GetAuthContext ดึงข้อมูล auth จาก context
+// user ถูกโหลดเพียงครั้งเดียวต่อ operation ไม่ว่าจะเรียกกี่ครั้งจาก resolver ที่ซ้อนกัน
 func GetAuthContext(ctx context.Context) (*AuthContext, error) {
 	if authCtx, ok := ctx.Value(AuthContextKey).(*AuthContext); ok {
 		return authCtx, nil
 	}
+	if la, ok := ctx.Value(lazyAuthKey{}).(*lazyAuth); ok {
+		if authCtx, err := la.get(); err == nil {
+			return authCtx, nil
+		}
+	}
 	return nil, fmt.Errorf("authentication required")
 }
 
@@ -115,7 +198,7 @@ func GetAuthContext(ctx context.Context) (*AuthContext, error) {
 func RequireAuth(ctx context.Context) (*AuthContext, error) {
 	authCtx, err := GetAuthContext(ctx)
 	if err != nil {
-		return nil, gqlerror.Errorf("Authentication required")
+		return nil, errcode.Unauthenticated("Authentication required")
 	}
 	return authCtx, nil
 }
@@ -133,7 +216,7 @@ func RequireRole(ctx context.Context, roles ...models.UserRole) (*AuthContext, e
 		}
 	}
 
-	return nil, gqlerror.Errorf("Insufficient permissions")
+	return nil, errcode.Forbidden("Insufficient permissions")
 }
 
 // RequirePermission ตรวจสอบว่า user มี permission ที่กำหนด
@@ -144,7 +227,7 @@ func RequirePermission(ctx context.Context, permission permissions.Permission) (
 	}
 
 	if !authCtx.Permissions.HasPermission(authCtx.User, permission) {
-		return nil, gqlerror.Errorf("Permission denied: %s", permission)
+		return nil, errcode.Forbidden("Permission denied: %s", permission)
 	}
 
 	return authCtx, nil
@@ -158,7 +241,7 @@ func RequireFacultyPermission(ctx context.Context, permission permissions.Permis
 	}
 
 	if !authCtx.Permissions.HasFacultyPermission(authCtx.User, permission, facultyID) {
-		return nil, gqlerror.Errorf("Faculty permission denied: %s for faculty %d", permission, facultyID)
+		return nil, errcode.Forbidden("Faculty permission denied: %s for faculty %d", permission, facultyID)
 	}
 
 	return authCtx, nil
@@ -172,7 +255,7 @@ func RequireDepartmentPermission(ctx context.Context, permission permissions.Per
 	}
 
 	if !authCtx.Permissions.HasDepartmentPermission(authCtx.User, permission, departmentID) {
-		return nil, gqlerror.Errorf("Department permission denied: %s for department %d", permission, departmentID)
+		return nil, errcode.Forbidden("Department permission denied: %s for department %d", permission, departmentID)
 	}
 
 	return authCtx, nil
@@ -187,11 +270,11 @@ func CanManageUser(ctx context.Context, targetUserID uint, db *gorm.DB) (*AuthCo
 
 	var targetUser models.User
 	if err := db.First(&targetUser, targetUserID).Error; err != nil {
-		return nil, gqlerror.Errorf("User not found")
+		return nil, errcode.NotFound("User not found")
 	}
 
 	if !authCtx.Permissions.CanManageUser(authCtx.User, &targetUser) {
-		return nil, gqlerror.Errorf("Cannot manage this user")
+		return nil, errcode.Forbidden("Cannot manage this user")
 	}
 
 	return authCtx, nil
@@ -209,7 +292,7 @@ func IsOwnerOrAdmin(ctx context.Context, ownerID uint) (*AuthContext, error) {
 		return authCtx, nil
 	}
 
-	return nil, gqlerror.Errorf("Access denied: not owner or admin")
+	return nil, errcode.Forbidden("Access denied: not owner or admin")
 }
 
 // FilterByFaculty กรองข้อมูลตาม faculty ของ user
@@ -232,4 +315,79 @@ func FilterByFaculty(ctx context.Context, query *gorm.DB, facultyField string) *
 	}
 
 	return query
-}
\ No newline at end of file
+}
+
+// FilterActivitiesByFaculty กรองกิจกรรมตาม faculty ของ user โดยนับรวมคณะที่ร่วมจัด (activity_faculties)
+// กิจกรรมที่ไม่มีคณะและไม่มีผู้ร่วมจัดถือเป็นกิจกรรมของทุกคณะ
+func FilterActivitiesByFaculty(ctx context.Context, query *gorm.DB, activityTable string) *gorm.DB {
+	authCtx, err := GetAuthContext(ctx)
+	if err != nil {
+		return query
+	}
+
+	// Super admin สามารถดูทุกอย่าง
+	if authCtx.User.Role == models.UserRoleSuperAdmin {
+		return query
+	}
+
+	if authCtx.User.FacultyID != nil {
+		facultyField := activityTable + ".faculty_id"
+		idField := activityTable + ".id"
+		return query.Where(
+			facultyField+" = ? OR "+idField+" IN ("+models.ActivityCoHostSubquery+") OR ("+facultyField+" IS NULL AND NOT EXISTS (SELECT 1 FROM activity_faculties WHERE activity_faculties.activity_id = "+idField+"))",
+			*authCtx.User.FacultyID, *authCtx.User.FacultyID,
+		)
+	}
+
+	return query
+}
+
+// FilterByDepartment กรองข้อมูลให้เหลือเฉพาะ department ของ user ถ้า user มีขอบเขตระดับภาควิชา
+// ไม่เช่นนั้นเหลือเฉพาะ department ที่อยู่ในคณะของ user; ข้อมูลที่ไม่ผูกกับ department ยังเห็นได้
+// ใช้ต่อจาก FilterByFaculty ได้
+func FilterByDepartment(ctx context.Context, query *gorm.DB, departmentField string) *gorm.DB {
+	authCtx, err := GetAuthContext(ctx)
+	if err != nil || departmentField == "" {
+		return query
+	}
+
+	// Super admin สามารถดูทุกอย่าง
+	if authCtx.User.Role == models.UserRoleSuperAdmin {
+		return query
+	}
+
+	// Regular admin ประจำภาควิชาเห็นเฉพาะภาควิชาตัวเอง
+	if departmentID := authCtx.User.GetDepartmentScope(); departmentID != nil {
+		return query.Where(departmentField+" = ? OR "+departmentField+" IS NULL", *departmentID)
+	}
+
+	if authCtx.User.FacultyID != nil {
+		return query.Where(departmentField+" IN (SELECT id FROM departments WHERE faculty_id = ?) OR "+departmentField+" IS NULL", *authCtx.User.FacultyID)
+	}
+
+	return query
+}
+
+// InFacultyScope ตรวจสอบว่า target faculty อยู่ในขอบเขตของ user (super admin ผ่านทั้งหมด)
+func InFacultyScope(authCtx *AuthContext, targetFacultyID *uint) bool {
+	if authCtx.User.Role == models.UserRoleSuperAdmin {
+		return true
+	}
+
+	// Admin อื่นๆ จัดการได้เฉพาะข้อมูลในคณะตัวเอง
+	return authCtx.FacultyID != nil && targetFacultyID != nil && *authCtx.FacultyID == *targetFacultyID
+}
+
+// RequireFacultyScope ตรวจสอบว่า faculty-scoped mutation ทำงานกับคณะของ user เท่านั้น
+func RequireFacultyScope(ctx context.Context, targetFacultyID *uint) (*AuthContext, error) {
+	authCtx, err := RequireAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !InFacultyScope(authCtx, targetFacultyID) {
+		return nil, errcode.Forbidden("Access denied: resource belongs to another faculty")
+	}
+
+	return authCtx, nil
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("opening dry run database: %v", err)
+	}
+	return db
+}
+
+func TestFilterByDepartment(t *testing.T) {
+	departmentID := uint(5)
+	withDepartment := func(role models.UserRole, department *uint) context.Context {
+		ctx := withTestAuth(1, role, 10)
+		ctx.Value(AuthContextKey).(*AuthContext).User.DepartmentID = department
+		return ctx
+	}
+
+	const base = `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL`
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{"anonymous", context.Background(), base, []interface{}{}},
+		{"super admin", withDepartment(models.UserRoleSuperAdmin, &departmentID), base, []interface{}{}},
+		{
+			"department regular admin", withDepartment(models.UserRoleRegularAdmin, &departmentID),
+			`SELECT * FROM "users" WHERE (department_id = $1 OR department_id IS NULL) AND "users"."deleted_at" IS NULL`,
+			[]interface{}{uint(5)},
+		},
+		{
+			"faculty regular admin", withDepartment(models.UserRoleRegularAdmin, nil),
+			`SELECT * FROM "users" WHERE (department_id IN (SELECT id FROM departments WHERE faculty_id = $1) OR department_id IS NULL) AND "users"."deleted_at" IS NULL`,
+			[]interface{}{uint(10)},
+		},
+		{
+			// Only regular admins are limited to their department
+			"faculty admin with a department", withDepartment(models.UserRoleFacultyAdmin, &departmentID),
+			`SELECT * FROM "users" WHERE (department_id IN (SELECT id FROM departments WHERE faculty_id = $1) OR department_id IS NULL) AND "users"."deleted_at" IS NULL`,
+			[]interface{}{uint(10)},
+		},
+	}
+
+	db := newDryRunDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []models.User
+			stmt := FilterByDepartment(tt.ctx, db.Model(&models.User{}), "department_id").Find(&users).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s\nwant  %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+// runOperation runs an operation sent with the token through the auth extension, looking the
+// caller up lookups times as nested resolvers would
+func runOperation(t testing.TB, gam *GraphQLAuthMiddleware, token string, lookups int) {
+	ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+		Headers: http.Header{"Authorization": []string{"Bearer " + token}},
+	})
+	gam.ExtractAuth().(graphql.OperationInterceptor).InterceptOperation(ctx, func(ctx context.Context) graphql.ResponseHandler {
+		for i := 0; i < lookups; i++ {
+			if authCtx, err := GetAuthContext(ctx); err != nil || authCtx.UserID != 7 {
+				t.Fatalf("GetAuthContext() = %+v, %v, want user 7", authCtx, err)
+			}
+		}
+		return nil
+	})
+}
+
+func TestInterceptOperationLoadsTheUserOncePerOperation(t *testing.T) {
+	token := testToken(t)
+	for _, lookups := range []int{0, 1, 5} {
+		gam, fake := newTestAuthMiddleware(t)
+		runOperation(t, gam, token, lookups)
+
+		want := 1
+		if lookups == 0 {
+			want = 0
+		}
+		if got := len(fakedb.Containing(fake.Statements(), `FROM "users"`)); got != want {
+			t.Errorf("%d lookups ran %d user queries, want %d", lookups, got, want)
+		}
+	}
+}
+
+// BenchmarkLazyAuth shows an operation that never checks auth skips the user query, and ten
+// lookups cost what one does
+func BenchmarkLazyAuth(b *testing.B) {
+	token := testToken(b)
+	for _, lookups := range []int{0, 1, 10} {
+		b.Run(fmt.Sprintf("%d lookups", lookups), func(b *testing.B) {
+			gam, _ := newTestAuthMiddleware(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runOperation(b, gam, token, lookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/permissions"
+)
+
+// AuthDirective implements @auth
+func AuthDirective(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, err := RequireAuth(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// HasRoleDirective implements @hasRole(roles)
+func HasRoleDirective(ctx context.Context, obj interface{}, next graphql.Resolver, roles []models.UserRole) (interface{}, error) {
+	if _, err := RequireRole(ctx, roles...); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// HasPermissionDirective implements @hasPermission(permission)
+func HasPermissionDirective(ctx context.Context, obj interface{}, next graphql.Resolver, permission string) (interface{}, error) {
+	if _, err := RequirePermission(ctx, permissions.Permission(permission)); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/kruakemaths/tru-activity/backend/graph/generated"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func errorCode(err error) string {
+	var gqlErr *gqlerror.Error
+	if !errors.As(err, &gqlErr) {
+		return ""
+	}
+	code, _ := gqlErr.Extensions["code"].(string)
+	return code
+}
+
+func TestDirectivesReportErrorCodes(t *testing.T) {
+	student := withTestAuth(1, models.UserRoleStudent, 10)
+	admin := withTestAuth(2, models.UserRoleSuperAdmin, 10)
+	resolved := func(ctx context.Context) (interface{}, error) { return "resolved", nil }
+
+	tests := []struct {
+		name     string
+		call     func() (interface{}, error)
+		wantCode string
+	}{
+		{"auth, signed out", func() (interface{}, error) {
+			return AuthDirective(context.Background(), nil, resolved)
+		}, "UNAUTHENTICATED"},
+		{"auth, signed in", func() (interface{}, error) {
+			return AuthDirective(student, nil, resolved)
+		}, ""},
+		{"role, signed out", func() (interface{}, error) {
+			return HasRoleDirective(context.Background(), nil, resolved, []models.UserRole{models.UserRoleSuperAdmin})
+		}, "UNAUTHENTICATED"},
+		{"role missing", func() (interface{}, error) {
+			return HasRoleDirective(student, nil, resolved, []models.UserRole{models.UserRoleSuperAdmin})
+		}, "FORBIDDEN"},
+		{"role held", func() (interface{}, error) {
+			return HasRoleDirective(admin, nil, resolved, []models.UserRole{models.UserRoleSuperAdmin})
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.call()
+			if tt.wantCode == "" {
+				if err != nil || value != "resolved" {
+					t.Errorf("directive = %v, %v, want the field resolved", value, err)
+				}
+				return
+			}
+			if value != nil || err == nil {
+				t.Fatalf("directive = %v, %v, want an error", value, err)
+			}
+			if code := errorCode(err); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDirectiveErrorCodesReachTheResponse(t *testing.T) {
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Directives: generated.DirectiveRoot{
+			Auth:          AuthDirective,
+			HasRole:       HasRoleDirective,
+			HasPermission: HasPermissionDirective,
+		},
+	}))
+	srv.AddTransport(transport.POST{})
+
+	resp := postQuery(t, srv, "{ users { id } }")
+	if len(resp.Errors) != 1 {
+		t.Fatalf("got %d errors, want 1: %+v", len(resp.Errors), resp.Errors)
+	}
+	if code := resp.Errors[0].Extensions["code"]; code != "UNAUTHENTICATED" {
+		t.Errorf("code = %v, want UNAUTHENTICATED", code)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	DefaultSlowOperationThreshold = time.Second
+	DefaultHighComplexity         = 200
+)
+
+// PerformanceExtension records how long each query and mutation takes, with its complexity, so
+// slow operations can be told apart: an expensive query, or a cheap one slowed down by the data
+// layer. Register it before the security middleware, whose complexity it reuses; without it the
+// complexity is computed here. Subscriptions are not timed.
+type PerformanceExtension struct {
+	Monitor *monitoring.PerformanceMonitor
+	// SlowThreshold is how long an operation takes to be logged as slow
+	SlowThreshold time.Duration
+	// HighComplexity is the complexity from which a slow operation is blamed on the query
+	HighComplexity int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = PerformanceExtension{}
+
+func (pe PerformanceExtension) ExtensionName() string {
+	return "Performance"
+}
+
+func (pe PerformanceExtension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (pe PerformanceExtension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if pe.Monitor == nil || oc == nil || oc.Operation == nil || oc.Operation.Operation == ast.Subscription {
+		return next(ctx)
+	}
+
+	started := time.Now()
+	complexity := -1
+	ctx = context.WithValue(ctx, operationComplexityKey{}, &complexity)
+	handler := next(ctx)
+
+	first := true
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if !first {
+			return resp
+		}
+		first = false
+
+		if complexity < 0 {
+			complexity = OperationComplexity(oc)
+		}
+		pe.record(ctx, oc, complexity, time.Since(started))
+		return resp
+	}
+}
+
+func (pe PerformanceExtension) record(ctx context.Context, oc *graphql.OperationContext, complexity int, duration time.Duration) {
+	name := oc.OperationName
+	if name == "" {
+		name = "anonymous"
+	}
+	cost := monitoring.OperationCost{
+		Name:       name,
+		Type:       string(oc.Operation.Operation),
+		Complexity: complexity,
+		Duration:   duration,
+		Slow:       duration >= pe.SlowThreshold,
+	}
+
+	if cost.Slow {
+		if complexity >= pe.HighComplexity {
+			log.Printf("Slow GraphQL %s %s took %v at complexity %d: the query is expensive", cost.Type, name, duration, complexity)
+		} else {
+			log.Printf("Slow GraphQL %s %s took %v at complexity %d only: check the database", cost.Type, name, duration, complexity)
+		}
+	}
+
+	go func() {
+		if err := pe.Monitor.RecordOperationCost(context.WithoutCancel(ctx), cost); err != nil {
+			log.Printf("Failed to record cost of GraphQL operation %s: %v", name, err)
+		}
+	}()
+}
+
+type operationComplexityKey struct{}
+
+// reportOperationComplexity hands the complexity the security middleware computed to the
+// PerformanceExtension timing the operation, if any
+func reportOperationComplexity(ctx context.Context, complexity int) {
+	if reported, ok := ctx.Value(operationComplexityKey{}).(*int); ok {
+		*reported = complexity
+	}
+}
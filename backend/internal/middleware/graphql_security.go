@@ -5,13 +5,25 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/kruakemaths/tru-activity/backend/pkg/ratelimit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/kruakemaths/tru-activity/backend/pkg/security"
 	"github.com/redis/go-redis/v9"
 	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 const (
@@ -19,24 +31,151 @@ const (
 	MaxQueryDepth        = 15
 	MaxQueryComplexity   = 1000
 	MaxQueryNodes        = 100
+	MaxFragmentSpreads   = 100  // fragment spreads expanded per operation, counting repeats
+	ListFieldComplexity  = 10   // default cost of a list field without @complexity
+	DefaultPageSize      = 20   // page size assumed for paginated fields sent without one
+	ComplexityDirective  = "complexity"
 	DefaultRateLimit     = 100  // requests per minute
 	AdminRateLimit       = 1000 // higher limit for admins
 	QRScanRateLimit      = 30   // QR scans per minute
+	AnonymousRateLimit   = 30   // unauthenticated requests per minute per IP
+	AuthRateLimit        = 10   // login/register attempts per minute per IP
 	
 	// Redis keys
 	RateLimitPrefix      = "rate_limit:"
+	IPRateLimitPrefix    = "rate_limit:ip:"
+	AuthRateLimitPrefix  = "auth_rate_limit:"
+	LoginAccountPrefix   = "auth_rate_limit:account:"
 	QRScanLimitPrefix    = "qr_scan_limit:"
 	QueryCachePrefix     = "query_cache:"
 	SecurityEventPrefix  = "security_event:"
+
+	// Input limits
+	MaxStringInputLength = 10000
 )
 
+// Pagination arguments that scale a field's child complexity unless @complexity names others
+var DefaultComplexityMultipliers = []string{"limit", "first"}
+
+// Input validation rules
+var (
+	// Length caps for known fields, matching their column sizes
+	fieldLengthLimits = map[string]int{
+		"title":          200,
+		"name":           200,
+		"location":       200,
+		"scanLocation":   200,
+		"firstName":      50,
+		"lastName":       50,
+		"code":           10,
+		"description":    5000,
+		"notes":          5000,
+		"recurrenceRule": 500,
+	}
+
+	// Optional character allowlists for fields with a fixed format
+	fieldAllowlists = map[string]func(rune) bool{
+		"code": isCodeChar,
+	}
+
+	// Fields rendered as HTML by the frontend
+	htmlRenderedFields = map[string]bool{
+		"description": true,
+		"notes":       true,
+		"message":     true,
+	}
+
+	scriptPatterns = []string{"<script", "javascript:", "onload=", "onerror="}
+)
+
+// Mutations that accept credentials and need brute-force protection
+var authMutationFields = map[string]bool{
+	"login":                true,
+	"register":             true,
+	"requestPasswordReset": true,
+	"resetPassword":        true,
+}
+
+// RateLimitConfig sets the per-role and per-operation limits enforced over a rolling window
+type RateLimitConfig struct {
+	Window         time.Duration
+	DefaultLimit   int
+	AdminLimit     int
+	AnonymousLimit int
+	AuthLimit      int
+	QRScanLimit    int
+}
+
+// DefaultRateLimitConfig returns the standard limits per rolling minute
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Window:         time.Minute,
+		DefaultLimit:   DefaultRateLimit,
+		AdminLimit:     AdminRateLimit,
+		AnonymousLimit: AnonymousRateLimit,
+		AuthLimit:      AuthRateLimit,
+		QRScanLimit:    QRScanRateLimit,
+	}
+}
+
+// WithDefaults fills in zero fields from DefaultRateLimitConfig
+func (c RateLimitConfig) WithDefaults() RateLimitConfig {
+	defaults := DefaultRateLimitConfig()
+	if c.Window <= 0 {
+		c.Window = defaults.Window
+	}
+	if c.DefaultLimit <= 0 {
+		c.DefaultLimit = defaults.DefaultLimit
+	}
+	if c.AdminLimit <= 0 {
+		c.AdminLimit = defaults.AdminLimit
+	}
+	if c.AnonymousLimit <= 0 {
+		c.AnonymousLimit = defaults.AnonymousLimit
+	}
+	if c.AuthLimit <= 0 {
+		c.AuthLimit = defaults.AuthLimit
+	}
+	if c.QRScanLimit <= 0 {
+		c.QRScanLimit = defaults.QRScanLimit
+	}
+	return c
+}
+
 type SecurityMiddleware struct {
 	redisClient *redis.Client
+	auditLogger *audit.AuditLogger
+	rateLimiter *ratelimit.SlidingWindowLimiter
+	rateLimits  RateLimitConfig
+
+	// maxQRPayload is the longest qrData argument accepted
+	maxQRPayload int
+
+	// fieldPermissions decides which fields each caller may read
+	fieldPermissions *FieldPermissions
+
+	// queryBudgets are the soft complexity and row limits per role
+	queryBudgets QueryBudgets
 }
 
-func NewSecurityMiddleware(redisClient *redis.Client) *SecurityMiddleware {
+// NewSecurityMiddleware creates the middleware; zero rate limits and maxQRPayload use the defaults.
+// Register it after the auth extension, whose user it rate limits and authorizes.
+func NewSecurityMiddleware(redisClient *redis.Client, auditLogger *audit.AuditLogger, rateLimits RateLimitConfig, maxQRPayload int) *SecurityMiddleware {
+	if maxQRPayload <= 0 {
+		maxQRPayload = security.DefaultMaxQRPayloadLength
+	}
+
 	return &SecurityMiddleware{
 		redisClient: redisClient,
+		auditLogger: auditLogger,
+		rateLimiter: ratelimit.NewSlidingWindowLimiter(redisClient),
+		rateLimits:  rateLimits.WithDefaults(),
+
+		maxQRPayload: maxQRPayload,
+
+		fieldPermissions: DefaultFieldPermissions,
+
+		queryBudgets: DefaultQueryBudgets(),
 	}
 }
 
@@ -45,55 +184,108 @@ func (s *SecurityMiddleware) ExtensionName() string {
 	return "Security"
 }
 
-func (s *SecurityMiddleware) Validate(schema *ast.Schema) error {
+func (s *SecurityMiddleware) Validate(schema graphql.ExecutableSchema) error {
 	return nil
 }
 
-// Request validation and security checks
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+	graphql.ResponseInterceptor
+} = &SecurityMiddleware{}
+
+// Request validation and security checks. They run once per operation; a subscription's
+// response handler is called for every event it sends.
 func (s *SecurityMiddleware) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	// Get operation context
+	oc := graphql.GetOperationContext(ctx)
+	if oc == nil || oc.Operation == nil {
+		return next(ctx)
+	}
+	
+	// 1. Query Depth Limiting
+	if err := s.checkQueryDepth(oc.Operation, oc.Doc.Fragments); err != nil {
+		return graphql.OneShot(operationErrorResponse(ctx, err))
+	}
+	
+	// 2. Query Complexity Analysis; past the caller's role budget the query still runs,
+	// with fewer list rows
+	complexity, err := s.checkQueryComplexity(oc.Operation, oc.Doc.Fragments, oc.Variables)
+	if err != nil {
+		return graphql.OneShot(operationErrorResponse(ctx, err))
+	}
+	reportOperationComplexity(ctx, complexity)
+	rowBudget := s.queryBudgets.rowBudgetFor(fieldCallerFromContext(ctx), complexity)
+	ctx = WithRowBudget(ctx, rowBudget)
+	
+	// 3. Rate Limiting; every response reports the caller's quota
+	rateLimit, err := s.checkRateLimit(ctx, oc)
+	recordRateLimit(ctx, rateLimit)
+	if err != nil {
+		resp := operationErrorResponse(ctx, err)
+		annotateRateLimit(resp, rateLimit)
+		return graphql.OneShot(resp)
+	}
+	
+	// 4. Input Validation
+	if err := s.validateInputs(oc.Variables); err != nil {
+		return graphql.OneShot(operationErrorResponse(ctx, err))
+	}
+	
+	// 5. Log security event
+	s.logSecurityEvent(ctx, oc, "operation_started")
+	
+	handler := next(ctx)
 	return func(ctx context.Context) *graphql.Response {
-		// Get operation context
-		oc := graphql.GetOperationContext(ctx)
-		
-		// 1. Query Depth Limiting
-		if err := s.checkQueryDepth(oc.Operation); err != nil {
-			return graphql.ErrorResponse(ctx, err.Error())
-		}
-		
-		// 2. Query Complexity Analysis
-		if err := s.checkQueryComplexity(oc.Operation); err != nil {
-			return graphql.ErrorResponse(ctx, err.Error())
-		}
+		resp := handler(ctx)
 		
-		// 3. Rate Limiting
-		if err := s.checkRateLimit(ctx, oc); err != nil {
-			return graphql.ErrorResponse(ctx, err.Error())
-		}
-		
-		// 4. Input Validation
-		if err := s.validateInputs(oc.Variables); err != nil {
-			return graphql.ErrorResponse(ctx, err.Error())
+		// 6. A successful login clears the attempt counter of the account signed in to; the
+		// IP's counter stays, or signing in to one's own account would reset it between guesses
+		if resp != nil && len(resp.Errors) == 0 {
+			if email := loginEmail(oc); email != "" {
+				s.resetAccountRateLimit(ctx, email)
+			}
 		}
 		
-		// 5. Log security event
-		s.logSecurityEvent(ctx, oc, "operation_started")
+		// 7. Flag list results cut short by the row budget
+		rowBudget.annotate(resp)
+		annotateRateLimit(resp, rateLimit)
 		
-		// Call next handler and return its response
-		handler := next(ctx)
-		return handler(ctx)
+		return resp
+	}
+}
+
+// operationErrorResponse rejects an operation, keeping the code extension of a coded error
+func operationErrorResponse(ctx context.Context, err error) *graphql.Response {
+	var gqlErr *gqlerror.Error
+	if !errors.As(err, &gqlErr) {
+		return graphql.ErrorResponse(ctx, "%s", err.Error())
+	}
+	if gqlErr.Path == nil {
+		gqlErr.Path = graphql.GetPath(ctx)
 	}
+	return &graphql.Response{Errors: gqlerror.List{gqlErr}}
 }
 
 // Query depth checking
-func (s *SecurityMiddleware) checkQueryDepth(operation *ast.OperationDefinition) error {
-	depth := s.calculateDepth(operation.SelectionSet, 0)
+func (s *SecurityMiddleware) checkQueryDepth(operation *ast.OperationDefinition, fragments ast.FragmentDefinitionList) error {
+	spreads := 0
+	depth := s.calculateDepth(operation.SelectionSet, 0, fragments, map[string]bool{}, &spreads)
+	if spreads > MaxFragmentSpreads {
+		return errcode.Validation("query expands more than %d fragment spreads", MaxFragmentSpreads)
+	}
 	if depth > MaxQueryDepth {
-		return fmt.Errorf("query depth %d exceeds maximum allowed depth %d", depth, MaxQueryDepth)
+		return errcode.Validation("query depth %d exceeds maximum allowed depth %d", depth, MaxQueryDepth)
 	}
 	return nil
 }
 
-func (s *SecurityMiddleware) calculateDepth(selectionSet ast.SelectionSet, currentDepth int) int {
+// calculateDepth walks the selection set, resolving named fragments in place. visiting holds the
+// fragments on the current path so self-referential fragments can't recurse forever; spreads
+// counts every expansion, so fragments spreading each other many times over can't fan out
+// into an exponential walk.
+func (s *SecurityMiddleware) calculateDepth(selectionSet ast.SelectionSet, currentDepth int, fragments ast.FragmentDefinitionList, visiting map[string]bool, spreads *int) int {
 	if currentDepth > MaxQueryDepth {
 		return currentDepth
 	}
@@ -103,35 +295,58 @@ func (s *SecurityMiddleware) calculateDepth(selectionSet ast.SelectionSet, curre
 		switch sel := selection.(type) {
 		case *ast.Field:
 			if sel.SelectionSet != nil {
-				depth := s.calculateDepth(sel.SelectionSet, currentDepth+1)
+				depth := s.calculateDepth(sel.SelectionSet, currentDepth+1, fragments, visiting, spreads)
 				if depth > maxDepth {
 					maxDepth = depth
 				}
 			}
 		case *ast.InlineFragment:
-			depth := s.calculateDepth(sel.SelectionSet, currentDepth)
+			depth := s.calculateDepth(sel.SelectionSet, currentDepth, fragments, visiting, spreads)
 			if depth > maxDepth {
 				maxDepth = depth
 			}
 		case *ast.FragmentSpread:
-			// For fragment spreads, we would need to resolve the fragment
-			// This is a simplified implementation
-			maxDepth = currentDepth + 1
+			definition := resolveFragment(sel, fragments)
+			if definition == nil || visiting[sel.Name] {
+				continue
+			}
+			*spreads++
+			if *spreads > MaxFragmentSpreads {
+				return maxDepth
+			}
+			visiting[sel.Name] = true
+			depth := s.calculateDepth(definition.SelectionSet, currentDepth, fragments, visiting, spreads)
+			delete(visiting, sel.Name)
+			if depth > maxDepth {
+				maxDepth = depth
+			}
 		}
 	}
 	return maxDepth
 }
 
-// Query complexity analysis
-func (s *SecurityMiddleware) checkQueryComplexity(operation *ast.OperationDefinition) error {
-	complexity := s.calculateComplexity(operation.SelectionSet)
+// Query complexity analysis; MaxQueryComplexity is the hard cap whatever the caller's role
+func (s *SecurityMiddleware) checkQueryComplexity(operation *ast.OperationDefinition, fragments ast.FragmentDefinitionList, variables map[string]interface{}) (int, error) {
+	complexity := calculateComplexity(operation.SelectionSet, fragments, variables, map[string]bool{})
 	if complexity > MaxQueryComplexity {
-		return fmt.Errorf("query complexity %d exceeds maximum allowed complexity %d", complexity, MaxQueryComplexity)
+		return complexity, errcode.Validation("query complexity %d exceeds maximum allowed complexity %d", complexity, MaxQueryComplexity)
 	}
-	return nil
+	return complexity, nil
 }
 
-func (s *SecurityMiddleware) calculateComplexity(selectionSet ast.SelectionSet) int {
+// OperationComplexity returns the complexity of an operation, as the query complexity check
+// computes it
+func OperationComplexity(oc *graphql.OperationContext) int {
+	if oc == nil || oc.Operation == nil {
+		return 0
+	}
+	return calculateComplexity(oc.Operation.SelectionSet, oc.Doc.Fragments, oc.Variables, map[string]bool{})
+}
+
+// calculateComplexity sums field costs taken from the schema. Each field costs its @complexity
+// value (list fields default to ListFieldComplexity) plus its children multiplied by the
+// field's pagination arguments, so a larger page size scales the cost of what it returns.
+func calculateComplexity(selectionSet ast.SelectionSet, fragments ast.FragmentDefinitionList, variables map[string]interface{}, visiting map[string]bool) int {
 	complexity := 0
 	nodeCount := 0
 	
@@ -143,76 +358,153 @@ func (s *SecurityMiddleware) calculateComplexity(selectionSet ast.SelectionSet)
 		
 		switch sel := selection.(type) {
 		case *ast.Field:
-			// Base complexity for field
-			fieldComplexity := 1
-			
-			// Increase complexity for list fields
-			if s.isListField(sel.Name) {
-				fieldComplexity *= 10
-			}
-			
-			// Increase complexity for expensive operations
-			if s.isExpensiveField(sel.Name) {
-				fieldComplexity *= 5
-			}
-			
-			complexity += fieldComplexity
+			cost, multipliers := fieldCost(sel.Definition)
+			complexity += cost
 			
-			// Add complexity for nested fields
+			// Add complexity for nested fields, scaled by the requested page size
 			if sel.SelectionSet != nil {
-				complexity += s.calculateComplexity(sel.SelectionSet)
+				childComplexity := calculateComplexity(sel.SelectionSet, fragments, variables, visiting)
+				complexity += childComplexity * paginationMultiplier(sel, multipliers, variables)
 			}
 			
 		case *ast.InlineFragment:
-			complexity += s.calculateComplexity(sel.SelectionSet)
+			complexity += calculateComplexity(sel.SelectionSet, fragments, variables, visiting)
 		case *ast.FragmentSpread:
-			complexity += 5 // Base complexity for fragments
+			definition := resolveFragment(sel, fragments)
+			if definition == nil || visiting[sel.Name] {
+				continue
+			}
+			visiting[sel.Name] = true
+			complexity += calculateComplexity(definition.SelectionSet, fragments, variables, visiting)
+			delete(visiting, sel.Name)
+		}
+		
+		if complexity > MaxQueryComplexity {
+			return complexity
 		}
 	}
 	
 	return complexity
 }
 
-func (s *SecurityMiddleware) isListField(fieldName string) bool {
-	listFields := []string{
-		"activities", "users", "participations", "faculties", 
-		"departments", "systemMetrics", "facultyMetrics",
+// resolveFragment returns the definition a fragment spread refers to
+func resolveFragment(spread *ast.FragmentSpread, fragments ast.FragmentDefinitionList) *ast.FragmentDefinition {
+	if spread.Definition != nil {
+		return spread.Definition
+	}
+	return fragments.ForName(spread.Name)
+}
+
+// fieldCost reads the field's @complexity directive. Fields without one cost 1,
+// or ListFieldComplexity when they return a list.
+func fieldCost(definition *ast.FieldDefinition) (int, []string) {
+	if definition == nil {
+		return 1, DefaultComplexityMultipliers
 	}
 	
-	for _, field := range listFields {
-		if field == fieldName {
-			return true
+	cost := 1
+	if definition.Type != nil && definition.Type.Elem != nil {
+		cost = ListFieldComplexity
+	}
+	multipliers := DefaultComplexityMultipliers
+	
+	directive := definition.Directives.ForName(ComplexityDirective)
+	if directive == nil {
+		return cost, multipliers
+	}
+	
+	if arg := directive.Arguments.ForName("value"); arg != nil {
+		if value, err := arg.Value.Value(nil); err == nil {
+			if n, ok := toInt(value); ok {
+				cost = n
+			}
 		}
 	}
-	return false
+	if arg := directive.Arguments.ForName("multipliers"); arg != nil {
+		multipliers = nil
+		for _, child := range arg.Value.Children {
+			multipliers = append(multipliers, child.Value.Raw)
+		}
+	}
+	
+	return cost, multipliers
 }
 
-func (s *SecurityMiddleware) isExpensiveField(fieldName string) bool {
-	expensiveFields := []string{
-		"systemMetrics", "facultyMetrics", "activityAnalytics",
-		"participationStats", "subscriptionAnalytics",
+// paginationMultiplier returns the largest page size argument of the field. A field taking a
+// page size but sent without one returns as many items as the resolver likes, so it counts as
+// its argument's default or DefaultPageSize items. Fields without page size arguments count once.
+func paginationMultiplier(field *ast.Field, multipliers []string, variables map[string]interface{}) int {
+	if len(multipliers) == 0 || field.Definition == nil {
+		return 1
 	}
 	
-	for _, field := range expensiveFields {
-		if field == fieldName {
-			return true
+	args := field.ArgumentMap(variables)
+	multiplier, paginated, sized := 1, false, false
+	for _, name := range multipliers {
+		if field.Definition.Arguments.ForName(name) == nil {
+			continue
+		}
+		paginated = true
+		if n, ok := toInt(args[name]); ok {
+			sized = true
+			if n > multiplier {
+				multiplier = n
+			}
 		}
 	}
-	return false
+	if paginated && !sized {
+		return DefaultPageSize
+	}
+	return multiplier
 }
 
-// Rate limiting per user/faculty
-func (s *SecurityMiddleware) checkRateLimit(ctx context.Context, oc *graphql.OperationContext) error {
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
+
+// Rate limiting per user/faculty. The limiter's result is returned with or without an error.
+func (s *SecurityMiddleware) checkRateLimit(ctx context.Context, oc *graphql.OperationContext) (*ratelimit.Result, error) {
+	// Credential mutations are limited per IP regardless of authentication
+	if authFields := authRootFields(oc); len(authFields) > 0 {
+		// Aliases could otherwise try many passwords for a single hit
+		if len(authFields) > 1 {
+			return nil, errcode.Validation("only one of login, register, requestPasswordReset and resetPassword may be sent per operation")
+		}
+		result, err := s.checkAuthRateLimit(ctx)
+		if err != nil || result == nil {
+			return result, err
+		}
+		if email := loginEmail(oc); email != "" {
+			return s.checkAccountRateLimit(ctx, email)
+		}
+		return result, nil
+	}
+	
 	userID := getUserID(ctx)
 	if userID == "" {
-		return fmt.Errorf("authentication required")
+		// Unauthenticated operations fall back to IP-based limiting
+		key := fmt.Sprintf("%s%s", IPRateLimitPrefix, clientIPOrUnknown(ctx))
+		return s.checkRedisRateLimit(ctx, key, s.rateLimits.AnonymousLimit, s.rateLimits.Window)
 	}
 	
 	// Determine rate limit based on user role
-	limit := DefaultRateLimit
+	limit := s.rateLimits.DefaultLimit
 	userRole := getUserRole(ctx)
-	if userRole == "SUPER_ADMIN" || userRole == "FACULTY_ADMIN" {
-		limit = AdminRateLimit
+	if userRole == models.UserRoleSuperAdmin || userRole == models.UserRoleFacultyAdmin {
+		limit = s.rateLimits.AdminLimit
 	}
 	
 	// Special rate limiting for QR scan operations
@@ -222,34 +514,180 @@ func (s *SecurityMiddleware) checkRateLimit(ctx context.Context, oc *graphql.Ope
 	
 	// General rate limiting
 	key := fmt.Sprintf("%s%s", RateLimitPrefix, userID)
-	return s.checkRedisRateLimit(ctx, key, limit, time.Minute)
+	return s.checkRedisRateLimit(ctx, key, limit, s.rateLimits.Window)
 }
 
-func (s *SecurityMiddleware) checkQRScanRateLimit(ctx context.Context, userID string) error {
-	key := fmt.Sprintf("%s%s", QRScanLimitPrefix, userID)
-	return s.checkRedisRateLimit(ctx, key, QRScanRateLimit, time.Minute)
+// checkAuthRateLimit applies the stricter per-IP limit to login/register style mutations
+func (s *SecurityMiddleware) checkAuthRateLimit(ctx context.Context) (*ratelimit.Result, error) {
+	clientIP := clientIPOrUnknown(ctx)
+	key := fmt.Sprintf("%s%s", AuthRateLimitPrefix, clientIP)
+	
+	result, err := s.rateLimiter.Allow(ctx, key, s.rateLimits.AuthLimit, s.rateLimits.Window)
+	if err != nil {
+		// An unavailable Redis doesn't take the API down with it
+		log.Printf("Auth rate limit check failed, allowing operation: %v", err)
+		return nil, nil
+	}
+	
+	if !result.Allowed {
+		s.logSecurityEvent(ctx, nil, fmt.Sprintf("auth_rate_limit_exceeded:%d", result.Count))
+		
+		// Report once per window rather than on every blocked attempt
+		if result.Count == s.rateLimits.AuthLimit+1 && s.auditLogger != nil {
+			go s.auditLogger.LogSecurityEvent(context.Background(), &audit.SecurityEvent{
+				EventType: audit.SecurityEventBruteForce,
+				IPAddress: clientIP,
+				UserAgent: getUserAgent(ctx),
+				Details: map[string]interface{}{
+					"attempts":    result.Count,
+					"limit":       s.rateLimits.AuthLimit,
+					"time_window": s.rateLimits.Window.String(),
+				},
+				RiskLevel: audit.RiskLevelHigh,
+				Blocked:   true,
+			})
+		}
+		return result, rateLimitedError(result, "too many authentication attempts, please try again later")
+	}
+	
+	return result, nil
 }
 
-func (s *SecurityMiddleware) checkRedisRateLimit(ctx context.Context, key string, limit int, window time.Duration) error {
-	// Use Redis for distributed rate limiting
-	pipe := s.redisClient.Pipeline()
+// checkAccountRateLimit limits login attempts on one account, however many addresses they
+// come from
+func (s *SecurityMiddleware) checkAccountRateLimit(ctx context.Context, email string) (*ratelimit.Result, error) {
+	result, err := s.rateLimiter.Allow(ctx, accountRateLimitKey(email), s.rateLimits.AuthLimit, s.rateLimits.Window)
+	if err != nil {
+		log.Printf("Account rate limit check failed, allowing operation: %v", err)
+		return nil, nil
+	}
 	
-	// Increment counter
-	incr := pipe.Incr(ctx, key)
-	pipe.Expire(ctx, key, window)
+	if !result.Allowed {
+		s.logSecurityEvent(ctx, nil, fmt.Sprintf("account_rate_limit_exceeded:%d", result.Count))
+		return result, rateLimitedError(result, "too many authentication attempts, please try again later")
+	}
 	
-	_, err := pipe.Exec(ctx)
+	return result, nil
+}
+
+// resetAccountRateLimit clears the attempt counter of an account so its owner isn't locked out
+// by their own typos once they get in
+func (s *SecurityMiddleware) resetAccountRateLimit(ctx context.Context, email string) {
+	if err := s.rateLimiter.Reset(ctx, accountRateLimitKey(email)); err != nil {
+		log.Printf("Failed to reset account rate limit: %v", err)
+	}
+}
+
+// accountRateLimitKey keys login attempts by a hash of the email, keeping addresses out of Redis
+func accountRateLimitKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return LoginAccountPrefix + hex.EncodeToString(sum[:])
+}
+
+// authRootFields returns the credential mutation fields of the operation, aliases included
+func authRootFields(oc *graphql.OperationContext) []*ast.Field {
+	if oc == nil || oc.Operation == nil || oc.Operation.Operation != ast.Mutation {
+		return nil
+	}
+	var fields []*ast.Field
+	for _, field := range rootFields(oc) {
+		if authMutationFields[field.Name] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// loginEmail returns the email of the operation's login field, if it has one
+func loginEmail(oc *graphql.OperationContext) string {
+	for _, field := range authRootFields(oc) {
+		if field.Name != "login" {
+			continue
+		}
+		input, _ := field.ArgumentMap(oc.Variables)["input"].(map[string]interface{})
+		email, _ := input["email"].(string)
+		return email
+	}
+	return ""
+}
+
+// rootFields returns the operation's top-level fields, including those selected through
+// fragments
+func rootFields(oc *graphql.OperationContext) []*ast.Field {
+	if oc == nil || oc.Operation == nil {
+		return nil
+	}
+	var fragments ast.FragmentDefinitionList
+	if oc.Doc != nil {
+		fragments = oc.Doc.Fragments
+	}
+	return collectFields(oc.Operation.SelectionSet, fragments, map[string]bool{})
+}
+
+func collectFields(selectionSet ast.SelectionSet, fragments ast.FragmentDefinitionList, visiting map[string]bool) []*ast.Field {
+	var fields []*ast.Field
+	for _, selection := range selectionSet {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			fields = append(fields, sel)
+		case *ast.InlineFragment:
+			fields = append(fields, collectFields(sel.SelectionSet, fragments, visiting)...)
+		case *ast.FragmentSpread:
+			definition := resolveFragment(sel, fragments)
+			if definition == nil || visiting[sel.Name] {
+				continue
+			}
+			visiting[sel.Name] = true
+			fields = append(fields, collectFields(definition.SelectionSet, fragments, visiting)...)
+			delete(visiting, sel.Name)
+		}
+	}
+	return fields
+}
+
+func (s *SecurityMiddleware) checkQRScanRateLimit(ctx context.Context, userID string) (*ratelimit.Result, error) {
+	key := fmt.Sprintf("%s%s", QRScanLimitPrefix, userID)
+	return s.checkRedisRateLimit(ctx, key, s.rateLimits.QRScanLimit, s.rateLimits.Window)
+}
+
+// checkRedisRateLimit enforces limit over a rolling window shared by all instances
+func (s *SecurityMiddleware) checkRedisRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*ratelimit.Result, error) {
+	result, err := s.rateLimiter.Allow(ctx, key, limit, window)
 	if err != nil {
-		return fmt.Errorf("rate limit check failed: %v", err)
+		log.Printf("Rate limit check failed, allowing operation: %v", err)
+		return nil, nil
 	}
 	
-	count := incr.Val()
-	if count > int64(limit) {
-		s.logSecurityEvent(ctx, nil, fmt.Sprintf("rate_limit_exceeded:%d", count))
-		return fmt.Errorf("rate limit exceeded: %d requests per %v", limit, window)
+	if !result.Allowed {
+		s.logSecurityEvent(ctx, nil, fmt.Sprintf("rate_limit_exceeded:%d", result.Count))
+		return result, rateLimitedError(result, "rate limit exceeded: %d requests per %v", limit, window)
 	}
 	
-	return nil
+	return result, nil
+}
+
+// rateLimitedError rejects an operation, telling the client when to retry
+func rateLimitedError(result *ratelimit.Result, format string, args ...interface{}) error {
+	err := errcode.RateLimited(format, args...)
+	err.Extensions["retryAfter"] = result.RetryAfterSeconds()
+	return err
+}
+
+// annotateRateLimit reports the caller's quota in the rateLimit response extension, carrying
+// the same values as the X-RateLimit-* and Retry-After headers, so websocket clients get it too
+func annotateRateLimit(resp *graphql.Response, result *ratelimit.Result) {
+	if resp == nil || result == nil {
+		return
+	}
+	if resp.Extensions == nil {
+		resp.Extensions = make(map[string]interface{})
+	}
+	resp.Extensions["rateLimit"] = map[string]interface{}{
+		"limit":      result.Limit,
+		"remaining":  result.Remaining,
+		"reset":      result.Reset.Unix(),
+		"retryAfter": result.RetryAfterSeconds(),
+	}
 }
 
 func (s *SecurityMiddleware) isQRScanOperation(operationName string) bool {
@@ -266,7 +704,7 @@ func (s *SecurityMiddleware) isQRScanOperation(operationName string) bool {
 func (s *SecurityMiddleware) validateInputs(variables map[string]interface{}) error {
 	for key, value := range variables {
 		if err := s.validateInput(key, value); err != nil {
-			return fmt.Errorf("invalid input for %s: %v", key, err)
+			return errcode.Validation("invalid input for %s: %v", key, err)
 		}
 	}
 	return nil
@@ -283,7 +721,7 @@ func (s *SecurityMiddleware) validateInput(key string, value interface{}) error
 	case "activityID", "userID", "facultyID":
 		return s.validateID(value)
 	default:
-		return s.validateGenericInput(value)
+		return s.validateGenericInput(key, value)
 	}
 }
 
@@ -298,8 +736,7 @@ func (s *SecurityMiddleware) validateEmail(value interface{}) error {
 		return fmt.Errorf("invalid email format")
 	}
 	
-	// Check for malicious patterns
-	if s.containsMaliciousPatterns(email) {
+	if containsControlChars(email) {
 		return fmt.Errorf("email contains invalid characters")
 	}
 	
@@ -333,48 +770,72 @@ func (s *SecurityMiddleware) validateQRData(value interface{}) error {
 		return fmt.Errorf("qrData must be a string")
 	}
 	
-	// QR data should be base64 or JSON string
-	if len(qrData) == 0 || len(qrData) > 2000 {
-		return fmt.Errorf("qrData length must be between 1 and 2000 characters")
+	// QR data is binary, base64 or JSON encoded; see security.DecodeQRData
+	if len(qrData) == 0 || len(qrData) > s.maxQRPayload {
+		return fmt.Errorf("qrData length must be between 1 and %d characters", s.maxQRPayload)
 	}
 	
-	// Check for malicious patterns
-	if s.containsMaliciousPatterns(qrData) {
-		return fmt.Errorf("qrData contains invalid patterns")
+	if containsControlChars(qrData) {
+		return fmt.Errorf("qrData contains invalid characters")
 	}
 	
 	return nil
 }
 
+// validateID accepts the numeric IDs of the database, sent as strings or numbers; a null
+// leaves an optional ID argument unset
 func (s *SecurityMiddleware) validateID(value interface{}) error {
-	id, ok := value.(string)
-	if !ok {
+	var id string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		id = v
+	case json.Number:
+		id = v.String()
+	default:
 		return fmt.Errorf("ID must be a string")
 	}
 	
-	// UUID format validation (adjust based on your ID format)
-	if len(id) < 10 || len(id) > 50 {
-		return fmt.Errorf("ID length must be between 10 and 50 characters")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("ID must be a whole number")
 	}
 	
 	return nil
 }
 
-func (s *SecurityMiddleware) validateGenericInput(value interface{}) error {
+// validateGenericInput applies per-field rules to free-form input. Queries are
+// parameterized, so text is not scanned for SQL keywords; it is only checked for
+// size, control characters, field allowlists and script injection in HTML fields.
+func (s *SecurityMiddleware) validateGenericInput(key string, value interface{}) error {
 	switch v := value.(type) {
 	case string:
-		if len(v) > 10000 { // Prevent extremely large strings
+		maxLength := MaxStringInputLength
+		if limit, ok := fieldLengthLimits[key]; ok {
+			maxLength = limit
+		}
+		if utf8.RuneCountInString(v) > maxLength {
 			return fmt.Errorf("string input too large")
 		}
-		if s.containsMaliciousPatterns(v) {
-			return fmt.Errorf("input contains malicious patterns")
+		if containsControlChars(v) {
+			return fmt.Errorf("input contains invalid control characters")
+		}
+		if allowed, ok := fieldAllowlists[key]; ok {
+			for _, char := range v {
+				if !allowed(char) {
+					return fmt.Errorf("input contains invalid characters")
+				}
+			}
+		}
+		if htmlRenderedFields[key] && containsScriptPatterns(v) {
+			return fmt.Errorf("input contains script content")
 		}
 	case []interface{}:
 		if len(v) > 1000 { // Prevent extremely large arrays
 			return fmt.Errorf("array input too large")
 		}
 		for _, item := range v {
-			if err := s.validateGenericInput(item); err != nil {
+			if err := s.validateGenericInput(key, item); err != nil {
 				return err
 			}
 		}
@@ -382,35 +843,46 @@ func (s *SecurityMiddleware) validateGenericInput(value interface{}) error {
 		if len(v) > 100 { // Prevent extremely large objects
 			return fmt.Errorf("object input too large")
 		}
-		for key, val := range v {
-			if err := s.validateGenericInput(val); err != nil {
-				return err
+		for field, val := range v {
+			if len(field) > 100 || containsControlChars(field) {
+				return fmt.Errorf("object key is invalid")
 			}
-			if s.containsMaliciousPatterns(key) {
-				return fmt.Errorf("object key contains malicious patterns")
+			if err := s.validateGenericInput(field, val); err != nil {
+				return fmt.Errorf("%s: %v", field, err)
 			}
 		}
 	}
 	return nil
 }
 
-func (s *SecurityMiddleware) containsMaliciousPatterns(input string) bool {
-	maliciousPatterns := []string{
-		"<script", "javascript:", "onload=", "onerror=",
-		"SELECT ", "INSERT ", "UPDATE ", "DELETE ", "DROP ",
-		"UNION ", "OR 1=1", "' OR '1'='1", "admin'--",
-		"../", "..\\", "/etc/passwd", "cmd.exe",
+// containsControlChars reports null bytes and control characters other than common whitespace
+func containsControlChars(input string) bool {
+	for _, char := range input {
+		if char == '\n' || char == '\r' || char == '\t' {
+			continue
+		}
+		if unicode.IsControl(char) {
+			return true
+		}
 	}
-	
+	return false
+}
+
+// containsScriptPatterns detects script injection in fields that are rendered as HTML
+func containsScriptPatterns(input string) bool {
 	lowerInput := strings.ToLower(input)
-	for _, pattern := range maliciousPatterns {
-		if strings.Contains(lowerInput, strings.ToLower(pattern)) {
+	for _, pattern := range scriptPatterns {
+		if strings.Contains(lowerInput, pattern) {
 			return true
 		}
 	}
 	return false
 }
 
+func isCodeChar(char rune) bool {
+	return (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '-' || char == '_'
+}
+
 // Security event logging
 func (s *SecurityMiddleware) logSecurityEvent(ctx context.Context, oc *graphql.OperationContext, eventType string) {
 	userID := getUserID(ctx)
@@ -430,7 +902,7 @@ func (s *SecurityMiddleware) logSecurityEvent(ctx context.Context, oc *graphql.O
 	}
 	
 	// Store in Redis for real-time monitoring
-	key := fmt.Sprintf("%s%s:%d", SecurityEventPrefix, userID, timestamp)
+	key := rediskeys.Keyf("%s%s:%d", SecurityEventPrefix, userID, timestamp)
 	s.redisClient.HMSet(ctx, key, event)
 	s.redisClient.Expire(ctx, key, 24*time.Hour) // Keep for 24 hours
 }
@@ -440,23 +912,17 @@ func (s *SecurityMiddleware) hashQuery(query string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// Helper functions to extract context information
+// Helper functions to extract context information; the user comes from the auth extension,
+// which must be registered before this middleware
 func getUserID(ctx context.Context) string {
-	if userID := ctx.Value("user_id"); userID != nil {
-		if id, ok := userID.(string); ok {
-			return id
-		}
+	if caller := fieldCallerFromContext(ctx); caller.Authenticated {
+		return strconv.FormatUint(uint64(caller.UserID), 10)
 	}
 	return ""
 }
 
-func getUserRole(ctx context.Context) string {
-	if role := ctx.Value("user_role"); role != nil {
-		if r, ok := role.(string); ok {
-			return r
-		}
-	}
-	return ""
+func getUserRole(ctx context.Context) models.UserRole {
+	return fieldCallerFromContext(ctx).Role
 }
 
 func getClientIP(ctx context.Context) string {
@@ -468,6 +934,13 @@ func getClientIP(ctx context.Context) string {
 	return ""
 }
 
+func clientIPOrUnknown(ctx context.Context) string {
+	if clientIP := getClientIP(ctx); clientIP != "" {
+		return clientIP
+	}
+	return "unknown"
+}
+
 func getUserAgent(ctx context.Context) string {
 	if ua := ctx.Value("user_agent"); ua != nil {
 		if userAgent, ok := ua.(string); ok {
@@ -482,36 +955,19 @@ func (s *SecurityMiddleware) InterceptField(ctx context.Context, next graphql.Re
 	fc := graphql.GetFieldContext(ctx)
 	
 	// Check field-level permissions
-	if err := s.checkFieldPermission(ctx, fc.Field.Name); err != nil {
-		s.logSecurityEvent(ctx, nil, fmt.Sprintf("field_access_denied:%s", fc.Field.Name))
+	if err := s.checkFieldPermission(ctx, fc); err != nil {
+		s.logSecurityEvent(ctx, nil, fmt.Sprintf("field_access_denied:%s.%s", fc.Object, fc.Field.Name))
 		return nil, err
 	}
 	
 	return next(ctx)
 }
 
-func (s *SecurityMiddleware) checkFieldPermission(ctx context.Context, fieldName string) error {
-	userRole := getUserRole(ctx)
-	
-	// Define field-level permissions
-	restrictedFields := map[string][]string{
-		"systemMetrics":      {"SUPER_ADMIN"},
-		"allUsers":          {"SUPER_ADMIN"},
-		"facultyMetrics":    {"SUPER_ADMIN", "FACULTY_ADMIN"},
-		"subscriptions":     {"SUPER_ADMIN", "FACULTY_ADMIN"},
-		"qrSecret":          {"STUDENT"}, // Students can only access their own QR secret
-		"adminActions":      {"SUPER_ADMIN", "FACULTY_ADMIN", "REGULAR_ADMIN"},
-	}
-	
-	if allowedRoles, exists := restrictedFields[fieldName]; exists {
-		for _, role := range allowedRoles {
-			if role == userRole {
-				return nil
-			}
-		}
-		return fmt.Errorf("insufficient permissions to access field: %s", fieldName)
+func (s *SecurityMiddleware) checkFieldPermission(ctx context.Context, fc *graphql.FieldContext) error {
+	caller := fieldCallerFromContext(ctx)
+	if !s.fieldPermissions.Allows(caller, fc.Object, fc.Field.Name, parentObject(fc)) {
+		return errcode.Forbidden("insufficient permissions to access field: %s", fc.Field.Name)
 	}
-	
 	return nil
 }
 
@@ -519,13 +975,18 @@ func (s *SecurityMiddleware) checkFieldPermission(ctx context.Context, fieldName
 func (s *SecurityMiddleware) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
 	resp := next(ctx)
 	
-	// Filter sensitive data based on user permissions
-	if resp.Data != nil {
+	oc := graphql.GetOperationContext(ctx)
+	
+	// Filter sensitive data based on user permissions. Most queries select nothing the caller is
+	// denied, and those responses pass through untouched, keeping their field order.
+	caller := fieldCallerFromContext(ctx)
+	if resp != nil && resp.Data != nil && oc != nil && oc.Operation != nil &&
+		s.selectsDeniedField(caller, oc.Operation.SelectionSet) {
 		// Unmarshal the JSON data first
 		var dataInterface interface{}
 		if err := json.Unmarshal(resp.Data, &dataInterface); err == nil {
 			// Apply filtering
-			filteredData := s.filterResponseData(ctx, dataInterface)
+			filteredData := s.filterResponseData(caller, oc.Operation.SelectionSet, dataInterface)
 			// Convert filtered data back to json.RawMessage
 			if filteredBytes, err := json.Marshal(filteredData); err == nil {
 				resp.Data = filteredBytes
@@ -537,76 +998,78 @@ func (s *SecurityMiddleware) InterceptResponse(ctx context.Context, next graphql
 	return resp
 }
 
-func (s *SecurityMiddleware) filterResponseData(ctx context.Context, data interface{}) interface{} {
-	userRole := getUserRole(ctx)
-	userID := getUserID(ctx)
-	
-	switch d := data.(type) {
-	case map[string]interface{}:
-		filtered := make(map[string]interface{})
-		for key, value := range d {
-			if s.canAccessField(userRole, userID, key) {
-				filtered[key] = s.filterResponseData(ctx, value)
+// selectsDeniedField reports whether the selections include a field the caller may never read
+func (s *SecurityMiddleware) selectsDeniedField(caller FieldCaller, selections ast.SelectionSet) bool {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if s.fieldPermissions.Check(caller, fieldObject(sel), sel.Name) == FieldDenied ||
+				s.selectsDeniedField(caller, sel.SelectionSet) {
+				return true
+			}
+		case *ast.InlineFragment:
+			if s.selectsDeniedField(caller, sel.SelectionSet) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if sel.Definition != nil && s.selectsDeniedField(caller, sel.Definition.SelectionSet) {
+				return true
 			}
 		}
-		return filtered
+	}
+	return false
+}
+
+// filterResponseData walks the response alongside the query's selections so each key is
+// checked against the same registry as InterceptField. Ownership rules were already applied
+// while resolving, so only fields the caller can never read are blanked here.
+func (s *SecurityMiddleware) filterResponseData(caller FieldCaller, selections ast.SelectionSet, data interface{}) interface{} {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		s.filterSelections(caller, selections, d)
+		return d
 	case []interface{}:
-		filtered := make([]interface{}, 0, len(d))
-		for _, item := range d {
-			filteredItem := s.filterResponseData(ctx, item)
-			if filteredItem != nil {
-				filtered = append(filtered, filteredItem)
-			}
+		for i, item := range d {
+			d[i] = s.filterResponseData(caller, selections, item)
 		}
-		return filtered
+		return d
 	default:
 		return data
 	}
 }
 
-func (s *SecurityMiddleware) canAccessField(userRole, userID, fieldName string) bool {
-	// Define field access rules
-	sensitiveFields := []string{"password", "secret", "token", "privateKey"}
-	
-	// Remove sensitive fields for all users
-	for _, field := range sensitiveFields {
-		if field == fieldName {
-			return false
-		}
-	}
-	
-	// Role-based field access
-	switch userRole {
-	case "SUPER_ADMIN":
-		return true // Super admin can access all fields
-	case "FACULTY_ADMIN":
-		// Faculty admin cannot access other faculties' sensitive data
-		restrictedFields := []string{"otherFacultyData", "systemSecrets"}
-		for _, field := range restrictedFields {
-			if field == fieldName {
-				return false
+func (s *SecurityMiddleware) filterSelections(caller FieldCaller, selections ast.SelectionSet, data map[string]interface{}) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			key := sel.Alias
+			if key == "" {
+				key = sel.Name
 			}
-		}
-		return true
-	case "REGULAR_ADMIN":
-		// Regular admin has limited access
-		allowedFields := []string{"assignedActivities", "scanResults", "basicUserData"}
-		for _, field := range allowedFields {
-			if field == fieldName {
-				return true
+			value, ok := data[key]
+			if !ok {
+				continue
 			}
-		}
-		return false
-	case "STUDENT":
-		// Students can only access their own data
-		personalFields := []string{"myActivities", "myQRCode", "myParticipations"}
-		for _, field := range personalFields {
-			if field == fieldName {
-				return true
+
+			if s.fieldPermissions.Check(caller, fieldObject(sel), sel.Name) == FieldDenied {
+				data[key] = nil
+				continue
+			}
+			data[key] = s.filterResponseData(caller, sel.SelectionSet, value)
+		case *ast.InlineFragment:
+			s.filterSelections(caller, sel.SelectionSet, data)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				s.filterSelections(caller, sel.Definition.SelectionSet, data)
 			}
 		}
-		return false
 	}
-	
-	return false
-}
\ No newline at end of file
+}
+
+// fieldObject names the type a selected field belongs to, or "*" when the query was not validated
+func fieldObject(field *ast.Field) string {
+	if field.ObjectDefinition != nil {
+		return field.ObjectDefinition.Name
+	}
+	return "*"
+}
@@ -0,0 +1,397 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/kruakemaths/tru-activity/backend/graph/generated"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// newSecurityTestServer serves the app's schema behind the security middleware. No resolvers
+// are set, so only operations the middleware rejects can be tested.
+func newSecurityTestServer() *handler.Server {
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0))
+	return srv
+}
+
+type testGraphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postQuery(t *testing.T, h http.Handler, query string) testGraphQLResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp testGraphQLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestSecurityMiddlewareRejectsOperationsOverTheLimits(t *testing.T) {
+	// { me { faculty { departments { faculty { ... } } } } }, one level past the limit
+	fields := []string{"me"}
+	for len(fields) <= MaxQueryDepth {
+		if len(fields)%2 == 1 {
+			fields = append(fields, "faculty")
+		} else {
+			fields = append(fields, "departments")
+		}
+	}
+	deep := "{ " + strings.Join(fields, " { id ") + " { id" + strings.Repeat(" }", len(fields)) + " }"
+
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"depth", deep, "query depth"},
+		{"complexity", "{ users(limit: 500) { id email firstName lastName } }", "query complexity"},
+	}
+
+	srv := newSecurityTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postQuery(t, srv, tt.query)
+			if len(resp.Errors) != 1 {
+				t.Fatalf("got %d errors, want 1: %+v", len(resp.Errors), resp.Errors)
+			}
+			if code := resp.Errors[0].Extensions["code"]; code != "VALIDATION" {
+				t.Errorf("code = %v, want VALIDATION", code)
+			}
+			if !strings.Contains(resp.Errors[0].Message, tt.message) {
+				t.Errorf("message = %q, want it to mention %q", resp.Errors[0].Message, tt.message)
+			}
+			if string(resp.Data) != "" && string(resp.Data) != "null" {
+				t.Errorf("data = %s, want none", resp.Data)
+			}
+		})
+	}
+}
+
+func TestSecurityMiddlewareAllowsOneAuthFieldPerOperation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"aliases", `mutation {
+			a: login(input: {email: "a@example.com", password: "guess1"}) { token }
+			b: login(input: {email: "a@example.com", password: "guess2"}) { token }
+		}`},
+		{"fragment", `mutation {
+			login(input: {email: "a@example.com", password: "guess1"}) { token }
+			...Guess
+		}
+		fragment Guess on Mutation { again: login(input: {email: "a@example.com", password: "guess2"}) { token } }`},
+		{"mixed fields", `mutation {
+			login(input: {email: "a@example.com", password: "guess1"}) { token }
+			requestPasswordReset(email: "a@example.com")
+		}`},
+	}
+
+	srv := newSecurityTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postQuery(t, srv, tt.query)
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "VALIDATION" {
+				t.Fatalf("errors = %+v, want one VALIDATION error", resp.Errors)
+			}
+		})
+	}
+}
+
+func TestLoginEmail(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"literal", `mutation { login(input: {email: "a@example.com", password: "p"}) { token } }`, nil, "a@example.com"},
+		{"variables", `mutation ($input: LoginInput!) { login(input: $input) { token } }`,
+			map[string]interface{}{"input": map[string]interface{}{"email": "b@example.com", "password": "p"}}, "b@example.com"},
+		{"inline fragment", `mutation { ... on Mutation { login(input: {email: "c@example.com", password: "p"}) { token } } }`, nil, "c@example.com"},
+		{"other auth mutation", `mutation { requestPasswordReset(email: "a@example.com") }`, nil, ""},
+		{"query", `{ me { id } }`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(schema, tt.query)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", tt.query, err)
+			}
+			oc := &graphql.OperationContext{Doc: doc, Operation: doc.Operations[0], Variables: tt.variables}
+			if got := loginEmail(oc); got != tt.want {
+				t.Errorf("loginEmail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Attempts on an account count together whatever the case of the email
+	if accountRateLimitKey(" A@Example.com") != accountRateLimitKey("a@example.com") {
+		t.Error("account rate limit keys differ by the case of the email")
+	}
+}
+
+func TestQueryDepthResolvesFragments(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Node { id: ID! child: Node }
+		type Query { node: Node }`})
+
+	// nested returns a query reaching levels fields deep through a chain of fragments
+	nested := func(levels int) string {
+		query := "{ node { ...F1 } }"
+		for i := 1; i < levels; i++ {
+			query += fmt.Sprintf(" fragment F%d on Node { child { ...F%d } }", i, i+1)
+		}
+		return query + fmt.Sprintf(" fragment F%d on Node { id }", levels)
+	}
+	// fanOut returns a query whose fragments spread the next one three times, 3^levels
+	// expansions in all
+	fanOut := func(levels int) string {
+		query := "{ node { ...F1 } }"
+		for i := 1; i < levels; i++ {
+			query += fmt.Sprintf(" fragment F%d on Node { ...F%d ...F%d ...F%d }", i, i+1, i+1, i+1)
+		}
+		return query + fmt.Sprintf(" fragment F%d on Node { id }", levels)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"nested fragments within the limit", nested(MaxQueryDepth), ""},
+		{"nested fragments past the limit", nested(MaxQueryDepth + 1), "query depth 16"},
+		{"few spreads", fanOut(3), ""},
+		{"fragment fan-out", fanOut(12), "fragment spreads"},
+	}
+
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, errs := gqlparser.LoadQuery(schema, tt.query)
+			if errs != nil {
+				t.Fatalf("parsing %q: %v", tt.query, errs)
+			}
+			err := s.checkQueryDepth(doc.Operations[0], doc.Fragments)
+			if tt.message == "" {
+				if err != nil {
+					t.Errorf("checkQueryDepth: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("checkQueryDepth() = %v, want an error mentioning %q", err, tt.message)
+			}
+		})
+	}
+
+	// Validation rejects fragment cycles before the middleware runs; the walk still stops
+	// when it meets a fragment already on its path
+	doc, err := parser.ParseQuery(&ast.Source{Input: `{ node { ...A } }
+		fragment A on Node { child { ...B } }
+		fragment B on Node { child { ...A } }`})
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	spreads := 0
+	if depth := s.calculateDepth(doc.Operations[0].SelectionSet, 0, doc.Fragments, map[string]bool{}, &spreads); depth != 3 {
+		t.Errorf("depth of a fragment cycle = %d, want 3", depth)
+	}
+}
+
+func TestComplexityScalesWithPageSize(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Item { id: ID! name: String! }
+		type Query {
+			items(first: Int): [Item!]!
+			pagedItems(limit: Int = 50): [Item!]!
+			tags: [String!]!
+			item: Item
+		}`})
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      int
+	}{
+		{"first", "{ items(first: 10) { id name } }", nil, 10 + 2*10},
+		{"1000 items", "{ items(first: 1000) { id name } }", nil, 10 + 2*1000},
+		{"variable", "query ($n: Int) { items(first: $n) { id name } }", map[string]interface{}{"n": 1000}, 10 + 2*1000},
+		// Unbounded pages cost as much as a default page, not a single item
+		{"no page size", "{ items { id name } }", nil, 10 + 2*DefaultPageSize},
+		{"null page size", "query ($n: Int) { items(first: $n) { id name } }", map[string]interface{}{"n": nil}, 10 + 2*DefaultPageSize},
+		{"argument default", "{ pagedItems { id name } }", nil, 10 + 2*50},
+		{"not paginated", "{ tags item { id name } }", nil, 10 + 1 + 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(schema, tt.query)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", tt.query, err)
+			}
+			got := calculateComplexity(doc.Operations[0].SelectionSet, doc.Fragments, tt.variables, map[string]bool{})
+			if got != tt.want {
+				t.Errorf("complexity = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// A thousand items of the app's users are past the limit
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+	appSchema := generated.NewExecutableSchema(generated.Config{}).Schema()
+	doc, err := gqlparser.LoadQuery(appSchema, "{ users(limit: 1000) { id email } }")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	if _, err := s.checkQueryComplexity(doc.Operations[0], doc.Fragments, nil); err == nil {
+		t.Error("1000 users within the complexity limit, want an error")
+	}
+}
+
+func TestValidateGenericInputCountsCharacters(t *testing.T) {
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+
+	// 50 Thai characters are 150 bytes but fit the 50-character firstName limit
+	if err := s.validateGenericInput("firstName", strings.Repeat("ก", 50)); err != nil {
+		t.Errorf("50 Thai characters rejected: %v", err)
+	}
+	if err := s.validateGenericInput("firstName", strings.Repeat("ก", 51)); err == nil {
+		t.Error("51 Thai characters accepted, want an error")
+	}
+}
+
+func TestValidateInputsAcceptsDatabaseIDs(t *testing.T) {
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		wantErr   bool
+	}{
+		{"numeric ID", map[string]interface{}{"activityID": "12"}, false},
+		{"number", map[string]interface{}{"userID": json.Number("7")}, false},
+		{"null optional ID", map[string]interface{}{"facultyID": nil}, false},
+		{"not a number", map[string]interface{}{"activityID": "12 OR 1=1"}, true},
+		{"negative", map[string]interface{}{"userID": "-1"}, true},
+		{"empty", map[string]interface{}{"facultyID": ""}, true},
+		{"object", map[string]interface{}{"facultyID": map[string]interface{}{"id": "1"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validateInputs(tt.variables)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateInputs(%v) = %v, want error %v", tt.variables, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateInputsFieldRules(t *testing.T) {
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+	input := func(field string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{"input": map[string]interface{}{field: value}}
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		wantErr   bool
+	}{
+		// Text that the old SQL keyword blocklist rejected
+		{"SQL keyword in a title", input("title", "SELECT a research topic"), false},
+		{"keyword inside a word", input("title", "Updateson"), false},
+		{"quotes and dashes", input("description", "Bring O'Reilly books -- and a pen; DROP by at noon"), false},
+		{"union in a location", input("location", "Student Union, Building 2"), false},
+		{"Thai text", input("description", "กิจกรรมอาสาพัฒนาชุมชน"), false},
+		{"line breaks", input("notes", "first line\nsecond line"), false},
+
+		{"script in an HTML field", input("description", `<script>alert(1)</script>`), true},
+		{"event handler in an HTML field", input("notes", `<img src=x onerror=alert(1)>`), true},
+		{"control character", input("title", "Orientation\x00"), true},
+		{"title too long", input("title", strings.Repeat("a", 201)), true},
+		{"invalid code character", input("code", "ENG 01"), true},
+		{"oversized list", map[string]interface{}{"tags": make([]interface{}, 1001)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validateInputs(tt.variables)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateInputs() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInterceptResponseFiltersOnlyDeniedFields(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+	s := NewSecurityMiddleware(nil, nil, RateLimitConfig{}, 0)
+	student := withTestAuth(1, models.UserRoleStudent, 10)
+
+	tests := []struct {
+		name  string
+		query string
+		data  string
+		want  string
+	}{
+		{
+			// Passed through byte for byte, keeping the resolver's field order
+			"student activity list",
+			"{ activities { title id startDate } }",
+			`{"activities":[{"title":"Orientation","id":"1","startDate":"2026-03-01T09:00:00Z"}]}`,
+			`{"activities":[{"title":"Orientation","id":"1","startDate":"2026-03-01T09:00:00Z"}]}`,
+		},
+		{
+			"super admin field",
+			"{ activities { id } subscriptions { id } }",
+			`{"activities":[{"id":"1"}],"subscriptions":[{"id":"3"}]}`,
+			`{"activities":[{"id":"1"}],"subscriptions":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(schema, tt.query)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", tt.query, err)
+			}
+			ctx := graphql.WithOperationContext(student, &graphql.OperationContext{
+				Doc:       doc,
+				Operation: doc.Operations[0],
+			})
+
+			resp := s.InterceptResponse(ctx, func(context.Context) *graphql.Response {
+				return &graphql.Response{Data: json.RawMessage(tt.data)}
+			})
+			if string(resp.Data) != tt.want {
+				t.Errorf("data = %s, want %s", resp.Data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+
+	// Redis keys
+	IdempotencyKeyPrefix = "idempotency:"
+
+	MaxIdempotencyKeyLength = 255
+	idempotencyPending      = "pending"
+	idempotencyPollInterval = 100 * time.Millisecond
+)
+
+type idempotencyRecord struct {
+	RequestHash string            `json:"request_hash"`
+	Response    *graphql.Response `json:"response"`
+}
+
+// IdempotencyMiddleware replays the stored result of a mutation when a client retries it with
+// the same Idempotency-Key header, so flaky connections don't submit the same change twice.
+// Keys are scoped per user; anonymous requests and queries are never cached.
+//
+// Mutations that create records or count against limits benefit most: joinActivity,
+// createActivity, scanQRCode, createActivityAssignment, createSubscription and
+// requestPasswordReset. Mutations that are naturally idempotent (updates by ID) are
+// unaffected either way.
+type IdempotencyMiddleware struct {
+	redisClient *redis.Client
+	ttl         time.Duration
+	pendingTTL  time.Duration
+}
+
+func NewIdempotencyMiddleware(redisClient *redis.Client, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		redisClient: redisClient,
+		ttl:         ttl,
+		pendingTTL:  30 * time.Second,
+	}
+}
+
+func (im *IdempotencyMiddleware) ExtensionName() string {
+	return "Idempotency"
+}
+
+func (im *IdempotencyMiddleware) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (im *IdempotencyMiddleware) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	oc := graphql.GetOperationContext(ctx)
+	if oc == nil || oc.Operation == nil || oc.Operation.Operation != ast.Mutation {
+		return next(ctx)
+	}
+
+	idempotencyKey := oc.Headers.Get(IdempotencyHeader)
+	if idempotencyKey == "" {
+		return next(ctx)
+	}
+	if len(idempotencyKey) > MaxIdempotencyKeyLength {
+		return graphql.ErrorResponse(ctx, "%s header is too long", IdempotencyHeader)
+	}
+
+	authCtx, err := GetAuthContext(ctx)
+	if err != nil {
+		return next(ctx)
+	}
+
+	key := rediskeys.Key(IdempotencyKeyPrefix) + strconv.FormatUint(uint64(authCtx.UserID), 10) + ":" + idempotencyKey
+	requestHash := hashIdempotentRequest(oc)
+
+	// The first request claims the key; concurrent duplicates wait for its result
+	claimed, err := im.redisClient.SetNX(ctx, key, idempotencyPending, im.pendingTTL).Result()
+	if err != nil {
+		// Don't block mutations when Redis is unavailable
+		log.Printf("Idempotency check failed for %s: %v", key, err)
+		return next(ctx)
+	}
+
+	if !claimed {
+		return im.replay(ctx, key, requestHash)
+	}
+
+	resp := next(ctx)
+
+	// Failed mutations are not stored so the client can retry them
+	if resp == nil || len(resp.Errors) > 0 {
+		im.redisClient.Del(ctx, key)
+		return resp
+	}
+
+	record, err := json.Marshal(&idempotencyRecord{RequestHash: requestHash, Response: resp})
+	if err != nil {
+		im.redisClient.Del(ctx, key)
+		return resp
+	}
+	if err := im.redisClient.Set(ctx, key, record, im.ttl).Err(); err != nil {
+		log.Printf("Failed to store idempotent response for %s: %v", key, err)
+	}
+
+	return resp
+}
+
+// replay waits for the in-flight request holding key and returns its stored response
+func (im *IdempotencyMiddleware) replay(ctx context.Context, key, requestHash string) *graphql.Response {
+	ticker := time.NewTicker(idempotencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		value, err := im.redisClient.Get(ctx, key).Result()
+		switch {
+		case err == redis.Nil:
+			// The original request failed and released the key
+			return graphql.ErrorResponse(ctx, "previous request with this %s failed, please retry", IdempotencyHeader)
+		case err != nil:
+			return graphql.ErrorResponse(ctx, "failed to check %s", IdempotencyHeader)
+		case value != idempotencyPending:
+			var record idempotencyRecord
+			if err := json.Unmarshal([]byte(value), &record); err != nil || record.Response == nil {
+				return graphql.ErrorResponse(ctx, "failed to replay %s", IdempotencyHeader)
+			}
+			if record.RequestHash != requestHash {
+				return graphql.ErrorResponse(ctx, "%s was already used for a different request", IdempotencyHeader)
+			}
+			return record.Response
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return graphql.ErrorResponse(ctx, "request cancelled")
+		}
+	}
+}
+
+// hashIdempotentRequest fingerprints the mutation so a reused key can't replay a different request
+func hashIdempotentRequest(oc *graphql.OperationContext) string {
+	variables, _ := json.Marshal(oc.Variables)
+	hash := sha256.New()
+	hash.Write([]byte(oc.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write([]byte(oc.OperationName))
+	hash.Write([]byte{0})
+	hash.Write(variables)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// newIdempotencyTestServer serves a join mutation that takes a while and numbers each run,
+// behind the idempotency middleware, for a signed in student
+func newIdempotencyTestServer(t *testing.T, runs *int32) http.Handler {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query { ok: Boolean }
+		type Mutation { join(activityID: ID!): Int! }`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(ctx context.Context, typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			// The work happens when the response is read, inside the response interceptors
+			done := false
+			return func(ctx context.Context) *graphql.Response {
+				if done {
+					return nil
+				}
+				done = true
+				run := atomic.AddInt32(runs, 1)
+				time.Sleep(200 * time.Millisecond)
+				return &graphql.Response{Data: json.RawMessage(fmt.Sprintf(`{"join":%d}`, run))}
+			}
+		},
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(NewIdempotencyMiddleware(client, time.Minute))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.ServeHTTP(w, r.WithContext(withTestAuth(7, models.UserRoleStudent, 1)))
+	})
+}
+
+func postMutation(h http.Handler, idempotencyKey, activityID string) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"query":     "mutation ($id: ID!) { join(activityID: $id) }",
+		"variables": map[string]string{"id": activityID},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyHeader, idempotencyKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Body.String()
+}
+
+func TestIdempotencyCollapsesConcurrentDuplicates(t *testing.T) {
+	var runs int32
+	srv := newIdempotencyTestServer(t, &runs)
+
+	// A client retrying the same join five times before the first one answered
+	const attempts = 5
+	responses := make([]string, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = postMutation(srv, "join-11", "11")
+		}(i)
+	}
+	wg.Wait()
+
+	if runs != 1 {
+		t.Errorf("mutation ran %d times, want once", runs)
+	}
+	for i, resp := range responses {
+		if resp != `{"data":{"join":1}}` {
+			t.Errorf("attempt %d got %s, want the first run's response", i, resp)
+		}
+	}
+
+	// Once stored, a later retry is replayed too
+	if resp := postMutation(srv, "join-11", "11"); resp != `{"data":{"join":1}}` || runs != 1 {
+		t.Errorf("late retry got %s after %d runs, want the stored response", resp, runs)
+	}
+
+	// A new key is a new request, and a reused key can't carry a different one
+	if resp := postMutation(srv, "join-12", "12"); resp != `{"data":{"join":2}}` {
+		t.Errorf("new key got %s, want a second run", resp)
+	}
+	if resp := postMutation(srv, "join-11", "12"); !strings.Contains(resp, "already used for a different request") {
+		t.Errorf("reused key got %s, want it refused", resp)
+	}
+	if runs != 2 {
+		t.Errorf("mutation ran %d times, want 2", runs)
+	}
+}
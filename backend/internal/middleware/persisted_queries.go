@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+// PersistedQueryAllowlist holds the operations clients may run, keyed by the hex SHA-256 of
+// the query text. Safe for concurrent use.
+type PersistedQueryAllowlist struct {
+	mu      sync.RWMutex
+	queries map[string]string
+}
+
+func NewPersistedQueryAllowlist() *PersistedQueryAllowlist {
+	return &PersistedQueryAllowlist{queries: make(map[string]string)}
+}
+
+func hashPersistedQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Add allows query and returns its hash
+func (a *PersistedQueryAllowlist) Add(query string) string {
+	hash := hashPersistedQuery(query)
+	a.mu.Lock()
+	a.queries[hash] = query
+	a.mu.Unlock()
+	return hash
+}
+
+func (a *PersistedQueryAllowlist) Get(hash string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	query, ok := a.queries[hash]
+	return query, ok
+}
+
+func (a *PersistedQueryAllowlist) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.queries)
+}
+
+// addVerified allows query after checking it matches the hash it was registered under
+func (a *PersistedQueryAllowlist) addVerified(hash, query string) error {
+	if hashPersistedQuery(query) != hash {
+		return fmt.Errorf("persisted query %s does not match its hash", hash)
+	}
+	a.Add(query)
+	return nil
+}
+
+// LoadFile adds the queries in a JSON manifest mapping each hash to its query text, the format
+// written by persisted query generators, and returns how many were loaded
+func (a *PersistedQueryAllowlist) LoadFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read persisted queries: %v", err)
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0, fmt.Errorf("failed to parse persisted queries: %v", err)
+	}
+
+	for hash, query := range manifest {
+		if err := a.addVerified(hash, query); err != nil {
+			return 0, err
+		}
+	}
+	return len(manifest), nil
+}
+
+// LoadDB adds the queries stored in the persisted_queries table and returns how many were loaded
+func (a *PersistedQueryAllowlist) LoadDB(ctx context.Context, db *gorm.DB) (int, error) {
+	var stored []models.PersistedQuery
+	if err := db.WithContext(ctx).Find(&stored).Error; err != nil {
+		return 0, fmt.Errorf("failed to load persisted queries: %v", err)
+	}
+
+	for _, pq := range stored {
+		if err := a.addVerified(pq.Hash, pq.Query); err != nil {
+			return 0, err
+		}
+	}
+	return len(stored), nil
+}
+
+// StrictPersistedQueries replaces automatic persisted queries when only registered operations
+// may run. Requests may send the APQ hash alone or the full query; either way an operation
+// missing from the allowlist is rejected with PERSISTED_QUERY_NOT_ALLOWED. With
+// AllowRegistration, unknown queries sent in full are added instead, as APQ would.
+type StrictPersistedQueries struct {
+	Allowlist         *PersistedQueryAllowlist
+	AllowRegistration bool
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = StrictPersistedQueries{}
+
+func (s StrictPersistedQueries) ExtensionName() string {
+	return "StrictPersistedQueries"
+}
+
+func (s StrictPersistedQueries) Validate(schema graphql.ExecutableSchema) error {
+	if s.Allowlist == nil {
+		return errors.New("StrictPersistedQueries.Allowlist can not be nil")
+	}
+	return nil
+}
+
+func (s StrictPersistedQueries) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	var sentHash string
+	if ext, ok := rawParams.Extensions["persistedQuery"].(map[string]interface{}); ok {
+		sentHash, _ = ext["sha256Hash"].(string)
+	}
+
+	if rawParams.Query == "" {
+		if sentHash == "" {
+			return errcode.Validation("query or persisted query hash is required")
+		}
+		query, ok := s.Allowlist.Get(sentHash)
+		if !ok {
+			return persistedQueryNotAllowed(sentHash)
+		}
+		rawParams.Query = query
+		return nil
+	}
+
+	hash := hashPersistedQuery(rawParams.Query)
+	if sentHash != "" && sentHash != hash {
+		return errcode.Validation("persisted query hash does not match the query")
+	}
+	if _, ok := s.Allowlist.Get(hash); ok {
+		return nil
+	}
+	if s.AllowRegistration {
+		s.Allowlist.Add(rawParams.Query)
+		return nil
+	}
+	return persistedQueryNotAllowed(hash)
+}
+
+func persistedQueryNotAllowed(hash string) *gqlerror.Error {
+	err := errcode.New(errcode.CodePersistedQueryNotAllowed, "operation is not a registered persisted query")
+	err.Extensions["hash"] = hash
+	return err
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+// QueryBudget is a role's soft limits for one operation. Operations more complex than
+// Complexity still run, up to the MaxQueryComplexity backstop, but their row budget is cut in
+// proportion. List resolvers stop returning rows once Rows have been returned across the operation.
+type QueryBudget struct {
+	Complexity int
+	Rows       int
+}
+
+// QueryBudgets holds each role's budget; Anonymous applies to signed-out callers and unknown roles
+type QueryBudgets struct {
+	Anonymous QueryBudget
+	Roles     map[models.UserRole]QueryBudget
+}
+
+// DefaultQueryBudgets gives admins, who run the analytics queries, more room than students
+func DefaultQueryBudgets() QueryBudgets {
+	return QueryBudgets{
+		Anonymous: QueryBudget{Complexity: 200, Rows: 200},
+		Roles: map[models.UserRole]QueryBudget{
+			models.UserRoleStudent:      {Complexity: 300, Rows: 500},
+			models.UserRoleRegularAdmin: {Complexity: 500, Rows: 2000},
+			models.UserRoleFacultyAdmin: {Complexity: 800, Rows: 5000},
+			models.UserRoleSuperAdmin:   {Complexity: MaxQueryComplexity, Rows: 10000},
+		},
+	}
+}
+
+func (qb QueryBudgets) forCaller(caller FieldCaller) QueryBudget {
+	if caller.Authenticated {
+		if budget, ok := qb.Roles[caller.Role]; ok {
+			return budget
+		}
+	}
+	return qb.Anonymous
+}
+
+// rowBudgetFor returns the row budget of an operation of the given complexity
+func (qb QueryBudgets) rowBudgetFor(caller FieldCaller, complexity int) *RowBudget {
+	budget := qb.forCaller(caller)
+	rb := &RowBudget{remaining: budget.Rows}
+
+	if complexity > budget.Complexity {
+		rb.remaining = budget.Rows * budget.Complexity / complexity
+		if rb.remaining < 1 {
+			rb.remaining = 1
+		}
+		rb.warnings = append(rb.warnings, fmt.Sprintf(
+			"query complexity %d exceeds the budget of %d for your role; list results are limited to %d rows",
+			complexity, budget.Complexity, rb.remaining))
+	}
+	rb.limit = rb.remaining
+	return rb
+}
+
+// RowBudget counts the rows an operation's list resolvers may still return. A nil
+// RowBudget places no limit.
+type RowBudget struct {
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	truncated bool
+	warnings  []string
+}
+
+type rowBudgetKey struct{}
+
+// WithRowBudget attaches the operation's row budget for list resolvers to draw on
+func WithRowBudget(ctx context.Context, rb *RowBudget) context.Context {
+	return context.WithValue(ctx, rowBudgetKey{}, rb)
+}
+
+// RowBudgetFrom returns the operation's row budget, or nil without one
+func RowBudgetFrom(ctx context.Context) *RowBudget {
+	rb, _ := ctx.Value(rowBudgetKey{}).(*RowBudget)
+	return rb
+}
+
+// Limit caps a list resolver's requested limit by the rows left. Past the budget it asks for
+// one extra row, so Take can tell whether the result was cut short.
+func (rb *RowBudget) Limit(requested *int) *int {
+	if rb == nil {
+		return requested
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if requested != nil && *requested <= rb.remaining {
+		return requested
+	}
+	limit := rb.remaining + 1
+	return &limit
+}
+
+// Take records that a list resolver fetched n rows and returns how many of them it may return
+func (rb *RowBudget) Take(n int) int {
+	if rb == nil {
+		return n
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if n > rb.remaining {
+		n = rb.remaining
+		rb.truncated = true
+	}
+	rb.remaining -= n
+	return n
+}
+
+// annotate marks a response whose list results were cut short with a truncated extension
+// and explains why in warnings
+func (rb *RowBudget) annotate(resp *graphql.Response) {
+	if rb == nil || resp == nil {
+		return
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	warnings := append([]string(nil), rb.warnings...)
+	if rb.truncated {
+		warnings = append(warnings, fmt.Sprintf("list results were truncated to stay within the row budget of %d rows", rb.limit))
+	}
+	if len(warnings) == 0 {
+		return
+	}
+
+	if resp.Extensions == nil {
+		resp.Extensions = make(map[string]interface{})
+	}
+	if rb.truncated {
+		resp.Extensions["truncated"] = true
+	}
+	resp.Extensions["warnings"] = warnings
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+func TestQueryBudgetsByRole(t *testing.T) {
+	budgets := DefaultQueryBudgets()
+	student := FieldCaller{Authenticated: true, UserID: 7, Role: models.UserRoleStudent}
+	admin := FieldCaller{Authenticated: true, UserID: 1, Role: models.UserRoleFacultyAdmin}
+
+	tests := []struct {
+		name       string
+		caller     FieldCaller
+		complexity int
+		wantRows   int
+		wantWarn   bool
+	}{
+		{"student within budget", student, 300, 500, false},
+		{"student over budget", student, 600, 250, true},
+		{"admin runs the same query in full", admin, 600, 5000, false},
+		{"admin over budget", admin, 1600, 2500, true},
+		{"signed out caller", FieldCaller{}, 300, 133, true},
+		{"unknown role", FieldCaller{Authenticated: true, Role: "guest"}, 100, 200, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := budgets.rowBudgetFor(tt.caller, tt.complexity)
+			if rb.remaining != tt.wantRows {
+				t.Errorf("row budget = %d, want %d", rb.remaining, tt.wantRows)
+			}
+			if got := len(rb.warnings) > 0; got != tt.wantWarn {
+				t.Errorf("warnings = %v, want warning %v", rb.warnings, tt.wantWarn)
+			}
+		})
+	}
+
+	// However complex the query, at least one row is allowed
+	if rb := budgets.rowBudgetFor(student, 1000000); rb.remaining != 1 {
+		t.Errorf("row budget of a huge query = %d, want 1", rb.remaining)
+	}
+}
+
+func TestRowBudgetTruncatesAcrossLists(t *testing.T) {
+	rb := DefaultQueryBudgets().rowBudgetFor(FieldCaller{Authenticated: true, Role: models.UserRoleStudent}, 100)
+
+	// A request within the budget keeps its limit; past it, one extra row is fetched
+	requested := 300
+	if got := rb.Limit(&requested); *got != 300 {
+		t.Errorf("Limit(300) = %d, want 300", *got)
+	}
+	if got := rb.Take(300); got != 300 {
+		t.Fatalf("Take(300) = %d, want 300", got)
+	}
+	if got := rb.Limit(nil); *got != 201 {
+		t.Errorf("Limit(nil) after 300 rows = %d, want 201", *got)
+	}
+	if got := rb.Take(201); got != 200 {
+		t.Fatalf("Take(201) = %d, want the 200 rows left", got)
+	}
+	if got := rb.Take(10); got != 0 {
+		t.Errorf("Take(10) past the budget = %d, want 0", got)
+	}
+
+	resp := &graphql.Response{}
+	rb.annotate(resp)
+	if resp.Extensions["truncated"] != true {
+		t.Errorf("extensions = %v, want truncated", resp.Extensions)
+	}
+	warnings, _ := resp.Extensions["warnings"].([]string)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "500 rows") {
+		t.Errorf("warnings = %v, want the 500 row budget explained", warnings)
+	}
+
+	// Responses within budget are left alone
+	untouched := &graphql.Response{}
+	DefaultQueryBudgets().rowBudgetFor(FieldCaller{}, 10).annotate(untouched)
+	if untouched.Extensions != nil {
+		t.Errorf("extensions = %v, want none within budget", untouched.Extensions)
+	}
+
+	// Without a budget nothing is limited
+	var none *RowBudget
+	if got := none.Take(50); got != 50 || none.Limit(nil) != nil {
+		t.Errorf("nil RowBudget limited the result")
+	}
+}
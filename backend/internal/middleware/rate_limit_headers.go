@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/ratelimit"
+)
+
+type rateLimitHeadersKey struct{}
+
+type rateLimitRecorder struct {
+	mu     sync.Mutex
+	result *ratelimit.Result
+}
+
+// WithRateLimitHeaders writes the X-RateLimit-* and Retry-After headers of the request's rate
+// limit check, allowed or not. Websocket clients get the same values in the rateLimit extension.
+func WithRateLimitHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &rateLimitRecorder{}
+		ctx := context.WithValue(r.Context(), rateLimitHeadersKey{}, recorder)
+		next.ServeHTTP(&rateLimitHeaderWriter{ResponseWriter: w, recorder: recorder}, r.WithContext(ctx))
+	})
+}
+
+// recordRateLimit keeps the result for WithRateLimitHeaders to write; without it, it does nothing
+func recordRateLimit(ctx context.Context, result *ratelimit.Result) {
+	recorder, ok := ctx.Value(rateLimitHeadersKey{}).(*rateLimitRecorder)
+	if !ok || result == nil {
+		return
+	}
+	recorder.mu.Lock()
+	recorder.result = result
+	recorder.mu.Unlock()
+}
+
+type rateLimitHeaderWriter struct {
+	http.ResponseWriter
+	recorder    *rateLimitRecorder
+	wroteHeader bool
+}
+
+func (w *rateLimitHeaderWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.recorder.mu.Lock()
+		result := w.recorder.result
+		w.recorder.mu.Unlock()
+		if result != nil {
+			for name, value := range result.Headers() {
+				// Retry-After only means something once the quota is used up
+				if name == "Retry-After" && result.RetryAfter == 0 {
+					continue
+				}
+				w.Header().Set(name, value)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *rateLimitHeaderWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *rateLimitHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
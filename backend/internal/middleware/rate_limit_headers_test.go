@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/ratelimit"
+)
+
+func TestWithRateLimitHeaders(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		result  *ratelimit.Result
+		want    map[string]string
+		without []string
+	}{
+		{
+			name:   "allowed",
+			result: &ratelimit.Result{Allowed: true, Count: 3, Limit: 100, Remaining: 97, Reset: reset},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "100",
+				"X-RateLimit-Remaining": "97",
+				"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+			},
+			without: []string{"Retry-After"},
+		},
+		{
+			name:   "rejected",
+			result: &ratelimit.Result{Allowed: false, Count: 101, Limit: 100, Reset: reset, RetryAfter: 1500 * time.Millisecond},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "100",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+				"Retry-After":           "2",
+			},
+		},
+		{
+			name:    "not checked",
+			without: []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := WithRateLimitHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				recordRateLimit(r.Context(), tt.result)
+				w.Write([]byte(`{"data":null}`))
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+
+			for name, want := range tt.want {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			for _, name := range tt.without {
+				if got := rec.Header().Get(name); got != "" {
+					t.Errorf("%s = %q, want it unset", name, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TracingMiddleware starts a span per HTTP request, continuing any trace propagated by the
+// caller, and stores it with the request ID in the request's user context.
+// It expects the requestid middleware to run first.
+func TracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !monitoring.TracingEnabled() {
+			return c.Next()
+		}
+
+		headers := propagation.HeaderCarrier(http.Header{})
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			headers.Set(string(key), string(value))
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headers)
+
+		if requestID, ok := c.Locals("requestid").(string); ok {
+			ctx = monitoring.WithRequestID(ctx, requestID)
+		}
+
+		ctx, span := monitoring.StartSpan(ctx, c.Method()+" "+c.Path(),
+			attribute.String("http.method", c.Method()),
+			attribute.String("http.target", c.OriginalURL()),
+		)
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= fiber.StatusInternalServerError && err == nil {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		monitoring.EndSpan(span, err)
+		return err
+	}
+}
+
+// GraphQLTracer is a gqlgen extension adding a span per operation and per resolver call.
+// Only register it when tracing is enabled.
+type GraphQLTracer struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = GraphQLTracer{}
+
+func (GraphQLTracer) ExtensionName() string {
+	return "GraphQLTracer"
+}
+
+func (GraphQLTracer) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (GraphQLTracer) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+
+	name := "graphql.operation"
+	attrs := []attribute.KeyValue{}
+	if oc.Operation != nil {
+		name = fmt.Sprintf("graphql.%s", oc.Operation.Operation)
+		attrs = append(attrs, attribute.String("graphql.operation.type", string(oc.Operation.Operation)))
+	}
+	if oc.OperationName != "" {
+		name += " " + oc.OperationName
+		attrs = append(attrs, attribute.String("graphql.operation.name", oc.OperationName))
+	}
+
+	ctx, span := monitoring.StartSpan(ctx, name, attrs...)
+	handler := next(ctx)
+
+	return func(ctx context.Context) *graphql.Response {
+		response := handler(ctx)
+
+		// Subscriptions call the handler once per event; ending an ended span is a no-op
+		var err error
+		if response != nil && len(response.Errors) > 0 {
+			err = response.Errors
+		}
+		monitoring.EndSpan(span, err)
+		return response
+	}
+}
+
+func (GraphQLTracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+
+	// Fields read straight off a struct are not worth a span each
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := monitoring.StartSpan(ctx, fc.Object+"."+fc.Field.Name,
+		attribute.String("graphql.field.path", fc.Path().String()),
+	)
+	result, err := next(ctx)
+	monitoring.EndSpan(span, err)
+	return result, err
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+type websocketSessionKey struct{}
+
+// websocketSession is one authenticated GraphQL websocket; cancelling it makes gqlgen close the socket
+type websocketSession struct {
+	userID uint
+	cancel context.CancelFunc
+}
+
+type websocketRegistry struct {
+	mu       sync.Mutex
+	sessions map[uint]map[*websocketSession]struct{}
+}
+
+func newWebsocketRegistry() *websocketRegistry {
+	return &websocketRegistry{sessions: make(map[uint]map[*websocketSession]struct{})}
+}
+
+func (wr *websocketRegistry) add(session *websocketSession) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.sessions[session.userID] == nil {
+		wr.sessions[session.userID] = make(map[*websocketSession]struct{})
+	}
+	wr.sessions[session.userID][session] = struct{}{}
+}
+
+func (wr *websocketRegistry) remove(session *websocketSession) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	delete(wr.sessions[session.userID], session)
+	if len(wr.sessions[session.userID]) == 0 {
+		delete(wr.sessions, session.userID)
+	}
+}
+
+// closeUser cancels every websocket of the user and returns how many there were
+func (wr *websocketRegistry) closeUser(userID uint) int {
+	wr.mu.Lock()
+	sessions := wr.sessions[userID]
+	delete(wr.sessions, userID)
+	wr.mu.Unlock()
+
+	for session := range sessions {
+		session.cancel()
+	}
+	return len(sessions)
+}
+
+// WebsocketInit authenticates a GraphQL websocket from its connection_init payload, which
+// carries either "Authorization": "Bearer <token>" or a bare "token". Handshakes without a
+// valid token are rejected. The connection is closed when the token expires or the user's
+// sessions are terminated.
+func (gam *GraphQLAuthMiddleware) WebsocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	authHeader := payload.Authorization()
+	if authHeader == "" {
+		if token := payload.GetString("token"); token != "" {
+			authHeader = "Bearer " + token
+		}
+	}
+	if strings.TrimSpace(authHeader) == "" {
+		return nil, nil, fmt.Errorf("authentication required")
+	}
+
+	authCtx, err := gam.authenticate(ctx, authHeader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("authentication failed: %v", err)
+	}
+
+	ctx = context.WithValue(ctx, AuthContextKey, authCtx)
+
+	// gqlgen closes the socket, sending this reason, once the context is done: the token
+	// expired or the user's sessions were terminated
+	ctx = transport.AppendCloseReason(ctx, "session ended, please log in again")
+	var cancel context.CancelFunc
+	if authCtx.Claims.ExpiresAt != nil {
+		ctx, cancel = context.WithDeadline(ctx, authCtx.Claims.ExpiresAt.Time)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	session := &websocketSession{userID: authCtx.UserID, cancel: cancel}
+	gam.websockets.add(session)
+	ctx = context.WithValue(ctx, websocketSessionKey{}, session)
+
+	return ctx, nil, nil
+}
+
+// WebsocketClose forgets the socket and releases the timer set up by WebsocketInit
+func (gam *GraphQLAuthMiddleware) WebsocketClose(ctx context.Context, closeCode int) {
+	if session, ok := ctx.Value(websocketSessionKey{}).(*websocketSession); ok {
+		gam.websockets.remove(session)
+		session.cancel()
+	}
+}
+
+// CloseUserWebsockets closes every GraphQL websocket the user has open on this instance
+func (gam *GraphQLAuthMiddleware) CloseUserWebsockets(userID uint) int {
+	return gam.websockets.closeUser(userID)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
+)
+
+var testJWTService = auth.NewJWTService("test-secret", 1, auth.ClaimOptions{})
+
+// newTestAuthMiddleware authenticates tokens of testJWTService against a database holding
+// one active student, user 7
+func newTestAuthMiddleware(t testing.TB) (*GraphQLAuthMiddleware, *fakedb.DB) {
+	db, fake := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.Contains(query, `FROM "users"`) {
+			return fakedb.Result{
+				Columns: []string{"id", "email", "role", "is_active"},
+				Rows:    [][]driver.Value{{int64(7), "student@example.com", "student", true}},
+			}
+		}
+		return fakedb.Result{}
+	})
+	return NewGraphQLAuthMiddleware(testJWTService, nil, db), fake
+}
+
+func testToken(t testing.TB) string {
+	t.Helper()
+	token, err := testJWTService.GenerateToken(auth.TokenSubject{UserID: 7, Email: "student@example.com", Role: "student"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+func TestWebsocketInitAuthenticatesFromThePayload(t *testing.T) {
+	token := testToken(t)
+
+	tests := []struct {
+		name    string
+		payload transport.InitPayload
+		wantErr string
+	}{
+		{"authorization header", transport.InitPayload{"Authorization": "Bearer " + token}, ""},
+		{"bare token", transport.InitPayload{"token": token}, ""},
+		{"no token", transport.InitPayload{}, "authentication required"},
+		{"invalid token", transport.InitPayload{"token": token + "x"}, "authentication failed"},
+		{"wrong scheme", transport.InitPayload{"Authorization": "Basic " + token}, "authentication failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gam, _ := newTestAuthMiddleware(t)
+			ctx, _, err := gam.WebsocketInit(context.Background(), tt.payload)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("WebsocketInit() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WebsocketInit: %v", err)
+			}
+			defer gam.WebsocketClose(ctx, 1000)
+
+			authCtx, err := GetAuthContext(ctx)
+			if err != nil || authCtx.UserID != 7 {
+				t.Fatalf("auth context = %+v, %v, want user 7", authCtx, err)
+			}
+			// The socket is closed when the token expires
+			deadline, ok := ctx.Deadline()
+			if !ok || deadline.Sub(time.Now()) > time.Hour {
+				t.Errorf("deadline = %v, %v, want the token's expiry within the hour", deadline, ok)
+			}
+		})
+	}
+}
+
+func TestCloseUserWebsockets(t *testing.T) {
+	gam, _ := newTestAuthMiddleware(t)
+	payload := transport.InitPayload{"token": testToken(t)}
+
+	first, _, err := gam.WebsocketInit(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("WebsocketInit: %v", err)
+	}
+	second, _, err := gam.WebsocketInit(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("WebsocketInit: %v", err)
+	}
+
+	// A socket the client closed itself is forgotten
+	gam.WebsocketClose(second, 1000)
+	if second.Err() == nil {
+		t.Error("closed socket's context is still live")
+	}
+
+	if closed := gam.CloseUserWebsockets(7); closed != 1 {
+		t.Errorf("CloseUserWebsockets() = %d, want 1", closed)
+	}
+	if first.Err() == nil {
+		t.Error("socket was not closed")
+	}
+	if closed := gam.CloseUserWebsockets(7); closed != 0 {
+		t.Errorf("CloseUserWebsockets() again = %d, want 0", closed)
+	}
+}
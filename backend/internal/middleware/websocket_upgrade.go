@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// HTTPHandlerWithWebsockets serves h from Fiber like adaptor.HTTPHandler, except for websocket
+// upgrades. The adaptor's response writer can't be hijacked, so a websocket transport behind
+// it never gets a connection; upgrades are instead handed the raw connection and served by
+// websockets.
+func HTTPHandlerWithWebsockets(h, websockets http.Handler) fiber.Handler {
+	serveHTTP := adaptor.HTTPHandler(h)
+	return func(c *fiber.Ctx) error {
+		if !strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") {
+			return serveHTTP(c)
+		}
+
+		// Fiber reuses the request's memory once the handler returns, before the connection
+		// is handed over, so the upgrade is read back from a copy of its header
+		header := append([]byte(nil), c.Request().Header.Header()...)
+		remoteAddr := c.Context().RemoteAddr().String()
+
+		c.Context().HijackSetNoResponse(true)
+		c.Context().Hijack(func(conn net.Conn) {
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(header)))
+			if err != nil {
+				log.Printf("Failed to read websocket upgrade request: %v", err)
+				return
+			}
+			req.RemoteAddr = remoteAddr
+			websockets.ServeHTTP(&hijackResponseWriter{conn: conn, header: make(http.Header)}, req)
+		})
+		return nil
+	}
+}
+
+// hijackResponseWriter writes straight to a hijacked connection. Upgraders take the connection
+// over through Hijack; any other response is written once and the connection closed.
+type hijackResponseWriter struct {
+	conn        net.Conn
+	header      http.Header
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *hijackResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *hijackResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+	w.wroteHeader = true
+	w.header.Set("Connection", "close")
+	fmt.Fprintf(w.conn, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	w.header.Write(w.conn)
+	io.WriteString(w.conn, "\r\n")
+}
+
+func (w *hijackResponseWriter) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.conn.Write(p)
+}
+
+func (w *hijackResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.hijacked {
+		return nil, nil, http.ErrHijacked
+	}
+	w.hijacked = true
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
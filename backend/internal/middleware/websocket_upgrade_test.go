@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+	"github.com/kruakemaths/tru-activity/backend/graph/generated"
+)
+
+// serveFiber serves handler on /query from a Fiber app on a local port and returns its address
+func serveFiber(t *testing.T, handler fiber.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.All("/query", handler)
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return ln.Addr().String()
+}
+
+func TestHTTPHandlerWithWebsocketsServesGraphQLWebsockets(t *testing.T) {
+	gam, _ := newTestAuthMiddleware(t)
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{}))
+	srv.AddTransport(transport.Websocket{
+		Upgrader:  websocket.Upgrader{CheckOrigin: NewWebsocketOriginCheck(NewOriginMatcher([]string{"https://activity.example.com"}))},
+		InitFunc:  gam.WebsocketInit,
+		CloseFunc: gam.WebsocketClose,
+	})
+	srv.AddTransport(transport.POST{})
+	addr := serveFiber(t, HTTPHandlerWithWebsockets(srv, WithRemoteAddr(srv)))
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}, HandshakeTimeout: 5 * time.Second}
+	header := http.Header{"Origin": []string{"https://activity.example.com"}}
+	conn, _, err := dialer.Dial("ws://"+addr+"/query", header)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	defer conn.Close()
+
+	init := map[string]interface{}{"type": "connection_init", "payload": map[string]interface{}{"token": testToken(t)}}
+	if err := conn.WriteJSON(init); err != nil {
+		t.Fatalf("sending connection_init: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ack struct {
+		Type string `json:"type"`
+	}
+	if err := conn.ReadJSON(&ack); err != nil || ack.Type != "connection_ack" {
+		t.Fatalf("reply to connection_init = %+v, %v, want connection_ack", ack, err)
+	}
+
+	// Plain requests still go through the ordinary handler
+	resp := postQuery(t, remoteHandler{addr}, "{ __typename }")
+	if len(resp.Errors) != 0 || string(resp.Data) != `{"__typename":"Query"}` {
+		t.Errorf("POST response = %s %+v, want the typename", resp.Data, resp.Errors)
+	}
+
+	// Browsers on other origins can't open a socket with the user's credentials
+	header.Set("Origin", "https://evil.example.com")
+	_, rejected, err := dialer.Dial("ws://"+addr+"/query", header)
+	if err == nil || rejected == nil || rejected.StatusCode != http.StatusForbidden {
+		t.Errorf("upgrade from another origin = %v, %v, want 403", rejected, err)
+	}
+}
+
+// remoteHandler forwards postQuery's requests to a running server
+type remoteHandler struct {
+	addr string
+}
+
+func (c remoteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.URL.Scheme = "http"
+	r.URL.Host = c.addr
+	r.RequestURI = ""
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
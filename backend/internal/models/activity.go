@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +14,7 @@ const (
 	ActivityStatusActive    ActivityStatus = "active"
 	ActivityStatusCompleted ActivityStatus = "completed"
 	ActivityStatusCancelled ActivityStatus = "cancelled"
+	ActivityStatusArchived  ActivityStatus = "archived"
 )
 
 type ActivityType string
@@ -25,6 +27,14 @@ const (
 	ActivityTypeOther      ActivityType = "other"
 )
 
+// AttendancePolicy controls which scan defines the attendance time when a participant is scanned more than once
+type AttendancePolicy string
+
+const (
+	AttendancePolicyFirstScan  AttendancePolicy = "first_scan"
+	AttendancePolicyLatestScan AttendancePolicy = "latest_scan"
+)
+
 type Activity struct {
 	ID               uint             `json:"id" gorm:"primaryKey"`
 	Title            string           `json:"title" gorm:"size:200;not null"`
@@ -39,6 +49,7 @@ type Activity struct {
 	Points           int              `json:"points" gorm:"default:0"`
 	FacultyID        *uint            `json:"faculty_id"`
 	Faculty          *Faculty         `json:"faculty,omitempty"`
+	CoHostFaculties  []Faculty        `json:"co_host_faculties" gorm:"many2many:activity_faculties"`
 	DepartmentID     *uint            `json:"department_id"`
 	Department       *Department      `json:"department,omitempty"`
 	CreatedByID      uint             `json:"created_by_id"`
@@ -51,6 +62,12 @@ type Activity struct {
 	ParentActivity   *Activity        `json:"parent_activity,omitempty"`
 	QRCodeRequired   bool             `json:"qr_code_required" gorm:"default:true"`
 	AutoApprove      bool             `json:"auto_approve" gorm:"default:false"`
+	AttendancePolicy AttendancePolicy `json:"attendance_policy" gorm:"type:varchar(20);default:'first_scan'"`
+	QRExpiryMinutes  *int             `json:"qr_expiry_minutes"`
+	Timezone         string           `json:"timezone" gorm:"size:64;not null;default:'Asia/Bangkok'"`
+	ArchivedAt       *time.Time       `json:"archived_at" gorm:"index"`
+	UnstaffedAlertSentAt *time.Time   `json:"unstaffed_alert_sent_at"`
+	Version          int              `json:"version" gorm:"not null;default:1"`
 	CreatedAt        time.Time        `json:"created_at"`
 	UpdatedAt        time.Time        `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
@@ -61,6 +78,44 @@ type Activity struct {
 	ChildActivities  []Activity        `json:"child_activities" gorm:"foreignKey:ParentActivityID"`
 }
 
+// DefaultTimezone is the institution's zone, for activities of faculties that don't set one
+const DefaultTimezone = "Asia/Bangkok"
+
+// LoadTimezone loads an IANA time zone such as "Asia/Bangkok". Unlike time.LoadLocation it
+// rejects "" and "Local", which depend on the server rather than naming a zone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// TimeLocation returns the zone the activity is scheduled in, falling back to DefaultTimezone
+func (a *Activity) TimeLocation() *time.Location {
+	if loc, err := LoadTimezone(a.Timezone); err == nil {
+		return loc
+	}
+	if loc, err := LoadTimezone(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// ActivityCoHostSubquery selects the activities a faculty co-hosts, for use in IN (...)
+const ActivityCoHostSubquery = "SELECT activity_id FROM activity_faculties WHERE faculty_id = ?"
+
+// FacultyIDs returns the activity's own faculty followed by its co-hosts; CoHostFaculties must be loaded
+func (a *Activity) FacultyIDs() []uint {
+	ids := make([]uint, 0, len(a.CoHostFaculties)+1)
+	if a.FacultyID != nil {
+		ids = append(ids, *a.FacultyID)
+	}
+	for _, faculty := range a.CoHostFaculties {
+		ids = append(ids, faculty.ID)
+	}
+	return ids
+}
+
 type ParticipationStatus string
 
 const (
@@ -69,6 +124,7 @@ const (
 	ParticipationStatusRejected  ParticipationStatus = "rejected"
 	ParticipationStatusAttended  ParticipationStatus = "attended"
 	ParticipationStatusAbsent    ParticipationStatus = "absent"
+	ParticipationStatusWithdrawn ParticipationStatus = "withdrawn"
 )
 
 type Participation struct {
@@ -86,6 +142,8 @@ type Participation struct {
 	ScannedBy    *User               `json:"scanned_by,omitempty"`
 	ScanLocation string              `json:"scan_location" gorm:"size:200"`
 	Notes        string              `json:"notes" gorm:"type:text"`
+	ReschedulePending    bool        `json:"reschedule_pending" gorm:"default:false;index"`
+	RescheduleNotifiedAt *time.Time  `json:"reschedule_notified_at"`
 	CreatedAt    time.Time           `json:"created_at"`
 	UpdatedAt    time.Time           `json:"updated_at"`
 }
@@ -150,6 +208,12 @@ type QRScanLog struct {
 	ScanLocation   string         `json:"scan_location" gorm:"size:200"`
 	IPAddress      string         `json:"ip_address" gorm:"size:45"`
 	UserAgent      string         `json:"user_agent" gorm:"type:text"`
+	// OfflineScanID is the scanner's own ID for a scan it accepted offline; set once the scan is reconciled
+	OfflineScanID  *string        `json:"offline_scan_id" gorm:"size:64"`
+	// NeedsReview marks a reconciled offline scan whose code was revoked or replayed
+	NeedsReview    bool           `json:"needs_review" gorm:"not null;default:false"`
+	// ScannerTokenID is the scanner token a volunteer scanned with; ScannedByID is then its issuer
+	ScannerTokenID *string        `json:"scanner_token_id" gorm:"size:32"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
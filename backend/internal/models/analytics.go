@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -78,6 +79,50 @@ const (
 	AlertSeverityCritical AlertSeverity = "critical"
 )
 
+// DefaultMinAlertSeverity is the lowest severity delivered to alert subscribers that don't set one
+const DefaultMinAlertSeverity = AlertSeverityWarning
+
+var alertSeverityRanks = map[AlertSeverity]int{
+	AlertSeverityInfo:     1,
+	AlertSeverityWarning:  2,
+	AlertSeverityError:    3,
+	AlertSeverityCritical: 4,
+}
+
+// ParseAlertSeverity reads a severity in any case; WARN is accepted for warning
+func ParseAlertSeverity(s string) (AlertSeverity, bool) {
+	severity := AlertSeverity(strings.ToLower(strings.TrimSpace(s)))
+	if severity == "warn" {
+		severity = AlertSeverityWarning
+	}
+	_, ok := alertSeverityRanks[severity]
+	return severity, ok
+}
+
+// AtLeast reports whether s is min or more severe. An unrecognised severity passes, so
+// alerts of a new or misspelt level are never silently dropped.
+func (s AlertSeverity) AtLeast(min AlertSeverity) bool {
+	rank, ok := alertSeverityRanks[s]
+	return !ok || rank >= alertSeverityRanks[min]
+}
+
+// AlertSeverityOf returns the severity of published alert data: a SystemAlert, or the same
+// decoded from JSON into a map. It returns "" when data carries no severity.
+func AlertSeverityOf(data interface{}) AlertSeverity {
+	switch alert := data.(type) {
+	case *SystemAlert:
+		return alert.Severity
+	case SystemAlert:
+		return alert.Severity
+	case map[string]interface{}:
+		if severity, ok := alert["severity"].(string); ok {
+			parsed, _ := ParseAlertSeverity(severity)
+			return parsed
+		}
+	}
+	return ""
+}
+
 // SystemAlert represents system-wide alerts and notifications
 type SystemAlert struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
@@ -12,6 +12,7 @@ type Faculty struct {
 	Code        string         `json:"code" gorm:"uniqueIndex;size:10;not null"`
 	Description string         `json:"description" gorm:"type:text"`
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	Timezone    string         `json:"timezone" gorm:"size:64"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
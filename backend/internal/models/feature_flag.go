@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Feature flags consulted by the application
+const (
+	FeatureRecurringActivities = "recurring_activities"
+	FeatureQRBadges            = "qr_badges"
+)
+
+// KnownFeatureFlags lists every flag that can be set; unset flags are off
+var KnownFeatureFlags = []string{FeatureRecurringActivities, FeatureQRBadges}
+
+// FeatureFlag turns a feature on or off for one faculty, or for every faculty when FacultyID
+// is nil; a faculty's own flag takes precedence over the global one. RolloutPercentage, when
+// set, limits an enabled flag to that share of users.
+type FeatureFlag struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	FacultyID         *uint     `json:"faculty_id" gorm:"index"`
+	Faculty           *Faculty  `json:"faculty,omitempty"`
+	Name              string    `json:"name" gorm:"size:50;not null;index"`
+	Enabled           bool      `json:"enabled" gorm:"not null;default:false"`
+	RolloutPercentage *int      `json:"rollout_percentage"`
+	UpdatedByID       *uint     `json:"updated_by_id"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// IsKnownFeatureFlag reports whether name is one of KnownFeatureFlags
+func IsKnownFeatureFlag(name string) bool {
+	for _, known := range KnownFeatureFlags {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
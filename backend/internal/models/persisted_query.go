@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PersistedQuery is a GraphQL operation clients may run when persisted queries are enforced.
+// Hash is the hex SHA-256 of Query, as sent in the APQ persistedQuery extension.
+type PersistedQuery struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Hash      string    `json:"hash" gorm:"size:64;not null;uniqueIndex"`
+	Name      string    `json:"name" gorm:"size:100"`
+	Query     string    `json:"query" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -33,6 +33,8 @@ type Subscription struct {
 	NotificationSent7Days bool               `json:"notification_sent_7_days" gorm:"default:false"`
 	NotificationSent1Day  bool               `json:"notification_sent_1_day" gorm:"default:false"`
 	LastNotificationAt    *time.Time         `json:"last_notification_at"`
+	ExpiryWarningDays     *int               `json:"expiry_warning_days"` // smallest threshold warned for; nil since the last renewal
+	Version               int                `json:"version" gorm:"not null;default:1"`
 	CreatedAt             time.Time          `json:"created_at"`
 	UpdatedAt             time.Time          `json:"updated_at"`
 	DeletedAt             gorm.DeletedAt     `json:"deleted_at" gorm:"index"`
@@ -68,6 +70,27 @@ func (s *Subscription) NeedsNotification() bool {
 	return false
 }
 
+// DueExpiryWarning returns the threshold whose expiry warning is due: the smallest of
+// thresholds (days before expiry) the subscription has reached, unless a warning was already
+// published for it or a smaller one. Thresholds skipped between checks are not warned separately.
+func (s *Subscription) DueExpiryWarning(thresholds []int) (int, bool) {
+	if s.IsExpired() {
+		return 0, false
+	}
+
+	daysLeft := s.DaysUntilExpiry()
+	due := -1
+	for _, threshold := range thresholds {
+		if daysLeft <= threshold && (due < 0 || threshold < due) {
+			due = threshold
+		}
+	}
+	if due < 0 || (s.ExpiryWarningDays != nil && *s.ExpiryWarningDays <= due) {
+		return 0, false
+	}
+	return due, true
+}
+
 // GetNotificationType returns which type of notification is needed
 func (s *Subscription) GetNotificationType() string {
 	daysLeft := s.DaysUntilExpiry()
@@ -83,5 +106,28 @@ func (s *Subscription) GetNotificationType() string {
 	return ""
 }
 
+// endDateForDaysLeft is the earliest end date for which DaysUntilExpiry reports days at now
+func endDateForDaysLeft(now time.Time, days int) time.Time {
+	return now.Add(time.Duration(days) * 24 * time.Hour)
+}
+
+// WhereDaysUntilExpiry narrows a subscription query to unexpired subscriptions whose
+// DaysUntilExpiry is between minDays and maxDays, so lists can be filtered and paginated in SQL
+func WhereDaysUntilExpiry(db *gorm.DB, now time.Time, minDays, maxDays int) *gorm.DB {
+	return db.Where("end_date >= ? AND end_date < ?", endDateForDaysLeft(now, minDays), endDateForDaysLeft(now, maxDays+1))
+}
+
+// WhereNeedsNotification narrows a subscription query to those NeedsNotification reports, or
+// with needed false, does not report
+func WhereNeedsNotification(db *gorm.DB, now time.Time, needed bool) *gorm.DB {
+	group := db.Session(&gorm.Session{NewDB: true})
+	sevenDays := WhereDaysUntilExpiry(group, now, 2, 7).Where(&Subscription{}, "NotificationSent7Days")
+	oneDay := WhereDaysUntilExpiry(group, now, 1, 1).Where(&Subscription{}, "NotificationSent1Day")
+	if needed {
+		return db.Where(group.Where(sevenDays).Or(oneDay))
+	}
+	return db.Not(group.Where(sevenDays).Or(oneDay))
+}
+
 // IsSubscriptionData implements the GraphQL union interface for Subscription
 func (s *Subscription) IsSubscriptionData() {}
\ No newline at end of file
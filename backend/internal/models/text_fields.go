@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Text field limits, in characters. Titles, names and locations match their column sizes.
+const (
+	ActivityTitleMaxLength       = 200
+	ActivityDescriptionMaxLength = 5000
+	ActivityLocationMaxLength    = 200
+	FacultyNameMaxLength         = 100
+	FacultyCodeMaxLength         = 10
+	FacultyDescriptionMaxLength  = 2000
+	DepartmentNameMaxLength      = 100
+	DepartmentCodeMaxLength      = 10
+)
+
+// TextFieldError reports a text field that failed validation
+type TextFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *TextFieldError) Error() string {
+	return e.Message
+}
+
+// textRule trims one text column and checks its length
+type textRule struct {
+	column   string // as used in update maps
+	field    string // input field reported in errors
+	label    string
+	maxLen   int
+	required bool
+}
+
+var activityTextRules = []textRule{
+	{column: "title", field: "title", label: "title", maxLen: ActivityTitleMaxLength, required: true},
+	{column: "description", field: "description", label: "description", maxLen: ActivityDescriptionMaxLength},
+	{column: "location", field: "location", label: "location", maxLen: ActivityLocationMaxLength},
+}
+
+var facultyTextRules = []textRule{
+	{column: "name", field: "name", label: "name", maxLen: FacultyNameMaxLength, required: true},
+	{column: "code", field: "code", label: "code", maxLen: FacultyCodeMaxLength, required: true},
+	{column: "description", field: "description", label: "description", maxLen: FacultyDescriptionMaxLength},
+}
+
+var departmentTextRules = []textRule{
+	{column: "name", field: "name", label: "name", maxLen: DepartmentNameMaxLength, required: true},
+	{column: "code", field: "code", label: "code", maxLen: DepartmentCodeMaxLength, required: true},
+}
+
+func (rule textRule) apply(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if rule.required && value == "" {
+		return "", &TextFieldError{Field: rule.field, Message: fmt.Sprintf("%s is required", rule.label)}
+	}
+	if utf8.RuneCountInString(value) > rule.maxLen {
+		return "", &TextFieldError{Field: rule.field, Message: fmt.Sprintf("%s must be at most %d characters", rule.label, rule.maxLen)}
+	}
+	return value, nil
+}
+
+// sanitizeTextFields applies rules to a save. fields point at the model's values, in rule
+// order. Updates with a column map are checked in the map, for the columns it sets.
+func sanitizeTextFields(tx *gorm.DB, rules []textRule, fields ...*string) error {
+	if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		for _, rule := range rules {
+			value, ok := updates[rule.column].(string)
+			if !ok {
+				continue
+			}
+			cleaned, err := rule.apply(value)
+			if err != nil {
+				return err
+			}
+			updates[rule.column] = cleaned
+		}
+		return nil
+	}
+
+	for i, rule := range rules {
+		cleaned, err := rule.apply(*fields[i])
+		if err != nil {
+			return err
+		}
+		*fields[i] = cleaned
+	}
+	return nil
+}
+
+// BeforeSave trims and checks the activity's text fields
+func (a *Activity) BeforeSave(tx *gorm.DB) error {
+	return sanitizeTextFields(tx, activityTextRules, &a.Title, &a.Description, &a.Location)
+}
+
+// BeforeSave trims and checks the faculty's text fields
+func (f *Faculty) BeforeSave(tx *gorm.DB) error {
+	return sanitizeTextFields(tx, facultyTextRules, &f.Name, &f.Code, &f.Description)
+}
+
+// BeforeSave trims and checks the department's text fields
+func (d *Department) BeforeSave(tx *gorm.DB) error {
+	return sanitizeTextFields(tx, departmentTextRules, &d.Name, &d.Code)
+}
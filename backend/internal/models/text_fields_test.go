@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("opening dry run database: %v", err)
+	}
+	return db
+}
+
+func TestActivityTextFieldsOnCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		activity  Activity
+		wantTitle string
+		wantField string
+	}{
+		{"trimmed", Activity{Title: "  Orientation \n", Location: " Hall A "}, "Orientation", ""},
+		{"blank title", Activity{Title: "   "}, "", "title"},
+		// Limits count characters, so Thai text gets the full length
+		{"title at the limit", Activity{Title: strings.Repeat("ก", ActivityTitleMaxLength)}, strings.Repeat("ก", ActivityTitleMaxLength), ""},
+		{"title over the limit", Activity{Title: strings.Repeat("ก", ActivityTitleMaxLength+1)}, "", "title"},
+		{"description over the limit", Activity{Title: "Talk", Description: strings.Repeat("a", ActivityDescriptionMaxLength+1)}, "", "description"},
+	}
+	db := newDryRunDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := tt.activity
+			err := db.Create(&activity).Error
+			if tt.wantField != "" {
+				var fieldErr *TextFieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField {
+					t.Fatalf("Create() error = %v, want a %s field error", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if activity.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", activity.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestTextFieldsOnMapUpdates(t *testing.T) {
+	db := newDryRunDB(t)
+
+	updates := map[string]interface{}{"title": "  Renamed  ", "location": " Hall B", "points": 5}
+	if err := db.Model(&Activity{ID: 1}).Updates(updates).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	if updates["title"] != "Renamed" || updates["location"] != "Hall B" {
+		t.Errorf("updates = %v, want trimmed text", updates)
+	}
+
+	// Only the columns being set are checked, so a partial update needs no title
+	if err := db.Model(&Activity{ID: 1}).Updates(map[string]interface{}{"points": 5}).Error; err != nil {
+		t.Errorf("update without text columns: %v", err)
+	}
+
+	err := db.Model(&Faculty{ID: 1}).Updates(map[string]interface{}{"code": "ENGINEERING1"}).Error
+	var fieldErr *TextFieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "code" {
+		t.Errorf("faculty code update error = %v, want a code field error", err)
+	}
+
+	err = db.Model(&Department{ID: 1}).Updates(map[string]interface{}{"name": " "}).Error
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "name" {
+		t.Errorf("blank department name error = %v, want a name field error", err)
+	}
+}
@@ -96,8 +96,13 @@ func (u *User) CanManageActivity(activity *Activity) bool {
 		if u.ID == activity.CreatedByID {
 			return true
 		}
-		if activity.FacultyID != nil && u.FacultyID != nil && *activity.FacultyID == *u.FacultyID {
-			return true
+		// รวมถึงกิจกรรมที่คณะตัวเองเป็นผู้ร่วมจัด
+		if u.FacultyID != nil {
+			for _, facultyID := range activity.FacultyIDs() {
+				if facultyID == *u.FacultyID {
+					return true
+				}
+			}
 		}
 	}
 	// เจ้าของกิจกรรมสามารถจัดการได้
@@ -119,6 +124,14 @@ func (u *User) CanMarkAttendance() bool {
 	return u.Role == UserRoleSuperAdmin || u.Role == UserRoleFacultyAdmin || u.Role == UserRoleRegularAdmin
 }
 
+// GetDepartmentScope คืน department ที่ user ถูกจำกัดให้เห็น (regular admin ประจำภาควิชา); nil หมายถึงไม่จำกัดระดับภาควิชา
+func (u *User) GetDepartmentScope() *uint {
+	if u.Role == UserRoleRegularAdmin {
+		return u.DepartmentID
+	}
+	return nil
+}
+
 func (u *User) GetAccessibleFacultyIDs() []uint {
 	if u.Role == UserRoleSuperAdmin {
 		return []uint{} // Empty slice หมายถึงทุก faculty
@@ -0,0 +1,167 @@
+// Package fakedb is a database/sql driver for tests that records the statements GORM runs
+// and answers them from a function, so code that needs a Postgres connection can be tested
+// without one.
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Result is what the fake database answers to one statement
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	// Err, when set, fails the statement, e.g. with a constraint violation
+	Err error
+}
+
+// Responder answers a statement; it may be called from several goroutines at once
+type Responder func(query string, args []driver.NamedValue) Result
+
+// DB records the statements run against it, including BEGIN, COMMIT and ROLLBACK
+type DB struct {
+	mu         sync.Mutex
+	statements []string
+	respond    Responder
+}
+
+func (db *DB) run(query string, args []driver.NamedValue) Result {
+	db.mu.Lock()
+	db.statements = append(db.statements, query)
+	db.mu.Unlock()
+	if db.respond == nil {
+		return Result{}
+	}
+	return db.respond(query, args)
+}
+
+// Statements returns the statements run so far
+func (db *DB) Statements() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.statements...)
+}
+
+// Open returns a Postgres GORM session on a fake database answering from respond; a nil
+// respond answers every statement with no rows
+func Open(t testing.TB, respond Responder) (*gorm.DB, *DB) {
+	t.Helper()
+	fake := &DB{respond: respond}
+	sqlDB := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening GORM on the fake database: %v", err)
+	}
+	return db, fake
+}
+
+// Containing returns the statements that include every fragment
+func Containing(statements []string, fragments ...string) []string {
+	var matched []string
+	for _, statement := range statements {
+		all := true
+		for _, fragment := range fragments {
+			if !strings.Contains(statement, fragment) {
+				all = false
+				break
+			}
+		}
+		if all {
+			matched = append(matched, statement)
+		}
+	}
+	return matched
+}
+
+type connector struct {
+	db *DB
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{db: c.db}, nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, fmt.Errorf("fake databases are opened with fakedb.Open")
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.run("BEGIN", nil)
+	return tx{db: c.db}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &rows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t tx) Commit() error {
+	t.db.run("COMMIT", nil)
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.db.run("ROLLBACK", nil)
+	return nil
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
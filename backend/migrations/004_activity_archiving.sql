@@ -0,0 +1,12 @@
+-- Migration for automatic archiving of completed activities
+
+-- Add archived status to the activity status enum
+ALTER TYPE activity_status ADD VALUE IF NOT EXISTS 'archived';
+
+-- Track when an activity was archived
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+-- Index used by the archive scheduler to find completed activities
+CREATE INDEX IF NOT EXISTS idx_activities_status_end_date ON activities(status, end_date);
+CREATE INDEX IF NOT EXISTS idx_activities_archived_at ON activities(archived_at);
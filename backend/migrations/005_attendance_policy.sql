@@ -0,0 +1,5 @@
+-- Migration for the per-activity attendance policy
+
+-- Controls whether the first or the latest scan defines attendance time
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS attendance_policy VARCHAR(20) NOT NULL DEFAULT 'first_scan';
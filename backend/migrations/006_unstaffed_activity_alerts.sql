@@ -0,0 +1,8 @@
+-- Migration for unstaffed activity alerts
+
+-- Records when faculty admins were warned that an activity has no assigned scanner
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS unstaffed_alert_sent_at TIMESTAMP WITH TIME ZONE;
+
+-- Speeds up the scanner lookup used by the unstaffed activity check
+CREATE INDEX IF NOT EXISTS idx_activity_assignments_activity_scan ON activity_assignments(activity_id, can_scan_qr);
@@ -0,0 +1,8 @@
+-- Migration for activity reschedule handling
+
+-- Participants must confirm or withdraw after an activity's dates change
+ALTER TABLE participations
+    ADD COLUMN IF NOT EXISTS reschedule_pending BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS reschedule_notified_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_participations_reschedule_pending ON participations(reschedule_pending);
@@ -0,0 +1,14 @@
+-- Migration for activity keyword search
+
+-- Trigram matching covers Thai text, which is written without spaces between words
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Full-text index over title, description and location
+CREATE INDEX IF NOT EXISTS idx_activities_search_vector ON activities USING GIN (
+    to_tsvector('simple', (coalesce(activities.title, '') || ' ' || coalesce(activities.description, '') || ' ' || coalesce(activities.location, '')))
+);
+
+-- Trigram index for substring (ILIKE) matches over the same text
+CREATE INDEX IF NOT EXISTS idx_activities_search_trgm ON activities USING GIN (
+    (coalesce(activities.title, '') || ' ' || coalesce(activities.description, '') || ' ' || coalesce(activities.location, '')) gin_trgm_ops
+);
@@ -0,0 +1,8 @@
+-- Migration for optimistic concurrency control on admin edits
+
+-- Incremented on every update; clients send the version they read to detect conflicting edits
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- Migration for per-activity QR expiry overrides
+
+-- Minutes QR codes issued for the activity stay valid, e.g. for printed badges; NULL keeps the standard expiry
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS qr_expiry_minutes INTEGER;
@@ -0,0 +1,15 @@
+-- Migration for per-faculty feature flags
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id SERIAL PRIMARY KEY,
+    faculty_id INTEGER REFERENCES faculties(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    rollout_percentage INTEGER CHECK (rollout_percentage BETWEEN 0 AND 100),
+    updated_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One flag per faculty, plus one global flag (NULL faculty) per name
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feature_flags_scope ON feature_flags (COALESCE(faculty_id, 0), name);
@@ -0,0 +1,11 @@
+-- Migration for the persisted query allowlist
+
+CREATE TABLE IF NOT EXISTS persisted_queries (
+    id SERIAL PRIMARY KEY,
+    hash VARCHAR(64) NOT NULL,
+    name VARCHAR(100),
+    query TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_persisted_queries_hash ON persisted_queries (hash);
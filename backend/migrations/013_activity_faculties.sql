@@ -0,0 +1,10 @@
+-- Migration for activities co-hosted by several faculties
+
+-- Faculties co-hosting an activity besides activities.faculty_id; their admins may manage it
+CREATE TABLE IF NOT EXISTS activity_faculties (
+    activity_id INTEGER NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+    faculty_id INTEGER NOT NULL REFERENCES faculties(id) ON DELETE CASCADE,
+    PRIMARY KEY (activity_id, faculty_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_faculties_faculty_id ON activity_faculties(faculty_id);
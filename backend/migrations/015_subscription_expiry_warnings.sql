@@ -0,0 +1,5 @@
+-- Migration for scheduled subscription expiry warnings
+
+-- Smallest threshold (days before expiry) a realtime warning was published for; NULL until the first
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS expiry_warning_days INTEGER;
@@ -0,0 +1,12 @@
+-- Migration for reconciling QR scans accepted by offline scanners
+
+ALTER TABLE qr_scan_logs
+    ADD COLUMN IF NOT EXISTS offline_scan_id VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A resubmitted offline scan finds its earlier result instead of being counted again
+CREATE UNIQUE INDEX IF NOT EXISTS idx_qr_scan_logs_offline_scan
+    ON qr_scan_logs(activity_id, offline_scan_id) WHERE offline_scan_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_qr_scan_logs_needs_review
+    ON qr_scan_logs(activity_id) WHERE needs_review;
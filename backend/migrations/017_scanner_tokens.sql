@@ -0,0 +1,8 @@
+-- Migration for volunteer scanner tokens
+
+-- The token a scan was made with; scanned_by_id is then the admin who issued it
+ALTER TABLE qr_scan_logs
+    ADD COLUMN IF NOT EXISTS scanner_token_id VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_qr_scan_logs_scanner_token
+    ON qr_scan_logs(scanner_token_id) WHERE scanner_token_id IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Migration for activity time zones
+
+-- A faculty's zone for its new activities; NULL uses the institution's default
+ALTER TABLE faculties
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+-- The zone an activity is scheduled in. Start and end dates stay UTC instants; the zone is
+-- what they are displayed and repeated in.
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+UPDATE activities SET timezone = 'Asia/Bangkok' WHERE timezone IS NULL;
+
+ALTER TABLE activities
+    ALTER COLUMN timezone SET DEFAULT 'Asia/Bangkok',
+    ALTER COLUMN timezone SET NOT NULL;
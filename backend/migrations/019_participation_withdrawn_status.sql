@@ -0,0 +1,4 @@
+-- Migration for withdrawn participations
+
+-- Students leaving an activity they were approved for keep their participation as withdrawn
+ALTER TYPE participation_status ADD VALUE IF NOT EXISTS 'withdrawn';
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -15,21 +19,30 @@ import (
 type AuditLogger struct {
 	db          *gorm.DB
 	redisClient *redis.Client
+	masker      *DetailMasker
+
+	// Batching state, see StartBatching
+	batchMu sync.RWMutex
+	batch   BatchConfig
+	queue   chan *AuditEvent
+	flushed chan struct{}
+	closed  bool
 }
 
 // AuditEvent represents an audit log entry
 type AuditEvent struct {
 	ID            string                 `json:"id" gorm:"primaryKey"`
-	UserID        string                 `json:"user_id" gorm:"index"`
+	UserID        string                 `json:"user_id" gorm:"index;index:idx_audit_events_user_ip,priority:1"`
 	UserRole      string                 `json:"user_role"`
 	Action        string                 `json:"action" gorm:"index"`
 	Resource      string                 `json:"resource" gorm:"index"`
 	ResourceID    string                 `json:"resource_id" gorm:"index"`
 	FacultyID     string                 `json:"faculty_id" gorm:"index"`
-	Details       map[string]interface{} `json:"details" gorm:"type:jsonb"`
-	IPAddress     string                 `json:"ip_address"`
+	Details       map[string]interface{} `json:"details" gorm:"type:jsonb;serializer:json"`
+	// Indexes for checkBruteForce and checkUnusualAccess; see migrations/014_audit_hot_path_indexes.sql
+	IPAddress     string                 `json:"ip_address" gorm:"index:idx_audit_events_failed_logins,priority:1,where:action = 'LOGIN' AND success = false;index:idx_audit_events_user_ip,priority:2"`
 	UserAgent     string                 `json:"user_agent"`
-	Timestamp     time.Time              `json:"timestamp" gorm:"index"`
+	Timestamp     time.Time              `json:"timestamp" gorm:"index;index:idx_audit_events_failed_logins,priority:2;index:idx_audit_events_user_ip,priority:3"`
 	Success       bool                   `json:"success"`
 	ErrorMessage  string                 `json:"error_message,omitempty"`
 	SessionID     string                 `json:"session_id"`
@@ -43,9 +56,10 @@ type SecurityEvent struct {
 	ID            string                 `json:"id" gorm:"primaryKey"`
 	EventType     string                 `json:"event_type" gorm:"index"`
 	UserID        string                 `json:"user_id" gorm:"index"`
+	FacultyID     string                 `json:"faculty_id,omitempty" gorm:"index"`
 	IPAddress     string                 `json:"ip_address" gorm:"index"`
 	UserAgent     string                 `json:"user_agent"`
-	Details       map[string]interface{} `json:"details" gorm:"type:jsonb"`
+	Details       map[string]interface{} `json:"details" gorm:"type:jsonb;serializer:json"`
 	RiskLevel     string                 `json:"risk_level" gorm:"index"` // LOW, MEDIUM, HIGH, CRITICAL
 	Blocked       bool                   `json:"blocked"`
 	Timestamp     time.Time              `json:"timestamp" gorm:"index"`
@@ -62,7 +76,7 @@ type PerformanceMetric struct {
 	CacheHits     int                    `json:"cache_hits"`
 	CacheMisses   int                    `json:"cache_misses"`
 	UserID        string                 `json:"user_id" gorm:"index"`
-	Details       map[string]interface{} `json:"details" gorm:"type:jsonb"`
+	Details       map[string]interface{} `json:"details" gorm:"type:jsonb;serializer:json"`
 	Timestamp     time.Time              `json:"timestamp" gorm:"index"`
 	CreatedAt     time.Time              `json:"created_at"`
 }
@@ -70,14 +84,26 @@ type PerformanceMetric struct {
 // Constants for audit logging
 const (
 	// Actions
-	ActionCreate = "CREATE"
-	ActionRead   = "READ"
-	ActionUpdate = "UPDATE"
-	ActionDelete = "DELETE"
-	ActionLogin  = "LOGIN"
-	ActionLogout = "LOGOUT"
-	ActionScan   = "SCAN_QR"
-	ActionExport = "EXPORT"
+	ActionCreate  = "CREATE"
+	ActionRead    = "READ"
+	ActionUpdate  = "UPDATE"
+	ActionDelete  = "DELETE"
+	ActionRestore = "RESTORE"
+	ActionLogin   = "LOGIN"
+	ActionLogout  = "LOGOUT"
+	ActionScan    = "SCAN_QR"
+	ActionExport  = "EXPORT"
+	ActionImport  = "IMPORT"
+
+	// ActionTerminateSessions is an admin signing a user out of every session
+	ActionTerminateSessions = "TERMINATE_SESSIONS"
+
+	// ActionRebuildSearchIndex and ActionRecomputeMetrics start maintenance jobs
+	ActionRebuildSearchIndex = "REBUILD_SEARCH_INDEX"
+	ActionRecomputeMetrics   = "RECOMPUTE_METRICS"
+
+	// ActionMarkAttendanceManual is attendance marked by staff without a QR scan
+	ActionMarkAttendanceManual = "MARK_ATTENDANCE_MANUAL"
 	
 	// Resources
 	ResourceUser         = "USER"
@@ -88,6 +114,10 @@ const (
 	ResourceSubscription = "SUBSCRIPTION"
 	ResourceQRCode       = "QR_CODE"
 	ResourceReport       = "REPORT"
+	ResourceFeatureFlag  = "FEATURE_FLAG"
+	ResourceActivityTemplate = "ACTIVITY_TEMPLATE"
+	ResourceMaintenanceJob = "MAINTENANCE_JOB"
+	ResourceScannerToken = "SCANNER_TOKEN"
 	
 	// Severities
 	SeverityInfo     = "INFO"
@@ -110,6 +140,10 @@ const (
 	SecurityEventQRTampering         = "QR_TAMPERING"
 	SecurityEventBruteForce          = "BRUTE_FORCE"
 	SecurityEventPrivilegeEscalation = "PRIVILEGE_ESCALATION"
+	SecurityEventPasswordResetRequest = "PASSWORD_RESET_REQUEST"
+	SecurityEventPasswordReset        = "PASSWORD_RESET"
+	// A QR scan accepted without its Redis checks while Redis was unavailable
+	SecurityEventQRDegraded = "QR_DEGRADED_VALIDATION"
 	
 	// Risk Levels
 	RiskLevelLow      = "LOW"
@@ -123,6 +157,7 @@ func NewAuditLogger(db *gorm.DB, redisClient *redis.Client) *AuditLogger {
 	return &AuditLogger{
 		db:          db,
 		redisClient: redisClient,
+		masker:      NewDetailMasker(DefaultMaskingRules()),
 	}
 }
 
@@ -156,12 +191,24 @@ func (al *AuditLogger) LogEvent(ctx context.Context, event *AuditEvent) error {
 	if event.SessionID == "" {
 		event.SessionID = getSessionIDFromContext(ctx)
 	}
+	event.Details = al.masker.Mask(event.Details)
 	
+	// In async mode the flusher stores the event; a full queue falls back to a direct write
+	async := al.asyncWrites()
+	if async && al.enqueue(event) {
+		return nil
+	}
+
 	// Store in database
 	if err := al.db.WithContext(ctx).Create(event).Error; err != nil {
 		return fmt.Errorf("failed to store audit event: %v", err)
 	}
 	
+	// Redis monitoring and pattern checks are batched when the flusher is running
+	if !async && al.enqueue(event) {
+		return nil
+	}
+
 	// Also store in Redis for real-time monitoring
 	go al.storeInRedis(ctx, event)
 	
@@ -185,12 +232,16 @@ func (al *AuditLogger) LogSecurityEvent(ctx context.Context, event *SecurityEven
 	if event.UserID == "" {
 		event.UserID = getUserIDFromContext(ctx)
 	}
+	if event.FacultyID == "" {
+		event.FacultyID = getFacultyIDFromContext(ctx)
+	}
 	if event.IPAddress == "" {
 		event.IPAddress = getIPFromContext(ctx)
 	}
 	if event.UserAgent == "" {
 		event.UserAgent = getUserAgentFromContext(ctx)
 	}
+	event.Details = al.masker.Mask(event.Details)
 	
 	// Store in database
 	if err := al.db.WithContext(ctx).Create(event).Error; err != nil {
@@ -376,6 +427,56 @@ func (al *AuditLogger) GetAuditLogs(ctx context.Context, filters AuditFilters, l
 	return events, total, nil
 }
 
+// GetResourceAuditTrail retrieves the change history (create/update/delete) of a single resource,
+// newest first. One extra older event is returned when available so callers can diff the last entry.
+func (al *AuditLogger) GetResourceAuditTrail(ctx context.Context, resource, resourceID string, limit, offset int) ([]AuditEvent, int64, error) {
+	var events []AuditEvent
+	var total int64
+	
+	query := al.db.WithContext(ctx).Model(&AuditEvent{}).
+		Where("resource = ? AND resource_id = ?", resource, resourceID).
+		Where("action IN ?", []string{ActionCreate, ActionUpdate, ActionDelete})
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	if err := query.Order("timestamp DESC").Order("id DESC").Limit(limit + 1).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return events, total, nil
+}
+
+// DetailChange describes a single changed key between two audit event details
+type DetailChange struct {
+	Field    string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// DiffDetails compares the details of two audit events and returns the changed keys sorted by name
+func DiffDetails(previous, current map[string]interface{}) []DetailChange {
+	var changes []DetailChange
+	
+	for key, newValue := range current {
+		oldValue, exists := previous[key]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, DetailChange{Field: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, oldValue := range previous {
+		if _, exists := current[key]; !exists {
+			changes = append(changes, DetailChange{Field: key, OldValue: oldValue})
+		}
+	}
+	
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
 // GetSecurityEvents retrieves security events
 func (al *AuditLogger) GetSecurityEvents(ctx context.Context, filters SecurityFilters, limit, offset int) ([]SecurityEvent, int64, error) {
 	var events []SecurityEvent
@@ -506,25 +607,25 @@ func (al *AuditLogger) storeInRedis(ctx context.Context, event *AuditEvent) {
 	pipe := al.redisClient.Pipeline()
 	
 	// Add to recent events list
-	pipe.LPush(ctx, "audit:recent_events", eventData)
-	pipe.LTrim(ctx, "audit:recent_events", 0, 999) // Keep last 1000 events
-	pipe.Expire(ctx, "audit:recent_events", 24*time.Hour)
+	pipe.LPush(ctx, rediskeys.Key("audit:recent_events"), eventData)
+	pipe.LTrim(ctx, rediskeys.Key("audit:recent_events"), 0, 999) // Keep last 1000 events
+	pipe.Expire(ctx, rediskeys.Key("audit:recent_events"), 24*time.Hour)
 	
 	// Update counters
 	today := time.Now().Format("2006-01-02")
-	pipe.Incr(ctx, fmt.Sprintf("audit:daily_count:%s", today))
-	pipe.Incr(ctx, fmt.Sprintf("audit:action_count:%s:%s", event.Action, today))
-	pipe.Incr(ctx, fmt.Sprintf("audit:resource_count:%s:%s", event.Resource, today))
+	pipe.Incr(ctx, rediskeys.Keyf("audit:daily_count:%s", today))
+	pipe.Incr(ctx, rediskeys.Keyf("audit:action_count:%s:%s", event.Action, today))
+	pipe.Incr(ctx, rediskeys.Keyf("audit:resource_count:%s:%s", event.Resource, today))
 	
 	if !event.Success {
-		pipe.Incr(ctx, fmt.Sprintf("audit:failed_count:%s", today))
+		pipe.Incr(ctx, rediskeys.Keyf("audit:failed_count:%s", today))
 	}
 	
 	// Set expiry for counters
-	pipe.Expire(ctx, fmt.Sprintf("audit:daily_count:%s", today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("audit:action_count:%s:%s", event.Action, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("audit:resource_count:%s:%s", event.Resource, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("audit:failed_count:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("audit:daily_count:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("audit:action_count:%s:%s", event.Action, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("audit:resource_count:%s:%s", event.Resource, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("audit:failed_count:%s", today), 7*24*time.Hour)
 	
 	pipe.Exec(ctx)
 }
@@ -536,20 +637,20 @@ func (al *AuditLogger) storeSecurityEventInRedis(ctx context.Context, event *Sec
 	pipe := al.redisClient.Pipeline()
 	
 	// Add to security events list
-	pipe.LPush(ctx, "security:recent_events", eventData)
-	pipe.LTrim(ctx, "security:recent_events", 0, 499) // Keep last 500 events
-	pipe.Expire(ctx, "security:recent_events", 24*time.Hour)
+	pipe.LPush(ctx, rediskeys.Key(SecurityRecentEventsKey), eventData)
+	pipe.LTrim(ctx, rediskeys.Key(SecurityRecentEventsKey), 0, 499) // Keep last 500 events
+	pipe.Expire(ctx, rediskeys.Key(SecurityRecentEventsKey), 24*time.Hour)
 	
 	// Update security counters
 	today := time.Now().Format("2006-01-02")
-	pipe.Incr(ctx, fmt.Sprintf("security:daily_count:%s", today))
-	pipe.Incr(ctx, fmt.Sprintf("security:type_count:%s:%s", event.EventType, today))
-	pipe.Incr(ctx, fmt.Sprintf("security:risk_count:%s:%s", event.RiskLevel, today))
+	pipe.Incr(ctx, rediskeys.Keyf("security:daily_count:%s", today))
+	pipe.Incr(ctx, rediskeys.Keyf("security:type_count:%s:%s", event.EventType, today))
+	pipe.Incr(ctx, rediskeys.Keyf("security:risk_count:%s:%s", event.RiskLevel, today))
 	
 	// Set expiry for counters
-	pipe.Expire(ctx, fmt.Sprintf("security:daily_count:%s", today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("security:type_count:%s:%s", event.EventType, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("security:risk_count:%s:%s", event.RiskLevel, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("security:daily_count:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("security:type_count:%s:%s", event.EventType, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("security:risk_count:%s:%s", event.RiskLevel, today), 7*24*time.Hour)
 	
 	pipe.Exec(ctx)
 }
@@ -563,20 +664,20 @@ func (al *AuditLogger) updatePerformanceMetrics(ctx context.Context, metric *Per
 	hour := time.Now().Format("2006-01-02:15")
 	
 	// Update counters
-	pipe.Incr(ctx, fmt.Sprintf("perf:count:%s:%s", metric.Operation, today))
-	pipe.IncrBy(ctx, fmt.Sprintf("perf:duration:%s:%s", metric.Operation, today), metric.Duration)
-	pipe.IncrBy(ctx, fmt.Sprintf("perf:queries:%s:%s", metric.Operation, today), int64(metric.QueryCount))
+	pipe.Incr(ctx, rediskeys.Keyf("perf:count:%s:%s", metric.Operation, today))
+	pipe.IncrBy(ctx, rediskeys.Keyf("perf:duration:%s:%s", metric.Operation, today), metric.Duration)
+	pipe.IncrBy(ctx, rediskeys.Keyf("perf:queries:%s:%s", metric.Operation, today), int64(metric.QueryCount))
 	
 	// Hourly metrics for more granular analysis
-	pipe.Incr(ctx, fmt.Sprintf("perf:count:%s:%s", metric.Operation, hour))
-	pipe.IncrBy(ctx, fmt.Sprintf("perf:duration:%s:%s", metric.Operation, hour), metric.Duration)
+	pipe.Incr(ctx, rediskeys.Keyf("perf:count:%s:%s", metric.Operation, hour))
+	pipe.IncrBy(ctx, rediskeys.Keyf("perf:duration:%s:%s", metric.Operation, hour), metric.Duration)
 	
 	// Set expiry
-	pipe.Expire(ctx, fmt.Sprintf("perf:count:%s:%s", metric.Operation, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("perf:duration:%s:%s", metric.Operation, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("perf:queries:%s:%s", metric.Operation, today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("perf:count:%s:%s", metric.Operation, hour), 48*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("perf:duration:%s:%s", metric.Operation, hour), 48*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("perf:count:%s:%s", metric.Operation, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("perf:duration:%s:%s", metric.Operation, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("perf:queries:%s:%s", metric.Operation, today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("perf:count:%s:%s", metric.Operation, hour), 48*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("perf:duration:%s:%s", metric.Operation, hour), 48*time.Hour)
 	
 	pipe.Exec(ctx)
 }
@@ -606,10 +707,12 @@ func (al *AuditLogger) checkBruteForce(ctx context.Context, event *AuditEvent) {
 	// Count failed login attempts from the same IP in the last hour
 	oneHourAgo := time.Now().Add(-time.Hour)
 	
+	// action and success stay literals so the partial index idx_audit_events_failed_logins
+	// matches; bound as parameters, a generic plan would scan the table instead
 	var failedAttempts int64
 	al.db.WithContext(ctx).Model(&AuditEvent{}).
-		Where("action = ? AND success = false AND ip_address = ? AND timestamp > ?", 
-			ActionLogin, event.IPAddress, oneHourAgo).
+		Where("action = '"+ActionLogin+"' AND success = false AND ip_address = ? AND timestamp > ?",
+			event.IPAddress, oneHourAgo).
 		Count(&failedAttempts)
 	
 	if failedAttempts >= 5 { // Threshold for brute force
@@ -627,12 +730,14 @@ func (al *AuditLogger) checkBruteForce(ctx context.Context, event *AuditEvent) {
 
 // checkPrivilegeEscalation checks for privilege escalation attempts
 func (al *AuditLogger) checkPrivilegeEscalation(ctx context.Context, event *AuditEvent) {
-	// Check if a non-admin user is trying to modify admin privileges
+	// Flag admin roles granted by students, and faculty or super admin granted by anyone but a super admin
 	if details, ok := event.Details["role_changes"]; ok {
 		if roleChanges, ok := details.(map[string]interface{}); ok {
 			if newRole, exists := roleChanges["new_role"]; exists {
-				if strings.Contains(fmt.Sprintf("%v", newRole), "ADMIN") && 
-				   event.UserRole == "STUDENT" {
+				granted := strings.ToLower(fmt.Sprintf("%v", newRole))
+				actor := strings.ToLower(event.UserRole)
+				if (strings.Contains(granted, "admin") && actor == "student") ||
+					((granted == "super_admin" || granted == "faculty_admin") && actor != "super_admin") {
 					securityEvent := &SecurityEvent{
 						EventType: SecurityEventPrivilegeEscalation,
 						Details:   event.Details,
@@ -653,6 +758,7 @@ func (al *AuditLogger) checkUnusualAccess(ctx context.Context, event *AuditEvent
 	// Count access attempts from this IP in the last 24 hours
 	yesterday := time.Now().Add(-24 * time.Hour)
 	
+	// Served by idx_audit_events_user_ip
 	var accessCount int64
 	al.db.WithContext(ctx).Model(&AuditEvent{}).
 		Where("user_id = ? AND ip_address = ? AND timestamp > ?", 
@@ -681,7 +787,9 @@ func (al *AuditLogger) triggerSecurityAlert(ctx context.Context, event *Security
 		"event_type": event.EventType,
 		"risk_level": event.RiskLevel,
 		"user_id":    event.UserID,
+		"faculty_id": event.FacultyID,
 		"ip_address": event.IPAddress,
+		"blocked":    event.Blocked,
 		"timestamp":  event.Timestamp.Unix(),
 		"details":    event.Details,
 	}
@@ -689,11 +797,11 @@ func (al *AuditLogger) triggerSecurityAlert(ctx context.Context, event *Security
 	alertJSON, _ := json.Marshal(alertData)
 	
 	// Add to alerts queue
-	al.redisClient.LPush(ctx, "security:alerts", alertJSON)
-	al.redisClient.Expire(ctx, "security:alerts", 24*time.Hour)
+	al.redisClient.LPush(ctx, rediskeys.Key(SecurityAlertsKey), alertJSON)
+	al.redisClient.Expire(ctx, rediskeys.Key(SecurityAlertsKey), 24*time.Hour)
 	
 	// Publish to real-time notification system
-	al.redisClient.Publish(ctx, "security_alerts", alertJSON)
+	al.redisClient.Publish(ctx, rediskeys.Key(SecurityAlertsChannel), alertJSON)
 	
 	fmt.Printf("SECURITY ALERT: %s - Risk Level: %s - User: %s - IP: %s\n", 
 		event.EventType, event.RiskLevel, event.UserID, event.IPAddress)
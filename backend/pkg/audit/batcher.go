@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+)
+
+// BatchConfig controls how audit events are buffered before they are flushed
+type BatchConfig struct {
+	// Size flushes once this many events are buffered
+	Size int
+	// FlushInterval flushes whatever is buffered at least this often
+	FlushInterval time.Duration
+	// QueueSize bounds the number of events waiting for the flusher
+	QueueSize int
+	// AsyncWrites moves the database insert into the batch as well. LogEvent then
+	// returns before the event is stored and cannot report write errors; call Close
+	// on shutdown so buffered events are not lost.
+	AsyncWrites bool
+}
+
+// StartBatching routes Redis monitoring updates (and, in async mode, database writes)
+// through a single background flusher instead of a goroutine per event
+func (al *AuditLogger) StartBatching(cfg BatchConfig) {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 500 * time.Millisecond
+	}
+	if cfg.QueueSize < cfg.Size {
+		cfg.QueueSize = cfg.Size * 10
+	}
+
+	al.batchMu.Lock()
+	defer al.batchMu.Unlock()
+	if al.queue != nil {
+		return
+	}
+
+	al.batch = cfg
+	al.queue = make(chan *AuditEvent, cfg.QueueSize)
+	al.flushed = make(chan struct{})
+	go al.runFlusher()
+}
+
+// Close stops the flusher after writing every buffered event
+func (al *AuditLogger) Close() {
+	al.batchMu.Lock()
+	if al.queue == nil || al.closed {
+		al.batchMu.Unlock()
+		return
+	}
+	al.closed = true
+	close(al.queue)
+	al.batchMu.Unlock()
+
+	<-al.flushed
+}
+
+// enqueue hands an event to the flusher. It returns false when batching is off
+// or the queue is full, leaving the caller to handle the event directly.
+func (al *AuditLogger) enqueue(event *AuditEvent) bool {
+	al.batchMu.RLock()
+	defer al.batchMu.RUnlock()
+
+	if al.queue == nil || al.closed {
+		return false
+	}
+
+	select {
+	case al.queue <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+func (al *AuditLogger) asyncWrites() bool {
+	al.batchMu.RLock()
+	defer al.batchMu.RUnlock()
+	return al.queue != nil && !al.closed && al.batch.AsyncWrites
+}
+
+func (al *AuditLogger) runFlusher() {
+	defer close(al.flushed)
+
+	ticker := time.NewTicker(al.batch.FlushInterval)
+	defer ticker.Stop()
+
+	pending := make([]*AuditEvent, 0, al.batch.Size)
+	for {
+		select {
+		case event, ok := <-al.queue:
+			if !ok {
+				al.flush(pending)
+				return
+			}
+			pending = append(pending, event)
+			if len(pending) >= al.batch.Size {
+				al.flush(pending)
+				pending = make([]*AuditEvent, 0, al.batch.Size)
+			}
+		case <-ticker.C:
+			if len(pending) > 0 {
+				al.flush(pending)
+				pending = make([]*AuditEvent, 0, al.batch.Size)
+			}
+		}
+	}
+}
+
+// flush writes one batch with a multi-row insert (async mode) and a single Redis pipeline
+func (al *AuditLogger) flush(events []*AuditEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ctx := context.Background()
+
+	if al.batch.AsyncWrites {
+		if err := al.db.WithContext(ctx).CreateInBatches(events, len(events)).Error; err != nil {
+			log.Printf("Failed to store %d audit events: %v", len(events), err)
+		}
+	}
+
+	al.storeBatchInRedis(ctx, events)
+
+	for _, event := range events {
+		al.analyzeSecurityPatterns(ctx, event)
+	}
+}
+
+// storeBatchInRedis is the batched form of storeInRedis; counters are summed per key
+func (al *AuditLogger) storeBatchInRedis(ctx context.Context, events []*AuditEvent) {
+	today := time.Now().Format("2006-01-02")
+	counters := make(map[string]int64)
+	eventData := make([]interface{}, 0, len(events))
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		eventData = append(eventData, data)
+
+		counters[rediskeys.Keyf("audit:daily_count:%s", today)]++
+		counters[rediskeys.Keyf("audit:action_count:%s:%s", event.Action, today)]++
+		counters[rediskeys.Keyf("audit:resource_count:%s:%s", event.Resource, today)]++
+		if !event.Success {
+			counters[rediskeys.Keyf("audit:failed_count:%s", today)]++
+		}
+	}
+
+	pipe := al.redisClient.Pipeline()
+
+	if len(eventData) > 0 {
+		pipe.LPush(ctx, rediskeys.Key("audit:recent_events"), eventData...)
+		pipe.LTrim(ctx, rediskeys.Key("audit:recent_events"), 0, 999) // Keep last 1000 events
+		pipe.Expire(ctx, rediskeys.Key("audit:recent_events"), 24*time.Hour)
+	}
+
+	for key, count := range counters {
+		pipe.IncrBy(ctx, key, count)
+		pipe.Expire(ctx, key, 7*24*time.Hour)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to store %d audit events in Redis: %v", len(events), err)
+	}
+}
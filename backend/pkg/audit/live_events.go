@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+)
+
+const (
+	// Redis Keys
+	SecurityRecentEventsKey = "security:recent_events"
+	SecurityAlertsKey       = "security:alerts"
+	SecurityAlertsChannel   = "security_alerts"
+
+	MaxLiveSecurityEvents = 500
+)
+
+// LiveSecurityEvent is a security event read back from Redis. Alert is set for high-risk
+// events that were also queued as alerts.
+type LiveSecurityEvent struct {
+	SecurityEvent
+	Alert bool `json:"alert"`
+}
+
+// securityAlert is the payload triggerSecurityAlert queues and publishes
+type securityAlert struct {
+	EventID   string                 `json:"event_id"`
+	EventType string                 `json:"event_type"`
+	RiskLevel string                 `json:"risk_level"`
+	UserID    string                 `json:"user_id"`
+	FacultyID string                 `json:"faculty_id"`
+	IPAddress string                 `json:"ip_address"`
+	Blocked   bool                   `json:"blocked"`
+	Timestamp int64                  `json:"timestamp"`
+	Details   map[string]interface{} `json:"details"`
+}
+
+func (a *securityAlert) event() *LiveSecurityEvent {
+	return &LiveSecurityEvent{
+		SecurityEvent: SecurityEvent{
+			ID:        a.EventID,
+			EventType: a.EventType,
+			UserID:    a.UserID,
+			FacultyID: a.FacultyID,
+			IPAddress: a.IPAddress,
+			Details:   a.Details,
+			RiskLevel: a.RiskLevel,
+			Blocked:   a.Blocked,
+			Timestamp: time.Unix(a.Timestamp, 0),
+		},
+		Alert: true,
+	}
+}
+
+// GetLiveSecurityEvents merges the recent security events and the alert queue kept in Redis,
+// newest first. Unlike GetSecurityEvents it does not touch the database.
+func (al *AuditLogger) GetLiveSecurityEvents(ctx context.Context, limit int) ([]*LiveSecurityEvent, error) {
+	if limit <= 0 || limit > MaxLiveSecurityEvents {
+		limit = MaxLiveSecurityEvents
+	}
+
+	pipe := al.redisClient.Pipeline()
+	recentCmd := pipe.LRange(ctx, rediskeys.Key(SecurityRecentEventsKey), 0, int64(limit-1))
+	alertsCmd := pipe.LRange(ctx, rediskeys.Key(SecurityAlertsKey), 0, int64(limit-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read live security events: %v", err)
+	}
+
+	byID := make(map[string]*LiveSecurityEvent)
+	events := make([]*LiveSecurityEvent, 0, len(recentCmd.Val()))
+	for _, raw := range recentCmd.Val() {
+		var event LiveSecurityEvent
+		if err := json.Unmarshal([]byte(raw), &event.SecurityEvent); err != nil {
+			continue
+		}
+		if _, seen := byID[event.ID]; seen {
+			continue
+		}
+		byID[event.ID] = &event
+		events = append(events, &event)
+	}
+
+	// Alerts repeat high-risk events; they only add events already trimmed from the recent list
+	for _, raw := range alertsCmd.Val() {
+		var alert securityAlert
+		if err := json.Unmarshal([]byte(raw), &alert); err != nil {
+			continue
+		}
+		if event, ok := byID[alert.EventID]; ok {
+			event.Alert = true
+			continue
+		}
+		event := alert.event()
+		byID[event.ID] = event
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// SubscribeSecurityAlerts streams alerts published on the security_alerts channel until ctx is done
+func (al *AuditLogger) SubscribeSecurityAlerts(ctx context.Context) (<-chan *LiveSecurityEvent, error) {
+	pubsub := al.redisClient.Subscribe(ctx, rediskeys.Key(SecurityAlertsChannel))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to security alerts: %v", err)
+	}
+
+	events := make(chan *LiveSecurityEvent)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var alert securityAlert
+				if err := json.Unmarshal([]byte(msg.Payload), &alert); err != nil {
+					log.Printf("Skipping malformed security alert: %v", err)
+					continue
+				}
+				select {
+				case events <- alert.event():
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// MaskHash replaces a value with a keyed hash, so events about the same value can still be correlated
+	MaskHash = "hash"
+	// MaskRedact replaces a value with RedactedValue
+	MaskRedact = "redact"
+
+	RedactedValue = "[REDACTED]"
+	// maskedHashPrefix marks hashed values; the hash is truncated to 16 hex characters
+	maskedHashPrefix = "hmac:"
+)
+
+// MaskingRules names the detail keys to mask. Patterns match keys case-insensitively at any
+// depth of the details and may use * wildcards, e.g. "*_token". Redaction wins when a key
+// matches both lists.
+type MaskingRules struct {
+	HashKeys   []string
+	RedactKeys []string
+	// HashSecret keys the hash, so a hashed email can't be found by hashing a list of emails
+	HashSecret string
+}
+
+// DefaultMaskingRules hashes contact details and identifiers, and redacts credentials
+func DefaultMaskingRules() MaskingRules {
+	return MaskingRules{
+		HashKeys:   []string{"email", "*_email", "student_id"},
+		RedactKeys: []string{"password", "*_password", "password_*", "secret", "*_secret", "token", "*_token", "authorization", "cookie"},
+	}
+}
+
+// DetailMasker applies masking rules to event details before they are stored
+type DetailMasker struct {
+	hashKeys   []string
+	redactKeys []string
+	secret     []byte
+}
+
+// NewDetailMasker compiles rules into a masker
+func NewDetailMasker(rules MaskingRules) *DetailMasker {
+	lower := func(patterns []string) []string {
+		var out []string
+		for _, pattern := range patterns {
+			if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+				out = append(out, pattern)
+			}
+		}
+		return out
+	}
+	return &DetailMasker{
+		hashKeys:   lower(rules.HashKeys),
+		redactKeys: lower(rules.RedactKeys),
+		secret:     []byte(rules.HashSecret),
+	}
+}
+
+// SetMasking replaces the rules used to mask event details; a nil masker stores details as given
+func (al *AuditLogger) SetMasking(masker *DetailMasker) {
+	al.masker = masker
+}
+
+// Mask returns a copy of details with sensitive keys masked. The original map is not changed,
+// since callers may still use it.
+func (dm *DetailMasker) Mask(details map[string]interface{}) map[string]interface{} {
+	if dm == nil || details == nil {
+		return details
+	}
+	return dm.maskMap(details)
+}
+
+func (dm *DetailMasker) maskMap(details map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(details))
+	for key, value := range details {
+		switch dm.actionFor(key) {
+		case MaskRedact:
+			masked[key] = RedactedValue
+		case MaskHash:
+			masked[key] = dm.hashValue(value)
+		default:
+			masked[key] = dm.maskValue(value)
+		}
+	}
+	return masked
+}
+
+// maskValue looks for sensitive keys inside nested details
+func (dm *DetailMasker) maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return dm.maskMap(v)
+	case []map[string]interface{}:
+		masked := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = dm.maskMap(item)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = dm.maskValue(item)
+		}
+		return masked
+	default:
+		return value
+	}
+}
+
+func (dm *DetailMasker) actionFor(key string) string {
+	key = strings.ToLower(key)
+	if matchesAny(dm.redactKeys, key) {
+		return MaskRedact
+	}
+	if matchesAny(dm.hashKeys, key) {
+		return MaskHash
+	}
+	return ""
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashValue hashes a value for correlation. Strings are trimmed and lowercased first, so
+// "Ann@Example.com" and "ann@example.com" hash alike; lists hash element by element.
+func (dm *DetailMasker) hashValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return v
+		}
+		return dm.hashString(v)
+	case []string:
+		hashed := make([]string, len(v))
+		for i, item := range v {
+			hashed[i] = dm.hashString(item)
+		}
+		return hashed
+	case []interface{}:
+		hashed := make([]interface{}, len(v))
+		for i, item := range v {
+			hashed[i] = dm.hashValue(item)
+		}
+		return hashed
+	default:
+		return dm.hashString(fmt.Sprint(v))
+	}
+}
+
+func (dm *DetailMasker) hashString(value string) string {
+	// Details copied from an event that was already masked keep their hashes
+	if strings.HasPrefix(value, maskedHashPrefix) || value == RedactedValue {
+		return value
+	}
+	mac := hmac.New(sha256.New, dm.secret)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return maskedHashPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDetailMaskerMasksSensitiveKeys(t *testing.T) {
+	masker := NewDetailMasker(MaskingRules{
+		HashKeys:   DefaultMaskingRules().HashKeys,
+		RedactKeys: DefaultMaskingRules().RedactKeys,
+		HashSecret: "audit-secret",
+	})
+	details := map[string]interface{}{
+		"Email":         "Ann@Example.com",
+		"student_id":    "6400123",
+		"new_password":  "hunter2",
+		"refresh_token": "eyJhbGciOi",
+		"title":         "Open day",
+		"changes": map[string]interface{}{
+			"contact_email": "ann@example.com",
+			"Authorization": "Bearer eyJhbGciOi",
+		},
+		"participants": []interface{}{
+			map[string]interface{}{"student_id": "6400124", "status": "approved"},
+		},
+	}
+
+	masked := masker.Mask(details)
+
+	for _, key := range []string{"new_password", "refresh_token"} {
+		if masked[key] != RedactedValue {
+			t.Errorf("%s = %v, want it redacted", key, masked[key])
+		}
+	}
+	if masked["title"] != "Open day" {
+		t.Errorf("title = %v, want it kept", masked["title"])
+	}
+
+	email, _ := masked["Email"].(string)
+	studentID, _ := masked["student_id"].(string)
+	for key, value := range map[string]string{"Email": email, "student_id": studentID} {
+		if !strings.HasPrefix(value, maskedHashPrefix) || len(value) != len(maskedHashPrefix)+16 {
+			t.Errorf("%s = %q, want a truncated keyed hash", key, value)
+		}
+	}
+
+	// Nested details are masked too, and the same email hashes alike whatever its case
+	changes := masked["changes"].(map[string]interface{})
+	if changes["contact_email"] != email {
+		t.Errorf("nested contact_email = %v, want the hash of Email %s", changes["contact_email"], email)
+	}
+	if changes["Authorization"] != RedactedValue {
+		t.Errorf("nested Authorization = %v, want it redacted", changes["Authorization"])
+	}
+	participant := masked["participants"].([]interface{})[0].(map[string]interface{})
+	if participant["student_id"] == "6400124" || participant["status"] != "approved" {
+		t.Errorf("participant = %v, want only student_id masked", participant)
+	}
+
+	// The caller's map is left as given
+	if details["Email"] != "Ann@Example.com" || details["changes"].(map[string]interface{})["Authorization"] != "Bearer eyJhbGciOi" {
+		t.Errorf("Mask changed the original details: %v", details)
+	}
+
+	// Masking again keeps the hashes, and another secret gives other hashes
+	if again := masker.Mask(masked); again["Email"] != email {
+		t.Errorf("masking a masked email gave %v, want %s", again["Email"], email)
+	}
+	other := NewDetailMasker(MaskingRules{HashKeys: []string{"email"}, HashSecret: "other-secret"})
+	if got := other.Mask(details)["Email"]; got == email {
+		t.Errorf("hash with another secret = %v, want it to differ", got)
+	}
+}
+
+func TestDetailMaskerRedactionWinsOverHashing(t *testing.T) {
+	masker := NewDetailMasker(MaskingRules{HashKeys: []string{"*_id"}, RedactKeys: []string{"session_*"}})
+	masked := masker.Mask(map[string]interface{}{"session_id": "abc", "user_id": 7})
+
+	if masked["session_id"] != RedactedValue {
+		t.Errorf("session_id = %v, want it redacted", masked["session_id"])
+	}
+	if id, _ := masked["user_id"].(string); !strings.HasPrefix(id, maskedHashPrefix) {
+		t.Errorf("user_id = %v, want it hashed", masked["user_id"])
+	}
+
+	var none *DetailMasker
+	if got := none.Mask(map[string]interface{}{"password": "hunter2"}); got["password"] != "hunter2" {
+		t.Errorf("nil masker changed details: %v", got)
+	}
+}
+
+func TestLogEventStoresMaskedDetails(t *testing.T) {
+	var stored []string
+	db, _ := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.HasPrefix(query, `INSERT INTO "audit_events"`) {
+			for _, arg := range args {
+				stored = append(stored, fmt.Sprint(arg.Value))
+			}
+			return fakedb.Result{RowsAffected: 1}
+		}
+		return fakedb.Result{}
+	})
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	logger := NewAuditLogger(db, client)
+	details := map[string]interface{}{"email": "ann@example.com", "password": "hunter2", "title": "Open day"}
+	if err := logger.LogEvent(context.Background(), &AuditEvent{Action: ActionUpdate, Resource: ResourceUser, Details: details, Success: true}); err != nil {
+		t.Fatalf("LogEvent: %v", err)
+	}
+
+	row := strings.Join(stored, " ")
+	if row == "" {
+		t.Fatal("no audit event was stored")
+	}
+	for _, secret := range []string{"ann@example.com", "hunter2"} {
+		if strings.Contains(row, secret) {
+			t.Errorf("stored audit event contains %q: %s", secret, row)
+		}
+	}
+	if !strings.Contains(row, RedactedValue) || !strings.Contains(row, maskedHashPrefix) || !strings.Contains(row, "Open day") {
+		t.Errorf("stored details = %s, want the password redacted, the email hashed and the title kept", row)
+	}
+}
@@ -1,41 +1,87 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// maxOrgNameLength caps embedded faculty and department names so tokens stay header sized
+const maxOrgNameLength = 64
+
 type JWTClaims struct {
-	UserID       uint   `json:"user_id"`
-	Email        string `json:"email"`
-	Role         string `json:"role"`
-	FacultyID    *uint  `json:"faculty_id,omitempty"`
-	DepartmentID *uint  `json:"department_id,omitempty"`
+	UserID       uint      `json:"user_id"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	FacultyID    *uint     `json:"faculty_id,omitempty"`
+	DepartmentID *uint     `json:"department_id,omitempty"`
+	Faculty      *OrgClaim `json:"faculty,omitempty"`
+	Department   *OrgClaim `json:"department,omitempty"`
+	// Epoch is the user's session epoch when the token was issued; signing out all sessions bumps it
+	Epoch int64 `json:"epoch,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// OrgClaim identifies a faculty or department without a database lookup
+type OrgClaim struct {
+	ID   uint   `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+// ClaimOptions chooses which optional claims are embedded in issued tokens
+type ClaimOptions struct {
+	OrgCodes bool
+	OrgNames bool // only takes effect together with OrgCodes
+}
+
+// TokenSubject is who a token is issued to. Faculty and Department are embedded
+// according to the service's ClaimOptions.
+type TokenSubject struct {
+	UserID       uint
+	Email        string
+	Role         string
+	FacultyID    *uint
+	DepartmentID *uint
+	Faculty      *OrgClaim
+	Department   *OrgClaim
+	Epoch        int64
+}
+
 type JWTService struct {
-	secretKey      string
-	expireHours    int
+	secretKey   string
+	expireHours int
+	options     ClaimOptions
 }
 
-func NewJWTService(secretKey string, expireHours int) *JWTService {
+func NewJWTService(secretKey string, expireHours int, options ClaimOptions) *JWTService {
 	return &JWTService{
 		secretKey:   secretKey,
 		expireHours: expireHours,
+		options:     options,
 	}
 }
 
-func (j *JWTService) GenerateToken(userID uint, email, role string, facultyID, departmentID *uint) (string, error) {
+func (j *JWTService) GenerateToken(subject TokenSubject) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := JWTClaims{
-		UserID:       userID,
-		Email:        email,
-		Role:         role,
-		FacultyID:    facultyID,
-		DepartmentID: departmentID,
+		UserID:       subject.UserID,
+		Email:        subject.Email,
+		Role:         subject.Role,
+		FacultyID:    subject.FacultyID,
+		DepartmentID: subject.DepartmentID,
+		Faculty:      j.orgClaim(subject.Faculty),
+		Department:   j.orgClaim(subject.Department),
+		Epoch:        subject.Epoch,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(j.expireHours))),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "tru-activity",
@@ -46,6 +92,30 @@ func (j *JWTService) GenerateToken(userID uint, email, role string, facultyID, d
 	return token.SignedString([]byte(j.secretKey))
 }
 
+func (j *JWTService) orgClaim(org *OrgClaim) *OrgClaim {
+	if org == nil || !j.options.OrgCodes {
+		return nil
+	}
+
+	claim := &OrgClaim{ID: org.ID, Code: org.Code}
+	if j.options.OrgNames {
+		name := []rune(org.Name)
+		if len(name) > maxOrgNameLength {
+			name = name[:maxOrgNameLength]
+		}
+		claim.Name = string(name)
+	}
+	return claim
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -72,5 +142,14 @@ func (j *JWTService) RefreshToken(tokenString string) (string, error) {
 	}
 
 	// Generate new token with same claims but updated expiry
-	return j.GenerateToken(claims.UserID, claims.Email, claims.Role, claims.FacultyID, claims.DepartmentID)
-}
\ No newline at end of file
+	return j.GenerateToken(TokenSubject{
+		UserID:       claims.UserID,
+		Email:        claims.Email,
+		Role:         claims.Role,
+		FacultyID:    claims.FacultyID,
+		DepartmentID: claims.DepartmentID,
+		Faculty:      claims.Faculty,
+		Department:   claims.Department,
+		Epoch:        claims.Epoch,
+	})
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis Keys
+	PasswordResetTokenKey = "password_reset:"
+	PasswordResetUserKey  = "password_reset_user:"
+	PasswordResetLimitKey = "password_reset_limit:"
+
+	PasswordResetTokenTTL      = 30 * time.Minute
+	InviteTokenTTL             = 7 * 24 * time.Hour
+	PasswordResetLimitWindow   = time.Hour
+	PasswordResetLimitPerEmail = 3
+	PasswordResetLimitPerIP    = 10
+)
+
+// PasswordResetStore issues and validates single-use password reset tokens.
+// Only the SHA-256 hash of a token is stored so a Redis dump can't be used to reset passwords.
+type PasswordResetStore struct {
+	redisClient *redis.Client
+}
+
+func NewPasswordResetStore(redisClient *redis.Client) *PasswordResetStore {
+	return &PasswordResetStore{
+		redisClient: redisClient,
+	}
+}
+
+// CreateToken generates a new reset token for the user, replacing any outstanding one
+func (p *PasswordResetStore) CreateToken(ctx context.Context, userID uint) (string, error) {
+	return p.createToken(ctx, userID, PasswordResetTokenTTL)
+}
+
+// CreateInviteToken generates a reset token for a newly created account to set its first
+// password with. It lasts longer than a reset token, since the user didn't ask for it.
+func (p *PasswordResetStore) CreateInviteToken(ctx context.Context, userID uint) (string, error) {
+	return p.createToken(ctx, userID, InviteTokenTTL)
+}
+
+func (p *PasswordResetStore) createToken(ctx context.Context, userID uint, ttl time.Duration) (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate reset token: %v", err)
+	}
+	token := hex.EncodeToString(bytes)
+	tokenHash := hashResetToken(token)
+
+	userKey := rediskeys.Keyf("%s%d", PasswordResetUserKey, userID)
+
+	// Invalidate the previous token so only the latest email works
+	if previous, err := p.redisClient.Get(ctx, userKey).Result(); err == nil {
+		p.redisClient.Del(ctx, rediskeys.Key(PasswordResetTokenKey+previous))
+	}
+
+	pipe := p.redisClient.TxPipeline()
+	pipe.Set(ctx, rediskeys.Key(PasswordResetTokenKey+tokenHash), userID, ttl)
+	pipe.Set(ctx, userKey, tokenHash, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store reset token: %v", err)
+	}
+
+	return token, nil
+}
+
+// ConsumeToken validates the token and deletes it, returning the user it was issued to
+func (p *PasswordResetStore) ConsumeToken(ctx context.Context, token string) (uint, error) {
+	tokenHash := hashResetToken(token)
+	tokenKey := rediskeys.Key(PasswordResetTokenKey + tokenHash)
+
+	value, err := p.redisClient.GetDel(ctx, tokenKey).Result()
+	if err == redis.Nil {
+		return 0, fmt.Errorf("invalid or expired reset token")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to validate reset token: %v", err)
+	}
+
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid or expired reset token")
+	}
+
+	p.redisClient.Del(ctx, rediskeys.Keyf("%s%d", PasswordResetUserKey, userID))
+	return uint(userID), nil
+}
+
+// AllowRequest counts a reset request against the given scope (e.g. "email:x" or "ip:y")
+func (p *PasswordResetStore) AllowRequest(ctx context.Context, scope string, limit int) (bool, error) {
+	key := rediskeys.Key(PasswordResetLimitKey + scope)
+
+	pipe := p.redisClient.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, PasswordResetLimitWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to check reset rate limit: %v", err)
+	}
+
+	return incr.Val() <= int64(limit), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis Keys
+	ScannerTokenKey         = "scanner_token:"
+	ScannerTokenActivityKey = "scanner_tokens_activity:"
+
+	DefaultScannerTokenTTL = 8 * time.Hour
+	MinScannerTokenTTL     = 5 * time.Minute
+	MaxScannerTokenTTL     = 24 * time.Hour
+)
+
+// ErrInvalidScannerToken is returned for a scanner token that is malformed, expired or revoked
+var ErrInvalidScannerToken = errors.New("invalid or expired scanner token")
+
+// ScannerToken lets its holder scan QR codes for one activity, without an admin account.
+// The token itself is "<id>.<secret>"; only the SHA-256 hash of the secret is stored.
+type ScannerToken struct {
+	ID         string    `json:"id"`
+	ActivityID uint      `json:"activity_id"`
+	IssuedByID uint      `json:"issued_by_id"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	SecretHash string    `json:"secret_hash"`
+}
+
+// ScannerTokenStore issues, validates and revokes scanner tokens. A token lasts until it
+// expires or is revoked.
+type ScannerTokenStore struct {
+	redisClient *redis.Client
+}
+
+func NewScannerTokenStore(redisClient *redis.Client) *ScannerTokenStore {
+	return &ScannerTokenStore{
+		redisClient: redisClient,
+	}
+}
+
+// Issue creates a token for activityID lasting ttl, and returns it with its record
+func (s *ScannerTokenStore) Issue(ctx context.Context, activityID, issuedByID uint, ttl time.Duration) (string, *ScannerToken, error) {
+	if ttl < MinScannerTokenTTL || ttl > MaxScannerTokenTTL {
+		return "", nil, fmt.Errorf("scanner tokens must last between %v and %v", MinScannerTokenTTL, MaxScannerTokenTTL)
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate scanner token: %v", err)
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate scanner token: %v", err)
+	}
+
+	now := time.Now()
+	token := &ScannerToken{
+		ID:         id,
+		ActivityID: activityID,
+		IssuedByID: issuedByID,
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(ttl),
+		SecretHash: hashResetToken(secret),
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode scanner token: %v", err)
+	}
+
+	activityKey := rediskeys.Keyf("%s%d", ScannerTokenActivityKey, activityID)
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, rediskeys.Key(ScannerTokenKey+id), data, ttl)
+	pipe.SAdd(ctx, activityKey, id)
+	// No token outlives the index, however long the tokens issued before it last
+	pipe.Expire(ctx, activityKey, MaxScannerTokenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", nil, fmt.Errorf("failed to store scanner token: %v", err)
+	}
+
+	return id + "." + secret, token, nil
+}
+
+// Validate returns the record of a presented token, or ErrInvalidScannerToken
+func (s *ScannerTokenStore) Validate(ctx context.Context, presented string) (*ScannerToken, error) {
+	id, secret, ok := strings.Cut(presented, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidScannerToken
+	}
+
+	token, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || subtle.ConstantTimeCompare([]byte(token.SecretHash), []byte(hashResetToken(secret))) != 1 {
+		return nil, ErrInvalidScannerToken
+	}
+	return token, nil
+}
+
+// Get returns an unexpired, unrevoked token by ID, or nil when there is none
+func (s *ScannerTokenStore) Get(ctx context.Context, id string) (*ScannerToken, error) {
+	data, err := s.redisClient.Get(ctx, rediskeys.Key(ScannerTokenKey+id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scanner token: %v", err)
+	}
+
+	var token ScannerToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode scanner token: %v", err)
+	}
+	return &token, nil
+}
+
+// ListForActivity returns the activity's unexpired, unrevoked tokens, oldest first
+func (s *ScannerTokenStore) ListForActivity(ctx context.Context, activityID uint) ([]*ScannerToken, error) {
+	activityKey := rediskeys.Keyf("%s%d", ScannerTokenActivityKey, activityID)
+	ids, err := s.redisClient.SMembers(ctx, activityKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scanner tokens: %v", err)
+	}
+
+	tokens := make([]*ScannerToken, 0, len(ids))
+	for _, id := range ids {
+		token, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			// Expired; drop it from the index
+			s.redisClient.SRem(ctx, activityKey, id)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].IssuedAt.Before(tokens[j].IssuedAt) })
+	return tokens, nil
+}
+
+// Revoke deletes a token, so it stops working immediately
+func (s *ScannerTokenStore) Revoke(ctx context.Context, token *ScannerToken) error {
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, rediskeys.Key(ScannerTokenKey+token.ID))
+	pipe.SRem(ctx, rediskeys.Keyf("%s%d", ScannerTokenActivityKey, token.ActivityID), token.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke scanner token: %v", err)
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
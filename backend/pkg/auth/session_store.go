@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis Keys
+	SessionRevokedKey = "session_revoked:"
+	SessionEpochKey   = "session_epoch:"
+	TokenRevokedKey   = "token_revoked:"
+)
+
+// SessionStore tracks revoked sessions so stateless JWTs can be invalidated early
+type SessionStore struct {
+	redisClient *redis.Client
+}
+
+func NewSessionStore(redisClient *redis.Client) *SessionStore {
+	return &SessionStore{
+		redisClient: redisClient,
+	}
+}
+
+// CurrentEpoch returns the session epoch to embed in a new token for the user
+func (s *SessionStore) CurrentEpoch(ctx context.Context, userID uint) (int64, error) {
+	epoch, err := s.redisClient.Get(ctx, rediskeys.Keyf("%s%d", SessionEpochKey, userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read session epoch: %v", err)
+	}
+	return epoch, nil
+}
+
+// RevokeUserSessions invalidates every token issued to the user so far by bumping their
+// session epoch. Unlike comparing issue times, this cannot miss a token issued in the same second.
+func (s *SessionStore) RevokeUserSessions(ctx context.Context, userID uint) error {
+	key := rediskeys.Keyf("%s%d", SessionEpochKey, userID)
+
+	// The epoch never expires: tokens issued after a revoke carry the current epoch and outlive
+	// any TTL counted from the revoke, so a counter restarted from zero would let them survive
+	// the next one. Persist clears the TTL earlier versions set.
+	pipe := s.redisClient.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.Persist(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return nil
+}
+
+// RevokeToken invalidates a single token by its ID until it would have expired anyway
+func (s *SessionStore) RevokeToken(ctx context.Context, claims *JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("token cannot be revoked individually")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.redisClient.Set(ctx, rediskeys.Key(TokenRevokedKey+claims.ID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %v", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token itself was revoked or was issued in an earlier session epoch
+func (s *SessionStore) IsRevoked(ctx context.Context, claims *JWTClaims) bool {
+	keys := []string{
+		rediskeys.Keyf("%s%d", SessionEpochKey, claims.UserID),
+		// Revocations recorded by issue time before session epochs existed
+		rediskeys.Keyf("%s%d", SessionRevokedKey, claims.UserID),
+	}
+	if claims.ID != "" {
+		keys = append(keys, rediskeys.Key(TokenRevokedKey+claims.ID))
+	}
+
+	values, err := s.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false
+	}
+
+	if epoch, ok := parseInt(values[0]); ok && claims.Epoch < epoch {
+		return true
+	}
+	if revokedAt, ok := parseInt(values[1]); ok && claims.IssuedAt != nil && claims.IssuedAt.Unix() < revokedAt {
+		return true
+	}
+	return len(values) > 2 && values[2] != nil
+}
+
+func parseInt(value interface{}) (int64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
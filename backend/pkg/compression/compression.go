@@ -0,0 +1,162 @@
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	DefaultMinBytes = 1024
+
+	MIMEEventStream = "text/event-stream"
+)
+
+var (
+	// ResponseEncodings lists the HTTP response encodings we can produce, in order of preference
+	ResponseEncodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+	// SupportedEncodings lists the per-event SSE encodings clients can decode with standard libraries
+	SupportedEncodings = []string{EncodingGzip, EncodingDeflate}
+
+	// DefaultContentTypes are the response types compressed when no allowlist is configured
+	DefaultContentTypes = []string{
+		"application/json",
+		"application/graphql-response+json",
+		"text/html",
+		"text/plain",
+		"text/css",
+		"application/javascript",
+	}
+)
+
+type Config struct {
+	Enabled      bool
+	MinBytes     int      // payloads smaller than this are sent as-is
+	ContentTypes []string // allowlist of compressible media types
+}
+
+// ShouldCompress reports whether a payload of the given size is worth compressing
+func (c Config) ShouldCompress(size int) bool {
+	return c.Enabled && size >= c.MinBytes
+}
+
+// AllowsContentType reports whether responses of this Content-Type may be compressed.
+// Event streams are never compressed as a whole since that would delay each flush.
+func (c Config) AllowsContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" || mediaType == MIMEEventStream {
+		return false
+	}
+
+	allowed := c.ContentTypes
+	if len(allowed) == 0 {
+		allowed = DefaultContentTypes
+	}
+	for _, candidate := range allowed {
+		if mediaType == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateEncoding picks the best supported encoding from an Accept-Encoding header.
+// An encoding listed by name takes its own q-value, so "gzip;q=0" refuses gzip even
+// alongside "*". It returns "" when the client accepts none of them.
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	explicit := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseEncoding(part)
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			explicit[name] = q
+		}
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, encoding := range supported {
+		q, listed := explicit[encoding]
+		if !listed {
+			q = wildcard
+		}
+		// Ties keep the earlier (preferred) encoding
+		if q > bestQ {
+			best = encoding
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// Compress encodes body with the given encoding
+func Compress(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch encoding {
+	case EncodingBrotli:
+		writer := brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	case EncodingGzip:
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	case EncodingDeflate:
+		writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func parseEncoding(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				return name, 0
+			}
+			q = value
+		}
+	}
+
+	return name, q
+}
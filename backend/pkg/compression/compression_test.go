@@ -0,0 +1,29 @@
+package compression
+
+import "testing"
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"br, gzip", EncodingBrotli},
+		{"gzip, br", EncodingBrotli},
+		{"gzip", EncodingGzip},
+		{"br;q=0.5, gzip", EncodingGzip},
+		{"*", EncodingBrotli},
+		{"gzip;q=0, *", EncodingBrotli},
+		{"br;q=0, gzip;q=0, *", EncodingDeflate},
+		{"br;q=0, gzip;q=0, deflate;q=0, *", ""},
+		{"*, br;q=0", EncodingGzip},
+		{"*;q=0", ""},
+		{"identity", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NegotiateEncoding(tt.acceptEncoding, ResponseEncodings); got != tt.want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
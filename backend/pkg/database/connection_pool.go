@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+// DefaultPoolSampleInterval is how often pool statistics are recorded
+const DefaultPoolSampleInterval = 30 * time.Second
+
+// PoolConfig sizes the database connection pool
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration // 0 keeps connections forever
+	ConnMaxIdleTime time.Duration // 0 keeps idle connections forever
+}
+
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    10,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+}
+
+// Validate rejects settings database/sql would silently adjust or that leave the pool unbounded
+func (c PoolConfig) Validate() error {
+	if c.MaxOpenConns <= 0 {
+		return fmt.Errorf("max open connections must be positive, got %d", c.MaxOpenConns)
+	}
+	if c.MaxIdleConns < 0 {
+		return fmt.Errorf("max idle connections cannot be negative, got %d", c.MaxIdleConns)
+	}
+	if c.MaxIdleConns > c.MaxOpenConns {
+		return fmt.Errorf("max idle connections (%d) cannot exceed max open connections (%d)", c.MaxIdleConns, c.MaxOpenConns)
+	}
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("connection lifetimes cannot be negative")
+	}
+	return nil
+}
+
+// ConnectionPool applies a PoolConfig to a database handle and samples its usage
+type ConnectionPool struct {
+	db     *sql.DB
+	config PoolConfig
+
+	// The last sample, and the cumulative counters it was taken from for per-interval deltas
+	sampled monitoring.DatabasePoolStats
+	last    sql.DBStats
+
+	mu sync.RWMutex
+}
+
+// NewConnectionPool validates config and applies it to db
+func NewConnectionPool(db *sql.DB, config PoolConfig) (*ConnectionPool, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	return &ConnectionPool{db: db, config: config, last: db.Stats()}, nil
+}
+
+// Sample reads the pool's current usage; waits and closed connections count since the previous sample
+func (cp *ConnectionPool) Sample() monitoring.DatabasePoolStats {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	current := cp.db.Stats()
+	stats := monitoring.DatabasePoolStats{
+		MaxOpen:           current.MaxOpenConnections,
+		Open:              current.OpenConnections,
+		InUse:             current.InUse,
+		Idle:              current.Idle,
+		WaitCount:         current.WaitCount - cp.last.WaitCount,
+		WaitDuration:      current.WaitDuration - cp.last.WaitDuration,
+		MaxIdleClosed:     current.MaxIdleClosed - cp.last.MaxIdleClosed,
+		MaxIdleTimeClosed: current.MaxIdleTimeClosed - cp.last.MaxIdleTimeClosed,
+		MaxLifetimeClosed: current.MaxLifetimeClosed - cp.last.MaxLifetimeClosed,
+	}
+	cp.last = current
+	cp.sampled = stats
+	return stats
+}
+
+// LastSample returns the sample last recorded by StartMonitoring or Sample
+func (cp *ConnectionPool) LastSample() monitoring.DatabasePoolStats {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	return cp.sampled
+}
+
+// Config returns the settings applied to the pool
+func (cp *ConnectionPool) Config() PoolConfig {
+	return cp.config
+}
+
+// StartMonitoring records a pool sample as performance metrics every interval until ctx is done
+func (cp *ConnectionPool) StartMonitoring(ctx context.Context, monitor *monitoring.PerformanceMonitor, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPoolSampleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := cp.Sample()
+			if stats.WaitCount > 0 {
+				log.Printf("Database pool saturated: %d callers waited %v for a connection (%d/%d in use)",
+					stats.WaitCount, stats.WaitDuration, stats.InUse, stats.MaxOpen)
+			}
+			if err := monitor.RecordDatabasePool(ctx, stats); err != nil {
+				log.Printf("Failed to record database pool stats: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
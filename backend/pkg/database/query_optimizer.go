@@ -3,16 +3,20 @@ package database
 import (
 	"context"
 	"crypto/md5"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -22,9 +26,10 @@ type QueryOptimizer struct {
 	db          *gorm.DB
 	redisClient *redis.Client
 	
-	// Query statistics
+	// Query statistics; statsMu guards updates to the stored QueryStats
 	queryStats  sync.Map
 	slowQueries sync.Map
+	statsMu     sync.Mutex
 	
 	// Configuration
 	slowQueryThreshold time.Duration
@@ -68,24 +73,6 @@ type QueryCacheEntry struct {
 	HitCount  int64       `json:"hit_count"`
 }
 
-// ConnectionPool manages database connections with enhanced monitoring
-type ConnectionPool struct {
-	db           *sql.DB
-	maxOpen      int
-	maxIdle      int
-	maxLifetime  time.Duration
-	connTimeout  time.Duration
-	
-	// Statistics
-	totalConnections    int64
-	activeConnections   int64
-	idleConnections     int64
-	connectionFailures  int64
-	connectionWaitTime  time.Duration
-	
-	mu sync.RWMutex
-}
-
 // NewQueryOptimizer creates a new query optimizer
 func NewQueryOptimizer(db *gorm.DB, redisClient *redis.Client) *QueryOptimizer {
 	qo := &QueryOptimizer{
@@ -108,9 +95,10 @@ func NewQueryOptimizer(db *gorm.DB, redisClient *redis.Client) *QueryOptimizer {
 
 // setupQueryLogger sets up custom GORM logger for query monitoring
 func (qo *QueryOptimizer) setupQueryLogger() {
+	// Wrap the configured logger so its log level is kept
 	customLogger := &QueryLogger{
 		optimizer: qo,
-		Interface: logger.Default,
+		Interface: qo.db.Logger,
 	}
 	
 	qo.db.Logger = customLogger
@@ -136,9 +124,13 @@ func (ql *QueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sq
 
 // recordQueryMetrics records query execution metrics
 func (qo *QueryOptimizer) recordQueryMetrics(ctx context.Context, query string, duration time.Duration, rowsAffected int64, err error) {
-	queryHash := hashQuery(query)
+	// Queries arrive with their values interpolated; strip them so every call of a
+	// statement shares one entry and no literal reaches the stats
+	sanitized := sanitizeQuery(query)
+	queryHash := hashQuery(sanitized)
 	
 	// Update query statistics
+	qo.statsMu.Lock()
 	if stats, exists := qo.queryStats.Load(queryHash); exists {
 		s := stats.(*QueryStats)
 		s.TotalCalls++
@@ -158,7 +150,7 @@ func (qo *QueryOptimizer) recordQueryMetrics(ctx context.Context, query string,
 		}
 	} else {
 		stats := &QueryStats{
-			Query:          sanitizeQuery(query),
+			Query:          sanitized,
 			QueryHash:      queryHash,
 			TotalCalls:     1,
 			TotalDuration:  duration,
@@ -175,12 +167,13 @@ func (qo *QueryOptimizer) recordQueryMetrics(ctx context.Context, query string,
 		
 		qo.queryStats.Store(queryHash, stats)
 	}
+	qo.statsMu.Unlock()
 	
 	// Log slow queries
 	if duration > qo.slowQueryThreshold {
 		slowQuery := &SlowQuery{
 			ID:           fmt.Sprintf("%s_%d", queryHash, time.Now().UnixNano()),
-			Query:        sanitizeQuery(query),
+			Query:        sanitized,
 			QueryHash:    queryHash,
 			Duration:     duration,
 			Tables:       extractTables(query),
@@ -204,14 +197,14 @@ func (qo *QueryOptimizer) logSlowQuery(ctx context.Context, slowQuery *SlowQuery
 	slowQueryJSON, _ := json.Marshal(slowQuery)
 	
 	pipe := qo.redisClient.Pipeline()
-	pipe.LPush(ctx, "slow_queries", slowQueryJSON)
-	pipe.LTrim(ctx, "slow_queries", 0, 999) // Keep last 1000 slow queries
-	pipe.Expire(ctx, "slow_queries", 24*time.Hour)
+	pipe.LPush(ctx, rediskeys.Key("slow_queries"), slowQueryJSON)
+	pipe.LTrim(ctx, rediskeys.Key("slow_queries"), 0, 999) // Keep last 1000 slow queries
+	pipe.Expire(ctx, rediskeys.Key("slow_queries"), 24*time.Hour)
 	
 	// Update slow query counters
 	today := time.Now().Format("2006-01-02")
-	pipe.Incr(ctx, fmt.Sprintf("slow_queries_count:%s", today))
-	pipe.Expire(ctx, fmt.Sprintf("slow_queries_count:%s", today), 7*24*time.Hour)
+	pipe.Incr(ctx, rediskeys.Keyf("slow_queries_count:%s", today))
+	pipe.Expire(ctx, rediskeys.Keyf("slow_queries_count:%s", today), 7*24*time.Hour)
 	
 	pipe.Exec(ctx)
 	
@@ -226,35 +219,38 @@ func (qo *QueryOptimizer) storeMetricsInRedis(ctx context.Context, queryHash str
 	today := time.Now().Format("2006-01-02")
 	hour := time.Now().Format("2006-01-02:15")
 	
-	pipe.Incr(ctx, fmt.Sprintf("query_count:%s", today))
-	pipe.Incr(ctx, fmt.Sprintf("query_count:%s", hour))
-	pipe.IncrBy(ctx, fmt.Sprintf("query_duration:%s", today), duration.Milliseconds())
-	pipe.IncrBy(ctx, fmt.Sprintf("query_duration:%s", hour), duration.Milliseconds())
+	pipe.Incr(ctx, rediskeys.Keyf("query_count:%s", today))
+	pipe.Incr(ctx, rediskeys.Keyf("query_count:%s", hour))
+	pipe.IncrBy(ctx, rediskeys.Keyf("query_duration:%s", today), duration.Milliseconds())
+	pipe.IncrBy(ctx, rediskeys.Keyf("query_duration:%s", hour), duration.Milliseconds())
 	
 	if hasError {
-		pipe.Incr(ctx, fmt.Sprintf("query_errors:%s", today))
-		pipe.Incr(ctx, fmt.Sprintf("query_errors:%s", hour))
+		pipe.Incr(ctx, rediskeys.Keyf("query_errors:%s", today))
+		pipe.Incr(ctx, rediskeys.Keyf("query_errors:%s", hour))
 	}
 	
 	// Per-query metrics
-	pipe.Incr(ctx, fmt.Sprintf("query_stats:%s:count", queryHash))
-	pipe.IncrBy(ctx, fmt.Sprintf("query_stats:%s:duration", queryHash), duration.Milliseconds())
+	pipe.Incr(ctx, rediskeys.Keyf("query_stats:%s:count", queryHash))
+	pipe.IncrBy(ctx, rediskeys.Keyf("query_stats:%s:duration", queryHash), duration.Milliseconds())
 	
 	// Set expiry
-	pipe.Expire(ctx, fmt.Sprintf("query_count:%s", today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_count:%s", hour), 48*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_duration:%s", today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_duration:%s", hour), 48*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_errors:%s", today), 7*24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_errors:%s", hour), 48*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_stats:%s:count", queryHash), 24*time.Hour)
-	pipe.Expire(ctx, fmt.Sprintf("query_stats:%s:duration", queryHash), 24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_count:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_count:%s", hour), 48*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_duration:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_duration:%s", hour), 48*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_errors:%s", today), 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_errors:%s", hour), 48*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_stats:%s:count", queryHash), 24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Keyf("query_stats:%s:duration", queryHash), 24*time.Hour)
 	
 	pipe.Exec(ctx)
 }
 
 // OptimizedFind executes an optimized find query with caching
-func (qo *QueryOptimizer) OptimizedFind(ctx context.Context, dest interface{}, query interface{}, args ...interface{}) error {
+func (qo *QueryOptimizer) OptimizedFind(ctx context.Context, dest interface{}, query interface{}, args ...interface{}) (err error) {
+	ctx, span := monitoring.StartSpan(ctx, "db.optimized_find", attribute.String("db.model", getTypeName(dest)))
+	defer func() { monitoring.EndSpan(span, err) }()
+
 	// Generate cache key
 	cacheKey := generateCacheKey("find", query, args...)
 	
@@ -263,9 +259,11 @@ func (qo *QueryOptimizer) OptimizedFind(ctx context.Context, dest interface{}, q
 		if cached, err := qo.getCachedQuery(ctx, cacheKey); err == nil {
 			// Cache hit
 			qo.recordCacheHit(ctx, cacheKey)
+			span.SetAttributes(attribute.Bool("db.cache_hit", true))
 			return mapToStruct(cached, dest)
 		}
 		qo.recordCacheMiss(ctx, cacheKey)
+		span.SetAttributes(attribute.Bool("db.cache_hit", false))
 	}
 	
 	// Execute query with optimizations
@@ -276,7 +274,6 @@ func (qo *QueryOptimizer) OptimizedFind(ctx context.Context, dest interface{}, q
 	// Apply query optimizations
 	db = qo.applyQueryOptimizations(db, query)
 	
-	var err error
 	switch q := query.(type) {
 	case string:
 		err = db.Raw(q, args...).Scan(dest).Error
@@ -300,7 +297,10 @@ func (qo *QueryOptimizer) OptimizedFind(ctx context.Context, dest interface{}, q
 }
 
 // OptimizedCreate executes an optimized create operation
-func (qo *QueryOptimizer) OptimizedCreate(ctx context.Context, value interface{}) error {
+func (qo *QueryOptimizer) OptimizedCreate(ctx context.Context, value interface{}) (err error) {
+	ctx, span := monitoring.StartSpan(ctx, "db.optimized_create", attribute.String("db.model", getTypeName(value)))
+	defer func() { monitoring.EndSpan(span, err) }()
+
 	start := time.Now()
 	
 	db := qo.db.WithContext(ctx)
@@ -316,7 +316,7 @@ func (qo *QueryOptimizer) OptimizedCreate(ctx context.Context, value interface{}
 	}
 	
 	// Single create
-	err := db.Create(value).Error
+	err = db.Create(value).Error
 	duration := time.Since(start)
 	
 	// Invalidate related caches
@@ -331,11 +331,14 @@ func (qo *QueryOptimizer) OptimizedCreate(ctx context.Context, value interface{}
 }
 
 // OptimizedUpdate executes an optimized update operation
-func (qo *QueryOptimizer) OptimizedUpdate(ctx context.Context, dest interface{}, updates interface{}) error {
+func (qo *QueryOptimizer) OptimizedUpdate(ctx context.Context, dest interface{}, updates interface{}) (err error) {
+	ctx, span := monitoring.StartSpan(ctx, "db.optimized_update", attribute.String("db.model", getTypeName(dest)))
+	defer func() { monitoring.EndSpan(span, err) }()
+
 	start := time.Now()
 	
 	db := qo.db.WithContext(ctx)
-	err := db.Model(dest).Updates(updates).Error
+	err = db.Model(dest).Updates(updates).Error
 	duration := time.Since(start)
 	
 	// Invalidate related caches
@@ -372,7 +375,7 @@ func (qo *QueryOptimizer) applyQueryOptimizations(db *gorm.DB, query interface{}
 
 // getCachedQuery retrieves a cached query result
 func (qo *QueryOptimizer) getCachedQuery(ctx context.Context, cacheKey string) (interface{}, error) {
-	cacheData, err := qo.redisClient.Get(ctx, fmt.Sprintf("query_cache:%s", cacheKey)).Result()
+	cacheData, err := qo.redisClient.Get(ctx, rediskeys.Keyf("query_cache:%s", cacheKey)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -384,14 +387,14 @@ func (qo *QueryOptimizer) getCachedQuery(ctx context.Context, cacheKey string) (
 	
 	// Check if expired
 	if time.Now().After(entry.ExpiresAt) {
-		qo.redisClient.Del(ctx, fmt.Sprintf("query_cache:%s", cacheKey))
+		qo.redisClient.Del(ctx, rediskeys.Keyf("query_cache:%s", cacheKey))
 		return nil, fmt.Errorf("cache expired")
 	}
 	
 	// Update hit count
 	entry.HitCount++
 	if updatedData, err := json.Marshal(entry); err == nil {
-		qo.redisClient.Set(ctx, fmt.Sprintf("query_cache:%s", cacheKey), updatedData, qo.cacheTimeout)
+		qo.redisClient.Set(ctx, rediskeys.Keyf("query_cache:%s", cacheKey), updatedData, qo.cacheTimeout)
 	}
 	
 	return entry.Result, nil
@@ -413,7 +416,7 @@ func (qo *QueryOptimizer) cacheQuery(ctx context.Context, cacheKey string, resul
 	}
 	
 	if entryData, err := json.Marshal(entry); err == nil {
-		qo.redisClient.Set(ctx, fmt.Sprintf("query_cache:%s", cacheKey), entryData, qo.cacheTimeout*2)
+		qo.redisClient.Set(ctx, rediskeys.Keyf("query_cache:%s", cacheKey), entryData, qo.cacheTimeout*2)
 	}
 }
 
@@ -444,7 +447,7 @@ func (qo *QueryOptimizer) invalidateRelatedCaches(ctx context.Context, entity in
 	typeName := getTypeName(entity)
 	
 	// Get all cache keys related to this entity type
-	pattern := fmt.Sprintf("query_cache:*%s*", strings.ToLower(typeName))
+	pattern := rediskeys.Keyf("query_cache:*%s*", strings.ToLower(typeName))
 	keys, err := qo.redisClient.Keys(ctx, pattern).Result()
 	if err != nil {
 		return
@@ -459,15 +462,15 @@ func (qo *QueryOptimizer) invalidateRelatedCaches(ctx context.Context, entity in
 // recordCacheHit records a cache hit
 func (qo *QueryOptimizer) recordCacheHit(ctx context.Context, cacheKey string) {
 	today := time.Now().Format("2006-01-02")
-	qo.redisClient.Incr(ctx, fmt.Sprintf("cache_hits:%s", today))
-	qo.redisClient.Expire(ctx, fmt.Sprintf("cache_hits:%s", today), 7*24*time.Hour)
+	qo.redisClient.Incr(ctx, rediskeys.Keyf("cache_hits:%s", today))
+	qo.redisClient.Expire(ctx, rediskeys.Keyf("cache_hits:%s", today), 7*24*time.Hour)
 }
 
 // recordCacheMiss records a cache miss
 func (qo *QueryOptimizer) recordCacheMiss(ctx context.Context, cacheKey string) {
 	today := time.Now().Format("2006-01-02")
-	qo.redisClient.Incr(ctx, fmt.Sprintf("cache_misses:%s", today))
-	qo.redisClient.Expire(ctx, fmt.Sprintf("cache_misses:%s", today), 7*24*time.Hour)
+	qo.redisClient.Incr(ctx, rediskeys.Keyf("cache_misses:%s", today))
+	qo.redisClient.Expire(ctx, rediskeys.Keyf("cache_misses:%s", today), 7*24*time.Hour)
 }
 
 // Analysis and optimization methods
@@ -571,7 +574,7 @@ func (qo *QueryOptimizer) cleanupExpiredCache() {
 	ctx := context.Background()
 	
 	// Get all cache keys
-	keys, err := qo.redisClient.Keys(ctx, "query_cache:*").Result()
+	keys, err := qo.redisClient.Keys(ctx, rediskeys.Key("query_cache:*")).Result()
 	if err != nil {
 		return
 	}
@@ -615,31 +618,90 @@ func (qo *QueryOptimizer) GetQueryStatistics() map[string]*QueryStats {
 	return stats
 }
 
-// GetSlowQueries returns recent slow queries
+// QueryStatsSort orders a query statistics report
+type QueryStatsSort string
+
+const (
+	QueryStatsByAverageDuration QueryStatsSort = "avg_duration"
+	QueryStatsByCallCount       QueryStatsSort = "call_count"
+	QueryStatsByErrorCount      QueryStatsSort = "error_count"
+)
+
+// TopQueryStatistics returns copies of up to limit query statistics, highest first by sortBy
+func (qo *QueryOptimizer) TopQueryStatistics(limit int, sortBy QueryStatsSort) []QueryStats {
+	var stats []QueryStats
+	qo.statsMu.Lock()
+	qo.queryStats.Range(func(key, value interface{}) bool {
+		stats = append(stats, *value.(*QueryStats))
+		return true
+	})
+	qo.statsMu.Unlock()
+
+	sortKey := func(s QueryStats) int64 {
+		switch sortBy {
+		case QueryStatsByCallCount:
+			return s.TotalCalls
+		case QueryStatsByErrorCount:
+			return s.ErrorCount
+		default:
+			return int64(s.AverageDuration)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if a, b := sortKey(stats[i]), sortKey(stats[j]); a != b {
+			return a > b
+		}
+		return stats[i].QueryHash < stats[j].QueryHash
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
+
+// QueryStatistics returns a copy of the statistics for one query hash
+func (qo *QueryOptimizer) QueryStatistics(queryHash string) (QueryStats, bool) {
+	value, ok := qo.queryStats.Load(queryHash)
+	if !ok {
+		return QueryStats{}, false
+	}
+	qo.statsMu.Lock()
+	defer qo.statsMu.Unlock()
+	return *value.(*QueryStats), true
+}
+
+// GetSlowQueries returns up to limit slow queries, newest first
 func (qo *QueryOptimizer) GetSlowQueries(limit int) []*SlowQuery {
 	var queries []*SlowQuery
-	count := 0
 	
 	qo.slowQueries.Range(func(key, value interface{}) bool {
-		if count >= limit {
-			return false
-		}
-		
-		query := value.(*SlowQuery)
-		queries = append(queries, query)
-		count++
+		queries = append(queries, value.(*SlowQuery))
 		return true
 	})
 	
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Timestamp.After(queries[j].Timestamp)
+	})
+	if limit > 0 && len(queries) > limit {
+		queries = queries[:limit]
+	}
 	return queries
 }
 
 // Helper functions
 
+var (
+	whitespacePattern     = regexp.MustCompile(`\s+`)
+	placeholderPattern    = regexp.MustCompile(`\$\d+`)
+	stringLiteralPattern  = regexp.MustCompile(`'(?:[^']|'')*'`) // double quotes are identifiers in Postgres
+	numericLiteralPattern = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?\b`)
+)
+
 func hashQuery(query string) string {
 	// Normalize query by removing values and whitespace
-	normalized := regexp.MustCompile(`\s+`).ReplaceAllString(query, " ")
-	normalized = regexp.MustCompile(`\$\d+`).ReplaceAllString(normalized, "?")
+	normalized := whitespacePattern.ReplaceAllString(query, " ")
+	normalized = placeholderPattern.ReplaceAllString(normalized, "?")
 	normalized = strings.TrimSpace(normalized)
 	
 	hash := md5.Sum([]byte(normalized))
@@ -648,7 +710,8 @@ func hashQuery(query string) string {
 
 func sanitizeQuery(query string) string {
 	// Remove potential sensitive data from query for logging
-	sanitized := regexp.MustCompile(`('[^']*'|"[^"]*")`).ReplaceAllString(query, "'***'")
+	sanitized := stringLiteralPattern.ReplaceAllString(query, "'***'")
+	sanitized = numericLiteralPattern.ReplaceAllString(sanitized, "${1}?")
 	return sanitized
 }
 
@@ -680,9 +743,7 @@ func generateCacheKey(operation string, query interface{}, args ...interface{})
 		Args:      args,
 	}
 	
-	jsonData, _ := json.Marshal(data)
-	hash := md5.Sum(jsonData)
-	return fmt.Sprintf("%x", hash)[:16]
+	return utils.HashKey(data)
 }
 
 func mapToStruct(src interface{}, dest interface{}) error {
@@ -0,0 +1,56 @@
+// Package errcode attaches machine-readable codes to GraphQL errors, in the "code"
+// extension, so clients can tell failures apart without parsing messages.
+package errcode
+
+import (
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type Code string
+
+const (
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeValidation      Code = "VALIDATION"
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeConflict        Code = "CONFLICT"
+
+	// CodePersistedQueryNotAllowed rejects operations missing from the persisted query allowlist
+	CodePersistedQueryNotAllowed Code = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// New returns an error with a human-readable message and the given code. gqlgen fills in the
+// path when a resolver returns it.
+func New(code Code, format string, args ...interface{}) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    fmt.Sprintf(format, args...),
+		Extensions: map[string]interface{}{"code": string(code)},
+	}
+}
+
+func Unauthenticated(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeUnauthenticated, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeForbidden, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeNotFound, format, args...)
+}
+
+func Validation(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeValidation, format, args...)
+}
+
+func RateLimited(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeRateLimited, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *gqlerror.Error {
+	return New(CodeConflict, format, args...)
+}
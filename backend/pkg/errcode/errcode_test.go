@@ -0,0 +1,39 @@
+package errcode
+
+import (
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *gqlerror.Error
+		want Code
+	}{
+		{"unauthenticated", Unauthenticated("sign in"), CodeUnauthenticated},
+		{"forbidden", Forbidden("no"), CodeForbidden},
+		{"not found", NotFound("activity %s not found", "12"), CodeNotFound},
+		{"validation", Validation("bad"), CodeValidation},
+		{"rate limited", RateLimited("slow down"), CodeRateLimited},
+		{"conflict", Conflict("changed"), CodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Extensions["code"]; got != string(tt.want) {
+				t.Errorf("code = %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := NotFound("activity %s not found", "12"); err.Message != "activity 12 not found" {
+		t.Errorf("message = %q, want it formatted", err.Message)
+	}
+	// Each error gets its own extensions, so callers can add to them
+	a, b := Conflict("a"), Conflict("b")
+	a.Extensions["currentVersion"] = 2
+	if _, ok := b.Extensions["currentVersion"]; ok {
+		t.Error("errors share their extensions")
+	}
+}
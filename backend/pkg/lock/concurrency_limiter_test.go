@@ -0,0 +1,73 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestConcurrencyLimiterRejectsPastTheLimit(t *testing.T) {
+	client := newTestRedis(t)
+	limiter := NewConcurrencyLimiter(client, "export", 2, time.Minute)
+	ctx := context.Background()
+
+	// Five exports started at once by the same admin
+	var (
+		mu       sync.Mutex
+		releases []func()
+		rejected int
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(ctx, "7")
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				releases = append(releases, release)
+			case ErrConcurrencyLimitReached:
+				rejected++
+			default:
+				t.Errorf("Acquire: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(releases) != 2 || rejected != 3 {
+		t.Fatalf("%d exports started and %d rejected, want 2 and 3", len(releases), rejected)
+	}
+	if running, err := limiter.Running(ctx, "7"); err != nil || running != 2 {
+		t.Errorf("Running() = %d, %v, want 2", running, err)
+	}
+
+	// Other admins have their own slots
+	if release, err := limiter.Acquire(ctx, "8"); err != nil {
+		t.Errorf("another admin's export was rejected: %v", err)
+	} else {
+		release()
+	}
+
+	// A finished export frees its slot
+	releases[0]()
+	release, err := limiter.Acquire(ctx, "7")
+	if err != nil {
+		t.Fatalf("export after one finished was rejected: %v", err)
+	}
+	release()
+}
@@ -0,0 +1,166 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis Keys
+	LockKeyPrefix = "lock:"
+
+	DefaultLockTTL = 5 * time.Minute
+
+	// RunOnce settings
+	DoneKeySuffix    = ":done"
+	LockPollInterval = 500 * time.Millisecond
+)
+
+// ErrLockHeld is returned when another instance already holds the lock
+var ErrLockHeld = errors.New("lock is held by another instance")
+
+// releaseScript deletes the key only if it still holds our token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type DistributedLock struct {
+	redisClient *redis.Client
+}
+
+func NewDistributedLock(redisClient *redis.Client) *DistributedLock {
+	return &DistributedLock{
+		redisClient: redisClient,
+	}
+}
+
+// Acquire tries to take the lock for key. The returned release function must be called
+// once the guarded work is done; the TTL protects against holders that crash.
+func (dl *DistributedLock) Acquire(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	lockKey := rediskeys.Key(LockKeyPrefix + key)
+	ok, err := dl.redisClient.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %v", key, err)
+	}
+	if !ok {
+		return nil, ErrLockHeld
+	}
+
+	release := func() {
+		releaseScript.Run(context.Background(), dl.redisClient, []string{lockKey}, token)
+	}
+	return release, nil
+}
+
+// WithLock runs fn while holding the lock for key
+func (dl *DistributedLock) WithLock(ctx context.Context, key string, ttl time.Duration, fn func() error) error {
+	release, err := dl.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn()
+}
+
+// RunOnce runs fn on a single instance. Instances that find the lock held wait for the
+// holder to finish and then return without running fn. If the holder dies before
+// finishing, a waiter takes over. Waiting is bounded by wait.
+func (dl *DistributedLock) RunOnce(ctx context.Context, key string, ttl, wait time.Duration, fn func() error) error {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
+	doneKey := rediskeys.Key(LockKeyPrefix + key + DoneKeySuffix)
+	deadline := time.Now().Add(wait)
+
+	for {
+		done, err := dl.isDone(ctx, doneKey)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		release, err := dl.Acquire(ctx, key, ttl)
+		if err == nil {
+			return dl.runAndMarkDone(ctx, doneKey, wait, release, fn)
+		}
+		if err != ErrLockHeld {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for another instance to finish %s", wait, key)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(LockPollInterval):
+		}
+	}
+}
+
+func (dl *DistributedLock) runAndMarkDone(ctx context.Context, doneKey string, markerTTL time.Duration, release func(), fn func() error) error {
+	defer release()
+
+	// The previous holder may have finished between our check and acquiring the lock
+	done, err := dl.isDone(ctx, doneKey)
+	if err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	// The marker only needs to outlive instances that started alongside us,
+	// so a later restart runs fn again
+	if markerTTL <= 0 {
+		markerTTL = LockPollInterval
+	}
+	if err := dl.redisClient.Set(ctx, doneKey, time.Now().Unix(), markerTTL).Err(); err != nil {
+		return fmt.Errorf("failed to mark %s done: %v", doneKey, err)
+	}
+	return nil
+}
+
+func (dl *DistributedLock) isDone(ctx context.Context, doneKey string) (bool, error) {
+	count, err := dl.redisClient.Exists(ctx, doneKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %v", doneKey, err)
+	}
+	return count > 0, nil
+}
+
+func generateToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %v", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,87 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRunOnceRunsOnOneInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// Two instances starting together, each with its own connection
+	const instances = 2
+	var runs, finished int32
+	errs := make([]error, instances)
+	returnedEarly := make([]bool, instances)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		dl := NewDistributedLock(client)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = dl.RunOnce(ctx, "warm", time.Minute, 5*time.Second, func() error {
+				atomic.AddInt32(&runs, 1)
+				time.Sleep(100 * time.Millisecond)
+				atomic.StoreInt32(&finished, 1)
+				return nil
+			})
+			// Neither instance may return before the work is done
+			returnedEarly[i] = atomic.LoadInt32(&finished) == 0
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range errs {
+		if errs[i] != nil {
+			t.Errorf("instance %d: RunOnce: %v", i, errs[i])
+		}
+		if returnedEarly[i] {
+			t.Errorf("instance %d returned before the work finished", i)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("fn ran %d times, want once", runs)
+	}
+	if server.Exists(rediskeys.Key(LockKeyPrefix + "warm")) {
+		t.Error("lock still held after RunOnce returned")
+	}
+}
+
+func TestRunOnceTakesOverFromACrashedHolder(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	ctx := context.Background()
+
+	// An instance took the lock and died before marking the work done
+	lockKey := rediskeys.Key(LockKeyPrefix + "warm")
+	server.Set(lockKey, "crashed")
+	server.SetTTL(lockKey, time.Minute)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		server.FastForward(time.Minute)
+	}()
+
+	runs := 0
+	err := NewDistributedLock(client).RunOnce(ctx, "warm", time.Minute, 5*time.Second, func() error {
+		runs++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("fn ran %d times after the holder's lock expired, want once", runs)
+	}
+}
@@ -0,0 +1,216 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAlertNotifyThrottle is the least time between two notifications for the same metric
+	DefaultAlertNotifyThrottle = 15 * time.Minute
+
+	alertNotifyTimeout = 10 * time.Second
+)
+
+// AlertNotification is sent when a metric crosses its critical level and again when it clears
+type AlertNotification struct {
+	Alert        PerformanceAlert
+	Resolved     bool
+	DashboardURL string
+}
+
+// Subject is a one-line summary, used as the email subject
+func (n AlertNotification) Subject() string {
+	if n.Resolved {
+		return fmt.Sprintf("[RESOLVED] %s is back under its alert threshold", n.Alert.MetricName)
+	}
+	return fmt.Sprintf("[%s] %s threshold exceeded", n.Alert.Level, n.Alert.MetricName)
+}
+
+// Text is the full message body
+func (n AlertNotification) Text() string {
+	var b strings.Builder
+	b.WriteString(n.Subject())
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "Metric: %s\n", n.Alert.MetricName)
+	fmt.Fprintf(&b, "Value: %.2f\n", n.Alert.Value)
+	fmt.Fprintf(&b, "Threshold: %.2f\n", n.Alert.Threshold)
+	fmt.Fprintf(&b, "Raised at: %s\n", n.Alert.Timestamp.Format(time.RFC3339))
+	if n.Resolved && n.Alert.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved at: %s\n", n.Alert.ResolvedAt.Format(time.RFC3339))
+	}
+	if n.DashboardURL != "" {
+		fmt.Fprintf(&b, "Dashboard: %s\n", n.DashboardURL)
+	}
+	return b.String()
+}
+
+// AlertNotifier delivers alert notifications to people, e.g. by email or chat
+type AlertNotifier interface {
+	Name() string
+	Notify(ctx context.Context, notification AlertNotification) error
+}
+
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		WebhookURL: webhookURL,
+		Client:     &http.Client{Timeout: alertNotifyTimeout},
+	}
+}
+
+func (sn *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (sn *SlackNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	body, err := json.Marshal(map[string]string{"text": notification.Text()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sn.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sn.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier sends notifications over SMTP
+type EmailNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (en *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (en *EmailNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	if len(en.To) == 0 {
+		return fmt.Errorf("no alert email recipients configured")
+	}
+
+	var auth smtp.Auth
+	if en.Username != "" {
+		auth = smtp.PlainAuth("", en.Username, en.Password, en.Host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
+		en.From, strings.Join(en.To, ", "), notification.Subject(), notification.Text())
+
+	addr := fmt.Sprintf("%s:%s", en.Host, en.Port)
+	return smtp.SendMail(addr, auth, en.From, en.To, []byte(msg))
+}
+
+// alertNotifications pages on critical alerts, at most once per throttle window per metric,
+// and follows up when a paged alert clears
+type alertNotifications struct {
+	notifiers    []AlertNotifier
+	throttle     time.Duration
+	dashboardURL string
+
+	mu           sync.Mutex
+	lastNotified map[string]time.Time
+	// Metrics paged for whose resolution no notification has been sent yet
+	firing map[string]bool
+}
+
+// SetAlertNotifiers routes critical alerts to the notifiers. A zero throttle uses
+// DefaultAlertNotifyThrottle; dashboardURL is linked from every message.
+func (pm *PerformanceMonitor) SetAlertNotifiers(notifiers []AlertNotifier, throttle time.Duration, dashboardURL string) {
+	if throttle <= 0 {
+		throttle = DefaultAlertNotifyThrottle
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.notifications = &alertNotifications{
+		notifiers:    notifiers,
+		throttle:     throttle,
+		dashboardURL: dashboardURL,
+		lastNotified: make(map[string]time.Time),
+		firing:       make(map[string]bool),
+	}
+}
+
+func (pm *PerformanceMonitor) alertNotifications() *alertNotifications {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.notifications
+}
+
+// notifyAlert pages for a critical alert unless the metric was paged for within the throttle window
+func (pm *PerformanceMonitor) notifyAlert(alert PerformanceAlert) {
+	an := pm.alertNotifications()
+	if an == nil || len(an.notifiers) == 0 || alert.Level != "CRITICAL" {
+		return
+	}
+
+	an.mu.Lock()
+	if last, ok := an.lastNotified[alert.MetricName]; ok && time.Since(last) < an.throttle {
+		an.mu.Unlock()
+		return
+	}
+	an.lastNotified[alert.MetricName] = time.Now()
+	an.firing[alert.MetricName] = true
+	an.mu.Unlock()
+
+	go an.send(AlertNotification{Alert: alert, DashboardURL: an.dashboardURL})
+}
+
+// notifyResolved reports that a paged alert has cleared
+func (pm *PerformanceMonitor) notifyResolved(alert PerformanceAlert) {
+	an := pm.alertNotifications()
+	if an == nil || len(an.notifiers) == 0 {
+		return
+	}
+
+	an.mu.Lock()
+	paged := an.firing[alert.MetricName]
+	delete(an.firing, alert.MetricName)
+	an.mu.Unlock()
+
+	if paged {
+		go an.send(AlertNotification{Alert: alert, Resolved: true, DashboardURL: an.dashboardURL})
+	}
+}
+
+func (an *alertNotifications) send(notification AlertNotification) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertNotifyTimeout)
+	defer cancel()
+
+	for _, notifier := range an.notifiers {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			log.Printf("Failed to send %s alert notification for %s: %v", notifier.Name(), notification.Alert.MetricName, err)
+		}
+	}
+}
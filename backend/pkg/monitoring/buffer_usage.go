@@ -0,0 +1,108 @@
+package monitoring
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultChannelBufferSize = 100
+
+	// DefaultOverflowThreshold is how many drops in a row mark a consumer as slow
+	DefaultOverflowThreshold = 10
+
+	BufferOverflowMetric = "channel_buffer_overflow"
+)
+
+// BufferConfig sizes a per-connection channel buffer. OnOverflow, if set, is called once each
+// time a connection's consecutive drops reach OverflowThreshold.
+type BufferConfig struct {
+	Size              int
+	OverflowThreshold int
+	OnOverflow        func(BufferOverflow)
+}
+
+// WithDefaults fills in zero fields
+func (c BufferConfig) WithDefaults() BufferConfig {
+	if c.Size <= 0 {
+		c.Size = DefaultChannelBufferSize
+	}
+	if c.OverflowThreshold <= 0 {
+		c.OverflowThreshold = DefaultOverflowThreshold
+	}
+	return c
+}
+
+// BufferOverflow describes a connection whose buffer keeps filling up
+type BufferOverflow struct {
+	Kind         string // websocket or sse
+	ConnectionID string
+	UserID       uint
+	Usage        BufferUsageStats
+}
+
+// BufferUsage tracks how full one connection's channel buffer runs. Safe for concurrent use.
+type BufferUsage struct {
+	capacity      int
+	highWatermark atomic.Int64
+	drops         atomic.Int64
+	dropStreak    atomic.Int64
+}
+
+// BufferUsageStats is a snapshot of a BufferUsage
+type BufferUsageStats struct {
+	Capacity      int   `json:"capacity"`
+	HighWatermark int   `json:"high_watermark"`
+	Drops         int64 `json:"drops"`
+}
+
+func NewBufferUsage(capacity int) *BufferUsage {
+	return &BufferUsage{capacity: capacity}
+}
+
+// Sent records a successful send that left depth messages queued
+func (b *BufferUsage) Sent(depth int) {
+	b.dropStreak.Store(0)
+	for {
+		current := b.highWatermark.Load()
+		if int64(depth) <= current || b.highWatermark.CompareAndSwap(current, int64(depth)) {
+			return
+		}
+	}
+}
+
+// Dropped records a message dropped because the buffer was full and returns the number of
+// drops in a row
+func (b *BufferUsage) Dropped() int64 {
+	b.drops.Add(1)
+	return b.dropStreak.Add(1)
+}
+
+func (b *BufferUsage) Stats() BufferUsageStats {
+	return BufferUsageStats{
+		Capacity:      b.capacity,
+		HighWatermark: int(b.highWatermark.Load()),
+		Drops:         b.drops.Load(),
+	}
+}
+
+// RecordBufferOverflow stores a slow consumer as a performance metric, valued at its total drops
+func (pm *PerformanceMonitor) RecordBufferOverflow(ctx context.Context, overflow BufferOverflow) error {
+	return pm.RecordMetric(ctx, MetricPoint{
+		Name:  BufferOverflowMetric,
+		Value: float64(overflow.Usage.Drops),
+		Unit:  "messages",
+		Tags: map[string]string{
+			"kind":          overflow.Kind,
+			"connection_id": overflow.ConnectionID,
+			"user_id":       strconv.FormatUint(uint64(overflow.UserID), 10),
+		},
+		Fields: map[string]interface{}{
+			"capacity":       overflow.Usage.Capacity,
+			"high_watermark": overflow.Usage.HighWatermark,
+		},
+		Timestamp: time.Now(),
+	})
+}
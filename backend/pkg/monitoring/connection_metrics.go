@@ -0,0 +1,97 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ConnectionsOpenedMetric  = "connections_opened_per_second"
+	ConnectionsClosedMetric  = "connections_closed_per_second"
+	ConnectionLifetimeMetric = "connection_lifetime_avg"
+	MessagesBroadcastMetric  = "messages_broadcast_per_second"
+	ConnectionDropsMetric    = "connection_channel_full_drops"
+
+	DefaultConnectionSampleInterval = 10 * time.Second
+)
+
+// ConnectionMetricsSample is one sample of realtime connection activity. Counts cover the
+// Interval since the previous sample; Active is the number of open connections at the end of it.
+type ConnectionMetricsSample struct {
+	Interval time.Duration `json:"interval"`
+	Active   int           `json:"active"`
+	Opened   int64         `json:"opened"`
+	Closed   int64         `json:"closed"`
+	// Messages queued to a connection; one event fanned out to ten connections counts ten
+	Broadcasts int64 `json:"broadcasts"`
+	// Messages dropped because a connection's channel was full
+	Drops int64 `json:"drops"`
+	// Time the connections closed in the interval had been open, summed
+	ClosedLifetime time.Duration `json:"closed_lifetime"`
+}
+
+func (s ConnectionMetricsSample) perSecond(n int64) float64 {
+	if s.Interval <= 0 {
+		return 0
+	}
+	return float64(n) / s.Interval.Seconds()
+}
+
+// AverageLifetime returns how long the connections closed in the interval had been open on average
+func (s ConnectionMetricsSample) AverageLifetime() time.Duration {
+	if s.Closed == 0 {
+		return 0
+	}
+	return s.ClosedLifetime / time.Duration(s.Closed)
+}
+
+// RecordConnectionMetrics stores a connection sample as performance metrics, so load tests
+// can follow connection churn and fan-out over time
+func (pm *PerformanceMonitor) RecordConnectionMetrics(ctx context.Context, instanceID string, sample ConnectionMetricsSample) error {
+	timestamp := time.Now()
+	tags := map[string]string{"component": "realtime", "instance_id": instanceID}
+
+	points := []MetricPoint{
+		{
+			Name:   ConnectionsOpenedMetric,
+			Value:  sample.perSecond(sample.Opened),
+			Unit:   "per_second",
+			Fields: map[string]interface{}{"opened": sample.Opened, "active": sample.Active},
+		},
+		{
+			Name:   ConnectionsClosedMetric,
+			Value:  sample.perSecond(sample.Closed),
+			Unit:   "per_second",
+			Fields: map[string]interface{}{"closed": sample.Closed, "active": sample.Active},
+		},
+		{
+			Name:   MessagesBroadcastMetric,
+			Value:  sample.perSecond(sample.Broadcasts),
+			Unit:   "per_second",
+			Fields: map[string]interface{}{"broadcasts": sample.Broadcasts},
+		},
+		{
+			Name:  ConnectionDropsMetric,
+			Value: float64(sample.Drops),
+			Unit:  "messages",
+		},
+	}
+	// Without closed connections there is no lifetime to average
+	if sample.Closed > 0 {
+		points = append(points, MetricPoint{
+			Name:   ConnectionLifetimeMetric,
+			Value:  sample.AverageLifetime().Seconds(),
+			Unit:   "seconds",
+			Fields: map[string]interface{}{"closed": sample.Closed},
+		})
+	}
+
+	for _, point := range points {
+		point.Tags = tags
+		point.Timestamp = timestamp
+		if err := pm.RecordMetric(ctx, point); err != nil {
+			return err
+		}
+	}
+	return nil
+}
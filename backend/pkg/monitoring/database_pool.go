@@ -0,0 +1,76 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+)
+
+// DatabasePoolSaturationMetric is the share of the pool's maximum open connections in use, in percent
+const DatabasePoolSaturationMetric = "database_pool_saturation"
+
+// DatabasePoolStats is one sample of the database connection pool. Waits cover the time
+// since the previous sample.
+type DatabasePoolStats struct {
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+	// Connections closed since the previous sample for exceeding the idle limit, idle time or lifetime
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// Saturation returns the in-use share of the maximum open connections in percent
+func (s DatabasePoolStats) Saturation() float64 {
+	if s.MaxOpen <= 0 {
+		return 0
+	}
+	return float64(s.InUse) / float64(s.MaxOpen) * 100
+}
+
+// RecordDatabasePool stores a pool sample as performance metrics. Saturation is alerted on;
+// callers waiting for a connection show the pool is too small before saturation reaches 100.
+func (pm *PerformanceMonitor) RecordDatabasePool(ctx context.Context, stats DatabasePoolStats) error {
+	timestamp := time.Now()
+	tags := map[string]string{"component": "database"}
+
+	if err := pm.RecordMetric(ctx, MetricPoint{
+		Name:  DatabasePoolSaturationMetric,
+		Value: stats.Saturation(),
+		Unit:  "percent",
+		Tags:  tags,
+		Fields: map[string]interface{}{
+			"max_open":             stats.MaxOpen,
+			"open":                 stats.Open,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"max_idle_closed":      stats.MaxIdleClosed,
+			"max_idle_time_closed": stats.MaxIdleTimeClosed,
+			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+		},
+		Timestamp: timestamp,
+	}); err != nil {
+		return err
+	}
+
+	if err := pm.RecordMetric(ctx, MetricPoint{
+		Name:      "database_pool_wait_count",
+		Value:     float64(stats.WaitCount),
+		Unit:      "count",
+		Tags:      tags,
+		Timestamp: timestamp,
+	}); err != nil {
+		return err
+	}
+
+	return pm.RecordMetric(ctx, MetricPoint{
+		Name:      "database_pool_wait_time",
+		Value:     float64(stats.WaitDuration) / float64(time.Millisecond),
+		Unit:      "ms",
+		Tags:      tags,
+		Timestamp: timestamp,
+	})
+}
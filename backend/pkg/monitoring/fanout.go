@@ -0,0 +1,55 @@
+package monitoring
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+const (
+	FanoutSizeMetric     = "realtime_fanout_size"
+	FanoutDurationMetric = "realtime_fanout_duration"
+
+	// DefaultFanoutAlertThreshold is how many recipients of one event raise a warning alert
+	DefaultFanoutAlertThreshold = 1000
+)
+
+// FanoutSample describes one event published to many recipients
+type FanoutSample struct {
+	Event string
+	// Recipients the event was meant for, and those it was published to after the cap
+	Recipients int
+	Published  int
+	Duration   time.Duration
+	// Background is set when the fan-out was published by the background worker
+	Background bool
+}
+
+// RecordFanout stores a fan-out's size and duration as performance metrics
+func (pm *PerformanceMonitor) RecordFanout(ctx context.Context, sample FanoutSample) error {
+	timestamp := time.Now()
+	tags := map[string]string{
+		"component":  "realtime",
+		"event":      sample.Event,
+		"background": strconv.FormatBool(sample.Background),
+	}
+
+	if err := pm.RecordMetric(ctx, MetricPoint{
+		Name:      FanoutSizeMetric,
+		Value:     float64(sample.Recipients),
+		Unit:      "recipients",
+		Tags:      tags,
+		Fields:    map[string]interface{}{"published": sample.Published},
+		Timestamp: timestamp,
+	}); err != nil {
+		return err
+	}
+	return pm.RecordMetric(ctx, MetricPoint{
+		Name:      FanoutDurationMetric,
+		Value:     float64(sample.Duration.Microseconds()) / 1000,
+		Unit:      "ms",
+		Tags:      tags,
+		Fields:    map[string]interface{}{"published": sample.Published},
+		Timestamp: timestamp,
+	})
+}
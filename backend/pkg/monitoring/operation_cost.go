@@ -0,0 +1,157 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// GraphQLOperationDurationMetric is the duration of each GraphQL query and mutation, in ms
+	GraphQLOperationDurationMetric = "graphql_operation_duration"
+
+	// operationCostKey holds a day's operation counts and durations per complexity bucket
+	operationCostKey = "metrics:graphql_cost:"
+	operationCostTTL = 31 * 24 * time.Hour
+
+	// MaxOperationCostReportDays is the longest period an operation cost report covers
+	MaxOperationCostReportDays = 30
+)
+
+// ComplexityBuckets are the lowest complexities of each bucket after the first, which starts at 0
+var ComplexityBuckets = []int{50, 200, 500}
+
+// OperationCost is one GraphQL operation's complexity and how long it took
+type OperationCost struct {
+	Name       string
+	Type       string
+	Complexity int
+	Duration   time.Duration
+	Slow       bool
+}
+
+// ComplexityBucketStats aggregates the operations whose complexity fell in one bucket
+type ComplexityBucketStats struct {
+	Label         string
+	MinComplexity int
+	// MaxComplexity is nil for the last, open-ended bucket
+	MaxComplexity  *int
+	Operations     int64
+	SlowOperations int64
+	TotalDuration  time.Duration
+}
+
+// AverageDuration returns the bucket's mean operation duration
+func (s *ComplexityBucketStats) AverageDuration() time.Duration {
+	if s.Operations == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Operations)
+}
+
+// complexityBuckets returns an empty stats entry per bucket
+func complexityBuckets() []ComplexityBucketStats {
+	buckets := make([]ComplexityBucketStats, 0, len(ComplexityBuckets)+1)
+	lower := 0
+	for _, next := range ComplexityBuckets {
+		upper := next - 1
+		buckets = append(buckets, ComplexityBucketStats{
+			Label:         fmt.Sprintf("%d-%d", lower, upper),
+			MinComplexity: lower,
+			MaxComplexity: &upper,
+		})
+		lower = next
+	}
+	return append(buckets, ComplexityBucketStats{
+		Label:         fmt.Sprintf("%d+", lower),
+		MinComplexity: lower,
+	})
+}
+
+// ComplexityBucket returns the label of the bucket complexity falls in
+func ComplexityBucket(complexity int) string {
+	buckets := complexityBuckets()
+	for _, bucket := range buckets {
+		if bucket.MaxComplexity == nil || complexity <= *bucket.MaxComplexity {
+			return bucket.Label
+		}
+	}
+	return buckets[len(buckets)-1].Label
+}
+
+// RecordOperationCost records an operation's duration as a metric tagged with its complexity
+// bucket, and adds it to the day's totals for OperationCostReport
+func (pm *PerformanceMonitor) RecordOperationCost(ctx context.Context, cost OperationCost) error {
+	bucket := ComplexityBucket(cost.Complexity)
+	now := time.Now()
+
+	err := pm.RecordMetric(ctx, MetricPoint{
+		Name:  GraphQLOperationDurationMetric,
+		Value: float64(cost.Duration.Microseconds()) / 1000,
+		Unit:  "ms",
+		Tags: map[string]string{
+			"operation":         cost.Name,
+			"type":              cost.Type,
+			"complexity_bucket": bucket,
+		},
+		Fields: map[string]interface{}{
+			"complexity": cost.Complexity,
+			"slow":       cost.Slow,
+		},
+		Timestamp: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	key := rediskeys.Key(operationCostKey + now.UTC().Format("2006-01-02"))
+	pipe := pm.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, key, bucket+":count", 1)
+	pipe.HIncrBy(ctx, key, bucket+":duration_us", cost.Duration.Microseconds())
+	if cost.Slow {
+		pipe.HIncrBy(ctx, key, bucket+":slow", 1)
+	}
+	pipe.Expire(ctx, key, operationCostTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store operation cost: %v", err)
+	}
+	return nil
+}
+
+// OperationCostReport returns the operations of the last days, today included, per complexity
+// bucket from the cheapest. Slow cheap operations point at the data layer; slow expensive ones
+// at the query.
+func (pm *PerformanceMonitor) OperationCostReport(ctx context.Context, days int) ([]ComplexityBucketStats, error) {
+	if days < 1 || days > MaxOperationCostReportDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxOperationCostReportDays)
+	}
+
+	today := time.Now().UTC()
+	pipe := pm.redisClient.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, days)
+	for i := range cmds {
+		cmds[i] = pipe.HGetAll(ctx, rediskeys.Key(operationCostKey+today.AddDate(0, 0, -i).Format("2006-01-02")))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load operation costs: %v", err)
+	}
+
+	buckets := complexityBuckets()
+	for _, cmd := range cmds {
+		totals := cmd.Val()
+		for i := range buckets {
+			bucket := &buckets[i]
+			count, _ := strconv.ParseInt(totals[bucket.Label+":count"], 10, 64)
+			slow, _ := strconv.ParseInt(totals[bucket.Label+":slow"], 10, 64)
+			durationUs, _ := strconv.ParseInt(totals[bucket.Label+":duration_us"], 10, 64)
+			bucket.Operations += count
+			bucket.SlowOperations += slow
+			bucket.TotalDuration += time.Duration(durationUs) * time.Microsecond
+		}
+	}
+	return buckets, nil
+}
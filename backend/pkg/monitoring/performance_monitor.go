@@ -8,10 +8,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+const (
+	// Pub/sub health probe
+	PubSubHealthChannel = "health:pubsub"
+	PubSubHealthTimeout = 2 * time.Second
+)
+
 // PerformanceMonitor handles performance monitoring and alerting
 type PerformanceMonitor struct {
 	db             *gorm.DB
@@ -19,6 +26,13 @@ type PerformanceMonitor struct {
 	metrics        sync.Map
 	alertThresholds map[string]AlertThreshold
 	mu             sync.RWMutex
+
+	// Round-trip probe: tokens waiting to be seen by the health subscriber
+	pubSubWaiters  sync.Map
+	pubSubProbe    sync.Once
+
+	// Pages people on critical alerts; nil until SetAlertNotifiers
+	notifications *alertNotifications
 }
 
 // MetricPoint represents a single performance metric measurement
@@ -138,13 +152,27 @@ func NewPerformanceMonitor(db *gorm.DB, redisClient *redis.Client) *PerformanceM
 				Duration:      2 * time.Minute,
 				Enabled:       true,
 			},
-			"database_connections": {
-				MetricName:    "database_connections",
+			DatabasePoolSaturationMetric: {
+				MetricName:    DatabasePoolSaturationMetric,
 				WarningLevel:  80.0,  // 80% of max connections
 				CriticalLevel: 95.0,  // 95% of max connections
 				Duration:      1 * time.Minute,
 				Enabled:       true,
 			},
+			BufferOverflowMetric: {
+				MetricName:    BufferOverflowMetric,
+				WarningLevel:  DefaultOverflowThreshold, // messages dropped by one connection
+				CriticalLevel: 100,
+				Duration:      5 * time.Minute,
+				Enabled:       true,
+			},
+			FanoutSizeMetric: {
+				MetricName:    FanoutSizeMetric,
+				WarningLevel:  DefaultFanoutAlertThreshold, // recipients of one event
+				CriticalLevel: 10 * DefaultFanoutAlertThreshold,
+				Duration:      5 * time.Minute,
+				Enabled:       true,
+			},
 		},
 	}
 	
@@ -158,7 +186,7 @@ func NewPerformanceMonitor(db *gorm.DB, redisClient *redis.Client) *PerformanceM
 // RecordMetric records a performance metric
 func (pm *PerformanceMonitor) RecordMetric(ctx context.Context, metric MetricPoint) error {
 	// Store in Redis for real-time monitoring
-	key := fmt.Sprintf("metrics:%s:%d", metric.Name, metric.Timestamp.Unix())
+	key := rediskeys.Keyf("metrics:%s:%d", metric.Name, metric.Timestamp.Unix())
 	
 	metricData, err := json.Marshal(metric)
 	if err != nil {
@@ -171,14 +199,14 @@ func (pm *PerformanceMonitor) RecordMetric(ctx context.Context, metric MetricPoi
 	pipe.Set(ctx, key, metricData, 24*time.Hour)
 	
 	// Add to time series
-	pipe.ZAdd(ctx, fmt.Sprintf("metrics:series:%s", metric.Name), redis.Z{
+	pipe.ZAdd(ctx, rediskeys.Keyf("metrics:series:%s", metric.Name), redis.Z{
 		Score:  float64(metric.Timestamp.Unix()),
 		Member: metric.Value,
 	})
 	
 	// Keep only last 24 hours of data
 	dayAgo := time.Now().Add(-24 * time.Hour).Unix()
-	pipe.ZRemRangeByScore(ctx, fmt.Sprintf("metrics:series:%s", metric.Name), "-inf", fmt.Sprintf("%d", dayAgo))
+	pipe.ZRemRangeByScore(ctx, rediskeys.Keyf("metrics:series:%s", metric.Name), "-inf", fmt.Sprintf("%d", dayAgo))
 	
 	// Update rolling averages
 	pm.updateRollingAverages(ctx, pipe, metric)
@@ -197,19 +225,19 @@ func (pm *PerformanceMonitor) RecordMetric(ctx context.Context, metric MetricPoi
 // updateRollingAverages updates rolling averages for metrics
 func (pm *PerformanceMonitor) updateRollingAverages(ctx context.Context, pipe redis.Pipeliner, metric MetricPoint) {
 	// 1-minute rolling average
-	minuteKey := fmt.Sprintf("avg:1m:%s:%d", metric.Name, metric.Timestamp.Unix()/60)
+	minuteKey := rediskeys.Keyf("avg:1m:%s:%d", metric.Name, metric.Timestamp.Unix()/60)
 	pipe.LPush(ctx, minuteKey, metric.Value)
 	pipe.LTrim(ctx, minuteKey, 0, 59) // Keep last 60 values
 	pipe.Expire(ctx, minuteKey, 2*time.Minute)
 	
 	// 5-minute rolling average
-	fiveMinuteKey := fmt.Sprintf("avg:5m:%s:%d", metric.Name, metric.Timestamp.Unix()/300)
+	fiveMinuteKey := rediskeys.Keyf("avg:5m:%s:%d", metric.Name, metric.Timestamp.Unix()/300)
 	pipe.LPush(ctx, fiveMinuteKey, metric.Value)
 	pipe.LTrim(ctx, fiveMinuteKey, 0, 299) // Keep last 300 values
 	pipe.Expire(ctx, fiveMinuteKey, 10*time.Minute)
 	
 	// 1-hour rolling average
-	hourKey := fmt.Sprintf("avg:1h:%s:%d", metric.Name, metric.Timestamp.Unix()/3600)
+	hourKey := rediskeys.Keyf("avg:1h:%s:%d", metric.Name, metric.Timestamp.Unix()/3600)
 	pipe.LPush(ctx, hourKey, metric.Value)
 	pipe.LTrim(ctx, hourKey, 0, 3599) // Keep last 3600 values
 	pipe.Expire(ctx, hourKey, 2*time.Hour)
@@ -392,13 +420,66 @@ func (pm *PerformanceMonitor) GetSystemHealth(ctx context.Context) (*SystemHealt
 	health.Details["gc_cycles"] = memStats.NumGC
 	health.Details["goroutines"] = runtime.NumGoroutine()
 	
+	// Realtime features depend on pub/sub delivery, which a PING alone doesn't prove
+	health.Details["pubsub_status"] = "HEALTHY"
+	if err := pm.CheckPubSubRoundTrip(ctx, PubSubHealthTimeout); err != nil {
+		health.Details["pubsub_status"] = "DEGRADED"
+		health.Details["pubsub_error"] = err.Error()
+		if health.Status == "HEALTHY" {
+			health.Status = "DEGRADED"
+		}
+	}
+	
 	return health, nil
 }
 
+// CheckRedisHealth is the lightweight liveness check for Redis
+func (pm *PerformanceMonitor) CheckRedisHealth(ctx context.Context) error {
+	return pm.redisClient.Ping(ctx).Err()
+}
+
+// CheckPubSubRoundTrip publishes a unique token on the health channel and waits for the
+// background subscriber to receive it. Use it for readiness, not liveness.
+func (pm *PerformanceMonitor) CheckPubSubRoundTrip(ctx context.Context, timeout time.Duration) error {
+	pm.pubSubProbe.Do(pm.startPubSubProbe)
+	
+	token := fmt.Sprintf("%d", time.Now().UnixNano())
+	received := make(chan struct{})
+	pm.pubSubWaiters.Store(token, received)
+	defer pm.pubSubWaiters.Delete(token)
+	
+	if err := pm.redisClient.Publish(ctx, rediskeys.Key(PubSubHealthChannel), token).Err(); err != nil {
+		return fmt.Errorf("failed to publish health token: %v", err)
+	}
+	
+	select {
+	case <-received:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("pub/sub round-trip timed out after %s", timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startPubSubProbe keeps a subscription on the health channel open for the lifetime of the monitor
+func (pm *PerformanceMonitor) startPubSubProbe() {
+	pubsub := pm.redisClient.Subscribe(context.Background(), rediskeys.Key(PubSubHealthChannel))
+	
+	go func() {
+		defer pubsub.Close()
+		for msg := range pubsub.Channel() {
+			if waiter, ok := pm.pubSubWaiters.LoadAndDelete(msg.Payload); ok {
+				close(waiter.(chan struct{}))
+			}
+		}
+	}()
+}
+
 // getLatestMetricValue gets the latest value for a metric
 func (pm *PerformanceMonitor) getLatestMetricValue(ctx context.Context, metricName string) (float64, error) {
 	// Get latest value from time series
-	values, err := pm.redisClient.ZRevRange(ctx, fmt.Sprintf("metrics:series:%s", metricName), 0, 0).Result()
+	values, err := pm.redisClient.ZRevRange(ctx, rediskeys.Keyf("metrics:series:%s", metricName), 0, 0).Result()
 	if err != nil || len(values) == 0 {
 		return 0, fmt.Errorf("no data available for metric %s", metricName)
 	}
@@ -414,7 +495,7 @@ func (pm *PerformanceMonitor) getLatestMetricValue(ctx context.Context, metricNa
 
 // getActiveAlerts gets currently active alerts
 func (pm *PerformanceMonitor) getActiveAlerts(ctx context.Context) ([]PerformanceAlert, error) {
-	alertsJSON, err := pm.redisClient.LRange(ctx, "alerts:active", 0, -1).Result()
+	alertsJSON, err := pm.redisClient.LRange(ctx, rediskeys.Key("alerts:active"), 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -482,30 +563,32 @@ func (pm *PerformanceMonitor) storeAlert(ctx context.Context, alert PerformanceA
 	pipe := pm.redisClient.Pipeline()
 	
 	// Add to active alerts
-	pipe.LPush(ctx, "alerts:active", alertJSON)
-	pipe.LTrim(ctx, "alerts:active", 0, 99) // Keep last 100 alerts
+	pipe.LPush(ctx, rediskeys.Key("alerts:active"), alertJSON)
+	pipe.LTrim(ctx, rediskeys.Key("alerts:active"), 0, 99) // Keep last 100 alerts
 	
 	// Add to all alerts history
-	pipe.LPush(ctx, "alerts:history", alertJSON)
-	pipe.LTrim(ctx, "alerts:history", 0, 999) // Keep last 1000 alerts
+	pipe.LPush(ctx, rediskeys.Key("alerts:history"), alertJSON)
+	pipe.LTrim(ctx, rediskeys.Key("alerts:history"), 0, 999) // Keep last 1000 alerts
 	
 	// Set expiry
-	pipe.Expire(ctx, "alerts:active", 24*time.Hour)
-	pipe.Expire(ctx, "alerts:history", 7*24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Key("alerts:active"), 24*time.Hour)
+	pipe.Expire(ctx, rediskeys.Key("alerts:history"), 7*24*time.Hour)
 	
 	// Publish to real-time notification system
-	pipe.Publish(ctx, "performance_alerts", alertJSON)
+	pipe.Publish(ctx, rediskeys.Key("performance_alerts"), alertJSON)
 	
 	pipe.Exec(ctx)
 	
 	fmt.Printf("PERFORMANCE ALERT: %s - %s: %.2f > %.2f\n", 
 		alert.Level, alert.MetricName, alert.Value, alert.Threshold)
+
+	pm.notifyAlert(alert)
 }
 
 // resolveAlert resolves an active alert
 func (pm *PerformanceMonitor) resolveAlert(ctx context.Context, metricName string) {
 	// Get active alerts
-	alertsJSON, err := pm.redisClient.LRange(ctx, "alerts:active", 0, -1).Result()
+	alertsJSON, err := pm.redisClient.LRange(ctx, rediskeys.Key("alerts:active"), 0, -1).Result()
 	if err != nil {
 		return
 	}
@@ -526,9 +609,12 @@ func (pm *PerformanceMonitor) resolveAlert(ctx context.Context, metricName strin
 			
 			if updatedJSON, err := json.Marshal(alert); err == nil {
 				// Publish resolution
-				pm.redisClient.Publish(ctx, "performance_alerts_resolved", updatedJSON)
+				pm.redisClient.Publish(ctx, rediskeys.Key("performance_alerts_resolved"), updatedJSON)
 				fmt.Printf("ALERT RESOLVED: %s - %s\n", alert.Level, alert.MetricName)
 			}
+			if alert.Level == "CRITICAL" {
+				pm.notifyResolved(alert)
+			}
 		} else {
 			// Keep unresolved alerts
 			updatedAlerts = append(updatedAlerts, alertJSON)
@@ -537,14 +623,14 @@ func (pm *PerformanceMonitor) resolveAlert(ctx context.Context, metricName strin
 	
 	// Update active alerts list
 	pipe := pm.redisClient.Pipeline()
-	pipe.Del(ctx, "alerts:active")
+	pipe.Del(ctx, rediskeys.Key("alerts:active"))
 	if len(updatedAlerts) > 0 {
 		// Convert []string to []interface{}
 		interfaceAlerts := make([]interface{}, len(updatedAlerts))
 		for i, alert := range updatedAlerts {
 			interfaceAlerts[i] = alert
 		}
-		pipe.LPush(ctx, "alerts:active", interfaceAlerts...)
+		pipe.LPush(ctx, rediskeys.Key("alerts:active"), interfaceAlerts...)
 	}
 	pipe.Exec(ctx)
 }
@@ -585,7 +671,7 @@ func (pm *PerformanceMonitor) startAlertMonitoring() {
 
 // cleanupOldAlerts removes old resolved alerts
 func (pm *PerformanceMonitor) cleanupOldAlerts(ctx context.Context) {
-	alertsJSON, err := pm.redisClient.LRange(ctx, "alerts:active", 0, -1).Result()
+	alertsJSON, err := pm.redisClient.LRange(ctx, rediskeys.Key("alerts:active"), 0, -1).Result()
 	if err != nil {
 		return
 	}
@@ -607,14 +693,14 @@ func (pm *PerformanceMonitor) cleanupOldAlerts(ctx context.Context) {
 	
 	// Update active alerts list
 	pipe := pm.redisClient.Pipeline()
-	pipe.Del(ctx, "alerts:active")
+	pipe.Del(ctx, rediskeys.Key("alerts:active"))
 	if len(activeAlerts) > 0 {
 		// Convert []string to []interface{}
 		interfaceAlerts := make([]interface{}, len(activeAlerts))
 		for i, alert := range activeAlerts {
 			interfaceAlerts[i] = alert
 		}
-		pipe.LPush(ctx, "alerts:active", interfaceAlerts...)
+		pipe.LPush(ctx, rediskeys.Key("alerts:active"), interfaceAlerts...)
 	}
 	pipe.Exec(ctx)
 }
@@ -622,7 +708,7 @@ func (pm *PerformanceMonitor) cleanupOldAlerts(ctx context.Context) {
 // GetMetricsData gets historical metrics data
 func (pm *PerformanceMonitor) GetMetricsData(ctx context.Context, metricName string, startTime, endTime time.Time) ([]MetricPoint, error) {
 	// Get data from time series
-	values, err := pm.redisClient.ZRangeByScore(ctx, fmt.Sprintf("metrics:series:%s", metricName), &redis.ZRangeBy{
+	values, err := pm.redisClient.ZRangeByScore(ctx, rediskeys.Keyf("metrics:series:%s", metricName), &redis.ZRangeBy{
 		Min: fmt.Sprintf("%d", startTime.Unix()),
 		Max: fmt.Sprintf("%d", endTime.Unix()),
 	}).Result()
@@ -169,6 +169,82 @@ TRU Activity System
 	}
 }
 
+// SendPasswordResetEmail sends the password reset link to the user
+func (ns *NotificationService) SendPasswordResetEmail(user *models.User, resetURL string) error {
+	subject := "TRU Activity - Password Reset"
+	body := fmt.Sprintf(`Dear %s %s,
+
+We received a request to reset the password for your TRU Activity account.
+
+Use the link below to choose a new password. The link expires in 30 minutes.
+
+%s
+
+If you did not request a password reset, you can ignore this email.
+
+Best regards,
+TRU Activity System
+`, user.FirstName, user.LastName, resetURL)
+
+	return ns.sendEmail(user.Email, subject, body)
+}
+
+// SendAccountInviteEmail tells an imported user their account exists and links to setting a password
+func (ns *NotificationService) SendAccountInviteEmail(user *models.User, setPasswordURL string) error {
+	subject := "TRU Activity - Your Account Is Ready"
+	body := fmt.Sprintf(`Dear %s %s,
+
+An account has been created for you in TRU Activity with student ID %s.
+
+Use the link below to choose your password and sign in. The link expires in 7 days; after that,
+use "Forgot password" on the sign-in page to get a new one.
+
+%s
+
+Best regards,
+TRU Activity System
+`, user.FirstName, user.LastName, user.StudentID, setPasswordURL)
+
+	return ns.sendEmail(user.Email, subject, body)
+}
+
+// SendUnstaffedActivityAlert warns the activity's faculty admins that nobody is assigned to scan QR codes
+func (ns *NotificationService) SendUnstaffedActivityAlert(activity *models.Activity) error {
+	if activity.FacultyID == nil {
+		return nil
+	}
+
+	var facultyAdmins []models.User
+	if err := ns.DB.Where("faculty_id = ? AND role = ?", *activity.FacultyID, models.UserRoleFacultyAdmin).Find(&facultyAdmins).Error; err != nil {
+		return fmt.Errorf("failed to fetch faculty admins: %v", err)
+	}
+
+	subject := fmt.Sprintf("TRU Activity - No scanner assigned to %s", activity.Title)
+	body := fmt.Sprintf(`Dear Faculty Administrator,
+
+The activity "%s" starts on %s but no admin is assigned to scan QR codes for it.
+
+Please assign at least one scanner before the activity starts so attendance can be recorded.
+
+Best regards,
+TRU Activity System
+`, activity.Title, activity.StartDate.Format("2006-01-02 15:04"))
+
+	sent := 0
+	for _, admin := range facultyAdmins {
+		if err := ns.sendEmail(admin.Email, subject, body); err != nil {
+			log.Printf("Failed to send unstaffed activity alert to %s: %v", admin.Email, err)
+			continue
+		}
+		sent++
+	}
+
+	if len(facultyAdmins) > 0 && sent == 0 {
+		return fmt.Errorf("failed to notify any faculty admin")
+	}
+	return nil
+}
+
 func (ns *NotificationService) sendEmail(to, subject, body string) error {
 	auth := smtp.PlainAuth("", ns.SMTPConfig.Username, ns.SMTPConfig.Password, ns.SMTPConfig.Host)
 
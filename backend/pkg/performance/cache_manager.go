@@ -3,18 +3,32 @@ package performance
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// ErrCacheMiss is returned by Get when the key is not cached
+var ErrCacheMiss = errors.New("cache miss")
+
+// ErrCacheUnavailable is returned instead of the Redis error when the cache can't be read,
+// so callers treat it like a miss and fall back to the source
+var ErrCacheUnavailable = errors.New("cache unavailable")
+
 // CacheManager handles all caching operations
 type CacheManager struct {
 	redisClient *redis.Client
 	db         *gorm.DB
+	breaker    *CircuitBreaker
 }
 
 // CacheConfig defines caching configuration for different types
@@ -63,6 +77,20 @@ var (
 		TTL:       5 * time.Minute,
 		Tags:      []string{"queries"},
 	}
+	
+	// Feature flags are read on nearly every request and invalidated whenever one changes
+	FeatureFlagCacheConfig = CacheConfig{
+		KeyPrefix: "feature_flags:",
+		TTL:       time.Hour,
+		Tags:      []string{"feature_flags"},
+	}
+
+	// The admin dashboard is polled from every open landing page, so even a short TTL saves most queries
+	DashboardCacheConfig = CacheConfig{
+		KeyPrefix: "dashboard:",
+		TTL:       30 * time.Second,
+		Tags:      []string{"dashboard"},
+	}
 )
 
 // NewCacheManager creates a new cache manager
@@ -70,9 +98,25 @@ func NewCacheManager(redisClient *redis.Client, db *gorm.DB) *CacheManager {
 	return &CacheManager{
 		redisClient: redisClient,
 		db:         db,
+		breaker:    NewCircuitBreaker("cache", DefaultBreakerThreshold, DefaultBreakerCooldown),
 	}
 }
 
+// call runs fn against Redis through the circuit breaker, skipping it while Redis is failing
+func (cm *CacheManager) call(fn func() error) error {
+	if !cm.breaker.Allow() {
+		return ErrCacheUnavailable
+	}
+	err := fn()
+	cm.breaker.Record(err)
+	return err
+}
+
+// BreakerStats reports the cache circuit breaker state for health checks
+func (cm *CacheManager) BreakerStats() map[string]interface{} {
+	return cm.breaker.Stats()
+}
+
 // Set stores a value in cache with the given configuration
 func (cm *CacheManager) Set(ctx context.Context, key string, value interface{}, config CacheConfig) error {
 	// Serialize value
@@ -82,7 +126,7 @@ func (cm *CacheManager) Set(ctx context.Context, key string, value interface{},
 	}
 	
 	// Build full key
-	fullKey := config.KeyPrefix + key
+	fullKey := rediskeys.Key(config.KeyPrefix + key)
 	
 	// Store in Redis
 	pipe := cm.redisClient.Pipeline()
@@ -92,24 +136,39 @@ func (cm *CacheManager) Set(ctx context.Context, key string, value interface{},
 	
 	// Add to tag sets for cache invalidation
 	for _, tag := range config.Tags {
-		tagKey := "tag:" + tag
+		tagKey := rediskeys.Key("tag:" + tag)
 		pipe.SAdd(ctx, tagKey, fullKey)
 		pipe.Expire(ctx, tagKey, config.TTL+time.Hour) // Keep tags longer
 	}
 	
 	// Execute pipeline
-	_, err = pipe.Exec(ctx)
-	return err
+	return cm.call(func() error {
+		_, err := pipe.Exec(ctx)
+		return err
+	})
 }
 
-// Get retrieves a value from cache
+// Get retrieves a value from cache. It fails open: a Redis error is logged and returned as
+// ErrCacheUnavailable, which callers handle like ErrCacheMiss by reading the source.
 func (cm *CacheManager) Get(ctx context.Context, key string, config CacheConfig, dest interface{}) error {
-	fullKey := config.KeyPrefix + key
+	fullKey := rediskeys.Key(config.KeyPrefix + key)
 	
-	data, err := cm.redisClient.Get(ctx, fullKey).Result()
-	if err != nil {
+	var data string
+	err := cm.call(func() error {
+		var err error
+		data, err = cm.redisClient.Get(ctx, fullKey).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return ErrCacheMiss
+	}
+	if err == ErrCacheUnavailable {
 		return err
 	}
+	if err != nil {
+		log.Printf("Cache read of %s failed, falling back to source: %v", fullKey, err)
+		return ErrCacheUnavailable
+	}
 	
 	return json.Unmarshal([]byte(data), dest)
 }
@@ -129,7 +188,7 @@ func (cm *CacheManager) GetOrSet(ctx context.Context, key string, config CacheCo
 	}
 	
 	// Store in cache
-	if err := cm.Set(ctx, key, value, config); err != nil {
+	if err := cm.Set(ctx, key, value, config); err != nil && err != ErrCacheUnavailable {
 		// Log error but don't fail the request
 		fmt.Printf("Warning: failed to cache value for key %s: %v\n", key, err)
 	}
@@ -145,16 +204,23 @@ func (cm *CacheManager) GetOrSet(ctx context.Context, key string, config CacheCo
 
 // Delete removes a key from cache
 func (cm *CacheManager) Delete(ctx context.Context, key string, config CacheConfig) error {
-	fullKey := config.KeyPrefix + key
-	return cm.redisClient.Del(ctx, fullKey).Err()
+	fullKey := rediskeys.Key(config.KeyPrefix + key)
+	return cm.call(func() error {
+		return cm.redisClient.Del(ctx, fullKey).Err()
+	})
 }
 
 // InvalidateByTag removes all cache entries with the given tag
 func (cm *CacheManager) InvalidateByTag(ctx context.Context, tag string) error {
-	tagKey := "tag:" + tag
+	tagKey := rediskeys.Key("tag:" + tag)
 	
 	// Get all keys with this tag
-	keys, err := cm.redisClient.SMembers(ctx, tagKey).Result()
+	var keys []string
+	err := cm.call(func() error {
+		var err error
+		keys, err = cm.redisClient.SMembers(ctx, tagKey).Result()
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -168,8 +234,10 @@ func (cm *CacheManager) InvalidateByTag(ctx context.Context, tag string) error {
 	pipe.Del(ctx, keys...)
 	pipe.Del(ctx, tagKey) // Also delete the tag set
 	
-	_, err = pipe.Exec(ctx)
-	return err
+	return cm.call(func() error {
+		_, err := pipe.Exec(ctx)
+		return err
+	})
 }
 
 // Query cache for GraphQL queries
@@ -183,10 +251,10 @@ func (cm *CacheManager) GetCachedQuery(ctx context.Context, queryHash string, va
 	return cm.Get(ctx, key, QueryCacheConfig, dest)
 }
 
+// hashVariables keys cached results by variables; identical variables give the same key
+// whatever order they were set in
 func (cm *CacheManager) hashVariables(variables map[string]interface{}) string {
-	// Simple hash of variables for cache key
-	data, _ := json.Marshal(variables)
-	return fmt.Sprintf("%x", data)[:16] // Use first 16 chars of hex
+	return utils.HashKey(variables)
 }
 
 // Specific caching methods for different entities
@@ -197,7 +265,7 @@ func (cm *CacheManager) CacheUser(ctx context.Context, userID string, user inter
 }
 
 func (cm *CacheManager) GetCachedUser(ctx context.Context, userID string, dest interface{}) error {
-	return cm.Get(ctx, userID, UserCacheConfig, dest)
+	return cm.GetWithStats(ctx, userID, UserCacheConfig, dest)
 }
 
 func (cm *CacheManager) InvalidateUser(ctx context.Context, userID string) error {
@@ -218,7 +286,7 @@ func (cm *CacheManager) InvalidateActivity(ctx context.Context, activityID strin
 	pipe := cm.redisClient.Pipeline()
 	
 	// Delete the activity itself
-	fullKey := ActivityCacheConfig.KeyPrefix + activityID
+	fullKey := rediskeys.Key(ActivityCacheConfig.KeyPrefix + activityID)
 	pipe.Del(ctx, fullKey)
 	
 	// Invalidate related queries
@@ -237,6 +305,14 @@ func (cm *CacheManager) GetCachedFaculty(ctx context.Context, facultyID string,
 	return cm.Get(ctx, facultyID, FacultyCacheConfig, dest)
 }
 
+// InvalidateFaculty drops the cached faculty and every cached faculty query
+func (cm *CacheManager) InvalidateFaculty(ctx context.Context, facultyID string) error {
+	if err := cm.Delete(ctx, facultyID, FacultyCacheConfig); err != nil {
+		return err
+	}
+	return cm.InvalidateByTag(ctx, "faculties")
+}
+
 // Metrics caching with automatic refresh
 func (cm *CacheManager) CacheMetrics(ctx context.Context, metricsKey string, metrics interface{}) error {
 	return cm.Set(ctx, metricsKey, metrics, MetricsCacheConfig)
@@ -294,19 +370,21 @@ func (cm *CacheManager) SetMany(ctx context.Context, keyValues map[string]interf
 			return fmt.Errorf("failed to marshal value for key %s: %v", key, err)
 		}
 		
-		fullKey := config.KeyPrefix + key
+		fullKey := rediskeys.Key(config.KeyPrefix + key)
 		pipe.Set(ctx, fullKey, data, config.TTL)
 		
 		// Add to tag sets
 		for _, tag := range config.Tags {
-			tagKey := "tag:" + tag
+			tagKey := rediskeys.Key("tag:" + tag)
 			pipe.SAdd(ctx, tagKey, fullKey)
 			pipe.Expire(ctx, tagKey, config.TTL+time.Hour)
 		}
 	}
 	
-	_, err := pipe.Exec(ctx)
-	return err
+	return cm.call(func() error {
+		_, err := pipe.Exec(ctx)
+		return err
+	})
 }
 
 func (cm *CacheManager) GetMany(ctx context.Context, keys []string, config CacheConfig) (map[string]interface{}, error) {
@@ -317,13 +395,20 @@ func (cm *CacheManager) GetMany(ctx context.Context, keys []string, config Cache
 	// Build full keys
 	fullKeys := make([]string, len(keys))
 	for i, key := range keys {
-		fullKeys[i] = config.KeyPrefix + key
+		fullKeys[i] = rediskeys.Key(config.KeyPrefix + key)
 	}
 	
-	// Get all values
-	values, err := cm.redisClient.MGet(ctx, fullKeys...).Result()
-	if err != nil {
-		return nil, err
+	// Get all values; like Get, a failing cache reads as all misses
+	var values []interface{}
+	if err := cm.call(func() error {
+		var err error
+		values, err = cm.redisClient.MGet(ctx, fullKeys...).Result()
+		return err
+	}); err != nil {
+		if err != ErrCacheUnavailable {
+			log.Printf("Cache read of %d keys failed, falling back to source: %v", len(keys), err)
+		}
+		return make(map[string]interface{}), nil
 	}
 	
 	// Parse results
@@ -341,82 +426,100 @@ func (cm *CacheManager) GetMany(ctx context.Context, keys []string, config Cache
 }
 
 // Cache warming - preload frequently accessed data
-func (cm *CacheManager) WarmCache(ctx context.Context) error {
-	// Warm up faculty cache
-	go cm.warmFaculties(ctx)
-	
-	// Warm up active activities
-	go cm.warmActiveActivities(ctx)
-	
-	// Warm up system metrics
-	go cm.warmSystemMetrics(ctx)
-	
-	return nil
+
+// WarmCache preloads faculties, active activities and headline metrics, returning how many
+// entries were cached. A failing warmer doesn't stop the others.
+func (cm *CacheManager) WarmCache(ctx context.Context) (int, error) {
+	warmers := []struct {
+		name string
+		warm func(context.Context) (int, error)
+	}{
+		{"faculties", cm.warmFaculties},
+		{"active activities", cm.warmActiveActivities},
+		{"system metrics", cm.warmSystemMetrics},
+	}
+
+	warmed := 0
+	var errs []error
+	for _, w := range warmers {
+		n, err := w.warm(ctx)
+		warmed += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.name, err))
+		}
+	}
+	return warmed, errors.Join(errs...)
 }
 
-func (cm *CacheManager) warmFaculties(ctx context.Context) {
-	var faculties []interface{}
-	if err := cm.db.WithContext(ctx).Find(&faculties).Error; err != nil {
-		fmt.Printf("Failed to warm faculty cache: %v\n", err)
-		return
+func (cm *CacheManager) warmFaculties(ctx context.Context) (int, error) {
+	var faculties []models.Faculty
+	if err := cm.db.WithContext(ctx).Where("is_active = ?", true).Find(&faculties).Error; err != nil {
+		return 0, err
 	}
-	
-	keyValues := make(map[string]interface{})
-	for _, faculty := range faculties {
-		if f, ok := faculty.(map[string]interface{}); ok {
-			if id, exists := f["id"]; exists {
-				keyValues[fmt.Sprintf("%v", id)] = faculty
-			}
-		}
+
+	keyValues := make(map[string]interface{}, len(faculties))
+	for i := range faculties {
+		keyValues[strconv.FormatUint(uint64(faculties[i].ID), 10)] = &faculties[i]
 	}
-	
-	cm.SetMany(ctx, keyValues, FacultyCacheConfig)
+	if err := cm.SetMany(ctx, keyValues, FacultyCacheConfig); err != nil {
+		return 0, err
+	}
+	return len(keyValues), nil
 }
 
-func (cm *CacheManager) warmActiveActivities(ctx context.Context) {
-	var activities []interface{}
-	if err := cm.db.WithContext(ctx).Where("status = ?", "ACTIVE").Find(&activities).Error; err != nil {
-		fmt.Printf("Failed to warm activity cache: %v\n", err)
-		return
+func (cm *CacheManager) warmActiveActivities(ctx context.Context) (int, error) {
+	var activities []models.Activity
+	if err := cm.db.WithContext(ctx).Where("status = ?", models.ActivityStatusActive).Find(&activities).Error; err != nil {
+		return 0, err
 	}
-	
-	keyValues := make(map[string]interface{})
-	for _, activity := range activities {
-		if a, ok := activity.(map[string]interface{}); ok {
-			if id, exists := a["id"]; exists {
-				keyValues[fmt.Sprintf("%v", id)] = activity
-			}
-		}
+
+	keyValues := make(map[string]interface{}, len(activities))
+	for i := range activities {
+		keyValues[strconv.FormatUint(uint64(activities[i].ID), 10)] = &activities[i]
 	}
-	
-	cm.SetMany(ctx, keyValues, ActivityCacheConfig)
+	if err := cm.SetMany(ctx, keyValues, ActivityCacheConfig); err != nil {
+		return 0, err
+	}
+	return len(keyValues), nil
 }
 
-func (cm *CacheManager) warmSystemMetrics(ctx context.Context) {
-	// Pre-compute and cache common metrics
-	metrics := map[string]func() (interface{}, error){
-		"daily_stats": func() (interface{}, error) {
-			// Compute daily statistics
-			return map[string]interface{}{
-				"total_users":       0, // Implement actual computation
-				"active_activities": 0,
-				"daily_scans":      0,
-			}, nil
+func (cm *CacheManager) warmSystemMetrics(ctx context.Context) (int, error) {
+	db := cm.db.WithContext(ctx)
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var totalUsers, activeActivities, dailyScans, totalFaculties, totalStudents int64
+	counts := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{db.Model(&models.User{}), &totalUsers},
+		{db.Model(&models.Activity{}).Where("status = ?", models.ActivityStatusActive), &activeActivities},
+		{db.Model(&models.QRScanLog{}).Where("scan_timestamp >= ?", startOfDay), &dailyScans},
+		{db.Model(&models.Faculty{}).Where("is_active = ?", true), &totalFaculties},
+		{db.Model(&models.User{}).Where("role = ?", models.UserRoleStudent), &totalStudents},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	metrics := map[string]interface{}{
+		"daily_stats": map[string]interface{}{
+			"total_users":       totalUsers,
+			"active_activities": activeActivities,
+			"daily_scans":       dailyScans,
 		},
-		"faculty_counts": func() (interface{}, error) {
-			// Compute faculty statistics
-			return map[string]interface{}{
-				"total_faculties": 0,
-				"total_students":  0,
-			}, nil
+		"faculty_counts": map[string]interface{}{
+			"total_faculties": totalFaculties,
+			"total_students":  totalStudents,
 		},
 	}
-	
-	for key, computeFunc := range metrics {
-		if value, err := computeFunc(); err == nil {
-			cm.Set(ctx, key, value, MetricsCacheConfig)
-		}
+	if err := cm.SetMany(ctx, metrics, MetricsCacheConfig); err != nil {
+		return 0, err
 	}
+	return len(metrics), nil
 }
 
 // Cache statistics and monitoring
@@ -434,8 +537,8 @@ func (cm *CacheManager) GetCacheStats(ctx context.Context) (map[string]interface
 	// Get hit/miss ratios for different cache types
 	cacheTypes := []string{"user", "activity", "faculty", "metrics"}
 	for _, cacheType := range cacheTypes {
-		hitKey := fmt.Sprintf("cache_hits:%s", cacheType)
-		missKey := fmt.Sprintf("cache_misses:%s", cacheType)
+		hitKey := rediskeys.Keyf("cache_hits:%s", cacheType)
+		missKey := rediskeys.Keyf("cache_misses:%s", cacheType)
 		
 		hits, _ := cm.redisClient.Get(ctx, hitKey).Int64()
 		misses, _ := cm.redisClient.Get(ctx, missKey).Int64()
@@ -458,15 +561,21 @@ func (cm *CacheManager) GetCacheStats(ctx context.Context) (map[string]interface
 
 // Record cache hit/miss for monitoring
 func (cm *CacheManager) recordCacheHit(ctx context.Context, cacheType string) {
-	key := fmt.Sprintf("cache_hits:%s", cacheType)
-	cm.redisClient.Incr(ctx, key)
-	cm.redisClient.Expire(ctx, key, 24*time.Hour)
+	cm.incrementCounter(ctx, rediskeys.Keyf("cache_hits:%s", cacheType))
 }
 
 func (cm *CacheManager) recordCacheMiss(ctx context.Context, cacheType string) {
-	key := fmt.Sprintf("cache_misses:%s", cacheType)
-	cm.redisClient.Incr(ctx, key)
-	cm.redisClient.Expire(ctx, key, 24*time.Hour)
+	cm.incrementCounter(ctx, rediskeys.Keyf("cache_misses:%s", cacheType))
+}
+
+func (cm *CacheManager) incrementCounter(ctx context.Context, key string) {
+	cm.call(func() error {
+		pipe := cm.redisClient.Pipeline()
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, 24*time.Hour)
+		_, err := pipe.Exec(ctx)
+		return err
+	})
 }
 
 // Enhanced Get method with hit/miss tracking
@@ -476,64 +585,9 @@ func (cm *CacheManager) GetWithStats(ctx context.Context, key string, config Cac
 	err := cm.Get(ctx, key, config, dest)
 	if err == nil {
 		cm.recordCacheHit(ctx, cacheType)
-	} else {
+	} else if err == ErrCacheMiss {
 		cm.recordCacheMiss(ctx, cacheType)
 	}
 	
 	return err
 }
-
-// Cache middleware for automatic caching
-type CacheMiddleware struct {
-	cacheManager *CacheManager
-}
-
-func NewCacheMiddleware(cacheManager *CacheManager) *CacheMiddleware {
-	return &CacheMiddleware{
-		cacheManager: cacheManager,
-	}
-}
-
-// Auto-cache GraphQL queries based on operation name
-func (cm *CacheMiddleware) CacheQuery(ctx context.Context, operationName string, variables map[string]interface{}, result interface{}) bool {
-	// Define cacheable operations
-	cacheableOperations := map[string]CacheConfig{
-		"GetActivities":      ActivityCacheConfig,
-		"GetFaculties":       FacultyCacheConfig,
-		"GetSystemMetrics":   MetricsCacheConfig,
-		"GetFacultyMetrics":  MetricsCacheConfig,
-		"GetUser":            UserCacheConfig,
-	}
-	
-	config, cacheable := cacheableOperations[operationName]
-	if !cacheable {
-		return false
-	}
-	
-	// Try to get from cache
-	key := fmt.Sprintf("%s:%s", operationName, cm.cacheManager.hashVariables(variables))
-	if err := cm.cacheManager.Get(ctx, key, config, result); err == nil {
-		return true // Cache hit
-	}
-	
-	return false // Cache miss
-}
-
-func (cm *CacheMiddleware) StoreQueryResult(ctx context.Context, operationName string, variables map[string]interface{}, result interface{}) {
-	// Define cacheable operations
-	cacheableOperations := map[string]CacheConfig{
-		"GetActivities":      ActivityCacheConfig,
-		"GetFaculties":       FacultyCacheConfig,
-		"GetSystemMetrics":   MetricsCacheConfig,
-		"GetFacultyMetrics":  MetricsCacheConfig,
-		"GetUser":            UserCacheConfig,
-	}
-	
-	config, cacheable := cacheableOperations[operationName]
-	if !cacheable {
-		return
-	}
-	
-	key := fmt.Sprintf("%s:%s", operationName, cm.cacheManager.hashVariables(variables))
-	cm.cacheManager.Set(ctx, key, result, config)
-}
\ No newline at end of file
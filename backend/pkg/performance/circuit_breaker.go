@@ -0,0 +1,137 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreaker stops calls to a failing dependency for a cooldown after threshold
+// consecutive failures, then lets one trial call through to decide whether to close again
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial call is in flight
+}
+
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow reports whether a call may go ahead
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = BreakerHalfOpen
+		cb.trial = true
+		return true
+	case BreakerHalfOpen:
+		if cb.trial {
+			return false
+		}
+		cb.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record feeds the outcome of an allowed call back into the breaker. A cache miss counts
+// as success; a cancelled caller says nothing about the dependency and is ignored.
+func (cb *CircuitBreaker) Record(err error) {
+	if errors.Is(err, context.Canceled) {
+		cb.mu.Lock()
+		cb.trial = false
+		cb.mu.Unlock()
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err == nil || errors.Is(err, redis.Nil) {
+		if cb.state != BreakerClosed {
+			log.Printf("%s circuit breaker closed", cb.name)
+		}
+		cb.state = BreakerClosed
+		cb.failures = 0
+		cb.trial = false
+		return
+	}
+
+	cb.failures++
+	if cb.state == BreakerHalfOpen || (cb.state == BreakerClosed && cb.failures >= cb.threshold) {
+		log.Printf("%s circuit breaker opened after %d consecutive failures: %v", cb.name, cb.failures, err)
+		cb.state = BreakerOpen
+		cb.openedAt = time.Now()
+	}
+	cb.trial = false
+}
+
+// State returns the current state; an open breaker whose cooldown has passed reports half open
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == BreakerOpen && time.Since(cb.openedAt) >= cb.cooldown {
+		return BreakerHalfOpen
+	}
+	return cb.state
+}
+
+// Stats describes the breaker for health checks
+func (cb *CircuitBreaker) Stats() map[string]interface{} {
+	state := cb.State()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"state":                string(state),
+		"consecutive_failures": cb.failures,
+	}
+	if state != BreakerClosed {
+		stats["opened_at"] = cb.openedAt.Unix()
+	}
+	return stats
+}
@@ -3,10 +3,13 @@ package performance
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -30,6 +33,7 @@ type dataLoader struct {
 	cache       *sync.Map
 	redisClient *redis.Client
 	config      CacheConfig
+	breaker     *CircuitBreaker
 	
 	// Batching fields
 	batch       []string
@@ -50,6 +54,8 @@ type DataLoaderConfig struct {
 	BatchSize  int
 	BatchDelay time.Duration
 	Cache      CacheConfig
+	// Breaker guards the Redis cache; loaders get their own when nil
+	Breaker *CircuitBreaker
 }
 
 // NewDataLoader creates a new DataLoader instance
@@ -59,6 +65,7 @@ func NewDataLoader(batchFunc BatchFunc, redisClient *redis.Client, config DataLo
 		cache:       &sync.Map{},
 		redisClient: redisClient,
 		config:      config.Cache,
+		breaker:     config.Breaker,
 		waiting:     make(map[string][]chan *loadResult),
 		batchSize:   config.BatchSize,
 		batchDelay:  config.BatchDelay,
@@ -70,6 +77,9 @@ func NewDataLoader(batchFunc BatchFunc, redisClient *redis.Client, config DataLo
 	if dl.batchDelay == 0 {
 		dl.batchDelay = 16 * time.Millisecond
 	}
+	if dl.breaker == nil {
+		dl.breaker = NewCircuitBreaker("dataloader cache", DefaultBreakerThreshold, DefaultBreakerCooldown)
+	}
 	
 	return dl
 }
@@ -171,7 +181,9 @@ func (dl *dataLoader) Clear(key string) {
 	
 	// Also clear from Redis if enabled
 	if dl.config.EnableRedis && dl.redisClient != nil {
-		go dl.redisClient.Del(context.Background(), dl.redisKey(key))
+		go dl.callRedis(func() error {
+			return dl.redisClient.Del(context.Background(), dl.redisKey(key)).Err()
+		})
 	}
 }
 
@@ -243,15 +255,37 @@ func (dl *dataLoader) executeBatch(ctx context.Context) {
 
 // Redis helper methods
 func (dl *dataLoader) redisKey(key string) string {
-	return fmt.Sprintf("dataloader:%s", key)
+	return rediskeys.Keyf("dataloader:%s", key)
+}
+
+// callRedis runs fn through the circuit breaker. Callers treat any error as a miss and
+// load from the batch function instead.
+func (dl *dataLoader) callRedis(fn func() error) error {
+	if !dl.breaker.Allow() {
+		return ErrCacheUnavailable
+	}
+	err := fn()
+	dl.breaker.Record(err)
+	return err
 }
 
 func (dl *dataLoader) loadFromRedis(ctx context.Context, key string) (interface{}, error) {
-	return dl.redisClient.Get(ctx, dl.redisKey(key)).Result()
+	var value string
+	err := dl.callRedis(func() error {
+		var err error
+		value, err = dl.redisClient.Get(ctx, dl.redisKey(key)).Result()
+		return err
+	})
+	if err != nil && err != redis.Nil && err != ErrCacheUnavailable {
+		log.Printf("DataLoader cache read of %s failed, loading from source: %v", key, err)
+	}
+	return value, err
 }
 
 func (dl *dataLoader) storeInRedis(ctx context.Context, key string, value interface{}) {
-	dl.redisClient.Set(ctx, dl.redisKey(key), value, dl.config.TTL)
+	dl.callRedis(func() error {
+		return dl.redisClient.Set(ctx, dl.redisKey(key), value, dl.config.TTL).Err()
+	})
 }
 
 // Specific DataLoaders for different entity types
@@ -423,7 +457,8 @@ func NewFacultyDataLoader(db *gorm.DB, redisClient *redis.Client) *FacultyDataLo
 	}
 }
 
-// ParticipationDataLoader for loading participations by user or activity
+// ParticipationDataLoader for loading participations by user or activity, each with its
+// user and its activity's faculty and department
 type ParticipationDataLoader struct {
 	DataLoader
 	db *gorm.DB
@@ -434,12 +469,9 @@ func NewParticipationDataLoader(db *gorm.DB, redisClient *redis.Client) *Partici
 		// Keys can be in format "user:userID" or "activity:activityID"
 		userIDs := []string{}
 		activityIDs := []string{}
-		keyMap := make(map[string]string) // maps actual key to type:id
-		
 		for _, key := range keys {
 			parts := strings.SplitN(key, ":", 2)
 			if len(parts) == 2 {
-				keyMap[key] = key
 				if parts[0] == "user" {
 					userIDs = append(userIDs, parts[1])
 				} else if parts[0] == "activity" {
@@ -448,49 +480,42 @@ func NewParticipationDataLoader(db *gorm.DB, redisClient *redis.Client) *Partici
 			}
 		}
 		
-		var participations []interface{}
-		
-		// Query for user participations
-		if len(userIDs) > 0 {
-			var userParticipations []interface{}
-			if err := db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&userParticipations).Error; err != nil {
-				return nil, err
+		participationGroups := make(map[string][]models.Participation)
+		load := func(column string, ids []string, keyOf func(p *models.Participation) string) error {
+			if len(ids) == 0 {
+				return nil
 			}
-			participations = append(participations, userParticipations...)
-		}
-		
-		// Query for activity participations
-		if len(activityIDs) > 0 {
-			var activityParticipations []interface{}
-			if err := db.WithContext(ctx).Where("activity_id IN ?", activityIDs).Find(&activityParticipations).Error; err != nil {
-				return nil, err
+			var participations []models.Participation
+			if err := db.WithContext(ctx).
+				Preload("User").
+				Preload("Activity.Faculty").
+				Preload("Activity.Department").
+				Where(column+" IN ?", ids).
+				Find(&participations).Error; err != nil {
+				return err
+			}
+			for i := range participations {
+				key := keyOf(&participations[i])
+				participationGroups[key] = append(participationGroups[key], participations[i])
 			}
-			participations = append(participations, activityParticipations...)
+			return nil
 		}
 		
-		// Group participations by key
-		participationGroups := make(map[string][]interface{})
-		for _, participation := range participations {
-			if p, ok := participation.(map[string]interface{}); ok {
-				if userID, exists := p["user_id"]; exists {
-					key := fmt.Sprintf("user:%v", userID)
-					participationGroups[key] = append(participationGroups[key], participation)
-				}
-				if activityID, exists := p["activity_id"]; exists {
-					key := fmt.Sprintf("activity:%v", activityID)
-					participationGroups[key] = append(participationGroups[key], participation)
-				}
-			}
+		if err := load("user_id", userIDs, func(p *models.Participation) string {
+			return fmt.Sprintf("user:%d", p.UserID)
+		}); err != nil {
+			return nil, err
+		}
+		if err := load("activity_id", activityIDs, func(p *models.Participation) string {
+			return fmt.Sprintf("activity:%d", p.ActivityID)
+		}); err != nil {
+			return nil, err
 		}
 		
 		// Return results in the same order as requested keys
 		results := make([]interface{}, len(keys))
 		for i, key := range keys {
-			if group, exists := participationGroups[key]; exists {
-				results[i] = group
-			} else {
-				results[i] = []interface{}{}
-			}
+			results[i] = participationGroups[key]
 		}
 		
 		return results, nil
@@ -500,11 +525,13 @@ func NewParticipationDataLoader(db *gorm.DB, redisClient *redis.Client) *Partici
 		BatchSize:  40,
 		BatchDelay: 16 * time.Millisecond,
 		Cache: CacheConfig{
-			KeyPrefix:   "participation:",
-			TTL:         5 * time.Minute, // Participations change frequently
-			Tags:        []string{"participations"},
-			MaxSize:     800,
-			EnableRedis: true,
+			KeyPrefix: "participation:",
+			TTL:       5 * time.Minute, // Participations change frequently
+			Tags:      []string{"participations"},
+			MaxSize:   800,
+			// Loaded participations are structs rather than encoded strings, and a withdrawal
+			// has to show up on the next request, so they're only cached for the operation
+			EnableRedis: false,
 		},
 	}
 	
@@ -514,6 +541,17 @@ func NewParticipationDataLoader(db *gorm.DB, redisClient *redis.Client) *Partici
 	}
 }
 
+// LoadByUser returns a user's participations, batched with the other loads of the operation.
+// The slice is shared with the loader's cache and must not be modified.
+func (l *ParticipationDataLoader) LoadByUser(ctx context.Context, userID uint) ([]models.Participation, error) {
+	value, err := l.Load(ctx, fmt.Sprintf("user:%d", userID))
+	if err != nil {
+		return nil, err
+	}
+	participations, _ := value.([]models.Participation)
+	return participations, nil
+}
+
 // DataLoaderContainer holds all DataLoaders
 type DataLoaderContainer struct {
 	User          *UserDataLoader
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript drops hits older than the window from a sorted set scored by time and
+// records the hit if fewer than the limit (ARGV[5]) remain. Only allowed hits are recorded,
+// so the set never holds more than the limit and a client retrying while blocked gets in as
+// soon as its earlier hits leave the window. Blocked hits are counted in KEYS[2] instead.
+// It returns the hits recorded in the window, the oldest one's score and the blocked hits.
+var slidingWindowScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1] - ARGV[2])
+local count = redis.call("ZCARD", KEYS[1])
+local blocked = 0
+if count < tonumber(ARGV[5]) then
+	redis.call("ZADD", KEYS[1], ARGV[1], ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[4])
+	count = count + 1
+else
+	blocked = redis.call("INCR", KEYS[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[4])
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {count, tonumber(oldest[2]) or tonumber(ARGV[1]), blocked}
+`)
+
+// BlockedKeySuffix names the counter of a key's blocked hits
+const BlockedKeySuffix = ":blocked"
+
+// Result describes the state of a key after a hit
+type Result struct {
+	Allowed    bool
+	Count      int // hits in the current window, including this one and blocked ones
+	Limit      int
+	Remaining  int
+	Reset      time.Time     // when the oldest hit leaves the window, freeing a slot
+	RetryAfter time.Duration // until the next hit is allowed; zero while slots remain
+}
+
+// Headers returns the standard rate limit response headers for the result
+func (r *Result) Headers() map[string]string {
+	return map[string]string{
+		"X-RateLimit-Limit":     strconv.Itoa(r.Limit),
+		"X-RateLimit-Remaining": strconv.Itoa(r.Remaining),
+		"X-RateLimit-Reset":     strconv.FormatInt(r.Reset.Unix(), 10),
+		"Retry-After":           strconv.Itoa(r.RetryAfterSeconds()),
+	}
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as Retry-After expects
+func (r *Result) RetryAfterSeconds() int {
+	return int((r.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// SlidingWindowLimiter enforces a limit over any rolling window, unlike a fixed
+// INCR/EXPIRE counter that allows a burst of twice the limit across a window boundary
+type SlidingWindowLimiter struct {
+	redisClient *redis.Client
+	now         func() time.Time
+}
+
+func NewSlidingWindowLimiter(redisClient *redis.Client) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		redisClient: redisClient,
+		now:         time.Now,
+	}
+}
+
+// Allow records a hit for key and reports whether it is within limit over the last window
+func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	now := l.now()
+	nowMicros := now.UnixMicro()
+	// Hits from several instances may share a timestamp, so members get a random suffix
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
+
+	values, err := slidingWindowScript.Run(ctx, l.redisClient, rediskeys.Keys(key, key+BlockedKeySuffix),
+		nowMicros, window.Microseconds(), member, window.Milliseconds(), limit).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check failed: %v", err)
+	}
+
+	recorded, blocked := int(values[0]), int(values[2])
+	result := &Result{
+		Allowed: blocked == 0,
+		Count:   recorded + blocked,
+		Limit:   limit,
+	}
+	if remaining := limit - recorded; remaining > 0 {
+		result.Remaining = remaining
+	}
+	// The window never holds more than the limit, so the next slot frees when the oldest
+	// recorded hit leaves it
+	result.Reset = time.UnixMicro(values[1]).Add(window)
+	if result.Remaining == 0 {
+		result.RetryAfter = result.Reset.Sub(now)
+	}
+
+	return result, nil
+}
+
+// Reset clears all recorded and blocked hits for key
+func (l *SlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	return l.redisClient.Del(ctx, rediskeys.Keys(key, key+BlockedKeySuffix)...).Err()
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestLimiter returns a limiter on an in-memory Redis whose clock the test moves
+func newTestLimiter(t *testing.T) (*SlidingWindowLimiter, *miniredis.Miniredis, *time.Time) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	limiter := NewSlidingWindowLimiter(client)
+	limiter.now = func() time.Time { return now }
+	return limiter, server, &now
+}
+
+func allowN(t *testing.T, limiter *SlidingWindowLimiter, n int) (allowed int, last *Result) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		result, err := limiter.Allow(context.Background(), "client", 5, time.Minute)
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if result.Allowed {
+			allowed++
+		}
+		last = result
+	}
+	return allowed, last
+}
+
+func TestSlidingWindowBlocksBurstsAcrossTheMinuteBoundary(t *testing.T) {
+	limiter, _, now := newTestLimiter(t)
+
+	// A fixed per-minute counter would allow five just before the minute and five just after
+	*now = now.Add(59 * time.Second)
+	if allowed, _ := allowN(t, limiter, 5); allowed != 5 {
+		t.Fatalf("allowed %d of the first burst, want 5", allowed)
+	}
+	*now = now.Add(2 * time.Second)
+	allowed, result := allowN(t, limiter, 5)
+	if allowed != 0 {
+		t.Errorf("allowed %d of the burst after the boundary, want 0", allowed)
+	}
+	if want := 58 * time.Second; result.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", result.RetryAfter, want)
+	}
+
+	// A full window after the first burst the slots are free again
+	*now = now.Add(58 * time.Second)
+	if allowed, _ := allowN(t, limiter, 5); allowed != 5 {
+		t.Errorf("allowed %d a window later, want 5", allowed)
+	}
+}
+
+func TestSlidingWindowDoesNotRecordBlockedHits(t *testing.T) {
+	limiter, server, now := newTestLimiter(t)
+
+	allowN(t, limiter, 5)
+	// A client polling far past the limit
+	*now = now.Add(30 * time.Second)
+	_, result := allowN(t, limiter, 100)
+	if result.Allowed || result.Count != 105 || result.Remaining != 0 {
+		t.Errorf("result after polling = %+v, want blocked with 105 hits", result)
+	}
+	if members, err := server.ZMembers("client"); err != nil || len(members) != 5 {
+		t.Errorf("window holds %d hits (%v), want the 5 allowed ones", len(members), err)
+	}
+
+	// Still polling, it gets in once the allowed hits leave the window
+	*now = now.Add(31 * time.Second)
+	if allowed, _ := allowN(t, limiter, 1); allowed != 1 {
+		t.Error("blocked client still blocked a window after its allowed hits")
+	}
+}
+
+func TestSlidingWindowReset(t *testing.T) {
+	limiter, _, _ := newTestLimiter(t)
+
+	allowN(t, limiter, 6)
+	if err := limiter.Reset(context.Background(), "client"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	_, result := allowN(t, limiter, 1)
+	if !result.Allowed || result.Count != 1 || result.Remaining != 4 {
+		t.Errorf("result after reset = %+v, want the first of 5", result)
+	}
+}
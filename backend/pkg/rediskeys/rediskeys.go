@@ -0,0 +1,48 @@
+// Package rediskeys builds the Redis keys and pub/sub channels the app uses under one
+// configurable prefix, e.g. "tru:prod:", so environments or deployments sharing a Redis
+// don't read or overwrite each other's data. Every key, glob pattern and channel passed to
+// Redis goes through Key, Keyf or Keys; keys read back from Redis, e.g. by KEYS, are already
+// prefixed.
+package rediskeys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prefix is set once at startup, before any component touches Redis
+var prefix string
+
+// SetPrefix sets the prefix of every key; "" leaves keys unprefixed. A prefix that doesn't end
+// in ":" gets one, so "tru:prod" and "tru:prod:" are the same namespace.
+func SetPrefix(p string) {
+	if p != "" && !strings.HasSuffix(p, ":") {
+		p += ":"
+	}
+	prefix = p
+}
+
+// Prefix returns the current prefix, for logging
+func Prefix() string {
+	return prefix
+}
+
+// Key returns the Redis key or channel for name. Glob patterns, e.g. for SCAN or PSUBSCRIBE,
+// are built the same way.
+func Key(name string) string {
+	return prefix + name
+}
+
+// Keyf formats the name of a key and returns its Redis key
+func Keyf(format string, args ...interface{}) string {
+	return prefix + fmt.Sprintf(format, args...)
+}
+
+// Keys returns the Redis keys for names, e.g. for the KEYS of a script
+func Keys(names ...string) []string {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = prefix + name
+	}
+	return keys
+}
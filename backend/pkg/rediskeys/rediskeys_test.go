@@ -0,0 +1,41 @@
+package rediskeys
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	t.Cleanup(func() { SetPrefix("") })
+
+	tests := []struct {
+		name       string
+		prefix     string
+		wantPrefix string
+		wantKey    string
+		wantKeyf   string
+		wantKeys   []string
+	}{
+		{"unprefixed", "", "", "session:1", "rate_limit:ip:10.0.0.1", []string{"a", "b"}},
+		{"with colon", "tru:prod:", "tru:prod:", "tru:prod:session:1", "tru:prod:rate_limit:ip:10.0.0.1", []string{"tru:prod:a", "tru:prod:b"}},
+		{"colon added", "tru:prod", "tru:prod:", "tru:prod:session:1", "tru:prod:rate_limit:ip:10.0.0.1", []string{"tru:prod:a", "tru:prod:b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPrefix(tt.prefix)
+
+			if got := Prefix(); got != tt.wantPrefix {
+				t.Errorf("Prefix() = %q, want %q", got, tt.wantPrefix)
+			}
+			if got := Key("session:1"); got != tt.wantKey {
+				t.Errorf("Key() = %q, want %q", got, tt.wantKey)
+			}
+			if got := Keyf("rate_limit:ip:%s", "10.0.0.1"); got != tt.wantKeyf {
+				t.Errorf("Keyf() = %q, want %q", got, tt.wantKeyf)
+			}
+			if got := Keys("a", "b"); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("Keys() = %q, want %q", got, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+const (
+	// minReorderTick bounds how often held payloads are checked for release
+	minReorderTick = 10 * time.Millisecond
+	// reorderStreamIdle is how long a stream's position is remembered after its last event
+	reorderStreamIdle = 10 * time.Minute
+)
+
+// eventReorderer holds subscription payloads back for up to a window so each stream's events
+// are forwarded in sequence order. A payload is released as soon as it is the next in its
+// stream, or when its window runs out even though earlier ones are still missing. Payloads at
+// or below the last forwarded sequence are duplicates or arrived too late, and are dropped.
+type eventReorderer struct {
+	window  time.Duration
+	streams map[string]*streamOrder
+}
+
+type streamOrder struct {
+	last     int64 // highest sequence forwarded; 0 before the first
+	pending  []heldPayload
+	lastSeen time.Time
+}
+
+type heldPayload struct {
+	payload  *services.SubscriptionPayload
+	deadline time.Time
+}
+
+func newEventReorderer(window time.Duration) *eventReorderer {
+	return &eventReorderer{
+		window:  window,
+		streams: make(map[string]*streamOrder),
+	}
+}
+
+// add takes a received payload and returns the payloads now ready to forward, in order
+func (er *eventReorderer) add(payload *services.SubscriptionPayload, now time.Time) []*services.SubscriptionPayload {
+	// Locally generated messages carry no sequence and pass straight through
+	if payload.Stream == "" || payload.Sequence == 0 {
+		return []*services.SubscriptionPayload{payload}
+	}
+
+	stream, ok := er.streams[payload.Stream]
+	if !ok {
+		stream = &streamOrder{}
+		er.streams[payload.Stream] = stream
+	}
+	stream.lastSeen = now
+
+	if payload.Sequence <= stream.last {
+		log.Printf("Dropping %s event %d on %s: already past sequence %d", payload.Type, payload.Sequence, payload.Stream, stream.last)
+		return nil
+	}
+
+	i := sort.Search(len(stream.pending), func(i int) bool {
+		return stream.pending[i].payload.Sequence >= payload.Sequence
+	})
+	if i < len(stream.pending) && stream.pending[i].payload.Sequence == payload.Sequence {
+		return nil
+	}
+	stream.pending = append(stream.pending, heldPayload{})
+	copy(stream.pending[i+1:], stream.pending[i:])
+	stream.pending[i] = heldPayload{payload: payload, deadline: now.Add(er.window)}
+
+	return stream.release(now)
+}
+
+// flush returns the held payloads whose window has run out, and forgets idle streams
+func (er *eventReorderer) flush(now time.Time) []*services.SubscriptionPayload {
+	var ready []*services.SubscriptionPayload
+	for name, stream := range er.streams {
+		ready = append(ready, stream.release(now)...)
+		if len(stream.pending) == 0 && now.Sub(stream.lastSeen) > reorderStreamIdle {
+			delete(er.streams, name)
+		}
+	}
+	return ready
+}
+
+func (so *streamOrder) release(now time.Time) []*services.SubscriptionPayload {
+	var ready []*services.SubscriptionPayload
+	for len(so.pending) > 0 {
+		head := so.pending[0]
+		if head.payload.Sequence != so.last+1 && now.Before(head.deadline) {
+			break
+		}
+		ready = append(ready, head.payload)
+		so.last = head.payload.Sequence
+		so.pending = so.pending[1:]
+	}
+	return ready
+}
+
+// ordered returns the connection's payloads, reordered by sequence within the resolver's
+// reorder window. Without a window they are passed on in receive order, and clients are
+// expected to order them by sequence themselves.
+func (r *SubscriptionResolver) ordered(ctx context.Context, conn *services.Connection) <-chan *services.SubscriptionPayload {
+	if r.ReorderWindow <= 0 {
+		return conn.Channel
+	}
+
+	out := make(chan *services.SubscriptionPayload)
+	go func() {
+		reorderer := newEventReorderer(r.ReorderWindow)
+		tick := r.ReorderWindow / 2
+		if tick < minReorderTick {
+			tick = minReorderTick
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			var ready []*services.SubscriptionPayload
+			select {
+			case msg, ok := <-conn.Channel:
+				if !ok {
+					return
+				}
+				ready = reorderer.add(msg, time.Now())
+			case now := <-ticker.C:
+				ready = reorderer.flush(now)
+			case <-ctx.Done():
+				return
+			case <-conn.Context.Done():
+				return
+			}
+
+			for _, msg := range ready {
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				case <-conn.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
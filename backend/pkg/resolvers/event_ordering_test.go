@@ -0,0 +1,92 @@
+package resolvers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+func sequenced(stream string, sequence int64) *services.SubscriptionPayload {
+	return &services.SubscriptionPayload{Type: "activity_updated", Stream: stream, Sequence: sequence}
+}
+
+func sequences(payloads []*services.SubscriptionPayload) []int64 {
+	seqs := make([]int64, len(payloads))
+	for i, payload := range payloads {
+		seqs[i] = payload.Sequence
+	}
+	return seqs
+}
+
+func assertSequences(t *testing.T, step string, got []*services.SubscriptionPayload, want ...int64) {
+	t.Helper()
+	seqs := sequences(got)
+	if len(seqs) != len(want) {
+		t.Fatalf("%s: released %v, want %v", step, seqs, want)
+	}
+	for i := range want {
+		if seqs[i] != want[i] {
+			t.Fatalf("%s: released %v, want %v", step, seqs, want)
+		}
+	}
+}
+
+func TestEventReordererHoldsOutOfOrderEvents(t *testing.T) {
+	start := time.Now()
+	er := newEventReorderer(time.Second)
+
+	assertSequences(t, "first event", er.add(sequenced("activity:1", 1), start), 1)
+
+	// 3 and 4 arrive ahead of 2 and are held back until it shows up
+	assertSequences(t, "event 3 before 2", er.add(sequenced("activity:1", 3), start))
+	assertSequences(t, "event 4 before 2", er.add(sequenced("activity:1", 4), start))
+	assertSequences(t, "flush inside the window", er.flush(start.Add(500*time.Millisecond)))
+	assertSequences(t, "missing event 2", er.add(sequenced("activity:1", 2), start.Add(600*time.Millisecond)), 2, 3, 4)
+
+	// Duplicates and events behind the forwarded position are dropped
+	assertSequences(t, "duplicate of 4", er.add(sequenced("activity:1", 4), start.Add(700*time.Millisecond)))
+	assertSequences(t, "late event 1", er.add(sequenced("activity:1", 1), start.Add(700*time.Millisecond)))
+
+	// Other streams keep their own position
+	assertSequences(t, "other stream", er.add(sequenced("activity:2", 1), start), 1)
+
+	// Unsequenced messages pass straight through
+	local := &services.SubscriptionPayload{Type: "welcome"}
+	if got := er.add(local, start); len(got) != 1 || got[0] != local {
+		t.Fatalf("local message released %v, want it forwarded as is", got)
+	}
+}
+
+func TestEventReordererReleasesAfterWindow(t *testing.T) {
+	start := time.Now()
+	er := newEventReorderer(time.Second)
+
+	er.add(sequenced("activity:1", 1), start)
+	assertSequences(t, "event 3 before 2", er.add(sequenced("activity:1", 3), start))
+	assertSequences(t, "event 5 before 4", er.add(sequenced("activity:1", 5), start.Add(200*time.Millisecond)))
+
+	// Once 3's window runs out it is forwarded without 2; 5 still waits for 4
+	assertSequences(t, "window of 3 expired", er.flush(start.Add(time.Second)), 3)
+	assertSequences(t, "event 2 too late", er.add(sequenced("activity:1", 2), start.Add(time.Second)))
+	assertSequences(t, "window of 5 expired", er.flush(start.Add(1200*time.Millisecond)), 5)
+	assertSequences(t, "next in sequence", er.add(sequenced("activity:1", 6), start.Add(1300*time.Millisecond)), 6)
+}
+
+func TestEventReordererForgetsIdleStreams(t *testing.T) {
+	start := time.Now()
+	er := newEventReorderer(time.Second)
+
+	er.add(sequenced("activity:1", 5), start)
+	er.flush(start.Add(time.Second))
+	if _, ok := er.streams["activity:1"]; !ok {
+		t.Fatal("active stream was forgotten")
+	}
+
+	er.flush(start.Add(reorderStreamIdle + time.Second))
+	if _, ok := er.streams["activity:1"]; ok {
+		t.Fatal("idle stream was kept")
+	}
+	// A restarted stream begins again from its first event
+	assertSequences(t, "restarted stream", er.add(sequenced("activity:1", 1), start.Add(reorderStreamIdle+2*time.Second)), 1)
+}
@@ -2,25 +2,35 @@ package resolvers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/kruakemaths/tru-activity/backend/graph/model"
 	"github.com/kruakemaths/tru-activity/backend/internal/middleware"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/auth"
 	"github.com/kruakemaths/tru-activity/backend/pkg/services"
 )
 
 type SubscriptionResolver struct {
 	ConnectionManager *services.ConnectionManager
 	PubSubService     *services.PubSubService
+	// ReorderWindow holds payloads back for up to this long to forward each stream in sequence
+	// order; zero forwards them as received
+	ReorderWindow time.Duration
+	// ScannerTokens lets volunteers follow the scans of the activity their token is for
+	ScannerTokens *auth.ScannerTokenStore
 }
 
-func NewSubscriptionResolver(cm *services.ConnectionManager, pubsub *services.PubSubService) *SubscriptionResolver {
+func NewSubscriptionResolver(cm *services.ConnectionManager, pubsub *services.PubSubService, reorderWindow time.Duration, scannerTokens *auth.ScannerTokenStore) *SubscriptionResolver {
 	return &SubscriptionResolver{
 		ConnectionManager: cm,
 		PubSubService:     pubsub,
+		ReorderWindow:     reorderWindow,
+		ScannerTokens:     scannerTokens,
 	}
 }
 
@@ -161,6 +171,7 @@ func (r *SubscriptionResolver) SystemAlerts(ctx context.Context, filter *model.S
 		return nil, fmt.Errorf("failed to create connection: %v", err)
 	}
 
+	minSeverity := models.DefaultMinAlertSeverity
 	filters := make(map[string]interface{})
 	if filter != nil {
 		if filter.FacultyID != nil {
@@ -169,33 +180,33 @@ func (r *SubscriptionResolver) SystemAlerts(ctx context.Context, filter *model.S
 		if filter.Types != nil {
 			filters["types"] = filter.Types
 		}
+		if filter.MinSeverity != nil {
+			// The enum arrives in its GraphQL spelling, e.g. WARNING
+			if severity, ok := models.ParseAlertSeverity(string(*filter.MinSeverity)); ok {
+				minSeverity = severity
+			}
+		}
 	}
+	filters["min_severity"] = string(minSeverity)
 
 	if err := r.ConnectionManager.Subscribe(connection.ID, "system_alerts", filters); err != nil {
 		return nil, fmt.Errorf("failed to subscribe: %v", err)
 	}
 
 	output := make(chan *model.SubscriptionPayload)
-	go r.handleSystemAlerts(ctx, connection, output)
+	go r.handleSystemAlerts(ctx, connection, minSeverity, output)
 
 	return output, nil
 }
 
-// QrScanEvents resolves QR scan events for admins
+// QrScanEvents resolves QR scan events for admins, and for volunteers with a scanner token
+// for the activity. A volunteer's subscription ends when their token expires.
 func (r *SubscriptionResolver) QrScanEvents(ctx context.Context, activityID *string) (<-chan *model.SubscriptionPayload, error) {
-	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, models.UserRoleRegularAdmin)
+	authCtx, err := middleware.RequireAuth(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	connection, err := r.ConnectionManager.CreateConnection(authCtx.UserID, authCtx.User, map[string]interface{}{
-		"subscription_type": "qr_scan_events",
-		"activity_id":       activityID,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create connection: %v", err)
-	}
-
 	filters := make(map[string]interface{})
 	if activityID != nil {
 		// Verify user can access this activity
@@ -204,11 +215,31 @@ func (r *SubscriptionResolver) QrScanEvents(ctx context.Context, activityID *str
 			return nil, fmt.Errorf("invalid activity ID")
 		}
 
-		if !r.canUserScanForActivity(authCtx.User, uint(activityIDUint)) {
+		allowed, expiresAt := r.canUserScanForActivity(ctx, authCtx.User, uint(activityIDUint))
+		if !allowed {
 			return nil, fmt.Errorf("access denied to activity scanning")
 		}
+		if !expiresAt.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, expiresAt)
+			// Released once the subscription ends
+			go func() {
+				<-ctx.Done()
+				cancel()
+			}()
+		}
 
 		filters["activity_id"] = *activityID
+	} else if !authCtx.User.IsAdmin() {
+		return nil, fmt.Errorf("access denied to activity scanning")
+	}
+
+	connection, err := r.ConnectionManager.CreateConnection(authCtx.UserID, authCtx.User, map[string]interface{}{
+		"subscription_type": "qr_scan_events",
+		"activity_id":       activityID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection: %v", err)
 	}
 
 	if err := r.ConnectionManager.Subscribe(connection.ID, "qr_scan_events", filters); err != nil {
@@ -399,14 +430,39 @@ func (r *SubscriptionResolver) Heartbeat(ctx context.Context) (<-chan string, er
 	return output, nil
 }
 
+// ConnectionStats streams live connection statistics from every instance to super admins
+func (r *SubscriptionResolver) ConnectionStats(ctx context.Context) (<-chan *model.ConnectionStats, error) {
+	authCtx, err := middleware.RequireRole(ctx, models.UserRoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := r.ConnectionManager.CreateConnection(authCtx.UserID, authCtx.User, map[string]interface{}{
+		"subscription_type": "connection_stats",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection: %v", err)
+	}
+
+	if err := r.ConnectionManager.Subscribe(connection.ID, "connection_stats", nil); err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %v", err)
+	}
+
+	output := make(chan *model.ConnectionStats)
+	go r.handleConnectionStats(ctx, connection, output)
+
+	return output, nil
+}
+
 // Helper methods for message handling
 
 func (r *SubscriptionResolver) handlePersonalNotifications(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			if r.shouldReceivePersonalNotification(conn.User, msg) {
 				output <- convertToGraphQLPayload(msg)
 			}
@@ -421,9 +477,10 @@ func (r *SubscriptionResolver) handlePersonalNotifications(ctx context.Context,
 func (r *SubscriptionResolver) handleActivityUpdates(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -436,9 +493,10 @@ func (r *SubscriptionResolver) handleActivityUpdates(ctx context.Context, conn *
 func (r *SubscriptionResolver) handleFacultyUpdates(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -448,13 +506,14 @@ func (r *SubscriptionResolver) handleFacultyUpdates(ctx context.Context, conn *s
 	}
 }
 
-func (r *SubscriptionResolver) handleSystemAlerts(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
+func (r *SubscriptionResolver) handleSystemAlerts(ctx context.Context, conn *services.Connection, minSeverity models.AlertSeverity, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
-			if r.shouldReceiveSystemAlert(conn.User, msg) {
+		case msg := <-in:
+			if r.shouldReceiveSystemAlert(conn.User, minSeverity, msg) {
 				output <- convertToGraphQLPayload(msg)
 			}
 		case <-ctx.Done():
@@ -468,9 +527,10 @@ func (r *SubscriptionResolver) handleSystemAlerts(ctx context.Context, conn *ser
 func (r *SubscriptionResolver) handleQRScanEvents(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -483,9 +543,10 @@ func (r *SubscriptionResolver) handleQRScanEvents(ctx context.Context, conn *ser
 func (r *SubscriptionResolver) handleParticipationEvents(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -498,9 +559,10 @@ func (r *SubscriptionResolver) handleParticipationEvents(ctx context.Context, co
 func (r *SubscriptionResolver) handleSubscriptionWarnings(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -513,9 +575,10 @@ func (r *SubscriptionResolver) handleSubscriptionWarnings(ctx context.Context, c
 func (r *SubscriptionResolver) handleActivityAssignments(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -528,9 +591,10 @@ func (r *SubscriptionResolver) handleActivityAssignments(ctx context.Context, co
 func (r *SubscriptionResolver) handleNewActivities(ctx context.Context, conn *services.Connection, output chan<- *model.SubscriptionPayload) {
 	defer close(output)
 
+	in := r.ordered(ctx, conn)
 	for {
 		select {
-		case msg := <-conn.Channel:
+		case msg := <-in:
 			output <- convertToGraphQLPayload(msg)
 		case <-ctx.Done():
 			return
@@ -558,6 +622,35 @@ func (r *SubscriptionResolver) handleHeartbeat(ctx context.Context, conn *servic
 	}
 }
 
+func (r *SubscriptionResolver) handleConnectionStats(ctx context.Context, conn *services.Connection, output chan<- *model.ConnectionStats) {
+	defer close(output)
+
+	for {
+		select {
+		case msg, ok := <-conn.Channel:
+			if !ok {
+				return
+			}
+			if msg.Type != "connection_stats" {
+				continue
+			}
+			stats, err := convertToConnectionStats(msg)
+			if err != nil {
+				continue
+			}
+			select {
+			case output <- stats:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-conn.Context.Done():
+			return
+		}
+	}
+}
+
 // Helper methods for permission checking
 
 func (r *SubscriptionResolver) canUserAccessActivity(user *models.User, activityID uint) bool {
@@ -573,9 +666,22 @@ func (r *SubscriptionResolver) canUserAccessFaculty(user *models.User, facultyID
 	return user.FacultyID != nil && *user.FacultyID == facultyID
 }
 
-func (r *SubscriptionResolver) canUserScanForActivity(user *models.User, activityID uint) bool {
-	// Implementation would check if user can scan QR codes for this activity
-	return user.IsAdmin()
+// canUserScanForActivity allows admins, and users presenting a scanner token for the activity.
+// For a token it also returns when the token expires.
+func (r *SubscriptionResolver) canUserScanForActivity(ctx context.Context, user *models.User, activityID uint) (bool, time.Time) {
+	if user.IsAdmin() {
+		return true, time.Time{}
+	}
+
+	presented, _ := ctx.Value("scanner_token").(string)
+	if presented == "" || r.ScannerTokens == nil {
+		return false, time.Time{}
+	}
+	token, err := r.ScannerTokens.Validate(ctx, presented)
+	if err != nil || token.ActivityID != activityID {
+		return false, time.Time{}
+	}
+	return true, token.ExpiresAt
 }
 
 func (r *SubscriptionResolver) shouldReceivePersonalNotification(user *models.User, msg *services.SubscriptionPayload) bool {
@@ -583,24 +689,117 @@ func (r *SubscriptionResolver) shouldReceivePersonalNotification(user *models.Us
 	return true
 }
 
-func (r *SubscriptionResolver) shouldReceiveSystemAlert(user *models.User, msg *services.SubscriptionPayload) bool {
-	// Implement filtering logic for system alerts based on user role
-	return user.IsAdmin()
+func (r *SubscriptionResolver) shouldReceiveSystemAlert(user *models.User, minSeverity models.AlertSeverity, msg *services.SubscriptionPayload) bool {
+	// Admins only, and only alerts at or above the subscriber's minimum severity
+	return user.IsAdmin() && models.AlertSeverityOf(msg.Data).AtLeast(minSeverity)
 }
 
 // Utility functions
 
 func convertToGraphQLPayload(payload *services.SubscriptionPayload) *model.SubscriptionPayload {
+	var stream *string
+	if payload.Stream != "" {
+		stream = &payload.Stream
+	}
+
 	return &model.SubscriptionPayload{
 		Type:      payload.Type,
 		Timestamp: payload.Timestamp,
 		Data:      nil, // This would need proper type conversion based on payload type
+		Stream:    stream,
+		Sequence:  int(payload.Sequence),
 		Metadata: &model.SubscriptionMetadata{
 			Source: getStringFromMap(payload.Metadata, "source"),
 		},
 	}
 }
 
+// convertToConnectionStats decodes stats that arrive from pub/sub as generic JSON
+func convertToConnectionStats(payload *services.SubscriptionPayload) (*model.ConnectionStats, error) {
+	raw, err := json.Marshal(payload.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats services.ConnectionStats
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return nil, err
+	}
+
+	return ConvertConnectionStats(&stats, payload.Timestamp), nil
+}
+
+// ConvertConnectionStats maps ConnectionManager statistics onto the GraphQL type
+func ConvertConnectionStats(stats *services.ConnectionStats, timestamp time.Time) *model.ConnectionStats {
+	facultyConnections := convertFacultyConnectionCounts(stats.FacultyConnections)
+
+	activeSubscriptions := make([]*model.SubscriptionTypeCount, 0, len(stats.ActiveSubscriptions))
+	for subType, count := range stats.ActiveSubscriptions {
+		activeSubscriptions = append(activeSubscriptions, &model.SubscriptionTypeCount{
+			Type:  subType,
+			Count: count,
+		})
+	}
+	sort.Slice(activeSubscriptions, func(i, j int) bool {
+		return activeSubscriptions[i].Type < activeSubscriptions[j].Type
+	})
+
+	var cluster *model.ClusterConnectionStats
+	if stats.Cluster != nil {
+		cluster = &model.ClusterConnectionStats{
+			Instances:          stats.Cluster.Instances,
+			TotalConnections:   stats.Cluster.TotalConnections,
+			FacultyConnections: convertFacultyConnectionCounts(stats.Cluster.FacultyConnections),
+		}
+	}
+
+	slowConnections := make([]*model.SlowConnection, 0, len(stats.SlowConnections))
+	for _, slow := range stats.SlowConnections {
+		slowConnections = append(slowConnections, &model.SlowConnection{
+			ConnectionID:  slow.ConnectionID,
+			UserID:        strconv.FormatUint(uint64(slow.UserID), 10),
+			HighWatermark: slow.HighWatermark,
+			Drops:         int(slow.Drops),
+		})
+	}
+
+	return &model.ConnectionStats{
+		InstanceID:          stats.InstanceID,
+		TotalConnections:    stats.TotalConnections,
+		FacultyConnections:  facultyConnections,
+		ActiveSubscriptions: activeSubscriptions,
+		IdleConnections:     stats.IdleConnections,
+		IdleTimeoutSeconds:  int(stats.IdleTimeout.Seconds()),
+		Draining:            stats.Draining,
+		UptimeSeconds:       int(stats.Uptime.Seconds()),
+		Timestamp:           timestamp,
+		Cluster:             cluster,
+		Buffer: &model.ConnectionBufferStats{
+			Size:            stats.BufferSize,
+			HighWatermark:   stats.BufferHighWatermark,
+			Drops:           int(stats.BufferDrops),
+			SlowConnections: slowConnections,
+		},
+	}
+}
+
+func convertFacultyConnectionCounts(counts map[uint]int) []*model.FacultyConnectionCount {
+	facultyIDs := make([]uint, 0, len(counts))
+	for facultyID := range counts {
+		facultyIDs = append(facultyIDs, facultyID)
+	}
+	sort.Slice(facultyIDs, func(i, j int) bool { return facultyIDs[i] < facultyIDs[j] })
+
+	facultyConnections := make([]*model.FacultyConnectionCount, 0, len(facultyIDs))
+	for _, facultyID := range facultyIDs {
+		facultyConnections = append(facultyConnections, &model.FacultyConnectionCount{
+			FacultyID:   strconv.FormatUint(uint64(facultyID), 10),
+			Connections: counts[facultyID],
+		})
+	}
+	return facultyConnections
+}
+
 func getStringFromMap(m map[string]interface{}, key string) *string {
 	if m != nil {
 		if val, ok := m[key].(string); ok {
@@ -610,12 +809,19 @@ func getStringFromMap(m map[string]interface{}, key string) *string {
 	return nil
 }
 
+// getRemoteAddr returns the client IP the auth extension took from the request headers, which
+// for websockets are the headers of the upgrade request
 func getRemoteAddr(ctx context.Context) string {
-	// Extract remote address from context
+	if clientIP, _ := ctx.Value("client_ip").(string); clientIP != "" {
+		return clientIP
+	}
 	return "unknown"
 }
 
+// getUserAgent returns the user agent the auth extension took from the request headers
 func getUserAgent(ctx context.Context) string {
-	// Extract user agent from context
+	if userAgent, _ := ctx.Value("user_agent").(string); userAgent != "" {
+		return userAgent
+	}
 	return "unknown"
 }
\ No newline at end of file
@@ -0,0 +1,65 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/services"
+)
+
+func TestConnectionClientFromContext(t *testing.T) {
+	// The auth extension stores both from the request, or the websocket upgrade request
+	ctx := context.WithValue(context.Background(), "client_ip", "198.51.100.7")
+	ctx = context.WithValue(ctx, "user_agent", "Mozilla/5.0 (TRU Activity)")
+
+	if got := getRemoteAddr(ctx); got != "198.51.100.7" {
+		t.Errorf("getRemoteAddr() = %q, want 198.51.100.7", got)
+	}
+	if got := getUserAgent(ctx); got != "Mozilla/5.0 (TRU Activity)" {
+		t.Errorf("getUserAgent() = %q, want the request's user agent", got)
+	}
+
+	empty := context.WithValue(context.Background(), "user_agent", "")
+	if got := getRemoteAddr(empty); got != "unknown" {
+		t.Errorf("getRemoteAddr() without a client IP = %q, want unknown", got)
+	}
+	if got := getUserAgent(empty); got != "unknown" {
+		t.Errorf("getUserAgent() with an empty user agent = %q, want unknown", got)
+	}
+}
+
+func TestSystemAlertSeverityBoundaries(t *testing.T) {
+	r := &SubscriptionResolver{}
+	admin := &models.User{Role: models.UserRoleFacultyAdmin}
+	severities := []models.AlertSeverity{models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityError, models.AlertSeverityCritical}
+
+	for i, minSeverity := range severities {
+		for j, severity := range severities {
+			msg := &services.SubscriptionPayload{Type: "system_alert", Data: &models.SystemAlert{Severity: severity}}
+			if got, want := r.shouldReceiveSystemAlert(admin, minSeverity, msg), j >= i; got != want {
+				t.Errorf("minimum %s: %s alert delivered = %v, want %v", minSeverity, severity, got, want)
+			}
+		}
+	}
+
+	// Alerts without a known severity are never dropped, and students get none
+	unknown := &services.SubscriptionPayload{Type: "system_alert", Data: map[string]interface{}{"severity": "fatal"}}
+	if !r.shouldReceiveSystemAlert(admin, models.AlertSeverityCritical, unknown) {
+		t.Error("alert of an unknown severity was dropped")
+	}
+	critical := &services.SubscriptionPayload{Type: "system_alert", Data: &models.SystemAlert{Severity: models.AlertSeverityCritical}}
+	if r.shouldReceiveSystemAlert(&models.User{Role: models.UserRoleStudent}, models.AlertSeverityInfo, critical) {
+		t.Error("a student received a system alert")
+	}
+
+	// The GraphQL enum spelling and WARN both parse
+	for _, name := range []string{"WARNING", "warn", " Warning "} {
+		if severity, ok := models.ParseAlertSeverity(name); !ok || severity != models.AlertSeverityWarning {
+			t.Errorf("ParseAlertSeverity(%q) = %q, %v, want warning", name, severity, ok)
+		}
+	}
+	if _, ok := models.ParseAlertSeverity("loud"); ok {
+		t.Error("ParseAlertSeverity accepted an unknown severity")
+	}
+}
@@ -0,0 +1,136 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// GenerateQRStrings issues QR strings for many students at once, keyed by student ID.
+// Secrets are read with one MGET and any missing ones stored in one pipeline, instead of a
+// round trip per student. A non-zero expiresAt replaces the standard expiry and may be at
+// most MaxQRValidity away. Students whose code could not be generated are returned in failures.
+func (qsm *QRSecurityManager) GenerateQRStrings(ctx context.Context, studentIDs []string, expiresAt time.Time) (map[string]string, map[string]error, error) {
+	codes := make(map[string]string, len(studentIDs))
+	failures := make(map[string]error)
+
+	var expiry int64
+	if !expiresAt.IsZero() {
+		if time.Until(expiresAt) > MaxQRValidity {
+			return nil, nil, fmt.Errorf("QR expiry is more than %s away", MaxQRValidity)
+		}
+		expiry = expiresAt.Unix()
+	}
+
+	secrets, err := qsm.getUserQRSecrets(ctx, studentIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(secrets) == 0 {
+		return codes, failures, nil
+	}
+
+	// Hashing the secret with scrypt dominates, so spread it over the CPUs
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		generated = make(map[string]*QRData, len(secrets))
+		jobs      = make(chan string)
+	)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for studentID := range jobs {
+				// Once the caller gives up, the rest fail fast with the context error
+				var qrData *QRData
+				err := ctx.Err()
+				if err == nil {
+					qrData, err = qsm.buildQRData(studentID, secrets[studentID], expiry)
+				}
+
+				mu.Lock()
+				if err != nil {
+					failures[studentID] = err
+				} else {
+					generated[studentID] = qrData
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for studentID := range secrets {
+		jobs <- studentID
+	}
+	close(jobs)
+	wg.Wait()
+
+	pipe := qsm.redisClient.Pipeline()
+	for studentID, qrData := range generated {
+		// Same encoding as GenerateQRString
+		encoded, err := EncodeQRData(qrData, qsm.format.Encoding)
+		if err != nil {
+			failures[studentID] = err
+			continue
+		}
+		codes[studentID] = encoded
+		queueQRGeneration(ctx, pipe, studentID, qrData)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Warning: failed to track bulk QR generation: %v", err)
+	}
+
+	return codes, failures, nil
+}
+
+// getUserQRSecrets is the bulk form of getUserQRSecret
+func (qsm *QRSecurityManager) getUserQRSecrets(ctx context.Context, studentIDs []string) (map[string][]byte, error) {
+	secrets := make(map[string][]byte, len(studentIDs))
+	keys := make([]string, 0, len(studentIDs))
+	ids := make([]string, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		if _, seen := secrets[studentID]; seen || studentID == "" {
+			continue
+		}
+		secrets[studentID] = nil
+		keys = append(keys, QRSecretKey+studentID)
+		ids = append(ids, studentID)
+	}
+	if len(keys) == 0 {
+		return secrets, nil
+	}
+
+	values, err := qsm.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get QR secrets: %v", err)
+	}
+
+	pipe := qsm.redisClient.Pipeline()
+	for i, studentID := range ids {
+		if encoded, ok := values[i].(string); ok {
+			if secret, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				secrets[studentID] = secret
+				continue
+			}
+		}
+
+		secret := make([]byte, QRSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate random secret: %v", err)
+		}
+		secrets[studentID] = secret
+		pipe.Set(ctx, QRSecretKey+studentID, base64.StdEncoding.EncodeToString(secret), qrSecretTTL)
+	}
+	if pipe.Len() > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to store secrets: %v", err)
+		}
+	}
+
+	return secrets, nil
+}
@@ -0,0 +1,198 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+)
+
+const (
+	// SecurityLevelHigh is a scan that passed every check
+	SecurityLevelHigh = "high"
+	// SecurityLevelDegraded is a scan accepted while Redis was down: signature, timestamp and
+	// expiry were checked, but the blacklist, replay, rate limit and secret checks were skipped
+	SecurityLevelDegraded = "degraded"
+
+	// maxDegradedScans bounds the scans waiting for replay reconciliation; past it scans are refused
+	maxDegradedScans = 10000
+)
+
+// errQRRedisUnavailable is returned without calling Redis while the breaker is open
+var errQRRedisUnavailable = errors.New("qr redis circuit breaker is open")
+
+// QRRedisPolicy configures the circuit breaker around QR validation's Redis checks. After
+// FailureThreshold consecutive failures Redis is skipped for Cooldown. With Degrade set, scans
+// are then validated in degraded mode instead of failing.
+type QRRedisPolicy struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+	Degrade          bool
+}
+
+// degradedScan is a scan accepted without a replay check, to be checked once Redis is back
+type degradedScan struct {
+	qrData     QRData
+	activityID string
+	scannerID  string
+	facultyID  string
+	clientIP   string
+	acceptedAt time.Time
+}
+
+// degradedScans remembers the scans accepted in degraded mode. It also rejects a code
+// scanned twice on this instance while Redis is down.
+type degradedScans struct {
+	mu          sync.Mutex
+	pending     []degradedScan
+	signatures  map[string]bool
+	reconciling atomic.Bool
+}
+
+// SetAuditLogger records degraded scans and replays found on reconciliation as security events
+func (qsm *QRSecurityManager) SetAuditLogger(auditLogger *audit.AuditLogger) {
+	qsm.auditLogger = auditLogger
+}
+
+// RedisBreakerStats reports the QR Redis circuit breaker state for health checks
+func (qsm *QRSecurityManager) RedisBreakerStats() map[string]interface{} {
+	stats := qsm.breaker.Stats()
+	qsm.degraded.mu.Lock()
+	stats["degraded_scans_pending"] = len(qsm.degraded.pending)
+	qsm.degraded.mu.Unlock()
+	return stats
+}
+
+// redisCall runs a validation check against Redis through the circuit breaker. The first
+// success after scans were accepted in degraded mode starts their reconciliation.
+func (qsm *QRSecurityManager) redisCall(fn func() error) error {
+	if !qsm.breaker.Allow() {
+		return errQRRedisUnavailable
+	}
+	err := fn()
+	qsm.breaker.Record(err)
+
+	if err == nil && qsm.hasDegradedScans() && qsm.degraded.reconciling.CompareAndSwap(false, true) {
+		go func() {
+			defer qsm.degraded.reconciling.Store(false)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			qsm.ReconcileDegradedScans(ctx)
+		}()
+	}
+	return err
+}
+
+// redisDown reports whether the breaker is open, so validation can skip Redis up front
+func (qsm *QRSecurityManager) redisDown() bool {
+	return qsm.breaker.State() == performance.BreakerOpen
+}
+
+func (qsm *QRSecurityManager) hasDegradedScans() bool {
+	qsm.degraded.mu.Lock()
+	defer qsm.degraded.mu.Unlock()
+	return len(qsm.degraded.pending) > 0
+}
+
+// acceptDegradedScan queues a scan for reconciliation. It returns false for a code already
+// scanned on this instance during the outage, or once too many scans are waiting.
+func (qsm *QRSecurityManager) acceptDegradedScan(scan degradedScan) (accepted bool, full bool) {
+	qsm.degraded.mu.Lock()
+	defer qsm.degraded.mu.Unlock()
+
+	if qsm.degraded.signatures[scan.qrData.Signature] {
+		return false, false
+	}
+	if len(qsm.degraded.pending) >= maxDegradedScans {
+		return false, true
+	}
+	if qsm.degraded.signatures == nil {
+		qsm.degraded.signatures = make(map[string]bool)
+	}
+	qsm.degraded.signatures[scan.qrData.Signature] = true
+	qsm.degraded.pending = append(qsm.degraded.pending, scan)
+	return true, false
+}
+
+// ReconcileDegradedScans runs the skipped replay and blacklist checks for scans accepted in
+// degraded mode and marks their codes used. Codes that turn out to have been used or revoked
+// already are reported as security events. Scans left unchecked by a Redis failure stay queued.
+func (qsm *QRSecurityManager) ReconcileDegradedScans(ctx context.Context) {
+	qsm.degraded.mu.Lock()
+	pending := qsm.degraded.pending
+	qsm.degraded.pending = nil
+	qsm.degraded.mu.Unlock()
+
+	var replays, revoked int
+	for i, scan := range pending {
+		blacklisted, err := qsm.isQRBlacklisted(ctx, scan.qrData.Signature)
+		var used bool
+		if err == nil {
+			used, err = qsm.isQRUsed(ctx, scan.qrData.Signature)
+		}
+		if err == nil {
+			err = qsm.markQRUsed(ctx, &scan.qrData)
+		}
+		if err != nil {
+			log.Printf("QR reconciliation stopped with %d scans left: %v", len(pending)-i, err)
+			qsm.requeueDegradedScans(pending[i:])
+			return
+		}
+
+		switch {
+		case blacklisted:
+			revoked++
+			qsm.logDegradedEvent(ctx, scan, audit.SecurityEventQRTampering, audit.RiskLevelHigh, "revoked QR code accepted while Redis was unavailable")
+		case used:
+			replays++
+			qsm.logDegradedEvent(ctx, scan, audit.SecurityEventQRTampering, audit.RiskLevelHigh, "replayed QR code accepted while Redis was unavailable")
+		}
+	}
+
+	qsm.degraded.mu.Lock()
+	for _, scan := range pending {
+		delete(qsm.degraded.signatures, scan.qrData.Signature)
+	}
+	qsm.degraded.mu.Unlock()
+
+	if len(pending) > 0 {
+		log.Printf("Reconciled %d QR scans accepted in degraded mode: %d replays, %d revoked", len(pending), replays, revoked)
+	}
+}
+
+func (qsm *QRSecurityManager) requeueDegradedScans(scans []degradedScan) {
+	qsm.degraded.mu.Lock()
+	defer qsm.degraded.mu.Unlock()
+	qsm.degraded.pending = append(scans, qsm.degraded.pending...)
+}
+
+// logDegradedEvent records a security event about a scan validated in degraded mode
+func (qsm *QRSecurityManager) logDegradedEvent(ctx context.Context, scan degradedScan, eventType, riskLevel, reason string) {
+	if qsm.auditLogger == nil {
+		log.Printf("QR security event %s for student %s: %s", eventType, scan.qrData.StudentID, reason)
+		return
+	}
+
+	event := &audit.SecurityEvent{
+		EventType: eventType,
+		UserID:    scan.qrData.StudentID,
+		FacultyID: scan.facultyID,
+		IPAddress: scan.clientIP,
+		Details: map[string]interface{}{
+			"reason":         reason,
+			"activity_id":    scan.activityID,
+			"scanner_id":     scan.scannerID,
+			"security_level": SecurityLevelDegraded,
+			"accepted_at":    scan.acceptedAt.Unix(),
+		},
+		RiskLevel: riskLevel,
+	}
+	if err := qsm.auditLogger.LogSecurityEvent(ctx, event); err != nil {
+		log.Printf("Failed to log %s security event: %v", eventType, err)
+	}
+}
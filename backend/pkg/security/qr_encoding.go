@@ -0,0 +1,177 @@
+package security
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// QREncoding selects how new QR codes are written out. Scanning accepts every encoding.
+type QREncoding string
+
+const (
+	// QREncodingJSON is base64 encoded JSON, the original format
+	QREncodingJSON QREncoding = "json"
+
+	// QREncodingBinary packs the fields into a fixed layout, base32 encoded behind
+	// QRBinaryPrefix. It is about half the JSON length and uses only characters
+	// from the QR alphanumeric set, so the printed code is far less dense.
+	QREncodingBinary QREncoding = "binary"
+
+	QRBinaryPrefix = "TQ1"
+
+	// DefaultMaxQRPayloadLength bounds the scanned string accepted before it is decoded
+	DefaultMaxQRPayloadLength = 2000
+)
+
+// Binary layout, all integers big endian:
+//
+//	version(1) timestamp(8) expires_at(8) nonce(16) secret_hash(32) signature(32) student_id_len(1) student_id
+const (
+	qrNonceBytes      = 16
+	qrHashBytes       = 32
+	qrBinaryFixedSize = 1 + 8 + 8 + qrNonceBytes + qrHashBytes + qrHashBytes + 1
+)
+
+var qrBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// QRCodeFormat configures QR encoding and the largest scanned payload accepted
+type QRCodeFormat struct {
+	Encoding   QREncoding
+	MaxPayload int
+}
+
+// WithDefaults fills in zero fields
+func (f QRCodeFormat) WithDefaults() QRCodeFormat {
+	if f.Encoding == "" {
+		f.Encoding = QREncodingJSON
+	}
+	if f.MaxPayload <= 0 {
+		f.MaxPayload = DefaultMaxQRPayloadLength
+	}
+	return f
+}
+
+// ParseQREncoding accepts "json" or "binary", case-insensitively
+func ParseQREncoding(s string) (QREncoding, error) {
+	switch encoding := QREncoding(strings.ToLower(strings.TrimSpace(s))); encoding {
+	case QREncodingJSON, QREncodingBinary:
+		return encoding, nil
+	}
+	return "", fmt.Errorf("unknown QR encoding %q", s)
+}
+
+// EncodeQRData writes qrData in the given encoding
+func EncodeQRData(qrData *QRData, encoding QREncoding) (string, error) {
+	if encoding == QREncodingBinary {
+		return encodeQRBinary(qrData)
+	}
+
+	jsonData, err := json.Marshal(qrData)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal QR data: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(jsonData), nil
+}
+
+// DecodeQRData reads a scanned QR string in any supported encoding: binary, detected by
+// QRBinaryPrefix, base64 encoded JSON, or plain JSON from older scanner clients
+func DecodeQRData(s string) (*QRData, error) {
+	if strings.HasPrefix(s, QRBinaryPrefix) {
+		return decodeQRBinary(strings.TrimPrefix(s, QRBinaryPrefix))
+	}
+
+	raw := []byte(s)
+	if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QR encoding: %v", err)
+		}
+		raw = decoded
+	}
+
+	var qrData QRData
+	if err := json.Unmarshal(raw, &qrData); err != nil {
+		return nil, fmt.Errorf("invalid QR data: %v", err)
+	}
+	return &qrData, nil
+}
+
+func encodeQRBinary(qrData *QRData) (string, error) {
+	if qrData.Version < 0 || qrData.Version > 0xff {
+		return "", fmt.Errorf("QR version %d does not fit the binary encoding", qrData.Version)
+	}
+	if len(qrData.StudentID) > 0xff {
+		return "", fmt.Errorf("student ID is too long for the binary encoding")
+	}
+
+	nonce, err := decodeHexField(qrData.Nonce, qrNonceBytes, "nonce")
+	if err != nil {
+		return "", err
+	}
+	secretHash, err := decodeHexField(qrData.SecretHash, qrHashBytes, "secret hash")
+	if err != nil {
+		return "", err
+	}
+	signature, err := decodeHexField(qrData.Signature, qrHashBytes, "signature")
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 0, qrBinaryFixedSize+len(qrData.StudentID))
+	buf = append(buf, byte(qrData.Version))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(qrData.Timestamp))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(qrData.ExpiresAt))
+	buf = append(buf, nonce...)
+	buf = append(buf, secretHash...)
+	buf = append(buf, signature...)
+	buf = append(buf, byte(len(qrData.StudentID)))
+	buf = append(buf, qrData.StudentID...)
+
+	return QRBinaryPrefix + qrBase32.EncodeToString(buf), nil
+}
+
+func decodeQRBinary(s string) (*QRData, error) {
+	buf, err := qrBase32.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QR encoding: %v", err)
+	}
+	if len(buf) < qrBinaryFixedSize {
+		return nil, fmt.Errorf("QR data is truncated")
+	}
+
+	qrData := &QRData{Version: int(buf[0])}
+	buf = buf[1:]
+	qrData.Timestamp = int64(binary.BigEndian.Uint64(buf))
+	buf = buf[8:]
+	qrData.ExpiresAt = int64(binary.BigEndian.Uint64(buf))
+	buf = buf[8:]
+	qrData.Nonce = hex.EncodeToString(buf[:qrNonceBytes])
+	buf = buf[qrNonceBytes:]
+	qrData.SecretHash = hex.EncodeToString(buf[:qrHashBytes])
+	buf = buf[qrHashBytes:]
+	qrData.Signature = hex.EncodeToString(buf[:qrHashBytes])
+	buf = buf[qrHashBytes:]
+
+	studentIDLen := int(buf[0])
+	buf = buf[1:]
+	if len(buf) != studentIDLen {
+		return nil, fmt.Errorf("QR data has the wrong length")
+	}
+	qrData.StudentID = string(buf)
+
+	return qrData, nil
+}
+
+// decodeHexField converts a hex encoded field back to its fixed-size bytes
+func decodeHexField(value string, size int, name string) ([]byte, error) {
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != size {
+		return nil, fmt.Errorf("QR %s is not %d hex encoded bytes", name, size)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,89 @@
+package security
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// qrAlphanumeric is the QR alphanumeric mode character set
+const qrAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+func TestQRBinaryEncodingRoundTrip(t *testing.T) {
+	qsm, _ := newTestQRManager(t, 0)
+	ctx := context.Background()
+
+	issued, err := qsm.GenerateQRData(ctx, "6501234567")
+	if err != nil {
+		t.Fatalf("GenerateQRData: %v", err)
+	}
+	withExpiry := *issued
+	withExpiry.ExpiresAt = issued.Timestamp + int64(time.Hour.Seconds())
+
+	for _, qrData := range []*QRData{issued, &withExpiry} {
+		encoded, err := EncodeQRData(qrData, QREncodingBinary)
+		if err != nil {
+			t.Fatalf("EncodeQRData: %v", err)
+		}
+		if !strings.HasPrefix(encoded, QRBinaryPrefix) {
+			t.Errorf("binary code %q lacks the %s prefix", encoded, QRBinaryPrefix)
+		}
+		for _, r := range encoded {
+			if !strings.ContainsRune(qrAlphanumeric, r) {
+				t.Errorf("binary code contains %q, outside the QR alphanumeric set", r)
+				break
+			}
+		}
+		jsonEncoded, _ := EncodeQRData(qrData, QREncodingJSON)
+		if len(encoded) >= len(jsonEncoded) {
+			t.Errorf("binary code is %d characters, not shorter than the %d of JSON", len(encoded), len(jsonEncoded))
+		}
+
+		decoded, err := DecodeQRData(encoded)
+		if err != nil {
+			t.Fatalf("DecodeQRData: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, qrData) {
+			t.Errorf("round trip = %+v, want %+v", decoded, qrData)
+		}
+	}
+
+	// The decoded code still carries a valid signature and secret
+	encoded, _ := EncodeQRData(issued, QREncodingBinary)
+	result, err := qsm.ValidateQRData(ctx, encoded, "scanner-1", "11", "1", "127.0.0.1", "test")
+	if err != nil || !result.Valid {
+		t.Errorf("ValidateQRData(binary) = %+v, %v, want a valid scan", result, err)
+	}
+}
+
+func TestQRBinaryEncodingRejectsMalformedData(t *testing.T) {
+	qsm, _ := newTestQRManager(t, 0)
+	issued, err := qsm.GenerateQRData(context.Background(), "6501234567")
+	if err != nil {
+		t.Fatalf("GenerateQRData: %v", err)
+	}
+	encoded, _ := EncodeQRData(issued, QREncodingBinary)
+
+	for name, s := range map[string]string{
+		"truncated":      encoded[:len(encoded)/2],
+		"extra bytes":    encoded + "AAAA",
+		"not base32":     QRBinaryPrefix + "not-base32!",
+		"missing fields": QRBinaryPrefix + qrBase32.EncodeToString([]byte{2, 0, 0}),
+	} {
+		if _, err := DecodeQRData(s); err == nil {
+			t.Errorf("%s: DecodeQRData succeeded, want an error", name)
+		}
+	}
+
+	badNonce := *issued
+	badNonce.Nonce = "not hex"
+	longID := *issued
+	longID.StudentID = strings.Repeat("6", 256)
+	for name, qrData := range map[string]*QRData{"bad nonce": &badNonce, "long student ID": &longID} {
+		if _, err := EncodeQRData(qrData, QREncodingBinary); err == nil {
+			t.Errorf("%s: EncodeQRData succeeded, want an error", name)
+		}
+	}
+}
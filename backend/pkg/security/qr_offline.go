@@ -0,0 +1,126 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MaxOfflineScanAge is how long after a scan its scanner may still submit it for reconciliation
+const MaxOfflineScanAge = 7 * 24 * time.Hour
+
+// OfflineScanCheck is the outcome of validating a scan a scanner accepted while offline
+type OfflineScanCheck struct {
+	Valid     bool
+	StudentID string
+	Message   string
+	// Reason is the failure reason, as in QRScanAttempt.ErrorReason; empty when valid
+	Reason string
+	// NeedsReview marks a genuine code that can't be accepted as is: it was revoked, used
+	// already or issued under a secret the student has since replaced. The student may well
+	// have attended, so a person decides rather than the scan being dropped.
+	NeedsReview bool
+	// QRTimestamp is when the code was issued; zero when it couldn't be parsed
+	QRTimestamp time.Time
+
+	qrData QRData
+}
+
+// CheckOfflineScan runs the server-side validation a scan skipped while its scanner was
+// offline. The code's validity is judged at scannedAt, the scanner's time of the scan, rather
+// than now, and rate limits don't apply since the scans happened in the past. The code isn't
+// marked used; call MarkOfflineScanUsed once the attendance has been recorded.
+func (qsm *QRSecurityManager) CheckOfflineScan(ctx context.Context, qrDataStr string, scannedAt time.Time, scannerID, activityID, facultyID, clientIP, userAgent string) (*OfflineScanCheck, error) {
+	check := &OfflineScanCheck{}
+	attempt := &QRScanAttempt{
+		QRData:     qrDataStr,
+		Timestamp:  time.Now(),
+		IPAddress:  clientIP,
+		UserAgent:  userAgent,
+		ActivityID: activityID,
+		ScannerID:  scannerID,
+		FacultyID:  facultyID,
+	}
+	reject := func(reason, message string, review bool) (*OfflineScanCheck, error) {
+		check.Reason = reason
+		check.Message = message
+		check.NeedsReview = review
+		attempt.ErrorReason = reason
+		qsm.logScanAttempt(ctx, attempt)
+		return check, nil
+	}
+
+	now := time.Now()
+	skew := qsm.clockSkew
+	if scannedAt.After(now.Add(skew)) {
+		return reject("future_timestamp", "Scan time is in the future", false)
+	}
+	if now.Sub(scannedAt) > MaxOfflineScanAge {
+		return reject("scan_too_old", "Scan is too old to reconcile", false)
+	}
+
+	if len(qrDataStr) > qsm.format.MaxPayload {
+		return reject("payload_too_large", "QR data is too large", false)
+	}
+	decoded, err := DecodeQRData(qrDataStr)
+	if err != nil {
+		return reject("parse_error", "Invalid QR data format", false)
+	}
+	qrData := *decoded
+	check.qrData = qrData
+	check.StudentID = qrData.StudentID
+	check.QRTimestamp = time.Unix(qrData.Timestamp, 0)
+	attempt.UserID = qrData.StudentID
+
+	if qrData.Version != QRSignatureVersion {
+		return reject("version_mismatch", "QR code version not supported", false)
+	}
+
+	scanned := scannedAt.Unix()
+	attempt.ClockSkew = qrData.observedSkew(scanned)
+	if qrData.expired(scanned - int64(skew.Seconds())) {
+		return reject("expired", "QR code had expired when it was scanned", false)
+	}
+	if qrData.Timestamp > scanned+int64(skew.Seconds()) {
+		return reject("future_timestamp", "QR code timestamp is invalid", false)
+	}
+
+	// A forged code is rejected outright; the checks below concern genuine ones
+	if valid, err := qsm.verifyQRSignature(&qrData); err != nil {
+		return check, fmt.Errorf("signature validation failed: %v", err)
+	} else if !valid {
+		return reject("invalid_signature", "QR code signature is invalid", false)
+	}
+
+	if blacklisted, err := qsm.isQRBlacklisted(ctx, qrData.Signature); err != nil {
+		return check, fmt.Errorf("blacklist check failed: %v", err)
+	} else if blacklisted {
+		return reject("blacklisted", "QR code has been revoked", true)
+	}
+
+	if used, err := qsm.isQRUsed(ctx, qrData.Signature); err != nil {
+		return check, fmt.Errorf("replay check failed: %v", err)
+	} else if used {
+		return reject("replay_attack", "QR code had already been used", true)
+	}
+
+	if valid, err := qsm.validateSecret(ctx, &qrData); err != nil {
+		return check, fmt.Errorf("secret validation failed: %v", err)
+	} else if !valid {
+		return reject("invalid_secret", "QR secret has changed since the code was issued", true)
+	}
+
+	check.Valid = true
+	check.Message = "QR code is valid"
+	attempt.Success = true
+	qsm.logScanAttempt(ctx, attempt)
+	return check, nil
+}
+
+// MarkOfflineScanUsed marks a valid offline scan's code used, so it can't be replayed
+func (qsm *QRSecurityManager) MarkOfflineScanUsed(ctx context.Context, check *OfflineScanCheck) error {
+	if !check.Valid {
+		return nil
+	}
+	return qsm.markQRUsed(ctx, &check.qrData)
+}
@@ -6,27 +6,45 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"time"
 	"context"
 
+	"github.com/kruakemaths/tru-activity/backend/pkg/audit"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/scrypt"
 )
 
 const (
 	// QR Security Constants
 	QRSecretLength     = 32
+	qrSecretTTL        = 30 * 24 * time.Hour
 	QRExpiryDuration   = 15 * time.Minute
+	// MaxQRValidity caps activity expiry overrides; secrets themselves live 30 days
+	MaxQRValidity      = 7 * 24 * time.Hour
 	MaxQRScanAttempts  = 5
+	// DefaultQRClockSkew is the drift tolerated between scanner and server clocks
+	DefaultQRClockSkew = time.Minute
+	MaxScannerScans    = 120
+	MaxQRGenerations   = 10
 	QRSignatureVersion = 2
+
+	// QRGenerationAuditInterval is how often a student's own code generation is worth auditing
+	QRGenerationAuditInterval = time.Hour
 	
 	// Redis Keys
 	QRSecretKey        = "qr_secret:"
 	QRUsageKey         = "qr_usage:"
 	QRScanAttemptKey   = "qr_scan_attempt:"
+	QRScannerScanKey   = "qr_scanner_scan:"
 	QRBlacklistKey     = "qr_blacklist:"
+	QROwnerKey         = "qr_owner:"
+	QRGenerationKey    = "qr_generation_count:"
+	QRGenAuditKey      = "qr_generation_audited:"
 	
 	// Security Parameters
 	ScryptN = 32768
@@ -38,6 +56,37 @@ type QRSecurityManager struct {
 	redisClient   *redis.Client
 	masterSecret  []byte
 	signatureKey  []byte
+	rateLimits    QRScanRateLimits
+	clockSkew     time.Duration
+	format        QRCodeFormat
+	redisPolicy   QRRedisPolicy
+	breaker       *performance.CircuitBreaker
+	degraded      degradedScans
+	auditLogger   *audit.AuditLogger
+}
+
+// QRScanRateLimits caps scans over fixed windows, separately per student and per scanner
+// device, so a busy check-in gate isn't throttled by one student rescanning. Students are also
+// limited in how many codes they generate for themselves.
+type QRScanRateLimits struct {
+	StudentLimit     int
+	StudentWindow    time.Duration
+	ScannerLimit     int
+	ScannerWindow    time.Duration
+	GenerationLimit  int
+	GenerationWindow time.Duration
+}
+
+// DefaultQRScanRateLimits returns the standard per-minute limits
+func DefaultQRScanRateLimits() QRScanRateLimits {
+	return QRScanRateLimits{
+		StudentLimit:     MaxQRScanAttempts,
+		StudentWindow:    time.Minute,
+		ScannerLimit:     MaxScannerScans,
+		ScannerWindow:    time.Minute,
+		GenerationLimit:  MaxQRGenerations,
+		GenerationWindow: time.Minute,
+	}
 }
 
 type QRData struct {
@@ -47,12 +96,40 @@ type QRData struct {
 	Version     int    `json:"version"`
 	Signature   string `json:"signature"`
 	SecretHash  string `json:"secret_hash"`
+	// ExpiresAt overrides QRExpiryDuration for codes issued with a longer validity, e.g. printed badges
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+}
+
+// expiresAt returns the code's expiry override, or QRExpiryDuration after issue without one
+func (d *QRData) expiresAt() int64 {
+	if d.ExpiresAt != 0 {
+		return d.ExpiresAt
+	}
+	return d.Timestamp + int64(QRExpiryDuration.Seconds())
+}
+
+// expired reports whether the code is past its expiry
+func (d *QRData) expired(now int64) bool {
+	return now > d.expiresAt()
+}
+
+// observedSkew returns how many seconds now falls outside the code's validity window, for
+// diagnosing scanner clocks; see QRScanAttempt.ClockSkew
+func (d *QRData) observedSkew(now int64) int64 {
+	switch {
+	case d.Timestamp > now:
+		return d.Timestamp - now
+	case now > d.expiresAt():
+		return d.expiresAt() - now
+	}
+	return 0
 }
 
 type QRValidationResult struct {
 	Valid         bool   `json:"valid"`
 	StudentID     string `json:"student_id,omitempty"`
 	Message       string `json:"message"`
+	ErrorReason   string `json:"error_reason,omitempty"`
 	SecurityLevel string `json:"security_level"`
 	Timestamp     int64  `json:"timestamp"`
 }
@@ -67,9 +144,32 @@ type QRScanAttempt struct {
 	UserAgent     string    `json:"user_agent"`
 	ActivityID    string    `json:"activity_id"`
 	ScannerID     string    `json:"scanner_id"`
+	FacultyID     string    `json:"faculty_id,omitempty"`
+	// ClockSkew is how far outside its validity window a code was scanned, in seconds: positive
+	// when dated ahead of the server clock, negative when past its expiry. Zero when inside it.
+	ClockSkew     int64     `json:"clock_skew_seconds,omitempty"`
 }
 
-func NewQRSecurityManager(redisClient *redis.Client, masterSecret []byte) *QRSecurityManager {
+// QR scan failure reasons reported on the security dashboard
+var QRFailureReasons = []string{"expired", "replay_attack", "invalid_signature", "student_rate_limited", "scanner_rate_limited", "blacklisted"}
+
+// QRDailySecurityMetrics holds one day of scan counters
+type QRDailySecurityMetrics struct {
+	Date           string           `json:"date"`
+	TotalScans     int64            `json:"total_scans"`
+	SuccessScans   int64            `json:"success_scans"`
+	FailureScans   int64            `json:"failure_scans"`
+	FailureReasons map[string]int64 `json:"failure_reasons"`
+}
+
+// NewQRSecurityManager creates the QR manager. clockSkew is tolerated on both ends of a code's
+// validity window; zero uses DefaultQRClockSkew. format selects the encoding of new codes.
+// redisPolicy sets when validation stops calling a failing Redis and whether it then degrades.
+func NewQRSecurityManager(redisClient *redis.Client, masterSecret []byte, rateLimits QRScanRateLimits, clockSkew time.Duration, format QRCodeFormat, redisPolicy QRRedisPolicy) *QRSecurityManager {
+	if clockSkew <= 0 {
+		clockSkew = DefaultQRClockSkew
+	}
+
 	// Derive signature key from master secret
 	signatureKey := sha256.Sum256(append(masterSecret, []byte("qr_signature")...))
 	
@@ -77,6 +177,11 @@ func NewQRSecurityManager(redisClient *redis.Client, masterSecret []byte) *QRSec
 		redisClient:  redisClient,
 		masterSecret: masterSecret,
 		signatureKey: signatureKey[:],
+		rateLimits:   rateLimits,
+		clockSkew:    clockSkew,
+		format:       format.WithDefaults(),
+		redisPolicy:  redisPolicy,
+		breaker:      performance.NewCircuitBreaker("QR validation redis", redisPolicy.FailureThreshold, redisPolicy.Cooldown),
 	}
 }
 
@@ -88,6 +193,23 @@ func (qsm *QRSecurityManager) GenerateQRData(ctx context.Context, studentID stri
 		return nil, fmt.Errorf("failed to get QR secret: %v", err)
 	}
 	
+	qrData, err := qsm.buildQRData(studentID, secret, 0)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Track QR usage
+	if err := qsm.trackQRGeneration(ctx, studentID, qrData); err != nil {
+		// Log error but don't fail - this is for monitoring
+		fmt.Printf("Warning: failed to track QR generation: %v\n", err)
+	}
+	
+	return qrData, nil
+}
+
+// buildQRData creates signed QR data from the student's secret. A zero expiresAt means
+// the standard QRExpiryDuration applies.
+func (qsm *QRSecurityManager) buildQRData(studentID string, secret []byte, expiresAt int64) (*QRData, error) {
 	// Generate nonce for this QR code
 	nonce, err := qsm.generateNonce()
 	if err != nil {
@@ -108,6 +230,7 @@ func (qsm *QRSecurityManager) GenerateQRData(ctx context.Context, studentID stri
 		Nonce:      nonce,
 		Version:    QRSignatureVersion,
 		SecretHash: secretHash,
+		ExpiresAt:  expiresAt,
 	}
 	
 	// Generate signature
@@ -117,21 +240,20 @@ func (qsm *QRSecurityManager) GenerateQRData(ctx context.Context, studentID stri
 	}
 	
 	qrData.Signature = signature
-	
-	// Track QR usage
-	if err := qsm.trackQRGeneration(ctx, studentID, qrData); err != nil {
-		// Log error but don't fail - this is for monitoring
-		fmt.Printf("Warning: failed to track QR generation: %v\n", err)
-	}
-	
 	return qrData, nil
 }
 
-// Validate QR data with comprehensive security checks
-func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr string, scannerID string, activityID string, clientIP string, userAgent string) (*QRValidationResult, error) {
-	result := &QRValidationResult{
+// Validate QR data with comprehensive security checks.
+// facultyID is the activity's faculty, used to scope security metrics; it may be empty.
+func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr string, scannerID string, activityID string, facultyID string, clientIP string, userAgent string) (result *QRValidationResult, err error) {
+	ctx, span := monitoring.StartSpan(ctx, "qr.validate",
+		attribute.String("qr.activity_id", activityID),
+		attribute.String("qr.scanner_id", scannerID),
+	)
+
+	result = &QRValidationResult{
 		Valid:         false,
-		SecurityLevel: "high",
+		SecurityLevel: SecurityLevelHigh,
 		Timestamp:     time.Now().Unix(),
 	}
 	
@@ -143,32 +265,58 @@ func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr stri
 		UserAgent:  userAgent,
 		ActivityID: activityID,
 		ScannerID:  scannerID,
+		FacultyID:  facultyID,
 	}
 	
 	defer func() {
 		// Always log the scan attempt
+		result.ErrorReason = attempt.ErrorReason
 		qsm.logScanAttempt(ctx, attempt)
+
+		span.SetAttributes(
+			attribute.Bool("qr.valid", result.Valid),
+			attribute.String("qr.failure_reason", attempt.ErrorReason),
+		)
+		monitoring.EndSpan(span, err)
 	}()
 	
-	// 1. Parse QR data
-	var qrData QRData
-	if err := json.Unmarshal([]byte(qrDataStr), &qrData); err != nil {
+	// 1. Parse QR data, in whichever encoding it was issued
+	if len(qrDataStr) > qsm.format.MaxPayload {
+		result.Message = "QR data is too large"
+		attempt.ErrorReason = "payload_too_large"
+		return result, nil
+	}
+	decoded, err := DecodeQRData(qrDataStr)
+	if err != nil {
 		result.Message = "Invalid QR data format"
 		attempt.ErrorReason = "parse_error"
 		return result, nil
 	}
+	qrData := *decoded
 	
 	attempt.UserID = qrData.StudentID
 	
+	// While Redis is failing, scans may be validated in degraded mode: the checks needing
+	// Redis (blacklist, replay, rate limits, secret) are skipped and reconciled on recovery
+	degraded := qsm.redisPolicy.Degrade && qsm.redisDown()
+	degrade := func() bool {
+		if qsm.redisPolicy.Degrade {
+			degraded = true
+		}
+		return degraded
+	}
+	
 	// 2. Check if QR is blacklisted
-	if blacklisted, err := qsm.isQRBlacklisted(ctx, qrData.Signature); err != nil {
-		result.Message = "QR validation service error"
-		attempt.ErrorReason = "service_error"
-		return result, fmt.Errorf("blacklist check failed: %v", err)
-	} else if blacklisted {
-		result.Message = "QR code has been revoked"
-		attempt.ErrorReason = "blacklisted"
-		return result, nil
+	if !degraded {
+		if blacklisted, err := qsm.isQRBlacklisted(ctx, qrData.Signature); err != nil && !degrade() {
+			result.Message = "QR validation service error"
+			attempt.ErrorReason = "service_error"
+			return result, fmt.Errorf("blacklist check failed: %v", err)
+		} else if blacklisted {
+			result.Message = "QR code has been revoked"
+			attempt.ErrorReason = "blacklisted"
+			return result, nil
+		}
 	}
 	
 	// 3. Version check
@@ -178,42 +326,59 @@ func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr stri
 		return result, nil
 	}
 	
-	// 4. Timestamp validation (prevent expired and future QR codes)
+	// 4. Timestamp validation (prevent expired and future QR codes), tolerating clock skew
 	now := time.Now().Unix()
-	qrTime := qrData.Timestamp
+	attempt.ClockSkew = qrData.observedSkew(now)
+	skew := int64(qsm.clockSkew.Seconds())
 	
-	if now-qrTime > int64(QRExpiryDuration.Seconds()) {
+	if qrData.expired(now - skew) {
 		result.Message = "QR code has expired"
 		attempt.ErrorReason = "expired"
 		return result, nil
 	}
 	
-	if qrTime > now+60 { // Allow 1 minute clock skew
+	if qrData.Timestamp > now+skew {
 		result.Message = "QR code timestamp is invalid"
 		attempt.ErrorReason = "future_timestamp"
 		return result, nil
 	}
 	
 	// 5. Replay attack prevention
-	if used, err := qsm.isQRUsed(ctx, qrData.Signature); err != nil {
-		result.Message = "QR validation service error"
-		attempt.ErrorReason = "service_error"
-		return result, fmt.Errorf("replay check failed: %v", err)
-	} else if used {
-		result.Message = "QR code has already been used"
-		attempt.ErrorReason = "replay_attack"
-		return result, nil
+	if !degraded {
+		if used, err := qsm.isQRUsed(ctx, qrData.Signature); err != nil && !degrade() {
+			result.Message = "QR validation service error"
+			attempt.ErrorReason = "service_error"
+			return result, fmt.Errorf("replay check failed: %v", err)
+		} else if used {
+			result.Message = "QR code has already been used"
+			attempt.ErrorReason = "replay_attack"
+			return result, nil
+		}
 	}
 	
-	// 6. Rate limiting check
-	if exceeded, err := qsm.checkScanRateLimit(ctx, qrData.StudentID); err != nil {
-		result.Message = "QR validation service error"
-		attempt.ErrorReason = "service_error"
-		return result, fmt.Errorf("rate limit check failed: %v", err)
-	} else if exceeded {
-		result.Message = "Too many scan attempts"
-		attempt.ErrorReason = "rate_limited"
-		return result, nil
+	// 6. Rate limiting check; scans refused for the student don't count against the scanner
+	limits := qsm.rateLimits
+	if !degraded {
+		if exceeded, err := qsm.checkScanRateLimit(ctx, rediskeys.Key(QRScanAttemptKey+qrData.StudentID), limits.StudentLimit, limits.StudentWindow); err != nil && !degrade() {
+			result.Message = "QR validation service error"
+			attempt.ErrorReason = "service_error"
+			return result, fmt.Errorf("rate limit check failed: %v", err)
+		} else if exceeded {
+			result.Message = "Too many scan attempts for this student"
+			attempt.ErrorReason = "student_rate_limited"
+			return result, nil
+		}
+	}
+	if scannerID != "" && !degraded {
+		if exceeded, err := qsm.checkScanRateLimit(ctx, rediskeys.Key(QRScannerScanKey+scannerID), limits.ScannerLimit, limits.ScannerWindow); err != nil && !degrade() {
+			result.Message = "QR validation service error"
+			attempt.ErrorReason = "service_error"
+			return result, fmt.Errorf("rate limit check failed: %v", err)
+		} else if exceeded {
+			result.Message = "This scanner is scanning too fast, please wait a moment"
+			attempt.ErrorReason = "scanner_rate_limited"
+			return result, nil
+		}
 	}
 	
 	// 7. Signature validation
@@ -228,22 +393,51 @@ func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr stri
 	}
 	
 	// 8. Secret validation
-	if valid, err := qsm.validateSecret(ctx, &qrData); err != nil {
-		result.Message = "QR secret validation error"
-		attempt.ErrorReason = "secret_error"
-		return result, fmt.Errorf("secret validation failed: %v", err)
-	} else if !valid {
-		result.Message = "QR secret is invalid"
-		attempt.ErrorReason = "invalid_secret"
-		return result, nil
+	if !degraded {
+		if valid, err := qsm.validateSecret(ctx, &qrData); err != nil && !degrade() {
+			result.Message = "QR secret validation error"
+			attempt.ErrorReason = "secret_error"
+			return result, fmt.Errorf("secret validation failed: %v", err)
+		} else if err == nil && !valid {
+			result.Message = "QR secret is invalid"
+			attempt.ErrorReason = "invalid_secret"
+			return result, nil
+		}
 	}
 	
 	// 9. Mark QR as used to prevent replay
-	if err := qsm.markQRUsed(ctx, qrData.Signature, qrData.StudentID); err != nil {
-		// This is critical - if we can't mark it as used, reject the scan
-		result.Message = "QR processing error"
-		attempt.ErrorReason = "mark_used_error"
-		return result, fmt.Errorf("failed to mark QR as used: %v", err)
+	if !degraded {
+		if err := qsm.markQRUsed(ctx, &qrData); err != nil && !degrade() {
+			// This is critical - if we can't mark it as used, reject the scan
+			result.Message = "QR processing error"
+			attempt.ErrorReason = "mark_used_error"
+			return result, fmt.Errorf("failed to mark QR as used: %v", err)
+		}
+	}
+	
+	// In degraded mode the scan is remembered instead, for the replay check to run on recovery
+	if degraded {
+		scan := degradedScan{
+			qrData:     qrData,
+			activityID: activityID,
+			scannerID:  scannerID,
+			facultyID:  facultyID,
+			clientIP:   clientIP,
+			acceptedAt: time.Now(),
+		}
+		accepted, full := qsm.acceptDegradedScan(scan)
+		if full {
+			result.Message = "QR validation service error"
+			attempt.ErrorReason = "service_error"
+			return result, fmt.Errorf("too many QR scans awaiting reconciliation")
+		}
+		if !accepted {
+			result.Message = "QR code has already been used"
+			attempt.ErrorReason = "replay_attack"
+			return result, nil
+		}
+		result.SecurityLevel = SecurityLevelDegraded
+		go qsm.logDegradedEvent(context.WithoutCancel(ctx), scan, audit.SecurityEventQRDegraded, audit.RiskLevelMedium, "QR scan accepted without replay, blacklist and rate limit checks while Redis was unavailable")
 	}
 	
 	// Success!
@@ -257,7 +451,7 @@ func (qsm *QRSecurityManager) ValidateQRData(ctx context.Context, qrDataStr stri
 
 // Get or generate QR secret for a user
 func (qsm *QRSecurityManager) getUserQRSecret(ctx context.Context, studentID string) ([]byte, error) {
-	key := QRSecretKey + studentID
+	key := rediskeys.Key(QRSecretKey + studentID)
 	
 	// Try to get existing secret
 	secretStr, err := qsm.redisClient.Get(ctx, key).Result()
@@ -273,7 +467,7 @@ func (qsm *QRSecurityManager) getUserQRSecret(ctx context.Context, studentID str
 	
 	// Store secret (expires in 30 days, will be regenerated)
 	secretB64 := base64.StdEncoding.EncodeToString(secret)
-	if err := qsm.redisClient.Set(ctx, key, secretB64, 30*24*time.Hour).Err(); err != nil {
+	if err := qsm.redisClient.Set(ctx, key, secretB64, qrSecretTTL).Err(); err != nil {
 		return nil, fmt.Errorf("failed to store secret: %v", err)
 	}
 	
@@ -312,6 +506,10 @@ func (qsm *QRSecurityManager) signQRData(qrData *QRData) (string, error) {
 		qrData.Version,
 		qrData.SecretHash,
 	)
+	// Only codes with an expiry override sign it, so standard codes keep their signatures
+	if qrData.ExpiresAt != 0 {
+		payload += fmt.Sprintf(":%d", qrData.ExpiresAt)
+	}
 	
 	mac := hmac.New(sha256.New, qsm.signatureKey)
 	mac.Write([]byte(payload))
@@ -335,7 +533,11 @@ func (qsm *QRSecurityManager) verifyQRSignature(qrData *QRData) (bool, error) {
 // Validate the secret hash
 func (qsm *QRSecurityManager) validateSecret(ctx context.Context, qrData *QRData) (bool, error) {
 	// Get user's current secret
-	secret, err := qsm.getUserQRSecret(ctx, qrData.StudentID)
+	var secret []byte
+	err := qsm.redisCall(func() (err error) {
+		secret, err = qsm.getUserQRSecret(ctx, qrData.StudentID)
+		return err
+	})
 	if err != nil {
 		return false, err
 	}
@@ -351,75 +553,126 @@ func (qsm *QRSecurityManager) validateSecret(ctx context.Context, qrData *QRData
 
 // Check if QR has been used (prevent replay attacks)
 func (qsm *QRSecurityManager) isQRUsed(ctx context.Context, signature string) (bool, error) {
-	key := QRUsageKey + signature
-	exists, err := qsm.redisClient.Exists(ctx, key).Result()
+	key := rediskeys.Key(QRUsageKey + signature)
+	var exists int64
+	err := qsm.redisCall(func() (err error) {
+		exists, err = qsm.redisClient.Exists(ctx, key).Result()
+		return err
+	})
 	return exists > 0, err
 }
 
 // Mark QR as used
-func (qsm *QRSecurityManager) markQRUsed(ctx context.Context, signature string, studentID string) error {
-	key := QRUsageKey + signature
+func (qsm *QRSecurityManager) markQRUsed(ctx context.Context, qrData *QRData) error {
+	key := rediskeys.Key(QRUsageKey + qrData.Signature)
 	
 	// Store with expiry (longer than QR expiry to prevent replay)
 	usage := map[string]interface{}{
-		"student_id": studentID,
+		"student_id": qrData.StudentID,
 		"used_at":    time.Now().Unix(),
 	}
 	
-	pipe := qsm.redisClient.Pipeline()
-	pipe.HMSet(ctx, key, usage)
-	pipe.Expire(ctx, key, 2*QRExpiryDuration) // Double the QR expiry
-	_, err := pipe.Exec(ctx)
+	ttl := 2 * QRExpiryDuration // Double the QR expiry
+	if qrData.ExpiresAt != 0 {
+		// Long-lived codes must stay marked until they expire
+		ttl = time.Until(time.Unix(qrData.ExpiresAt, 0)) + QRExpiryDuration
+	}
 	
-	return err
+	return qsm.redisCall(func() error {
+		pipe := qsm.redisClient.Pipeline()
+		pipe.HMSet(ctx, key, usage)
+		pipe.Expire(ctx, key, ttl)
+		_, err := pipe.Exec(ctx)
+		return err
+	})
 }
 
-// Check scan rate limiting
-func (qsm *QRSecurityManager) checkScanRateLimit(ctx context.Context, studentID string) (bool, error) {
-	key := QRScanAttemptKey + studentID
-	
-	// Allow MaxQRScanAttempts per minute
-	count, err := qsm.redisClient.Incr(ctx, key).Result()
+// Check scan rate limiting: allow limit scans per window for key
+func (qsm *QRSecurityManager) checkScanRateLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	count, err := qsm.countInWindow(ctx, key, window)
 	if err != nil {
 		return false, err
 	}
 	
+	return count > int64(limit), nil
+}
+
+// countInWindow counts another event for key and returns the count in the current window
+func (qsm *QRSecurityManager) countInWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var count int64
+	err := qsm.redisCall(func() (err error) {
+		count, err = qsm.redisClient.Incr(ctx, key).Result()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	
 	if count == 1 {
 		// Set expiry on first increment
-		qsm.redisClient.Expire(ctx, key, time.Minute)
+		qsm.redisClient.Expire(ctx, key, window)
 	}
 	
-	return count > MaxQRScanAttempts, nil
+	return count, nil
 }
 
 // Check if QR is blacklisted
 func (qsm *QRSecurityManager) isQRBlacklisted(ctx context.Context, signature string) (bool, error) {
-	key := QRBlacklistKey + signature
-	exists, err := qsm.redisClient.Exists(ctx, key).Result()
+	key := rediskeys.Key(QRBlacklistKey + signature)
+	var exists int64
+	err := qsm.redisCall(func() (err error) {
+		exists, err = qsm.redisClient.Exists(ctx, key).Result()
+		return err
+	})
 	return exists > 0, err
 }
 
-// Blacklist a QR code (e.g., if user reports it as compromised)
-func (qsm *QRSecurityManager) BlacklistQR(ctx context.Context, signature string, reason string) error {
-	key := QRBlacklistKey + signature
+// Blacklist a QR code (e.g., if user reports it as compromised); returns when the entry expires
+func (qsm *QRSecurityManager) BlacklistQR(ctx context.Context, signature string, reason string) (time.Time, error) {
+	key := rediskeys.Key(QRBlacklistKey + signature)
 	
 	blacklistEntry := map[string]interface{}{
 		"blacklisted_at": time.Now().Unix(),
 		"reason":        reason,
 	}
 	
-	// Blacklist for 24 hours
+	// Blacklist for 24 hours, or for as long as a long-lived code stays valid
+	ttl := 24 * time.Hour
+	if ownerTTL, err := qsm.redisClient.TTL(ctx, rediskeys.Key(QROwnerKey+signature)).Result(); err == nil && ownerTTL > ttl {
+		ttl = ownerTTL
+	}
+	
 	pipe := qsm.redisClient.Pipeline()
 	pipe.HMSet(ctx, key, blacklistEntry)
-	pipe.Expire(ctx, key, 24*time.Hour)
-	_, err := pipe.Exec(ctx)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return time.Time{}, err
+	}
 	
-	return err
+	return time.Now().Add(ttl), nil
+}
+
+// QROwner returns the student a QR signature was issued to, or "" once its record has expired
+func (qsm *QRSecurityManager) QROwner(ctx context.Context, signature string) (string, error) {
+	studentID, err := qsm.redisClient.Get(ctx, rediskeys.Key(QROwnerKey+signature)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return studentID, err
+}
+
+// ValidQRSignature reports whether s has the shape of a QR signature: hex HMAC-SHA256
+func ValidQRSignature(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
 }
 
 // Regenerate QR secret for a user (e.g., if compromised)
 func (qsm *QRSecurityManager) RegenerateUserSecret(ctx context.Context, studentID string) error {
-	key := QRSecretKey + studentID
+	key := rediskeys.Key(QRSecretKey + studentID)
 	
 	// Delete existing secret
 	if err := qsm.redisClient.Del(ctx, key).Err(); err != nil {
@@ -432,24 +685,38 @@ func (qsm *QRSecurityManager) RegenerateUserSecret(ctx context.Context, studentI
 
 // Track QR generation for monitoring
 func (qsm *QRSecurityManager) trackQRGeneration(ctx context.Context, studentID string, qrData *QRData) error {
-	event := map[string]interface{}{
-		"student_id": studentID,
-		"timestamp":  qrData.Timestamp,
-		"nonce":      qrData.Nonce,
-		"version":    qrData.Version,
-		"generated_at": time.Now().Unix(),
-	}
-	
 	// Store generation event
-	key := fmt.Sprintf("qr_generation:%s:%d", studentID, qrData.Timestamp)
 	pipe := qsm.redisClient.Pipeline()
-	pipe.HMSet(ctx, key, event)
-	pipe.Expire(ctx, key, 24*time.Hour)
+	queueQRGeneration(ctx, pipe, studentID, qrData)
 	_, err := pipe.Exec(ctx)
 	
 	return err
 }
 
+// queueQRGeneration adds a generation event to pipe
+func queueQRGeneration(ctx context.Context, pipe redis.Pipeliner, studentID string, qrData *QRData) {
+	event := map[string]interface{}{
+		"student_id":   studentID,
+		"timestamp":    qrData.Timestamp,
+		"nonce":        qrData.Nonce,
+		"version":      qrData.Version,
+		"generated_at": time.Now().Unix(),
+	}
+
+	key := rediskeys.Keyf("qr_generation:%s:%d", studentID, qrData.Timestamp)
+	pipe.HMSet(ctx, key, event)
+	pipe.Expire(ctx, key, 24*time.Hour)
+
+	// Remember the owner so a leaked code can be revoked by its signature
+	ownerTTL := 24 * time.Hour
+	if qrData.ExpiresAt != 0 {
+		if untilExpiry := time.Until(time.Unix(qrData.ExpiresAt, 0)); untilExpiry > ownerTTL {
+			ownerTTL = untilExpiry
+		}
+	}
+	pipe.Set(ctx, rediskeys.Key(QROwnerKey+qrData.Signature), studentID, ownerTTL)
+}
+
 // Log scan attempt for security monitoring
 func (qsm *QRSecurityManager) logScanAttempt(ctx context.Context, attempt *QRScanAttempt) {
 	// Convert to map for Redis storage
@@ -466,9 +733,12 @@ func (qsm *QRSecurityManager) logScanAttempt(ctx context.Context, attempt *QRSca
 	if attempt.ErrorReason != "" {
 		attemptMap["error_reason"] = attempt.ErrorReason
 	}
+	if attempt.ClockSkew != 0 {
+		attemptMap["clock_skew_seconds"] = attempt.ClockSkew
+	}
 	
 	// Store attempt log
-	key := fmt.Sprintf("qr_scan_log:%s:%d", attempt.ScannerID, attempt.Timestamp.Unix())
+	key := rediskeys.Keyf("qr_scan_log:%s:%d", attempt.ScannerID, attempt.Timestamp.Unix())
 	pipe := qsm.redisClient.Pipeline()
 	pipe.HMSet(ctx, key, attemptMap)
 	pipe.Expire(ctx, key, 7*24*time.Hour) // Keep logs for 7 days
@@ -489,28 +759,35 @@ func (qsm *QRSecurityManager) updateSecurityMetrics(ctx context.Context, attempt
 	
 	pipe := qsm.redisClient.Pipeline()
 	
-	// Total scans
-	pipe.Incr(ctx, fmt.Sprintf("metrics:qr_scans:%s", today))
-	
-	// Success/failure counts
-	if attempt.Success {
-		pipe.Incr(ctx, fmt.Sprintf("metrics:qr_success:%s", today))
-	} else {
-		pipe.Incr(ctx, fmt.Sprintf("metrics:qr_failure:%s", today))
+	// Institution-wide counters, plus faculty-scoped ones when the activity's faculty is known
+	prefixes := []string{qrMetricsPrefix("")}
+	if attempt.FacultyID != "" {
+		prefixes = append(prefixes, qrMetricsPrefix(attempt.FacultyID))
+	}
+	for _, prefix := range prefixes {
+		// Total scans
+		pipe.Incr(ctx, fmt.Sprintf("%sqr_scans:%s", prefix, today))
 		
-		// Track failure reasons
-		if attempt.ErrorReason != "" {
-			pipe.Incr(ctx, fmt.Sprintf("metrics:qr_failure:%s:%s", today, attempt.ErrorReason))
+		// Success/failure counts
+		if attempt.Success {
+			pipe.Incr(ctx, fmt.Sprintf("%sqr_success:%s", prefix, today))
+		} else {
+			pipe.Incr(ctx, fmt.Sprintf("%sqr_failure:%s", prefix, today))
+			
+			// Track failure reasons
+			if attempt.ErrorReason != "" {
+				pipe.Incr(ctx, fmt.Sprintf("%sqr_failure:%s:%s", prefix, today, attempt.ErrorReason))
+			}
 		}
 	}
 	
 	// Per-user metrics
 	if attempt.UserID != "" {
-		pipe.Incr(ctx, fmt.Sprintf("metrics:user_scans:%s:%s", attempt.UserID, today))
+		pipe.Incr(ctx, rediskeys.Keyf("metrics:user_scans:%s:%s", attempt.UserID, today))
 	}
 	
 	// Per-scanner metrics
-	pipe.Incr(ctx, fmt.Sprintf("metrics:scanner_scans:%s:%s", attempt.ScannerID, today))
+	pipe.Incr(ctx, rediskeys.Keyf("metrics:scanner_scans:%s:%s", attempt.ScannerID, today))
 	
 	// Set expiry for all metrics (keep for 30 days)
 	// This would need to be done for each key individually in a real implementation
@@ -521,6 +798,14 @@ func (qsm *QRSecurityManager) updateSecurityMetrics(ctx context.Context, attempt
 	}
 }
 
+// qrMetricsPrefix returns the Redis key prefix for institution-wide or per-faculty scan metrics
+func qrMetricsPrefix(facultyID string) string {
+	if facultyID == "" {
+		return rediskeys.Key("metrics:")
+	}
+	return rediskeys.Key("metrics:faculty:" + facultyID + ":")
+}
+
 // Get security metrics for monitoring dashboard
 func (qsm *QRSecurityManager) GetSecurityMetrics(ctx context.Context, days int) (map[string]interface{}, error) {
 	metrics := make(map[string]interface{})
@@ -530,9 +815,9 @@ func (qsm *QRSecurityManager) GetSecurityMetrics(ctx context.Context, days int)
 		date := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
 		
 		// Get daily metrics
-		totalScans, _ := qsm.redisClient.Get(ctx, fmt.Sprintf("metrics:qr_scans:%s", date)).Int64()
-		successScans, _ := qsm.redisClient.Get(ctx, fmt.Sprintf("metrics:qr_success:%s", date)).Int64()
-		failureScans, _ := qsm.redisClient.Get(ctx, fmt.Sprintf("metrics:qr_failure:%s", date)).Int64()
+		totalScans, _ := qsm.redisClient.Get(ctx, rediskeys.Keyf("metrics:qr_scans:%s", date)).Int64()
+		successScans, _ := qsm.redisClient.Get(ctx, rediskeys.Keyf("metrics:qr_success:%s", date)).Int64()
+		failureScans, _ := qsm.redisClient.Get(ctx, rediskeys.Keyf("metrics:qr_failure:%s", date)).Int64()
 		
 		metrics[date] = map[string]interface{}{
 			"total_scans":   totalScans,
@@ -544,6 +829,113 @@ func (qsm *QRSecurityManager) GetSecurityMetrics(ctx context.Context, days int)
 	return metrics, nil
 }
 
+// GetDailySecurityMetrics returns scan counters and failure reasons for the last N days, oldest first.
+// A non-empty facultyID limits the counters to scans for that faculty's activities.
+func (qsm *QRSecurityManager) GetDailySecurityMetrics(ctx context.Context, days int, facultyID string) ([]QRDailySecurityMetrics, error) {
+	prefix := qrMetricsPrefix(facultyID)
+	dates := make([]string, days)
+	for i := 0; i < days; i++ {
+		dates[i] = time.Now().AddDate(0, 0, i-days+1).Format("2006-01-02")
+	}
+
+	// Read every counter in one round trip; missing keys count as zero
+	pipe := qsm.redisClient.Pipeline()
+	type dailyCmds struct {
+		total, success, failure *redis.StringCmd
+		reasons                 map[string]*redis.StringCmd
+	}
+	cmds := make([]dailyCmds, days)
+	for i, date := range dates {
+		cmds[i] = dailyCmds{
+			total:   pipe.Get(ctx, fmt.Sprintf("%sqr_scans:%s", prefix, date)),
+			success: pipe.Get(ctx, fmt.Sprintf("%sqr_success:%s", prefix, date)),
+			failure: pipe.Get(ctx, fmt.Sprintf("%sqr_failure:%s", prefix, date)),
+			reasons: make(map[string]*redis.StringCmd, len(QRFailureReasons)),
+		}
+		for _, reason := range QRFailureReasons {
+			cmds[i].reasons[reason] = pipe.Get(ctx, fmt.Sprintf("%sqr_failure:%s:%s", prefix, date, reason))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read QR security metrics: %v", err)
+	}
+
+	metrics := make([]QRDailySecurityMetrics, days)
+	for i, date := range dates {
+		total, _ := cmds[i].total.Int64()
+		success, _ := cmds[i].success.Int64()
+		failure, _ := cmds[i].failure.Int64()
+		reasons := make(map[string]int64, len(QRFailureReasons))
+		for reason, cmd := range cmds[i].reasons {
+			reasons[reason], _ = cmd.Int64()
+		}
+		metrics[i] = QRDailySecurityMetrics{
+			Date:           date,
+			TotalScans:     total,
+			SuccessScans:   success,
+			FailureScans:   failure,
+			FailureReasons: reasons,
+		}
+	}
+
+	return metrics, nil
+}
+
+// IssuedQRCode is a QR code a student generated for themselves
+type IssuedQRCode struct {
+	QRString  string
+	ExpiresAt time.Time
+	// Audit is set on the student's first code in QRGenerationAuditInterval, so refreshing
+	// clients don't flood the audit log
+	Audit bool
+}
+
+// QRGenerationLimitError is returned when a student generates more codes than the limit allows
+type QRGenerationLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *QRGenerationLimitError) Error() string {
+	return "too many QR codes generated"
+}
+
+// IssueQRCode generates a QR string for the student within their generation limit, e.g. for
+// an app that shows the code with a countdown and refreshes it before it expires
+func (qsm *QRSecurityManager) IssueQRCode(ctx context.Context, studentID string) (*IssuedQRCode, error) {
+	if limit := qsm.rateLimits.GenerationLimit; limit > 0 {
+		key := rediskeys.Key(QRGenerationKey + studentID)
+		count, err := qsm.countInWindow(ctx, key, qsm.rateLimits.GenerationWindow)
+		if err != nil {
+			return nil, fmt.Errorf("generation limit check failed: %v", err)
+		}
+		if count > int64(limit) {
+			retryAfter, err := qsm.redisClient.TTL(ctx, key).Result()
+			if err != nil || retryAfter < 0 {
+				retryAfter = qsm.rateLimits.GenerationWindow
+			}
+			return nil, &QRGenerationLimitError{RetryAfter: retryAfter}
+		}
+	}
+
+	qrData, err := qsm.GenerateQRData(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	qrString, err := EncodeQRData(qrData, qsm.format.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without Redis there's nothing to throttle the audit by, so the code is audited
+	first, err := qsm.redisClient.SetNX(ctx, rediskeys.Key(QRGenAuditKey+studentID), 1, QRGenerationAuditInterval).Result()
+
+	return &IssuedQRCode{
+		QRString:  qrString,
+		ExpiresAt: time.Unix(qrData.expiresAt(), 0),
+		Audit:     first || err != nil,
+	}, nil
+}
+
 // Generate QR string for client-side QR code generation
 func (qsm *QRSecurityManager) GenerateQRString(ctx context.Context, studentID string) (string, error) {
 	qrData, err := qsm.GenerateQRData(ctx, studentID)
@@ -551,12 +943,5 @@ func (qsm *QRSecurityManager) GenerateQRString(ctx context.Context, studentID st
 		return "", err
 	}
 	
-	// Convert to JSON string
-	jsonData, err := json.Marshal(qrData)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal QR data: %v", err)
-	}
-	
-	// Base64 encode for QR code
-	return base64.StdEncoding.EncodeToString(jsonData), nil
+	return EncodeQRData(qrData, qsm.format.Encoding)
 }
\ No newline at end of file
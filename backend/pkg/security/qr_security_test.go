@@ -0,0 +1,138 @@
+package security
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestQRManager(t *testing.T, clockSkew time.Duration) (*QRSecurityManager, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	qsm := NewQRSecurityManager(client, []byte("test master secret"), DefaultQRScanRateLimits(), clockSkew, QRCodeFormat{}, QRRedisPolicy{})
+	return qsm, server
+}
+
+// signedQRString returns a student's code issued at timestamp, signed as the manager would
+func signedQRString(t *testing.T, qsm *QRSecurityManager, studentID string, timestamp, expiresAt int64) string {
+	t.Helper()
+	secret, err := qsm.getUserQRSecret(context.Background(), studentID)
+	if err != nil {
+		t.Fatalf("getUserQRSecret: %v", err)
+	}
+	secretHash, err := qsm.hashSecret(secret, studentID, timestamp)
+	if err != nil {
+		t.Fatalf("hashSecret: %v", err)
+	}
+	qrData := &QRData{
+		StudentID:  studentID,
+		Timestamp:  timestamp,
+		Nonce:      "nonce-" + studentID,
+		Version:    QRSignatureVersion,
+		SecretHash: secretHash,
+		ExpiresAt:  expiresAt,
+	}
+	if qrData.Signature, err = qsm.signQRData(qrData); err != nil {
+		t.Fatalf("signQRData: %v", err)
+	}
+	encoded, err := EncodeQRData(qrData, QREncodingJSON)
+	if err != nil {
+		t.Fatalf("EncodeQRData: %v", err)
+	}
+	return encoded
+}
+
+func TestValidateQRDataClockSkewEdges(t *testing.T) {
+	const skew = 30 * time.Second
+	qsm, server := newTestQRManager(t, skew)
+	// Each edge is approached to within a margin so the test can't race the clock's second
+	const margin = 3
+	now := time.Now().Unix()
+	skewSeconds := int64(skew.Seconds())
+	validity := int64(QRExpiryDuration.Seconds())
+
+	tests := []struct {
+		name       string
+		timestamp  int64
+		expiresAt  int64
+		wantReason string
+		wantSkew   int64
+	}{
+		{"fresh", now - 60, 0, "", 0},
+		{"ahead within the skew", now + skewSeconds - margin, 0, "", 1},
+		{"ahead past the skew", now + skewSeconds + margin, 0, "future_timestamp", 1},
+		{"expired within the skew", now - validity - skewSeconds + margin, 0, "", -1},
+		{"expired past the skew", now - validity - skewSeconds - margin, 0, "expired", -1},
+		{"override expired within the skew", now - 60, now - skewSeconds + margin, "", -1},
+		{"override expired past the skew", now - 60, now - skewSeconds - margin, "expired", -1},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			studentID := "6500" + strconv.Itoa(i)
+			scannerID := "scanner-" + strconv.Itoa(i)
+			qrString := signedQRString(t, qsm, studentID, tt.timestamp, tt.expiresAt)
+
+			result, err := qsm.ValidateQRData(context.Background(), qrString, scannerID, "11", "1", "127.0.0.1", "test")
+			if err != nil {
+				t.Fatalf("ValidateQRData: %v", err)
+			}
+			if result.ErrorReason != tt.wantReason || result.Valid != (tt.wantReason == "") {
+				t.Errorf("ValidateQRData() = valid %v, reason %q, want reason %q", result.Valid, result.ErrorReason, tt.wantReason)
+			}
+
+			// Scans outside the window record which way the scanner's clock was out, even
+			// when the skew tolerance let them through
+			var logged int64
+			for _, key := range server.Keys() {
+				if strings.HasPrefix(key, rediskeys.Key("qr_scan_log:"+scannerID+":")) {
+					logged, _ = strconv.ParseInt(server.HGet(key, "clock_skew_seconds"), 10, 64)
+				}
+			}
+			if skewDirection(logged) != tt.wantSkew {
+				t.Errorf("clock skew logged as %d, want sign %d", logged, tt.wantSkew)
+			}
+		})
+	}
+}
+
+func skewDirection(n int64) int64 {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+func TestQRDataObservedSkew(t *testing.T) {
+	const now = int64(1_800_000_000)
+	validity := int64(QRExpiryDuration.Seconds())
+
+	tests := []struct {
+		name string
+		data QRData
+		want int64
+	}{
+		{"inside the window", QRData{Timestamp: now - 10}, 0},
+		{"at issue", QRData{Timestamp: now}, 0},
+		{"at expiry", QRData{Timestamp: now - validity}, 0},
+		{"ahead", QRData{Timestamp: now + 45}, 45},
+		{"past expiry", QRData{Timestamp: now - validity - 20}, -20},
+		{"past the override", QRData{Timestamp: now - 60, ExpiresAt: now - 5}, -5},
+	}
+	for _, tt := range tests {
+		if got := tt.data.observedSkew(now); got != tt.want {
+			t.Errorf("%s: observedSkew() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
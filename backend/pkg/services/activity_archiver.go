@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"gorm.io/gorm"
+)
+
+const (
+	ArchiveLockKey      = "activity_archiver"
+	ArchiveLockTTL      = 10 * time.Minute
+	ArchiveScanInterval = 1 * time.Hour
+)
+
+type ActivityArchiver struct {
+	DB             *gorm.DB
+	EventPublisher *EventPublisher
+	lock           *lock.DistributedLock
+	archiveAfter   time.Duration
+}
+
+func NewActivityArchiver(db *gorm.DB, publisher *EventPublisher, distributedLock *lock.DistributedLock, archiveAfterDays int) *ActivityArchiver {
+	return &ActivityArchiver{
+		DB:             db,
+		EventPublisher: publisher,
+		lock:           distributedLock,
+		archiveAfter:   time.Duration(archiveAfterDays) * 24 * time.Hour,
+	}
+}
+
+// ArchiveCompletedActivities moves activities that completed before the archive period into ARCHIVED
+func (aa *ActivityArchiver) ArchiveCompletedActivities() (int, error) {
+	cutoff := time.Now().Add(-aa.archiveAfter)
+
+	var activities []models.Activity
+	if err := aa.DB.Where("status = ? AND end_date < ?", models.ActivityStatusCompleted, cutoff).
+		Find(&activities).Error; err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	for i := range activities {
+		activity := &activities[i]
+		now := time.Now()
+
+		// Only transition rows that are still completed, in case an admin changed it meanwhile
+		result := aa.DB.Model(&models.Activity{}).
+			Where("id = ? AND status = ?", activity.ID, models.ActivityStatusCompleted).
+			Updates(map[string]interface{}{
+				"status":      models.ActivityStatusArchived,
+				"archived_at": now,
+			})
+		if result.Error != nil {
+			log.Printf("Failed to archive activity %d: %v", activity.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		activity.Status = models.ActivityStatusArchived
+		activity.ArchivedAt = &now
+		archived++
+
+		if aa.EventPublisher != nil {
+			if err := aa.EventPublisher.PublishActivityArchived(activity, &EventContext{
+				ActivityID: &activity.ID,
+				FacultyID:  activity.FacultyID,
+				Source:     "activity_archiver",
+			}); err != nil {
+				log.Printf("Failed to publish archive event for activity %d: %v", activity.ID, err)
+			}
+		}
+	}
+
+	return archived, nil
+}
+
+// runOnce archives under the distributed lock so only one instance does the work
+func (aa *ActivityArchiver) runOnce() {
+	err := aa.lock.WithLock(context.Background(), ArchiveLockKey, ArchiveLockTTL, func() error {
+		count, err := aa.ArchiveCompletedActivities()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Archived %d completed activities", count)
+		}
+		return nil
+	})
+
+	if err == lock.ErrLockHeld {
+		return
+	}
+	if err != nil {
+		log.Printf("Error archiving completed activities: %v", err)
+	}
+}
+
+// StartArchiveScheduler starts a background loop that periodically archives completed activities
+func (aa *ActivityArchiver) StartArchiveScheduler(ctx context.Context) {
+	ticker := time.NewTicker(ArchiveScanInterval)
+	defer ticker.Stop()
+
+	// Run once immediately
+	aa.runOnce()
+
+	for {
+		select {
+		case <-ticker.C:
+			aa.runOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+)
+
+func TestArchiveCompletedActivities(t *testing.T) {
+	ended := time.Now().Add(-40 * 24 * time.Hour)
+	db, fake := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		switch {
+		case strings.HasPrefix(query, "SELECT"):
+			return fakedb.Result{
+				Columns: []string{"id", "status", "end_date"},
+				Rows: [][]driver.Value{
+					{int64(1), "completed", ended},
+					{int64(2), "completed", ended},
+				},
+			}
+		case strings.HasPrefix(query, `UPDATE "activities"`):
+			if !hasArg(args, string(models.ActivityStatusArchived)) || !hasArg(args, string(models.ActivityStatusCompleted)) {
+				t.Errorf("update %s %v doesn't move completed to archived", query, args)
+			}
+			// Activity 2 was reopened by an admin after it was read
+			if hasArg(args, int64(2)) {
+				return fakedb.Result{}
+			}
+			return fakedb.Result{RowsAffected: 1}
+		}
+		return fakedb.Result{}
+	})
+
+	archiver := NewActivityArchiver(db, nil, nil, 30)
+	archived, err := archiver.ArchiveCompletedActivities()
+	if err != nil {
+		t.Fatalf("ArchiveCompletedActivities: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("archived %d activities, want 1", archived)
+	}
+
+	statements := fake.Statements()
+	selects := fakedb.Containing(statements, `FROM "activities"`, "status = $1 AND end_date < $2")
+	if len(selects) != 1 {
+		t.Errorf("statements = %q, want one read of completed activities ended before the cutoff", statements)
+	}
+	// Each transition only lands while the activity is still completed
+	updates := fakedb.Containing(statements, `UPDATE "activities"`, `"status"=`, "id = $", "AND status = $")
+	if len(updates) != 2 {
+		t.Errorf("statements = %q, want a guarded update per activity", statements)
+	}
+}
+
+func hasArg(args []driver.NamedValue, value driver.Value) bool {
+	for _, arg := range args {
+		if arg.Value == value {
+			return true
+		}
+	}
+	return false
+}
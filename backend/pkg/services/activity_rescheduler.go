@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RescheduleLockKey      = "activity_rescheduler"
+	RescheduleLockTTL      = 10 * time.Minute
+	RescheduleScanInterval = 15 * time.Minute
+)
+
+var ErrNoReschedulePending = errors.New("participation has no pending reschedule")
+
+// ActivityRescheduler flags participants of a rescheduled activity until they confirm or withdraw.
+// Unanswered reschedules are confirmed automatically once the confirm window passes; a zero window disables that.
+type ActivityRescheduler struct {
+	DB                 *gorm.DB
+	EventPublisher     *EventPublisher
+	lock               *lock.DistributedLock
+	confirmWindow      time.Duration
+	reopenRegistration bool
+}
+
+func NewActivityRescheduler(db *gorm.DB, publisher *EventPublisher, distributedLock *lock.DistributedLock, confirmWindowHours int, reopenRegistration bool) *ActivityRescheduler {
+	return &ActivityRescheduler{
+		DB:                 db,
+		EventPublisher:     publisher,
+		lock:               distributedLock,
+		confirmWindow:      time.Duration(confirmWindowHours) * time.Hour,
+		reopenRegistration: reopenRegistration,
+	}
+}
+
+// DatesChanged reports whether an update moves the activity's start or end date
+func DatesChanged(activity *models.Activity, newStart, newEnd *time.Time) bool {
+	return (newStart != nil && !newStart.Equal(activity.StartDate)) ||
+		(newEnd != nil && !newEnd.Equal(activity.EndDate))
+}
+
+// HandleReschedule marks approved participations as pending confirmation within tx and, once it
+// commits, notifies those participants. The activity must already hold its new dates. It returns
+// the number of participants flagged.
+func (ar *ActivityRescheduler) HandleReschedule(tx *gorm.DB, after *database.AfterCommit, activity *models.Activity, oldStart, oldEnd time.Time, ctx *EventContext) (int, error) {
+	var userIDs []uint
+	if err := tx.Model(&models.Participation{}).
+		Where("activity_id = ? AND status = ?", activity.ID, models.ParticipationStatusApproved).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	if len(userIDs) > 0 {
+		if err := tx.Model(&models.Participation{}).
+			Where("activity_id = ? AND status = ? AND user_id IN ?", activity.ID, models.ParticipationStatusApproved, userIDs).
+			Updates(map[string]interface{}{
+				"reschedule_pending":     true,
+				"reschedule_notified_at": time.Now(),
+			}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	// A completed activity moved into the future takes registrations again
+	if ar.reopenRegistration && activity.Status == models.ActivityStatusCompleted && activity.EndDate.After(time.Now()) {
+		if err := tx.Model(activity).Update("status", models.ActivityStatusActive).Error; err != nil {
+			return 0, err
+		}
+		activity.Status = models.ActivityStatusActive
+	}
+
+	if ar.EventPublisher != nil {
+		after.Do(func() {
+			if err := ar.EventPublisher.PublishActivityRescheduled(activity, oldStart, oldEnd, userIDs, ctx); err != nil {
+				log.Printf("Failed to publish reschedule of activity %d: %v", activity.ID, err)
+			}
+		})
+	}
+
+	return len(userIDs), nil
+}
+
+// Respond records a participant's answer to a reschedule; declining withdraws the
+// participation, keeping it and its history. The participation's Activity must be loaded.
+func (ar *ActivityRescheduler) Respond(participation *models.Participation, accept bool) error {
+	status := models.ParticipationStatusApproved
+	if !accept {
+		status = models.ParticipationStatusWithdrawn
+	}
+
+	err := ar.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Participation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, participation.ID).Error; err != nil {
+			return err
+		}
+		if !current.ReschedulePending {
+			return ErrNoReschedulePending
+		}
+		return tx.Model(&current).Updates(map[string]interface{}{
+			"reschedule_pending": false,
+			"status":             status,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	participation.ReschedulePending = false
+	participation.Status = status
+	if !accept && ar.EventPublisher != nil {
+		if err := ar.EventPublisher.PublishParticipationUpdated(participation, "withdrawn", &EventContext{
+			UserID:     &participation.UserID,
+			FacultyID:  participation.Activity.FacultyID,
+			ActivityID: &participation.ActivityID,
+			Source:     "reschedule_response",
+		}); err != nil {
+			log.Printf("Failed to publish withdrawal of user %d from rescheduled activity %d: %v",
+				participation.UserID, participation.ActivityID, err)
+		}
+	}
+	return nil
+}
+
+// AutoConfirmExpired confirms reschedules nobody answered within the confirm window
+func (ar *ActivityRescheduler) AutoConfirmExpired() (int64, error) {
+	result := ar.DB.Model(&models.Participation{}).
+		Where("reschedule_pending = ? AND reschedule_notified_at < ?", true, time.Now().Add(-ar.confirmWindow)).
+		Update("reschedule_pending", false)
+	return result.RowsAffected, result.Error
+}
+
+// runOnce auto-confirms under the distributed lock so only one instance does the work
+func (ar *ActivityRescheduler) runOnce() {
+	err := ar.lock.WithLock(context.Background(), RescheduleLockKey, RescheduleLockTTL, func() error {
+		count, err := ar.AutoConfirmExpired()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Auto-confirmed %d rescheduled participations", count)
+		}
+		return nil
+	})
+
+	if err == lock.ErrLockHeld {
+		return
+	}
+	if err != nil {
+		log.Printf("Error auto-confirming rescheduled participations: %v", err)
+	}
+}
+
+// StartAutoConfirmScheduler starts a background loop that confirms expired reschedules
+func (ar *ActivityRescheduler) StartAutoConfirmScheduler(ctx context.Context) {
+	if ar.confirmWindow <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(RescheduleScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ar.runOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
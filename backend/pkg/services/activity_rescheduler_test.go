@@ -0,0 +1,114 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+)
+
+func TestDatesChanged(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	activity := &models.Activity{StartDate: start, EndDate: end}
+	later := start.Add(time.Hour)
+	sameInstant := start.In(time.FixedZone("ICT", 7*60*60))
+
+	tests := []struct {
+		name     string
+		newStart *time.Time
+		newEnd   *time.Time
+		want     bool
+	}{
+		{"no dates", nil, nil, false},
+		{"same start in another zone", &sameInstant, nil, false},
+		{"start moved", &later, nil, true},
+		{"end moved", nil, &later, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DatesChanged(activity, tt.newStart, tt.newEnd); got != tt.want {
+				t.Errorf("DatesChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// lockedParticipation answers the locking read of Respond with a participation in that state
+func lockedParticipation(pending bool) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.HasPrefix(query, "SELECT") {
+			return fakedb.Result{
+				Columns: []string{"id", "user_id", "activity_id", "status", "reschedule_pending"},
+				Rows:    [][]driver.Value{{int64(3), int64(7), int64(11), "approved", pending}},
+			}
+		}
+		return fakedb.Result{RowsAffected: 1}
+	}
+}
+
+func TestRescheduleRespond(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     bool
+		wantStatus models.ParticipationStatus
+	}{
+		{"accept", true, models.ParticipationStatusApproved},
+		// Declining keeps the participation, withdrawn
+		{"decline", false, models.ParticipationStatusWithdrawn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := fakedb.Open(t, lockedParticipation(true))
+			ar := NewActivityRescheduler(db, nil, nil, 48, false)
+			participation := &models.Participation{ID: 3, UserID: 7, ActivityID: 11, Status: models.ParticipationStatusApproved, ReschedulePending: true}
+
+			if err := ar.Respond(participation, tt.accept); err != nil {
+				t.Fatalf("Respond: %v", err)
+			}
+			if participation.Status != tt.wantStatus || participation.ReschedulePending {
+				t.Errorf("participation = %s, pending %v, want %s, not pending", participation.Status, participation.ReschedulePending, tt.wantStatus)
+			}
+
+			statements := fake.Statements()
+			if len(fakedb.Containing(statements, "DELETE")) != 0 {
+				t.Errorf("participation was deleted: %q", statements)
+			}
+			if len(fakedb.Containing(statements, "SELECT", "FOR UPDATE")) != 1 {
+				t.Errorf("participation was not locked: %q", statements)
+			}
+			updates := fakedb.Containing(statements, `UPDATE "participations" SET`, `"reschedule_pending"`, `"status"`)
+			if len(updates) != 1 {
+				t.Errorf("got %d status updates, want 1: %q", len(updates), statements)
+			}
+			if statements[0] != "BEGIN" || statements[len(statements)-1] != "COMMIT" {
+				t.Errorf("statements did not run in one transaction: %q", statements)
+			}
+		})
+	}
+}
+
+func TestRescheduleRespondWithoutPendingReschedule(t *testing.T) {
+	// Another request answered between loading the participation and locking it
+	db, fake := fakedb.Open(t, lockedParticipation(false))
+	ar := NewActivityRescheduler(db, nil, nil, 48, false)
+	participation := &models.Participation{ID: 3, UserID: 7, ActivityID: 11, Status: models.ParticipationStatusApproved, ReschedulePending: true}
+
+	if err := ar.Respond(participation, false); !errors.Is(err, ErrNoReschedulePending) {
+		t.Fatalf("Respond() = %v, want ErrNoReschedulePending", err)
+	}
+	statements := fake.Statements()
+	if len(fakedb.Containing(statements, `UPDATE "participations"`)) != 0 {
+		t.Errorf("participation was updated: %q", statements)
+	}
+	if statements[len(statements)-1] != "ROLLBACK" {
+		t.Errorf("transaction was not rolled back: %q", statements)
+	}
+	if participation.Status != models.ParticipationStatusApproved {
+		t.Errorf("status = %s, want it unchanged", participation.Status)
+	}
+}
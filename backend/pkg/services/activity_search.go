@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultActivitySearchLimit = 20
+	MaxActivitySearchLimit     = 100
+)
+
+// activitySearchText must match the expressions indexed in migration 008_activity_search.sql.
+// The 'simple' configuration splits on whitespace, which suits Thai text without stemming
+// English words away; Thai phrases written without spaces are matched by the trigram fallback.
+const (
+	activitySearchText   = "(coalesce(activities.title, '') || ' ' || coalesce(activities.description, '') || ' ' || coalesce(activities.location, ''))"
+	activitySearchVector = "to_tsvector('simple', " + activitySearchText + ")"
+	activitySearchQuery  = "plainto_tsquery('simple', ?)"
+)
+
+// SearchActivities ranks activities in scope against a keyword query by full-text relevance,
+// with trigram similarity covering substring matches. It returns one page and the total match count.
+func SearchActivities(scope *gorm.DB, term string, limit, offset int) ([]models.Activity, int64, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return []models.Activity{}, 0, nil
+	}
+
+	pattern := "%" + escapeLikePattern(term) + "%"
+	match := scope.Session(&gorm.Session{}).Model(&models.Activity{}).
+		Where("("+activitySearchVector+" @@ "+activitySearchQuery+" OR "+activitySearchText+" ILIKE ?)", term, pattern)
+
+	var total int64
+	if err := match.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count activity search results: %v", err)
+	}
+	if total == 0 {
+		return []models.Activity{}, 0, nil
+	}
+
+	var activities []models.Activity
+	err := match.Session(&gorm.Session{}).
+		Select("activities.*, ts_rank("+activitySearchVector+", "+activitySearchQuery+") + similarity("+activitySearchText+", ?) AS search_rank", term, term).
+		Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy").
+		Order("search_rank DESC, activities.start_date DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&activities).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search activities: %v", err)
+	}
+
+	return activities, total, nil
+}
+
+// escapeLikePattern makes user input match literally inside a LIKE pattern
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -8,11 +12,24 @@ import (
 	"time"
 
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
 	"gorm.io/gorm"
 )
 
+// RecurringActivityLockTTL bounds how long a crashed generator can block the same series
+const RecurringActivityLockTTL = 2 * time.Minute
+
+// ErrOperationInProgress is returned when another instance or request is generating the same series
+var ErrOperationInProgress = errors.New("operation already in progress")
+
 type ActivityService struct {
 	DB *gorm.DB
+
+	// DateRules validates the schedule of every generated occurrence
+	DateRules ActivityDateRules
+
+	// Lock keeps two callers, on any instance, from generating the same recurring series at once
+	Lock *lock.DistributedLock
 }
 
 type RecurrenceRule struct {
@@ -23,8 +40,8 @@ type RecurrenceRule struct {
 	ByWeekDay []int     // Days of week (0=Sunday, 1=Monday, etc.)
 }
 
-func NewActivityService(db *gorm.DB) *ActivityService {
-	return &ActivityService{DB: db}
+func NewActivityService(db *gorm.DB, distributedLock *lock.DistributedLock) *ActivityService {
+	return &ActivityService{DB: db, DateRules: DefaultActivityDateRules, Lock: distributedLock}
 }
 
 // CreateActivityFromTemplate creates a new activity from a template
@@ -40,8 +57,9 @@ func (as *ActivityService) CreateActivityFromTemplate(templateID uint, input *Ac
 		Description:     input.Description,
 		Type:            template.Type,
 		Status:          models.ActivityStatusDraft,
-		StartDate:       input.StartDate,
-		EndDate:         input.EndDate,
+		StartDate:       input.StartDate.UTC(),
+		EndDate:         input.EndDate.UTC(),
+		Timezone:        input.Timezone,
 		Location:        template.Location,
 		MaxParticipants: template.MaxParticipants,
 		RequireApproval: template.RequireApproval,
@@ -80,28 +98,64 @@ func (as *ActivityService) CreateActivityFromTemplate(templateID uint, input *Ac
 	}
 
 	if err := as.DB.Create(&activity).Error; err != nil {
-		return nil, fmt.Errorf("failed to create activity: %v", err)
+		return nil, fmt.Errorf("failed to create activity: %w", err)
 	}
 
 	return &activity, nil
 }
 
-// CreateRecurringActivities creates multiple activities based on recurrence rule
+// CreateRecurringActivities creates multiple activities based on recurrence rule.
+// Concurrent calls for the same series get ErrOperationInProgress instead of creating duplicates.
 func (as *ActivityService) CreateRecurringActivities(baseActivity *models.Activity, recurrenceRule string, createdByID uint) ([]*models.Activity, error) {
+	if as.Lock != nil {
+		release, err := as.Lock.Acquire(context.Background(), recurringActivityLockKey(baseActivity, recurrenceRule, createdByID), RecurringActivityLockTTL)
+		if err == lock.ErrLockHeld {
+			return nil, ErrOperationInProgress
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	return as.createRecurringActivities(baseActivity, recurrenceRule, createdByID)
+}
+
+// recurringActivityLockKey identifies a series by who creates it, what it is and when it starts
+func recurringActivityLockKey(baseActivity *models.Activity, recurrenceRule string, createdByID uint) string {
+	facultyID := uint(0)
+	if baseActivity.FacultyID != nil {
+		facultyID = *baseActivity.FacultyID
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%s|%s", createdByID, facultyID, baseActivity.Title,
+		baseActivity.StartDate.UTC().Format(time.RFC3339), recurrenceRule)))
+	return "recurring_activity:" + hex.EncodeToString(sum[:16])
+}
+
+func (as *ActivityService) createRecurringActivities(baseActivity *models.Activity, recurrenceRule string, createdByID uint) ([]*models.Activity, error) {
 	rule, err := as.parseRecurrenceRule(recurrenceRule)
 	if err != nil {
 		return nil, fmt.Errorf("invalid recurrence rule: %v", err)
 	}
 
+	if err := as.DateRules.Validate(baseActivity.StartDate, baseActivity.EndDate, true); err != nil {
+		return nil, err
+	}
+
+	// Occurrences are stepped in the activity's zone, so each starts at the same local time
+	// and BYDAY days are local weekdays, whatever the offset from UTC
+	loc := baseActivity.TimeLocation()
 	activities := []*models.Activity{}
-	currentDate := baseActivity.StartDate
+	currentDate := baseActivity.StartDate.In(loc)
 	duration := baseActivity.EndDate.Sub(baseActivity.StartDate)
+	baseActivity.StartDate = baseActivity.StartDate.UTC()
+	baseActivity.EndDate = baseActivity.EndDate.UTC()
 	
 	// Create parent activity
 	baseActivity.IsRecurring = true
 	baseActivity.RecurrenceRule = recurrenceRule
 	if err := as.DB.Create(baseActivity).Error; err != nil {
-		return nil, fmt.Errorf("failed to create base activity: %v", err)
+		return nil, fmt.Errorf("failed to create base activity: %w", err)
 	}
 	activities = append(activities, baseActivity)
 
@@ -118,15 +172,19 @@ func (as *ActivityService) CreateRecurringActivities(baseActivity *models.Activi
 
 		childActivity := *baseActivity // Copy base activity
 		childActivity.ID = 0          // Reset ID for new record
-		childActivity.StartDate = nextDate
-		childActivity.EndDate = nextDate.Add(duration)
+		childActivity.StartDate = nextDate.UTC()
+		childActivity.EndDate = nextDate.Add(duration).UTC()
 		childActivity.ParentActivityID = &baseActivity.ID
 		childActivity.IsRecurring = false
 		childActivity.RecurrenceRule = ""
 		childActivity.Title = fmt.Sprintf("%s (%s)", baseActivity.Title, nextDate.Format("2006-01-02"))
 
+		if err := as.DateRules.Validate(childActivity.StartDate, childActivity.EndDate, true); err != nil {
+			return activities, fmt.Errorf("invalid occurrence on %s: %w", nextDate.Format("2006-01-02"), err)
+		}
+
 		if err := as.DB.Create(&childActivity).Error; err != nil {
-			return activities, fmt.Errorf("failed to create recurring activity: %v", err)
+			return activities, fmt.Errorf("failed to create recurring activity: %w", err)
 		}
 
 		activities = append(activities, &childActivity)
@@ -190,13 +248,13 @@ func (as *ActivityService) GetAdminActivities(adminID uint) ([]models.Activity,
 
 // GetFacultyActivities gets activities scoped to a faculty
 func (as *ActivityService) GetFacultyActivities(facultyID uint, includeAllFaculty bool) ([]models.Activity, error) {
-	query := as.DB.Preload("Faculty").Preload("Department").Preload("CreatedBy")
+	query := as.DB.Preload("Faculty").Preload("CoHostFaculties").Preload("Department").Preload("CreatedBy")
 
 	if includeAllFaculty {
 		// Include both faculty-specific and cross-faculty activities
-		query = query.Where("faculty_id = ? OR faculty_id IS NULL", facultyID)
+		query = query.Where("faculty_id = ? OR id IN ("+models.ActivityCoHostSubquery+") OR (faculty_id IS NULL AND NOT EXISTS (SELECT 1 FROM activity_faculties WHERE activity_faculties.activity_id = activities.id))", facultyID, facultyID)
 	} else {
-		query = query.Where("faculty_id = ?", facultyID)
+		query = query.Where("faculty_id = ? OR id IN ("+models.ActivityCoHostSubquery+")", facultyID, facultyID)
 	}
 
 	var activities []models.Activity
@@ -207,6 +265,30 @@ func (as *ActivityService) GetFacultyActivities(facultyID uint, includeAllFacult
 	return activities, nil
 }
 
+// GetUnstaffedActivities returns live or upcoming QR activities that no admin is assigned to scan for.
+// A nil facultyID covers all faculties; a non-nil startsBefore only includes activities starting before it.
+func (as *ActivityService) GetUnstaffedActivities(facultyID *uint, startsBefore *time.Time) ([]models.Activity, error) {
+	var activities []models.Activity
+	query := as.DB.Preload("Faculty").Preload("Department").
+		Where("status = ? AND qr_code_required = ? AND end_date > ?", models.ActivityStatusActive, true, time.Now()).
+		Where("NOT EXISTS (?)", as.DB.Model(&models.ActivityAssignment{}).
+			Select("1").
+			Where("activity_assignments.activity_id = activities.id AND activity_assignments.can_scan_qr = ?", true))
+
+	if facultyID != nil {
+		query = query.Where("faculty_id = ? OR id IN ("+models.ActivityCoHostSubquery+")", *facultyID, *facultyID)
+	}
+	if startsBefore != nil {
+		query = query.Where("start_date <= ?", *startsBefore)
+	}
+
+	if err := query.Order("start_date ASC").Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch unstaffed activities: %v", err)
+	}
+
+	return activities, nil
+}
+
 // Helper types and methods
 
 type ActivityInput struct {
@@ -214,6 +296,7 @@ type ActivityInput struct {
 	Description     string     `json:"description"`
 	StartDate       time.Time  `json:"start_date"`
 	EndDate         time.Time  `json:"end_date"`
+	Timezone        string     `json:"timezone"` // empty uses the database default
 	Location        string     `json:"location"`
 	MaxParticipants *int       `json:"max_participants"`
 	RequireApproval *bool      `json:"require_approval"`
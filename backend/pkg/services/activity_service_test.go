@@ -0,0 +1,155 @@
+package services
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+)
+
+// unstaffedCondition is the NOT EXISTS filter that drops activities with a scanner assigned
+var unstaffedCondition = regexp.MustCompile(`NOT EXISTS \(SELECT 1 FROM "activity_assignments" WHERE \(activity_assignments\.activity_id = activities\.id AND activity_assignments\.can_scan_qr = \$(\d+)\)`)
+
+func TestGetUnstaffedActivities(t *testing.T) {
+	// Activity 1 has a scanner; activity 2 only an assignment without scan rights; activity 3 none
+	canScan := map[int64]bool{1: true, 2: false}
+	db, fake := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		if !strings.HasPrefix(query, `SELECT * FROM "activities"`) {
+			return fakedb.Result{}
+		}
+		// Answer the NOT EXISTS filter the way Postgres would
+		match := unstaffedCondition.FindStringSubmatch(query)
+		if match == nil {
+			t.Fatalf("query does not exclude staffed activities: %s", query)
+		}
+		n, _ := strconv.Atoi(match[1])
+		wantScan, _ := args[n-1].Value.(bool)
+
+		result := fakedb.Result{Columns: []string{"id", "status", "qr_code_required"}}
+		for _, id := range []int64{1, 2, 3} {
+			if scanner, assigned := canScan[id]; assigned && scanner == wantScan {
+				continue
+			}
+			result.Rows = append(result.Rows, []driver.Value{id, string(models.ActivityStatusActive), true})
+		}
+		return result
+	})
+	service := &ActivityService{DB: db}
+
+	activities, err := service.GetUnstaffedActivities(nil, nil)
+	if err != nil {
+		t.Fatalf("GetUnstaffedActivities: %v", err)
+	}
+	var ids []uint
+	for _, activity := range activities {
+		ids = append(ids, activity.ID)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("unstaffed activities = %v, want [2 3]", ids)
+	}
+
+	// A faculty filter keeps co-hosted activities without loosening the other conditions
+	facultyID := uint(4)
+	startsBefore := time.Now().Add(24 * time.Hour)
+	if _, err := service.GetUnstaffedActivities(&facultyID, &startsBefore); err != nil {
+		t.Fatalf("GetUnstaffedActivities(faculty): %v", err)
+	}
+	statements := fakedb.Containing(fake.Statements(), `SELECT * FROM "activities"`)
+	last := statements[len(statements)-1]
+	for _, fragment := range []string{
+		"AND (faculty_id = $5 OR id IN (SELECT activity_id FROM activity_faculties WHERE faculty_id = $6))",
+		"AND start_date <= $7",
+	} {
+		if !strings.Contains(last, fragment) {
+			t.Errorf("query %s\nis missing %s", last, fragment)
+		}
+	}
+}
+
+func TestRecurringActivitiesAcrossZoneBoundary(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("loading zone: %v", err)
+	}
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatalf("loading zone: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		timezone  string
+		start     time.Time
+		rule      string
+		wantStart time.Time
+		wantTitle string
+	}{
+		{
+			// Daylight saving time ends on November 1, so the next Thursday is an hour later in UTC
+			name:      "weekly across the end of daylight saving time",
+			timezone:  "America/New_York",
+			start:     time.Date(2026, 10, 29, 9, 0, 0, 0, newYork),
+			rule:      "FREQ=WEEKLY;COUNT=1",
+			wantStart: time.Date(2026, 11, 5, 14, 0, 0, 0, time.UTC),
+			wantTitle: "Study group (2026-11-05)",
+		},
+		{
+			// 06:00 on Monday in Bangkok is still Sunday in UTC
+			name:      "BYDAY on a local weekday that is another day in UTC",
+			timezone:  "Asia/Bangkok",
+			start:     time.Date(2026, 10, 19, 6, 0, 0, 0, bangkok),
+			rule:      "FREQ=WEEKLY;COUNT=1;BYDAY=MO",
+			wantStart: time.Date(2026, 10, 25, 23, 0, 0, 0, time.UTC),
+			wantTitle: "Study group (2026-10-26)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var nextID int64
+			db, _ := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+				if strings.HasPrefix(query, `INSERT INTO "activities"`) {
+					nextID++
+					return fakedb.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{nextID}}}
+				}
+				return fakedb.Result{}
+			})
+			// The fixed dates may be past by now
+			service := &ActivityService{DB: db, DateRules: ActivityDateRules{StartGrace: time.Since(tt.start) + time.Hour}}
+
+			base := &models.Activity{Title: "Study group", Timezone: tt.timezone, StartDate: tt.start, EndDate: tt.start.Add(2 * time.Hour)}
+			activities, err := service.createRecurringActivities(base, tt.rule, 1)
+			if err != nil {
+				t.Fatalf("createRecurringActivities: %v", err)
+			}
+			if len(activities) != 2 {
+				t.Fatalf("created %d activities, want the series and one occurrence", len(activities))
+			}
+
+			// Every occurrence is stored in UTC, and keeps its local start time and duration
+			for _, activity := range activities {
+				if activity.StartDate.Location() != time.UTC || activity.EndDate.Location() != time.UTC {
+					t.Errorf("activity %d is stored as %v - %v, want UTC", activity.ID, activity.StartDate, activity.EndDate)
+				}
+			}
+			occurrence := activities[1]
+			if !occurrence.StartDate.Equal(tt.wantStart) {
+				t.Errorf("occurrence starts at %v, want %v", occurrence.StartDate, tt.wantStart)
+			}
+			if local := occurrence.StartDate.In(occurrence.TimeLocation()); local.Hour() != tt.start.Hour() || local.Weekday() != tt.start.Weekday() {
+				t.Errorf("occurrence starts at %v locally, want %s at %02d:00", local, tt.start.Weekday(), tt.start.Hour())
+			}
+			if d := occurrence.EndDate.Sub(occurrence.StartDate); d != 2*time.Hour {
+				t.Errorf("occurrence lasts %v, want 2h", d)
+			}
+			if occurrence.Title != tt.wantTitle {
+				t.Errorf("occurrence title = %q, want %q", occurrence.Title, tt.wantTitle)
+			}
+		})
+	}
+}
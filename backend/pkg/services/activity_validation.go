@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActivityDateRules bounds the schedule an activity may be created with
+type ActivityDateRules struct {
+	// StartGrace allows a start slightly in the past, e.g. an activity entered as it begins
+	StartGrace time.Duration
+	// MaxDuration caps end minus start (0 disables the cap)
+	MaxDuration time.Duration
+}
+
+var DefaultActivityDateRules = ActivityDateRules{
+	StartGrace:  time.Hour,
+	MaxDuration: 30 * 24 * time.Hour,
+}
+
+// ActivityDateError reports which input field failed validation
+type ActivityDateError struct {
+	Field   string
+	Message string
+}
+
+func (e *ActivityDateError) Error() string {
+	return e.Message
+}
+
+// Validate checks an activity schedule. checkStart is false when an existing activity keeps
+// its start date, so ongoing activities can still have their end date edited.
+func (rules ActivityDateRules) Validate(start, end time.Time, checkStart bool) error {
+	if checkStart && start.Before(time.Now().Add(-rules.StartGrace)) {
+		return &ActivityDateError{Field: "startDate", Message: "start date cannot be in the past"}
+	}
+	if !end.After(start) {
+		return &ActivityDateError{Field: "endDate", Message: "end date must be after start date"}
+	}
+	if rules.MaxDuration > 0 && end.Sub(start) > rules.MaxDuration {
+		return &ActivityDateError{Field: "endDate", Message: fmt.Sprintf("activity cannot last longer than %s", formatDays(rules.MaxDuration))}
+	}
+	return nil
+}
+
+func formatDays(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	if days > 1 {
+		return fmt.Sprintf("%d days", days)
+	}
+	return d.String()
+}
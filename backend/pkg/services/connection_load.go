@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+const (
+	// SyntheticUserIDBase keeps synthetic connections clear of real users' IDs and per-user limits
+	SyntheticUserIDBase uint = 1_000_000_000
+
+	MaxSyntheticConnections = 5000
+)
+
+// SyntheticLoad is a set of fake connections opened to load-test realtime delivery. Each one
+// belongs to its own fake student and reads its channel as fast as a healthy client would.
+type SyntheticLoad struct {
+	cm          *ConnectionManager
+	connections []*Connection
+	closeOnce   sync.Once
+}
+
+// OpenSyntheticConnections opens count fake connections subscribed to subscriptionTypes. When
+// the connection limit is reached part way, the connections opened so far are returned with
+// the error and must still be closed.
+func (cm *ConnectionManager) OpenSyntheticConnections(count int, subscriptionTypes []string) (*SyntheticLoad, error) {
+	if count <= 0 || count > MaxSyntheticConnections {
+		return nil, fmt.Errorf("synthetic connection count must be between 1 and %d", MaxSyntheticConnections)
+	}
+
+	load := &SyntheticLoad{cm: cm, connections: make([]*Connection, 0, count)}
+	for i := 0; i < count; i++ {
+		userID := SyntheticUserIDBase + uint(i)
+		user := &models.User{ID: userID, Role: models.UserRoleStudent, IsActive: true}
+
+		conn, err := cm.CreateConnection(userID, user, map[string]interface{}{"synthetic": true})
+		if err != nil {
+			return load, fmt.Errorf("opened %d of %d synthetic connections: %w", len(load.connections), count, err)
+		}
+		load.connections = append(load.connections, conn)
+
+		go func() {
+			for range conn.Channel {
+			}
+		}()
+
+		for _, subscriptionType := range subscriptionTypes {
+			if err := cm.Subscribe(conn.ID, subscriptionType, nil); err != nil {
+				return load, err
+			}
+		}
+	}
+
+	log.Printf("Opened %d synthetic connections subscribed to %v", len(load.connections), subscriptionTypes)
+	return load, nil
+}
+
+// Len returns the number of synthetic connections opened
+func (sl *SyntheticLoad) Len() int {
+	return len(sl.connections)
+}
+
+// Close closes every synthetic connection still open
+func (sl *SyntheticLoad) Close() {
+	sl.closeOnce.Do(func() {
+		for _, conn := range sl.connections {
+			sl.cm.CloseConnection(conn.ID)
+		}
+		log.Printf("Closed %d synthetic connections", len(sl.connections))
+	})
+}
@@ -2,25 +2,59 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
 )
 
+const (
+	DefaultConnectionIdleTimeout     = 10 * time.Minute // Cloud Run friendly timeout
+	DefaultConnectionCleanupInterval = 2 * time.Minute
+
+	// MaxConnectionsPerUser allows mobile + web + tablet
+	MaxConnectionsPerUser = 3
+
+	ConnectionEvictChannel = "connection_evict"
+
+	// MaxSlowConnectionsReported caps the slow connections listed in stats
+	MaxSlowConnectionsReported = 20
+)
+
+var ErrConnectionManagerDraining = errors.New("instance is shutting down, please reconnect")
+
 type ConnectionManager struct {
 	connections     map[string]*Connection
 	userConnections map[uint]map[string]*Connection // userID -> connectionID -> connection
 	mutex           sync.RWMutex
 	pubSub          *PubSubService
+	registry        *ConnectionRegistry // nil on single-instance deployments
+	buffers         monitoring.BufferConfig
+	droppedMessages atomic.Int64
 	cleanup         *time.Ticker
+
+	// Running totals for connection metrics; see SampleMetrics
+	openedConnections atomic.Int64
+	closedConnections atomic.Int64
+	closedLifetime    atomic.Int64 // nanoseconds
+	broadcastMessages atomic.Int64
+	metricsMutex      sync.Mutex
+	lastMetrics       connectionTotals
+	lastMetricsAt     time.Time
+
 	ctx             context.Context
 	cancel          context.CancelFunc
 	maxConnections  int
 	idleTimeout     time.Duration
 	instanceID      string
+	startedAt       time.Time
+	draining        bool
 }
 
 type Connection struct {
@@ -33,10 +67,29 @@ type Connection struct {
 	Context        context.Context        `json:"-"`
 	Cancel         context.CancelFunc     `json:"-"`
 	Channel        chan *SubscriptionPayload `json:"-"`
+	Buffer         *monitoring.BufferUsage `json:"-"`
 	Metadata       map[string]interface{} `json:"metadata"`
 	mutex          sync.RWMutex           `json:"-"`
 }
 
+// RemoteAddr returns the client address the connection was opened from, or "" when unknown
+func (c *Connection) RemoteAddr() string {
+	return c.metadataString("remote_addr")
+}
+
+// UserAgent returns the user agent of the client that opened the connection, or "" when unknown
+func (c *Connection) UserAgent() string {
+	return c.metadataString("user_agent")
+}
+
+func (c *Connection) metadataString(key string) string {
+	value, _ := c.Metadata[key].(string)
+	if value == "unknown" {
+		return ""
+	}
+	return value
+}
+
 type Subscription struct {
 	Type       string                 `json:"type"`
 	Filters    map[string]interface{} `json:"filters"`
@@ -50,55 +103,118 @@ type SubscriptionPayload struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Data      interface{}            `json:"data"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	// Stream is the pub/sub channel the payload came from and Sequence its position there;
+	// both are empty for messages generated locally, e.g. the welcome message
+	Stream    string                 `json:"stream,omitempty"`
+	Sequence  int64                  `json:"sequence,omitempty"`
 }
 
 type ConnectionStats struct {
 	TotalConnections    int                    `json:"total_connections"`
 	UserConnections     map[uint]int           `json:"user_connections"`
+	FacultyConnections  map[uint]int           `json:"faculty_connections"`
 	ActiveSubscriptions map[string]int         `json:"active_subscriptions"`
+	IdleConnections     int                    `json:"idle_connections"`
+	IdleTimeout         time.Duration          `json:"idle_timeout"`
+	Draining            bool                   `json:"draining"`
 	InstanceID          string                 `json:"instance_id"`
 	Uptime             time.Duration          `json:"uptime"`
 	MemoryUsage        int64                  `json:"memory_usage_bytes"`
+	Cluster            *ClusterConnectionStats `json:"cluster,omitempty"`
+
+	// Channel buffer usage. Drops count every message dropped since the instance started;
+	// slow connections are the open connections that dropped messages, worst first.
+	BufferSize          int                     `json:"buffer_size"`
+	BufferHighWatermark int                     `json:"buffer_high_watermark"`
+	BufferDrops         int64                   `json:"buffer_drops"`
+	SlowConnections     []ConnectionBufferStats `json:"slow_connections,omitempty"`
+}
+
+// ConnectionBufferStats is one connection's channel buffer usage
+type ConnectionBufferStats struct {
+	ConnectionID  string `json:"connection_id"`
+	UserID        uint   `json:"user_id"`
+	RemoteAddr    string `json:"remote_addr,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty"`
+	HighWatermark int    `json:"high_watermark"`
+	Drops         int64  `json:"drops"`
 }
 
-func NewConnectionManager(pubSub *PubSubService, instanceID string, maxConnections int) *ConnectionManager {
+// NewConnectionManager creates a connection manager; zero idleTimeout, cleanupInterval or buffer
+// settings use the defaults. With a registry, per-user limits and stats cover every instance.
+func NewConnectionManager(pubSub *PubSubService, registry *ConnectionRegistry, instanceID string, maxConnections int, idleTimeout, cleanupInterval time.Duration, buffers monitoring.BufferConfig) *ConnectionManager {
 	ctx, cancel := context.WithCancel(context.Background())
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultConnectionIdleTimeout
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = DefaultConnectionCleanupInterval
+	}
 	
 	cm := &ConnectionManager{
 		connections:     make(map[string]*Connection),
 		userConnections: make(map[uint]map[string]*Connection),
 		pubSub:          pubSub,
+		registry:        registry,
+		buffers:         buffers.WithDefaults(),
 		ctx:             ctx,
 		cancel:          cancel,
 		maxConnections:  maxConnections,
-		idleTimeout:     10 * time.Minute, // Cloud Run friendly timeout
+		idleTimeout:     idleTimeout,
 		instanceID:      instanceID,
+		startedAt:       time.Now(),
+		lastMetricsAt:   time.Now(),
 	}
 
 	// Start cleanup routine for idle connections
-	cm.cleanup = time.NewTicker(2 * time.Minute)
+	cm.cleanup = time.NewTicker(cleanupInterval)
 	go cm.startCleanupRoutine()
 
+	if registry != nil {
+		cm.heartbeat()
+		go cm.startRegistryRoutine()
+	}
+
 	// Subscribe to global events
 	cm.subscribeToGlobalEvents()
 
-	log.Printf("Connection manager initialized for instance %s (max connections: %d)", instanceID, maxConnections)
+	log.Printf("Connection manager initialized for instance %s (max connections: %d, idle timeout: %v, buffer: %d)", instanceID, maxConnections, idleTimeout, cm.buffers.Size)
 	return cm
 }
 
 // CreateConnection creates a new WebSocket connection
 func (cm *ConnectionManager) CreateConnection(userID uint, user *models.User, metadata map[string]interface{}) (*Connection, error) {
+	// The user's connections on other instances are looked up before taking the lock
+	var clusterConns []ConnectionEntry
+	clusterKnown := false
+	if cm.registry != nil {
+		entries, err := cm.registry.UserConnections(cm.ctx, userID)
+		if err != nil {
+			log.Printf("Falling back to the local connection limit for user %d: %v", userID, err)
+		} else {
+			clusterConns, clusterKnown = entries, true
+		}
+	}
+
 	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
+
+	// A draining instance sends new clients elsewhere
+	if cm.draining {
+		cm.mutex.Unlock()
+		return nil, ErrConnectionManagerDraining
+	}
 
 	// Check connection limits
 	if len(cm.connections) >= cm.maxConnections {
+		cm.mutex.Unlock()
 		return nil, fmt.Errorf("maximum connections reached (%d)", cm.maxConnections)
 	}
 
-	// Check per-user connection limit (max 3 per user for mobile + web + tablet)
-	if userConns := cm.userConnections[userID]; len(userConns) >= 3 {
-		// Close oldest connection
+	// Check per-user connection limit, closing the oldest connection wherever it lives
+	var evict *ConnectionEntry
+	if clusterKnown {
+		evict = cm.enforceClusterUserLimit(userID, clusterConns)
+	} else if userConns := cm.userConnections[userID]; len(userConns) >= MaxConnectionsPerUser {
 		cm.closeOldestUserConnection(userID)
 	}
 
@@ -114,7 +230,8 @@ func (cm *ConnectionManager) CreateConnection(userID uint, user *models.User, me
 		Subscriptions: make(map[string]*Subscription),
 		Context:       ctx,
 		Cancel:        cancel,
-		Channel:       make(chan *SubscriptionPayload, 100), // Buffer for messages
+		Channel:       make(chan *SubscriptionPayload, cm.buffers.Size),
+		Buffer:        monitoring.NewBufferUsage(cm.buffers.Size),
 		Metadata:      metadata,
 	}
 
@@ -128,8 +245,21 @@ func (cm *ConnectionManager) CreateConnection(userID uint, user *models.User, me
 
 	// Start connection handler
 	go cm.handleConnection(connection)
+	cm.mutex.Unlock()
+	cm.openedConnections.Add(1)
 
-	log.Printf("Created connection %s for user %d", connID, userID)
+	if cm.registry != nil {
+		cm.register(connection)
+	}
+	if evict != nil {
+		cm.evictRemoteConnection(evict)
+	}
+
+	if remoteAddr := connection.RemoteAddr(); remoteAddr != "" {
+		log.Printf("Created connection %s for user %d from %s (%s)", connID, userID, remoteAddr, connection.UserAgent())
+	} else {
+		log.Printf("Created connection %s for user %d", connID, userID)
+	}
 	return connection, nil
 }
 
@@ -146,6 +276,22 @@ func (cm *ConnectionManager) CloseConnection(connectionID string) {
 	cm.closeConnection(connection)
 }
 
+// CloseUserConnections closes every connection the user has on this instance and returns how many there were
+func (cm *ConnectionManager) CloseUserConnections(userID uint) int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	conns := cm.userConnections[userID]
+	closed := len(conns)
+	for _, conn := range conns {
+		cm.closeConnection(conn)
+	}
+	if closed > 0 {
+		log.Printf("Closed %d connections of user %d", closed, userID)
+	}
+	return closed
+}
+
 // Subscribe adds a subscription to a connection
 func (cm *ConnectionManager) Subscribe(connectionID, subscriptionType string, filters map[string]interface{}) error {
 	cm.mutex.RLock()
@@ -204,14 +350,7 @@ func (cm *ConnectionManager) BroadcastToUser(userID uint, payload *SubscriptionP
 	}
 
 	for _, connection := range userConns {
-		select {
-		case connection.Channel <- payload:
-			connection.mutex.Lock()
-			connection.LastActivity = time.Now()
-			connection.mutex.Unlock()
-		default:
-			log.Printf("Connection %s channel full, dropping message", connection.ID)
-		}
+		cm.deliver(connection, payload)
 	}
 }
 
@@ -227,41 +366,108 @@ func (cm *ConnectionManager) BroadcastToConnections(filter func(*Connection) boo
 	cm.mutex.RUnlock()
 
 	for _, connection := range connections {
-		select {
-		case connection.Channel <- payload:
-			connection.mutex.Lock()
-			connection.LastActivity = time.Now()
-			connection.mutex.Unlock()
-		default:
-			log.Printf("Connection %s channel full, dropping message", connection.ID)
+		cm.deliver(connection, payload)
+	}
+}
+
+// deliver queues payload without blocking, dropping it when the connection's buffer is full.
+// A connection that keeps dropping is reported once per streak as a slow consumer.
+func (cm *ConnectionManager) deliver(conn *Connection, payload *SubscriptionPayload) {
+	select {
+	case conn.Channel <- payload:
+		cm.broadcastMessages.Add(1)
+		conn.Buffer.Sent(len(conn.Channel))
+		conn.mutex.Lock()
+		conn.LastActivity = time.Now()
+		conn.mutex.Unlock()
+	default:
+		cm.droppedMessages.Add(1)
+		streak := conn.Buffer.Dropped()
+		log.Printf("Connection %s channel full, dropping message", conn.ID)
+		if streak == int64(cm.buffers.OverflowThreshold) && cm.buffers.OnOverflow != nil {
+			cm.buffers.OnOverflow(monitoring.BufferOverflow{
+				Kind:         "websocket",
+				ConnectionID: conn.ID,
+				UserID:       conn.UserID,
+				Usage:        conn.Buffer.Stats(),
+			})
 		}
 	}
 }
 
-// GetConnectionStats returns statistics about active connections
-func (cm *ConnectionManager) GetConnectionStats() *ConnectionStats {
+// GetConnectionStats returns statistics about this instance's connections, with totals across
+// every instance when includeCluster is set and a registry is configured
+func (cm *ConnectionManager) GetConnectionStats(includeCluster bool) *ConnectionStats {
+	var cluster *ClusterConnectionStats
+	if includeCluster && cm.registry != nil {
+		var err error
+		if cluster, err = cm.registry.ClusterStats(cm.ctx); err != nil {
+			log.Printf("Failed to load cluster connection stats: %v", err)
+		}
+	}
+
 	cm.mutex.RLock()
 	defer cm.mutex.RUnlock()
 
 	userCounts := make(map[uint]int)
+	facultyCounts := make(map[uint]int)
 	subscriptionCounts := make(map[string]int)
+	idleCount := 0
+	highWatermark := 0
+	var slow []ConnectionBufferStats
+	now := time.Now()
 
 	for _, conn := range cm.connections {
 		userCounts[conn.UserID]++
+		usage := conn.Buffer.Stats()
+		if usage.HighWatermark > highWatermark {
+			highWatermark = usage.HighWatermark
+		}
+		if usage.Drops > 0 {
+			slow = append(slow, ConnectionBufferStats{
+				ConnectionID:  conn.ID,
+				UserID:        conn.UserID,
+				RemoteAddr:    conn.RemoteAddr(),
+				UserAgent:     conn.UserAgent(),
+				HighWatermark: usage.HighWatermark,
+				Drops:         usage.Drops,
+			})
+		}
+		if conn.User != nil && conn.User.FacultyID != nil {
+			facultyCounts[*conn.User.FacultyID]++
+		}
 		
 		conn.mutex.RLock()
 		for subType := range conn.Subscriptions {
 			subscriptionCounts[subType]++
 		}
+		// Idle for more than half the timeout, i.e. on its way to being cleaned up
+		if now.Sub(conn.LastActivity) > cm.idleTimeout/2 {
+			idleCount++
+		}
 		conn.mutex.RUnlock()
 	}
 
+	sort.Slice(slow, func(i, j int) bool { return slow[i].Drops > slow[j].Drops })
+	if len(slow) > MaxSlowConnectionsReported {
+		slow = slow[:MaxSlowConnectionsReported]
+	}
+
 	return &ConnectionStats{
 		TotalConnections:    len(cm.connections),
 		UserConnections:     userCounts,
+		FacultyConnections:  facultyCounts,
 		ActiveSubscriptions: subscriptionCounts,
+		IdleConnections:     idleCount,
+		IdleTimeout:         cm.idleTimeout,
+		Draining:            cm.draining,
 		InstanceID:          cm.instanceID,
-		Uptime:             time.Since(time.Now()), // This should be instance start time
+		Uptime:             time.Since(cm.startedAt),
+		Cluster:            cluster,
+		BufferSize:          cm.buffers.Size,
+		BufferHighWatermark: highWatermark,
+		BufferDrops:         cm.droppedMessages.Load(),
+		SlowConnections:     slow,
 	}
 }
 
@@ -380,6 +586,13 @@ func (cm *ConnectionManager) closeConnection(conn *Connection) {
 
 	// Close channel
 	close(conn.Channel)
+
+	cm.closedConnections.Add(1)
+	cm.closedLifetime.Add(int64(time.Since(conn.ConnectedAt)))
+
+	if cm.registry != nil {
+		go cm.unregister(conn)
+	}
 }
 
 func (cm *ConnectionManager) cleanupConnection(connectionID string) {
@@ -404,6 +617,8 @@ func (cm *ConnectionManager) subscribeToGlobalEvents() {
 		GlobalActivityAssignments,
 		GlobalNewActivities,
 		HeartbeatChannel,
+		ConnectionStatsChannel,
+		ConnectionEvictChannel,
 	}
 
 	for _, pattern := range patterns {
@@ -416,6 +631,8 @@ func (cm *ConnectionManager) handlePubSubEvent(event *SubscriptionEvent) error {
 		Type:      event.Type,
 		Timestamp: event.Timestamp,
 		Data:      event.Data,
+		Stream:    event.Channel,
+		Sequence:  event.Sequence,
 		Metadata:  map[string]interface{}{
 			"source":    "pubsub",
 			"channel":   event.Channel,
@@ -425,6 +642,8 @@ func (cm *ConnectionManager) handlePubSubEvent(event *SubscriptionEvent) error {
 
 	// Apply event-specific routing logic
 	switch event.Type {
+	case "connection_evict":
+		cm.handleEvictEvent(event)
 	case "personal_notification":
 		if event.Metadata != nil && event.Metadata.UserID != nil {
 			cm.BroadcastToUser(*event.Metadata.UserID, payload)
@@ -447,18 +666,217 @@ func (cm *ConnectionManager) handlePubSubEvent(event *SubscriptionEvent) error {
 	return nil
 }
 
+// Cluster registry
+
+// enforceClusterUserLimit makes room for one more connection of userID. A local oldest
+// connection is closed here; a remote one is returned for the caller to evict once the
+// lock is released. Called with cm.mutex held.
+func (cm *ConnectionManager) enforceClusterUserLimit(userID uint, cluster []ConnectionEntry) *ConnectionEntry {
+	local := cm.userConnections[userID]
+
+	conns := make([]ConnectionEntry, 0, len(cluster)+len(local))
+	for _, entry := range cluster {
+		// Local entries may have closed since the lookup; local connections are added below
+		if entry.InstanceID != cm.instanceID {
+			conns = append(conns, entry)
+		}
+	}
+	for _, conn := range local {
+		conns = append(conns, ConnectionEntry{ID: conn.ID, InstanceID: cm.instanceID, UserID: userID, ConnectedAt: conn.ConnectedAt})
+	}
+	if len(conns) < MaxConnectionsPerUser {
+		return nil
+	}
+
+	oldest := conns[0]
+	for _, entry := range conns[1:] {
+		if entry.ConnectedAt.Before(oldest.ConnectedAt) {
+			oldest = entry
+		}
+	}
+
+	if oldest.InstanceID == cm.instanceID {
+		log.Printf("Closing oldest connection %s for user %d", oldest.ID, userID)
+		cm.closeConnection(local[oldest.ID])
+		return nil
+	}
+	return &oldest
+}
+
+// evictRemoteConnection asks the instance holding a connection to close it
+func (cm *ConnectionManager) evictRemoteConnection(entry *ConnectionEntry) {
+	log.Printf("Asking instance %s to close connection %s for user %d", entry.InstanceID, entry.ID, entry.UserID)
+	if err := cm.pubSub.Publish(ConnectionEvictChannel, &SubscriptionEvent{
+		Type:       "connection_evict",
+		InstanceID: cm.instanceID,
+		Data: map[string]interface{}{
+			"connection_id": entry.ID,
+			"instance_id":   entry.InstanceID,
+		},
+	}); err != nil {
+		log.Printf("Failed to evict connection %s: %v", entry.ID, err)
+	}
+}
+
+func (cm *ConnectionManager) handleEvictEvent(event *SubscriptionEvent) {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if instanceID, _ := data["instance_id"].(string); instanceID != cm.instanceID {
+		return
+	}
+	if connectionID, _ := data["connection_id"].(string); connectionID != "" {
+		log.Printf("Closing connection %s evicted by instance %s", connectionID, event.InstanceID)
+		cm.CloseConnection(connectionID)
+	}
+}
+
+func connectionEntry(conn *Connection) ConnectionEntry {
+	entry := ConnectionEntry{ID: conn.ID, UserID: conn.UserID, ConnectedAt: conn.ConnectedAt}
+	if conn.User != nil {
+		entry.FacultyID = conn.User.FacultyID
+	}
+	return entry
+}
+
+func (cm *ConnectionManager) register(conn *Connection) {
+	ctx, cancel := context.WithTimeout(cm.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := cm.registry.Register(ctx, connectionEntry(conn)); err != nil {
+		log.Printf("Failed to register connection %s: %v", conn.ID, err)
+		return
+	}
+
+	// The connection may have closed, and unregistered, before it was registered
+	cm.mutex.RLock()
+	_, open := cm.connections[conn.ID]
+	cm.mutex.RUnlock()
+	if !open {
+		cm.unregister(conn)
+	}
+}
+
+func (cm *ConnectionManager) unregister(conn *Connection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := cm.registry.Unregister(ctx, conn.ID, conn.UserID); err != nil {
+		log.Printf("Failed to unregister connection %s: %v", conn.ID, err)
+	}
+}
+
+// heartbeat keeps this instance alive in the registry, registering its connections again
+// if another instance reaped it, e.g. after a long GC pause or a Redis outage
+func (cm *ConnectionManager) heartbeat() {
+	ctx, cancel := context.WithTimeout(cm.ctx, 5*time.Second)
+	defer cancel()
+
+	revived, err := cm.registry.Heartbeat(ctx)
+	if err != nil {
+		log.Printf("Connection registry heartbeat failed: %v", err)
+		return
+	}
+	if !revived {
+		return
+	}
+
+	cm.mutex.RLock()
+	conns := make([]*Connection, 0, len(cm.connections))
+	for _, conn := range cm.connections {
+		conns = append(conns, conn)
+	}
+	cm.mutex.RUnlock()
+
+	for _, conn := range conns {
+		cm.register(conn)
+	}
+	if len(conns) > 0 {
+		log.Printf("Re-registered %d connections for instance %s", len(conns), cm.instanceID)
+	}
+}
+
+// startRegistryRoutine heartbeats and removes the connections of instances that stopped heartbeating
+func (cm *ConnectionManager) startRegistryRoutine() {
+	ticker := time.NewTicker(cm.registry.HeartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cm.heartbeat()
+
+			ctx, cancel := context.WithTimeout(cm.ctx, 10*time.Second)
+			reaped, err := cm.registry.ReapDeadInstances(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to reap dead instances: %v", err)
+			} else if reaped > 0 {
+				log.Printf("Removed connections of %d dead instances", reaped)
+			}
+		case <-cm.ctx.Done():
+			return
+		}
+	}
+}
+
+// Drain stops accepting connections and tells connected clients to reconnect elsewhere,
+// then waits up to gracePeriod for them to leave before closing whatever remains.
+// Cloud Run sends SIGTERM about 10 seconds before killing an instance.
+func (cm *ConnectionManager) Drain(gracePeriod time.Duration) {
+	cm.mutex.Lock()
+	cm.draining = true
+	count := len(cm.connections)
+	cm.mutex.Unlock()
+
+	log.Printf("Draining %d connections on instance %s", count, cm.instanceID)
+
+	cm.BroadcastToConnections(func(conn *Connection) bool { return true }, &SubscriptionPayload{
+		Type:      "connection_draining",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"instance_id": cm.instanceID,
+			"reconnect":   true,
+		},
+	})
+
+	deadline := time.Now().Add(gracePeriod)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		cm.mutex.RLock()
+		remaining := len(cm.connections)
+		cm.mutex.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		<-ticker.C
+	}
+
+	cm.Close()
+}
+
 // Close gracefully shuts down the connection manager
 func (cm *ConnectionManager) Close() error {
 	cm.cancel()
 	cm.cleanup.Stop()
 
 	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
-
 	// Close all connections
 	for _, conn := range cm.connections {
 		cm.closeConnection(conn)
 	}
+	cm.mutex.Unlock()
+
+	// Other instances would reap this one anyway, but only after the instance TTL
+	if cm.registry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := cm.registry.UnregisterInstance(ctx); err != nil {
+			log.Printf("Failed to remove instance %s from the connection registry: %v", cm.instanceID, err)
+		}
+	}
 
 	log.Printf("Connection manager closed for instance %s", cm.instanceID)
 	return nil
@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestConnectionClientMetadata(t *testing.T) {
+	tests := []struct {
+		name           string
+		metadata       map[string]interface{}
+		wantRemoteAddr string
+		wantUserAgent  string
+	}{
+		{"recorded", map[string]interface{}{"remote_addr": "198.51.100.7", "user_agent": "curl/8.5"}, "198.51.100.7", "curl/8.5"},
+		{"unknown", map[string]interface{}{"remote_addr": "unknown", "user_agent": "unknown"}, "", ""},
+		{"missing", map[string]interface{}{}, "", ""},
+		{"not a string", map[string]interface{}{"remote_addr": 42}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connection := &Connection{Metadata: tt.metadata}
+			if got := connection.RemoteAddr(); got != tt.wantRemoteAddr {
+				t.Errorf("RemoteAddr() = %q, want %q", got, tt.wantRemoteAddr)
+			}
+			if got := connection.UserAgent(); got != tt.wantUserAgent {
+				t.Errorf("UserAgent() = %q, want %q", got, tt.wantUserAgent)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+// connectionTotals are the connection manager's running totals at one point in time
+type connectionTotals struct {
+	opened         int64
+	closed         int64
+	closedLifetime int64
+	broadcasts     int64
+	drops          int64
+}
+
+func (cm *ConnectionManager) totals() connectionTotals {
+	return connectionTotals{
+		opened:         cm.openedConnections.Load(),
+		closed:         cm.closedConnections.Load(),
+		closedLifetime: cm.closedLifetime.Load(),
+		broadcasts:     cm.broadcastMessages.Load(),
+		drops:          cm.droppedMessages.Load(),
+	}
+}
+
+// SampleMetrics returns the connection activity since the previous sample
+func (cm *ConnectionManager) SampleMetrics() monitoring.ConnectionMetricsSample {
+	cm.mutex.RLock()
+	active := len(cm.connections)
+	cm.mutex.RUnlock()
+
+	cm.metricsMutex.Lock()
+	defer cm.metricsMutex.Unlock()
+
+	now := time.Now()
+	current := cm.totals()
+	sample := monitoring.ConnectionMetricsSample{
+		Interval:       now.Sub(cm.lastMetricsAt),
+		Active:         active,
+		Opened:         current.opened - cm.lastMetrics.opened,
+		Closed:         current.closed - cm.lastMetrics.closed,
+		Broadcasts:     current.broadcasts - cm.lastMetrics.broadcasts,
+		Drops:          current.drops - cm.lastMetrics.drops,
+		ClosedLifetime: time.Duration(current.closedLifetime - cm.lastMetrics.closedLifetime),
+	}
+	cm.lastMetrics = current
+	cm.lastMetricsAt = now
+	return sample
+}
+
+// StartMetricsExport records a connection sample as performance metrics every interval until ctx is done
+func (cm *ConnectionManager) StartMetricsExport(ctx context.Context, monitor *monitoring.PerformanceMonitor, interval time.Duration) {
+	if interval <= 0 {
+		interval = monitoring.DefaultConnectionSampleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := monitor.RecordConnectionMetrics(ctx, cm.instanceID, cm.SampleMetrics()); err != nil {
+				log.Printf("Failed to record connection metrics: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		case <-cm.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis Keys
+	ConnectionInstancesKey      = "ws_instances"         // sorted set of instance IDs scored by last heartbeat
+	ConnectionInstanceKeyPrefix = "ws_connections:"      // hash per instance: connection ID -> entry
+	UserConnectionsKeyPrefix    = "ws_user_connections:" // hash per user: connection ID -> entry
+
+	DefaultConnectionHeartbeatInterval = 15 * time.Second
+	DefaultConnectionInstanceTTL       = 60 * time.Second
+)
+
+// ConnectionEntry is a connection as recorded in the shared registry
+type ConnectionEntry struct {
+	ID          string    `json:"id"`
+	InstanceID  string    `json:"instance_id"`
+	UserID      uint      `json:"user_id"`
+	FacultyID   *uint     `json:"faculty_id,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// ClusterConnectionStats totals connections across every live instance
+type ClusterConnectionStats struct {
+	Instances          int          `json:"instances"`
+	TotalConnections   int          `json:"total_connections"`
+	FacultyConnections map[uint]int `json:"faculty_connections"`
+}
+
+// ConnectionRegistry records every instance's connections in Redis so connection limits and
+// stats can see the whole cluster. Instances heartbeat into a sorted set; the connections of an
+// instance that stops heartbeating for instanceTTL are removed by whichever instance notices first.
+type ConnectionRegistry struct {
+	redisClient       *redis.Client
+	instanceID        string
+	heartbeatInterval time.Duration
+	instanceTTL       time.Duration
+}
+
+// NewConnectionRegistry creates a registry; zero intervals use the defaults
+func NewConnectionRegistry(redisClient *redis.Client, instanceID string, heartbeatInterval, instanceTTL time.Duration) *ConnectionRegistry {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultConnectionHeartbeatInterval
+	}
+	if instanceTTL <= 0 {
+		instanceTTL = DefaultConnectionInstanceTTL
+	}
+	// A live instance must get several heartbeats in before it could be taken for dead
+	if instanceTTL < 2*heartbeatInterval {
+		instanceTTL = 2 * heartbeatInterval
+	}
+
+	return &ConnectionRegistry{
+		redisClient:       redisClient,
+		instanceID:        instanceID,
+		heartbeatInterval: heartbeatInterval,
+		instanceTTL:       instanceTTL,
+	}
+}
+
+// HeartbeatInterval is how often the owning instance should call Heartbeat
+func (cr *ConnectionRegistry) HeartbeatInterval() time.Duration {
+	return cr.heartbeatInterval
+}
+
+// Register records a connection of this instance
+func (cr *ConnectionRegistry) Register(ctx context.Context, entry ConnectionEntry) error {
+	entry.InstanceID = cr.instanceID
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection entry: %v", err)
+	}
+
+	pipe := cr.redisClient.TxPipeline()
+	pipe.HSet(ctx, cr.instanceKey(cr.instanceID), entry.ID, data)
+	pipe.HSet(ctx, cr.userKey(entry.UserID), entry.ID, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register connection %s: %v", entry.ID, err)
+	}
+	return nil
+}
+
+// Unregister removes a connection of this instance
+func (cr *ConnectionRegistry) Unregister(ctx context.Context, connectionID string, userID uint) error {
+	pipe := cr.redisClient.TxPipeline()
+	pipe.HDel(ctx, cr.instanceKey(cr.instanceID), connectionID)
+	pipe.HDel(ctx, cr.userKey(userID), connectionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to unregister connection %s: %v", connectionID, err)
+	}
+	return nil
+}
+
+// Heartbeat marks this instance alive. It reports true when the instance was not in the
+// registry, i.e. on first start or after being reaped as dead, so the caller should
+// register its connections again.
+func (cr *ConnectionRegistry) Heartbeat(ctx context.Context) (bool, error) {
+	added, err := cr.redisClient.ZAdd(ctx, rediskeys.Key(ConnectionInstancesKey), redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: cr.instanceID,
+	}).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record heartbeat: %v", err)
+	}
+	return added > 0, nil
+}
+
+// ReapDeadInstances removes the connections of every instance whose heartbeat is older than
+// the instance TTL. It returns how many instances were removed.
+func (cr *ConnectionRegistry) ReapDeadInstances(ctx context.Context) (int, error) {
+	deadline := time.Now().Add(-cr.instanceTTL).Unix()
+	instanceIDs, err := cr.redisClient.ZRangeByScore(ctx, rediskeys.Key(ConnectionInstancesKey), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(deadline, 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list dead instances: %v", err)
+	}
+
+	reaped := 0
+	for _, instanceID := range instanceIDs {
+		if instanceID == cr.instanceID {
+			continue
+		}
+		if err := cr.removeInstance(ctx, instanceID); err != nil {
+			return reaped, err
+		}
+		reaped++
+	}
+	return reaped, nil
+}
+
+// UnregisterInstance removes this instance and all of its connections, for shutdown
+func (cr *ConnectionRegistry) UnregisterInstance(ctx context.Context) error {
+	return cr.removeInstance(ctx, cr.instanceID)
+}
+
+// UserConnections returns the user's connections on live instances. Entries left behind by
+// instances that are no longer registered are removed on the way.
+func (cr *ConnectionRegistry) UserConnections(ctx context.Context, userID uint) ([]ConnectionEntry, error) {
+	key := cr.userKey(userID)
+	raw, err := cr.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load connections for user %d: %v", userID, err)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	entries := decodeConnectionEntries(raw)
+	instanceIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		instanceIDs = append(instanceIDs, entry.InstanceID)
+	}
+	heartbeats, err := cr.redisClient.ZMScore(ctx, rediskeys.Key(ConnectionInstancesKey), instanceIDs...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check instance heartbeats: %v", err)
+	}
+
+	deadline := float64(time.Now().Add(-cr.instanceTTL).Unix())
+	live := make([]ConnectionEntry, 0, len(entries))
+	var orphaned []string
+	for i, entry := range entries {
+		switch {
+		case heartbeats[i] == 0:
+			orphaned = append(orphaned, entry.ID)
+		case heartbeats[i] >= deadline:
+			live = append(live, entry)
+		}
+	}
+	if len(orphaned) > 0 {
+		cr.redisClient.HDel(ctx, key, orphaned...)
+	}
+	return live, nil
+}
+
+// ClusterStats totals connections over every live instance
+func (cr *ConnectionRegistry) ClusterStats(ctx context.Context) (*ClusterConnectionStats, error) {
+	deadline := time.Now().Add(-cr.instanceTTL).Unix()
+	instanceIDs, err := cr.redisClient.ZRangeByScore(ctx, rediskeys.Key(ConnectionInstancesKey), &redis.ZRangeBy{
+		Min: strconv.FormatInt(deadline, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list live instances: %v", err)
+	}
+
+	pipe := cr.redisClient.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(instanceIDs))
+	for i, instanceID := range instanceIDs {
+		cmds[i] = pipe.HGetAll(ctx, cr.instanceKey(instanceID))
+	}
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load instance connections: %v", err)
+		}
+	}
+
+	stats := &ClusterConnectionStats{
+		Instances:          len(instanceIDs),
+		FacultyConnections: make(map[uint]int),
+	}
+	for _, cmd := range cmds {
+		for _, entry := range decodeConnectionEntries(cmd.Val()) {
+			stats.TotalConnections++
+			if entry.FacultyID != nil {
+				stats.FacultyConnections[*entry.FacultyID]++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// removeInstance deletes an instance's connections from the user hashes, then the instance itself.
+// It is safe for several instances to reap the same dead instance at once.
+func (cr *ConnectionRegistry) removeInstance(ctx context.Context, instanceID string) error {
+	key := cr.instanceKey(instanceID)
+	raw, err := cr.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load connections of instance %s: %v", instanceID, err)
+	}
+
+	pipe := cr.redisClient.TxPipeline()
+	for _, entry := range decodeConnectionEntries(raw) {
+		pipe.HDel(ctx, cr.userKey(entry.UserID), entry.ID)
+	}
+	pipe.Del(ctx, key)
+	pipe.ZRem(ctx, rediskeys.Key(ConnectionInstancesKey), instanceID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove instance %s: %v", instanceID, err)
+	}
+	return nil
+}
+
+func (cr *ConnectionRegistry) instanceKey(instanceID string) string {
+	return rediskeys.Key(ConnectionInstanceKeyPrefix + instanceID)
+}
+
+func (cr *ConnectionRegistry) userKey(userID uint) string {
+	return rediskeys.Key(UserConnectionsKeyPrefix + strconv.FormatUint(uint64(userID), 10))
+}
+
+// decodeConnectionEntries skips entries that fail to decode rather than failing the lookup
+func decodeConnectionEntries(raw map[string]string) []ConnectionEntry {
+	entries := make([]ConnectionEntry, 0, len(raw))
+	for _, data := range raw {
+		var entry ConnectionEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
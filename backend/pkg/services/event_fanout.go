@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/monitoring"
+)
+
+const (
+	DefaultFanoutBatchSize           = 100
+	DefaultFanoutMaxRecipients       = 10000
+	DefaultFanoutBackgroundThreshold = 200
+	DefaultFanoutQueueSize           = 100
+)
+
+// FanoutConfig bounds how one event is published to many recipients, e.g. every admin of an
+// activity or every faculty. OnFanout, if set, is called in its own goroutine after each fan-out.
+type FanoutConfig struct {
+	// BatchSize is how many events are published per Redis round trip
+	BatchSize int
+	// MaxRecipients caps the recipients of one event; the rest are skipped and logged
+	MaxRecipients int
+	// Fan-outs to more recipients than BackgroundThreshold are published by the background
+	// worker, so the request that triggered them returns quickly
+	BackgroundThreshold int
+	// Fan-outs to more recipients than AlertThreshold are logged as warnings
+	AlertThreshold int
+	// QueueSize is how many fan-outs may wait for the worker; past it they are published inline
+	QueueSize int
+	OnFanout  func(monitoring.FanoutSample)
+}
+
+// WithDefaults fills in zero fields
+func (c FanoutConfig) WithDefaults() FanoutConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultFanoutBatchSize
+	}
+	if c.MaxRecipients <= 0 {
+		c.MaxRecipients = DefaultFanoutMaxRecipients
+	}
+	if c.BackgroundThreshold <= 0 {
+		c.BackgroundThreshold = DefaultFanoutBackgroundThreshold
+	}
+	if c.AlertThreshold <= 0 {
+		c.AlertThreshold = monitoring.DefaultFanoutAlertThreshold
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultFanoutQueueSize
+	}
+	return c
+}
+
+// fanout is one event's copies, one per recipient channel
+type fanout struct {
+	event      string
+	events     []ChannelEvent
+	recipients int
+}
+
+// fanOut publishes an event's copies in batches, inline or, for large fan-outs while the
+// worker runs, in the background
+func (ep *EventPublisher) fanOut(event string, events []ChannelEvent) {
+	f := &fanout{event: event, events: events, recipients: len(events)}
+	if f.recipients == 0 {
+		return
+	}
+
+	if f.recipients > ep.fanoutConfig.AlertThreshold {
+		log.Printf("Warning: event %s fans out to %d recipients, above the alert threshold of %d",
+			event, f.recipients, ep.fanoutConfig.AlertThreshold)
+	}
+	if f.recipients > ep.fanoutConfig.MaxRecipients {
+		log.Printf("Event %s fans out to %d recipients; only the first %d are notified",
+			event, f.recipients, ep.fanoutConfig.MaxRecipients)
+		f.events = f.events[:ep.fanoutConfig.MaxRecipients]
+	}
+
+	if f.recipients > ep.fanoutConfig.BackgroundThreshold && ep.fanoutWorker.Load() {
+		select {
+		case ep.fanoutQueue <- f:
+			return
+		default:
+			log.Printf("Fan-out queue is full, publishing event %s to %d recipients inline", event, len(f.events))
+		}
+	}
+	ep.publishFanout(f, false)
+}
+
+func (ep *EventPublisher) publishFanout(f *fanout, background bool) {
+	started := time.Now()
+	for start := 0; start < len(f.events); start += ep.fanoutConfig.BatchSize {
+		end := start + ep.fanoutConfig.BatchSize
+		if end > len(f.events) {
+			end = len(f.events)
+		}
+		if err := ep.PubSubService.PublishBatch(f.events[start:end]); err != nil {
+			log.Printf("Failed to publish event %s to recipients %d-%d: %v", f.event, start+1, end, err)
+		}
+	}
+
+	if ep.fanoutConfig.OnFanout != nil {
+		go ep.fanoutConfig.OnFanout(monitoring.FanoutSample{
+			Event:      f.event,
+			Recipients: f.recipients,
+			Published:  len(f.events),
+			Duration:   time.Since(started),
+			Background: background,
+		})
+	}
+}
+
+// StartFanoutWorker publishes the large fan-outs queued by fanOut until ctx is done. Without
+// it every fan-out is published inline.
+func (ep *EventPublisher) StartFanoutWorker(ctx context.Context) {
+	ep.fanoutWorker.Store(true)
+	defer ep.fanoutWorker.Store(false)
+
+	for {
+		select {
+		case f := <-ep.fanoutQueue:
+			ep.publishFanout(f, true)
+		case <-ctx.Done():
+			// Publish what was already queued rather than drop it
+			for {
+				select {
+				case f := <-ep.fanoutQueue:
+					ep.publishFanout(f, true)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
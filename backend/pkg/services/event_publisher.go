@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
@@ -14,6 +16,11 @@ type EventPublisher struct {
 	PubSubService    *PubSubService
 	ConnectionManager *ConnectionManager
 	instanceID       string
+
+	// Large fan-outs wait in fanoutQueue while StartFanoutWorker runs
+	fanoutConfig FanoutConfig
+	fanoutQueue  chan *fanout
+	fanoutWorker atomic.Bool
 }
 
 type EventContext struct {
@@ -24,12 +31,15 @@ type EventContext struct {
 	Source        string `json:"source,omitempty"`
 }
 
-func NewEventPublisher(db *gorm.DB, pubsub *PubSubService, cm *ConnectionManager, instanceID string) *EventPublisher {
+func NewEventPublisher(db *gorm.DB, pubsub *PubSubService, cm *ConnectionManager, instanceID string, fanoutConfig FanoutConfig) *EventPublisher {
+	fanoutConfig = fanoutConfig.WithDefaults()
 	return &EventPublisher{
 		DB:               db,
 		PubSubService:    pubsub,
 		ConnectionManager: cm,
 		instanceID:       instanceID,
+		fanoutConfig:     fanoutConfig,
+		fanoutQueue:      make(chan *fanout, fanoutConfig.QueueSize),
 	}
 }
 
@@ -38,15 +48,16 @@ func NewEventPublisher(db *gorm.DB, pubsub *PubSubService, cm *ConnectionManager
 func (ep *EventPublisher) PublishActivityCreated(activity *models.Activity, ctx *EventContext) error {
 	metadata := ep.createMetadata(ctx)
 	
-	// Publish to faculty members
-	if activity.FacultyID != nil {
-		if err := ep.PubSubService.PublishNewActivity(*activity.FacultyID, activity, metadata); err != nil {
-			log.Printf("Failed to publish new activity to faculty %d: %v", *activity.FacultyID, err)
+	// Publish to members of the hosting and co-hosting faculties
+	facultyIDs := ep.activityFacultyIDs(activity)
+	for _, facultyID := range facultyIDs {
+		if err := ep.PubSubService.PublishNewActivity(facultyID, activity, metadata); err != nil {
+			log.Printf("Failed to publish new activity to faculty %d: %v", facultyID, err)
 		}
 	}
 
 	// Publish to all students (cross-faculty activities)
-	if activity.FacultyID == nil {
+	if len(facultyIDs) == 0 {
 		if err := ep.publishSystemWideActivity(activity, "activity_created", metadata); err != nil {
 			log.Printf("Failed to publish system-wide activity: %v", err)
 		}
@@ -74,16 +85,44 @@ func (ep *EventPublisher) PublishActivityUpdated(activity *models.Activity, upda
 	}
 
 	// Notify faculty members if status changed
-	if updateType == "status_changed" && activity.FacultyID != nil {
-		if err := ep.PubSubService.PublishFacultyUpdate(*activity.FacultyID, map[string]interface{}{
-			"type":     "activity_status_changed",
+	if updateType == "status_changed" {
+		for _, facultyID := range ep.activityFacultyIDs(activity) {
+			if err := ep.PubSubService.PublishFacultyUpdate(facultyID, map[string]interface{}{
+				"type":     "activity_status_changed",
+				"activity": activity,
+			}, metadata); err != nil {
+				log.Printf("Failed to publish faculty update to faculty %d: %v", facultyID, err)
+			}
+		}
+	}
+
+	log.Printf("Published activity updated event for activity %d (type: %s)", activity.ID, updateType)
+	return nil
+}
+
+// PublishActivityArchived tells the activity's subscribers and its hosting faculties that it
+// left the default feeds
+func (ep *EventPublisher) PublishActivityArchived(activity *models.Activity, ctx *EventContext) error {
+	metadata := ep.createMetadata(ctx)
+	metadata.ActivityID = &activity.ID
+
+	if err := ep.PubSubService.PublishActivityUpdate(activity.ID, map[string]interface{}{
+		"activity":    activity,
+		"update_type": "archived",
+	}, metadata); err != nil {
+		return fmt.Errorf("failed to publish activity update: %v", err)
+	}
+
+	for _, facultyID := range ep.activityFacultyIDs(activity) {
+		if err := ep.PubSubService.PublishFacultyUpdate(facultyID, map[string]interface{}{
+			"type":     "activity_archived",
 			"activity": activity,
 		}, metadata); err != nil {
-			log.Printf("Failed to publish faculty update: %v", err)
+			log.Printf("Failed to publish faculty update to faculty %d: %v", facultyID, err)
 		}
 	}
 
-	log.Printf("Published activity updated event for activity %d (type: %s)", activity.ID, updateType)
+	log.Printf("Published activity archived event for activity %d", activity.ID)
 	return nil
 }
 
@@ -155,6 +194,38 @@ func (ep *EventPublisher) PublishParticipationUpdated(participation *models.Part
 	return nil
 }
 
+// PublishActivityRescheduled tells each affected participant the activity moved and asks them to confirm or withdraw
+func (ep *EventPublisher) PublishActivityRescheduled(activity *models.Activity, oldStart, oldEnd time.Time, userIDs []uint, ctx *EventContext) error {
+	metadata := ep.createMetadata(ctx)
+	metadata.ActivityID = &activity.ID
+
+	change := map[string]interface{}{
+		"activity_id":    activity.ID,
+		"old_start_date": oldStart,
+		"old_end_date":   oldEnd,
+		"new_start_date": activity.StartDate,
+		"new_end_date":   activity.EndDate,
+	}
+
+	notification := map[string]interface{}{
+		"type":    "activity_rescheduled",
+		"message": fmt.Sprintf("'%s' has been rescheduled, please confirm or withdraw your participation", activity.Title),
+		"change":  change,
+	}
+	ep.notifyUsers("activity_rescheduled", userIDs, notification, metadata)
+
+	if err := ep.PubSubService.PublishActivityUpdate(activity.ID, map[string]interface{}{
+		"activity":    activity,
+		"update_type": "rescheduled",
+		"change":      change,
+	}, metadata); err != nil {
+		return fmt.Errorf("failed to publish activity update: %v", err)
+	}
+
+	log.Printf("Published reschedule of activity %d to %d participants", activity.ID, len(userIDs))
+	return nil
+}
+
 // QR Scan Events
 
 func (ep *EventPublisher) PublishQRScanResult(scanResult *QRScanResult, activity *models.Activity, ctx *EventContext) error {
@@ -302,6 +373,7 @@ func (ep *EventPublisher) getParticipationMessage(updateType string, participati
 		"rejected":   fmt.Sprintf("Your participation in '%s' has been rejected", participation.Activity.Title),
 		"attended":   fmt.Sprintf("You have been marked as attended for: %s", participation.Activity.Title),
 		"qr_scanned": fmt.Sprintf("QR code scanned successfully for: %s", participation.Activity.Title),
+		"withdrawn":  fmt.Sprintf("You have withdrawn from: %s", participation.Activity.Title),
 	}
 
 	if message, exists := messages[updateType]; exists {
@@ -327,81 +399,98 @@ func (ep *EventPublisher) getSubscriptionWarningMessage(warningType string, subs
 
 func (ep *EventPublisher) notifyActivityAdmins(activity *models.Activity, data interface{}, metadata *SubscriptionMetadata) error {
 	// Find activity admins (super admins, faculty admins, and assigned regular admins)
-	var adminUsers []models.User
+	var adminIDs []uint
 
 	// Super admins
-	if err := ep.DB.Where("role = ?", models.UserRoleSuperAdmin).Find(&adminUsers).Error; err != nil {
+	if err := ep.DB.Model(&models.User{}).Where("role = ?", models.UserRoleSuperAdmin).Pluck("id", &adminIDs).Error; err != nil {
 		log.Printf("Failed to fetch super admins: %v", err)
 	}
 
-	// Faculty admins
-	if activity.FacultyID != nil {
-		var facultyAdmins []models.User
-		if err := ep.DB.Where("role = ? AND faculty_id = ?", models.UserRoleFacultyAdmin, *activity.FacultyID).Find(&facultyAdmins).Error; err != nil {
+	// Faculty admins of the hosting and co-hosting faculties
+	if facultyIDs := ep.activityFacultyIDs(activity); len(facultyIDs) > 0 {
+		var facultyAdminIDs []uint
+		if err := ep.DB.Model(&models.User{}).Where("role = ? AND faculty_id IN ?", models.UserRoleFacultyAdmin, facultyIDs).Pluck("id", &facultyAdminIDs).Error; err != nil {
 			log.Printf("Failed to fetch faculty admins: %v", err)
 		}
-		adminUsers = append(adminUsers, facultyAdmins...)
+		adminIDs = append(adminIDs, facultyAdminIDs...)
 	}
 
 	// Assigned regular admins
-	var assignments []models.ActivityAssignment
-	if err := ep.DB.Preload("Admin").Where("activity_id = ?", activity.ID).Find(&assignments).Error; err != nil {
+	var assignedIDs []uint
+	if err := ep.DB.Model(&models.ActivityAssignment{}).Where("activity_id = ?", activity.ID).Pluck("admin_id", &assignedIDs).Error; err != nil {
 		log.Printf("Failed to fetch activity assignments: %v", err)
 	}
-	for _, assignment := range assignments {
-		adminUsers = append(adminUsers, assignment.Admin)
-	}
-
-	// Send notifications
-	for _, admin := range adminUsers {
-		if err := ep.PubSubService.PublishPersonalNotification(admin.ID, data, metadata); err != nil {
-			log.Printf("Failed to notify admin %d: %v", admin.ID, err)
-		}
-	}
+	adminIDs = append(adminIDs, assignedIDs...)
 
+	ep.notifyUsers("activity_admins", adminIDs, data, metadata)
 	return nil
 }
 
 func (ep *EventPublisher) notifyFacultyAdmins(facultyID uint, data interface{}, metadata *SubscriptionMetadata) error {
-	var adminUsers []models.User
+	var adminIDs []uint
 
-	// Super admins
-	if err := ep.DB.Where("role = ?", models.UserRoleSuperAdmin).Find(&adminUsers).Error; err != nil {
-		log.Printf("Failed to fetch super admins: %v", err)
-	}
-
-	// Faculty admins
-	var facultyAdmins []models.User
-	if err := ep.DB.Where("role = ? AND faculty_id = ?", models.UserRoleFacultyAdmin, facultyID).Find(&facultyAdmins).Error; err != nil {
+	// Super admins and the faculty's admins
+	if err := ep.DB.Model(&models.User{}).
+		Where("role = ? OR (role = ? AND faculty_id = ?)", models.UserRoleSuperAdmin, models.UserRoleFacultyAdmin, facultyID).
+		Pluck("id", &adminIDs).Error; err != nil {
 		log.Printf("Failed to fetch faculty admins: %v", err)
 	}
-	adminUsers = append(adminUsers, facultyAdmins...)
 
-	// Send notifications
-	for _, admin := range adminUsers {
-		if err := ep.PubSubService.PublishPersonalNotification(admin.ID, data, metadata); err != nil {
-			log.Printf("Failed to notify faculty admin %d: %v", admin.ID, err)
+	ep.notifyUsers("faculty_admins", adminIDs, data, metadata)
+	return nil
+}
+
+// notifyUsers sends the same personal notification to each user once
+func (ep *EventPublisher) notifyUsers(event string, userIDs []uint, data interface{}, metadata *SubscriptionMetadata) {
+	seen := make(map[uint]bool, len(userIDs))
+	events := make([]ChannelEvent, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
 		}
+		seen[userID] = true
+		events = append(events, PersonalNotificationEvent(userID, data, metadata))
+	}
+	ep.fanOut(event, events)
+}
+
+// activityFacultyIDs returns the activity's own faculty followed by its co-hosts. An empty
+// result means the activity is open to every faculty.
+func (ep *EventPublisher) activityFacultyIDs(activity *models.Activity) []uint {
+	var coHostIDs []uint
+	if err := ep.DB.Table("activity_faculties").Where("activity_id = ?", activity.ID).Pluck("faculty_id", &coHostIDs).Error; err != nil {
+		log.Printf("Failed to fetch co-host faculties of activity %d: %v", activity.ID, err)
+		return activity.FacultyIDs()
 	}
 
-	return nil
+	facultyIDs := make([]uint, 0, len(coHostIDs)+1)
+	if activity.FacultyID != nil {
+		facultyIDs = append(facultyIDs, *activity.FacultyID)
+	}
+	for _, facultyID := range coHostIDs {
+		if activity.FacultyID == nil || facultyID != *activity.FacultyID {
+			facultyIDs = append(facultyIDs, facultyID)
+		}
+	}
+	return facultyIDs
 }
 
 func (ep *EventPublisher) publishSystemWideActivity(activity *models.Activity, eventType string, metadata *SubscriptionMetadata) error {
 	// Publish to all faculties for cross-faculty activities
-	var faculties []models.Faculty
-	if err := ep.DB.Where("is_active = true").Find(&faculties).Error; err != nil {
+	var facultyIDs []uint
+	if err := ep.DB.Model(&models.Faculty{}).Where("is_active = true").Pluck("id", &facultyIDs).Error; err != nil {
 		return fmt.Errorf("failed to fetch faculties: %v", err)
 	}
 
-	for _, faculty := range faculties {
-		if err := ep.PubSubService.PublishNewActivity(faculty.ID, map[string]interface{}{
-			"type":     eventType,
-			"activity": activity,
-		}, metadata); err != nil {
-			log.Printf("Failed to publish to faculty %d: %v", faculty.ID, err)
-		}
+	data := map[string]interface{}{
+		"type":     eventType,
+		"activity": activity,
 	}
+	events := make([]ChannelEvent, len(facultyIDs))
+	for i, facultyID := range facultyIDs {
+		events[i] = NewActivityEvent(facultyID, data, metadata)
+	}
+	ep.fanOut(eventType, events)
 
 	return nil
 }
@@ -429,14 +518,14 @@ func (ep *EventPublisher) PublishBulkActivityUpdates(activities []models.Activit
 // Connection status events
 
 func (ep *EventPublisher) PublishConnectionStats() error {
-	stats := ep.ConnectionManager.GetConnectionStats()
+	stats := ep.ConnectionManager.GetConnectionStats(true)
 	
 	metadata := &SubscriptionMetadata{
 		Source: fmt.Sprintf("instance_%s", ep.instanceID),
 	}
 
-	// Publish to system alerts channel for monitoring
-	if err := ep.PubSubService.Publish("connection_stats", &SubscriptionEvent{
+	// Publish to the stats channel for monitoring dashboards
+	if err := ep.PubSubService.Publish(ConnectionStatsChannel, &SubscriptionEvent{
 		Type:     "connection_stats",
 		Data:     stats,
 		Metadata: metadata,
@@ -445,4 +534,25 @@ func (ep *EventPublisher) PublishConnectionStats() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// StartConnectionStatsPublisher publishes this instance's connection stats every interval.
+// Idle instances with no connections stay quiet.
+func (ep *EventPublisher) StartConnectionStatsPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if ep.ConnectionManager.GetConnectionStats(false).TotalConnections == 0 {
+				continue
+			}
+			if err := ep.PublishConnectionStats(); err != nil {
+				log.Printf("Error publishing connection stats: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"gorm.io/gorm"
+)
+
+// FacultyComparisonMetric selects the value compared across faculties
+type FacultyComparisonMetric string
+
+const (
+	FacultyMetricStudents       FacultyComparisonMetric = "students"
+	FacultyMetricActivities     FacultyComparisonMetric = "activities"
+	FacultyMetricAttendanceRate FacultyComparisonMetric = "attendance_rate"
+	FacultyMetricPointsAwarded  FacultyComparisonMetric = "points_awarded"
+)
+
+// FacultyMetricValue is one faculty's value for a compared metric
+type FacultyMetricValue struct {
+	FacultyID uint    `json:"faculty_id"`
+	Value     float64 `json:"value"`
+}
+
+// FacultyComparisonService aggregates a single metric per faculty for institution-wide dashboards
+type FacultyComparisonService struct {
+	DB    *gorm.DB
+	cache *performance.CacheManager
+}
+
+func NewFacultyComparisonService(db *gorm.DB, cache *performance.CacheManager) *FacultyComparisonService {
+	return &FacultyComparisonService{
+		DB:    db,
+		cache: cache,
+	}
+}
+
+// Compare returns the metric for every faculty, highest first. The range bounds the
+// registration date for students and the activity start date for the other metrics.
+func (fs *FacultyComparisonService) Compare(ctx context.Context, metric FacultyComparisonMetric, from, to *time.Time) ([]FacultyMetricValue, error) {
+	if fs.cache == nil {
+		return fs.compute(metric, from, to)
+	}
+
+	var values []FacultyMetricValue
+	err := fs.cache.GetOrComputeMetrics(ctx, facultyComparisonCacheKey(metric, from, to), func() (interface{}, error) {
+		return fs.compute(metric, from, to)
+	}, &values)
+	return values, err
+}
+
+func (fs *FacultyComparisonService) compute(metric FacultyComparisonMetric, from, to *time.Time) ([]FacultyMetricValue, error) {
+	var query *gorm.DB
+
+	switch metric {
+	case FacultyMetricStudents:
+		query = fs.DB.Model(&models.User{}).
+			Select("users.faculty_id AS faculty_id, COUNT(*) AS value").
+			Where("users.role = ? AND users.faculty_id IS NOT NULL", models.UserRoleStudent).
+			Group("users.faculty_id")
+		query = applyDateRange(query, "users.created_at", from, to)
+	case FacultyMetricActivities:
+		query = fs.DB.Model(&models.Activity{}).
+			Select("activities.faculty_id AS faculty_id, COUNT(*) AS value").
+			Where("activities.faculty_id IS NOT NULL").
+			Group("activities.faculty_id")
+		query = applyDateRange(query, "activities.start_date", from, to)
+	case FacultyMetricAttendanceRate:
+		// Pending and rejected registrations never had a chance to attend
+		query = fs.DB.Model(&models.Participation{}).
+			Joins("JOIN activities ON activities.id = participations.activity_id AND activities.deleted_at IS NULL").
+			Select("activities.faculty_id AS faculty_id, "+
+				"SUM(CASE WHEN participations.status = ? THEN 1 ELSE 0 END)::float / COUNT(*) AS value",
+				models.ParticipationStatusAttended).
+			Where("activities.faculty_id IS NOT NULL AND participations.status IN ?", []models.ParticipationStatus{
+				models.ParticipationStatusApproved,
+				models.ParticipationStatusAttended,
+				models.ParticipationStatusAbsent,
+			}).
+			Group("activities.faculty_id")
+		query = applyDateRange(query, "activities.start_date", from, to)
+	case FacultyMetricPointsAwarded:
+		query = fs.DB.Model(&models.Participation{}).
+			Joins("JOIN activities ON activities.id = participations.activity_id AND activities.deleted_at IS NULL").
+			Select("activities.faculty_id AS faculty_id, COALESCE(SUM(activities.points), 0) AS value").
+			Where("activities.faculty_id IS NOT NULL AND participations.status = ?", models.ParticipationStatusAttended).
+			Group("activities.faculty_id")
+		query = applyDateRange(query, "activities.start_date", from, to)
+	default:
+		return nil, fmt.Errorf("unsupported faculty comparison metric: %s", metric)
+	}
+
+	var values []FacultyMetricValue
+	if err := query.Scan(&values).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate %s by faculty: %v", metric, err)
+	}
+
+	sortFacultyMetricValues(values)
+	return values, nil
+}
+
+// sortFacultyMetricValues orders values descending, breaking ties by faculty ID
+func sortFacultyMetricValues(values []FacultyMetricValue) {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Value != values[j].Value {
+			return values[i].Value > values[j].Value
+		}
+		return values[i].FacultyID < values[j].FacultyID
+	})
+}
+
+func applyDateRange(query *gorm.DB, column string, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		query = query.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(column+" <= ?", *to)
+	}
+	return query
+}
+
+func facultyComparisonCacheKey(metric FacultyComparisonMetric, from, to *time.Time) string {
+	key := "faculty_comparison:" + string(metric)
+	for _, bound := range []*time.Time{from, to} {
+		if bound == nil {
+			key += ":-"
+		} else {
+			key += fmt.Sprintf(":%d", bound.Unix())
+		}
+	}
+	return key
+}
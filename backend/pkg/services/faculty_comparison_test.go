@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/redis/go-redis/v9"
+)
+
+// perFaculty answers the aggregation with one row per faculty, in no particular order
+func perFaculty(rows ...[]driver.Value) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		if !strings.HasPrefix(query, "SELECT") {
+			return fakedb.Result{}
+		}
+		return fakedb.Result{Columns: []string{"faculty_id", "value"}, Rows: rows}
+	}
+}
+
+func TestFacultyComparisonAggregatesPerFaculty(t *testing.T) {
+	tests := []struct {
+		metric    FacultyComparisonMetric
+		rows      [][]driver.Value
+		fragments []string
+		args      []driver.Value
+		want      []FacultyMetricValue
+	}{
+		{
+			FacultyMetricStudents,
+			[][]driver.Value{{int64(1), int64(120)}, {int64(2), int64(340)}, {int64(3), int64(120)}},
+			[]string{`FROM "users"`, "users.faculty_id IS NOT NULL", `GROUP BY "users"."faculty_id"`},
+			[]driver.Value{string(models.UserRoleStudent)},
+			[]FacultyMetricValue{{2, 340}, {1, 120}, {3, 120}},
+		},
+		{
+			FacultyMetricActivities,
+			[][]driver.Value{{int64(1), int64(4)}, {int64(2), int64(9)}},
+			[]string{`FROM "activities"`, "activities.faculty_id IS NOT NULL", `GROUP BY "activities"."faculty_id"`},
+			nil,
+			[]FacultyMetricValue{{2, 9}, {1, 4}},
+		},
+		{
+			FacultyMetricAttendanceRate,
+			[][]driver.Value{{int64(1), 0.5}, {int64(2), 0.75}},
+			[]string{`FROM "participations"`, "JOIN activities", `GROUP BY "activities"."faculty_id"`, "::float / COUNT(*)"},
+			[]driver.Value{string(models.ParticipationStatusAttended), string(models.ParticipationStatusApproved), string(models.ParticipationStatusAbsent)},
+			[]FacultyMetricValue{{2, 0.75}, {1, 0.5}},
+		},
+		{
+			FacultyMetricPointsAwarded,
+			[][]driver.Value{{int64(3), 15.0}, {int64(1), 40.0}},
+			[]string{`FROM "participations"`, "SUM(activities.points)", `GROUP BY "activities"."faculty_id"`},
+			[]driver.Value{string(models.ParticipationStatusAttended)},
+			[]FacultyMetricValue{{1, 40}, {3, 15}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.metric), func(t *testing.T) {
+			var args []driver.NamedValue
+			respond := perFaculty(tt.rows...)
+			db, fake := fakedb.Open(t, func(query string, queryArgs []driver.NamedValue) fakedb.Result {
+				args = queryArgs
+				return respond(query, queryArgs)
+			})
+
+			got, err := NewFacultyComparisonService(db, nil).Compare(context.Background(), tt.metric, nil, nil)
+			if err != nil {
+				t.Fatalf("Compare: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+			if len(fakedb.Containing(fake.Statements(), tt.fragments...)) != 1 {
+				t.Errorf("aggregation %q is missing one of %q", fake.Statements(), tt.fragments)
+			}
+			for _, arg := range tt.args {
+				if !hasArg(args, arg) {
+					t.Errorf("aggregation args %v are missing %v", args, arg)
+				}
+			}
+		})
+	}
+
+	if _, err := NewFacultyComparisonService(nil, nil).Compare(context.Background(), "unknown", nil, nil); err == nil {
+		t.Error("Compare() of an unknown metric succeeded")
+	}
+}
+
+func TestFacultyComparisonCachesEachRange(t *testing.T) {
+	db, fake := fakedb.Open(t, perFaculty([]driver.Value{int64(1), int64(3)}, []driver.Value{int64(2), int64(5)}))
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	service := NewFacultyComparisonService(db, performance.NewCacheManager(client, db))
+	ctx := context.Background()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 6, 0)
+	for i := 0; i < 2; i++ {
+		got, err := service.Compare(ctx, FacultyMetricActivities, &from, &to)
+		if err != nil {
+			t.Fatalf("Compare: %v", err)
+		}
+		if want := []FacultyMetricValue{{2, 5}, {1, 3}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Compare() = %v, want %v", got, want)
+		}
+	}
+	if n := len(fakedb.Containing(fake.Statements(), "activities.start_date >= $", "activities.start_date <= $")); n != 1 {
+		t.Errorf("aggregated %d times for one range, want once", n)
+	}
+
+	// Another range is computed on its own
+	if _, err := service.Compare(ctx, FacultyMetricActivities, &from, nil); err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if n := len(fakedb.Containing(fake.Statements(), `FROM "activities"`)); n != 2 {
+		t.Errorf("aggregated %d times for two ranges, want 2", n)
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"strconv"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"gorm.io/gorm"
+)
+
+// featureFlagsCacheKey holds the whole flag table, which is small and read on nearly every request
+const featureFlagsCacheKey = "all"
+
+var (
+	ErrUnknownFeatureFlag       = errors.New("unknown feature flag")
+	ErrInvalidRolloutPercentage = errors.New("rollout percentage must be between 0 and 100")
+)
+
+// FeatureFlagService answers whether a feature is enabled for a faculty
+type FeatureFlagService struct {
+	DB    *gorm.DB
+	cache *performance.CacheManager
+}
+
+func NewFeatureFlagService(db *gorm.DB, cache *performance.CacheManager) *FeatureFlagService {
+	return &FeatureFlagService{
+		DB:    db,
+		cache: cache,
+	}
+}
+
+// IsFeatureEnabled reports whether flag is on for the faculty. A partial rollout counts as
+// off here since there is no user to place in it; use IsFeatureEnabledFor when there is one.
+func (fs *FeatureFlagService) IsFeatureEnabled(ctx context.Context, facultyID *uint, flag string) bool {
+	return fs.isEnabled(ctx, facultyID, flag, nil)
+}
+
+// IsFeatureEnabledFor is IsFeatureEnabled for a particular user, who is consistently in or
+// out of a partial rollout
+func (fs *FeatureFlagService) IsFeatureEnabledFor(ctx context.Context, facultyID *uint, flag string, userID uint) bool {
+	return fs.isEnabled(ctx, facultyID, flag, &userID)
+}
+
+func (fs *FeatureFlagService) isEnabled(ctx context.Context, facultyID *uint, flag string, userID *uint) bool {
+	flags, err := fs.all(ctx)
+	if err != nil {
+		log.Printf("Failed to load feature flags, treating %s as off: %v", flag, err)
+		return false
+	}
+
+	// The faculty's own flag wins over the global one
+	var match *models.FeatureFlag
+	for i := range flags {
+		f := &flags[i]
+		if f.Name != flag {
+			continue
+		}
+		if f.FacultyID == nil && match == nil {
+			match = f
+		} else if f.FacultyID != nil && facultyID != nil && *f.FacultyID == *facultyID {
+			match = f
+			break
+		}
+	}
+
+	if match == nil || !match.Enabled {
+		return false
+	}
+	if match.RolloutPercentage == nil || *match.RolloutPercentage >= 100 {
+		return true
+	}
+	if userID == nil {
+		return false
+	}
+	return rolloutBucket(flag, *userID) < *match.RolloutPercentage
+}
+
+// rolloutBucket places a user in 0-99 for a flag, independently of other flags
+func rolloutBucket(flag string, userID uint) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag + ":" + strconv.FormatUint(uint64(userID), 10)))
+	return int(h.Sum32() % 100)
+}
+
+func (fs *FeatureFlagService) all(ctx context.Context) ([]models.FeatureFlag, error) {
+	if fs.cache == nil {
+		return fs.load()
+	}
+
+	var flags []models.FeatureFlag
+	err := fs.cache.GetOrSet(ctx, featureFlagsCacheKey, performance.FeatureFlagCacheConfig, func() (interface{}, error) {
+		return fs.load()
+	}, &flags)
+	return flags, err
+}
+
+func (fs *FeatureFlagService) load() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	if err := fs.DB.Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// List returns the stored flags with their faculties. With a faculty, only that faculty's
+// flags and the global ones are returned.
+func (fs *FeatureFlagService) List(facultyID *uint) ([]models.FeatureFlag, error) {
+	query := fs.DB.Preload("Faculty").Order("name ASC, faculty_id ASC NULLS FIRST")
+	if facultyID != nil {
+		query = query.Where("faculty_id = ? OR faculty_id IS NULL", *facultyID)
+	}
+
+	var flags []models.FeatureFlag
+	if err := query.Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// Set creates or updates a flag, globally when facultyID is nil
+func (fs *FeatureFlagService) Set(ctx context.Context, facultyID *uint, name string, enabled bool, rolloutPercentage *int, updatedByID uint) (*models.FeatureFlag, error) {
+	if !models.IsKnownFeatureFlag(name) {
+		return nil, ErrUnknownFeatureFlag
+	}
+	if rolloutPercentage != nil && (*rolloutPercentage < 0 || *rolloutPercentage > 100) {
+		return nil, ErrInvalidRolloutPercentage
+	}
+
+	var flag models.FeatureFlag
+	err := fs.DB.Transaction(func(tx *gorm.DB) error {
+		err := scopeFeatureFlag(tx, facultyID, name).First(&flag).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		flag.FacultyID = facultyID
+		flag.Name = name
+		flag.Enabled = enabled
+		flag.RolloutPercentage = rolloutPercentage
+		flag.UpdatedByID = &updatedByID
+		return tx.Save(&flag).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fs.invalidate(ctx)
+	if err := fs.DB.Preload("Faculty").First(&flag, flag.ID).Error; err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// Clear removes a flag, so the faculty falls back to the global flag. It reports whether one existed.
+func (fs *FeatureFlagService) Clear(ctx context.Context, facultyID *uint, name string) (bool, error) {
+	result := scopeFeatureFlag(fs.DB, facultyID, name).Delete(&models.FeatureFlag{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	fs.invalidate(ctx)
+	return result.RowsAffected > 0, nil
+}
+
+func scopeFeatureFlag(db *gorm.DB, facultyID *uint, name string) *gorm.DB {
+	if facultyID == nil {
+		return db.Where("faculty_id IS NULL AND name = ?", name)
+	}
+	return db.Where("faculty_id = ? AND name = ?", *facultyID, name)
+}
+
+func (fs *FeatureFlagService) invalidate(ctx context.Context) {
+	if fs.cache == nil {
+		return
+	}
+	if err := fs.cache.Delete(ctx, featureFlagsCacheKey, performance.FeatureFlagCacheConfig); err != nil {
+		log.Printf("Failed to invalidate cached feature flags: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// Redis Keys
+	JoinLimitKeyPrefix = "join_limit:"
+
+	JoinLimitWindow = 24 * time.Hour
+)
+
+var (
+	ErrDailyJoinLimitReached  = errors.New("daily join limit reached")
+	ErrActiveJoinLimitReached = errors.New("active join limit reached")
+)
+
+// JoinLimiter caps how many activities a student may join per day and hold at once.
+// A limit of 0 disables that check.
+type JoinLimiter struct {
+	DB          *gorm.DB
+	redisClient *redis.Client
+	dailyLimit  int
+	activeLimit int
+}
+
+func NewJoinLimiter(db *gorm.DB, redisClient *redis.Client, dailyLimit, activeLimit int) *JoinLimiter {
+	return &JoinLimiter{
+		DB:          db,
+		redisClient: redisClient,
+		dailyLimit:  dailyLimit,
+		activeLimit: activeLimit,
+	}
+}
+
+// Reserve counts a join against the user's limits. The returned count is the user's
+// joins today including this one; callers should Release if the join does not go through.
+func (jl *JoinLimiter) Reserve(ctx context.Context, userID uint) (int64, error) {
+	if jl.activeLimit > 0 {
+		var active int64
+		if err := jl.DB.Model(&models.Participation{}).
+			Joins("JOIN activities ON activities.id = participations.activity_id").
+			Where("participations.user_id = ? AND participations.status IN ?", userID,
+				[]models.ParticipationStatus{models.ParticipationStatusPending, models.ParticipationStatusApproved}).
+			Where("activities.status = ? AND activities.end_date > ?", models.ActivityStatusActive, time.Now()).
+			Count(&active).Error; err != nil {
+			return 0, fmt.Errorf("failed to count active participations: %v", err)
+		}
+		if active >= int64(jl.activeLimit) {
+			return 0, ErrActiveJoinLimitReached
+		}
+	}
+
+	if jl.dailyLimit <= 0 {
+		return 0, nil
+	}
+
+	key := jl.dailyKey(userID)
+	pipe := jl.redisClient.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, JoinLimitWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to check join limit: %v", err)
+	}
+
+	count := incr.Val()
+	if count > int64(jl.dailyLimit) {
+		return count, ErrDailyJoinLimitReached
+	}
+	return count, nil
+}
+
+// Release returns a reserved daily slot after a join failed
+func (jl *JoinLimiter) Release(ctx context.Context, userID uint) {
+	if jl.dailyLimit <= 0 {
+		return
+	}
+	jl.redisClient.Decr(ctx, jl.dailyKey(userID))
+}
+
+// DailyLimit returns the number of joins allowed per day
+func (jl *JoinLimiter) DailyLimit() int {
+	return jl.dailyLimit
+}
+
+// ActiveLimit returns the number of active participations a user may hold
+func (jl *JoinLimiter) ActiveLimit() int {
+	return jl.activeLimit
+}
+
+func (jl *JoinLimiter) dailyKey(userID uint) string {
+	return rediskeys.Keyf("%s%d:%s", JoinLimitKeyPrefix, userID, time.Now().Format("2006-01-02"))
+}
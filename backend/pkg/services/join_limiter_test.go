@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestJoinLimiter(t *testing.T, active int64, dailyLimit, activeLimit int) (*JoinLimiter, *miniredis.Miniredis, *[][]driver.NamedValue) {
+	t.Helper()
+	counts := &[][]driver.NamedValue{}
+	db, _ := fakedb.Open(t, func(query string, args []driver.NamedValue) fakedb.Result {
+		if strings.HasPrefix(query, "SELECT count(*)") {
+			*counts = append(*counts, args)
+			return fakedb.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{active}}}
+		}
+		return fakedb.Result{}
+	})
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewJoinLimiter(db, client, dailyLimit, activeLimit), server, counts
+}
+
+func TestJoinLimiterDailyLimitBoundary(t *testing.T) {
+	limiter, server, _ := newTestJoinLimiter(t, 0, 3, 0)
+	ctx := context.Background()
+
+	// Joins up to the limit go through, the next one is refused
+	for want := int64(1); want <= 3; want++ {
+		count, err := limiter.Reserve(ctx, 7)
+		if err != nil || count != want {
+			t.Fatalf("join %d: Reserve() = %d, %v, want %d, nil", want, count, err, want)
+		}
+	}
+	count, err := limiter.Reserve(ctx, 7)
+	if !errors.Is(err, ErrDailyJoinLimitReached) || count != 4 {
+		t.Fatalf("join past the limit: Reserve() = %d, %v, want 4, ErrDailyJoinLimitReached", count, err)
+	}
+
+	// The refused join gives its slot back, so the student stays exactly at the limit
+	limiter.Release(ctx, 7)
+	if _, err := limiter.Reserve(ctx, 7); !errors.Is(err, ErrDailyJoinLimitReached) {
+		t.Errorf("Reserve() at the limit after a release = %v, want ErrDailyJoinLimitReached", err)
+	}
+	limiter.Release(ctx, 7)
+
+	// A join that failed for another reason frees its slot for the next one
+	limiter.Release(ctx, 7)
+	if count, err := limiter.Reserve(ctx, 7); err != nil || count != 3 {
+		t.Errorf("Reserve() after releasing a slot = %d, %v, want 3, nil", count, err)
+	}
+
+	// Other students have their own count, and the counter expires with the window
+	if _, err := limiter.Reserve(ctx, 8); err != nil {
+		t.Errorf("another student: Reserve() = %v", err)
+	}
+	if ttl := server.TTL(limiter.dailyKey(7)); ttl <= 0 || ttl > JoinLimitWindow {
+		t.Errorf("daily counter TTL = %s, want at most %s", ttl, JoinLimitWindow)
+	}
+}
+
+func TestJoinLimiterActiveLimitBoundary(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  int64
+		wantErr error
+	}{
+		{"below the limit", 4, nil},
+		{"at the limit", 5, ErrActiveJoinLimitReached},
+		{"past the limit", 6, ErrActiveJoinLimitReached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, server, counts := newTestJoinLimiter(t, tt.active, 10, 5)
+
+			_, err := limiter.Reserve(context.Background(), 7)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reserve() with %d active = %v, want %v", tt.active, err, tt.wantErr)
+			}
+			if len(*counts) != 1 {
+				t.Fatalf("counted active participations %d times, want once", len(*counts))
+			}
+			args := (*counts)[0]
+			if !hasArg(args, string(models.ParticipationStatusPending)) || !hasArg(args, string(models.ParticipationStatusApproved)) {
+				t.Errorf("active count args = %v, want pending and approved participations", args)
+			}
+
+			// A refused join does not use up a daily slot
+			used := server.Exists(limiter.dailyKey(7))
+			if used != (tt.wantErr == nil) {
+				t.Errorf("daily counter set = %v, want %v", used, tt.wantErr == nil)
+			}
+		})
+	}
+}
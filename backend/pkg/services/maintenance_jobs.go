@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"github.com/kruakemaths/tru-activity/backend/pkg/performance"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// Redis Keys
+	MaintenanceJobKeyPrefix = "maintenance_job:"
+
+	// MaintenanceJobRetention is how long a job's status stays queryable after it starts
+	MaintenanceJobRetention = 7 * 24 * time.Hour
+
+	SearchIndexLockKey = "search_index_rebuild"
+	SearchIndexLockTTL = time.Hour
+)
+
+// MaintenanceJobType names a maintenance job an admin can start
+type MaintenanceJobType string
+
+const (
+	MaintenanceJobRebuildSearchIndex MaintenanceJobType = "rebuild_search_index"
+	MaintenanceJobRecomputeMetrics   MaintenanceJobType = "recompute_metrics"
+)
+
+// MaintenanceJobStatus is the state of a maintenance job
+type MaintenanceJobStatus string
+
+const (
+	MaintenanceJobRunning   MaintenanceJobStatus = "running"
+	MaintenanceJobCompleted MaintenanceJobStatus = "completed"
+	MaintenanceJobFailed    MaintenanceJobStatus = "failed"
+)
+
+// ErrMaintenanceJobRunning is returned when a job of the same kind is already running on any instance
+var ErrMaintenanceJobRunning = errors.New("a job of this kind is already running")
+
+// searchIndexStatements rebuild the indexes created in migration 008_activity_search.sql.
+// CONCURRENTLY builds each index alongside the old one, so searches and writes carry on.
+var searchIndexStatements = []string{
+	"REINDEX INDEX CONCURRENTLY idx_activities_search_vector",
+	"REINDEX INDEX CONCURRENTLY idx_activities_search_trgm",
+	"ANALYZE activities",
+}
+
+// MaintenanceJob is the progress of a maintenance job. It is kept in Redis so any instance can report it.
+type MaintenanceJob struct {
+	ID          string               `json:"id"`
+	Type        MaintenanceJobType   `json:"type"`
+	Status      MaintenanceJobStatus `json:"status"`
+	Completed   int                  `json:"completed"`
+	Total       int                  `json:"total"`
+	Date        *time.Time           `json:"date,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	StartedByID uint                 `json:"started_by_id"`
+	StartedAt   time.Time            `json:"started_at"`
+	FinishedAt  *time.Time           `json:"finished_at,omitempty"`
+}
+
+// MaintenanceJobs runs the search index rebuild and metrics recomputation in the background.
+// Each kind of job runs under its own distributed lock, so at most one runs at a time across instances.
+type MaintenanceJobs struct {
+	DB          *gorm.DB
+	redisClient *redis.Client
+	lock        *lock.DistributedLock
+	snapshotter *MetricsSnapshotter
+	cache       *performance.CacheManager
+}
+
+func NewMaintenanceJobs(db *gorm.DB, redisClient *redis.Client, distributedLock *lock.DistributedLock, snapshotter *MetricsSnapshotter, cache *performance.CacheManager) *MaintenanceJobs {
+	return &MaintenanceJobs{
+		DB:          db,
+		redisClient: redisClient,
+		lock:        distributedLock,
+		snapshotter: snapshotter,
+		cache:       cache,
+	}
+}
+
+// StartSearchIndexRebuild rebuilds the activity search indexes, one index at a time
+func (mj *MaintenanceJobs) StartSearchIndexRebuild(ctx context.Context, startedByID uint) (*MaintenanceJob, error) {
+	job := &MaintenanceJob{
+		Type:        MaintenanceJobRebuildSearchIndex,
+		Total:       len(searchIndexStatements),
+		StartedByID: startedByID,
+	}
+	return mj.start(ctx, job, SearchIndexLockKey, SearchIndexLockTTL, func(ctx context.Context, job *MaintenanceJob) error {
+		for _, statement := range searchIndexStatements {
+			if err := mj.DB.WithContext(ctx).Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s: %v", statement, err)
+			}
+			job.Completed++
+			mj.save(ctx, job)
+		}
+		return nil
+	})
+}
+
+// StartMetricsRecompute recomputes the stored system and faculty metrics of date. It shares
+// the daily snapshot's lock, so it never overlaps the scheduled snapshot.
+func (mj *MaintenanceJobs) StartMetricsRecompute(ctx context.Context, date time.Time, startedByID uint) (*MaintenanceJob, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	if day.After(time.Now()) {
+		return nil, fmt.Errorf("metrics can't be recomputed for a future date")
+	}
+
+	job := &MaintenanceJob{
+		Type:        MaintenanceJobRecomputeMetrics,
+		Date:        &day,
+		StartedByID: startedByID,
+	}
+	return mj.start(ctx, job, MetricsSnapshotLockKey, MetricsSnapshotLockTTL, func(ctx context.Context, job *MaintenanceJob) error {
+		err := mj.snapshotter.snapshotDate(day, func(completed, total int) {
+			job.Completed = completed
+			job.Total = total
+			mj.save(ctx, job)
+		})
+		if err != nil {
+			return err
+		}
+
+		if mj.cache != nil {
+			if err := mj.cache.InvalidateByTag(ctx, "metrics"); err != nil {
+				log.Printf("Failed to invalidate cached metrics after recomputing %s: %v", day.Format("2006-01-02"), err)
+			}
+		}
+		return nil
+	})
+}
+
+// start takes the job's lock and runs it in the background, returning the job as started.
+// The lock is held until the job finishes.
+func (mj *MaintenanceJobs) start(ctx context.Context, job *MaintenanceJob, lockKey string, lockTTL time.Duration, run func(ctx context.Context, job *MaintenanceJob) error) (*MaintenanceJob, error) {
+	release, err := mj.lock.Acquire(ctx, lockKey, lockTTL)
+	if err == lock.ErrLockHeld {
+		return nil, ErrMaintenanceJobRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.ID = fmt.Sprintf("%s_%d", job.Type, time.Now().UnixNano())
+	job.Status = MaintenanceJobRunning
+	job.StartedAt = time.Now()
+	if err := mj.save(ctx, job); err != nil {
+		release()
+		return nil, err
+	}
+	started := *job
+
+	go func() {
+		defer release()
+
+		// The job outlives the request that started it
+		runCtx, cancel := context.WithTimeout(context.Background(), lockTTL)
+		defer cancel()
+
+		err := run(runCtx, job)
+		finished := time.Now()
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status = MaintenanceJobFailed
+			job.Error = err.Error()
+			log.Printf("Maintenance job %s failed: %v", job.ID, err)
+		} else {
+			job.Status = MaintenanceJobCompleted
+			log.Printf("Maintenance job %s completed in %v", job.ID, finished.Sub(job.StartedAt))
+		}
+		mj.save(context.Background(), job)
+	}()
+
+	return &started, nil
+}
+
+func (mj *MaintenanceJobs) save(ctx context.Context, job *MaintenanceJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance job: %v", err)
+	}
+	if err := mj.redisClient.Set(ctx, rediskeys.Key(MaintenanceJobKeyPrefix+job.ID), data, MaintenanceJobRetention).Err(); err != nil {
+		log.Printf("Failed to save maintenance job %s: %v", job.ID, err)
+		return fmt.Errorf("failed to save maintenance job: %v", err)
+	}
+	return nil
+}
+
+// Get returns a job started in the last MaintenanceJobRetention, or nil when there is none
+func (mj *MaintenanceJobs) Get(ctx context.Context, id string) (*MaintenanceJob, error) {
+	data, err := mj.redisClient.Get(ctx, rediskeys.Key(MaintenanceJobKeyPrefix+id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load maintenance job: %v", err)
+	}
+
+	var job MaintenanceJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance job: %v", err)
+	}
+	return &job, nil
+}
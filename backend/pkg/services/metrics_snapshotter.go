@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MetricsSnapshotLockKey      = "metrics_snapshotter"
+	MetricsSnapshotLockTTL      = 10 * time.Minute
+	MetricsSnapshotScanInterval = 1 * time.Hour
+)
+
+// MetricsSnapshotter stores one SystemMetrics row per day and one FacultyMetrics row per faculty per day
+type MetricsSnapshotter struct {
+	DB   *gorm.DB
+	lock *lock.DistributedLock
+}
+
+func NewMetricsSnapshotter(db *gorm.DB, distributedLock *lock.DistributedLock) *MetricsSnapshotter {
+	return &MetricsSnapshotter{
+		DB:   db,
+		lock: distributedLock,
+	}
+}
+
+// SnapshotDate aggregates counts as of the end of the given day and upserts that day's rows,
+// so running it again for the same date overwrites instead of duplicating. Counts of records
+// are limited to those created by the end of the day; statuses reflect the time of the run.
+func (ms *MetricsSnapshotter) SnapshotDate(date time.Time) error {
+	return ms.snapshotDate(date, nil)
+}
+
+// snapshotDate is SnapshotDate reporting progress after the system row and after each faculty.
+// Each row is upserted on its own, so a long run never holds a transaction open.
+func (ms *MetricsSnapshotter) snapshotDate(date time.Time, progress func(completed, total int)) error {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dayEnd := day.AddDate(0, 0, 1)
+
+	system, err := ms.systemMetrics(day, dayEnd)
+	if err != nil {
+		return err
+	}
+
+	if err := ms.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"total_faculties", "total_departments", "total_students", "total_activities",
+			"total_participations", "active_subscriptions", "expired_subscriptions", "updated_at", "deleted_at",
+		}),
+	}).Create(system).Error; err != nil {
+		return fmt.Errorf("failed to store system metrics: %v", err)
+	}
+
+	var faculties []models.Faculty
+	if err := ms.DB.Where("created_at < ?", dayEnd).Find(&faculties).Error; err != nil {
+		return fmt.Errorf("failed to load faculties: %v", err)
+	}
+
+	total := len(faculties) + 1
+	if progress != nil {
+		progress(1, total)
+	}
+
+	for i, faculty := range faculties {
+		metrics, err := ms.facultyMetrics(faculty.ID, day, dayEnd)
+		if err != nil {
+			return err
+		}
+
+		if err := ms.DB.Omit("Faculty").Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "faculty_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"total_students", "active_students", "total_activities", "completed_activities",
+				"total_participants", "average_attendance", "updated_at",
+			}),
+		}).Create(metrics).Error; err != nil {
+			return fmt.Errorf("failed to store metrics for faculty %d: %v", faculty.ID, err)
+		}
+		if progress != nil {
+			progress(i+2, total)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MetricsSnapshotter) systemMetrics(day, dayEnd time.Time) (*models.SystemMetrics, error) {
+	var faculties, departments, students, activities, participations, activeSubs, expiredSubs int64
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&faculties, ms.DB.Model(&models.Faculty{}).Where("created_at < ?", dayEnd)},
+		{&departments, ms.DB.Model(&models.Department{}).Where("created_at < ?", dayEnd)},
+		{&students, ms.DB.Model(&models.User{}).Where("role = ? AND created_at < ?", models.UserRoleStudent, dayEnd)},
+		{&activities, ms.DB.Model(&models.Activity{}).Where("created_at < ?", dayEnd)},
+		{&participations, ms.DB.Model(&models.Participation{}).Where("created_at < ?", dayEnd)},
+		{&activeSubs, ms.DB.Model(&models.Subscription{}).
+			Where("status = ? AND start_date < ? AND end_date >= ?", models.SubscriptionStatusActive, dayEnd, dayEnd)},
+		{&expiredSubs, ms.DB.Model(&models.Subscription{}).
+			Where("status = ? OR (status = ? AND end_date < ?)", models.SubscriptionStatusExpired, models.SubscriptionStatusActive, dayEnd)},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to aggregate system metrics: %v", err)
+		}
+	}
+
+	return &models.SystemMetrics{
+		TotalFaculties:       int(faculties),
+		TotalDepartments:     int(departments),
+		TotalStudents:        int(students),
+		TotalActivities:      int(activities),
+		TotalParticipations:  int(participations),
+		ActiveSubscriptions:  int(activeSubs),
+		ExpiredSubscriptions: int(expiredSubs),
+		Date:                 day,
+	}, nil
+}
+
+func (ms *MetricsSnapshotter) facultyMetrics(facultyID uint, day, dayEnd time.Time) (*models.FacultyMetrics, error) {
+	var students, activeStudents, activities, completed, participants int64
+
+	facultyParticipations := func() *gorm.DB {
+		return ms.DB.Model(&models.Participation{}).
+			Joins("JOIN activities ON activities.id = participations.activity_id AND activities.deleted_at IS NULL").
+			Where("activities.faculty_id = ? AND participations.created_at < ?", facultyID, dayEnd)
+	}
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&students, ms.DB.Model(&models.User{}).
+			Where("role = ? AND faculty_id = ? AND created_at < ?", models.UserRoleStudent, facultyID, dayEnd)},
+		{&activeStudents, ms.DB.Model(&models.User{}).
+			Where("role = ? AND faculty_id = ? AND is_active = ? AND created_at < ?", models.UserRoleStudent, facultyID, true, dayEnd)},
+		{&activities, ms.DB.Model(&models.Activity{}).Where("faculty_id = ? AND created_at < ?", facultyID, dayEnd)},
+		{&completed, ms.DB.Model(&models.Activity{}).
+			Where("faculty_id = ? AND created_at < ? AND status IN ?", facultyID, dayEnd,
+				[]models.ActivityStatus{models.ActivityStatusCompleted, models.ActivityStatusArchived})},
+		{&participants, facultyParticipations().
+			Where("participations.status IN ?", []models.ParticipationStatus{
+				models.ParticipationStatusApproved,
+				models.ParticipationStatusAttended,
+				models.ParticipationStatusAbsent,
+			}).
+			Distinct("participations.user_id")},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to aggregate metrics for faculty %d: %v", facultyID, err)
+		}
+	}
+
+	// Attendance only counts activities that had ended by the snapshot date
+	var attendance struct {
+		Attended int64
+		Expected int64
+	}
+	if err := facultyParticipations().
+		Select("COALESCE(SUM(CASE WHEN participations.status = ? THEN 1 ELSE 0 END), 0) AS attended, COUNT(*) AS expected",
+			models.ParticipationStatusAttended).
+		Where("activities.end_date < ? AND participations.status IN ?", dayEnd, []models.ParticipationStatus{
+			models.ParticipationStatusApproved,
+			models.ParticipationStatusAttended,
+			models.ParticipationStatusAbsent,
+		}).
+		Scan(&attendance).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate attendance for faculty %d: %v", facultyID, err)
+	}
+
+	averageAttendance := 0.0
+	if attendance.Expected > 0 {
+		averageAttendance = float64(attendance.Attended) / float64(attendance.Expected) * 100
+	}
+
+	return &models.FacultyMetrics{
+		FacultyID:           facultyID,
+		TotalStudents:       int(students),
+		ActiveStudents:      int(activeStudents),
+		TotalActivities:     int(activities),
+		CompletedActivities: int(completed),
+		TotalParticipants:   int(participants),
+		AverageAttendance:   averageAttendance,
+		Date:                day,
+	}, nil
+}
+
+// snapshotPending stores the previous day's snapshot once that day has ended
+func (ms *MetricsSnapshotter) snapshotPending() (bool, error) {
+	yesterday := time.Now().AddDate(0, 0, -1)
+	date := yesterday.Format("2006-01-02")
+
+	var existing int64
+	if err := ms.DB.Model(&models.SystemMetrics{}).Where("date = ?", date).Count(&existing).Error; err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	return true, ms.SnapshotDate(yesterday)
+}
+
+// runOnce snapshots under the distributed lock so only one instance does the work
+func (ms *MetricsSnapshotter) runOnce() {
+	err := ms.lock.WithLock(context.Background(), MetricsSnapshotLockKey, MetricsSnapshotLockTTL, func() error {
+		stored, err := ms.snapshotPending()
+		if err != nil {
+			return err
+		}
+		if stored {
+			log.Printf("Stored daily metrics snapshot")
+		}
+		return nil
+	})
+
+	if err == lock.ErrLockHeld {
+		return
+	}
+	if err != nil {
+		log.Printf("Error storing metrics snapshot: %v", err)
+	}
+}
+
+// StartSnapshotScheduler starts a background loop that stores each day's metrics once the day is over
+func (ms *MetricsSnapshotter) StartSnapshotScheduler(ctx context.Context) {
+	ticker := time.NewTicker(MetricsSnapshotScanInterval)
+	defer ticker.Stop()
+
+	// Run once immediately
+	ms.runOnce()
+
+	for {
+		select {
+		case <-ticker.C:
+			ms.runOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ListSystemMetrics returns stored system snapshots in date order
+func (ms *MetricsSnapshotter) ListSystemMetrics(from, to *time.Time) ([]models.SystemMetrics, error) {
+	var metrics []models.SystemMetrics
+	if err := applyDateRange(ms.DB.Model(&models.SystemMetrics{}), "date", from, to).
+		Order("date ASC").
+		Find(&metrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to load system metrics: %v", err)
+	}
+	return metrics, nil
+}
+
+// ListFacultyMetrics returns stored faculty snapshots in date order; a nil facultyID returns every faculty
+func (ms *MetricsSnapshotter) ListFacultyMetrics(facultyID *uint, from, to *time.Time) ([]models.FacultyMetrics, error) {
+	query := ms.DB.Model(&models.FacultyMetrics{}).Preload("Faculty")
+	if facultyID != nil {
+		query = query.Where("faculty_id = ?", *facultyID)
+	}
+
+	var metrics []models.FacultyMetrics
+	if err := applyDateRange(query, "date", from, to).
+		Order("date ASC, faculty_id ASC").
+		Find(&metrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to load faculty metrics: %v", err)
+	}
+	return metrics, nil
+}
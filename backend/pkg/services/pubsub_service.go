@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/rediskeys"
 )
 
 type PubSubService struct {
@@ -28,6 +29,9 @@ type SubscriptionEvent struct {
 	Metadata    *SubscriptionMetadata  `json:"metadata,omitempty"`
 	Filters     map[string]interface{} `json:"filters,omitempty"`
 	InstanceID  string                 `json:"instance_id"`
+	// Sequence orders the events of one channel, i.e. of one entity, across every instance.
+	// Pub/sub fan-in may deliver them out of order; subscribers reorder and dedupe by it.
+	Sequence    int64                  `json:"sequence"`
 }
 
 type SubscriptionMetadata struct {
@@ -52,6 +56,11 @@ const (
 	ActivityAssignmentsChannel      = "activity_assignments:%d"       // user_id
 	NewActivitiesChannel            = "new_activities:%d"             // faculty_id
 	HeartbeatChannel                = "heartbeat"
+	ConnectionStatsChannel          = "connection_stats"
+
+	// Per-channel event sequence counters; idle counters expire and restart from 1
+	EventSequenceKey = "realtime_seq:"
+	EventSequenceTTL = 7 * 24 * time.Hour
 	
 	// Global channels
 	GlobalPersonalNotifications    = "personal_notifications:*"
@@ -99,12 +108,18 @@ func (ps *PubSubService) Publish(channel string, event *SubscriptionEvent) error
 	event.Timestamp = time.Now()
 	event.Channel = channel
 
+	sequence, err := ps.nextSequence(channel)
+	if err != nil {
+		return fmt.Errorf("failed to sequence event for channel %s: %v", channel, err)
+	}
+	event.Sequence = sequence
+
 	data, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %v", err)
 	}
 
-	if err := ps.client.Publish(ps.ctx, channel, data).Err(); err != nil {
+	if err := ps.client.Publish(ps.ctx, rediskeys.Key(channel), data).Err(); err != nil {
 		return fmt.Errorf("failed to publish to channel %s: %v", channel, err)
 	}
 
@@ -112,6 +127,68 @@ func (ps *PubSubService) Publish(channel string, event *SubscriptionEvent) error
 	return nil
 }
 
+// ChannelEvent is an event bound for one channel, for PublishBatch
+type ChannelEvent struct {
+	Channel string
+	Event   *SubscriptionEvent
+}
+
+// PublishBatch publishes events in two round trips whatever their number: one sequencing
+// every channel, one publishing
+func (ps *PubSubService) PublishBatch(events []ChannelEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	sequences := make([]*redis.IntCmd, len(events))
+	_, err := ps.client.TxPipelined(ps.ctx, func(pipe redis.Pipeliner) error {
+		for i, e := range events {
+			key := rediskeys.Key(EventSequenceKey + e.Channel)
+			sequences[i] = pipe.Incr(ps.ctx, key)
+			pipe.Expire(ps.ctx, key, EventSequenceTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sequence %d events: %v", len(events), err)
+	}
+
+	now := time.Now()
+	pipe := ps.client.Pipeline()
+	for i, e := range events {
+		e.Event.Timestamp = now
+		e.Event.Channel = e.Channel
+		e.Event.Sequence = sequences[i].Val()
+
+		data, err := json.Marshal(e.Event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %v", err)
+		}
+		pipe.Publish(ps.ctx, rediskeys.Key(e.Channel), data)
+	}
+	if _, err := pipe.Exec(ps.ctx); err != nil {
+		return fmt.Errorf("failed to publish %d events: %v", len(events), err)
+	}
+	return nil
+}
+
+// nextSequence assigns the channel's next sequence number; the counter lives in Redis so
+// events published from different instances share one order
+func (ps *PubSubService) nextSequence(channel string) (int64, error) {
+	key := rediskeys.Key(EventSequenceKey + channel)
+
+	var incr *redis.IntCmd
+	_, err := ps.client.TxPipelined(ps.ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ps.ctx, key)
+		pipe.Expire(ps.ctx, key, EventSequenceTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
 // Subscribe subscribes to a channel pattern and handles messages
 func (ps *PubSubService) Subscribe(pattern string, handler EventHandler) error {
 	ps.mutex.Lock()
@@ -121,7 +198,7 @@ func (ps *PubSubService) Subscribe(pattern string, handler EventHandler) error {
 		return fmt.Errorf("already subscribed to pattern: %s", pattern)
 	}
 
-	pubsub := ps.client.PSubscribe(ps.ctx, pattern)
+	pubsub := ps.client.PSubscribe(ps.ctx, rediskeys.Key(pattern))
 	ps.publishers[pattern] = pubsub
 
 	// Start message handling goroutine
@@ -150,13 +227,20 @@ func (ps *PubSubService) Unsubscribe(pattern string) error {
 // Event publishing methods for different event types
 
 func (ps *PubSubService) PublishPersonalNotification(userID uint, data interface{}, metadata *SubscriptionMetadata) error {
-	channel := fmt.Sprintf(PersonalNotificationsChannel, userID)
-	event := &SubscriptionEvent{
-		Type:     "personal_notification",
-		Data:     data,
-		Metadata: metadata,
+	e := PersonalNotificationEvent(userID, data, metadata)
+	return ps.Publish(e.Channel, e.Event)
+}
+
+// PersonalNotificationEvent builds the event PublishPersonalNotification publishes
+func PersonalNotificationEvent(userID uint, data interface{}, metadata *SubscriptionMetadata) ChannelEvent {
+	return ChannelEvent{
+		Channel: fmt.Sprintf(PersonalNotificationsChannel, userID),
+		Event: &SubscriptionEvent{
+			Type:     "personal_notification",
+			Data:     data,
+			Metadata: metadata,
+		},
 	}
-	return ps.Publish(channel, event)
 }
 
 func (ps *PubSubService) PublishActivityUpdate(activityID uint, data interface{}, metadata *SubscriptionMetadata) error {
@@ -229,13 +313,20 @@ func (ps *PubSubService) PublishActivityAssignment(userID uint, assignment inter
 }
 
 func (ps *PubSubService) PublishNewActivity(facultyID uint, activity interface{}, metadata *SubscriptionMetadata) error {
-	channel := fmt.Sprintf(NewActivitiesChannel, facultyID)
-	event := &SubscriptionEvent{
-		Type:     "new_activity",
-		Data:     activity,
-		Metadata: metadata,
+	e := NewActivityEvent(facultyID, activity, metadata)
+	return ps.Publish(e.Channel, e.Event)
+}
+
+// NewActivityEvent builds the event PublishNewActivity publishes
+func NewActivityEvent(facultyID uint, activity interface{}, metadata *SubscriptionMetadata) ChannelEvent {
+	return ChannelEvent{
+		Channel: fmt.Sprintf(NewActivitiesChannel, facultyID),
+		Event: &SubscriptionEvent{
+			Type:     "new_activity",
+			Data:     activity,
+			Metadata: metadata,
+		},
 	}
-	return ps.Publish(channel, event)
 }
 
 func (ps *PubSubService) PublishHeartbeat() error {
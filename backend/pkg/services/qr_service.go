@@ -1,18 +1,30 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/kruakemaths/tru-activity/backend/internal/database"
 	"github.com/kruakemaths/tru-activity/backend/internal/models"
 	"github.com/kruakemaths/tru-activity/backend/pkg/utils"
 	"gorm.io/gorm"
 )
 
+// AttendanceRescanCooldown is the minimum gap between scans before a
+// latest-scan-wins activity moves the attendance time forward
+const AttendanceRescanCooldown = 1 * time.Minute
+
+// ErrRegistrationRequired is returned for a scan of a student who hasn't registered for an
+// activity that needs approval
+var ErrRegistrationRequired = errors.New("registration required")
+
 type QRService struct {
 	DB            *gorm.DB
 	SecretManager *utils.QRSecretManager
 	MaxQRAge      time.Duration
+	RetryPolicy   database.RetryPolicy
 }
 
 type QRScanRequest struct {
@@ -37,6 +49,7 @@ func NewQRService(db *gorm.DB, masterKey string, maxAge time.Duration) *QRServic
 		DB:            db,
 		SecretManager: utils.NewQRSecretManager(masterKey),
 		MaxQRAge:      maxAge,
+		RetryPolicy:   database.DefaultRetryPolicy,
 	}
 }
 
@@ -75,57 +88,38 @@ func (qs *QRService) ScanQRCode(req *QRScanRequest) (*QRScanResult, error) {
 		return qs.createFailedResult("Permission denied", req, "Admin does not have permission to scan for this activity"), nil
 	}
 
-	// Find or create participation
-	var participation models.Participation
-	err = qs.DB.Where("user_id = ? AND activity_id = ?", user.ID, req.ActivityID).First(&participation).Error
-	
-	if err == gorm.ErrRecordNotFound {
-		// Auto-register user if activity allows it
-		if activity.RequireApproval && !activity.AutoApprove {
-			return qs.createFailedResult("Registration required", req, "User must register for this activity first"), nil
-		}
-
-		// Create new participation
-		participation = models.Participation{
-			UserID:       user.ID,
-			ActivityID:   req.ActivityID,
-			Status:       models.ParticipationStatusApproved,
-			RegisteredAt: time.Now(),
-			ApprovedAt:   timePtr(time.Now()),
+	// Find or create participation, mark attendance and log the scan as one unit, retrying on
+	// deadlocks between concurrent scanners. Callers publish the result only after it commits.
+	var message string
+	var participation *models.Participation
+	var scanLog models.QRScanLog
+	err = database.RetryTransaction(context.Background(), qs.DB, qs.RetryPolicy, func(tx *gorm.DB) error {
+		var err error
+		participation, message, err = RecordScanAttendance(tx, &activity, user.ID, req.AdminID, time.Now(), req.ScanLocation)
+		if err != nil {
+			return err
 		}
 
-		if err := qs.DB.Create(&participation).Error; err != nil {
-			return qs.createFailedResult("Failed to create participation", req, err.Error()), nil
+		scanLog = qs.createScanLog(req, qrData, &user, true, "")
+		if err := tx.Create(&scanLog).Error; err != nil {
+			return fmt.Errorf("failed to log scan: %w", err)
 		}
-	} else if err != nil {
-		return qs.createFailedResult("Database error", req, err.Error()), nil
+		return nil
+	})
+	if err == ErrRegistrationRequired {
+		return qs.createFailedResult("Registration required", req, "User must register for this activity first"), nil
 	}
-
-	// Update participation with scan details
-	now := time.Now()
-	updates := map[string]interface{}{
-		"qr_scanned_at": &now,
-		"scanned_by_id": req.AdminID,
-		"scan_location": req.ScanLocation,
-		"attended_at":   &now,
-		"status":        models.ParticipationStatusAttended,
-	}
-
-	if err := qs.DB.Model(&participation).Updates(updates).Error; err != nil {
-		return qs.createFailedResult("Failed to update participation", req, err.Error()), nil
+	if err != nil {
+		return qs.createFailedResult("Failed to record attendance", req, err.Error()), nil
 	}
 
-	// Create successful scan log
-	scanLog := qs.createScanLog(req, qrData, &user, true, "")
-	qs.DB.Create(&scanLog)
-
 	// Reload participation with associations
-	qs.DB.Preload("User").Preload("Activity").First(&participation, participation.ID)
+	qs.DB.Preload("User").Preload("Activity").First(participation, participation.ID)
 
 	return &QRScanResult{
 		Success:       true,
-		Message:       "QR code scanned successfully",
-		Participation: &participation,
+		Message:       message,
+		Participation: participation,
 		User:          &user,
 		ScanLog:       &scanLog,
 	}, nil
@@ -257,6 +251,78 @@ func (qs *QRService) canAdminScanForActivity(admin *models.User, activity *model
 	return false
 }
 
+// RecordScanAttendance marks a student's attendance for a scan at scannedAt within tx,
+// registering them first when the activity lets them join without approval. It returns
+// ErrRegistrationRequired otherwise. Scans older than the recorded one, e.g. from a scanner
+// that was offline, don't move the scan time back.
+func RecordScanAttendance(tx *gorm.DB, activity *models.Activity, userID, scannerID uint, scannedAt time.Time, scanLocation string) (*models.Participation, string, error) {
+	var participation models.Participation
+	err := tx.Where("user_id = ? AND activity_id = ?", userID, activity.ID).First(&participation).Error
+
+	if err == gorm.ErrRecordNotFound {
+		// Auto-register user if activity allows it
+		if activity.RequireApproval && !activity.AutoApprove {
+			return nil, "", ErrRegistrationRequired
+		}
+
+		// Create new participation
+		now := time.Now()
+		participation = models.Participation{
+			UserID:       userID,
+			ActivityID:   activity.ID,
+			Status:       models.ParticipationStatusApproved,
+			RegisteredAt: now,
+			ApprovedAt:   timePtr(now),
+		}
+
+		if err := tx.Create(&participation).Error; err != nil {
+			return nil, "", fmt.Errorf("failed to create participation: %w", err)
+		}
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	// Update participation with scan details
+	updates := map[string]interface{}{
+		"status": models.ParticipationStatusAttended,
+	}
+	if participation.QRScannedAt == nil || scannedAt.After(*participation.QRScannedAt) {
+		updates["qr_scanned_at"] = &scannedAt
+		updates["scanned_by_id"] = scannerID
+		updates["scan_location"] = scanLocation
+	}
+
+	message := "QR code scanned successfully"
+	if shouldUpdateAttendedAt(activity, &participation, scannedAt) {
+		updates["attended_at"] = &scannedAt
+	} else {
+		message = "Scan recorded, attendance time unchanged"
+	}
+
+	if err := tx.Model(&participation).Updates(updates).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to update participation: %w", err)
+	}
+	return &participation, message, nil
+}
+
+// shouldUpdateAttendedAt applies the activity's attendance policy to a successful scan.
+// The first scan always sets the attendance time; later scans only move it for
+// latest-scan-wins activities and only once the rescan cooldown has passed.
+func shouldUpdateAttendedAt(activity *models.Activity, participation *models.Participation, scannedAt time.Time) bool {
+	if participation.AttendedAt == nil {
+		return true
+	}
+
+	if activity.AttendancePolicy != models.AttendancePolicyLatestScan {
+		return false
+	}
+
+	if participation.QRScannedAt != nil && scannedAt.Sub(*participation.QRScannedAt) < AttendanceRescanCooldown {
+		return false
+	}
+	return true
+}
+
 func parseQRScanRequest(reqStr string) *QRScanRequest {
 	// This is a helper for error cases - in practice, we'd parse the actual request
 	return &QRScanRequest{}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+)
+
+func TestShouldUpdateAttendedAtAcrossScans(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	withinCooldown := start.Add(AttendanceRescanCooldown / 2)
+	afterCooldown := start.Add(AttendanceRescanCooldown + time.Minute)
+	muchLater := afterCooldown.Add(2 * time.Hour)
+
+	tests := []struct {
+		name   string
+		policy models.AttendancePolicy
+		scans  []time.Time
+		want   []bool
+		wantAt time.Time
+	}{
+		{"first scan", models.AttendancePolicyFirstScan,
+			[]time.Time{start, withinCooldown, afterCooldown, muchLater},
+			[]bool{true, false, false, false}, start},
+		{"unset policy keeps the first scan", "",
+			[]time.Time{start, afterCooldown},
+			[]bool{true, false}, start},
+		{"latest scan", models.AttendancePolicyLatestScan,
+			[]time.Time{start, withinCooldown, afterCooldown, muchLater},
+			[]bool{true, false, true, true}, muchLater},
+		// The cooldown runs from the last recorded scan, even one that left the time unchanged
+		{"latest scan cooldown from the last scan", models.AttendancePolicyLatestScan,
+			[]time.Time{start, withinCooldown, withinCooldown.Add(AttendanceRescanCooldown / 2)},
+			[]bool{true, false, false}, start},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := &models.Activity{AttendancePolicy: tt.policy}
+			participation := &models.Participation{}
+
+			// Apply each scan the way ScanQR updates the participation
+			for i, scannedAt := range tt.scans {
+				scannedAt := scannedAt
+				got := shouldUpdateAttendedAt(activity, participation, scannedAt)
+				if got != tt.want[i] {
+					t.Errorf("scan %d at %s: shouldUpdateAttendedAt() = %v, want %v", i+1, scannedAt.Sub(start), got, tt.want[i])
+				}
+				if got {
+					participation.AttendedAt = &scannedAt
+				}
+				if participation.QRScannedAt == nil || scannedAt.After(*participation.QRScannedAt) {
+					participation.QRScannedAt = &scannedAt
+				}
+			}
+
+			if !participation.AttendedAt.Equal(tt.wantAt) {
+				t.Errorf("attended at %s, want %s", participation.AttendedAt.Sub(start), tt.wantAt.Sub(start))
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultScanSessionWindow = 5 * time.Minute
+	MaxScanSessionWindow     = time.Hour
+)
+
+// ScanSession summarises recent QR scanning at one activity
+type ScanSession struct {
+	Activity       models.Activity
+	SuccessCount   int
+	FailureCount   int
+	ScansPerMinute float64
+	LastScanAt     time.Time
+	Scanners       []models.User
+}
+
+// ScanMonitor reports which activities are being scanned right now, based on the scan log
+type ScanMonitor struct {
+	DB *gorm.DB
+}
+
+func NewScanMonitor(db *gorm.DB) *ScanMonitor {
+	return &ScanMonitor{DB: db}
+}
+
+type scanSessionRow struct {
+	ActivityID   uint
+	SuccessCount int
+	FailureCount int
+	LastScanAt   time.Time
+}
+
+// GetActiveScanSessions returns activities with scans inside the window, busiest first.
+// A non-nil facultyID restricts the result to that faculty's activities.
+func (sm *ScanMonitor) GetActiveScanSessions(window time.Duration, facultyID *uint) ([]ScanSession, error) {
+	if window <= 0 {
+		window = DefaultScanSessionWindow
+	}
+	if window > MaxScanSessionWindow {
+		window = MaxScanSessionWindow
+	}
+
+	query := sm.DB.Model(&models.QRScanLog{}).
+		Select("qr_scan_logs.activity_id AS activity_id, "+
+			"SUM(CASE WHEN qr_scan_logs.valid THEN 1 ELSE 0 END) AS success_count, "+
+			"SUM(CASE WHEN qr_scan_logs.valid THEN 0 ELSE 1 END) AS failure_count, "+
+			"MAX(qr_scan_logs.scan_timestamp) AS last_scan_at").
+		Where("qr_scan_logs.scan_timestamp >= ?", time.Now().Add(-window)).
+		Group("qr_scan_logs.activity_id").
+		Order("COUNT(*) DESC")
+
+	if facultyID != nil {
+		query = query.Joins("JOIN activities ON activities.id = qr_scan_logs.activity_id").
+			Where("activities.faculty_id = ?", *facultyID)
+	}
+
+	var rows []scanSessionRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate recent scans: %v", err)
+	}
+	if len(rows) == 0 {
+		return []ScanSession{}, nil
+	}
+
+	activityIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		activityIDs[i] = row.ActivityID
+	}
+
+	var activities []models.Activity
+	if err := sm.DB.Preload("Faculty").Where("id IN ?", activityIDs).Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch scanned activities: %v", err)
+	}
+	activityByID := make(map[uint]models.Activity, len(activities))
+	for _, activity := range activities {
+		activityByID[activity.ID] = activity
+	}
+
+	var assignments []models.ActivityAssignment
+	if err := sm.DB.Preload("Admin").
+		Where("activity_id IN ? AND can_scan_qr = ?", activityIDs, true).
+		Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch activity scanners: %v", err)
+	}
+	scannersByActivity := make(map[uint][]models.User)
+	for _, assignment := range assignments {
+		scannersByActivity[assignment.ActivityID] = append(scannersByActivity[assignment.ActivityID], assignment.Admin)
+	}
+
+	sessions := make([]ScanSession, 0, len(rows))
+	for _, row := range rows {
+		activity, ok := activityByID[row.ActivityID]
+		if !ok {
+			continue
+		}
+		sessions = append(sessions, ScanSession{
+			Activity:       activity,
+			SuccessCount:   row.SuccessCount,
+			FailureCount:   row.FailureCount,
+			ScansPerMinute: float64(row.SuccessCount+row.FailureCount) / window.Minutes(),
+			LastScanAt:     row.LastScanAt,
+			Scanners:       scannersByActivity[row.ActivityID],
+		})
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"database/sql/driver"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/testutil/fakedb"
+)
+
+type seededScan struct {
+	activityID int64
+	valid      bool
+	at         time.Time
+}
+
+// scanLog answers the scan monitor's queries from seeded scans, aggregating the scans at or
+// after the query's cutoff the way the GROUP BY would
+func scanLog(scans []seededScan) fakedb.Responder {
+	return func(query string, args []driver.NamedValue) fakedb.Result {
+		switch {
+		case strings.Contains(query, `FROM "qr_scan_logs"`):
+			cutoff := args[0].Value.(time.Time)
+			type group struct {
+				success, failure int64
+				last             time.Time
+			}
+			groups := map[int64]*group{}
+			for _, scan := range scans {
+				if scan.at.Before(cutoff) {
+					continue
+				}
+				g := groups[scan.activityID]
+				if g == nil {
+					g = &group{}
+					groups[scan.activityID] = g
+				}
+				if scan.valid {
+					g.success++
+				} else {
+					g.failure++
+				}
+				if scan.at.After(g.last) {
+					g.last = scan.at
+				}
+			}
+			result := fakedb.Result{Columns: []string{"activity_id", "success_count", "failure_count", "last_scan_at"}}
+			for id, g := range groups {
+				result.Rows = append(result.Rows, []driver.Value{id, g.success, g.failure, g.last})
+			}
+			sort.Slice(result.Rows, func(i, j int) bool {
+				a, b := result.Rows[i], result.Rows[j]
+				return a[1].(int64)+a[2].(int64) > b[1].(int64)+b[2].(int64)
+			})
+			return result
+		case strings.Contains(query, `FROM "activities"`):
+			result := fakedb.Result{Columns: []string{"id", "title"}}
+			for _, arg := range args {
+				result.Rows = append(result.Rows, []driver.Value{arg.Value, "Activity"})
+			}
+			return result
+		case strings.Contains(query, `FROM "activity_assignments"`):
+			return fakedb.Result{
+				Columns: []string{"id", "activity_id", "admin_id", "can_scan_qr"},
+				Rows:    [][]driver.Value{{int64(1), int64(1), int64(20), true}},
+			}
+		case strings.Contains(query, `FROM "users"`):
+			return fakedb.Result{Columns: []string{"id", "first_name"}, Rows: [][]driver.Value{{int64(20), "Scanner"}}}
+		}
+		return fakedb.Result{}
+	}
+}
+
+func TestGetActiveScanSessions(t *testing.T) {
+	now := time.Now()
+	scans := []seededScan{
+		{1, true, now.Add(-4 * time.Minute)},
+		{1, true, now.Add(-3 * time.Minute)},
+		{1, false, now.Add(-2 * time.Minute)},
+		{1, true, now.Add(-30 * time.Second)},
+		{2, true, now.Add(-time.Minute)},
+		// Older than the window
+		{2, true, now.Add(-20 * time.Minute)},
+		{2, true, now.Add(-21 * time.Minute)},
+		{3, true, now.Add(-time.Hour)},
+	}
+	db, fake := fakedb.Open(t, scanLog(scans))
+
+	sessions, err := NewScanMonitor(db).GetActiveScanSessions(5*time.Minute, nil)
+	if err != nil {
+		t.Fatalf("GetActiveScanSessions: %v", err)
+	}
+
+	// Busiest first, and the activity only scanned long ago is left out
+	if len(sessions) != 2 || sessions[0].Activity.ID != 1 || sessions[1].Activity.ID != 2 {
+		t.Fatalf("sessions = %+v, want activities 1 and 2", sessions)
+	}
+	busiest := sessions[0]
+	if busiest.SuccessCount != 3 || busiest.FailureCount != 1 {
+		t.Errorf("activity 1 scans = %d ok, %d failed, want 3, 1", busiest.SuccessCount, busiest.FailureCount)
+	}
+	if busiest.ScansPerMinute != 0.8 {
+		t.Errorf("activity 1 scans per minute = %v, want 0.8", busiest.ScansPerMinute)
+	}
+	if !busiest.LastScanAt.Equal(now.Add(-30 * time.Second)) {
+		t.Errorf("activity 1 last scan = %s, want 30s ago", now.Sub(busiest.LastScanAt))
+	}
+	if len(busiest.Scanners) != 1 || busiest.Scanners[0].ID != 20 {
+		t.Errorf("activity 1 scanners = %+v, want admin 20", busiest.Scanners)
+	}
+	if quiet := sessions[1]; quiet.SuccessCount != 1 || quiet.FailureCount != 0 || len(quiet.Scanners) != 0 {
+		t.Errorf("activity 2 = %+v, want one successful scan and no scanners", quiet)
+	}
+
+	// Faculty admins only see their faculty's activities
+	facultyID := uint(4)
+	if _, err := NewScanMonitor(db).GetActiveScanSessions(5*time.Minute, &facultyID); err != nil {
+		t.Fatalf("GetActiveScanSessions(faculty): %v", err)
+	}
+	if len(fakedb.Containing(fake.Statements(), `FROM "qr_scan_logs" JOIN activities`, "activities.faculty_id = $")) != 1 {
+		t.Errorf("faculty query is not scoped: %q", fake.Statements())
+	}
+}
+
+func TestGetActiveScanSessionsWindow(t *testing.T) {
+	now := time.Now()
+	db, _ := fakedb.Open(t, scanLog([]seededScan{{1, true, now.Add(-10 * time.Minute)}, {2, true, now.Add(-2 * time.Hour)}}))
+	monitor := NewScanMonitor(db)
+
+	tests := []struct {
+		window time.Duration
+		want   int
+	}{
+		{0, 0},                // the default five minutes
+		{15 * time.Minute, 1}, // wide enough for the scan
+		{-time.Minute, 0},     // invalid windows fall back to the default
+		{48 * time.Hour, 1},   // capped at an hour, leaving out the older scan
+	}
+	for _, tt := range tests {
+		sessions, err := monitor.GetActiveScanSessions(tt.window, nil)
+		if err != nil {
+			t.Fatalf("GetActiveScanSessions(%s): %v", tt.window, err)
+		}
+		if len(sessions) != tt.want {
+			t.Errorf("GetActiveScanSessions(%s) returned %d sessions, want %d", tt.window, len(sessions), tt.want)
+		}
+	}
+}
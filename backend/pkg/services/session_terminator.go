@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"log"
+)
+
+// SessionTerminateChannel carries requests for every instance to drop a user's realtime connections
+const SessionTerminateChannel = "session_terminate"
+
+// ConnectionCloser closes the user's connections of one kind on this instance and returns how many
+type ConnectionCloser func(userID uint) int
+
+// SessionTerminator drops a user's realtime connections (GraphQL websockets and subscriptions,
+// SSE streams) on every instance. Without pub/sub only this instance's connections are closed.
+type SessionTerminator struct {
+	pubSub     *PubSubService
+	instanceID string
+	closers    []ConnectionCloser
+}
+
+func NewSessionTerminator(pubSub *PubSubService, instanceID string, closers ...ConnectionCloser) *SessionTerminator {
+	return &SessionTerminator{
+		pubSub:     pubSub,
+		instanceID: instanceID,
+		closers:    closers,
+	}
+}
+
+// Start listens for terminations requested by other instances
+func (st *SessionTerminator) Start() error {
+	if st.pubSub == nil {
+		return nil
+	}
+	return st.pubSub.Subscribe(SessionTerminateChannel, st.handleTerminateEvent)
+}
+
+// TerminateUser closes the user's connections here and asks the other instances to do the same.
+// It returns the number closed on this instance.
+func (st *SessionTerminator) TerminateUser(userID uint) (int, error) {
+	closed := st.closeLocal(userID)
+
+	if st.pubSub == nil {
+		return closed, nil
+	}
+	if err := st.pubSub.Publish(SessionTerminateChannel, &SubscriptionEvent{
+		Type:       "session_terminate",
+		InstanceID: st.instanceID,
+		Data: map[string]interface{}{
+			"user_id": userID,
+		},
+	}); err != nil {
+		return closed, fmt.Errorf("failed to notify other instances: %v", err)
+	}
+	return closed, nil
+}
+
+func (st *SessionTerminator) closeLocal(userID uint) int {
+	closed := 0
+	for _, closer := range st.closers {
+		closed += closer(userID)
+	}
+	return closed
+}
+
+func (st *SessionTerminator) handleTerminateEvent(event *SubscriptionEvent) error {
+	// The requesting instance already closed its own connections
+	if event.InstanceID == st.instanceID {
+		return nil
+	}
+
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	userID, ok := data["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return nil
+	}
+
+	if closed := st.closeLocal(uint(userID)); closed > 0 {
+		log.Printf("Closed %d connections of user %d terminated by instance %s", closed, uint(userID), event.InstanceID)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"gorm.io/gorm"
+)
+
+const (
+	SubscriptionExpiryLockKey      = "subscription_expiry"
+	SubscriptionExpiryLockTTL      = 10 * time.Minute
+	SubscriptionExpiryScanInterval = 24 * time.Hour
+)
+
+// SubscriptionExpiryMonitor warns faculties as their subscription nears expiry, once per
+// threshold, and moves subscriptions past their end date to expired
+type SubscriptionExpiryMonitor struct {
+	DB             *gorm.DB
+	EventPublisher *EventPublisher
+	lock           *lock.DistributedLock
+	thresholds     []int
+}
+
+func NewSubscriptionExpiryMonitor(db *gorm.DB, publisher *EventPublisher, distributedLock *lock.DistributedLock, warningDays []int) *SubscriptionExpiryMonitor {
+	var thresholds []int
+	seen := make(map[int]bool)
+	for _, days := range warningDays {
+		if days > 0 && !seen[days] {
+			seen[days] = true
+			thresholds = append(thresholds, days)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+
+	return &SubscriptionExpiryMonitor{
+		DB:             db,
+		EventPublisher: publisher,
+		lock:           distributedLock,
+		thresholds:     thresholds,
+	}
+}
+
+// ExpireSubscriptions moves active subscriptions past their end date to expired and tells their faculties
+func (sm *SubscriptionExpiryMonitor) ExpireSubscriptions() (int, error) {
+	var subscriptions []models.Subscription
+	if err := sm.DB.Preload("Faculty").
+		Where("status = ? AND end_date < ?", models.SubscriptionStatusActive, time.Now()).
+		Find(&subscriptions).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+
+		// Only transition rows that are still active, in case the subscription was renewed meanwhile
+		result := sm.DB.Model(&models.Subscription{}).
+			Where("id = ? AND status = ? AND end_date < ?", subscription.ID, models.SubscriptionStatusActive, time.Now()).
+			Updates(map[string]interface{}{
+				"status":  models.SubscriptionStatusExpired,
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			log.Printf("Failed to expire subscription %d: %v", subscription.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		subscription.Status = models.SubscriptionStatusExpired
+		subscription.Version++
+		expired++
+		sm.publishWarning(subscription, "expired")
+	}
+
+	return expired, nil
+}
+
+// PublishExpiryWarnings sends an expiring_soon warning for each active subscription that has
+// reached a new threshold. The threshold is recorded before publishing, so a warning is sent
+// at most once even when several instances or runs overlap.
+func (sm *SubscriptionExpiryMonitor) PublishExpiryWarnings() (int, error) {
+	if len(sm.thresholds) == 0 {
+		return 0, nil
+	}
+
+	var subscriptions []models.Subscription
+	if err := models.WhereDaysUntilExpiry(sm.DB.Preload("Faculty"), time.Now(), 0, sm.thresholds[0]).
+		Where("status = ?", models.SubscriptionStatusActive).
+		Find(&subscriptions).Error; err != nil {
+		return 0, err
+	}
+
+	warned := 0
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		threshold, due := subscription.DueExpiryWarning(sm.thresholds)
+		if !due {
+			continue
+		}
+
+		result := sm.DB.Model(&models.Subscription{}).
+			Where("id = ? AND (expiry_warning_days IS NULL OR expiry_warning_days > ?)", subscription.ID, threshold).
+			Update("expiry_warning_days", threshold)
+		if result.Error != nil {
+			log.Printf("Failed to record expiry warning for subscription %d: %v", subscription.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		subscription.ExpiryWarningDays = &threshold
+		warned++
+		sm.publishWarning(subscription, "expiring_soon")
+	}
+
+	return warned, nil
+}
+
+func (sm *SubscriptionExpiryMonitor) publishWarning(subscription *models.Subscription, warningType string) {
+	if sm.EventPublisher == nil {
+		return
+	}
+	if err := sm.EventPublisher.PublishSubscriptionWarning(subscription, warningType, &EventContext{
+		FacultyID: &subscription.FacultyID,
+		Source:    "subscription_expiry",
+	}); err != nil {
+		log.Printf("Failed to publish %s warning for subscription %d: %v", warningType, subscription.ID, err)
+	}
+}
+
+// runOnce checks subscriptions under the distributed lock so only one instance does the work
+func (sm *SubscriptionExpiryMonitor) runOnce() {
+	err := sm.lock.WithLock(context.Background(), SubscriptionExpiryLockKey, SubscriptionExpiryLockTTL, func() error {
+		expired, err := sm.ExpireSubscriptions()
+		if err != nil {
+			return err
+		}
+		warned, err := sm.PublishExpiryWarnings()
+		if err != nil {
+			return err
+		}
+		if expired > 0 || warned > 0 {
+			log.Printf("Expired %d subscriptions and sent %d expiry warnings", expired, warned)
+		}
+		return nil
+	})
+
+	if err == lock.ErrLockHeld {
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking subscription expiry: %v", err)
+	}
+}
+
+// StartExpiryScheduler starts a background loop that checks subscription expiry daily
+func (sm *SubscriptionExpiryMonitor) StartExpiryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(SubscriptionExpiryScanInterval)
+	defer ticker.Stop()
+
+	// Run once immediately
+	sm.runOnce()
+
+	for {
+		select {
+		case <-ticker.C:
+			sm.runOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kruakemaths/tru-activity/backend/internal/models"
+	"github.com/kruakemaths/tru-activity/backend/pkg/lock"
+	"github.com/kruakemaths/tru-activity/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+const (
+	UnstaffedCheckLockKey  = "unstaffed_activity_monitor"
+	UnstaffedCheckLockTTL  = 5 * time.Minute
+	UnstaffedCheckInterval = 15 * time.Minute
+)
+
+// UnstaffedActivityMonitor alerts faculty admins about upcoming QR activities with no assigned scanner
+type UnstaffedActivityMonitor struct {
+	DB                  *gorm.DB
+	ActivityService     *ActivityService
+	NotificationService *notifications.NotificationService
+	lock                *lock.DistributedLock
+	leadTime            time.Duration
+}
+
+func NewUnstaffedActivityMonitor(db *gorm.DB, notificationService *notifications.NotificationService, distributedLock *lock.DistributedLock, leadHours int) *UnstaffedActivityMonitor {
+	return &UnstaffedActivityMonitor{
+		DB:                  db,
+		ActivityService:     NewActivityService(db, distributedLock),
+		NotificationService: notificationService,
+		lock:                distributedLock,
+		leadTime:            time.Duration(leadHours) * time.Hour,
+	}
+}
+
+// NotifyUnstaffedActivities alerts once per activity that starts within the lead time and has no scanner
+func (um *UnstaffedActivityMonitor) NotifyUnstaffedActivities() (int, error) {
+	startsBefore := time.Now().Add(um.leadTime)
+	activities, err := um.ActivityService.GetUnstaffedActivities(nil, &startsBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for i := range activities {
+		activity := &activities[i]
+		if activity.UnstaffedAlertSentAt != nil {
+			continue
+		}
+
+		if err := um.NotificationService.SendUnstaffedActivityAlert(activity); err != nil {
+			log.Printf("Failed to send unstaffed alert for activity %d: %v", activity.ID, err)
+			continue
+		}
+
+		now := time.Now()
+		if err := um.DB.Model(&models.Activity{}).Where("id = ?", activity.ID).
+			Update("unstaffed_alert_sent_at", now).Error; err != nil {
+			log.Printf("Failed to mark unstaffed alert for activity %d: %v", activity.ID, err)
+		}
+		notified++
+	}
+
+	return notified, nil
+}
+
+// runOnce checks under the distributed lock so only one instance sends alerts
+func (um *UnstaffedActivityMonitor) runOnce() {
+	err := um.lock.WithLock(context.Background(), UnstaffedCheckLockKey, UnstaffedCheckLockTTL, func() error {
+		count, err := um.NotifyUnstaffedActivities()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Sent unstaffed alerts for %d activities", count)
+		}
+		return nil
+	})
+
+	if err == lock.ErrLockHeld {
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking unstaffed activities: %v", err)
+	}
+}
+
+// StartUnstaffedCheckScheduler starts a background loop that periodically checks for unstaffed activities
+func (um *UnstaffedActivityMonitor) StartUnstaffedCheckScheduler(ctx context.Context) {
+	ticker := time.NewTicker(UnstaffedCheckInterval)
+	defer ticker.Stop()
+
+	// Run once immediately
+	um.runOnce()
+
+	for {
+		select {
+		case <-ticker.C:
+			um.runOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// BreachedPasswordFilter is a bloom filter over SHA-1 password hashes. Lookups never
+// miss a listed password but may rarely reject one that isn't listed.
+type BreachedPasswordFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+}
+
+// NewBreachedPasswordFilter sizes a filter for n entries at the given false positive rate
+func NewBreachedPasswordFilter(n int, falsePositiveRate float64) *BreachedPasswordFilter {
+	if n < 1 {
+		n = 1
+	}
+	size := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := int(math.Round(float64(size) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &BreachedPasswordFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// LoadBreachedPasswordFilter builds a filter from a list in the Have I Been Pwned
+// download format: one uppercase or lowercase SHA-1 hash per line, optionally followed by ":count"
+func LoadBreachedPasswordFilter(path string, falsePositiveRate float64) (*BreachedPasswordFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	filter := NewBreachedPasswordFilter(lines, falsePositiveRate)
+	scanner = bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" {
+			continue
+		}
+		digest, err := hex.DecodeString(hash)
+		if err != nil || len(digest) != sha1.Size {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		filter.add(digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	return filter, nil
+}
+
+// Add records a password as breached
+func (f *BreachedPasswordFilter) Add(password string) {
+	digest := sha1.Sum([]byte(password))
+	f.add(digest[:])
+}
+
+// Contains reports whether a password is (probably) in the breached list
+func (f *BreachedPasswordFilter) Contains(password string) bool {
+	digest := sha1.Sum([]byte(password))
+	for _, bit := range f.positions(digest[:]) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BreachedPasswordFilter) add(digest []byte) {
+	for _, bit := range f.positions(digest) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// positions derives the filter's bit positions from the SHA-1 digest by double hashing
+func (f *BreachedPasswordFilter) positions(digest []byte) []uint64 {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % f.size
+	}
+	return positions
+}
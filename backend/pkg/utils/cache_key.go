@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// CanonicalJSON encodes v with object keys sorted at every level, so values that differ only in
+// map insertion order or struct field order encode identically. Numbers keep their original text.
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoding into generic maps and encoding again sorts every object's keys
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// HashKey returns the full hex SHA-256 of v's canonical JSON, for cache keys that must not
+// depend on map ordering. Values JSON can't encode are hashed from their Go syntax instead.
+func HashKey(v interface{}) string {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", v))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
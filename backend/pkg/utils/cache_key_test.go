@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"sorted keys", map[string]interface{}{"b": 1, "a": map[string]interface{}{"d": true, "c": nil}}, `{"a":{"c":null,"d":true},"b":1}`},
+		{"struct fields", struct {
+			Z string `json:"z"`
+			A int    `json:"a"`
+		}{"x", 2}, `{"a":2,"z":"x"}`},
+		// Large IDs would lose digits as float64
+		{"numbers keep their text", map[string]interface{}{"id": uint64(9007199254740993)}, `{"id":9007199254740993}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON(tt.value)
+			if err != nil {
+				t.Fatalf("CanonicalJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	a := map[string]interface{}{"status": "active", "limit": 20, "filter": map[string]interface{}{"faculty": 1, "type": "seminar"}}
+	b := map[string]interface{}{"filter": map[string]interface{}{"type": "seminar", "faculty": 1}, "limit": 20, "status": "active"}
+
+	key := HashKey(a)
+	if len(key) != 64 {
+		t.Errorf("HashKey() = %q, want the full 64-character SHA-256", key)
+	}
+	if other := HashKey(b); other != key {
+		t.Errorf("HashKey() depends on key order: %s and %s", key, other)
+	}
+	if other := HashKey(map[string]interface{}{"status": "active", "limit": 21}); other == key {
+		t.Error("HashKey() is the same for different variables")
+	}
+
+	// Falls back to Go syntax for values JSON can't encode
+	if key := HashKey(make(chan int)); len(key) != 64 {
+		t.Errorf("HashKey() of a channel = %q, want a SHA-256", key)
+	}
+}
@@ -1,9 +1,15 @@
 package utils
 
 import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+const MinPasswordLength = 8
+
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -22,4 +28,78 @@ func CheckPassword(password, hashedPassword string) error {
 func CheckPasswordHash(password, hashedPassword string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// PasswordPolicy lists the rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// Breached rejects passwords found in a known-breach list; nil skips the check
+	Breached *BreachedPasswordFilter
+}
+
+// DefaultPasswordPolicy requires MinPasswordLength characters mixing letters and digits
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     MinPasswordLength,
+	RequireLetter: true,
+	RequireDigit:  true,
+}
+
+// PasswordPolicyError names the policy rule a password failed
+type PasswordPolicyError struct {
+	Rule    string
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+// ValidatePassword checks a password against a policy, returning a *PasswordPolicyError for the first rule it fails
+func ValidatePassword(password string, p PasswordPolicy) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &PasswordPolicyError{Rule: "min_length", Message: fmt.Sprintf("password must be at least %d characters", p.MinLength)}
+	}
+
+	var hasLetter, hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+			hasUpper = hasUpper || unicode.IsUpper(r)
+			hasLower = hasLower || unicode.IsLower(r)
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	switch {
+	case p.RequireLetter && !hasLetter:
+		return &PasswordPolicyError{Rule: "letter", Message: "password must contain a letter"}
+	case p.RequireUpper && !hasUpper:
+		return &PasswordPolicyError{Rule: "uppercase", Message: "password must contain an uppercase letter"}
+	case p.RequireLower && !hasLower:
+		return &PasswordPolicyError{Rule: "lowercase", Message: "password must contain a lowercase letter"}
+	case p.RequireDigit && !hasDigit:
+		return &PasswordPolicyError{Rule: "digit", Message: "password must contain a digit"}
+	case p.RequireSymbol && !hasSymbol:
+		return &PasswordPolicyError{Rule: "symbol", Message: "password must contain a symbol"}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		return &PasswordPolicyError{Rule: "breached", Message: "password appears in a list of breached passwords, please choose another"}
+	}
+	return nil
+}
+
+// ValidatePasswordStrength checks a password against DefaultPasswordPolicy
+func ValidatePasswordStrength(password string) error {
+	return ValidatePassword(password, DefaultPasswordPolicy)
+}
@@ -120,6 +120,7 @@ export const UPDATE_ACTIVITY_MUTATION = gql`
   mutation UpdateActivity($id: ID!, $input: UpdateActivityInput!) {
     updateActivity(id: $id, input: $input) {
       id
+      version
       title
       description
       type
@@ -175,7 +176,10 @@ export const JOIN_ACTIVITY_MUTATION = gql`
 
 export const LEAVE_ACTIVITY_MUTATION = gql`
   mutation LeaveActivity($activityID: ID!) {
-    leaveActivity(activityID: $activityID)
+    leaveActivity(activityID: $activityID) {
+      id
+      status
+    }
   }
 `;
 
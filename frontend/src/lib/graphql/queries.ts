@@ -226,38 +226,42 @@ export const GET_MY_ACTIVITIES = gql`
 export const GET_MY_PARTICIPATIONS = gql`
   query GetMyParticipations {
     myParticipations {
-      id
-      status
-      registeredAt
-      approvedAt
-      attendedAt
-      notes
-      activity {
+      participations {
         id
-        title
-        description
-        type
         status
-        startDate
-        endDate
-        location
-        points
-        faculty {
-          id
-          name
-          code
-        }
-        department {
-          id
-          name
-          code
-        }
-        createdBy {
+        registeredAt
+        approvedAt
+        attendedAt
+        notes
+        activity {
           id
-          firstName
-          lastName
+          title
+          description
+          type
+          status
+          startDate
+          endDate
+          location
+          points
+          faculty {
+            id
+            name
+            code
+          }
+          department {
+            id
+            name
+            code
+          }
+          createdBy {
+            id
+            firstName
+            lastName
+          }
         }
       }
+      totalPoints
+      attendedCount
     }
   }
 `;
\ No newline at end of file